@@ -0,0 +1,129 @@
+package main
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/mafredri/cdp"
+	"github.com/mafredri/cdp/protocol/fetch"
+)
+
+// 请求的最终处理方式
+const (
+	outcomeContinue = iota
+	outcomeFulfill
+	outcomeFail
+)
+
+// fakeFetch 内存中的 Fetch 域实现，记录每个请求被放行、改写或终止的时间；未实现的方法调用会 panic
+type fakeFetch struct {
+	cdp.Fetch
+
+	latency  time.Duration // 模拟每次 CDP 调用的往返耗时
+	body     string        // GetResponseBody 返回的响应体
+	inflight sync.Map      // RequestID -> 投递时间
+	done     chan sample
+	closed   chan struct{}
+	calls    atomic.Int64
+}
+
+// sample 单个请求的处理结果
+type sample struct {
+	latency time.Duration
+	outcome int
+}
+
+// newFakeFetch 创建假 Fetch 域
+func newFakeFetch(latency time.Duration, body string, buffer int) *fakeFetch {
+	return &fakeFetch{latency: latency, body: body, done: make(chan sample, buffer), closed: make(chan struct{})}
+}
+
+// collect 持续把处理结果交给 fn，直到 close 被调用且已缓冲的结果全部取完
+func (f *fakeFetch) collect(fn func(sample)) {
+	for {
+		select {
+		case s := <-f.done:
+			fn(s)
+		case <-f.closed:
+			for {
+				select {
+				case s := <-f.done:
+					fn(s)
+				default:
+					return
+				}
+			}
+		}
+	}
+}
+
+// close 停止收集，之后仍在处理中的请求结束时不再记录
+func (f *fakeFetch) close() {
+	close(f.closed)
+}
+
+// track 记录请求投递时间
+func (f *fakeFetch) track(id fetch.RequestID) {
+	f.inflight.Store(id, time.Now())
+}
+
+// finish 记录请求结束；同一请求只记录第一次结束调用
+func (f *fakeFetch) finish(ctx context.Context, id fetch.RequestID, outcome int) error {
+	f.calls.Add(1)
+	if f.latency > 0 {
+		select {
+		case <-time.After(f.latency):
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	v, ok := f.inflight.LoadAndDelete(id)
+	if !ok {
+		return nil
+	}
+	select {
+	case f.done <- sample{latency: time.Since(v.(time.Time)), outcome: outcome}:
+	case <-f.closed:
+	}
+	return nil
+}
+
+// pending 返回尚未结束的请求数
+func (f *fakeFetch) pending() int {
+	n := 0
+	f.inflight.Range(func(_, _ any) bool {
+		n++
+		return true
+	})
+	return n
+}
+
+func (f *fakeFetch) ContinueRequest(ctx context.Context, args *fetch.ContinueRequestArgs) error {
+	return f.finish(ctx, args.RequestID, outcomeContinue)
+}
+
+func (f *fakeFetch) ContinueResponse(ctx context.Context, args *fetch.ContinueResponseArgs) error {
+	return f.finish(ctx, args.RequestID, outcomeContinue)
+}
+
+func (f *fakeFetch) FulfillRequest(ctx context.Context, args *fetch.FulfillRequestArgs) error {
+	return f.finish(ctx, args.RequestID, outcomeFulfill)
+}
+
+func (f *fakeFetch) FailRequest(ctx context.Context, args *fetch.FailRequestArgs) error {
+	return f.finish(ctx, args.RequestID, outcomeFail)
+}
+
+func (f *fakeFetch) GetResponseBody(ctx context.Context, args *fetch.GetResponseBodyArgs) (*fetch.GetResponseBodyReply, error) {
+	f.calls.Add(1)
+	if f.latency > 0 {
+		select {
+		case <-time.After(f.latency):
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+	return &fetch.GetResponseBodyReply{Body: f.body}, nil
+}
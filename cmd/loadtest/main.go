@@ -0,0 +1,283 @@
+// Command loadtest 以合成的 RequestPaused 事件压测拦截处理流水线。
+//
+// 事件经由与真实浏览器事件流相同的并发分发与 handle 流程处理，CDP 调用由内存中的假 Fetch 域应答，
+// 结束后输出吞吐量、延迟分位数与丢弃率，用于在发布前发现规则引擎或工作池的性能回退。
+//
+// 用法：
+//
+//	go run ./cmd/loadtest -rate 2000 -duration 10s -concurrency 16 -rules rules.json
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"math/rand"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"cdpnetool/internal/cdp"
+	"cdpnetool/internal/logger"
+	"cdpnetool/pkg/model"
+	"cdpnetool/pkg/rulespec"
+
+	mcdp "github.com/mafredri/cdp"
+	"github.com/mafredri/cdp/protocol/fetch"
+	"github.com/mafredri/cdp/protocol/network"
+)
+
+// defaultRules 未指定规则文件时使用的示例规则：请求阶段改写请求头，响应阶段改写 JSON Body
+const defaultRules = `{
+  "id": "loadtest",
+  "name": "loadtest",
+  "version": "1.0",
+  "rules": [
+    {
+      "id": "req-header",
+      "name": "请求头改写",
+      "enabled": true,
+      "priority": 10,
+      "stage": "request",
+      "match": {"allOf": [{"type": "urlPrefix", "value": "https://api.example.com/"}]},
+      "actions": [{"type": "setHeader", "name": "X-Load-Test", "value": "1"}]
+    },
+    {
+      "id": "resp-body",
+      "name": "响应体改写",
+      "enabled": true,
+      "priority": 10,
+      "stage": "response",
+      "match": {"allOf": [{"type": "urlContains", "value": "/users/"}]},
+      "actions": [{"type": "patchBodyJson", "patches": [{"op": "replace", "path": "/data/name", "value": "load-test"}]}]
+    }
+  ]
+}`
+
+// defaultBody 响应阶段假 Fetch 返回的响应体
+const defaultBody = `{"code":0,"data":{"id":1,"name":"alice","tags":["a","b","c"]}}`
+
+// options 压测参数
+type options struct {
+	rate          int
+	duration      time.Duration
+	concurrency   int
+	rulesPath     string
+	responseRatio float64
+	matchRatio    float64
+	timeoutMS     int
+	cdpLatency    time.Duration
+	drain         time.Duration
+}
+
+func main() {
+	var o options
+	flag.IntVar(&o.rate, "rate", 1000, "每秒投递的事件数，0 表示不限速")
+	flag.DurationVar(&o.duration, "duration", 10*time.Second, "投递持续时间")
+	flag.IntVar(&o.concurrency, "concurrency", 16, "工作池并发数，0 表示不限制")
+	flag.StringVar(&o.rulesPath, "rules", "", "规则配置 JSON 文件，为空时使用内置示例规则")
+	flag.Float64Var(&o.responseRatio, "response-ratio", 0.5, "响应阶段事件占比（0-1）")
+	flag.Float64Var(&o.matchRatio, "match-ratio", 0.5, "URL 命中内置示例规则的事件占比（0-1）")
+	flag.IntVar(&o.timeoutMS, "timeout", 3000, "单个事件的处理超时（毫秒）")
+	flag.DurationVar(&o.cdpLatency, "cdp-latency", 0, "模拟每次 CDP 调用的往返耗时")
+	flag.DurationVar(&o.drain, "drain", 5*time.Second, "投递结束后等待未完成事件的最长时间")
+	flag.Parse()
+
+	cfg, err := loadRules(o.rulesPath)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "加载规则失败:", err)
+		os.Exit(1)
+	}
+
+	r, err := run(o, cfg)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "压测失败:", err)
+		os.Exit(1)
+	}
+	r.print(os.Stdout)
+}
+
+// loadRules 读取规则配置文件
+func loadRules(path string) (*rulespec.Config, error) {
+	data := []byte(defaultRules)
+	if path != "" {
+		b, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		data = b
+	}
+	var cfg rulespec.Config
+	if err := json.Unmarshal(data, &cfg); err != nil {
+		return nil, fmt.Errorf("JSON 解析失败: %w", err)
+	}
+	return &cfg, nil
+}
+
+// run 执行一次压测并汇总结果
+func run(o options, cfg *rulespec.Config) (*report, error) {
+	events := make(chan model.InterceptEvent, 4096)
+	mgr := cdp.New("", events, logger.NewNoopLogger())
+	mgr.SetRules(cfg)
+	mgr.SetConcurrency(o.concurrency)
+	mgr.SetRuntime(1<<20, o.timeoutMS)
+
+	ff := newFakeFetch(o.cdpLatency, defaultBody, 4096)
+	const target = model.TargetID("loadtest")
+	if err := mgr.InjectTarget(target, &mcdp.Client{Fetch: ff}); err != nil {
+		return nil, err
+	}
+	defer mgr.Detach(target)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() {
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-events:
+			}
+		}
+	}()
+
+	rep := &report{opts: o}
+	collected := make(chan struct{})
+	go func() {
+		defer close(collected)
+		ff.collect(rep.add)
+	}()
+
+	// 按已流逝时间补齐应投递的事件数，避免高速率下受定时器精度限制
+	rep.start = time.Now()
+	ticker := time.NewTicker(time.Millisecond)
+	defer ticker.Stop()
+	rng := rand.New(rand.NewSource(1))
+	for {
+		elapsed := time.Since(rep.start)
+		if elapsed >= o.duration {
+			break
+		}
+		want := rep.sent + 1
+		if o.rate > 0 {
+			want = int64(elapsed.Seconds() * float64(o.rate))
+		}
+		for ; rep.sent < want; rep.sent++ {
+			ev := syntheticEvent(rng, rep.sent, o)
+			ff.track(ev.RequestID)
+			if err := mgr.DispatchPaused(target, ev); err != nil {
+				return nil, err
+			}
+		}
+		if o.rate > 0 {
+			<-ticker.C
+		}
+	}
+	rep.sendElapsed = time.Since(rep.start)
+
+	// 等待已投递的事件处理完成
+	waitUntil := time.Now().Add(o.drain)
+	for ff.pending() > 0 && time.Now().Before(waitUntil) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	rep.elapsed = time.Since(rep.start)
+	rep.unfinished = ff.pending()
+	rep.cdpCalls = ff.calls.Load()
+	_, _, rep.poolSubmit, rep.poolDrop = mgr.GetPoolStats()
+	rep.stats = mgr.GetStats()
+
+	_ = mgr.Detach(target)
+	ff.close()
+	<-collected
+	return rep, nil
+}
+
+// syntheticEvent 构造第 n 个合成拦截事件
+func syntheticEvent(rng *rand.Rand, n int64, o options) *fetch.RequestPausedReply {
+	path := fmt.Sprintf("/static/%d.js", n)
+	if rng.Float64() < o.matchRatio {
+		path = fmt.Sprintf("/users/%d", n)
+	}
+	headers, _ := json.Marshal(map[string]string{
+		"Accept":     "application/json",
+		"User-Agent": "cdpnetool-loadtest",
+	})
+	ev := &fetch.RequestPausedReply{
+		RequestID:    fetch.RequestID(fmt.Sprintf("lt-%d", n)),
+		Request:      network.Request{URL: "https://api.example.com" + path, Method: "GET", Headers: headers},
+		FrameID:      "loadtest-frame",
+		ResourceType: network.ResourceTypeXHR,
+	}
+	if rng.Float64() < o.responseRatio {
+		code := 200
+		ev.ResponseStatusCode = &code
+		ev.ResponseHeaders = []fetch.HeaderEntry{
+			{Name: "Content-Type", Value: "application/json"},
+			{Name: "Content-Length", Value: fmt.Sprint(len(defaultBody))},
+		}
+	}
+	return ev
+}
+
+// report 压测结果
+type report struct {
+	opts        options
+	start       time.Time
+	sendElapsed time.Duration
+	elapsed     time.Duration
+	sent        int64
+	latencies   []time.Duration
+	outcomes    [3]int64
+	timedOut    int64
+	unfinished  int
+	cdpCalls    int64
+	poolSubmit  int64
+	poolDrop    int64
+	stats       model.EngineStats
+}
+
+// add 记录一个请求的处理结果
+func (r *report) add(s sample) {
+	r.latencies = append(r.latencies, s.latency)
+	r.outcomes[s.outcome]++
+	if s.latency >= time.Duration(r.opts.timeoutMS)*time.Millisecond {
+		r.timedOut++
+	}
+}
+
+// percentile 返回已排序延迟的 p 分位数
+func percentile(sorted []time.Duration, p float64) time.Duration {
+	if len(sorted) == 0 {
+		return 0
+	}
+	i := int(float64(len(sorted)-1) * p)
+	return sorted[i]
+}
+
+// print 输出压测报告
+func (r *report) print(w io.Writer) {
+	sort.Slice(r.latencies, func(i, j int) bool { return r.latencies[i] < r.latencies[j] })
+	done := int64(len(r.latencies))
+	rate := func(n int64) string {
+		if r.sent == 0 {
+			return "0.00%"
+		}
+		return fmt.Sprintf("%.2f%%", float64(n)/float64(r.sent)*100)
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "投递事件:     %d（%.0f/s，目标 %d/s，持续 %s）\n", r.sent, float64(r.sent)/r.sendElapsed.Seconds(), r.opts.rate, r.sendElapsed.Round(time.Millisecond))
+	fmt.Fprintf(&b, "完成事件:     %d（吞吐 %.0f/s，总耗时 %s）\n", done, float64(done)/r.elapsed.Seconds(), r.elapsed.Round(time.Millisecond))
+	fmt.Fprintf(&b, "处理方式:     放行 %d / 改写或拦截 %d / 终止 %d\n", r.outcomes[outcomeContinue], r.outcomes[outcomeFulfill], r.outcomes[outcomeFail])
+	fmt.Fprintf(&b, "规则命中:     %d / %d\n", r.stats.Matched, r.stats.Total)
+	fmt.Fprintf(&b, "CDP 调用:     %d\n", r.cdpCalls)
+	fmt.Fprintf(&b, "延迟:         p50 %s  p90 %s  p99 %s  max %s\n",
+		percentile(r.latencies, 0.50), percentile(r.latencies, 0.90), percentile(r.latencies, 0.99), percentile(r.latencies, 1))
+	fmt.Fprintf(&b, "队列丢弃:     %d（%s，入队 %d）\n", r.poolDrop, rate(r.poolDrop), r.poolSubmit)
+	fmt.Fprintf(&b, "超时降级:     %d（%s）\n", r.timedOut, rate(r.timedOut))
+	fmt.Fprintf(&b, "未完成:       %d（%s）\n", r.unfinished, rate(int64(r.unfinished)))
+	fmt.Fprint(w, b.String())
+}
@@ -280,16 +280,20 @@ function App() {
     // @ts-ignore
     if (window.runtime?.EventsOn) {
       // @ts-ignore
-      const unsubscribe = window.runtime.EventsOn('intercept-event', (event: InterceptEvent) => {
-        console.log('[Events] 收到拦截事件:', event)
+      const unsubscribe = window.runtime.EventsOn('intercept-events', (batch: { events: InterceptEvent[]; dropped: number }) => {
+        if (batch.dropped > 0) {
+          console.warn('[Events] 事件推送积压，已丢弃:', batch.dropped)
+        }
         // 事件由 store 处理，自动分发到匹配/未匹配列表
-        addInterceptEvent(event)
+        for (const event of batch.events) {
+          addInterceptEvent(event)
+        }
       })
-      console.log('[Events] 已订阅 intercept-event 事件')
+      console.log('[Events] 已订阅 intercept-events 事件')
       
       // 清理函数：在组件卸载或依赖变化时取消订阅
       return () => {
-        console.log('[Events] 取消订阅 intercept-event 事件')
+        console.log('[Events] 取消订阅 intercept-events 事件')
         if (unsubscribe) {
           unsubscribe()
         }
@@ -0,0 +1,66 @@
+package analyzer
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"sync"
+)
+
+// ContentChange 响应内容变化结果
+type ContentChange struct {
+	Endpoint string // 端点标识（METHOD scheme://host/path）
+	Previous string // 上次记录的响应体哈希
+	Current  string // 本次响应体哈希
+}
+
+// ContentTracker 按端点跟踪响应体哈希
+type ContentTracker struct {
+	mu     sync.Mutex
+	hashes map[string]string // 端点 -> 最近一次响应体的 SHA-256（十六进制）
+}
+
+// NewContentTracker 创建内容跟踪器
+func NewContentTracker() *ContentTracker {
+	return &ContentTracker{hashes: make(map[string]string)}
+}
+
+// ContentHash 计算响应体哈希
+func ContentHash(body []byte) string {
+	sum := sha256.Sum256(body)
+	return hex.EncodeToString(sum[:])
+}
+
+// Observe 记录端点最新的响应体哈希，若与上次不同则返回变化信息
+func (t *ContentTracker) Observe(endpoint string, body []byte) (*ContentChange, bool) {
+	hash := ContentHash(body)
+
+	t.mu.Lock()
+	prev, seen := t.hashes[endpoint]
+	t.hashes[endpoint] = hash
+	t.mu.Unlock()
+
+	if !seen || prev == hash {
+		return nil, false
+	}
+	return &ContentChange{Endpoint: endpoint, Previous: prev, Current: hash}, true
+}
+
+// Snapshot 导出全部端点哈希，用于跨会话持久化
+func (t *ContentTracker) Snapshot() map[string]string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	out := make(map[string]string, len(t.hashes))
+	for k, v := range t.hashes {
+		out[k] = v
+	}
+	return out
+}
+
+// Restore 导入之前保存的端点哈希
+func (t *ContentTracker) Restore(hashes map[string]string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for k, v := range hashes {
+		t.hashes[k] = v
+	}
+}
@@ -0,0 +1,152 @@
+package analyzer
+
+import (
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultDuplicateWindow 默认的重复请求判定窗口
+const DefaultDuplicateWindow = 2 * time.Second
+
+const (
+	duplicateMaxRecent    = 4096 // 同时跟踪的请求指纹上限，超出时先清理窗口外的指纹
+	duplicateMaxSummaries = 1000 // 汇总报告中记录的重复请求上限，超出后新的重复请求只发送通知
+)
+
+// DuplicateRequest 窗口内再次发送的相同请求
+type DuplicateRequest struct {
+	Method   string
+	URL      string
+	BodyHash string        // 请求体 SHA-256（十六进制），没有请求体时为空
+	Count    int           // 本轮连续出现的次数（含首次）
+	Interval time.Duration // 与上一次相同请求的间隔
+}
+
+// DuplicateSummary 一组相同请求的重复情况汇总
+type DuplicateSummary struct {
+	Method      string
+	URL         string
+	BodyHash    string
+	Duplicates  int           // 判定为重复的次数（不含每轮的首次）
+	MaxBurst    int           // 单轮连续出现的最多次数
+	MinInterval time.Duration // 相邻两次的最短间隔
+	First       time.Time     // 首次判定为重复的时间
+	Last        time.Time     // 最近一次判定为重复的时间
+}
+
+// duplicateEntry 一个请求指纹最近一次出现的情况
+type duplicateEntry struct {
+	last  time.Time
+	burst int
+}
+
+// DuplicateTracker 按方法、URL 与请求体哈希识别窗口内重复发送的请求
+type DuplicateTracker struct {
+	mu      sync.Mutex
+	window  time.Duration
+	recent  map[string]*duplicateEntry
+	summary map[string]*DuplicateSummary
+}
+
+// NewDuplicateTracker 创建重复请求跟踪器，window 不大于 0 时使用 DefaultDuplicateWindow
+func NewDuplicateTracker(window time.Duration) *DuplicateTracker {
+	if window <= 0 {
+		window = DefaultDuplicateWindow
+	}
+	return &DuplicateTracker{
+		window:  window,
+		recent:  make(map[string]*duplicateEntry),
+		summary: make(map[string]*DuplicateSummary),
+	}
+}
+
+// Window 返回判定窗口
+func (t *DuplicateTracker) Window() time.Duration {
+	return t.window
+}
+
+// Observe 记录一次请求，与上一次相同请求的间隔不超过窗口时返回重复信息
+func (t *DuplicateTracker) Observe(method, rawURL string, body []byte, now time.Time) (*DuplicateRequest, bool) {
+	method = strings.ToUpper(method)
+	var hash string
+	if len(body) > 0 {
+		hash = ContentHash(body)
+	}
+	key := method + " " + rawURL + " " + hash
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	e, ok := t.recent[key]
+	if !ok || now.Sub(e.last) > t.window {
+		if !ok {
+			t.pruneLocked(now)
+			e = &duplicateEntry{}
+			t.recent[key] = e
+		}
+		e.last, e.burst = now, 1
+		return nil, false
+	}
+
+	dup := &DuplicateRequest{Method: method, URL: rawURL, BodyHash: hash, Interval: now.Sub(e.last)}
+	e.last = now
+	e.burst++
+	dup.Count = e.burst
+
+	s, ok := t.summary[key]
+	if !ok && len(t.summary) < duplicateMaxSummaries {
+		s = &DuplicateSummary{Method: method, URL: rawURL, BodyHash: hash, MinInterval: dup.Interval, First: now}
+		t.summary[key] = s
+	}
+	if s != nil {
+		s.Duplicates++
+		s.Last = now
+		if dup.Count > s.MaxBurst {
+			s.MaxBurst = dup.Count
+		}
+		if dup.Interval < s.MinInterval {
+			s.MinInterval = dup.Interval
+		}
+	}
+	return dup, true
+}
+
+// pruneLocked 指纹数量达到上限时清理窗口外的指纹，调用方需持有 mu
+func (t *DuplicateTracker) pruneLocked(now time.Time) {
+	if len(t.recent) < duplicateMaxRecent {
+		return
+	}
+	for k, e := range t.recent {
+		if now.Sub(e.last) > t.window {
+			delete(t.recent, k)
+		}
+	}
+}
+
+// Report 返回重复请求汇总，按重复次数从多到少排序
+func (t *DuplicateTracker) Report() []DuplicateSummary {
+	t.mu.Lock()
+	out := make([]DuplicateSummary, 0, len(t.summary))
+	for _, s := range t.summary {
+		out = append(out, *s)
+	}
+	t.mu.Unlock()
+
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].Duplicates != out[j].Duplicates {
+			return out[i].Duplicates > out[j].Duplicates
+		}
+		return out[i].Last.After(out[j].Last)
+	})
+	return out
+}
+
+// Reset 清空已记录的请求与汇总
+func (t *DuplicateTracker) Reset() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.recent = make(map[string]*duplicateEntry)
+	t.summary = make(map[string]*DuplicateSummary)
+}
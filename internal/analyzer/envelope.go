@@ -0,0 +1,267 @@
+package analyzer
+
+import (
+	"bytes"
+	"encoding/xml"
+	"mime"
+	"net/url"
+	"regexp"
+	"strings"
+
+	"github.com/tidwall/gjson"
+)
+
+// 可识别的 API 封装格式
+const (
+	FormatJSONAPI = "jsonapi"
+	FormatGraphQL = "graphql"
+	FormatGRPCWeb = "grpc-web"
+	FormatSOAP    = "soap"
+)
+
+// Exchange 识别封装格式所需的一次请求/响应信息
+type Exchange struct {
+	Method       string
+	URL          string
+	RequestType  string // 请求 Content-Type
+	RequestBody  []byte
+	ResponseType string // 响应 Content-Type
+	ResponseBody []byte
+	SOAPAction   string // SOAPAction 请求头
+}
+
+// Envelope 识别出的封装格式及逻辑操作
+type Envelope struct {
+	Format    string // 封装格式
+	Resource  string // 资源：JSON:API 资源类型、GraphQL 操作类型、gRPC 服务、SOAP 服务
+	Operation string // 操作：JSON:API 动作、GraphQL 操作名、gRPC 方法、SOAP 操作
+}
+
+// Key 返回用于分组的逻辑操作标识，格式 format:resource/operation
+func (e Envelope) Key() string {
+	key := e.Format + ":" + e.Resource
+	if e.Operation != "" {
+		key += "/" + e.Operation
+	}
+	return key
+}
+
+// graphqlOperation 匹配 GraphQL 文档中的首个具名操作
+var graphqlOperation = regexp.MustCompile(`(?:^|[\s}])(query|mutation|subscription)\s+([_A-Za-z][_0-9A-Za-z]*)`)
+
+// DetectEnvelope 识别 JSON:API、GraphQL、gRPC-web、SOAP 封装并解析其操作与资源，无法识别时返回 false
+func DetectEnvelope(ex Exchange) (Envelope, bool) {
+	reqType, respType := mediaType(ex.RequestType), mediaType(ex.ResponseType)
+	if strings.HasPrefix(reqType, "application/grpc-web") || strings.HasPrefix(respType, "application/grpc-web") {
+		return grpcWebEnvelope(ex.URL)
+	}
+	if env, ok := graphqlEnvelope(ex, reqType); ok {
+		return env, true
+	}
+	if reqType == "application/vnd.api+json" || respType == "application/vnd.api+json" {
+		return jsonAPIEnvelope(ex)
+	}
+	if isSOAPType(reqType) || isSOAPType(respType) {
+		return soapEnvelope(ex)
+	}
+	return Envelope{}, false
+}
+
+// mediaType 返回小写的媒体类型，忽略参数
+func mediaType(contentType string) string {
+	if contentType == "" {
+		return ""
+	}
+	if mt, _, err := mime.ParseMediaType(contentType); err == nil {
+		return mt
+	}
+	mt, _, _ := strings.Cut(contentType, ";")
+	return strings.ToLower(strings.TrimSpace(mt))
+}
+
+// grpcWebEnvelope 从 /package.Service/Method 形式的路径解析服务与方法
+func grpcWebEnvelope(rawURL string) (Envelope, bool) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return Envelope{}, false
+	}
+	parts := strings.Split(strings.Trim(u.Path, "/"), "/")
+	if len(parts) < 2 {
+		return Envelope{}, false
+	}
+	return Envelope{Format: FormatGRPCWeb, Resource: parts[len(parts)-2], Operation: parts[len(parts)-1]}, true
+}
+
+// graphqlEnvelope 从请求体（含批量请求的首个操作）或 GET 查询参数中解析 GraphQL 操作
+func graphqlEnvelope(ex Exchange, reqType string) (Envelope, bool) {
+	var query, name string
+	switch {
+	case reqType == "application/graphql":
+		query = string(ex.RequestBody)
+	case len(ex.RequestBody) > 0 && (reqType == "" || strings.HasSuffix(reqType, "json")):
+		body := gjson.ParseBytes(ex.RequestBody)
+		if body.IsArray() {
+			body = body.Get("0")
+		}
+		q := body.Get("query")
+		persisted := body.Get("extensions.persistedQuery").Exists()
+		if !persisted && (q.Type != gjson.String || !isGraphQLDocument(q.String()) && !graphqlPath(ex.URL)) {
+			return Envelope{}, false
+		}
+		query, name = q.String(), body.Get("operationName").String()
+	default:
+		u, err := url.Parse(ex.URL)
+		if err != nil {
+			return Envelope{}, false
+		}
+		params := u.Query()
+		if !params.Has("query") && !params.Has("extensions") || !graphqlPath(ex.URL) {
+			return Envelope{}, false
+		}
+		query, name = params.Get("query"), params.Get("operationName")
+	}
+
+	env := Envelope{Format: FormatGraphQL, Resource: "query", Operation: name}
+	if m := graphqlOperation.FindStringSubmatch(query); m != nil {
+		env.Resource = m[1]
+		if env.Operation == "" {
+			env.Operation = m[2]
+		}
+	} else {
+		// 匿名操作
+		for _, kind := range []string{"mutation", "subscription"} {
+			if strings.HasPrefix(strings.TrimSpace(query), kind) {
+				env.Resource = kind
+			}
+		}
+	}
+	return env, true
+}
+
+// isGraphQLDocument 粗略判断字符串是否为 GraphQL 文档，用于排除普通的 {"query": "..."} 搜索请求
+func isGraphQLDocument(query string) bool {
+	q := strings.TrimSpace(query)
+	if strings.HasPrefix(q, "{") {
+		return true
+	}
+	for _, kw := range []string{"query", "mutation", "subscription", "fragment"} {
+		if strings.HasPrefix(q, kw) && strings.Contains(q, "{") {
+			return true
+		}
+	}
+	return false
+}
+
+// graphqlPath 判断 URL 路径是否包含 graphql
+func graphqlPath(rawURL string) bool {
+	u, err := url.Parse(rawURL)
+	return err == nil && strings.Contains(strings.ToLower(u.Path), "graphql")
+}
+
+// jsonAPIEnvelope 从主数据的 type 解析资源类型，并根据请求方法和是否为集合推断动作
+func jsonAPIEnvelope(ex Exchange) (Envelope, bool) {
+	env := Envelope{Format: FormatJSONAPI}
+	collection := false
+	for _, body := range [][]byte{ex.RequestBody, ex.ResponseBody} {
+		data := gjson.GetBytes(body, "data")
+		if !data.Exists() {
+			continue
+		}
+		if data.IsArray() {
+			collection = true
+			data = data.Get("0")
+		}
+		if t := data.Get("type").String(); t != "" {
+			env.Resource = t
+			break
+		}
+	}
+	if env.Resource == "" {
+		// 没有主数据时（如 DELETE 或空集合）以路径中的首个资源段代替
+		if u, err := url.Parse(ex.URL); err == nil {
+			parts := strings.Split(strings.Trim(u.Path, "/"), "/")
+			env.Resource = parts[0]
+			collection = len(parts)%2 == 1
+		}
+	}
+	switch strings.ToUpper(ex.Method) {
+	case "GET", "":
+		env.Operation = "get"
+		if collection {
+			env.Operation = "list"
+		}
+	case "POST":
+		env.Operation = "create"
+	case "PATCH", "PUT":
+		env.Operation = "update"
+	case "DELETE":
+		env.Operation = "delete"
+	default:
+		env.Operation = strings.ToLower(ex.Method)
+	}
+	return env, true
+}
+
+// isSOAPType 判断是否为 SOAP 可能使用的媒体类型
+func isSOAPType(mt string) bool {
+	return mt == "application/soap+xml" || mt == "text/xml" || mt == "application/xml"
+}
+
+// soapEnvelope 从 SOAPAction（或 SOAP 1.2 的 action 参数）或 Body 的首个子元素解析操作，服务取路径末段
+func soapEnvelope(ex Exchange) (Envelope, bool) {
+	op, ok := soapBodyElement(ex.RequestBody)
+	if !ok {
+		if op, ok = soapBodyElement(ex.ResponseBody); !ok {
+			return Envelope{}, false
+		}
+	}
+	action := strings.Trim(ex.SOAPAction, `" `)
+	if action == "" {
+		if _, params, err := mime.ParseMediaType(ex.RequestType); err == nil {
+			action = params["action"]
+		}
+	}
+	if action != "" {
+		if i := strings.LastIndexAny(action, "/#:"); i >= 0 && i < len(action)-1 {
+			action = action[i+1:]
+		}
+		op = action
+	}
+	env := Envelope{Format: FormatSOAP, Operation: op}
+	if u, err := url.Parse(ex.URL); err == nil {
+		env.Resource = u.Path[strings.LastIndex(u.Path, "/")+1:]
+	}
+	return env, true
+}
+
+// soapBodyElement 返回 SOAP Envelope 中 Body 的首个子元素本地名称
+func soapBodyElement(body []byte) (string, bool) {
+	if len(body) == 0 {
+		return "", false
+	}
+	dec := xml.NewDecoder(bytes.NewReader(body))
+	depth, inBody := 0, false
+	for {
+		tok, err := dec.Token()
+		if err != nil {
+			return "", false
+		}
+		switch t := tok.(type) {
+		case xml.StartElement:
+			depth++
+			switch {
+			case depth == 1 && t.Name.Local != "Envelope":
+				return "", false
+			case depth == 2 && t.Name.Local == "Body":
+				inBody = true
+			case depth == 3 && inBody:
+				return t.Name.Local, true
+			}
+		case xml.EndElement:
+			if depth == 2 {
+				inBody = false
+			}
+			depth--
+		}
+	}
+}
@@ -0,0 +1,155 @@
+// Package analyzer 实现对拦截流量的被动分析（结构漂移、内容变化、重复请求等）
+package analyzer
+
+import (
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/tidwall/gjson"
+)
+
+// SchemaDrift 结构漂移结果
+type SchemaDrift struct {
+	Endpoint string   // 端点标识（METHOD scheme://host/path）
+	Added    []string // 新增字段
+	Removed  []string // 移除字段
+	Changed  []string // 类型变化字段，格式 path:old->new
+}
+
+// SchemaTracker 按端点跟踪 JSON 响应结构
+type SchemaTracker struct {
+	mu     sync.Mutex
+	shapes map[string]map[string]string // 端点 -> 结构指纹（字段路径 -> 值类型）
+}
+
+// NewSchemaTracker 创建结构跟踪器
+func NewSchemaTracker() *SchemaTracker {
+	return &SchemaTracker{shapes: make(map[string]map[string]string)}
+}
+
+// EndpointKey 生成端点标识，忽略查询参数和片段
+func EndpointKey(method, rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return strings.ToUpper(method) + " " + rawURL
+	}
+	return strings.ToUpper(method) + " " + u.Scheme + "://" + u.Host + u.Path
+}
+
+// Observe 记录端点最新结构，若与上次结构不同则返回漂移信息
+func (t *SchemaTracker) Observe(endpoint, body string) (*SchemaDrift, bool) {
+	shape, ok := Fingerprint(body)
+	if !ok {
+		return nil, false
+	}
+
+	t.mu.Lock()
+	prev, seen := t.shapes[endpoint]
+	t.shapes[endpoint] = shape
+	t.mu.Unlock()
+
+	if !seen {
+		return nil, false
+	}
+	drift := diffShape(endpoint, prev, shape)
+	if len(drift.Added) == 0 && len(drift.Removed) == 0 && len(drift.Changed) == 0 {
+		return nil, false
+	}
+	return drift, true
+}
+
+// Snapshot 导出全部端点结构，用于跨会话持久化
+func (t *SchemaTracker) Snapshot() map[string]map[string]string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	out := make(map[string]map[string]string, len(t.shapes))
+	for k, v := range t.shapes {
+		out[k] = v
+	}
+	return out
+}
+
+// Restore 导入之前保存的端点结构
+func (t *SchemaTracker) Restore(shapes map[string]map[string]string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for k, v := range shapes {
+		t.shapes[k] = v
+	}
+}
+
+// Fingerprint 计算 JSON 文本的结构指纹，数组元素统一折叠为 []
+func Fingerprint(body string) (map[string]string, bool) {
+	body = strings.TrimSpace(body)
+	if body == "" || !gjson.Valid(body) {
+		return nil, false
+	}
+	root := gjson.Parse(body)
+	if !root.IsObject() && !root.IsArray() {
+		return nil, false
+	}
+	shape := make(map[string]string)
+	walkShape(shape, "$", root)
+	return shape, true
+}
+
+// walkShape 递归展开 JSON 节点
+func walkShape(shape map[string]string, path string, v gjson.Result) {
+	switch {
+	case v.IsObject():
+		shape[path] = "object"
+		v.ForEach(func(key, val gjson.Result) bool {
+			walkShape(shape, path+"."+key.String(), val)
+			return true
+		})
+	case v.IsArray():
+		shape[path] = "array"
+		v.ForEach(func(_, val gjson.Result) bool {
+			walkShape(shape, path+"[]", val)
+			return true
+		})
+	default:
+		shape[path] = jsonTypeName(v)
+	}
+}
+
+// jsonTypeName 返回标量值的类型名
+func jsonTypeName(v gjson.Result) string {
+	switch v.Type {
+	case gjson.Null:
+		return "null"
+	case gjson.False, gjson.True:
+		return "boolean"
+	case gjson.Number:
+		return "number"
+	default:
+		return "string"
+	}
+}
+
+// diffShape 比较两个结构指纹
+func diffShape(endpoint string, prev, cur map[string]string) *SchemaDrift {
+	d := &SchemaDrift{Endpoint: endpoint}
+	for p, typ := range cur {
+		old, ok := prev[p]
+		if !ok {
+			d.Added = append(d.Added, p)
+			continue
+		}
+		// null 与其他类型的切换通常是可选字段，不视为漂移
+		if old != typ && old != "null" && typ != "null" {
+			d.Changed = append(d.Changed, p+":"+old+"->"+typ)
+		}
+	}
+	for p := range prev {
+		if _, ok := cur[p]; !ok {
+			d.Removed = append(d.Removed, p)
+		}
+	}
+	sort.Strings(d.Added)
+	sort.Strings(d.Removed)
+	sort.Strings(d.Changed)
+	return d
+}
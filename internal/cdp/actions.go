@@ -4,14 +4,13 @@ import (
 	"context"
 	"encoding/base64"
 	"encoding/json"
-	"net/url"
 	"strings"
 	"time"
 
 	"github.com/mafredri/cdp/protocol/fetch"
 	"github.com/mafredri/cdp/protocol/network"
-	"github.com/tidwall/sjson"
 
+	"cdpnetool/internal/mutation"
 	"cdpnetool/pkg/rulespec"
 )
 
@@ -25,202 +24,45 @@ func NewActionExecutor(m *Manager) *ActionExecutor {
 	return &ActionExecutor{m: m}
 }
 
-// RequestMutation 请求修改结果
-type RequestMutation struct {
-	URL           *string
-	Method        *string
-	Headers       map[string]string
-	RemoveHeaders []string
-	Query         map[string]string
-	RemoveQuery   []string
-	Cookies       map[string]string
-	RemoveCookies []string
-	Body          *string
-	Block         *BlockResponse // 终结性行为
-}
+// 行为执行结果的类型由 mutation 包定义，浏览器拦截与内置代理共用
+type (
+	RequestMutation  = mutation.RequestMutation
+	ResponseMutation = mutation.ResponseMutation
+	BlockResponse    = mutation.BlockResponse
+	TerminateSpec    = mutation.TerminateSpec
+)
 
-// BlockResponse 拦截响应
-type BlockResponse struct {
-	StatusCode int
-	Headers    map[string]string
-	Body       []byte
+// ExecuteRequestActions 执行请求阶段的行为，返回修改结果；requestBody 为前序规则改写后的请求体，
+// vars 为行为模板可引用的变量。ctx 超时后不再执行后续行为，由调用方负责降级
+func (e *ActionExecutor) ExecuteRequestActions(ctx context.Context, actions []rulespec.Action, ev *fetch.RequestPausedReply, requestBody BodyContent, vars map[string]string) *RequestMutation {
+	return mutation.ExecuteRequest(ctx, actions, requestOf(ev), requestBody, vars)
 }
 
-// ResponseMutation 响应修改结果
-type ResponseMutation struct {
-	StatusCode    *int
-	Headers       map[string]string
-	RemoveHeaders []string
-	Body          *string
+// ExecuteResponseActions 执行响应阶段的行为，返回修改结果；responseBody 为前序规则改写后的响应体，
+// vars 为行为模板可引用的变量。ctx 超时后不再执行后续行为，由调用方负责降级
+func (e *ActionExecutor) ExecuteResponseActions(ctx context.Context, actions []rulespec.Action, ev *fetch.RequestPausedReply, responseBody BodyContent, vars map[string]string) *ResponseMutation {
+	return mutation.ExecuteResponse(ctx, actions, requestOf(ev), responseOf(ev), responseBody, vars)
 }
 
-// ExecuteRequestActions 执行请求阶段的行为，返回修改结果
-func (e *ActionExecutor) ExecuteRequestActions(actions []rulespec.Action, ev *fetch.RequestPausedReply) *RequestMutation {
-	mut := &RequestMutation{
-		Headers:       make(map[string]string),
-		Query:         make(map[string]string),
-		Cookies:       make(map[string]string),
-		RemoveHeaders: []string{},
-		RemoveQuery:   []string{},
-		RemoveCookies: []string{},
-	}
-
-	// 获取当前请求体用于修改
-	currentBody := e.getRequestBody(ev)
-
-	for _, action := range actions {
-		switch action.Type {
-		case rulespec.ActionSetUrl:
-			if v, ok := action.Value.(string); ok {
-				mut.URL = &v
-			}
-
-		case rulespec.ActionSetMethod:
-			if v, ok := action.Value.(string); ok {
-				mut.Method = &v
-			}
-
-		case rulespec.ActionSetHeader:
-			if v, ok := action.Value.(string); ok {
-				mut.Headers[action.Name] = v
-			}
-
-		case rulespec.ActionRemoveHeader:
-			mut.RemoveHeaders = append(mut.RemoveHeaders, action.Name)
-
-		case rulespec.ActionSetQueryParam:
-			if v, ok := action.Value.(string); ok {
-				mut.Query[action.Name] = v
-			}
-
-		case rulespec.ActionRemoveQueryParam:
-			mut.RemoveQuery = append(mut.RemoveQuery, action.Name)
-
-		case rulespec.ActionSetCookie:
-			if v, ok := action.Value.(string); ok {
-				mut.Cookies[action.Name] = v
-			}
-
-		case rulespec.ActionRemoveCookie:
-			mut.RemoveCookies = append(mut.RemoveCookies, action.Name)
-
-		case rulespec.ActionSetBody:
-			if v, ok := action.Value.(string); ok {
-				body := v
-				if action.GetEncoding() == rulespec.BodyEncodingBase64 {
-					if decoded, err := base64.StdEncoding.DecodeString(v); err == nil {
-						body = string(decoded)
-					}
-				}
-				currentBody = body
-				mut.Body = &currentBody
-			}
-
-		case rulespec.ActionReplaceBodyText:
-			if action.ReplaceAll {
-				currentBody = strings.ReplaceAll(currentBody, action.Search, action.Replace)
-			} else {
-				currentBody = strings.Replace(currentBody, action.Search, action.Replace, 1)
-			}
-			mut.Body = &currentBody
-
-		case rulespec.ActionPatchBodyJson:
-			if newBody, ok := applyJSONPatches(currentBody, action.Patches); ok {
-				currentBody = newBody
-				mut.Body = &currentBody
-			}
-
-		case rulespec.ActionSetFormField:
-			if v, ok := action.Value.(string); ok {
-				currentBody = setFormField(currentBody, action.Name, v, ev)
-				mut.Body = &currentBody
-			}
-
-		case rulespec.ActionRemoveFormField:
-			currentBody = removeFormField(currentBody, action.Name, ev)
-			mut.Body = &currentBody
-
-		case rulespec.ActionBlock:
-			// 终结性行为
-			mut.Block = &BlockResponse{
-				StatusCode: action.StatusCode,
-				Headers:    action.Headers,
-			}
-			if action.Body != "" {
-				body := action.Body
-				if action.GetBodyEncoding() == rulespec.BodyEncodingBase64 {
-					if decoded, err := base64.StdEncoding.DecodeString(action.Body); err == nil {
-						mut.Block.Body = decoded
-					} else {
-						mut.Block.Body = []byte(body)
-					}
-				} else {
-					mut.Block.Body = []byte(body)
-				}
-			}
-			return mut // 终结性行为，立即返回
-		}
-	}
-
-	return mut
+// requestOf 提取行为执行所需的原始请求信息
+func requestOf(ev *fetch.RequestPausedReply) *mutation.Request {
+	return &mutation.Request{Method: ev.Request.Method, URL: ev.Request.URL, Headers: requestHeaders(ev)}
 }
 
-// ExecuteResponseActions 执行响应阶段的行为，返回修改结果
-func (e *ActionExecutor) ExecuteResponseActions(actions []rulespec.Action, ev *fetch.RequestPausedReply, responseBody string) *ResponseMutation {
-	mut := &ResponseMutation{
-		Headers:       make(map[string]string),
-		RemoveHeaders: []string{},
-	}
-
-	currentBody := responseBody
-
-	for _, action := range actions {
-		switch action.Type {
-		case rulespec.ActionSetStatus:
-			if v, ok := action.Value.(float64); ok {
-				code := int(v)
-				mut.StatusCode = &code
-			} else if v, ok := action.Value.(int); ok {
-				mut.StatusCode = &v
-			}
-
-		case rulespec.ActionSetHeader:
-			if v, ok := action.Value.(string); ok {
-				mut.Headers[action.Name] = v
-			}
-
-		case rulespec.ActionRemoveHeader:
-			mut.RemoveHeaders = append(mut.RemoveHeaders, action.Name)
-
-		case rulespec.ActionSetBody:
-			if v, ok := action.Value.(string); ok {
-				body := v
-				if action.GetEncoding() == rulespec.BodyEncodingBase64 {
-					if decoded, err := base64.StdEncoding.DecodeString(v); err == nil {
-						body = string(decoded)
-					}
-				}
-				currentBody = body
-				mut.Body = &currentBody
-			}
-
-		case rulespec.ActionReplaceBodyText:
-			if action.ReplaceAll {
-				currentBody = strings.ReplaceAll(currentBody, action.Search, action.Replace)
-			} else {
-				currentBody = strings.Replace(currentBody, action.Search, action.Replace, 1)
-			}
-			mut.Body = &currentBody
-
-		case rulespec.ActionPatchBodyJson:
-			if newBody, ok := applyJSONPatches(currentBody, action.Patches); ok {
-				currentBody = newBody
-				mut.Body = &currentBody
-			}
-		}
+// responseOf 提取行为执行所需的原始响应信息，请求阶段状态码为 0
+func responseOf(ev *fetch.RequestPausedReply) *mutation.Response {
+	resp := &mutation.Response{StatusCode: getStatusCode(ev), Headers: make([]mutation.Header, len(ev.ResponseHeaders))}
+	for i, h := range ev.ResponseHeaders {
+		resp.Headers[i] = mutation.Header{Name: h.Name, Value: h.Value}
 	}
+	return resp
+}
 
-	return mut
+// requestHeaders 解析原始请求头
+func requestHeaders(ev *fetch.RequestPausedReply) map[string]string {
+	headers := make(map[string]string)
+	_ = json.Unmarshal(ev.Request.Headers, &headers)
+	return headers
 }
 
 // ApplyRequestMutation 应用请求修改到 CDP
@@ -228,6 +70,7 @@ func (e *ActionExecutor) ApplyRequestMutation(ctx context.Context, ts *targetSes
 	if ts == nil || ts.client == nil {
 		return
 	}
+	ts.settle(ev.RequestID)
 
 	// 处理终结性行为 block
 	if mut.Block != nil {
@@ -239,7 +82,7 @@ func (e *ActionExecutor) ApplyRequestMutation(ctx context.Context, ts *targetSes
 			args.ResponseHeaders = toHeaderEntries(mut.Block.Headers)
 		}
 		if len(mut.Block.Body) > 0 {
-			args.Body = mut.Block.Body
+			args.ResponseCode, args.ResponseHeaders, args.Body = applyRangePolicy(ev, args.ResponseCode, args.ResponseHeaders, mut.Block.Body)
 		}
 		_ = ts.client.Fetch.FulfillRequest(ctx, args)
 		return
@@ -249,7 +92,7 @@ func (e *ActionExecutor) ApplyRequestMutation(ctx context.Context, ts *targetSes
 	args := &fetch.ContinueRequestArgs{RequestID: ev.RequestID}
 
 	// URL 修改（包含 Query 修改）
-	finalURL := e.buildFinalURL(ev.Request.URL, mut)
+	finalURL := mutation.BuildURL(ev.Request.URL, mut)
 	if finalURL != nil {
 		args.URL = finalURL
 	}
@@ -267,7 +110,7 @@ func (e *ActionExecutor) ApplyRequestMutation(ctx context.Context, ts *targetSes
 
 	// Body 修改
 	if mut.Body != nil {
-		args.PostData = []byte(*mut.Body)
+		args.PostData = mut.Body.Data
 	}
 
 	_ = ts.client.Fetch.ContinueRequest(ctx, args)
@@ -278,6 +121,13 @@ func (e *ActionExecutor) ApplyResponseMutation(ctx context.Context, ts *targetSe
 	if ts == nil || ts.client == nil {
 		return
 	}
+	ts.settle(ev.RequestID)
+
+	// 响应体已被取走时只能通过 FulfillRequest 返回
+	if v, ok := ts.takenBodies.LoadAndDelete(ev.RequestID); ok && mut.Body == nil {
+		body := v.(BodyContent)
+		mut.Body = &body
+	}
 
 	// 如果需要修改 Body，必须使用 FulfillRequest
 	if mut.Body != nil {
@@ -289,13 +139,18 @@ func (e *ActionExecutor) ApplyResponseMutation(ctx context.Context, ts *targetSe
 			code = *mut.StatusCode
 		}
 
-		headers := e.buildFinalResponseHeaders(ev, mut)
+		headers := normalizeDecodedBody(e.buildFinalResponseHeaders(ev, mut), mut.Body.Data, setsContentEncoding(mut.Headers))
+		body := mut.Body.Data
+		// 只有规则整体替换的 Body 是完整内容，需要按 Range 切片；基于上游响应（可能已是 206 片段）的 Body 原样下发
+		if mut.BodyReplaced {
+			code, headers, body = applyRangePolicy(ev, code, headers, body)
+		}
 
 		args := &fetch.FulfillRequestArgs{
 			RequestID:       ev.RequestID,
 			ResponseCode:    code,
 			ResponseHeaders: headers,
-			Body:            []byte(*mut.Body),
+			Body:            body,
 		}
 		_ = ts.client.Fetch.FulfillRequest(ctx, args)
 		return
@@ -314,11 +169,85 @@ func (e *ActionExecutor) ApplyResponseMutation(ctx context.Context, ts *targetSe
 	_ = ts.client.Fetch.ContinueResponse(ctx, args)
 }
 
+// ApplyTerminate 按参数终止请求：等待指定时间后以截断 Body 结束响应或使请求失败。
+// 截断以完整的短响应返回（状态码与头部不变、长度与截断后的 Body 一致），不是连接中断；
+// 使请求失败被 CDP 拒绝时依次回退为 Failed 和放行，避免请求一直挂起。
+// 等待在独立协程中进行，不占用工作池
+func (e *ActionExecutor) ApplyTerminate(ts *targetSession, ev *fetch.RequestPausedReply, spec *TerminateSpec) {
+	if ts == nil || ts.client == nil || spec == nil {
+		return
+	}
+	run := func() {
+		ts.settle(ev.RequestID)
+		ctx, cancel := context.WithTimeout(ts.ctx, time.Second)
+		defer cancel()
+		if spec.Truncated {
+			// 截断的是解压后的内容，同样需要移除原编码
+			headers := make([]fetch.HeaderEntry, 0, len(ev.ResponseHeaders))
+			for _, h := range ev.ResponseHeaders {
+				if strings.EqualFold(h.Name, "Content-Length") || strings.EqualFold(h.Name, "Content-Encoding") {
+					continue
+				}
+				headers = append(headers, h)
+			}
+			code := getStatusCode(ev)
+			if code == 0 {
+				code = 200
+			}
+			err := ts.client.Fetch.FulfillRequest(ctx, &fetch.FulfillRequestArgs{
+				RequestID:       ev.RequestID,
+				ResponseCode:    code,
+				ResponseHeaders: headers,
+				Body:            spec.Body,
+			})
+			if err == nil {
+				return
+			}
+			e.m.log.Warn("返回截断响应失败，改为使请求失败", "url", ev.Request.URL, "error", err)
+		}
+		e.failTerminated(ctx, ts, ev, network.ErrorReason(spec.Reason))
+	}
+	if spec.After <= 0 {
+		run()
+		return
+	}
+	e.m.afterFunc(spec.After, run)
+}
+
+// failTerminated 以指定原因使被终止的请求失败；原因无效或被拒绝时回退为 Failed，仍失败则放行
+func (e *ActionExecutor) failTerminated(ctx context.Context, ts *targetSession, ev *fetch.RequestPausedReply, reason network.ErrorReason) {
+	if !reason.Valid() {
+		e.m.log.Warn("终止请求的错误原因无效，改用 Failed", "url", ev.Request.URL, "reason", string(reason))
+		reason = network.ErrorReasonFailed
+	}
+	err := ts.client.Fetch.FailRequest(ctx, &fetch.FailRequestArgs{RequestID: ev.RequestID, ErrorReason: reason})
+	if err == nil {
+		return
+	}
+	e.m.log.Warn("终止请求失败", "url", ev.Request.URL, "reason", string(reason), "error", err)
+	if reason != network.ErrorReasonFailed {
+		err = ts.client.Fetch.FailRequest(ctx, &fetch.FailRequestArgs{RequestID: ev.RequestID, ErrorReason: network.ErrorReasonFailed})
+		if err == nil {
+			return
+		}
+	}
+	e.m.log.Warn("无法终止请求，已放行", "url", ev.Request.URL, "error", err)
+	if ev.ResponseStatusCode != nil {
+		_ = ts.client.Fetch.ContinueResponse(ctx, &fetch.ContinueResponseArgs{RequestID: ev.RequestID})
+		return
+	}
+	_ = ts.client.Fetch.ContinueRequest(ctx, &fetch.ContinueRequestArgs{RequestID: ev.RequestID})
+}
+
 // ContinueRequest 继续原请求
 func (e *ActionExecutor) ContinueRequest(ctx context.Context, ts *targetSession, ev *fetch.RequestPausedReply) {
 	if ts == nil || ts.client == nil {
 		return
 	}
+	ts.settle(ev.RequestID)
+	if e.fulfillTaken(ctx, ts, ev) {
+		return
+	}
 	_ = ts.client.Fetch.ContinueRequest(ctx, &fetch.ContinueRequestArgs{RequestID: ev.RequestID})
 }
 
@@ -327,6 +256,10 @@ func (e *ActionExecutor) ContinueResponse(ctx context.Context, ts *targetSession
 	if ts == nil || ts.client == nil {
 		return
 	}
+	ts.settle(ev.RequestID)
+	if e.fulfillTaken(ctx, ts, ev) {
+		return
+	}
 	_ = ts.client.Fetch.ContinueResponse(ctx, &fetch.ContinueResponseArgs{RequestID: ev.RequestID})
 }
 
@@ -335,157 +268,42 @@ func (e *ActionExecutor) FailRequest(ctx context.Context, ts *targetSession, ev
 	if ts == nil || ts.client == nil {
 		return
 	}
+	ts.settle(ev.RequestID)
 	_ = ts.client.Fetch.FailRequest(ctx, &fetch.FailRequestArgs{
 		RequestID:   ev.RequestID,
 		ErrorReason: network.ErrorReason(reason),
 	})
 }
 
-// FetchResponseBody 获取响应体
-func (e *ActionExecutor) FetchResponseBody(ctx context.Context, ts *targetSession, requestID fetch.RequestID) (string, bool) {
+// FetchResponseBody 获取响应体，CDP 以 Base64 返回且内容非文本时标记为二进制
+func (e *ActionExecutor) FetchResponseBody(ctx context.Context, ts *targetSession, ev *fetch.RequestPausedReply) (BodyContent, bool) {
 	if ts == nil || ts.client == nil {
-		return "", false
+		return BodyContent{}, false
 	}
 	ctx2, cancel := context.WithTimeout(ctx, 500*time.Millisecond)
 	defer cancel()
-	rb, err := ts.client.Fetch.GetResponseBody(ctx2, &fetch.GetResponseBodyArgs{RequestID: requestID})
+	rb, err := ts.client.Fetch.GetResponseBody(ctx2, &fetch.GetResponseBodyArgs{RequestID: ev.RequestID})
 	if err != nil || rb == nil {
-		return "", false
+		return BodyContent{}, false
 	}
 	if rb.Base64Encoded {
-		if b, err := base64.StdEncoding.DecodeString(rb.Body); err == nil {
-			return string(b), true
+		b, err := base64.StdEncoding.DecodeString(rb.Body)
+		if err != nil {
+			return BodyContent{}, false
 		}
-		return "", false
-	}
-	return rb.Body, true
-}
-
-// getRequestBody 获取请求体
-func (e *ActionExecutor) getRequestBody(ev *fetch.RequestPausedReply) string {
-	return GetRequestBody(ev)
-}
-
-// buildFinalURL 构建最终 URL
-func (e *ActionExecutor) buildFinalURL(originalURL string, mut *RequestMutation) *string {
-	if mut.URL == nil && len(mut.Query) == 0 && len(mut.RemoveQuery) == 0 {
-		return nil
-	}
-
-	baseURL := originalURL
-	if mut.URL != nil {
-		baseURL = *mut.URL
-	}
-
-	// 如果没有 Query 修改，直接返回
-	if len(mut.Query) == 0 && len(mut.RemoveQuery) == 0 {
-		return &baseURL
-	}
-
-	// 解析并修改 Query
-	u, err := url.Parse(baseURL)
-	if err != nil {
-		return &baseURL
+		return mutation.NewBodyContent(b, responseContentType(ev)), true
 	}
-
-	q := u.Query()
-	// 移除参数
-	for _, name := range mut.RemoveQuery {
-		q.Del(name)
-	}
-	// 设置参数
-	for name, value := range mut.Query {
-		q.Set(name, value)
-	}
-	u.RawQuery = q.Encode()
-
-	result := u.String()
-	return &result
+	return BodyContent{Data: []byte(rb.Body)}, true
 }
 
 // buildFinalHeaders 构建最终请求头
 func (e *ActionExecutor) buildFinalHeaders(ev *fetch.RequestPausedReply, mut *RequestMutation) []fetch.HeaderEntry {
-	// 解析原始头部
-	originalHeaders := make(map[string]string)
-	_ = json.Unmarshal(ev.Request.Headers, &originalHeaders)
-
-	// 应用修改
-	// 1. 移除头部
-	for _, name := range mut.RemoveHeaders {
-		delete(originalHeaders, name)
-		// 不区分大小写删除
-		for k := range originalHeaders {
-			if strings.EqualFold(k, name) {
-				delete(originalHeaders, k)
-			}
-		}
-	}
-
-	// 2. 设置头部
-	for name, value := range mut.Headers {
-		originalHeaders[name] = value
-	}
-
-	// 3. 处理 Cookie 修改
-	if len(mut.Cookies) > 0 || len(mut.RemoveCookies) > 0 {
-		cookieStr := ""
-		for k, v := range originalHeaders {
-			if strings.EqualFold(k, "cookie") {
-				cookieStr = v
-				break
-			}
-		}
-		cookies := parseCookie(cookieStr)
-
-		// 移除 Cookie
-		for _, name := range mut.RemoveCookies {
-			delete(cookies, name)
-		}
-		// 设置 Cookie
-		for name, value := range mut.Cookies {
-			cookies[name] = value
-		}
-
-		// 重新构建 Cookie 字符串
-		if len(cookies) > 0 {
-			var parts []string
-			for k, v := range cookies {
-				parts = append(parts, k+"="+v)
-			}
-			originalHeaders["Cookie"] = strings.Join(parts, "; ")
-		} else {
-			delete(originalHeaders, "Cookie")
-			delete(originalHeaders, "cookie")
-		}
-	}
-
-	return toHeaderEntries(originalHeaders)
+	return toHeaderEntries(mutation.BuildRequestHeaders(requestHeaders(ev), mut))
 }
 
 // buildFinalResponseHeaders 构建最终响应头
 func (e *ActionExecutor) buildFinalResponseHeaders(ev *fetch.RequestPausedReply, mut *ResponseMutation) []fetch.HeaderEntry {
-	// 获取原始响应头
-	headers := make(map[string]string)
-	for _, h := range ev.ResponseHeaders {
-		headers[h.Name] = h.Value
-	}
-
-	// 移除头部
-	for _, name := range mut.RemoveHeaders {
-		delete(headers, name)
-		for k := range headers {
-			if strings.EqualFold(k, name) {
-				delete(headers, k)
-			}
-		}
-	}
-
-	// 设置头部
-	for name, value := range mut.Headers {
-		headers[name] = value
-	}
-
-	return toHeaderEntries(headers)
+	return toHeaderEntries(mutation.BuildResponseHeaders(responseOf(ev).Headers, mut))
 }
 
 // toHeaderEntries 将头部映射转换为 CDP 头部条目
@@ -497,89 +315,6 @@ func toHeaderEntries(h map[string]string) []fetch.HeaderEntry {
 	return out
 }
 
-// applyJSONPatches 应用 JSON Patch 操作，使用 sjson 实现高性能修改
-func applyJSONPatches(body string, patches []rulespec.JSONPatchOp) (string, bool) {
-	if body == "" || len(patches) == 0 {
-		return body, false
-	}
-
-	currentBody := body
-	modified := false
-
-	for _, patch := range patches {
-		if patch.Path == "" {
-			continue
-		}
-
-		// 将 JSON Patch 路径 (/a/b/c) 转换为 sjson 路径 (a.b.c)
-		path := patch.Path
-		path = strings.TrimPrefix(path, "/")
-		path = strings.ReplaceAll(path, "/", ".")
-
-		var err error
-		switch patch.Op {
-		case "add", "replace":
-			currentBody, err = sjson.Set(currentBody, path, patch.Value)
-			if err == nil {
-				modified = true
-			}
-		case "remove":
-			currentBody, err = sjson.Delete(currentBody, path)
-			if err == nil {
-				modified = true
-			}
-		}
-	}
-
-	return currentBody, modified
-}
-
-// setFormField 设置表单字段
-func setFormField(body, name, value string, ev *fetch.RequestPausedReply) string {
-	contentType := getContentType(ev)
-
-	if strings.Contains(contentType, "application/x-www-form-urlencoded") {
-		return setURLEncodedField(body, name, value)
-	}
-
-	if strings.Contains(contentType, "multipart/form-data") {
-		// TODO: 实现 multipart 表单修改
-		return body
-	}
-
-	return body
-}
-
-// removeFormField 移除表单字段
-func removeFormField(body, name string, ev *fetch.RequestPausedReply) string {
-	contentType := getContentType(ev)
-
-	if strings.Contains(contentType, "application/x-www-form-urlencoded") {
-		return removeURLEncodedField(body, name)
-	}
-
-	if strings.Contains(contentType, "multipart/form-data") {
-		// TODO: 实现 multipart 表单修改
-		return body
-	}
-
-	return body
-}
-
-// setURLEncodedField 设置 URL 编码表单字段
-func setURLEncodedField(body, name, value string) string {
-	values, _ := url.ParseQuery(body)
-	values.Set(name, value)
-	return values.Encode()
-}
-
-// removeURLEncodedField 移除 URL 编码表单字段
-func removeURLEncodedField(body, name string) string {
-	values, _ := url.ParseQuery(body)
-	values.Del(name)
-	return values.Encode()
-}
-
 // getContentType 获取 Content-Type
 func getContentType(ev *fetch.RequestPausedReply) string {
 	var headers map[string]string
@@ -0,0 +1,168 @@
+package cdp
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/mafredri/cdp/protocol/fetch"
+
+	"cdpnetool/internal/mutation"
+	"cdpnetool/internal/rules"
+	"cdpnetool/pkg/model"
+	"cdpnetool/pkg/rulespec"
+)
+
+// 认证质询应答方式
+const (
+	authResponseDefault     = "Default"
+	authResponseCredentials = "ProvideCredentials"
+)
+
+// authSourceProxy 质询来自代理服务器（407）
+const authSourceProxy = "Proxy"
+
+// consumeAuth 订阅认证质询事件，按请求阶段规则中的 provideCredentials 行为或上游代理认证信息自动应答
+func (m *Manager) consumeAuth(ts *targetSession) {
+	ar, err := ts.client.Fetch.AuthRequired(ts.ctx)
+	if err != nil {
+		m.log.Err(err, "订阅认证质询事件失败", "target", string(ts.id))
+		return
+	}
+	defer ar.Close()
+
+	for {
+		ev, err := ar.Recv()
+		if err != nil {
+			return
+		}
+		go m.handleAuth(ts, ev)
+	}
+}
+
+// handleAuth 处理一次认证质询；未配置凭据或凭据已被拒绝时交由浏览器默认处理
+func (m *Manager) handleAuth(ts *targetSession, ev *fetch.AuthRequiredReply) {
+	ctx, cancel := context.WithTimeout(ts.ctx, 2*time.Second)
+	defer cancel()
+
+	source := "Server"
+	if ev.AuthChallenge.Source != nil {
+		source = *ev.AuthChallenge.Source
+	}
+
+	// 复用请求阶段的匹配上下文
+	paused := &fetch.RequestPausedReply{
+		RequestID:    ev.RequestID,
+		Request:      ev.Request,
+		FrameID:      ev.FrameID,
+		ResourceType: ev.ResourceType,
+	}
+
+	action, matched := m.findCredentials(ts.id, paused, source)
+
+	// 规则未提供凭据时，代理质询使用会话配置的上游代理认证信息
+	var cred *model.ProxyCredential
+	if action != nil {
+		cred = &model.ProxyCredential{Username: action.Username, Password: action.Password}
+	} else if strings.EqualFold(source, authSourceProxy) {
+		cred = m.currentProxyAuth()
+	}
+
+	// 同一请求再次质询说明凭据被拒绝，不再重复应答以免死循环
+	if cred != nil && ts.markAuthTried(ev.RequestID, time.Now()) {
+		// 之后的质询由浏览器处理，不再需要该记录
+		ts.authTried.Delete(ev.RequestID)
+		m.log.Warn("自动认证凭据被拒绝，交由浏览器处理", "url", ev.Request.URL, "source", source, "realm", ev.AuthChallenge.Realm)
+		cred = nil
+	}
+
+	resp := fetch.AuthChallengeResponse{Response: authResponseDefault}
+	if cred != nil {
+		user, pass := cred.Username, cred.Password
+		resp = fetch.AuthChallengeResponse{Response: authResponseCredentials, Username: &user, Password: &pass}
+	}
+	if err := ts.client.Fetch.ContinueWithAuth(ctx, fetch.NewContinueWithAuthArgs(ev.RequestID, resp)); err != nil {
+		m.log.Err(err, "应答认证质询失败", "url", ev.Request.URL)
+		return
+	}
+
+	switch {
+	case cred == nil:
+		return
+	case action == nil:
+		m.log.Info("已使用上游代理认证信息应答质询", "url", ev.Request.URL, "scheme", ev.AuthChallenge.Scheme, "realm", ev.AuthChallenge.Realm)
+		return
+	}
+	requestInfo, responseInfo, _, _ := m.captureOriginalData(ctx, ts, paused, rulespec.StageRequest, matched)
+	m.sendMatchedEvent(ts.id, paused, "modified", buildRuleMatches(matched), requestInfo, responseInfo, nil)
+	m.log.Info("已自动应答认证质询", "url", ev.Request.URL, "source", source, "scheme", ev.AuthChallenge.Scheme, "realm", ev.AuthChallenge.Realm)
+}
+
+// authTriedTTL 自动应答记录的保留时间。应答后没有进入响应阶段的请求（未拦截响应、请求失败）
+// 无法得知何时结束，超过该时间视为已结束
+const authTriedTTL = time.Minute
+
+// markAuthTried 记录请求已自动应答，返回此前是否已应答过；同时清理超过 authTriedTTL 的记录
+func (ts *targetSession) markAuthTried(id fetch.RequestID, now time.Time) bool {
+	ts.authTried.Range(func(k, v any) bool {
+		if now.Sub(v.(time.Time)) > authTriedTTL {
+			ts.authTried.Delete(k)
+		}
+		return true
+	})
+	_, tried := ts.authTried.LoadOrStore(id, now)
+	return tried
+}
+
+// SetProxyAuth 设置上游代理认证信息，cred 为 nil 或用户名为空时代理质询交由浏览器处理
+func (m *Manager) SetProxyAuth(cred *model.ProxyCredential) {
+	if cred != nil && cred.Username == "" {
+		cred = nil
+	}
+	if cred != nil {
+		c := *cred
+		cred = &c
+	}
+	m.stateMu.Lock()
+	m.proxyAuth = cred
+	m.stateMu.Unlock()
+	m.refreshFetchPatterns()
+}
+
+// proxyAuthPatterns 配置了上游代理认证时拦截全部请求的请求阶段：
+// 浏览器只为被 Fetch 暂停的请求上报认证质询，规则推导的模式覆盖不到的请求会卡在代理的 407 上
+func (m *Manager) proxyAuthPatterns() []fetch.RequestPattern {
+	if m.currentProxyAuth() == nil {
+		return nil
+	}
+	return []fetch.RequestPattern{{URLPattern: strPtr("*"), RequestStage: fetch.RequestStageRequest}}
+}
+
+// currentProxyAuth 返回当前的上游代理认证信息
+func (m *Manager) currentProxyAuth() *model.ProxyCredential {
+	m.stateMu.RLock()
+	defer m.stateMu.RUnlock()
+	return m.proxyAuth
+}
+
+// findCredentials 查找与质询来源匹配的第一个 provideCredentials 行为
+func (m *Manager) findCredentials(target model.TargetID, ev *fetch.RequestPausedReply, source string) (*rulespec.Action, []*rules.MatchedRule) {
+	if m.engine == nil || m.captureOnly || !m.isHostPermitted(ev.Request.URL) {
+		return nil, nil
+	}
+	matched := m.engine.Reevaluate(m.buildEvalContext(target, ev), rulespec.StageRequest)
+	for _, mr := range matched {
+		for i := range mr.Rule.Actions {
+			a := &mr.Rule.Actions[i]
+			if a.Type != rulespec.ActionProvideCredentials {
+				continue
+			}
+			if a.AuthSource != "" && !strings.EqualFold(a.AuthSource, source) {
+				continue
+			}
+			rendered := mutation.RenderAction(*a, m.templateVars(mr.Params))
+			return &rendered, []*rules.MatchedRule{mr}
+		}
+	}
+	return nil, nil
+}
@@ -0,0 +1,30 @@
+package cdp
+
+import (
+	"fmt"
+	"strconv"
+
+	"cdpnetool/pkg/model"
+	"cdpnetool/pkg/rulespec"
+)
+
+// SetMaxFulfillBody 设置规则生成的 Body（mock 应答、改写后的请求体或响应体）的大小上限，不大于 0 表示不限制
+func (m *Manager) SetMaxFulfillBody(limit int64) {
+	m.maxFulfillBody = limit
+}
+
+// bodyOverLimit 判断规则生成的 Body 是否超过上限，超过时发送通知事件，调用方应放弃修改并原样放行
+func (m *Manager) bodyOverLimit(target model.TargetID, url string, stage rulespec.Stage, size int) bool {
+	if m.maxFulfillBody <= 0 || int64(size) <= m.maxFulfillBody {
+		return false
+	}
+	m.log.Warn("规则生成的 Body 超过上限，已放弃修改", "url", url, "stage", stage, "size", size, "limit", m.maxFulfillBody)
+	m.sendNotice(target, model.NoticeBodyTooLarge, url,
+		fmt.Sprintf("规则生成的 Body 大小 %d 字节超过上限 %d 字节，已放弃修改并原样放行", size, m.maxFulfillBody),
+		map[string]string{
+			"stage": string(stage),
+			"size":  strconv.Itoa(size),
+			"limit": strconv.FormatInt(m.maxFulfillBody, 10),
+		})
+	return true
+}
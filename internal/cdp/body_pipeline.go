@@ -0,0 +1,15 @@
+package cdp
+
+import (
+	"cdpnetool/pkg/model"
+	"cdpnetool/pkg/rulespec"
+)
+
+// bodySteps 为行为产生的 Body 变换记录补充所属规则
+func bodySteps(steps []model.BodyTransform, rule *rulespec.Rule) []model.BodyTransform {
+	for i := range steps {
+		steps[i].RuleID = rule.ID
+		steps[i].RuleName = rule.Name
+	}
+	return steps
+}
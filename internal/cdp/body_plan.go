@@ -0,0 +1,159 @@
+package cdp
+
+import (
+	"mime"
+	"strings"
+
+	"cdpnetool/internal/rules"
+	"cdpnetool/pkg/rulespec"
+)
+
+// 响应体读取计划：加载规则时按行为编译出每条响应阶段规则值得读取的响应体类型，
+// 命中后只在响应的 Content-Type 与大小符合计划时读取，避免只改头部的规则为二进制或超大响应读取 Body。
+// 规则的 URL 条件已由引擎匹配，计划按规则 ID 查找即对应该规则的 URL 模式。
+
+var (
+	textBodyTypes  = []string{"text/", "application/json", "+json", "application/xml", "+xml", "application/javascript", "application/x-www-form-urlencoded"}
+	jsonBodyTypes  = []string{"application/json", "+json"}
+	eventBodyTypes = []string{"text/", "application/json"} // 未命中需要 Body 的规则时，仅为事件记录读取的类型
+)
+
+// 按行为编译的 Content-Length 上限：文本类改写需要把整个 Body 载入内存处理，超过上限的响应不读取
+const (
+	maxJSONBodySize = 16 * 1024 * 1024
+	maxTextBodySize = 32 * 1024 * 1024
+)
+
+// bodyNeed 读取响应体的条件
+type bodyNeed struct {
+	maxSize int64    // Content-Length 上限，0 表示不在此处限制（超过 Body 大小阈值时由流式读取上限决定）
+	anyType bool     // 任意 Content-Type 都需要读取
+	untyped bool     // Content-Type 缺失或无法解析时也读取，由 maxSize 限制大小，内容是否为文本读取后再判断
+	types   []string // 值得读取的 Content-Type：以 / 结尾为前缀，以 + 开头为结构化后缀，其余为完整类型
+}
+
+// accepts 判断 Content-Type 是否在读取条件内
+func (n bodyNeed) accepts(ctype string) bool {
+	if n.anyType {
+		return true
+	}
+	mt, _, err := mime.ParseMediaType(ctype)
+	if err != nil {
+		mt = strings.TrimSpace(strings.SplitN(ctype, ";", 2)[0])
+	}
+	mt = strings.ToLower(mt)
+	if mt == "" || !strings.Contains(mt, "/") {
+		return n.untyped
+	}
+	for _, t := range n.types {
+		switch {
+		case strings.HasSuffix(t, "/") && strings.HasPrefix(mt, t),
+			strings.HasPrefix(t, "+") && strings.HasSuffix(mt, t),
+			mt == t:
+			return true
+		}
+	}
+	return false
+}
+
+// merge 合并两个读取条件，取更宽松的一方
+func (n bodyNeed) merge(o bodyNeed) bodyNeed {
+	out := bodyNeed{anyType: n.anyType || o.anyType, untyped: n.untyped || o.untyped}
+	if n.maxSize > 0 && o.maxSize > 0 {
+		out.maxSize = max(n.maxSize, o.maxSize)
+	}
+	if !out.anyType {
+		out.types = append(append([]string{}, n.types...), o.types...)
+	}
+	return out
+}
+
+// bodyFetchPlan 规则 ID -> 命中时读取响应体的条件，不在计划中的规则不需要读取响应体
+type bodyFetchPlan map[string]bodyNeed
+
+// compileBodyFetchPlan 按响应阶段规则的行为编译响应体读取计划：
+// JSON Patch 只需要 JSON，文本替换、表单字段和从 Body 提取变量需要文本，
+// 压缩、按字节截断和断点需要任意类型的原始 Body；setBody 整体替换 Body，不需要读取。
+// 文本类行为在 Content-Type 缺失时同样读取，避免规则作用于空内容而抹掉真实响应体
+func compileBodyFetchPlan(cfg *rulespec.Config) bodyFetchPlan {
+	plan := make(bodyFetchPlan)
+	if cfg == nil {
+		return plan
+	}
+	for _, rule := range cfg.EvaluationOrder(rulespec.StageResponse) {
+		var need bodyNeed
+		needed := false
+		add := func(n bodyNeed) {
+			if needed {
+				need = need.merge(n)
+			} else {
+				need, needed = n, true
+			}
+		}
+		for i := range rule.Actions {
+			a := &rule.Actions[i]
+			switch {
+			case a.Type == rulespec.ActionPatchBodyJson:
+				add(bodyNeed{maxSize: maxJSONBodySize, untyped: true, types: jsonBodyTypes})
+			case a.Type == rulespec.ActionReplaceBodyText, a.Type == rulespec.ActionSetFormField, a.Type == rulespec.ActionRemoveFormField:
+				add(bodyNeed{maxSize: maxTextBodySize, untyped: true, types: textBodyTypes})
+			case a.Type == rulespec.ActionCaptureVar && (a.From == "" || a.From == rulespec.CaptureFromBody):
+				add(bodyNeed{maxSize: maxTextBodySize, untyped: true, types: textBodyTypes})
+			case a.Type == rulespec.ActionCompressBody, a.Type == rulespec.ActionPause,
+				a.Type == rulespec.ActionTerminate && a.AfterBytes > 0:
+				add(bodyNeed{anyType: true})
+			}
+		}
+		if needed {
+			plan[rule.ID] = need
+		}
+	}
+	return plan
+}
+
+// need 返回命中规则读取响应体的合并条件，没有规则需要读取时返回 false
+func (p bodyFetchPlan) need(matched []*rules.MatchedRule) (bodyNeed, bool) {
+	var need bodyNeed
+	found := false
+	for _, mr := range matched {
+		n, ok := p[mr.Rule.ID]
+		if !ok {
+			continue
+		}
+		if found {
+			need = need.merge(n)
+		} else {
+			need, found = n, true
+		}
+	}
+	return need, found
+}
+
+// refreshBodyPlan 规则变化后重新编译响应体读取计划
+func (m *Manager) refreshBodyPlan() {
+	plan := compileBodyFetchPlan(m.currentConfig())
+	m.stateMu.Lock()
+	m.bodyPlan = plan
+	m.stateMu.Unlock()
+}
+
+// responseBodyNeed 返回命中规则读取响应体的条件与是否有规则需要读取；
+// 没有规则需要时只为事件记录读取 Body 大小阈值以内的文本和 JSON 响应
+func (m *Manager) responseBodyNeed(matched []*rules.MatchedRule) (bodyNeed, bool) {
+	m.stateMu.RLock()
+	plan := m.bodyPlan
+	m.stateMu.RUnlock()
+	if need, ok := plan.need(matched); ok {
+		return need, true
+	}
+	return m.defaultBodyNeed(), false
+}
+
+// defaultBodyNeed 未命中需要 Body 的规则时读取响应体的条件
+func (m *Manager) defaultBodyNeed() bodyNeed {
+	limit := m.bodySizeThreshold
+	if limit <= 0 {
+		limit = 4 * 1024 * 1024
+	}
+	return bodyNeed{maxSize: limit, types: eventBodyTypes}
+}
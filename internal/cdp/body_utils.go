@@ -3,9 +3,10 @@ package cdp
 import (
 	"encoding/base64"
 	"strings"
-	"unicode/utf8"
 
 	"github.com/mafredri/cdp/protocol/fetch"
+
+	"cdpnetool/internal/mutation"
 )
 
 // GetRequestBody 统一提取并解码请求体
@@ -14,12 +15,7 @@ func GetRequestBody(ev *fetch.RequestPausedReply) string {
 		return ""
 	}
 
-	// 1. 如果有 postData 直接返回（CDP 已处理为普通字符串）
-	if ev.Request.PostData != nil {
-		return *ev.Request.PostData
-	}
-
-	// 2. 如果有 postDataEntries，需要按条目解码并拼接
+	// 1. 优先使用 postDataEntries 按条目解码拼接，二进制内容不会被 UTF-8 转换破坏
 	if len(ev.Request.PostDataEntries) > 0 {
 		var sb strings.Builder
 		for _, entry := range ev.Request.PostDataEntries {
@@ -37,21 +33,28 @@ func GetRequestBody(ev *fetch.RequestPausedReply) string {
 		return sb.String()
 	}
 
+	// 2. 退回到 postData（CDP 已处理为普通字符串）
+	if ev.Request.PostData != nil {
+		return *ev.Request.PostData
+	}
+
 	return ""
 }
 
-// IsTextualBody 判断 Body 是否为文本类型，以便安全展示或匹配
-func IsTextualBody(data []byte, contentType string) bool {
-	lc := strings.ToLower(contentType)
-	// 常见的文本类型
-	if strings.HasPrefix(lc, "text/") ||
-		strings.Contains(lc, "json") ||
-		strings.Contains(lc, "xml") ||
-		strings.Contains(lc, "javascript") ||
-		strings.Contains(lc, "x-www-form-urlencoded") {
-		return true
-	}
+// BodyContent 二进制安全的 Body 内容
+type BodyContent = mutation.BodyContent
+
+// requestBodyContent 提取请求体
+func requestBodyContent(ev *fetch.RequestPausedReply) BodyContent {
+	return mutation.NewBodyContent([]byte(GetRequestBody(ev)), getContentType(ev))
+}
 
-	// 启发式检测：如果是有效的 UTF-8 且不包含过多的控制字符
-	return utf8.Valid(data)
+// responseContentType 获取响应的 Content-Type
+func responseContentType(ev *fetch.RequestPausedReply) string {
+	for _, h := range ev.ResponseHeaders {
+		if strings.EqualFold(h.Name, "Content-Type") {
+			return h.Value
+		}
+	}
+	return ""
 }
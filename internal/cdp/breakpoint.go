@@ -0,0 +1,308 @@
+package cdp
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/mafredri/cdp/protocol/fetch"
+	"github.com/mafredri/cdp/protocol/network"
+
+	"cdpnetool/internal/mutation"
+	"cdpnetool/pkg/model"
+	"cdpnetool/pkg/rulespec"
+)
+
+// pendingQueueSize 断点通知通道的缓冲大小
+const pendingQueueSize = 64
+
+// breakpoint 一个等待人工处理的断点
+type breakpoint struct {
+	stage  rulespec.Stage
+	decide chan breakpointDecision
+}
+
+// breakpointDecision 断点的处理结果
+type breakpointDecision struct {
+	reject bool
+	drop   bool // 拦截已停用或目标已断开，请求已由 releasePaused 放行
+	edits  *model.PendingEdits
+}
+
+// SetPendingChannel 设置断点通知通道，每个暂停的请求推送一次；通道由调用方持有和关闭
+func (m *Manager) SetPendingChannel(ch chan model.PendingItem) {
+	if ch != nil {
+		m.pendingCh = ch
+	}
+}
+
+// ApprovePending 放行断点暂停的请求，edits 不为 nil 时在规则修改的基础上再应用人工修改
+func (m *Manager) ApprovePending(id string, edits *model.PendingEdits) error {
+	m.bpMu.Lock()
+	bp, ok := m.breakpoints[id]
+	if ok && edits != nil {
+		if bp.stage == rulespec.StageRequest && edits.StatusCode != 0 {
+			m.bpMu.Unlock()
+			return fmt.Errorf("statusCode only applies to response stage")
+		}
+		if bp.stage == rulespec.StageResponse && (edits.URL != "" || edits.Method != "") {
+			m.bpMu.Unlock()
+			return fmt.Errorf("url and method only apply to request stage")
+		}
+	}
+	delete(m.breakpoints, id)
+	m.bpMu.Unlock()
+	if !ok {
+		return fmt.Errorf("pending item not found: %s", id)
+	}
+	bp.decide <- breakpointDecision{edits: edits}
+	return nil
+}
+
+// RejectPending 拒绝断点暂停的请求，请求以 Aborted 失败
+func (m *Manager) RejectPending(id string) error {
+	bp, ok := m.takeBreakpoint(id)
+	if !ok {
+		return fmt.Errorf("pending item not found: %s", id)
+	}
+	bp.decide <- breakpointDecision{reject: true}
+	return nil
+}
+
+// takeBreakpoint 取出断点，只有第一个取出者可以决定其处理结果
+func (m *Manager) takeBreakpoint(id string) (*breakpoint, bool) {
+	m.bpMu.Lock()
+	defer m.bpMu.Unlock()
+	bp, ok := m.breakpoints[id]
+	delete(m.breakpoints, id)
+	return bp, ok
+}
+
+// dropBreakpoints 丢弃全部断点；断点对应的请求在停用拦截时由 releasePaused 放行
+func (m *Manager) dropBreakpoints() {
+	m.bpMu.Lock()
+	all := m.breakpoints
+	m.breakpoints = make(map[string]*breakpoint)
+	m.bpMu.Unlock()
+	for _, bp := range all {
+		bp.decide <- breakpointDecision{drop: true}
+	}
+}
+
+// awaitBreakpoint 登记断点并推送通知，在独立协程中等待人工处理或超时，不占用工作池；
+// 超时按未修改放行处理，resolve 以新的上下文执行
+func (m *Manager) awaitBreakpoint(ts *targetSession, item model.PendingItem, timeout time.Duration, resolve func(ctx context.Context, d breakpointDecision)) {
+	now := time.Now()
+	item.ID = fmt.Sprintf("bp-%d", m.bpSeq.Add(1))
+	item.Target = ts.id
+	item.Timestamp = now.UnixMilli()
+	item.ExpiresAt = now.Add(timeout).UnixMilli()
+	if r := m.currentRedactor(); r != nil {
+		item.Request = redactRequestInfo(r, item.Request)
+		item.Response = redactResponseInfo(r, item.Response)
+	}
+
+	bp := &breakpoint{stage: rulespec.Stage(item.Stage), decide: make(chan breakpointDecision, 1)}
+	m.bpMu.Lock()
+	m.breakpoints[item.ID] = bp
+	m.bpMu.Unlock()
+
+	m.emitMu.RLock()
+	if !m.closed {
+		select {
+		case m.pendingCh <- item:
+		default:
+			m.log.Warn("断点通知通道已满，丢弃通知", "id", item.ID, "url", item.Request.URL)
+		}
+	}
+	m.emitMu.RUnlock()
+	m.log.Info("请求已在断点暂停", "id", item.ID, "stage", item.Stage, "url", item.Request.URL, "timeout", timeout)
+
+	go func() {
+		timer := time.NewTimer(timeout)
+		defer timer.Stop()
+		var d breakpointDecision
+		select {
+		case d = <-bp.decide:
+		case <-timer.C:
+			if _, ok := m.takeBreakpoint(item.ID); ok {
+				m.log.Info("断点等待超时，按规则修改放行", "id", item.ID, "url", item.Request.URL)
+			} else {
+				d = <-bp.decide // 超时的同时已被人工处理
+			}
+		case <-ts.ctx.Done():
+			m.takeBreakpoint(item.ID)
+			return
+		}
+		if d.drop {
+			return
+		}
+		ctx, cancel := context.WithTimeout(ts.ctx, delayedApplyTimeout)
+		defer cancel()
+		resolve(ctx, d)
+	}()
+}
+
+// pauseRequest 请求阶段断点：展示应用规则修改后的请求，放行时叠加人工修改
+func (m *Manager) pauseRequest(
+	ts *targetSession,
+	ev *fetch.RequestPausedReply,
+	mut *RequestMutation,
+	ruleMatches []model.RuleMatch,
+	requestInfo model.RequestInfo,
+	responseInfo model.ResponseInfo,
+	steps []model.BodyTransform,
+) {
+	shown := m.captureModifiedRequestData(requestInfo, mut)
+	if mut.Method != nil {
+		shown.Method = *mut.Method
+	}
+	item := model.PendingItem{Stage: string(rulespec.StageRequest), Request: shown, Response: responseInfo, Rules: ruleMatches}
+	m.awaitBreakpoint(ts, item, mut.Pause, func(ctx context.Context, d breakpointDecision) {
+		if d.reject {
+			m.executor.FailRequest(ctx, ts, ev, string(network.ErrorReasonAborted))
+			m.sendMatchedEvent(ts.id, ev, "blocked", ruleMatches, requestInfo, responseInfo, steps)
+			m.log.Info("断点请求已拒绝", "url", ev.Request.URL)
+			return
+		}
+		applyRequestEdits(ev, mut, d.edits)
+		if m.bodyOverLimit(ts.id, ev.Request.URL, rulespec.StageRequest, bodyLen(mut.Body)) {
+			m.continueRequestWithCredentials(ctx, ts, ev)
+			m.sendMatchedEvent(ts.id, ev, "passed", ruleMatches, requestInfo, responseInfo, steps)
+			return
+		}
+		result := m.applyRequestResult(ctx, ts, ev, mut, ruleMatches, requestInfo, responseInfo, steps)
+		m.log.Info("断点请求已放行", "url", ev.Request.URL, "result", result, "edited", d.edits != nil)
+	})
+}
+
+// pauseResponse 响应阶段断点：展示应用规则修改后的响应，放行时叠加人工修改
+func (m *Manager) pauseResponse(
+	ts *targetSession,
+	ev *fetch.RequestPausedReply,
+	mut *ResponseMutation,
+	responseBody BodyContent,
+	bypassBody bool,
+	ruleMatches []model.RuleMatch,
+	requestInfo model.RequestInfo,
+	responseInfo model.ResponseInfo,
+	steps []model.BodyTransform,
+) {
+	shown := m.captureModifiedResponseData(responseInfo, mut, responseBody)
+	if len(responseBody.Data) == 0 {
+		shown.BodyTruncated = responseInfo.BodyTruncated
+	}
+	item := model.PendingItem{Stage: string(rulespec.StageResponse), Request: requestInfo, Response: shown, Rules: ruleMatches}
+	m.awaitBreakpoint(ts, item, mut.Pause, func(ctx context.Context, d breakpointDecision) {
+		if d.reject {
+			m.executor.FailRequest(ctx, ts, ev, string(network.ErrorReasonAborted))
+			m.sendMatchedEvent(ts.id, ev, "blocked", ruleMatches, requestInfo, responseInfo, steps)
+			m.log.Info("断点响应已拒绝", "url", ev.Request.URL)
+			return
+		}
+		if applyResponseEdits(ev, mut, d.edits) {
+			responseBody, bypassBody = *mut.Body, false
+		}
+		if m.bodyOverLimit(ts.id, ev.Request.URL, rulespec.StageResponse, bodyLen(mut.Body)) {
+			m.continueResponseWithPolicy(ctx, ts, ev)
+			m.sendMatchedEvent(ts.id, ev, "passed", ruleMatches, requestInfo, responseInfo, steps)
+			return
+		}
+		result := m.applyResponseResult(ctx, ts, ev, mut, responseBody, bypassBody, ruleMatches, requestInfo, responseInfo, steps)
+		m.log.Info("断点响应已放行", "url", ev.Request.URL, "result", result, "edited", d.edits != nil)
+	})
+}
+
+// applyRequestEdits 将人工修改叠加到请求变更上
+func applyRequestEdits(ev *fetch.RequestPausedReply, mut *RequestMutation, e *model.PendingEdits) {
+	if e == nil {
+		return
+	}
+	if e.Method != "" {
+		method := e.Method
+		mut.Method = &method
+	}
+	if e.URL != "" {
+		u := e.URL
+		mut.URL = &u
+	}
+	mut.RemoveHeaders = editHeaders(mut.Headers, mut.RemoveHeaders, e)
+	for name := range mut.SecretHeaders {
+		if editsHeader(e, name) {
+			delete(mut.SecretHeaders, name)
+		}
+	}
+	if e.Body != nil {
+		body := mutation.NewBodyContent([]byte(*e.Body), getContentType(ev))
+		mut.Body = &body
+	}
+}
+
+// applyResponseEdits 将人工修改叠加到响应变更上，返回是否替换了 Body
+func applyResponseEdits(ev *fetch.RequestPausedReply, mut *ResponseMutation, e *model.PendingEdits) bool {
+	if e == nil {
+		return false
+	}
+	if e.StatusCode != 0 {
+		code := e.StatusCode
+		mut.StatusCode = &code
+	}
+	mut.RemoveHeaders = editHeaders(mut.Headers, mut.RemoveHeaders, e)
+	if e.Body == nil {
+		return false
+	}
+	body := mutation.NewBodyContent([]byte(*e.Body), responseContentType(ev))
+	mut.Body = &body
+	mut.BodyReplaced = true
+	return true
+}
+
+// editHeaders 应用头部的人工修改：被设置或移除的头部先不区分大小写地移除原值，返回新的移除列表
+func editHeaders(headers map[string]string, remove []string, e *model.PendingEdits) []string {
+	names := append(append([]string(nil), e.RemoveHeaders...), mapKeys(e.Headers)...)
+	for _, name := range names {
+		for k := range headers {
+			if strings.EqualFold(k, name) {
+				delete(headers, k)
+			}
+		}
+		remove = append(remove, name)
+	}
+	for k, v := range e.Headers {
+		headers[k] = v
+	}
+	return remove
+}
+
+// editsHeader 判断人工修改是否设置或移除了指定头部
+func editsHeader(e *model.PendingEdits, name string) bool {
+	for _, h := range e.RemoveHeaders {
+		if strings.EqualFold(h, name) {
+			return true
+		}
+	}
+	for h := range e.Headers {
+		if strings.EqualFold(h, name) {
+			return true
+		}
+	}
+	return false
+}
+
+// mapKeys 返回映射的全部键
+func mapKeys(m map[string]string) []string {
+	out := make([]string, 0, len(m))
+	for k := range m {
+		out = append(out, k)
+	}
+	return out
+}
+
+// bodyLen 返回 Body 长度，nil 视为 0
+func bodyLen(b *BodyContent) int {
+	if b == nil {
+		return 0
+	}
+	return len(b.Data)
+}
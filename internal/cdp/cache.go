@@ -0,0 +1,54 @@
+package cdp
+
+import (
+	"context"
+	"time"
+
+	"github.com/mafredri/cdp/protocol/network"
+)
+
+// SetCacheBypass 设置是否禁用浏览器缓存，并立即应用到所有已附加的目标；
+// 禁用后每个请求都会真正发出，改写后的响应不会被缓存中的旧内容掩盖
+func (m *Manager) SetCacheBypass(on bool) {
+	m.stateMu.Lock()
+	m.cacheBypass = on
+	m.stateMu.Unlock()
+
+	m.targetsMu.Lock()
+	defer m.targetsMu.Unlock()
+	for _, ts := range m.targets {
+		m.applyCacheBypass(ts)
+	}
+}
+
+// isCacheBypass 返回是否禁用浏览器缓存
+func (m *Manager) isCacheBypass() bool {
+	m.stateMu.RLock()
+	defer m.stateMu.RUnlock()
+	return m.cacheBypass
+}
+
+// applyCacheBypass 将缓存开关下发到目标，未禁用过缓存的目标无需下发
+func (m *Manager) applyCacheBypass(ts *targetSession) {
+	if ts == nil || ts.client == nil || ts.client.Network == nil {
+		return
+	}
+	on := m.isCacheBypass()
+	if !on && !ts.cacheDisabled {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(ts.ctx, 2*time.Second)
+	defer cancel()
+	// 缓存开关依赖 Network 域，拦截未启用时也需要先启用
+	if err := ts.client.Network.Enable(ctx, nil); err != nil {
+		m.log.Err(err, "启用 Network 域失败", "target", string(ts.id))
+		return
+	}
+	if err := ts.client.Network.SetCacheDisabled(ctx, network.NewSetCacheDisabledArgs(on)); err != nil {
+		m.log.Err(err, "设置浏览器缓存开关失败", "target", string(ts.id))
+		return
+	}
+	ts.cacheDisabled = on
+	m.log.Debug("已设置浏览器缓存开关", "target", string(ts.id), "disabled", on)
+}
@@ -0,0 +1,103 @@
+package cdp
+
+import (
+	"context"
+	"errors"
+	"regexp"
+	"strconv"
+	"time"
+
+	"github.com/mafredri/cdp/devtool"
+	"github.com/mafredri/cdp/protocol/fetch"
+	"github.com/mafredri/cdp/rpcc"
+
+	"cdpnetool/pkg/model"
+)
+
+// 各可选特性开始受支持的 Chromium 主版本
+const (
+	minPostDataEntriesVersion   = 81
+	minUserAgentMetadataVersion = 84
+)
+
+// capabilityProbeID 探测方法是否存在时使用的无效请求 ID
+const capabilityProbeID = "cdpnetool-capability-probe"
+
+// errMethodNotFound 浏览器不认识所调用方法时返回的 JSON-RPC 错误码
+const errMethodNotFound = -32601
+
+// chromeVersionRe 从 User-Agent 或 Browser 字段中提取 Chromium 主版本
+var chromeVersionRe = regexp.MustCompile(`(?:Chrome|Chromium)/(\d+)\.`)
+
+// defaultCapabilities 未探测时的能力：按支持全部可选特性处理
+func defaultCapabilities() model.BrowserCapabilities {
+	return model.BrowserCapabilities{
+		PostDataEntries:          true,
+		TakeResponseBodyAsStream: true,
+		UserAgentMetadata:        true,
+	}
+}
+
+// Capabilities 返回连接的浏览器版本与可选特性支持情况
+func (m *Manager) Capabilities() model.BrowserCapabilities {
+	m.stateMu.RLock()
+	defer m.stateMu.RUnlock()
+	return m.caps
+}
+
+// detectCapabilities 查询浏览器版本并探测可选特性，每个会话只在首次附加目标时执行；
+// 不支持的特性会被自动停用，而不是在处理请求时失败
+func (m *Manager) detectCapabilities(ts *targetSession) {
+	if m.Capabilities().Detected || ts.client == nil {
+		return
+	}
+	ctx, cancel := context.WithTimeout(ts.ctx, 2*time.Second)
+	defer cancel()
+
+	caps := defaultCapabilities()
+	caps.Detected = true
+	if v, err := devtool.New(m.devtoolsURL).Version(ctx); err != nil {
+		m.log.Warn("获取浏览器版本失败，按支持全部特性处理", "error", err)
+	} else {
+		caps.Browser = v.Browser
+		caps.ProtocolVersion = v.Protocol
+		caps.UserAgent = v.UserAgent
+		caps.V8Version = v.V8
+		caps.MajorVersion = chromiumMajor(v.UserAgent, v.Browser)
+		// 无法识别版本的浏览器按支持处理，由后续调用结果决定
+		if caps.MajorVersion > 0 {
+			caps.PostDataEntries = caps.MajorVersion >= minPostDataEntriesVersion
+			caps.UserAgentMetadata = caps.MajorVersion >= minUserAgentMetadataVersion
+		}
+	}
+	_, err := ts.client.Fetch.TakeResponseBodyAsStream(ctx, fetch.NewTakeResponseBodyAsStreamArgs(capabilityProbeID))
+	caps.TakeResponseBodyAsStream = !isMethodNotFound(err)
+
+	m.stateMu.Lock()
+	m.caps = caps
+	m.stateMu.Unlock()
+
+	m.log.Info("浏览器能力探测完成", "browser", caps.Browser, "protocol", caps.ProtocolVersion,
+		"postDataEntries", caps.PostDataEntries, "stream", caps.TakeResponseBodyAsStream, "uaMetadata", caps.UserAgentMetadata)
+	if unsupported := caps.Unsupported(); len(unsupported) > 0 {
+		m.log.Warn("浏览器不支持部分特性，已自动停用", "features", unsupported)
+	}
+}
+
+// chromiumMajor 从给定字段中依次提取 Chromium 主版本，均无法识别时返回 0
+func chromiumMajor(fields ...string) int {
+	for _, f := range fields {
+		if sm := chromeVersionRe.FindStringSubmatch(f); sm != nil {
+			if n, err := strconv.Atoi(sm[1]); err == nil {
+				return n
+			}
+		}
+	}
+	return 0
+}
+
+// isMethodNotFound 判断错误是否表示浏览器不支持所调用的方法
+func isMethodNotFound(err error) bool {
+	var re *rpcc.ResponseError
+	return errors.As(err, &re) && re.Code == errMethodNotFound
+}
@@ -0,0 +1,225 @@
+package cdp
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"strings"
+	"time"
+
+	"github.com/mafredri/cdp"
+	"github.com/mafredri/cdp/protocol/fetch"
+	"github.com/mafredri/cdp/protocol/network"
+
+	"cdpnetool/internal/mutation"
+	"cdpnetool/pkg/model"
+)
+
+// captureMaxPending 全量捕获中同时跟踪的未完成请求上限，超出后新请求不再记录
+const captureMaxPending = 2000
+
+// captureBodyTimeout 读取单个响应体的超时时间
+const captureBodyTimeout = 2 * time.Second
+
+// capturedRequest 全量捕获中跟踪的一次请求
+type capturedRequest struct {
+	request  model.RequestInfo
+	response model.ResponseInfo
+	start    int64
+	mimeType string
+	failure  *model.LoadFailure // 网络层失败时的原因
+}
+
+// markReported 记录请求已由拦截流程上报事件，全量捕获与失败记录不再重复记录
+func (m *Manager) markReported(ts *targetSession, ev *fetch.RequestPausedReply) {
+	if ev.NetworkID != nil {
+		ts.reported.Store(*ev.NetworkID, struct{}{})
+	}
+}
+
+// consumeCapture 订阅 Network 域的请求生命周期事件，在请求完成后读取响应体并发送未匹配事件；
+// 各事件流同步接收以保证同一请求的事件按发生顺序处理
+func (m *Manager) consumeCapture(ts *targetSession) {
+	sent, err := ts.client.Network.RequestWillBeSent(ts.ctx)
+	if err != nil {
+		m.log.Err(err, "订阅请求发送事件失败", "target", string(ts.id))
+		return
+	}
+	received, err := ts.client.Network.ResponseReceived(ts.ctx)
+	if err != nil {
+		sent.Close()
+		m.log.Err(err, "订阅响应接收事件失败", "target", string(ts.id))
+		return
+	}
+	finished, err := ts.client.Network.LoadingFinished(ts.ctx)
+	if err != nil {
+		sent.Close()
+		received.Close()
+		m.log.Err(err, "订阅加载完成事件失败", "target", string(ts.id))
+		return
+	}
+	failed, err := ts.client.Network.LoadingFailed(ts.ctx)
+	if err != nil {
+		sent.Close()
+		received.Close()
+		finished.Close()
+		m.log.Err(err, "订阅加载失败事件失败", "target", string(ts.id))
+		return
+	}
+	if err := cdp.Sync(sent, received, finished, failed); err != nil {
+		m.log.Err(err, "同步 Network 事件流失败", "target", string(ts.id))
+	}
+
+	go func() {
+		defer sent.Close()
+		defer received.Close()
+		defer finished.Close()
+		defer failed.Close()
+
+		pending := make(map[network.RequestID]*capturedRequest)
+		for {
+			select {
+			case <-ts.ctx.Done():
+				return
+			case <-sent.Ready():
+				ev, err := sent.Recv()
+				if err != nil {
+					return
+				}
+				// 重定向沿用同一个 RequestID，先记录上一跳
+				if prev, ok := pending[ev.RequestID]; ok && ev.RedirectResponse != nil {
+					prev.response = networkResponseInfo(ev.RedirectResponse)
+					m.sendCaptured(ts, ev.RequestID, prev)
+					delete(pending, ev.RequestID)
+				}
+				if len(pending) >= captureMaxPending || !isCapturableURL(ev.Request.URL) {
+					continue
+				}
+				pending[ev.RequestID] = newCapturedRequest(ev)
+			case <-received.Ready():
+				ev, err := received.Recv()
+				if err != nil {
+					return
+				}
+				if c, ok := pending[ev.RequestID]; ok {
+					c.response = networkResponseInfo(&ev.Response)
+					c.mimeType = ev.Response.MimeType
+				}
+			case <-finished.Ready():
+				ev, err := finished.Recv()
+				if err != nil {
+					return
+				}
+				c, ok := pending[ev.RequestID]
+				delete(pending, ev.RequestID)
+				if !ok {
+					ts.reported.Delete(ev.RequestID)
+					continue
+				}
+				go m.finishCaptured(ts, ev.RequestID, c, int64(ev.EncodedDataLength))
+			case <-failed.Ready():
+				ev, err := failed.Recv()
+				if err != nil {
+					return
+				}
+				c, ok := pending[ev.RequestID]
+				delete(pending, ev.RequestID)
+				if !ok {
+					ts.reported.Delete(ev.RequestID)
+					continue
+				}
+				c.failure = loadFailureOf(ev)
+				m.sendCaptured(ts, ev.RequestID, c)
+			}
+		}
+	}()
+}
+
+// newCapturedRequest 根据请求发送事件构建请求信息
+func newCapturedRequest(ev *network.RequestWillBeSentReply) *capturedRequest {
+	info := model.RequestInfo{
+		URL:          ev.Request.URL,
+		Method:       ev.Request.Method,
+		Headers:      make(map[string]string),
+		ResourceType: string(ev.Type),
+	}
+	_ = json.Unmarshal(ev.Request.Headers, &info.Headers)
+	// 复用拦截事件的请求体解析（优先按 postDataEntries 解码）
+	info.Body, info.BodyEncoding = requestBodyContent(&fetch.RequestPausedReply{Request: ev.Request}).EventBody()
+	return &capturedRequest{
+		request:  info,
+		response: model.ResponseInfo{Headers: map[string]string{}},
+		start:    time.Now().UnixMilli(),
+	}
+}
+
+// networkResponseInfo 将 Network 域的响应转换为响应信息（不含响应体）
+func networkResponseInfo(r *network.Response) model.ResponseInfo {
+	info := model.ResponseInfo{
+		StatusCode: r.Status,
+		Headers:    make(map[string]string),
+	}
+	networkConnInfo(r).apply(&info)
+	_ = json.Unmarshal(r.Headers, &info.Headers)
+	return info
+}
+
+// isCapturableURL 仅记录 HTTP(S) 请求，忽略 data:、blob: 等浏览器内部资源
+func isCapturableURL(u string) bool {
+	return strings.HasPrefix(u, "http://") || strings.HasPrefix(u, "https://")
+}
+
+// finishCaptured 读取已完成请求的响应体后发送事件；超过 Body 大小阈值的响应体不读取
+func (m *Manager) finishCaptured(ts *targetSession, id network.RequestID, c *capturedRequest, size int64) {
+	if _, ok := ts.reported.Load(id); !ok && m.isEnabled() {
+		if m.bodySizeThreshold > 0 && size > m.bodySizeThreshold {
+			c.response.BodyTruncated = true
+		} else if data, ok := m.networkResponseBody(ts, id); ok {
+			c.response.Body, c.response.BodyEncoding, c.response.BodyTruncated = m.eventBody(mutation.NewBodyContent(data, c.mimeType))
+		}
+	}
+	m.sendCaptured(ts, id, c)
+}
+
+// networkResponseBody 通过 Network.getResponseBody 读取响应体
+func (m *Manager) networkResponseBody(ts *targetSession, id network.RequestID) ([]byte, bool) {
+	ctx, cancel := context.WithTimeout(ts.ctx, captureBodyTimeout)
+	defer cancel()
+	rb, err := ts.client.Network.GetResponseBody(ctx, network.NewGetResponseBodyArgs(id))
+	if err != nil {
+		return nil, false
+	}
+	if rb.Base64Encoded {
+		data, err := base64.StdEncoding.DecodeString(rb.Body)
+		return data, err == nil
+	}
+	return []byte(rb.Body), true
+}
+
+// sendCaptured 发送全量捕获或失败记录的未匹配事件，网络层失败的请求以 failed 记录；已由拦截流程记录为匹配事件的请求跳过
+func (m *Manager) sendCaptured(ts *targetSession, id network.RequestID, c *capturedRequest) {
+	if _, ok := ts.reported.LoadAndDelete(id); ok || !m.isEnabled() {
+		return
+	}
+	c.response.Timing = model.ResponseTiming{StartTime: c.start, EndTime: time.Now().UnixMilli()}
+	var result string
+	if c.failure != nil {
+		result = "failed"
+	}
+	m.emit(model.InterceptEvent{
+		IsMatched: false,
+		Unmatched: &model.UnmatchedEvent{
+			NetworkEvent: model.NetworkEvent{
+				Session:     "", // 会在上层填充
+				Target:      ts.id,
+				Timestamp:   time.Now().UnixMilli(),
+				IsMatched:   false,
+				NetworkID:   string(id),
+				Request:     c.request,
+				Response:    c.response,
+				FinalResult: result,
+				Failure:     c.failure,
+			},
+		},
+	})
+}
@@ -0,0 +1,14 @@
+package cdp
+
+import (
+	"github.com/mafredri/cdp/protocol/fetch"
+
+	"cdpnetool/internal/mutation"
+)
+
+// compressResponse 压缩最终下发的响应体，失败时按未压缩的 Body 下发
+func (m *Manager) compressResponse(ev *fetch.RequestPausedReply, mut *ResponseMutation) {
+	if err := mutation.ApplyCompression(responseOf(ev), mut); err != nil {
+		m.log.Err(err, "压缩响应体失败", "url", ev.Request.URL, "encoding", mut.Compress)
+	}
+}
@@ -0,0 +1,31 @@
+package cdp
+
+import (
+	"cdpnetool/internal/mutation"
+	"cdpnetool/pkg/model"
+	"cdpnetool/pkg/rulespec"
+)
+
+// reportConflicts 为每个冲突发送 rule_conflict 通知，返回是否应放弃所有修改
+func (m *Manager) reportConflicts(target model.TargetID, url string, mm *mutation.Merger) bool {
+	if len(mm.Conflicts()) == 0 {
+		return false
+	}
+	abort := mm.Policy() == rulespec.ConflictAbort
+	for _, c := range mm.Conflicts() {
+		msg := "规则「" + c.Kept.Rule.Name + "」与「" + c.Dropped.Rule.Name + "」对 " + c.Field + " 设置了不同的值"
+		if abort {
+			msg += "，已放弃所有修改"
+		}
+		m.log.Warn("规则变更冲突", "field", c.Field, "kept", c.Kept.Rule.ID, "dropped", c.Dropped.Rule.ID, "policy", string(mm.Policy()))
+		m.sendNotice(target, model.NoticeRuleConflict, url, msg, map[string]string{
+			"field":        c.Field,
+			"policy":       string(mm.Policy()),
+			"keptRule":     c.Kept.Rule.ID,
+			"keptValue":    c.KeptValue,
+			"droppedRule":  c.Dropped.Rule.ID,
+			"droppedValue": c.DroppedValue,
+		})
+	}
+	return abort
+}
@@ -0,0 +1,192 @@
+package cdp
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/mafredri/cdp/devtool"
+	"github.com/mafredri/cdp/protocol/network"
+
+	"cdpnetool/pkg/model"
+)
+
+// cookieCallTimeout Cookie 操作的超时时间
+const cookieCallTimeout = 5 * time.Second
+
+// pageTarget 返回执行页面级操作的目标；未指定时使用任一已附加的页面目标，同一浏览器上下文中的 Cookie 是共享的
+func (m *Manager) pageTarget(target model.TargetID) (*targetSession, error) {
+	m.targetsMu.Lock()
+	defer m.targetsMu.Unlock()
+	if target != "" {
+		ts, ok := m.targets[target]
+		if !ok || ts.client == nil {
+			return nil, fmt.Errorf("target not attached: %s", target)
+		}
+		return ts, nil
+	}
+	for _, ts := range m.targets {
+		if ts.client != nil && ts.kind == devtool.Page {
+			return ts, nil
+		}
+	}
+	return nil, fmt.Errorf("no targets attached")
+}
+
+// GetCookies 获取适用于指定 URL 的 Cookie，urls 为空时返回目标当前页面及其子框架的 Cookie
+func (m *Manager) GetCookies(target model.TargetID, urls []string) ([]model.Cookie, error) {
+	ts, err := m.pageTarget(target)
+	if err != nil {
+		return nil, err
+	}
+	ctx, cancel := context.WithTimeout(ts.ctx, cookieCallTimeout)
+	defer cancel()
+	return m.getCookies(ctx, ts, urls)
+}
+
+// getCookies 通过 Network.getCookies 读取 Cookie
+func (m *Manager) getCookies(ctx context.Context, ts *targetSession, urls []string) ([]model.Cookie, error) {
+	args := network.NewGetCookiesArgs()
+	if len(urls) > 0 {
+		args.SetURLs(urls)
+	}
+	reply, err := ts.client.Network.GetCookies(ctx, args)
+	if err != nil {
+		return nil, err
+	}
+	out := make([]model.Cookie, 0, len(reply.Cookies))
+	for _, c := range reply.Cookies {
+		cookie := model.Cookie{
+			Name:     c.Name,
+			Value:    c.Value,
+			Domain:   c.Domain,
+			Path:     c.Path,
+			HTTPOnly: c.HTTPOnly,
+			Secure:   c.Secure,
+			SameSite: string(c.SameSite),
+		}
+		if !c.Session && c.Expires > 0 {
+			cookie.Expires = int64(c.Expires)
+		}
+		out = append(out, cookie)
+	}
+	return out, nil
+}
+
+// SetCookie 设置 Cookie，必须指定 URL 或 Domain
+func (m *Manager) SetCookie(target model.TargetID, c model.Cookie) error {
+	if c.Name == "" {
+		return fmt.Errorf("cookie name empty")
+	}
+	if c.URL == "" && c.Domain == "" {
+		return fmt.Errorf("cookie url or domain required")
+	}
+	ts, err := m.pageTarget(target)
+	if err != nil {
+		return err
+	}
+
+	args := network.NewSetCookieArgs(c.Name, c.Value)
+	if c.URL != "" {
+		args.SetURL(c.URL)
+	}
+	if c.Domain != "" {
+		args.SetDomain(c.Domain)
+	}
+	if c.Path != "" {
+		args.SetPath(c.Path)
+	}
+	if c.Secure {
+		args.SetSecure(true)
+	}
+	if c.HTTPOnly {
+		args.SetHTTPOnly(true)
+	}
+	if c.SameSite != "" {
+		sameSite, ok := parseSameSite(c.SameSite)
+		if !ok {
+			return fmt.Errorf("invalid sameSite: %s", c.SameSite)
+		}
+		args.SetSameSite(sameSite)
+	}
+	if c.Expires > 0 {
+		args.SetExpires(network.TimeSinceEpoch(c.Expires))
+	}
+
+	ctx, cancel := context.WithTimeout(ts.ctx, cookieCallTimeout)
+	defer cancel()
+	if _, err := ts.client.Network.SetCookie(ctx, args); err != nil {
+		return err
+	}
+	m.log.Info("已设置 Cookie", "target", string(ts.id), "name", c.Name, "domain", c.Domain, "url", c.URL)
+	return nil
+}
+
+// parseSameSite 解析 SameSite 取值，不区分大小写
+func parseSameSite(v string) (network.CookieSameSite, bool) {
+	for _, s := range []network.CookieSameSite{network.CookieSameSiteStrict, network.CookieSameSiteLax, network.CookieSameSiteNone} {
+		if strings.EqualFold(v, string(s)) {
+			return s, true
+		}
+	}
+	return "", false
+}
+
+// DeleteCookies 删除匹配条件的 Cookie，返回删除的数量；Name 为空时先读取 URL（未指定时为当前页面）
+// 下的全部 Cookie，再按 Domain/Path 过滤后逐个删除
+func (m *Manager) DeleteCookies(target model.TargetID, f model.CookieFilter) (int, error) {
+	ts, err := m.pageTarget(target)
+	if err != nil {
+		return 0, err
+	}
+	ctx, cancel := context.WithTimeout(ts.ctx, cookieCallTimeout)
+	defer cancel()
+
+	if f.Name != "" {
+		if err := ts.client.Network.DeleteCookies(ctx, deleteCookiesArgs(f.Name, f.URL, f.Domain, f.Path)); err != nil {
+			return 0, err
+		}
+		m.log.Info("已删除 Cookie", "target", string(ts.id), "name", f.Name, "domain", f.Domain, "url", f.URL)
+		return 1, nil
+	}
+
+	var urls []string
+	if f.URL != "" {
+		urls = []string{f.URL}
+	}
+	cookies, err := m.getCookies(ctx, ts, urls)
+	if err != nil {
+		return 0, err
+	}
+	n := 0
+	for _, c := range cookies {
+		if f.Domain != "" && !strings.EqualFold(c.Domain, f.Domain) {
+			continue
+		}
+		if f.Path != "" && c.Path != f.Path {
+			continue
+		}
+		if err := ts.client.Network.DeleteCookies(ctx, deleteCookiesArgs(c.Name, "", c.Domain, c.Path)); err != nil {
+			return n, err
+		}
+		n++
+	}
+	m.log.Info("已批量删除 Cookie", "target", string(ts.id), "count", n, "url", f.URL, "domain", f.Domain)
+	return n, nil
+}
+
+// deleteCookiesArgs 构造 Network.deleteCookies 参数
+func deleteCookiesArgs(name, url, domain, path string) *network.DeleteCookiesArgs {
+	args := network.NewDeleteCookiesArgs(name)
+	if url != "" {
+		args.SetURL(url)
+	}
+	if domain != "" {
+		args.SetDomain(domain)
+	}
+	if path != "" {
+		args.SetPath(path)
+	}
+	return args
+}
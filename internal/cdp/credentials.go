@@ -0,0 +1,137 @@
+package cdp
+
+import (
+	"context"
+	"net/url"
+	"strings"
+
+	"github.com/mafredri/cdp/protocol/fetch"
+
+	"cdpnetool/internal/mutation"
+	"cdpnetool/pkg/model"
+)
+
+// authorizationHeader 注入的认证请求头名
+const authorizationHeader = "Authorization"
+
+// credentialStore 按源查找需要注入的 Authorization 头
+type credentialStore struct {
+	byOrigin map[string]string // 规范化的源 -> Authorization 头的值
+	origins  []string          // 配置中的源，按添加顺序
+}
+
+// newCredentialStore 创建凭据表，信息不完整的条目会被忽略
+func newCredentialStore(creds []model.OriginCredential) *credentialStore {
+	s := &credentialStore{byOrigin: make(map[string]string)}
+	for _, c := range creds {
+		origin := normalizeOrigin(c.Origin)
+		value, ok := c.Authorization()
+		if origin == "" || !ok {
+			continue
+		}
+		if _, dup := s.byOrigin[origin]; !dup {
+			s.origins = append(s.origins, origin)
+		}
+		s.byOrigin[origin] = value
+	}
+	return s
+}
+
+// lookup 返回请求所属源的 Authorization 头
+func (s *credentialStore) lookup(rawURL string) (string, bool) {
+	if s == nil || len(s.byOrigin) == 0 {
+		return "", false
+	}
+	v, ok := s.byOrigin[normalizeOrigin(rawURL)]
+	return v, ok
+}
+
+// normalizeOrigin 将 URL 或源规范化为小写的 scheme://host[:port]，并省略默认端口
+func normalizeOrigin(raw string) string {
+	u, err := url.Parse(strings.TrimSpace(raw))
+	if err != nil || u.Scheme == "" || u.Host == "" {
+		return ""
+	}
+	scheme := strings.ToLower(u.Scheme)
+	host := strings.ToLower(u.Hostname())
+	port := u.Port()
+	if port == "" || (scheme == "https" && port == "443") || (scheme == "http" && port == "80") {
+		return scheme + "://" + host
+	}
+	return scheme + "://" + host + ":" + port
+}
+
+// SetCredentials 设置按源注入的认证信息，并按需更新请求阶段的拦截模式
+func (m *Manager) SetCredentials(creds []model.OriginCredential) {
+	m.stateMu.Lock()
+	m.credentials = newCredentialStore(creds)
+	m.stateMu.Unlock()
+	m.refreshFetchPatterns()
+}
+
+// currentCredentials 返回当前凭据表
+func (m *Manager) currentCredentials() *credentialStore {
+	m.stateMu.RLock()
+	defer m.stateMu.RUnlock()
+	return m.credentials
+}
+
+// credentialPatterns 返回凭据注入所需的请求阶段拦截模式
+func (m *Manager) credentialPatterns() []fetch.RequestPattern {
+	s := m.currentCredentials()
+	if s == nil {
+		return nil
+	}
+	out := make([]fetch.RequestPattern, 0, len(s.origins))
+	for _, origin := range s.origins {
+		out = append(out, fetch.RequestPattern{URLPattern: strPtr(escapeFetchPattern(origin) + "/*"), RequestStage: fetch.RequestStageRequest})
+	}
+	return out
+}
+
+// injectCredentials 将请求所属源的认证信息合并到请求修改中，返回是否注入；
+// 规则已设置或移除 Authorization 时保持规则的结果，只读捕获模式下不注入
+func (m *Manager) injectCredentials(ev *fetch.RequestPausedReply, mut *RequestMutation) bool {
+	if m.captureOnly || ev.ResponseStatusCode != nil {
+		return false
+	}
+	value, ok := m.currentCredentials().lookup(ev.Request.URL)
+	if !ok {
+		return false
+	}
+	for k := range mut.Headers {
+		if strings.EqualFold(k, authorizationHeader) {
+			return false
+		}
+	}
+	for _, k := range mut.RemoveHeaders {
+		if strings.EqualFold(k, authorizationHeader) {
+			return false
+		}
+	}
+	if mut.SecretHeaders == nil {
+		mut.SecretHeaders = make(map[string]string)
+	}
+	mut.SecretHeaders[authorizationHeader] = value
+	return true
+}
+
+// continueRequestWithCredentials 放行请求，请求所属源配置了认证信息或主机策略下有注入请求头时附带注入
+func (m *Manager) continueRequestWithCredentials(ctx context.Context, ts *targetSession, ev *fetch.RequestPausedReply) {
+	mut := mutation.NewRequestMutation()
+	creds := m.injectCredentials(ev, mut)
+	if m.injectExtraHeaders(ev, mut) || creds {
+		m.executor.ApplyRequestMutation(ctx, ts, ev, mut)
+		m.log.Debug("已注入源认证信息或请求头", "url", ev.Request.URL)
+		return
+	}
+	m.executor.ContinueRequest(ctx, ts, ev)
+}
+
+// maskSecret 掩码敏感值，仅保留认证方式前缀
+func maskSecret(v string) string {
+	if i := strings.IndexByte(v, ' '); i > 0 {
+		return v[:i] + " ******"
+	}
+	return "******"
+}
@@ -0,0 +1,22 @@
+package cdp
+
+import (
+	"context"
+	"time"
+)
+
+// delayedApplyTimeout 延迟结束后下发放行指令的超时时间
+const delayedApplyTimeout = 5 * time.Second
+
+// deferApply 延迟注入：等待 d 后在独立协程中以新的上下文执行 apply，不占用工作池；d 不大于 0 时直接执行
+func (m *Manager) deferApply(ctx context.Context, ts *targetSession, d time.Duration, apply func(ctx context.Context)) {
+	if d <= 0 {
+		apply(ctx)
+		return
+	}
+	m.afterFunc(d, func() {
+		ctx, cancel := context.WithTimeout(ts.ctx, delayedApplyTimeout)
+		defer cancel()
+		apply(ctx)
+	})
+}
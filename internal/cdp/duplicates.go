@@ -0,0 +1,52 @@
+package cdp
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/mafredri/cdp/protocol/fetch"
+	"github.com/mafredri/cdp/protocol/network"
+
+	"cdpnetool/pkg/model"
+)
+
+// consumeDuplicates 订阅 Network.requestWillBeSent，发现窗口内再次发送的相同请求时发送通知事件；
+// 基于 Network 域事件，不依赖拦截范围，重定向产生的后续请求不计入
+func (m *Manager) consumeDuplicates(ts *targetSession) {
+	sent, err := ts.client.Network.RequestWillBeSent(ts.ctx)
+	if err != nil {
+		m.log.Err(err, "订阅请求发送事件失败", "target", string(ts.id))
+		return
+	}
+
+	go func() {
+		defer sent.Close()
+		for {
+			ev, err := sent.Recv()
+			if err != nil {
+				return
+			}
+			if ev.RedirectResponse != nil || !isCapturableURL(ev.Request.URL) || !m.isEnabled() {
+				continue
+			}
+			m.observeDuplicate(ts.id, ev)
+		}
+	}()
+}
+
+// observeDuplicate 记录一次请求，判定为重复时发送通知事件
+func (m *Manager) observeDuplicate(target model.TargetID, ev *network.RequestWillBeSentReply) {
+	body := requestBodyContent(&fetch.RequestPausedReply{Request: ev.Request}).Data
+	dup, ok := m.duplicateTracker.Observe(ev.Request.Method, ev.Request.URL, body, time.Now())
+	if !ok {
+		return
+	}
+	m.log.Warn("检测到重复请求", "method", dup.Method, "url", dup.URL, "count", dup.Count, "interval", dup.Interval)
+	m.sendNotice(target, model.NoticeDuplicateRequest, dup.URL, "重复请求: "+dup.Method+" "+dup.URL, map[string]string{
+		"method":     dup.Method,
+		"url":        dup.URL,
+		"bodyHash":   dup.BodyHash,
+		"count":      strconv.Itoa(dup.Count),
+		"intervalMs": strconv.FormatInt(dup.Interval.Milliseconds(), 10),
+	})
+}
@@ -0,0 +1,36 @@
+package cdp
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/mafredri/cdp/protocol/fetch"
+)
+
+// normalizeDecodedBody 调整以解码后 Body fulfill 响应时的头部。
+// GetResponseBody 返回的是已解压的内容，保留原 Content-Encoding 会使浏览器再次解压而得到乱码，
+// 因此移除 Content-Encoding 并按实际长度重写 Content-Length；keepEncoding 为 true 时
+// （规则显式设置了 Content-Encoding）视为 Body 已按该编码处理，仅修正长度
+func normalizeDecodedBody(headers []fetch.HeaderEntry, body []byte, keepEncoding bool) []fetch.HeaderEntry {
+	out := make([]fetch.HeaderEntry, 0, len(headers)+1)
+	for _, h := range headers {
+		if strings.EqualFold(h.Name, "Content-Length") {
+			continue
+		}
+		if !keepEncoding && strings.EqualFold(h.Name, "Content-Encoding") {
+			continue
+		}
+		out = append(out, h)
+	}
+	return append(out, fetch.HeaderEntry{Name: "Content-Length", Value: strconv.Itoa(len(body))})
+}
+
+// setsContentEncoding 判断规则是否显式设置了 Content-Encoding
+func setsContentEncoding(headers map[string]string) bool {
+	for k := range headers {
+		if strings.EqualFold(k, "Content-Encoding") {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,123 @@
+package cdp
+
+import (
+	"context"
+	"encoding/json"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/mafredri/cdp/protocol/fetch"
+	"github.com/mafredri/cdp/protocol/network"
+)
+
+// SetExtraHeaders 设置轻量请求头注入，并立即应用到所有已附加的目标。
+// 未设置主机策略时注入由浏览器通过 Network.setExtraHTTPHeaders 完成，请求不经过 Fetch 暂停和工作池，与拦截开关无关；
+// 该方式无法按主机区分，设置了主机策略时改为在拦截的请求阶段只为允许修改的主机注入，此时只在拦截启用期间生效
+func (m *Manager) SetExtraHeaders(h map[string]string) {
+	m.stateMu.Lock()
+	m.extraHeaders = h
+	m.stateMu.Unlock()
+	m.refreshExtraHeaders()
+}
+
+// refreshExtraHeaders 注入请求头或主机策略变化后重新下发到所有目标并更新拦截模式
+func (m *Manager) refreshExtraHeaders() {
+	m.targetsMu.Lock()
+	for _, ts := range m.targets {
+		m.applyExtraHeaders(ts)
+	}
+	m.targetsMu.Unlock()
+	m.refreshFetchPatterns()
+}
+
+// currentExtraHeaders 返回当前轻量注入的请求头，只读捕获模式下返回 nil
+func (m *Manager) currentExtraHeaders() map[string]string {
+	if m.captureOnly {
+		return nil
+	}
+	m.stateMu.RLock()
+	defer m.stateMu.RUnlock()
+	return m.extraHeaders
+}
+
+// extraHeadersViaFetch 判断注入请求头是否需要经过拦截的请求阶段：设置了主机策略时浏览器级注入会绕过策略
+func (m *Manager) extraHeadersViaFetch() bool {
+	m.stateMu.RLock()
+	defer m.stateMu.RUnlock()
+	return m.hosts.active()
+}
+
+// extraHeaderPatterns 主机策略下注入请求头时拦截全部请求的请求阶段，与浏览器级注入一样不受拦截资源类型限制
+func (m *Manager) extraHeaderPatterns() []fetch.RequestPattern {
+	if len(m.currentExtraHeaders()) == 0 || !m.extraHeadersViaFetch() {
+		return nil
+	}
+	return []fetch.RequestPattern{{URLPattern: strPtr("*"), RequestStage: fetch.RequestStageRequest}}
+}
+
+// injectExtraHeaders 主机策略下将注入请求头合并到请求修改中，返回是否注入；
+// 与浏览器级注入一致，规则已设置或移除的请求头保持规则的结果。调用方需已确认主机允许修改
+func (m *Manager) injectExtraHeaders(ev *fetch.RequestPausedReply, mut *RequestMutation) bool {
+	if ev.ResponseStatusCode != nil || !m.extraHeadersViaFetch() {
+		return false
+	}
+	injected := false
+	for k, v := range m.currentExtraHeaders() {
+		if hasHeaderKey(mut.Headers, k) || slices.ContainsFunc(mut.RemoveHeaders, func(r string) bool { return strings.EqualFold(r, k) }) {
+			continue
+		}
+		if mut.Headers == nil {
+			mut.Headers = make(map[string]string)
+		}
+		mut.Headers[k] = v
+		injected = true
+	}
+	return injected
+}
+
+// hasHeaderKey 判断请求头集合中是否有同名（不区分大小写）的请求头
+func hasHeaderKey(h map[string]string, name string) bool {
+	for k := range h {
+		if strings.EqualFold(k, name) {
+			return true
+		}
+	}
+	return false
+}
+
+// applyExtraHeaders 将轻量注入的请求头下发到目标，清空配置或改为经拦截注入时下发空集合以移除已注入的请求头
+func (m *Manager) applyExtraHeaders(ts *targetSession) {
+	if ts == nil || ts.client == nil || ts.client.Network == nil {
+		return
+	}
+	h := m.currentExtraHeaders()
+	if m.extraHeadersViaFetch() {
+		h = nil
+	}
+	if len(h) == 0 && !ts.extraApplied {
+		return
+	}
+	if h == nil {
+		h = map[string]string{}
+	}
+	raw, err := json.Marshal(h)
+	if err != nil {
+		m.log.Err(err, "序列化注入请求头失败", "target", string(ts.id))
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(ts.ctx, 2*time.Second)
+	defer cancel()
+	// 请求头注入依赖 Network 域，拦截未启用时也需要先启用
+	if err := ts.client.Network.Enable(ctx, nil); err != nil {
+		m.log.Err(err, "启用 Network 域失败", "target", string(ts.id))
+		return
+	}
+	if err := ts.client.Network.SetExtraHTTPHeaders(ctx, network.NewSetExtraHTTPHeadersArgs(raw)); err != nil {
+		m.log.Err(err, "设置注入请求头失败", "target", string(ts.id))
+		return
+	}
+	ts.extraApplied = len(h) > 0
+	m.log.Debug("已应用轻量请求头注入", "target", string(ts.id), "count", len(h))
+}
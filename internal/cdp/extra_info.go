@@ -0,0 +1,358 @@
+package cdp
+
+import (
+	"encoding/json"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/mafredri/cdp"
+	"github.com/mafredri/cdp/protocol/fetch"
+	"github.com/mafredri/cdp/protocol/network"
+
+	"cdpnetool/pkg/model"
+	"cdpnetool/pkg/rulespec"
+)
+
+const (
+	extraInfoMaxPending = 2000                   // 同时跟踪附加信息的请求上限，超出后新请求不再记录
+	extraInfoLinger     = 30 * time.Second       // 请求完成后保留附加信息的时间，供稍后产生的事件（如全量捕获）使用
+	extraInfoWait       = 200 * time.Millisecond // 响应阶段评估 cookieBlocked 条件时等待响应附加信息的最长时间
+)
+
+// requestExtra 浏览器通过 *ExtraInfo 事件上报的一次请求的附加信息
+type requestExtra struct {
+	requestHeaders  string                // 实际发送的原始请求头行
+	requestBlocked  []model.BlockedCookie // 未随请求发送的 Cookie
+	responseHeaders string                // 实际接收的原始响应头行
+	responseBlocked []model.BlockedCookie // 响应中被拒绝保存的 Set-Cookie
+	responded       bool                  // 已收到响应附加信息
+}
+
+// extraInfoTable 单个目标中请求的附加信息，按 Network RequestID 关联
+type extraInfoTable struct {
+	mu      sync.Mutex
+	reqs    map[network.RequestID]*requestExtra
+	waiters map[network.RequestID][]chan struct{} // 等待响应附加信息的评估
+}
+
+// entry 返回请求的附加信息记录，不存在时创建；达到上限时返回 nil，调用方需持有 mu
+func (t *extraInfoTable) entry(id network.RequestID) *requestExtra {
+	if e, ok := t.reqs[id]; ok {
+		return e
+	}
+	if len(t.reqs) >= extraInfoMaxPending {
+		return nil
+	}
+	e := &requestExtra{}
+	t.reqs[id] = e
+	return e
+}
+
+// consumeExtraInfo 订阅 requestWillBeSentExtraInfo / responseReceivedExtraInfo，记录原始头部和被阻止的 Cookie，
+// 在请求结束后保留一段时间再删除
+func (m *Manager) consumeExtraInfo(ts *targetSession) {
+	reqInfo, err := ts.client.Network.RequestWillBeSentExtraInfo(ts.ctx)
+	if err != nil {
+		m.log.Err(err, "订阅请求附加信息事件失败", "target", string(ts.id))
+		return
+	}
+	respInfo, err := ts.client.Network.ResponseReceivedExtraInfo(ts.ctx)
+	if err != nil {
+		reqInfo.Close()
+		m.log.Err(err, "订阅响应附加信息事件失败", "target", string(ts.id))
+		return
+	}
+	finished, err := ts.client.Network.LoadingFinished(ts.ctx)
+	if err != nil {
+		reqInfo.Close()
+		respInfo.Close()
+		m.log.Err(err, "订阅加载完成事件失败", "target", string(ts.id))
+		return
+	}
+	failed, err := ts.client.Network.LoadingFailed(ts.ctx)
+	if err != nil {
+		reqInfo.Close()
+		respInfo.Close()
+		finished.Close()
+		m.log.Err(err, "订阅加载失败事件失败", "target", string(ts.id))
+		return
+	}
+	if err := cdp.Sync(reqInfo, respInfo, finished, failed); err != nil {
+		m.log.Err(err, "同步 Network 事件流失败", "target", string(ts.id))
+	}
+
+	t := &extraInfoTable{
+		reqs:    make(map[network.RequestID]*requestExtra),
+		waiters: make(map[network.RequestID][]chan struct{}),
+	}
+	m.extraInfos.Store(ts.id, t)
+
+	go func() {
+		defer reqInfo.Close()
+		defer respInfo.Close()
+		defer finished.Close()
+		defer failed.Close()
+		defer m.extraInfos.Delete(ts.id)
+
+		for {
+			select {
+			case <-ts.ctx.Done():
+				return
+			case <-reqInfo.Ready():
+				ev, err := reqInfo.Recv()
+				if err != nil {
+					return
+				}
+				t.requestSent(ev)
+			case <-respInfo.Ready():
+				ev, err := respInfo.Recv()
+				if err != nil {
+					return
+				}
+				t.responseReceived(ev)
+			case <-finished.Ready():
+				ev, err := finished.Recv()
+				if err != nil {
+					return
+				}
+				t.expire(ev.RequestID)
+			case <-failed.Ready():
+				ev, err := failed.Recv()
+				if err != nil {
+					return
+				}
+				t.expire(ev.RequestID)
+			}
+		}
+	}()
+}
+
+// requestSent 记录实际发送的请求头与未发送的 Cookie；重定向的每一跳覆盖上一跳
+func (t *extraInfoTable) requestSent(ev *network.RequestWillBeSentExtraInfoReply) {
+	var blocked []model.BlockedCookie
+	for _, c := range ev.AssociatedCookies {
+		if len(c.BlockedReasons) == 0 {
+			continue
+		}
+		reasons := make([]string, len(c.BlockedReasons))
+		for i, r := range c.BlockedReasons {
+			reasons[i] = string(r)
+		}
+		blocked = append(blocked, model.BlockedCookie{Name: c.Cookie.Name, Reasons: reasons})
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if e := t.entry(ev.RequestID); e != nil {
+		e.requestHeaders = headerLines(ev.Headers)
+		e.requestBlocked = blocked
+	}
+}
+
+// responseReceived 记录实际接收的响应头与被拒绝保存的 Set-Cookie；HTTP/2、HTTP/3 没有原始文本时按头部逐行还原
+func (t *extraInfoTable) responseReceived(ev *network.ResponseReceivedExtraInfoReply) {
+	var blocked []model.BlockedCookie
+	for _, c := range ev.BlockedCookies {
+		reasons := make([]string, len(c.BlockedReasons))
+		for i, r := range c.BlockedReasons {
+			reasons[i] = string(r)
+		}
+		name, _, _ := strings.Cut(c.CookieLine, "=")
+		if c.Cookie != nil {
+			name = c.Cookie.Name
+		}
+		blocked = append(blocked, model.BlockedCookie{Name: strings.TrimSpace(name), Reasons: reasons, Line: c.CookieLine})
+	}
+	raw := headerLines(ev.Headers)
+	if ev.HeadersText != nil && *ev.HeadersText != "" {
+		raw = strings.TrimRight(strings.ReplaceAll(*ev.HeadersText, "\r\n", "\n"), "\n")
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if e := t.entry(ev.RequestID); e != nil {
+		e.responseHeaders = raw
+		e.responseBlocked = blocked
+		e.responded = true
+	}
+	for _, ch := range t.waiters[ev.RequestID] {
+		close(ch)
+	}
+	delete(t.waiters, ev.RequestID)
+}
+
+// waitResponse 等待请求的响应附加信息，最多等待 d；附加信息可能晚于响应阶段的暂停事件到达
+func (t *extraInfoTable) waitResponse(id network.RequestID, d time.Duration) {
+	t.mu.Lock()
+	if e, ok := t.reqs[id]; ok && e.responded {
+		t.mu.Unlock()
+		return
+	}
+	ch := make(chan struct{})
+	t.waiters[id] = append(t.waiters[id], ch)
+	t.mu.Unlock()
+
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ch:
+		return
+	case <-timer.C:
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	list := t.waiters[id]
+	for i, w := range list {
+		if w == ch {
+			list = append(list[:i], list[i+1:]...)
+			break
+		}
+	}
+	if len(list) == 0 {
+		delete(t.waiters, id)
+	} else {
+		t.waiters[id] = list
+	}
+}
+
+// expire 请求结束后保留一段时间再删除附加信息
+func (t *extraInfoTable) expire(id network.RequestID) {
+	time.AfterFunc(extraInfoLinger, func() {
+		t.mu.Lock()
+		delete(t.reqs, id)
+		t.mu.Unlock()
+	})
+}
+
+// lookup 返回请求附加信息的副本
+func (t *extraInfoTable) lookup(id network.RequestID) (requestExtra, bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	e, ok := t.reqs[id]
+	if !ok {
+		return requestExtra{}, false
+	}
+	return *e, true
+}
+
+// extraInfoOf 返回目标中请求的附加信息
+func (m *Manager) extraInfoOf(target model.TargetID, id network.RequestID) (requestExtra, bool) {
+	v, ok := m.extraInfos.Load(target)
+	if !ok || id == "" {
+		return requestExtra{}, false
+	}
+	return v.(*extraInfoTable).lookup(id)
+}
+
+// blockedCookies 返回请求中被浏览器阻止的 Cookie（小写名称 -> 原因），供 cookieBlocked 条件使用；
+// 响应阶段且有规则使用该条件时，先等待响应附加信息到达
+func (m *Manager) blockedCookies(target model.TargetID, ev *fetch.RequestPausedReply) map[string][]string {
+	id := networkID(ev)
+	if v, ok := m.extraInfos.Load(target); ok && id != "" && ev.ResponseStatusCode != nil && usesCookieBlocked(m.currentConfig()) {
+		v.(*extraInfoTable).waitResponse(id, extraInfoWait)
+	}
+	extra, ok := m.extraInfoOf(target, id)
+	if !ok || len(extra.requestBlocked)+len(extra.responseBlocked) == 0 {
+		return nil
+	}
+	out := make(map[string][]string)
+	for _, list := range [][]model.BlockedCookie{extra.requestBlocked, extra.responseBlocked} {
+		for _, c := range list {
+			key := strings.ToLower(c.Name)
+			out[key] = append(out[key], c.Reasons...)
+		}
+	}
+	return out
+}
+
+// usesCookieBlocked 判断响应阶段的启用规则中是否有 cookieBlocked 条件
+func usesCookieBlocked(cfg *rulespec.Config) bool {
+	for _, r := range cfg.EvaluationOrder(rulespec.StageResponse) {
+		for _, list := range [][]rulespec.Condition{r.Match.AllOf, r.Match.AnyOf} {
+			for _, c := range list {
+				if c.Type == rulespec.ConditionCookieBlocked {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
+
+// withExtraInfo 为事件补充浏览器上报的原始头部和被阻止的 Cookie；请求阶段的事件通常早于请求实际发送，
+// 只有等待请求完成后发送的事件（全量捕获、耗时记录）才能带上完整信息
+func (m *Manager) withExtraInfo(evt model.InterceptEvent) model.InterceptEvent {
+	var e *model.NetworkEvent
+	switch {
+	case evt.Matched != nil:
+		matched := *evt.Matched
+		evt.Matched = &matched
+		e = &matched.NetworkEvent
+	case evt.Unmatched != nil:
+		unmatched := *evt.Unmatched
+		evt.Unmatched = &unmatched
+		e = &unmatched.NetworkEvent
+	default:
+		return evt
+	}
+	extra, ok := m.extraInfoOf(e.Target, network.RequestID(e.NetworkID))
+	if !ok {
+		return evt
+	}
+	r := m.currentRedactor()
+	if e.Request.RawHeaders == "" {
+		e.Request.RawHeaders = redactRawHeaders(r, extra.requestHeaders)
+	}
+	if e.Request.BlockedCookies == nil {
+		e.Request.BlockedCookies = extra.requestBlocked
+	}
+	if e.Stage != string(rulespec.StageRequest) {
+		if e.Response.RawHeaders == "" {
+			e.Response.RawHeaders = redactRawHeaders(r, extra.responseHeaders)
+		}
+		if e.Response.BlockedCookies == nil {
+			e.Response.BlockedCookies = extra.responseBlocked
+		}
+	}
+	return evt
+}
+
+// headerLines 将 CDP 头部还原为按名称排序的 "Name: value" 行，同名头部的多个值（以换行分隔）各占一行
+func headerLines(raw network.Headers) string {
+	var headers map[string]string
+	if err := json.Unmarshal(raw, &headers); err != nil || len(headers) == 0 {
+		return ""
+	}
+	names := make([]string, 0, len(headers))
+	for name := range headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var b strings.Builder
+	for _, name := range names {
+		for _, v := range strings.Split(headers[name], "\n") {
+			if b.Len() > 0 {
+				b.WriteByte('\n')
+			}
+			b.WriteString(name + ": " + v)
+		}
+	}
+	return b.String()
+}
+
+// redactRawHeaders 对原始头部行脱敏：认证头以掩码记录，其余内容中的密钥值替换为掩码
+func redactRawHeaders(r *strings.Replacer, raw string) string {
+	if raw == "" {
+		return ""
+	}
+	lines := strings.Split(raw, "\n")
+	for i, line := range lines {
+		name, value, ok := strings.Cut(line, ":")
+		if ok && (strings.EqualFold(name, authorizationHeader) || strings.EqualFold(name, "Proxy-Authorization")) {
+			lines[i] = name + ": " + maskSecret(strings.TrimSpace(value))
+		} else if r != nil {
+			lines[i] = r.Replace(line)
+		}
+	}
+	return strings.Join(lines, "\n")
+}
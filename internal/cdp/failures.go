@@ -0,0 +1,92 @@
+package cdp
+
+import (
+	"github.com/mafredri/cdp"
+	"github.com/mafredri/cdp/protocol/network"
+
+	"cdpnetool/pkg/model"
+)
+
+// consumeFailures 订阅 Network 域的请求生命周期事件，记录在网络层失败或被取消的请求（CORS、DNS、连接中断等）；
+// 这些请求可能从未暂停，拦截流程看不到。全量捕获模式下由 consumeCapture 一并记录
+func (m *Manager) consumeFailures(ts *targetSession) {
+	sent, err := ts.client.Network.RequestWillBeSent(ts.ctx)
+	if err != nil {
+		m.log.Err(err, "订阅请求发送事件失败", "target", string(ts.id))
+		return
+	}
+	finished, err := ts.client.Network.LoadingFinished(ts.ctx)
+	if err != nil {
+		sent.Close()
+		m.log.Err(err, "订阅加载完成事件失败", "target", string(ts.id))
+		return
+	}
+	failed, err := ts.client.Network.LoadingFailed(ts.ctx)
+	if err != nil {
+		sent.Close()
+		finished.Close()
+		m.log.Err(err, "订阅加载失败事件失败", "target", string(ts.id))
+		return
+	}
+	if err := cdp.Sync(sent, finished, failed); err != nil {
+		m.log.Err(err, "同步 Network 事件流失败", "target", string(ts.id))
+	}
+
+	go func() {
+		defer sent.Close()
+		defer finished.Close()
+		defer failed.Close()
+
+		pending := make(map[network.RequestID]*capturedRequest)
+		for {
+			select {
+			case <-ts.ctx.Done():
+				return
+			case <-sent.Ready():
+				ev, err := sent.Recv()
+				if err != nil {
+					return
+				}
+				// 重定向沿用同一个 RequestID，只保留最后一跳
+				if _, ok := pending[ev.RequestID]; !ok && (len(pending) >= captureMaxPending || !isCapturableURL(ev.Request.URL)) {
+					continue
+				}
+				pending[ev.RequestID] = newCapturedRequest(ev)
+			case <-finished.Ready():
+				ev, err := finished.Recv()
+				if err != nil {
+					return
+				}
+				delete(pending, ev.RequestID)
+				ts.reported.Delete(ev.RequestID)
+			case <-failed.Ready():
+				ev, err := failed.Recv()
+				if err != nil {
+					return
+				}
+				c, ok := pending[ev.RequestID]
+				delete(pending, ev.RequestID)
+				if !ok {
+					ts.reported.Delete(ev.RequestID)
+					continue
+				}
+				c.failure = loadFailureOf(ev)
+				m.log.Debug("请求在网络层失败", "url", c.request.URL, "error", ev.ErrorText)
+				m.sendCaptured(ts, ev.RequestID, c)
+			}
+		}
+	}()
+}
+
+// loadFailureOf 将 Network.loadingFailed 事件转换为失败原因
+func loadFailureOf(ev *network.LoadingFailedReply) *model.LoadFailure {
+	f := &model.LoadFailure{
+		ErrorText:     ev.ErrorText,
+		Canceled:      ev.Canceled != nil && *ev.Canceled,
+		BlockedReason: string(ev.BlockedReason),
+	}
+	if ev.CORSErrorStatus != nil {
+		f.CORSError = string(ev.CORSErrorStatus.CORSError)
+	}
+	return f
+}
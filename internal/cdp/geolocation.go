@@ -0,0 +1,62 @@
+package cdp
+
+import (
+	"context"
+	"time"
+
+	"github.com/mafredri/cdp/devtool"
+	"github.com/mafredri/cdp/protocol/emulation"
+
+	"cdpnetool/pkg/model"
+)
+
+// SetGeolocation 设置会话级地理位置覆盖并立即应用到所有已附加的页面目标，g 为 nil 时恢复实际位置；
+// 与拦截开关无关，页面仍需获得定位权限
+func (m *Manager) SetGeolocation(g *model.Geolocation) {
+	m.stateMu.Lock()
+	m.geolocation = g
+	m.stateMu.Unlock()
+
+	m.targetsMu.Lock()
+	defer m.targetsMu.Unlock()
+	for _, ts := range m.targets {
+		m.applyGeolocation(ts)
+	}
+}
+
+// currentGeolocation 返回当前地理位置覆盖
+func (m *Manager) currentGeolocation() *model.Geolocation {
+	m.stateMu.RLock()
+	defer m.stateMu.RUnlock()
+	return m.geolocation
+}
+
+// applyGeolocation 将地理位置覆盖下发到目标；Emulation 域仅页面目标可用，未覆盖过的目标无需清除
+func (m *Manager) applyGeolocation(ts *targetSession) {
+	if ts == nil || ts.client == nil || ts.client.Emulation == nil || ts.kind != devtool.Page {
+		return
+	}
+	g := m.currentGeolocation()
+	if g == nil && !ts.geoApplied {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(ts.ctx, 2*time.Second)
+	defer cancel()
+	if g == nil {
+		if err := ts.client.Emulation.ClearGeolocationOverride(ctx); err != nil {
+			m.log.Err(err, "清除地理位置覆盖失败", "target", string(ts.id))
+			return
+		}
+		ts.geoApplied = false
+		m.log.Debug("已清除地理位置覆盖", "target", string(ts.id))
+		return
+	}
+	args := emulation.NewSetGeolocationOverrideArgs().SetLatitude(g.Latitude).SetLongitude(g.Longitude).SetAccuracy(g.Accuracy)
+	if err := ts.client.Emulation.SetGeolocationOverride(ctx, args); err != nil {
+		m.log.Err(err, "设置地理位置覆盖失败", "target", string(ts.id))
+		return
+	}
+	ts.geoApplied = true
+	m.log.Debug("已应用地理位置覆盖", "target", string(ts.id), "latitude", g.Latitude, "longitude", g.Longitude)
+}
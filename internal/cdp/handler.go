@@ -2,11 +2,17 @@ package cdp
 
 import (
 	"context"
-	"encoding/json"
+	"errors"
+	"strconv"
+	"strings"
 	"time"
 
+	"github.com/mafredri/cdp/devtool"
 	"github.com/mafredri/cdp/protocol/fetch"
+	"github.com/mafredri/cdp/protocol/network"
 
+	"cdpnetool/internal/analyzer"
+	"cdpnetool/internal/mutation"
 	"cdpnetool/internal/rules"
 	"cdpnetool/pkg/model"
 	"cdpnetool/pkg/rulespec"
@@ -14,13 +20,9 @@ import (
 
 // handle 处理一次拦截事件并根据规则执行相应动作
 func (m *Manager) handle(ts *targetSession, ev *fetch.RequestPausedReply) {
-	to := m.processTimeoutMS
-	if to <= 0 {
-		to = 3000
-	}
-
-	ctx, cancel := context.WithTimeout(ts.ctx, time.Duration(to)*time.Millisecond)
+	ctx, cancel := context.WithTimeout(ts.ctx, m.processTimeout())
 	defer cancel()
+	defer ts.takenBodies.Delete(ev.RequestID)
 	start := time.Now()
 
 	// 判断阶段
@@ -29,64 +31,125 @@ func (m *Manager) handle(ts *targetSession, ev *fetch.RequestPausedReply) {
 	if ev.ResponseStatusCode != nil {
 		stage = rulespec.StageResponse
 		statusCode = *ev.ResponseStatusCode
+		// 已收到最终响应，认证质询不会再出现；401/407 之后可能还有质询，需保留记录以识别被拒绝的凭据
+		if statusCode != 401 && statusCode != 407 {
+			ts.authTried.Delete(ev.RequestID)
+		}
 	}
 
 	m.log.Debug("开始处理拦截事件", "stage", stage, "url", ev.Request.URL, "method", ev.Request.Method)
 
+	// 属于屏蔽的资源类别，不经规则处理直接失败
+	if m.blockResource(ctx, ts, ev) {
+		return
+	}
+
 	// 构建评估上下文（基于请求信息）
-	evalCtx := m.buildEvalContext(ev)
+	evalCtx := m.buildEvalContext(ts.id, ev)
 
-	// 评估匹配规则
-	if m.engine == nil {
-		// 无引擎，发送未匹配事件并放行
+	// 主机不在允许范围内，不经规则处理直接放行
+	if !m.isHostPermitted(ev.Request.URL) {
 		m.sendUnmatchedEvent(ts.id, ev, stage, statusCode)
-		m.executor.ContinueRequest(ctx, ts, ev)
+		if stage == rulespec.StageRequest {
+			m.executor.ContinueRequest(ctx, ts, ev)
+		} else {
+			m.executor.ContinueResponse(ctx, ts, ev)
+		}
+		m.log.Debug("主机不在允许修改范围内，直接放行", "url", ev.Request.URL)
 		return
 	}
 
-	matchedRules := m.engine.EvalForStage(evalCtx, stage)
+	// 中间件可在规则评估前放行或拒绝请求
+	if m.beforeEval(ctx, ts, ev, stage, statusCode) {
+		return
+	}
+
+	// 临时 Mock 优先于规则
+	matchedRules := m.quickMockFor(ts.id, ev, stage)
+
+	// 评估匹配规则
+	if matchedRules == nil && m.engine != nil {
+		matchedRules = m.engine.EvalForStage(evalCtx, stage)
+	}
+	if m.afterEval(ctx, ts, ev, stage, statusCode, &matchedRules) {
+		return
+	}
 	if len(matchedRules) == 0 {
 		// 未匹配，发送未匹配事件并放行
+		if stage == rulespec.StageResponse && m.observesAllResponses() {
+			m.observeResponse(ts.id, ev, m.getResponseBody(ctx, ts, ev))
+		}
 		m.sendUnmatchedEvent(ts.id, ev, stage, statusCode)
 		if stage == rulespec.StageRequest {
-			m.executor.ContinueRequest(ctx, ts, ev)
+			m.continueRequestWithCredentials(ctx, ts, ev)
 		} else {
-			m.executor.ContinueResponse(ctx, ts, ev)
+			m.continueResponseWithPolicy(ctx, ts, ev)
 		}
 		m.log.Debug("拦截事件处理完成，无匹配规则", "stage", stage, "duration", time.Since(start))
 		return
 	}
 
-	// 有匹配规则 - 捕获原始数据
-	requestInfo, responseInfo := m.captureOriginalData(ts, ev, stage)
+	// 有匹配规则 - 全量捕获不再重复记录该请求
+	m.markReported(ts, ev)
+
+	// 捕获原始数据
+	requestInfo, responseInfo, responseBody, err := m.captureOriginalData(ctx, ts, ev, stage, matchedRules)
+	if err != nil {
+		if errors.Is(err, errStreamNotTaken) {
+			// 响应体仍在浏览器中，放弃规则处理原样放行，避免请求一直挂起
+			m.log.Err(err, "获取响应体流失败，原样放行", "url", ev.Request.URL)
+			m.continueResponseWithPolicy(ctx, ts, ev)
+			return
+		}
+		// 响应体已被取走，TakeResponseBody 已使请求失败
+		m.log.Err(err, "流式读取响应体失败", "url", ev.Request.URL)
+		return
+	}
+	if _, taken := ts.takenBodies.Load(ev.RequestID); taken {
+		// 大响应体的读取耗时不计入处理超时
+		ctx, cancel = context.WithTimeout(ts.ctx, m.processTimeout())
+		defer cancel()
+	}
+	if m.deadlineExceeded(ctx, ts, ev) {
+		return
+	}
+	if stage == rulespec.StageResponse {
+		if !responseBody.IsBinary {
+			m.observeSchema(ts.id, ev, responseBody.Text())
+		}
+		m.observeContent(ts.id, ev, responseBody.Data)
+	}
+
+	// 只读捕获模式：记录匹配结果后原样放行，不执行任何行为
+	if m.captureOnly {
+		m.sendMatchedEvent(ts.id, ev, "passed", buildRuleMatches(matchedRules), requestInfo, responseInfo, nil)
+		if stage == rulespec.StageRequest {
+			m.executor.ContinueRequest(ctx, ts, ev)
+		} else {
+			m.executor.ContinueResponse(ctx, ts, ev)
+		}
+		m.log.Debug("只读捕获模式，跳过规则行为", "stage", stage, "url", ev.Request.URL)
+		return
+	}
 
 	// 执行所有匹配规则的行为（aggregate 模式）
 	if stage == rulespec.StageRequest {
 		m.executeRequestStageWithTracking(ctx, ts, ev, matchedRules, requestInfo, responseInfo, start)
 	} else {
-		m.executeResponseStageWithTracking(ctx, ts, ev, matchedRules, requestInfo, responseInfo, start)
+		m.executeResponseStageWithTracking(ctx, ts, ev, matchedRules, requestInfo, responseInfo, responseBody, start)
 	}
 }
 
-// captureOriginalData 捕获原始请求/响应数据
-func (m *Manager) captureOriginalData(ts *targetSession, ev *fetch.RequestPausedReply, stage rulespec.Stage) (model.RequestInfo, model.ResponseInfo) {
-	requestInfo := model.RequestInfo{
-		URL:          ev.Request.URL,
-		Method:       ev.Request.Method,
-		Headers:      make(map[string]string),
-		ResourceType: string(ev.ResourceType),
-	}
-
-	// 解析请求头
-	_ = json.Unmarshal(ev.Request.Headers, &requestInfo.Headers)
-
-	// 获取请求体
-	requestInfo.Body = GetRequestBody(ev)
+// captureOriginalData 捕获原始请求/响应数据，并返回二进制安全的原始响应体。
+// 响应体按读取计划读取：超过 Body 大小阈值的响应体仅在命中规则需要读取时以流方式读取，否则不读取
+func (m *Manager) captureOriginalData(ctx context.Context, ts *targetSession, ev *fetch.RequestPausedReply, stage rulespec.Stage, matched []*rules.MatchedRule) (model.RequestInfo, model.ResponseInfo, BodyContent, error) {
+	requestInfo := requestInfoOf(ev)
 
 	// 响应信息
 	responseInfo := model.ResponseInfo{
 		Headers: make(map[string]string),
 	}
+	var responseBody BodyContent
 
 	if stage == rulespec.StageResponse {
 		if ev.ResponseStatusCode != nil {
@@ -96,11 +159,45 @@ func (m *Manager) captureOriginalData(ts *targetSession, ev *fetch.RequestPaused
 		for _, h := range ev.ResponseHeaders {
 			responseInfo.Headers[h.Name] = h.Value
 		}
-		// 响应体需要单独获取
-		responseInfo.Body, _ = m.executor.FetchResponseBody(ts.ctx, ts, ev.RequestID)
+		// 响应体需要单独获取，按读取计划跳过规则用不到的二进制或超大响应
+		// 未读取的 Body 标记为 Unread，依赖原始内容的行为据此跳过，不会以空内容覆盖真实响应体
+		need, planned := m.responseBodyNeed(matched)
+		switch {
+		case !shouldGetBody(responseContentType(ev), responseContentLength(ev), need):
+			responseInfo.BodyTruncated = m.isLargeBody(ev)
+			responseBody.Unread = true
+		case !m.isLargeBody(ev):
+			body, ok := m.executor.FetchResponseBody(ctx, ts, ev)
+			if !ok {
+				body.Unread = true
+			}
+			responseBody = body
+		case planned && m.canStreamBody(ev):
+			body, err := m.executor.TakeResponseBody(ts, ev, m.streamBodyLimit)
+			if err != nil {
+				return requestInfo, responseInfo, BodyContent{}, err
+			}
+			responseBody = body
+		default:
+			responseInfo.BodyTruncated = true
+			responseBody.Unread = true
+		}
+		if len(responseBody.Data) > 0 {
+			responseInfo.Body, responseInfo.BodyEncoding, responseInfo.BodyTruncated = m.eventBody(responseBody)
+		}
 	}
 
-	return requestInfo, responseInfo
+	return requestInfo, responseInfo, responseBody, nil
+}
+
+// eventBody 返回事件中记录的 Body，超过 Body 大小阈值时只记录前缀
+func (m *Manager) eventBody(b BodyContent) (string, string, bool) {
+	truncated := m.bodySizeThreshold > 0 && int64(len(b.Data)) > m.bodySizeThreshold
+	if truncated {
+		b.Data = b.Data[:m.bodySizeThreshold]
+	}
+	body, encoding := b.EventBody()
+	return body, encoding, truncated
 }
 
 // buildRuleMatches 构建规则匹配信息列表
@@ -132,7 +229,11 @@ func (m *Manager) executeRequestStageWithTracking(
 	start time.Time,
 ) {
 	var aggregatedMut *RequestMutation
+	var steps []model.BodyTransform
+	merger := mutation.NewMerger(m.currentConfig().ConflictPolicy())
 	ruleMatches := buildRuleMatches(matchedRules)
+	// Body 按规则优先级依次变换，每条规则基于前序规则的结果
+	requestBody := requestBodyContent(ev)
 
 	for _, matched := range matchedRules {
 		rule := matched.Rule
@@ -141,48 +242,134 @@ func (m *Manager) executeRequestStageWithTracking(
 		}
 
 		// 执行当前规则的所有行为
-		mut := m.executor.ExecuteRequestActions(rule.Actions, ev)
+		mut := m.executor.ExecuteRequestActions(ctx, rule.Actions, ev, requestBody, m.templateVars(matched.Params))
+		if m.deadlineExceeded(ctx, ts, ev) {
+			return
+		}
 		if mut == nil {
 			continue
 		}
+		m.storeVars(ts.id, ev.Request.URL, mut.Captures)
+		steps = append(steps, bodySteps(mut.BodySteps, rule)...)
+		if mut.Body != nil {
+			requestBody = *mut.Body
+		}
+		// 终结性行为同样等待已命中的 delay 行为
+		delay := mut.Delay
+		if aggregatedMut != nil {
+			delay += aggregatedMut.Delay
+		}
 
 		// 检查是否是终结性行为（block）
 		if mut.Block != nil {
-			m.executor.ApplyRequestMutation(ctx, ts, ev, mut)
+			if len(mut.Block.Body) > 0 && m.shouldBypassRange(ev) {
+				m.executor.ContinueRequest(ctx, ts, ev)
+				m.sendMatchedEvent(ts.id, ev, "passed", ruleMatches, requestInfo, responseInfo, steps)
+				m.log.Info("Range 请求跳过 Body 改写", "rule", rule.ID, "url", ev.Request.URL)
+				return
+			}
+			if m.bodyOverLimit(ts.id, ev.Request.URL, rulespec.StageRequest, len(mut.Block.Body)) {
+				m.deferApply(ctx, ts, delay, func(ctx context.Context) {
+					m.continueRequestWithCredentials(ctx, ts, ev)
+				})
+				m.sendMatchedEvent(ts.id, ev, "passed", ruleMatches, requestInfo, responseInfo, steps)
+				return
+			}
+			m.deferApply(ctx, ts, delay, func(ctx context.Context) {
+				m.executor.ApplyRequestMutation(ctx, ts, ev, mut)
+			})
+			if mut.Block.Redirect {
+				m.sendMatchedEvent(ts.id, ev, "modified", ruleMatches, requestInfo, model.ResponseInfo{
+					StatusCode: mut.Block.StatusCode,
+					Headers:    mut.Block.Headers,
+				}, steps)
+				m.log.Info("请求被重定向", "rule", rule.ID, "url", ev.Request.URL, "location", mut.Block.Headers["Location"])
+				return
+			}
 			// 发送 blocked 事件
-			m.sendMatchedEvent(ts.id, "blocked", ruleMatches, requestInfo, responseInfo)
+			m.sendMatchedEvent(ts.id, ev, "blocked", ruleMatches, requestInfo, responseInfo, steps)
 			m.log.Info("请求被阻止", "rule", rule.ID, "url", ev.Request.URL)
 			return
 		}
 
+		// 终止行为（延时失败）
+		if mut.Terminate != nil {
+			mut.Terminate.After += delay
+			m.executor.ApplyTerminate(ts, ev, mut.Terminate)
+			m.sendMatchedEvent(ts.id, ev, "blocked", ruleMatches, requestInfo, responseInfo, steps)
+			m.log.Info("请求被终止", "rule", rule.ID, "url", ev.Request.URL, "after", mut.Terminate.After)
+			return
+		}
+
 		// 聚合变更
 		if aggregatedMut == nil {
-			aggregatedMut = mut
-		} else {
-			mergeRequestMutation(aggregatedMut, mut)
+			aggregatedMut = mutation.NewRequestMutation()
 		}
+		merger.MergeRequest(aggregatedMut, mut, matched)
 	}
 
-	// 应用聚合后的变更
-	var finalResult string
-	var modifiedRequestInfo model.RequestInfo
-	var modifiedResponseInfo model.ResponseInfo
+	// 规则变更相互矛盾且策略为放弃时，原样放行
+	if m.reportConflicts(ts.id, ev.Request.URL, merger) {
+		m.continueRequestWithCredentials(ctx, ts, ev)
+		m.sendMatchedEvent(ts.id, ev, "passed", ruleMatches, requestInfo, responseInfo, steps)
+		m.log.Info("规则变更冲突，放弃修改", "url", ev.Request.URL)
+		return
+	}
 
-	if aggregatedMut != nil && hasRequestMutation(aggregatedMut) {
-		m.executor.ApplyRequestMutation(ctx, ts, ev, aggregatedMut)
-		finalResult = "modified"
-		modifiedRequestInfo = m.captureModifiedRequestData(requestInfo, aggregatedMut)
-		modifiedResponseInfo = responseInfo
-	} else {
-		m.executor.ContinueRequest(ctx, ts, ev)
-		finalResult = "passed"
-		modifiedRequestInfo = requestInfo
-		modifiedResponseInfo = responseInfo
+	// 改写后的请求体过大时放弃全部修改
+	if aggregatedMut != nil && aggregatedMut.Body != nil && m.bodyOverLimit(ts.id, ev.Request.URL, rulespec.StageRequest, len(aggregatedMut.Body.Data)) {
+		m.deferApply(ctx, ts, aggregatedMut.Delay, func(ctx context.Context) {
+			m.continueRequestWithCredentials(ctx, ts, ev)
+		})
+		m.sendMatchedEvent(ts.id, ev, "passed", ruleMatches, requestInfo, responseInfo, steps)
+		return
+	}
+
+	// 按源注入认证信息，规则对 Authorization 的修改优先
+	if aggregatedMut == nil {
+		aggregatedMut = mutation.NewRequestMutation()
+	}
+	// 签名基于全部规则修改后的最终请求计算，签名写入的 Authorization 优先于源认证信息
+	m.executor.signRequest(ev, aggregatedMut)
+	m.injectCredentials(ev, aggregatedMut)
+	m.injectExtraHeaders(ev, aggregatedMut)
+
+	// 断点：人工确认后再应用变更
+	if aggregatedMut.Pause > 0 {
+		m.pauseRequest(ts, ev, aggregatedMut, ruleMatches, requestInfo, responseInfo, steps)
+		return
 	}
 
-	// 发送匹配事件
-	m.sendMatchedEvent(ts.id, finalResult, ruleMatches, modifiedRequestInfo, modifiedResponseInfo)
-	m.log.Debug("请求阶段处理完成", "result", finalResult, "duration", time.Since(start))
+	finalResult := m.applyRequestResult(ctx, ts, ev, aggregatedMut, ruleMatches, requestInfo, responseInfo, steps)
+	m.log.Debug("请求阶段处理完成", "result", finalResult, "delay", aggregatedMut.Delay, "duration", time.Since(start))
+}
+
+// applyRequestResult 应用聚合后的请求变更并发送匹配事件，返回处理结果
+func (m *Manager) applyRequestResult(
+	ctx context.Context,
+	ts *targetSession,
+	ev *fetch.RequestPausedReply,
+	mut *RequestMutation,
+	ruleMatches []model.RuleMatch,
+	requestInfo model.RequestInfo,
+	responseInfo model.ResponseInfo,
+	steps []model.BodyTransform,
+) string {
+	if result, done := m.beforeApplyRequest(ctx, ts, ev, mut, ruleMatches, requestInfo, responseInfo, steps); done {
+		return result
+	}
+	if !hasRequestMutation(mut) {
+		m.deferApply(ctx, ts, mut.Delay, func(ctx context.Context) {
+			m.executor.ContinueRequest(ctx, ts, ev)
+		})
+		m.sendMatchedEvent(ts.id, ev, "passed", ruleMatches, requestInfo, responseInfo, steps)
+		return "passed"
+	}
+	m.deferApply(ctx, ts, mut.Delay, func(ctx context.Context) {
+		m.executor.ApplyRequestMutation(ctx, ts, ev, mut)
+	})
+	m.sendMatchedEvent(ts.id, ev, "modified", ruleMatches, m.captureModifiedRequestData(requestInfo, mut), responseInfo, steps)
+	return "modified"
 }
 
 // executeResponseStageWithTracking 执行响应阶段的行为并跟踪变更
@@ -193,10 +380,13 @@ func (m *Manager) executeResponseStageWithTracking(
 	matchedRules []*rules.MatchedRule,
 	requestInfo model.RequestInfo,
 	responseInfo model.ResponseInfo,
+	originalBody BodyContent,
 	start time.Time,
 ) {
-	responseBody := responseInfo.Body
+	responseBody := originalBody
 	var aggregatedMut *ResponseMutation
+	var steps []model.BodyTransform
+	merger := mutation.NewMerger(m.currentConfig().ConflictPolicy())
 	ruleMatches := buildRuleMatches(matchedRules)
 
 	for _, matched := range matchedRules {
@@ -206,17 +396,33 @@ func (m *Manager) executeResponseStageWithTracking(
 		}
 
 		// 执行当前规则的所有行为
-		mut := m.executor.ExecuteResponseActions(rule.Actions, ev, responseBody)
+		mut := m.executor.ExecuteResponseActions(ctx, rule.Actions, ev, responseBody, m.templateVars(matched.Params))
+		if m.deadlineExceeded(ctx, ts, ev) {
+			return
+		}
 		if mut == nil {
 			continue
 		}
+		m.storeVars(ts.id, ev.Request.URL, mut.Captures)
+		steps = append(steps, bodySteps(mut.BodySteps, rule)...)
+
+		// 终止行为（截断或延时失败），同样等待已命中的 delay 行为
+		if mut.Terminate != nil {
+			mut.Terminate.After += mut.Delay
+			if aggregatedMut != nil {
+				mut.Terminate.After += aggregatedMut.Delay
+			}
+			m.executor.ApplyTerminate(ts, ev, mut.Terminate)
+			m.sendMatchedEvent(ts.id, ev, "blocked", ruleMatches, requestInfo, responseInfo, steps)
+			m.log.Info("响应被终止", "rule", rule.ID, "url", ev.Request.URL, "truncated", mut.Terminate.Truncated)
+			return
+		}
 
 		// 聚合变更
 		if aggregatedMut == nil {
-			aggregatedMut = mut
-		} else {
-			mergeResponseMutation(aggregatedMut, mut)
+			aggregatedMut = mutation.NewResponseMutation()
 		}
+		merger.MergeResponse(aggregatedMut, mut, matched)
 
 		// 更新 responseBody 供后续规则使用
 		if mut.Body != nil {
@@ -224,26 +430,93 @@ func (m *Manager) executeResponseStageWithTracking(
 		}
 	}
 
-	// 应用聚合后的变更
-	var finalResult string
+	// 规则变更相互矛盾且策略为放弃时，原样放行
+	if m.reportConflicts(ts.id, ev.Request.URL, merger) {
+		m.continueResponseWithPolicy(ctx, ts, ev)
+		m.sendMatchedEvent(ts.id, ev, "passed", ruleMatches, requestInfo, responseInfo, steps)
+		m.log.Info("规则变更冲突，放弃修改", "url", ev.Request.URL)
+		return
+	}
+
+	// Range 请求按策略跳过 Body 改写，仅保留状态码和头部修改
+	bypassBody := m.shouldBypassRange(ev)
+	if bypassBody && aggregatedMut != nil && aggregatedMut.Body != nil {
+		m.log.Info("Range 请求跳过 Body 改写", "url", ev.Request.URL)
+		aggregatedMut.Body = nil
+		responseBody = originalBody
+	}
 
-	if aggregatedMut != nil && hasResponseMutation(aggregatedMut) {
-		// 确保 Body 是最新的
-		if aggregatedMut.Body == nil && responseBody != "" {
-			aggregatedMut.Body = &responseBody
+	// 改写后的响应体过大时放弃全部修改
+	if aggregatedMut != nil && aggregatedMut.Body != nil && m.bodyOverLimit(ts.id, ev.Request.URL, rulespec.StageResponse, len(aggregatedMut.Body.Data)) {
+		m.deferApply(ctx, ts, aggregatedMut.Delay, func(ctx context.Context) {
+			m.continueResponseWithPolicy(ctx, ts, ev)
+		})
+		m.sendMatchedEvent(ts.id, ev, "passed", ruleMatches, requestInfo, responseInfo, steps)
+		return
+	}
+
+	// 会话级响应头策略，规则对同名响应头的修改优先
+	if p, ok := m.headerPolicyFor(ev); ok {
+		if aggregatedMut == nil {
+			aggregatedMut = mutation.NewResponseMutation()
 		}
-		m.executor.ApplyResponseMutation(ctx, ts, ev, aggregatedMut)
-		finalResult = "modified"
-		modifiedResponseInfo := m.captureModifiedResponseData(responseInfo, aggregatedMut, responseBody)
-		// 发送匹配事件
-		m.sendMatchedEvent(ts.id, finalResult, ruleMatches, requestInfo, modifiedResponseInfo)
-	} else {
-		m.executor.ContinueResponse(ctx, ts, ev)
-		finalResult = "passed"
-		// 发送匹配事件
-		m.sendMatchedEvent(ts.id, finalResult, ruleMatches, requestInfo, responseInfo)
+		applyHeaderPolicy(p, aggregatedMut)
 	}
-	m.log.Debug("响应阶段处理完成", "result", finalResult, "duration", time.Since(start))
+
+	if aggregatedMut == nil {
+		aggregatedMut = mutation.NewResponseMutation()
+	}
+
+	// 断点：人工确认后再应用变更
+	if aggregatedMut.Pause > 0 {
+		m.pauseResponse(ts, ev, aggregatedMut, responseBody, bypassBody, ruleMatches, requestInfo, responseInfo, steps)
+		return
+	}
+
+	finalResult := m.applyResponseResult(ctx, ts, ev, aggregatedMut, responseBody, bypassBody, ruleMatches, requestInfo, responseInfo, steps)
+	m.log.Debug("响应阶段处理完成", "result", finalResult, "delay", aggregatedMut.Delay, "duration", time.Since(start))
+}
+
+// applyResponseResult 应用聚合后的响应变更并发送匹配事件，返回处理结果
+func (m *Manager) applyResponseResult(
+	ctx context.Context,
+	ts *targetSession,
+	ev *fetch.RequestPausedReply,
+	mut *ResponseMutation,
+	responseBody BodyContent,
+	bypassBody bool,
+	ruleMatches []model.RuleMatch,
+	requestInfo model.RequestInfo,
+	responseInfo model.ResponseInfo,
+	steps []model.BodyTransform,
+) string {
+	result, replaced := m.beforeApplyResponse(ctx, ts, ev, mut, responseBody, ruleMatches, requestInfo, responseInfo, steps)
+	if result != "" {
+		return result
+	}
+	if replaced {
+		responseBody, bypassBody = *mut.Body, false
+	}
+	if !hasResponseMutation(mut) {
+		delay := mut.Delay + throttleDelay(ev, mut, responseBody, bypassBody)
+		m.deferApply(ctx, ts, delay, func(ctx context.Context) {
+			m.executor.ContinueResponse(ctx, ts, ev)
+		})
+		m.sendMatchedEvent(ts.id, ev, "passed", ruleMatches, requestInfo, responseInfo, steps)
+		return "passed"
+	}
+	// 确保 Body 是最新的
+	if mut.Body == nil && len(responseBody.Data) > 0 && !bypassBody {
+		mut.Body = &responseBody
+	}
+	m.compressResponse(ev, mut)
+	// 限速按最终下发的 Body 计算传输时间，计入放行前的等待
+	delay := mut.Delay + throttleDelay(ev, mut, responseBody, bypassBody)
+	m.deferApply(ctx, ts, delay, func(ctx context.Context) {
+		m.executor.ApplyResponseMutation(ctx, ts, ev, mut)
+	})
+	m.sendMatchedEvent(ts.id, ev, "modified", ruleMatches, requestInfo, m.captureModifiedResponseData(responseInfo, mut, responseBody), steps)
+	return "modified"
 }
 
 // captureModifiedRequestData 捕获修改后的请求数据
@@ -254,6 +527,7 @@ func (m *Manager) captureModifiedRequestData(original model.RequestInfo, mut *Re
 		ResourceType: original.ResourceType,
 		Headers:      make(map[string]string),
 		Body:         original.Body,
+		BodyEncoding: original.BodyEncoding,
 	}
 
 	// 复制原始 headers
@@ -273,22 +547,30 @@ func (m *Manager) captureModifiedRequestData(original model.RequestInfo, mut *Re
 	for k, v := range mut.Headers {
 		modified.Headers[k] = v
 	}
+	for k, v := range mut.SecretHeaders {
+		for h := range modified.Headers {
+			if strings.EqualFold(h, k) {
+				delete(modified.Headers, h)
+			}
+		}
+		modified.Headers[k] = maskSecret(v)
+	}
 
 	// 应用 body 修改
 	if mut.Body != nil {
-		modified.Body = *mut.Body
+		modified.Body, modified.BodyEncoding = mut.Body.EventBody()
 	}
 
 	return modified
 }
 
 // captureModifiedResponseData 捕获修改后的响应数据
-func (m *Manager) captureModifiedResponseData(original model.ResponseInfo, mut *ResponseMutation, finalBody string) model.ResponseInfo {
+func (m *Manager) captureModifiedResponseData(original model.ResponseInfo, mut *ResponseMutation, finalBody BodyContent) model.ResponseInfo {
 	modified := model.ResponseInfo{
 		StatusCode: original.StatusCode,
 		Headers:    make(map[string]string),
-		Body:       finalBody,
 	}
+	modified.Body, modified.BodyEncoding, modified.BodyTruncated = m.eventBody(finalBody)
 
 	// 复制原始 headers
 	for k, v := range original.Headers {
@@ -308,58 +590,16 @@ func (m *Manager) captureModifiedResponseData(original model.ResponseInfo, mut *
 		modified.Headers[k] = v
 	}
 
-	return modified
-}
-
-// mergeRequestMutation 合并请求变更
-func mergeRequestMutation(dst, src *RequestMutation) {
-	if src.URL != nil {
-		dst.URL = src.URL
-	}
-	if src.Method != nil {
-		dst.Method = src.Method
-	}
-	for k, v := range src.Headers {
-		if dst.Headers == nil {
-			dst.Headers = make(map[string]string)
-		}
-		dst.Headers[k] = v
-	}
-	for k, v := range src.Query {
-		if dst.Query == nil {
-			dst.Query = make(map[string]string)
+	// 改写 Body 时发出的是解压后的内容，与 normalizeDecodedBody 保持一致
+	if mut.Body != nil && !setsContentEncoding(mut.Headers) {
+		for k := range modified.Headers {
+			if strings.EqualFold(k, "Content-Encoding") {
+				delete(modified.Headers, k)
+			}
 		}
-		dst.Query[k] = v
 	}
-	for k, v := range src.Cookies {
-		if dst.Cookies == nil {
-			dst.Cookies = make(map[string]string)
-		}
-		dst.Cookies[k] = v
-	}
-	dst.RemoveHeaders = append(dst.RemoveHeaders, src.RemoveHeaders...)
-	dst.RemoveQuery = append(dst.RemoveQuery, src.RemoveQuery...)
-	dst.RemoveCookies = append(dst.RemoveCookies, src.RemoveCookies...)
-	if src.Body != nil {
-		dst.Body = src.Body
-	}
-}
 
-// mergeResponseMutation 合并响应变更
-func mergeResponseMutation(dst, src *ResponseMutation) {
-	if src.StatusCode != nil {
-		dst.StatusCode = src.StatusCode
-	}
-	for k, v := range src.Headers {
-		if dst.Headers == nil {
-			dst.Headers = make(map[string]string)
-		}
-		dst.Headers[k] = v
-	}
-	dst.RemoveHeaders = append(dst.RemoveHeaders, src.RemoveHeaders...)
-	if src.Body != nil {
-		dst.Body = src.Body
-	}
+	return modified
 }
 
 // hasRequestMutation 检查请求变更是否有效
@@ -367,42 +607,61 @@ func hasRequestMutation(m *RequestMutation) bool {
 	return m.URL != nil || m.Method != nil ||
 		len(m.Headers) > 0 || len(m.Query) > 0 || len(m.Cookies) > 0 ||
 		len(m.RemoveHeaders) > 0 || len(m.RemoveQuery) > 0 || len(m.RemoveCookies) > 0 ||
-		m.Body != nil
+		len(m.SecretHeaders) > 0 || m.Body != nil
 }
 
 // hasResponseMutation 检查响应变更是否有效
 func hasResponseMutation(m *ResponseMutation) bool {
-	return m.StatusCode != nil || len(m.Headers) > 0 || len(m.RemoveHeaders) > 0 || m.Body != nil
+	return m.StatusCode != nil || len(m.Headers) > 0 || len(m.RemoveHeaders) > 0 || m.Body != nil || m.Compress != ""
 }
 
 // dispatchPaused 根据并发配置调度单次拦截事件处理
 func (m *Manager) dispatchPaused(ts *targetSession, ev *fetch.RequestPausedReply) {
+	ts.trackPaused(ev)
+	ts.pipe.begin()
+	run := func() {
+		defer ts.pipe.end()
+		if !m.track() {
+			// 会话正在关闭，原样放行
+			m.degradeAndContinue(ts, ev, "会话已关闭")
+			return
+		}
+		defer m.work.Done()
+		m.handle(ts, ev)
+	}
 	if m.pool == nil {
-		go m.handle(ts, ev)
+		go run()
 		return
 	}
-	submitted := m.pool.submit(func() {
-		m.handle(ts, ev)
-	})
-	if !submitted {
+	if !m.pool.submitHost(hostOf(ev.Request.URL), run) {
 		m.degradeAndContinue(ts, ev, "并发队列已满")
+		ts.pipe.end()
 	}
 }
 
-// consume 持续接收拦截事件并按并发限制分发处理
-func (m *Manager) consume(ts *targetSession) {
+// subscribePaused 订阅目标的拦截事件流，需在启用 Fetch 之前调用
+func (m *Manager) subscribePaused(ts *targetSession) (fetch.RequestPausedClient, error) {
 	rp, err := ts.client.Fetch.RequestPaused(ts.ctx)
 	if err != nil {
 		m.log.Err(err, "订阅拦截事件流失败", "target", string(ts.id))
-		m.handleTargetStreamClosed(ts, err)
-		return
+		return nil, err
 	}
+	ts.pipe.setStream(rp)
+	return rp, nil
+}
+
+// consume 持续接收拦截事件并按并发限制分发处理
+func (m *Manager) consume(ts *targetSession, rp fetch.RequestPausedClient) {
 	defer rp.Close()
 
 	m.log.Info("开始消费拦截事件流", "target", string(ts.id))
 	for {
 		ev, err := rp.Recv()
 		if err != nil {
+			// 看门狗主动关闭的旧事件流，新的消费协程已接管
+			if ts.pipe.restarting.Swap(false) {
+				return
+			}
 			m.log.Err(err, "接收拦截事件失败", "target", string(ts.id))
 			m.handleTargetStreamClosed(ts, err)
 			return
@@ -418,15 +677,30 @@ func (m *Manager) handleTargetStreamClosed(ts *targetSession, err error) {
 		return
 	}
 
-	m.log.Warn("拦截流被中断，自动移除目标", "target", string(ts.id), "error", err)
+	m.log.Warn("拦截流被中断，移除目标", "target", string(ts.id), "error", err)
 
 	m.targetsMu.Lock()
-	defer m.targetsMu.Unlock()
-
-	if cur, ok := m.targets[ts.id]; ok && cur == ts {
+	cur, ok := m.targets[ts.id]
+	removed := ok && cur == ts
+	if removed {
 		m.closeTargetSession(cur)
 		delete(m.targets, ts.id)
 	}
+	m.targetsMu.Unlock()
+
+	// worker 目标由发现流程重新附加，仅页面目标自动重连
+	if removed && ts.kind == devtool.Page {
+		go m.reconnectTarget(ts)
+	}
+}
+
+// deadlineExceeded 检查本次处理是否已超时，超时则降级放行，避免超时后再发出改写调用
+func (m *Manager) deadlineExceeded(ctx context.Context, ts *targetSession, ev *fetch.RequestPausedReply) bool {
+	if ctx.Err() == nil {
+		return false
+	}
+	m.degradeAndContinue(ts, ev, "处理超时")
+	return true
 }
 
 // degradeAndContinue 统一的降级处理：直接放行请求
@@ -445,50 +719,132 @@ func (m *Manager) degradeAndContinue(ts *targetSession, ev *fetch.RequestPausedR
 	m.sendUnmatchedEvent(ts.id, ev, stage, statusCode)
 }
 
-// sendMatchedEvent 发送匹配事件
+// sendMatchedEvent 发送匹配事件，ev 不为 nil 时记录网络请求 ID 与阶段以关联同一请求的两个阶段
 func (m *Manager) sendMatchedEvent(
 	target model.TargetID,
+	ev *fetch.RequestPausedReply,
 	finalResult string,
 	matchedRules []model.RuleMatch,
 	requestInfo model.RequestInfo,
 	responseInfo model.ResponseInfo,
+	steps []model.BodyTransform,
 ) {
+	// 事件会被展示、存储和推送，其中引用的密钥值一律脱敏
+	if r := m.currentRedactor(); r != nil {
+		requestInfo = redactRequestInfo(r, requestInfo)
+		responseInfo = redactResponseInfo(r, responseInfo)
+	}
 	evt := model.InterceptEvent{
 		IsMatched: true,
 		Matched: &model.MatchedEvent{
 			NetworkEvent: model.NetworkEvent{
-				Session:      "", // 会在上层填充
-				Target:       target,
-				Timestamp:    time.Now().UnixMilli(),
-				IsMatched:    true,
-				Request:      requestInfo,
-				Response:     responseInfo,
-				FinalResult:  finalResult,
-				MatchedRules: matchedRules,
+				Session:        "", // 会在上层填充
+				Target:         target,
+				Timestamp:      time.Now().UnixMilli(),
+				IsMatched:      true,
+				Request:        requestInfo,
+				Response:       responseInfo,
+				FinalResult:    finalResult,
+				MatchedRules:   matchedRules,
+				BodyTransforms: steps,
 			},
 		},
 	}
+	if ev != nil {
+		evt.Matched.NetworkID, evt.Matched.Stage = eventLink(ev)
+		evt.Matched.Preload = m.preloadKind(target, ev)
+		if conn, ok := m.connectionOf(target, ev.Request.URL); ok && ev.ResponseStatusCode != nil {
+			conn.apply(&evt.Matched.Response)
+		}
+	}
+
+	m.emit(evt)
+}
+
+// observeResponse 对未匹配的响应进行被动分析
+func (m *Manager) observeResponse(target model.TargetID, ev *fetch.RequestPausedReply, body string) {
+	m.observeSchema(target, ev, body)
+	m.observeContent(target, ev, []byte(body))
+}
+
+// observeContent 记录成功响应的响应体哈希，与上次不同时发送通知事件
+func (m *Manager) observeContent(target model.TargetID, ev *fetch.RequestPausedReply, body []byte) {
+	if m.contentTracker == nil || len(body) == 0 {
+		return
+	}
+	if status := getStatusCode(ev); status < 200 || status >= 300 {
+		return
+	}
 
-	select {
-	case m.events <- evt:
-	default:
+	endpoint := analyzer.EndpointKey(ev.Request.Method, ev.Request.URL)
+	change, ok := m.contentTracker.Observe(endpoint, body)
+	if !ok {
+		return
 	}
+	m.log.Warn("检测到响应内容变化", "endpoint", endpoint, "previous", change.Previous, "current", change.Current)
+	m.sendNotice(target, model.NoticeContentChanged, ev.Request.URL, "响应内容发生变化: "+endpoint, map[string]string{
+		"endpoint": endpoint,
+		"previous": change.Previous,
+		"current":  change.Current,
+		"size":     strconv.Itoa(len(body)),
+	})
+}
+
+// observeSchema 记录 JSON 响应结构，发生漂移时发送通知事件
+func (m *Manager) observeSchema(target model.TargetID, ev *fetch.RequestPausedReply, body string) {
+	if m.schemaTracker == nil || body == "" {
+		return
+	}
+	isJSON := false
+	for _, h := range ev.ResponseHeaders {
+		if strings.EqualFold(h.Name, "content-type") && strings.Contains(strings.ToLower(h.Value), "json") {
+			isJSON = true
+			break
+		}
+	}
+	if !isJSON {
+		return
+	}
+
+	endpoint := analyzer.EndpointKey(ev.Request.Method, ev.Request.URL)
+	drift, ok := m.schemaTracker.Observe(endpoint, body)
+	if !ok {
+		return
+	}
+	m.log.Warn("检测到 JSON 响应结构变化", "endpoint", endpoint, "added", len(drift.Added), "removed", len(drift.Removed), "changed", len(drift.Changed))
+	m.sendNotice(target, model.NoticeSchemaDrift, ev.Request.URL, "响应结构发生变化: "+endpoint, map[string]string{
+		"endpoint": endpoint,
+		"added":    strings.Join(drift.Added, ","),
+		"removed":  strings.Join(drift.Removed, ","),
+		"changed":  strings.Join(drift.Changed, ","),
+	})
+}
+
+// sendNotice 发送会话级通知事件
+func (m *Manager) sendNotice(target model.TargetID, kind model.NoticeKind, url, message string, details map[string]string) {
+	evt := model.InterceptEvent{
+		Notice: &model.NoticeEvent{
+			Session:   "", // 会在上层填充
+			Target:    target,
+			Timestamp: time.Now().UnixMilli(),
+			Kind:      kind,
+			URL:       url,
+			Message:   message,
+			Details:   details,
+		},
+	}
+
+	m.emit(evt)
 }
 
 // sendUnmatchedEvent 发送未匹配事件
 func (m *Manager) sendUnmatchedEvent(target model.TargetID, ev *fetch.RequestPausedReply, stage rulespec.Stage, statusCode int) {
-	requestInfo := model.RequestInfo{
-		URL:          ev.Request.URL,
-		Method:       ev.Request.Method,
-		Headers:      make(map[string]string),
-		ResourceType: string(ev.ResourceType),
+	// 全量捕获模式下未匹配请求由 Network 事件在加载完成后记录（含响应体）
+	if m.fullCapture {
+		return
 	}
 
-	// 解析请求头
-	_ = json.Unmarshal(ev.Request.Headers, &requestInfo.Headers)
-
-	// 获取请求体
-	requestInfo.Body = GetRequestBody(ev)
+	requestInfo := requestInfoOf(ev)
 
 	// 响应信息
 	responseInfo := model.ResponseInfo{
@@ -497,6 +853,9 @@ func (m *Manager) sendUnmatchedEvent(target model.TargetID, ev *fetch.RequestPau
 	}
 
 	if stage == rulespec.StageResponse {
+		if conn, ok := m.connectionOf(target, ev.Request.URL); ok {
+			conn.apply(&responseInfo)
+		}
 		// 响应头
 		for _, h := range ev.ResponseHeaders {
 			responseInfo.Headers[h.Name] = h.Value
@@ -517,16 +876,33 @@ func (m *Manager) sendUnmatchedEvent(target model.TargetID, ev *fetch.RequestPau
 				Target:    target,
 				Timestamp: time.Now().UnixMilli(),
 				IsMatched: false,
+				NetworkID: string(networkID(ev)),
+				Stage:     string(stage),
+				Preload:   m.preloadKind(target, ev),
 				Request:   requestInfo,
 				Response:  responseInfo,
 			},
 		},
 	}
 
-	select {
-	case m.events <- evt:
-	default:
+	m.emit(evt)
+}
+
+// eventLink 返回拦截事件的网络请求 ID 与所处阶段
+func eventLink(ev *fetch.RequestPausedReply) (string, string) {
+	stage := rulespec.StageRequest
+	if ev.ResponseStatusCode != nil {
+		stage = rulespec.StageResponse
+	}
+	return string(networkID(ev)), string(stage)
+}
+
+// networkID 返回拦截事件对应的网络请求 ID，未关联网络请求时为空
+func networkID(ev *fetch.RequestPausedReply) network.RequestID {
+	if ev.NetworkID == nil {
+		return ""
 	}
+	return *ev.NetworkID
 }
 
 // getStatusCode 获取响应状态码
@@ -0,0 +1,110 @@
+package cdp
+
+import (
+	"context"
+	"strings"
+
+	"github.com/mafredri/cdp/protocol/fetch"
+	"github.com/mafredri/cdp/protocol/network"
+
+	"cdpnetool/internal/mutation"
+	"cdpnetool/pkg/model"
+)
+
+// headerPolicyResourceTypes 响应头策略需要在响应阶段暂停的资源类型，最终按 Content-Type 判断是否生效
+var headerPolicyResourceTypes = []network.ResourceType{
+	network.ResourceTypeDocument, network.ResourceTypeXHR, network.ResourceTypeFetch,
+}
+
+// SetHeaderPolicy 设置会话级响应头策略，并按需更新响应阶段的拦截模式
+func (m *Manager) SetHeaderPolicy(p model.HeaderPolicy) {
+	m.stateMu.Lock()
+	m.headerPolicy = p
+	m.stateMu.Unlock()
+	m.refreshFetchPatterns()
+}
+
+// currentHeaderPolicy 返回当前响应头策略
+func (m *Manager) currentHeaderPolicy() model.HeaderPolicy {
+	m.stateMu.RLock()
+	defer m.stateMu.RUnlock()
+	return m.headerPolicy
+}
+
+// headerPolicyPatterns 返回响应头策略所需的拦截模式，未配置策略时返回 nil
+func (m *Manager) headerPolicyPatterns() []fetch.RequestPattern {
+	if m.currentHeaderPolicy().IsEmpty() {
+		return nil
+	}
+	out := make([]fetch.RequestPattern, 0, len(headerPolicyResourceTypes))
+	for _, rt := range headerPolicyResourceTypes {
+		rt := rt
+		out = append(out, fetch.RequestPattern{URLPattern: strPtr("*"), ResourceType: &rt, RequestStage: fetch.RequestStageResponse})
+	}
+	return out
+}
+
+// headerPolicyFor 返回对该响应生效的策略：仅作用于响应阶段的 HTML/JSON 响应，只读捕获模式下不生效
+func (m *Manager) headerPolicyFor(ev *fetch.RequestPausedReply) (model.HeaderPolicy, bool) {
+	if m.captureOnly || ev.ResponseStatusCode == nil {
+		return model.HeaderPolicy{}, false
+	}
+	p := m.currentHeaderPolicy()
+	if p.IsEmpty() || !isHTMLOrJSON(responseContentType(ev)) {
+		return model.HeaderPolicy{}, false
+	}
+	return p, true
+}
+
+// isHTMLOrJSON 判断 Content-Type 是否为 HTML 或 JSON
+func isHTMLOrJSON(ctype string) bool {
+	ctype = strings.ToLower(ctype)
+	return strings.Contains(ctype, "text/html") || strings.Contains(ctype, "application/xhtml") || strings.Contains(ctype, "json")
+}
+
+// applyHeaderPolicy 将策略合并到响应修改中，返回是否产生了修改；规则已设置或移除的同名响应头保持规则的结果
+func applyHeaderPolicy(p model.HeaderPolicy, mut *ResponseMutation) bool {
+	touched := func(name string) bool {
+		for k := range mut.Headers {
+			if strings.EqualFold(k, name) {
+				return true
+			}
+		}
+		for _, k := range mut.RemoveHeaders {
+			if strings.EqualFold(k, name) {
+				return true
+			}
+		}
+		return false
+	}
+
+	changed := false
+	for _, name := range p.Remove {
+		if !touched(name) {
+			mut.RemoveHeaders = append(mut.RemoveHeaders, name)
+			changed = true
+		}
+	}
+	for name, value := range p.Set {
+		if !touched(name) {
+			// 先移除大小写不同的同名原始响应头，避免重复
+			mut.RemoveHeaders = append(mut.RemoveHeaders, name)
+			mut.Headers[name] = value
+			changed = true
+		}
+	}
+	return changed
+}
+
+// continueResponseWithPolicy 放行响应，存在生效的响应头策略时附带策略修改
+func (m *Manager) continueResponseWithPolicy(ctx context.Context, ts *targetSession, ev *fetch.RequestPausedReply) {
+	if p, ok := m.headerPolicyFor(ev); ok {
+		mut := mutation.NewResponseMutation()
+		if applyHeaderPolicy(p, mut) {
+			m.executor.ApplyResponseMutation(ctx, ts, ev, mut)
+			m.log.Debug("已应用响应头策略", "url", ev.Request.URL)
+			return
+		}
+	}
+	m.executor.ContinueResponse(ctx, ts, ev)
+}
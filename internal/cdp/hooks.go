@@ -0,0 +1,221 @@
+package cdp
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/mafredri/cdp/protocol/fetch"
+	"github.com/mafredri/cdp/protocol/network"
+
+	"cdpnetool/internal/rules"
+	"cdpnetool/pkg/model"
+	"cdpnetool/pkg/rulespec"
+)
+
+// Use 在挂载点注册中间件，先注册的中间件位于外层。只作用于浏览器拦截的请求，内置代理的请求不经过中间件
+func (m *Manager) Use(point model.HookPoint, mw model.Middleware) {
+	if mw == nil {
+		return
+	}
+	m.stateMu.Lock()
+	defer m.stateMu.Unlock()
+	if m.hooks == nil {
+		m.hooks = make(map[model.HookPoint][]model.Middleware)
+	}
+	m.hooks[point] = append(m.hooks[point], mw)
+}
+
+// hookChain 组装挂载点上的中间件，未注册中间件时返回 nil
+func (m *Manager) hookChain(point model.HookPoint) model.HookHandler {
+	m.stateMu.RLock()
+	mws := m.hooks[point]
+	m.stateMu.RUnlock()
+	if len(mws) == 0 {
+		return nil
+	}
+	h := model.HookHandler(func(context.Context, *model.Exchange) {})
+	for i := len(mws) - 1; i >= 0; i-- {
+		h = mws[i](h)
+	}
+	return h
+}
+
+// runHook 执行中间件链，中间件 panic 时记录日志并改为原样放行，避免请求一直挂起
+func (m *Manager) runHook(ctx context.Context, h model.HookHandler, ex *model.Exchange) {
+	defer func() {
+		if r := recover(); r != nil {
+			m.log.Err(fmt.Errorf("%v", r), "中间件发生 panic，原样放行", "point", string(ex.Point), "url", ex.Request.URL)
+			ex.Decision = model.HookPass
+			ex.Edits = nil
+		}
+	}()
+	h(ctx, ex)
+}
+
+// newExchange 构建中间件处理的请求信息，响应阶段附带状态码与响应头；会话 ID 由上层填充
+func (m *Manager) newExchange(point model.HookPoint, target model.TargetID, ev *fetch.RequestPausedReply, stage rulespec.Stage) *model.Exchange {
+	ex := &model.Exchange{
+		Point:   point,
+		Target:  target,
+		Stage:   string(stage),
+		Request: requestInfoOf(ev),
+	}
+	if stage == rulespec.StageResponse {
+		ex.Response = model.ResponseInfo{StatusCode: getStatusCode(ev), Headers: make(map[string]string, len(ev.ResponseHeaders))}
+		for _, h := range ev.ResponseHeaders {
+			ex.Response.Headers[h.Name] = h.Value
+		}
+	}
+	return ex
+}
+
+// beforeEval 执行 beforeEval 中间件，请求已被中间件处理完毕时返回 true
+func (m *Manager) beforeEval(ctx context.Context, ts *targetSession, ev *fetch.RequestPausedReply, stage rulespec.Stage, statusCode int) bool {
+	h := m.hookChain(model.HookBeforeEval)
+	if h == nil {
+		return false
+	}
+	ex := m.newExchange(model.HookBeforeEval, ts.id, ev, stage)
+	m.runHook(ctx, h, ex)
+	return m.hookDecided(ctx, ts, ev, stage, statusCode, ex)
+}
+
+// afterEval 执行 afterEval 中间件，按中间件的修改筛选命中的规则；请求已被中间件处理完毕时返回 true
+func (m *Manager) afterEval(ctx context.Context, ts *targetSession, ev *fetch.RequestPausedReply, stage rulespec.Stage, statusCode int, matched *[]*rules.MatchedRule) bool {
+	h := m.hookChain(model.HookAfterEval)
+	if h == nil {
+		return false
+	}
+	ex := m.newExchange(model.HookAfterEval, ts.id, ev, stage)
+	ex.Rules = buildRuleMatches(*matched)
+	m.runHook(ctx, h, ex)
+	if m.hookDecided(ctx, ts, ev, stage, statusCode, ex) {
+		return true
+	}
+
+	kept := make(map[string]bool, len(ex.Rules))
+	for _, r := range ex.Rules {
+		kept[r.RuleID] = true
+	}
+	var out []*rules.MatchedRule
+	for _, mr := range *matched {
+		if kept[mr.Rule.ID] {
+			out = append(out, mr)
+		}
+	}
+	*matched = out
+	return false
+}
+
+// hookDecided 按 beforeEval、afterEval 中间件的决定处理请求，未作出决定时返回 false
+func (m *Manager) hookDecided(ctx context.Context, ts *targetSession, ev *fetch.RequestPausedReply, stage rulespec.Stage, statusCode int, ex *model.Exchange) bool {
+	switch ex.Decision {
+	case model.HookFail:
+		m.markReported(ts, ev)
+		m.executor.FailRequest(ctx, ts, ev, string(network.ErrorReasonAborted))
+		m.sendMatchedEvent(ts.id, ev, "blocked", ex.Rules, ex.Request, ex.Response, nil)
+		m.log.Info("中间件使请求失败", "point", string(ex.Point), "url", ev.Request.URL)
+		return true
+	case model.HookPass:
+		m.sendUnmatchedEvent(ts.id, ev, stage, statusCode)
+		if stage == rulespec.StageRequest {
+			m.continueRequestWithCredentials(ctx, ts, ev)
+		} else {
+			m.continueResponseWithPolicy(ctx, ts, ev)
+		}
+		m.log.Debug("中间件跳过规则处理", "point", string(ex.Point), "url", ev.Request.URL)
+		return true
+	}
+	return false
+}
+
+// beforeApplyRequest 执行请求阶段的 beforeApply 中间件并叠加其修改；请求已被中间件处理完毕时返回处理结果与 true
+func (m *Manager) beforeApplyRequest(
+	ctx context.Context,
+	ts *targetSession,
+	ev *fetch.RequestPausedReply,
+	mut *RequestMutation,
+	ruleMatches []model.RuleMatch,
+	requestInfo model.RequestInfo,
+	responseInfo model.ResponseInfo,
+	steps []model.BodyTransform,
+) (string, bool) {
+	h := m.hookChain(model.HookBeforeApply)
+	if h == nil {
+		return "", false
+	}
+	ex := m.newExchange(model.HookBeforeApply, ts.id, ev, rulespec.StageRequest)
+	ex.Request = m.captureModifiedRequestData(requestInfo, mut)
+	if mut.Method != nil {
+		ex.Request.Method = *mut.Method
+	}
+	ex.Rules = ruleMatches
+	m.runHook(ctx, h, ex)
+
+	switch ex.Decision {
+	case model.HookFail:
+		m.executor.FailRequest(ctx, ts, ev, string(network.ErrorReasonAborted))
+		m.sendMatchedEvent(ts.id, ev, "blocked", ruleMatches, requestInfo, responseInfo, steps)
+		m.log.Info("中间件使请求失败", "point", string(ex.Point), "url", ev.Request.URL)
+		return "blocked", true
+	case model.HookPass:
+		m.continueRequestWithCredentials(ctx, ts, ev)
+		m.sendMatchedEvent(ts.id, ev, "passed", ruleMatches, requestInfo, responseInfo, steps)
+		m.log.Debug("中间件放弃规则修改", "point", string(ex.Point), "url", ev.Request.URL)
+		return "passed", true
+	}
+	applyRequestEdits(ev, mut, ex.Edits)
+	return "", false
+}
+
+// beforeApplyResponse 执行响应阶段的 beforeApply 中间件并叠加其修改，返回中间件是否替换了 Body；
+// 响应已被中间件处理完毕时 result 非空
+func (m *Manager) beforeApplyResponse(
+	ctx context.Context,
+	ts *targetSession,
+	ev *fetch.RequestPausedReply,
+	mut *ResponseMutation,
+	responseBody BodyContent,
+	ruleMatches []model.RuleMatch,
+	requestInfo model.RequestInfo,
+	responseInfo model.ResponseInfo,
+	steps []model.BodyTransform,
+) (result string, bodyReplaced bool) {
+	h := m.hookChain(model.HookBeforeApply)
+	if h == nil {
+		return "", false
+	}
+	ex := m.newExchange(model.HookBeforeApply, ts.id, ev, rulespec.StageResponse)
+	ex.Request = requestInfo
+	ex.Response = m.captureModifiedResponseData(responseInfo, mut, responseBody)
+	ex.Rules = ruleMatches
+	m.runHook(ctx, h, ex)
+
+	switch ex.Decision {
+	case model.HookFail:
+		m.executor.FailRequest(ctx, ts, ev, string(network.ErrorReasonAborted))
+		m.sendMatchedEvent(ts.id, ev, "blocked", ruleMatches, requestInfo, responseInfo, steps)
+		m.log.Info("中间件使响应失败", "point", string(ex.Point), "url", ev.Request.URL)
+		return "blocked", false
+	case model.HookPass:
+		m.continueResponseWithPolicy(ctx, ts, ev)
+		m.sendMatchedEvent(ts.id, ev, "passed", ruleMatches, requestInfo, responseInfo, steps)
+		m.log.Debug("中间件放弃规则修改", "point", string(ex.Point), "url", ev.Request.URL)
+		return "passed", false
+	}
+	return "", applyResponseEdits(ev, mut, ex.Edits)
+}
+
+// requestInfoOf 构建原始请求信息
+func requestInfoOf(ev *fetch.RequestPausedReply) model.RequestInfo {
+	info := model.RequestInfo{
+		URL:          ev.Request.URL,
+		Method:       ev.Request.Method,
+		Headers:      make(map[string]string),
+		ResourceType: string(ev.ResourceType),
+	}
+	_ = json.Unmarshal(ev.Request.Headers, &info.Headers)
+	info.Body, info.BodyEncoding = requestBodyContent(ev).EventBody()
+	return info
+}
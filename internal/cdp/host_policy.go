@@ -0,0 +1,90 @@
+package cdp
+
+import (
+	"net"
+	"net/url"
+	"strings"
+)
+
+// hostPolicy 会话级主机白名单/黑名单，决定哪些主机的请求允许被规则修改
+type hostPolicy struct {
+	allow []string
+	deny  []string
+}
+
+// newHostPolicy 创建主机策略，模式统一转为小写并去除空白
+func newHostPolicy(allow, deny []string) *hostPolicy {
+	return &hostPolicy{allow: normalizeHostPatterns(allow), deny: normalizeHostPatterns(deny)}
+}
+
+// active 判断是否设置了白名单或黑名单
+func (p *hostPolicy) active() bool {
+	return p != nil && (len(p.allow) > 0 || len(p.deny) > 0)
+}
+
+// permits 判断 URL 的主机是否允许被规则修改：命中黑名单则拒绝，白名单非空时必须命中白名单
+func (p *hostPolicy) permits(rawURL string) bool {
+	if !p.active() {
+		return true
+	}
+	host := hostOf(rawURL)
+	for _, pat := range p.deny {
+		if matchHostPattern(host, pat) {
+			return false
+		}
+	}
+	if len(p.allow) == 0 {
+		return true
+	}
+	for _, pat := range p.allow {
+		if matchHostPattern(host, pat) {
+			return true
+		}
+	}
+	return false
+}
+
+// hostOf 提取 URL 中不含端口的小写主机名
+func hostOf(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return ""
+	}
+	return strings.ToLower(u.Hostname())
+}
+
+// matchHostPattern 匹配主机模式：精确匹配，或 *.example.com 匹配 example.com 及其所有子域名
+func matchHostPattern(host, pattern string) bool {
+	if host == "" || pattern == "" {
+		return false
+	}
+	if pattern == "*" {
+		return true
+	}
+	if strings.HasPrefix(pattern, "*.") {
+		base := pattern[2:]
+		return host == base || strings.HasSuffix(host, "."+base)
+	}
+	return host == pattern
+}
+
+// normalizeHostPatterns 规范化主机模式列表，允许填写完整 URL 或带端口的主机
+func normalizeHostPatterns(patterns []string) []string {
+	out := make([]string, 0, len(patterns))
+	for _, p := range patterns {
+		p = strings.ToLower(strings.TrimSpace(p))
+		if p == "" {
+			continue
+		}
+		if strings.Contains(p, "://") {
+			if u, err := url.Parse(p); err == nil {
+				p = u.Host
+			}
+		}
+		if h, _, err := net.SplitHostPort(p); err == nil {
+			p = h
+		}
+		out = append(out, p)
+	}
+	return out
+}
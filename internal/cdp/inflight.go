@@ -0,0 +1,50 @@
+package cdp
+
+import (
+	"context"
+	"time"
+
+	"github.com/mafredri/cdp/protocol/fetch"
+)
+
+// releaseTimeout 断开或停用拦截前放行遗留暂停请求的总超时
+const releaseTimeout = 2 * time.Second
+
+// trackPaused 记录已暂停、尚未下发处理指令的请求
+func (ts *targetSession) trackPaused(ev *fetch.RequestPausedReply) {
+	ts.paused.Store(ev.RequestID, ev)
+}
+
+// settle 请求已下发放行、修改或失败指令，断开时无需再兜底放行
+func (ts *targetSession) settle(id fetch.RequestID) {
+	ts.paused.Delete(id)
+}
+
+// releasePaused 按原样放行目标上仍处于暂停状态的请求（处理中、断点等待或延迟中），
+// 避免断开或停用拦截后这些请求挂起到浏览器超时；需在关闭连接或停用 Fetch 之前调用。
+// 处理协程随后下发的指令会因请求已放行而失败，结果被忽略
+func (m *Manager) releasePaused(ts *targetSession) {
+	if ts == nil || ts.client == nil {
+		return
+	}
+	ctx, cancel := context.WithTimeout(ts.ctx, releaseTimeout)
+	defer cancel()
+
+	released := 0
+	ts.paused.Range(func(id, v any) bool {
+		if _, ok := ts.paused.LoadAndDelete(id); !ok {
+			return true
+		}
+		ev := v.(*fetch.RequestPausedReply)
+		if ev.ResponseStatusCode != nil {
+			m.executor.ContinueResponse(ctx, ts, ev)
+		} else {
+			m.executor.ContinueRequest(ctx, ts, ev)
+		}
+		released++
+		return ctx.Err() == nil
+	})
+	if released > 0 {
+		m.log.Info("已放行遗留的暂停请求", "target", string(ts.id), "count", released)
+	}
+}
@@ -0,0 +1,49 @@
+package cdp
+
+import (
+	"context"
+	"fmt"
+
+	"cdpnetool/pkg/model"
+
+	"github.com/mafredri/cdp"
+	"github.com/mafredri/cdp/devtool"
+	"github.com/mafredri/cdp/protocol/fetch"
+)
+
+// InjectTarget 注册一个不经浏览器连接的页面目标并开启拦截，拦截事件由调用方通过 DispatchPaused 投递。
+// client 通常由内存中的假 CDP 实现构成，用于压测处理流水线
+func (m *Manager) InjectTarget(id model.TargetID, client *cdp.Client) error {
+	if client == nil || client.Fetch == nil {
+		return fmt.Errorf("target client not initialized")
+	}
+
+	m.targetsMu.Lock()
+	defer m.targetsMu.Unlock()
+	if _, ok := m.targets[id]; ok {
+		return fmt.Errorf("目标已存在: %s", id)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	ts := &targetSession{id: id, kind: devtool.Page, client: client, ctx: ctx, cancel: cancel}
+	m.targets[id] = ts
+	m.setEnabled(true)
+
+	if m.pool != nil && m.pool.sem != nil {
+		m.pool.setLogger(m.log)
+		m.pool.start(ctx)
+	}
+	return nil
+}
+
+// DispatchPaused 向注入的目标投递一个拦截事件，与真实事件流走相同的并发分发与处理路径
+func (m *Manager) DispatchPaused(id model.TargetID, ev *fetch.RequestPausedReply) error {
+	m.targetsMu.Lock()
+	ts, ok := m.targets[id]
+	m.targetsMu.Unlock()
+	if !ok {
+		return fmt.Errorf("目标不存在: %s", id)
+	}
+	m.dispatchPaused(ts, ev)
+	return nil
+}
@@ -0,0 +1,66 @@
+package cdp
+
+import (
+	"context"
+	"time"
+
+	"github.com/mafredri/cdp/devtool"
+	"github.com/mafredri/cdp/protocol/emulation"
+
+	"cdpnetool/pkg/model"
+)
+
+// SetLocaleOverride 设置会话级时区与区域覆盖并立即应用到所有已附加的页面目标，字段为空时恢复浏览器默认值；
+// 与拦截开关无关
+func (m *Manager) SetLocaleOverride(o model.LocaleOverride) {
+	m.stateMu.Lock()
+	m.locale = o
+	m.stateMu.Unlock()
+
+	m.targetsMu.Lock()
+	defer m.targetsMu.Unlock()
+	for _, ts := range m.targets {
+		m.applyLocale(ts)
+	}
+}
+
+// currentLocale 返回当前时区与区域覆盖
+func (m *Manager) currentLocale() model.LocaleOverride {
+	m.stateMu.RLock()
+	defer m.stateMu.RUnlock()
+	return m.locale
+}
+
+// applyLocale 将时区与区域覆盖下发到目标；Emulation 域仅页面目标可用，
+// 两项分别下发，未覆盖过的项为空时无需恢复
+func (m *Manager) applyLocale(ts *targetSession) {
+	if ts == nil || ts.client == nil || ts.client.Emulation == nil || ts.kind != devtool.Page {
+		return
+	}
+	o := m.currentLocale()
+
+	ctx, cancel := context.WithTimeout(ts.ctx, 2*time.Second)
+	defer cancel()
+	if o.Timezone != "" || ts.tzApplied {
+		// 时区为空时恢复浏览器默认时区
+		if err := ts.client.Emulation.SetTimezoneOverride(ctx, emulation.NewSetTimezoneOverrideArgs(o.Timezone)); err != nil {
+			m.log.Err(err, "设置时区覆盖失败", "target", string(ts.id), "timezone", o.Timezone)
+		} else {
+			ts.tzApplied = o.Timezone != ""
+			m.log.Debug("已应用时区覆盖", "target", string(ts.id), "timezone", o.Timezone)
+		}
+	}
+	if o.Locale != "" || ts.localeApplied {
+		// 不带 locale 参数时恢复浏览器默认区域
+		args := emulation.NewSetLocaleOverrideArgs()
+		if o.Locale != "" {
+			args.SetLocale(o.Locale)
+		}
+		if err := ts.client.Emulation.SetLocaleOverride(ctx, args); err != nil {
+			m.log.Err(err, "设置区域覆盖失败", "target", string(ts.id), "locale", o.Locale)
+		} else {
+			ts.localeApplied = o.Locale != ""
+			m.log.Debug("已应用区域覆盖", "target", string(ts.id), "locale", o.Locale)
+		}
+	}
+}
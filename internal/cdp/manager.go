@@ -6,11 +6,16 @@ import (
 	"encoding/json"
 	"fmt"
 	"net/url"
+	"sort"
+	"strconv"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
+	"cdpnetool/internal/analyzer"
 	"cdpnetool/internal/logger"
+	"cdpnetool/internal/mutation"
 	"cdpnetool/internal/rules"
 	"cdpnetool/pkg/model"
 	"cdpnetool/pkg/rulespec"
@@ -18,6 +23,8 @@ import (
 	"github.com/mafredri/cdp"
 	"github.com/mafredri/cdp/devtool"
 	"github.com/mafredri/cdp/protocol/fetch"
+	"github.com/mafredri/cdp/protocol/network"
+	"github.com/mafredri/cdp/protocol/page"
 	"github.com/mafredri/cdp/rpcc"
 )
 
@@ -28,6 +35,8 @@ type Manager struct {
 	engine            *rules.Engine
 	executor          *ActionExecutor
 	bodySizeThreshold int64
+	streamBodyLimit   int64
+	maxFulfillBody    int64
 	processTimeoutMS  int
 	pool              *workerPool
 	events            chan model.InterceptEvent
@@ -35,15 +44,101 @@ type Manager struct {
 	targets           map[model.TargetID]*targetSession
 	stateMu           sync.RWMutex
 	enabled           bool
+	hosts             *hostPolicy
+	blocked           *resourceBlock     // 一键屏蔽的资源类别，受 stateMu 保护
+	pins              model.EndpointPins // 重点关注的接口，受 stateMu 保护
+	headerPolicy      model.HeaderPolicy
+	credentials       *credentialStore
+	proxyAuth         *model.ProxyCredential // 上游代理认证信息，受 stateMu 保护
+	userAgent         model.UserAgentOverride
+	geolocation       *model.Geolocation
+	locale            model.LocaleOverride
+	extraHeaders      map[string]string
+	secretVars        map[string]string                      // secret.<name> -> 密钥值
+	secretRedactor    *strings.Replacer                      // 事件中密钥值的脱敏替换器
+	sessionVars       map[string]string                      // 会话变量名（不含 var. 前缀） -> 值，由 captureVar 行为写入
+	hooks             map[model.HookPoint][]model.Middleware // 各挂载点注册的中间件，受 stateMu 保护
+	bodyPlan          bodyFetchPlan                          // 命中规则时读取响应体的计划，受 stateMu 保护
+	cacheBypass       bool
+	schemaTracker     *analyzer.SchemaTracker
+	contentTracker    *analyzer.ContentTracker
+	duplicateTracker  *analyzer.DuplicateTracker
+	rangePolicy       model.RangePolicy
+	captureOnly       bool
+	fullCapture       bool
+	observeOnly       bool         // 观察模式：不启用 Fetch，仅通过 Network 域记录流量
+	ruleCache         *rules.Cache // 规则求值缓存，默认每个会话独立
+	interceptWorkers  bool
+	hostConcurrency   int                // 单个主机的并发上限
+	workerCancel      context.CancelFunc // 停止 worker 目标发现，受 targetsMu 保护
+	sink              func(model.InterceptEvent)
+	watchdogRecover   bool
+	waitForReady      bool               // Enable 时要求所有目标就绪
+	watchdogCancel    context.CancelFunc // 停止流水线看门狗，受 targetsMu 保护
+	interceptStage    model.InterceptStage
+	resourceTypes     []string                  // 会话默认拦截的资源类型，为空时不限制
+	caps              model.BrowserCapabilities // 浏览器能力，受 stateMu 保护
+	captureTiming     bool
+	timings           sync.Map // model.TargetID -> *timingTracker
+	earlyHints        sync.Map // model.TargetID -> *hintSet
+	connections       sync.Map // model.TargetID -> *connTable
+	extraInfos        sync.Map // model.TargetID -> *extraInfoTable
+
+	bpMu        sync.Mutex
+	breakpoints map[string]*breakpoint // 等待人工处理的断点，受 bpMu 保护
+	bpSeq       atomic.Uint64
+	pendingCh   chan model.PendingItem
+
+	emitMu sync.RWMutex
+	closed bool                     // 已关闭，不再向事件和断点通道写入，受 emitMu 保护
+	timers map[*time.Timer]struct{} // 尚未触发的延迟任务，受 emitMu 保护
+	work   sync.WaitGroup           // 处理中的请求与已登记的延迟任务
+
+	proxyMu sync.Mutex
+	proxy   *proxyServer // 内置转发代理，未启动时为 nil，受 proxyMu 保护
+
+	mockMu     sync.Mutex
+	quickMocks map[string]*quickMock // 临时 Mock，受 mockMu 保护
+	mockSeq    atomic.Uint64
 }
 
 // targetSession 表示一个已附加并可拦截的 page 目标
 type targetSession struct {
 	id     model.TargetID
+	kind   devtool.Type // 目标类型（page / service_worker / shared_worker）
 	conn   *rpcc.Conn
 	client *cdp.Client
 	ctx    context.Context
 	cancel context.CancelFunc
+
+	pipe          pipelineState // 拦截流水线心跳
+	authOnce      sync.Once
+	authTried     sync.Map // 已自动应答过认证质询的 RequestID -> 应答时间，请求进入响应阶段、凭据被拒绝或超过 authTriedTTL 后删除
+	wsOnce        sync.Once
+	wsMu          sync.Mutex
+	wsScriptID    page.ScriptIdentifier // WebSocket 包装脚本标识
+	wsURLs        sync.Map              // WebSocket RequestID -> URL
+	scriptMu      sync.Mutex
+	pageScriptID  page.ScriptIdentifier // injectScript 合并脚本标识
+	pageScript    string                // 当前已注入的合并脚本
+	uaApplied     bool                  // 是否已下发 User-Agent 覆盖，受 Manager.targetsMu 保护
+	geoApplied    bool                  // 是否已下发地理位置覆盖，受 Manager.targetsMu 保护
+	tzApplied     bool                  // 是否已下发时区覆盖，受 Manager.targetsMu 保护
+	localeApplied bool                  // 是否已下发区域覆盖，受 Manager.targetsMu 保护
+	extraApplied  bool                  // 是否已下发轻量注入的请求头，受 Manager.targetsMu 保护
+	cacheDisabled bool                  // 是否已禁用浏览器缓存，受 Manager.targetsMu 保护
+
+	takenBodies sync.Map // 已通过流读取的响应体 RequestID -> BodyContent
+	paused      sync.Map // 已暂停、尚未下发处理指令的请求 RequestID -> *fetch.RequestPausedReply
+
+	captureOnce sync.Once
+	timingOnce  sync.Once
+	hintsOnce   sync.Once
+	connOnce    sync.Once
+	extraOnce   sync.Once
+	failureOnce sync.Once
+	dupOnce     sync.Once
+	reported    sync.Map // 已由拦截流程上报事件的 Network RequestID，全量捕获与失败记录时不再重复记录
 }
 
 // New 创建并返回一个管理器，用于管理 CDP 连接与拦截流程
@@ -56,6 +151,11 @@ func New(devtoolsURL string, events chan model.InterceptEvent, l logger.Logger)
 		log:         l,
 		events:      events,
 		targets:     make(map[model.TargetID]*targetSession),
+		ruleCache:   rules.NewCache(),
+		breakpoints: make(map[string]*breakpoint),
+		quickMocks:  make(map[string]*quickMock),
+		pendingCh:   make(chan model.PendingItem, pendingQueueSize),
+		caps:        defaultCapabilities(),
 	}
 	m.executor = NewActionExecutor(m)
 	return m
@@ -75,6 +175,23 @@ func (m *Manager) isEnabled() bool {
 	return m.enabled
 }
 
+// Intercepting 返回是否已启用拦截
+func (m *Manager) Intercepting() bool {
+	return m.isEnabled()
+}
+
+// AttachedTargets 返回已附加的目标，按 ID 排序
+func (m *Manager) AttachedTargets() []model.TargetID {
+	m.targetsMu.Lock()
+	defer m.targetsMu.Unlock()
+	out := make([]model.TargetID, 0, len(m.targets))
+	for id := range m.targets {
+		out = append(out, id)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i] < out[j] })
+	return out
+}
+
 // AttachTarget 附加到指定浏览器目标并建立 CDP 会话。
 func (m *Manager) AttachTarget(target model.TargetID) error {
 	m.targetsMu.Lock()
@@ -103,24 +220,13 @@ func (m *Manager) AttachTarget(target model.TargetID) error {
 		return fmt.Errorf("no target")
 	}
 
-	conn, err := rpcc.DialContext(ctx, selected.WebSocketDebuggerURL)
+	ts, err := dialTarget(ctx, cancel, selected)
 	if err != nil {
-		cancel()
 		m.log.Err(err, "连接浏览器 DevTools 失败")
 		return err
 	}
 
-	client := cdp.NewClient(conn)
-	ts := &targetSession{
-		id:     model.TargetID(selected.ID),
-		conn:   conn,
-		client: client,
-		ctx:    ctx,
-		cancel: cancel,
-	}
-
-	m.targets[ts.id] = ts
-	m.log.Info("附加浏览器目标成功", "target", string(ts.id))
+	m.registerTarget(ts)
 
 	// 如果会话已经启用拦截，则对新目标立即启用
 	if m.isEnabled() {
@@ -132,6 +238,41 @@ func (m *Manager) AttachTarget(target model.TargetID) error {
 	return nil
 }
 
+// registerTarget 登记新连接的目标并应用会话级设置，调用方需持有 targetsMu
+func (m *Manager) registerTarget(ts *targetSession) {
+	m.targets[ts.id] = ts
+	m.log.Info("附加浏览器目标成功", "target", string(ts.id))
+	m.detectCapabilities(ts)
+	m.applyTargetOverrides(ts)
+	m.sendConnectionState(ts.id, connStateConnected, "已附加浏览器目标", nil)
+}
+
+// dialTarget 连接目标的调试地址并创建 targetSession，失败时调用 cancel
+func dialTarget(ctx context.Context, cancel context.CancelFunc, t *devtool.Target) (*targetSession, error) {
+	conn, err := rpcc.DialContext(ctx, t.WebSocketDebuggerURL)
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+	return &targetSession{
+		id:     model.TargetID(t.ID),
+		kind:   t.Type,
+		conn:   conn,
+		client: cdp.NewClient(conn),
+		ctx:    ctx,
+		cancel: cancel,
+	}, nil
+}
+
+// applyTargetOverrides 将会话级浏览器设置（User-Agent、地理位置、时区与区域、注入请求头、缓存开关）应用到新附加的目标
+func (m *Manager) applyTargetOverrides(ts *targetSession) {
+	m.applyUserAgent(ts)
+	m.applyGeolocation(ts)
+	m.applyLocale(ts)
+	m.applyExtraHeaders(ts)
+	m.applyCacheBypass(ts)
+}
+
 // Detach 断开单个目标连接并释放资源。
 func (m *Manager) Detach(target model.TargetID) error {
 	m.targetsMu.Lock()
@@ -158,11 +299,12 @@ func (m *Manager) DetachAll() error {
 	return nil
 }
 
-// closeTargetSession 关闭单个 targetSession
+// closeTargetSession 放行仍在暂停的请求后关闭单个 targetSession
 func (m *Manager) closeTargetSession(ts *targetSession) {
 	if ts == nil {
 		return
 	}
+	m.releasePaused(ts)
 	if ts.cancel != nil {
 		ts.cancel()
 	}
@@ -180,17 +322,35 @@ func (m *Manager) Enable() error {
 		return fmt.Errorf("no targets attached")
 	}
 
+	failed := m.enableAll()
+
+	// 每个目标在 enableTarget 返回时已订阅事件流并启用 Fetch，等待就绪时只需报告未就绪的目标
+	if m.waitForReady && len(failed) > 0 {
+		sort.Strings(failed)
+		return fmt.Errorf("interception not active on targets: %s", strings.Join(failed, ", "))
+	}
+	m.log.Info("拦截功能启用完成")
+	return nil
+}
+
+// enableAll 启用拦截并对全部已附加目标启用，返回启用失败的目标 ID；调用方需持有 targetsMu
+func (m *Manager) enableAll() []string {
 	m.log.Info("开始启用拦截功能")
 	m.setEnabled(true)
 
+	var failed []string
 	for id, ts := range m.targets {
 		if err := m.enableTarget(ts); err != nil {
 			m.log.Err(err, "为目标启用拦截失败", "target", string(id))
+			failed = append(failed, string(id))
 		}
 	}
 
-	m.log.Info("拦截功能启用完成")
-	return nil
+	if m.interceptWorkers {
+		m.startWorkerWatch()
+	}
+	m.startWatchdog()
+	return failed
 }
 
 // enableTarget 为单个目标启用 Network/Fetch 并启动事件消费
@@ -203,12 +363,20 @@ func (m *Manager) enableTarget(ts *targetSession) error {
 		return err
 	}
 
-	p := "*"
-	patterns := []fetch.RequestPattern{
-		{URLPattern: &p, RequestStage: fetch.RequestStageRequest},
-		{URLPattern: &p, RequestStage: fetch.RequestStageResponse},
+	// 观察模式不启用 Fetch，请求不会被暂停，流量全部由 Network 域事件记录
+	if m.observeOnly {
+		m.startNetworkConsumers(ts)
+		m.sendNotice(ts.id, model.NoticeInterceptionActive, "", "目标已开始观察流量", nil)
+		return nil
+	}
+
+	// 先订阅拦截事件流再启用 Fetch，避免启用后、订阅前暂停的请求收不到事件
+	rp, err := m.subscribePaused(ts)
+	if err != nil {
+		return err
 	}
-	if err := ts.client.Fetch.Enable(ts.ctx, &fetch.EnableArgs{Patterns: patterns}); err != nil {
+	if err := m.applyFetchPatterns(ts); err != nil {
+		ts.pipe.closeStream()
 		return err
 	}
 
@@ -218,15 +386,99 @@ func (m *Manager) enableTarget(ts *targetSession) error {
 		m.pool.start(ts.ctx)
 	}
 
-	go m.consume(ts)
+	ts.authOnce.Do(func() { go m.consumeAuth(ts) })
+	m.startNetworkConsumers(ts)
+	m.installWebSocketShim(ts, m.currentConfig())
+	m.installPageScript(ts, m.currentConfig())
+
+	go m.consume(ts, rp)
+	m.sendNotice(ts.id, model.NoticeInterceptionActive, "", "目标拦截已生效", nil)
+	return nil
+}
+
+// startNetworkConsumers 启动基于 Network 域事件的消费者，每个目标只启动一次
+func (m *Manager) startNetworkConsumers(ts *targetSession) {
+	ts.wsOnce.Do(func() { m.consumeWebSocket(ts) })
+	ts.hintsOnce.Do(func() { m.consumeEarlyHints(ts) })
+	ts.connOnce.Do(func() { m.consumeConnections(ts) })
+	ts.extraOnce.Do(func() { m.consumeExtraInfo(ts) })
+	if m.duplicateTracker != nil {
+		ts.dupOnce.Do(func() { m.consumeDuplicates(ts) })
+	}
+	if m.fullCapture {
+		ts.captureOnce.Do(func() { m.consumeCapture(ts) })
+	} else {
+		ts.failureOnce.Do(func() { m.consumeFailures(ts) })
+	}
+	if m.captureTiming {
+		ts.timingOnce.Do(func() { m.consumeTiming(ts) })
+	}
+}
+
+// applyFetchPatterns 按当前规则推导的拦截模式启用 Fetch，没有可能命中的请求时停用 Fetch
+func (m *Manager) applyFetchPatterns(ts *targetSession) error {
+	patterns := buildRequestPatterns(m.currentConfig(), m.observesAllResponses(), m.effectiveInterceptStage())
+	patterns = append(patterns, m.headerPolicyPatterns()...)
+	patterns = append(patterns, m.credentialPatterns()...)
+	patterns = restrictResourceTypes(patterns, m.effectiveResourceTypes())
+	// 屏蔽的资源类别、临时 Mock、代理认证和注入请求头不受拦截资源类型的限制
+	patterns = append(patterns, m.resourceBlockPatterns()...)
+	patterns = append(patterns, m.quickMockPatterns(ts.id)...)
+	patterns = append(patterns, m.proxyAuthPatterns()...)
+	patterns = append(patterns, m.extraHeaderPatterns()...)
+	if len(patterns) == 0 {
+		m.log.Debug("没有需要拦截的请求模式，停用 Fetch", "target", string(ts.id))
+		if err := ts.client.Fetch.Disable(ts.ctx); err != nil {
+			return err
+		}
+		m.sendRulesApplied(ts, 0)
+		return nil
+	}
+	enableArgs := fetch.NewEnableArgs().SetPatterns(patterns).SetHandleAuthRequests(true)
+	if err := ts.client.Fetch.Enable(ts.ctx, enableArgs); err != nil {
+		return err
+	}
+	m.log.Debug("已更新拦截模式", "target", string(ts.id), "patterns", len(patterns))
+	m.sendRulesApplied(ts, len(patterns))
 	return nil
 }
 
+// sendRulesApplied 发送当前规则的拦截模式已在目标生效的通知
+func (m *Manager) sendRulesApplied(ts *targetSession, patterns int) {
+	details := map[string]string{"patterns": strconv.Itoa(patterns)}
+	if cfg := m.currentConfig(); cfg != nil {
+		details["config"] = cfg.ID
+		details["rules"] = strconv.Itoa(len(cfg.EvaluationOrder("")))
+	}
+	m.sendNotice(ts.id, model.NoticeRulesApplied, "", "规则已在目标生效", details)
+}
+
+// refreshFetchPatterns 规则变化后更新所有目标的拦截模式
+func (m *Manager) refreshFetchPatterns() {
+	if !m.isEnabled() || m.observeOnly {
+		return
+	}
+	m.targetsMu.Lock()
+	defer m.targetsMu.Unlock()
+	for id, ts := range m.targets {
+		if ts.client == nil {
+			continue
+		}
+		if err := m.applyFetchPatterns(ts); err != nil {
+			m.log.Err(err, "更新拦截模式失败", "target", string(id))
+		}
+	}
+}
+
 // Disable 停止拦截功能但保留连接
 func (m *Manager) Disable() error {
 	m.targetsMu.Lock()
 	defer m.targetsMu.Unlock()
 
+	m.stopWorkerWatch()
+	m.stopWatchdog()
+	m.dropBreakpoints()
+
 	if len(m.targets) == 0 {
 		m.setEnabled(false)
 		return nil
@@ -238,16 +490,19 @@ func (m *Manager) Disable() error {
 		if ts.client == nil {
 			continue
 		}
+		m.releasePaused(ts)
 		if err := ts.client.Fetch.Disable(ts.ctx); err != nil {
 			m.log.Err(err, "停用目标拦截失败", "target", string(id))
 		}
+		m.installWebSocketShim(ts, nil)
+		m.installPageScript(ts, nil)
 	}
 
 	return nil
 }
 
 // buildEvalContext 构造规则匹配上下文
-func (m *Manager) buildEvalContext(ev *fetch.RequestPausedReply) *rules.EvalContext {
+func (m *Manager) buildEvalContext(target model.TargetID, ev *fetch.RequestPausedReply) *rules.EvalContext {
 	h := map[string]string{}
 	q := map[string]string{}
 	ck := map[string]string{}
@@ -282,7 +537,7 @@ func (m *Manager) buildEvalContext(ev *fetch.RequestPausedReply) *rules.EvalCont
 
 	// 解析 Cookie
 	if v, ok := h["cookie"]; ok {
-		for name, val := range parseCookie(v) {
+		for name, val := range mutation.ParseCookie(v) {
 			ck[strings.ToLower(name)] = val
 		}
 	}
@@ -290,19 +545,35 @@ func (m *Manager) buildEvalContext(ev *fetch.RequestPausedReply) *rules.EvalCont
 	// 获取请求体
 	bodyText = GetRequestBody(ev)
 
+	// 响应阶段的状态码与响应头
+	var status int
+	var respHeaders map[string]string
+	if ev.ResponseStatusCode != nil {
+		status = *ev.ResponseStatusCode
+		respHeaders = make(map[string]string, len(ev.ResponseHeaders))
+		for _, e := range ev.ResponseHeaders {
+			respHeaders[strings.ToLower(e.Name)] = e.Value
+		}
+	}
+
 	return &rules.EvalContext{
-		URL:          ev.Request.URL,
-		Method:       ev.Request.Method,
-		ResourceType: resourceType,
-		Headers:      h,
-		Query:        q,
-		Cookies:      ck,
-		Body:         bodyText,
+		URL:            ev.Request.URL,
+		Method:         ev.Request.Method,
+		ResourceType:   resourceType,
+		Protocol:       m.protocolOf(target, ev.Request.URL),
+		BlockedCookies: m.blockedCookies(target, ev),
+		Headers:        h,
+		Query:          q,
+		Cookies:        ck,
+		Body:           bodyText,
+
+		StatusCode:      status,
+		ResponseHeaders: respHeaders,
 	}
 }
 
 // getResponseBody 获取响应体内容
-func (m *Manager) getResponseBody(ts *targetSession, ev *fetch.RequestPausedReply) string {
+func (m *Manager) getResponseBody(ctx context.Context, ts *targetSession, ev *fetch.RequestPausedReply) string {
 	var ctype string
 	var clen int64
 
@@ -321,11 +592,11 @@ func (m *Manager) getResponseBody(ts *targetSession, ev *fetch.RequestPausedRepl
 		}
 	}
 
-	if !shouldGetBody(ctype, clen, m.bodySizeThreshold) {
+	if !shouldGetBody(ctype, clen, m.defaultBodyNeed()) {
 		return ""
 	}
 
-	ctx2, cancel := context.WithTimeout(ts.ctx, 500*time.Millisecond)
+	ctx2, cancel := context.WithTimeout(ctx, 500*time.Millisecond)
 	defer cancel()
 	rb, err := ts.client.Fetch.GetResponseBody(ctx2, &fetch.GetResponseBodyArgs{RequestID: ev.RequestID})
 	if err != nil || rb == nil {
@@ -414,16 +685,37 @@ func (m *Manager) ListTargets(ctx context.Context) ([]model.TargetInfo, error) {
 
 // SetRules 设置新的规则配置并初始化引擎
 func (m *Manager) SetRules(cfg *rulespec.Config) {
-	m.engine = rules.New(cfg)
+	m.engine = rules.New(cfg, m.ruleCache)
+	m.refreshBodyPlan()
+	m.refreshFetchPatterns()
+	m.refreshWebSocketShims()
+	m.refreshPageScripts()
 }
 
 // UpdateRules 更新已有规则配置到引擎
 func (m *Manager) UpdateRules(cfg *rulespec.Config) {
 	if m.engine == nil {
-		m.engine = rules.New(cfg)
+		m.engine = rules.New(cfg, m.ruleCache)
 	} else {
 		m.engine.Update(cfg)
 	}
+	m.refreshBodyPlan()
+	m.refreshFetchPatterns()
+	m.refreshWebSocketShims()
+	m.refreshPageScripts()
+}
+
+// ReplaceRules 替换规则配置但保留计数条件与频率窗口的状态，仅用于切换规则启用状态
+func (m *Manager) ReplaceRules(cfg *rulespec.Config) {
+	if m.engine == nil {
+		m.UpdateRules(cfg)
+		return
+	}
+	m.engine.Replace(cfg)
+	m.refreshBodyPlan()
+	m.refreshFetchPatterns()
+	m.refreshWebSocketShims()
+	m.refreshPageScripts()
 }
 
 // SetConcurrency 配置拦截处理的并发工作协程数
@@ -431,18 +723,255 @@ func (m *Manager) SetConcurrency(n int) {
 	m.pool = newWorkerPool(n)
 	if m.pool != nil && m.pool.sem != nil {
 		m.pool.setLogger(m.log)
+		m.pool.setHostLimit(m.hostConcurrency)
 		m.log.Info("并发工作池已配置", "workers", n, "queueCap", m.pool.queueCap)
 	} else {
 		m.log.Info("并发工作池未限制，使用无界模式")
 	}
 }
 
+// SetHostConcurrency 设置单个主机同时占用的并发工作协程上限，0 表示不限制；
+// 避免单个响应缓慢的主机占满工作池，拖慢其他域名的拦截处理
+func (m *Manager) SetHostConcurrency(n int) {
+	m.hostConcurrency = n
+	if m.pool != nil {
+		m.pool.setHostLimit(n)
+	}
+}
+
 // SetRuntime 设置运行时阈值与处理超时时间
 func (m *Manager) SetRuntime(bodySizeThreshold int64, processTimeoutMS int) {
 	m.bodySizeThreshold = bodySizeThreshold
 	m.processTimeoutMS = processTimeoutMS
 }
 
+// SetHostPolicy 设置允许/禁止规则修改的主机列表，其他主机的请求始终原样放行
+func (m *Manager) SetHostPolicy(allow, deny []string) {
+	m.stateMu.Lock()
+	m.hosts = newHostPolicy(allow, deny)
+	m.stateMu.Unlock()
+	// WebSocket 帧在页面内改写，主机策略需同步到注入脚本
+	m.refreshWebSocketShims()
+	// 主机策略决定注入请求头经浏览器还是经拦截下发
+	m.refreshExtraHeaders()
+}
+
+// isHostPermitted 判断请求主机是否允许被规则修改
+func (m *Manager) isHostPermitted(rawURL string) bool {
+	m.stateMu.RLock()
+	defer m.stateMu.RUnlock()
+	return m.hosts.permits(rawURL)
+}
+
+// SetInterceptStage 设置会话默认拦截阶段
+func (m *Manager) SetInterceptStage(s model.InterceptStage) {
+	m.interceptStage = s
+}
+
+// effectiveInterceptStage 返回实际拦截阶段，规则配置中的设置优先于会话选项
+func (m *Manager) effectiveInterceptStage() model.InterceptStage {
+	switch s := model.InterceptStage(m.currentConfig().InterceptStage()); s {
+	case model.InterceptStageBoth, model.InterceptStageRequest, model.InterceptStageResponse:
+		return s
+	}
+	return m.interceptStage
+}
+
+// SetInterceptResourceTypes 设置会话默认拦截的资源类型，为空时不限制
+func (m *Manager) SetInterceptResourceTypes(types []string) {
+	m.resourceTypes = types
+}
+
+// effectiveResourceTypes 返回实际拦截的资源类型，规则配置中的设置优先于会话选项；
+// 无法识别的类型会被忽略，全部无法识别时不限制
+func (m *Manager) effectiveResourceTypes() []network.ResourceType {
+	names := m.currentConfig().InterceptResourceTypes()
+	if len(names) == 0 {
+		names = m.resourceTypes
+	}
+	var out []network.ResourceType
+	for _, name := range names {
+		rt, ok := lookupResourceType(name)
+		if !ok {
+			m.log.Warn("忽略无法识别的拦截资源类型", "type", name)
+			continue
+		}
+		out = append(out, rt)
+	}
+	return out
+}
+
+// SetEventSink 设置事件旁路回调，每个事件在推送到事件通道前都会调用，回调不得阻塞
+func (m *Manager) SetEventSink(fn func(model.InterceptEvent)) {
+	m.sink = fn
+}
+
+// pinnedSendTimeout 重点关注接口的事件在通道已满时等待推送的最长时间
+const pinnedSendTimeout = 2 * time.Second
+
+// SetPinnedEndpoints 设置重点关注的接口 URL 模式，其事件在通道已满时等待推送而不是直接丢弃
+func (m *Manager) SetPinnedEndpoints(pins model.EndpointPins) {
+	m.stateMu.Lock()
+	defer m.stateMu.Unlock()
+	m.pins = pins
+}
+
+// isPinned 判断事件是否属于重点关注的接口
+func (m *Manager) isPinned(evt model.InterceptEvent) bool {
+	m.stateMu.RLock()
+	defer m.stateMu.RUnlock()
+	_, ok := m.pins.Match(evt.RequestURL())
+	return ok
+}
+
+// emit 推送事件，通道已满时丢弃
+func (m *Manager) emit(evt model.InterceptEvent) {
+	switch {
+	case evt.Matched != nil:
+		setDisplayURL(&evt.Matched.NetworkEvent)
+		setOperation(&evt.Matched.NetworkEvent)
+	case evt.Unmatched != nil:
+		setDisplayURL(&evt.Unmatched.NetworkEvent)
+		setOperation(&evt.Unmatched.NetworkEvent)
+	}
+	if m.captureTiming && m.holdForTiming(evt) {
+		return
+	}
+	m.emitNow(evt)
+}
+
+// emitNow 立即推送事件，通道已满时丢弃；重点关注接口的事件最多等待 pinnedSendTimeout
+func (m *Manager) emitNow(evt model.InterceptEvent) {
+	evt = m.withExtraInfo(evt)
+	m.emitMu.RLock()
+	defer m.emitMu.RUnlock()
+	if m.closed {
+		return
+	}
+	if m.sink != nil {
+		m.sink(evt)
+	}
+	select {
+	case m.events <- evt:
+		return
+	default:
+	}
+	if !m.isPinned(evt) {
+		return
+	}
+	timer := time.NewTimer(pinnedSendTimeout)
+	defer timer.Stop()
+	select {
+	case m.events <- evt:
+	case <-timer.C:
+		m.log.Warn("事件通道持续已满，丢弃重点关注接口的事件", "url", evt.RequestURL())
+	}
+}
+
+// setDisplayURL 主机为国际化域名时为事件补充 Unicode 形式的 URL 用于显示
+func setDisplayURL(e *model.NetworkEvent) {
+	if u, ok := rules.UnicodeURL(e.Request.URL); ok {
+		e.DisplayURL = u
+	}
+}
+
+// setOperation 识别事件中的 API 封装格式，补充逻辑操作用于按操作分组
+func setOperation(e *model.NetworkEvent) {
+	env, ok := analyzer.DetectEnvelope(analyzer.Exchange{
+		Method:       e.Request.Method,
+		URL:          e.Request.URL,
+		RequestType:  headerValue(e.Request.Headers, "Content-Type"),
+		RequestBody:  eventBody(e.Request.Body, e.Request.BodyEncoding),
+		ResponseType: headerValue(e.Response.Headers, "Content-Type"),
+		ResponseBody: eventBody(e.Response.Body, e.Response.BodyEncoding),
+		SOAPAction:   headerValue(e.Request.Headers, "SOAPAction"),
+	})
+	if !ok {
+		return
+	}
+	e.Operation = &model.APIOperation{Format: env.Format, Resource: env.Resource, Operation: env.Operation, Key: env.Key()}
+}
+
+// headerValue 不区分大小写地查找头部值
+func headerValue(headers map[string]string, name string) string {
+	for k, v := range headers {
+		if strings.EqualFold(k, name) {
+			return v
+		}
+	}
+	return ""
+}
+
+// eventBody 返回事件中 Body 的原始字节
+func eventBody(body, encoding string) []byte {
+	if encoding == model.BodyEncodingBase64 {
+		if b, err := base64.StdEncoding.DecodeString(body); err == nil {
+			return b
+		}
+		return nil
+	}
+	return []byte(body)
+}
+
+// SetCaptureOnly 设置只读捕获模式，开启后匹配的规则只记录不执行
+func (m *Manager) SetCaptureOnly(on bool) {
+	m.captureOnly = on
+}
+
+// SetFullCapture 设置全量捕获模式，开启后未匹配的请求改由 Network 域事件记录并附带响应体
+func (m *Manager) SetFullCapture(on bool) {
+	m.fullCapture = on
+}
+
+// SetObserveOnly 设置观察模式，开启后不启用 Fetch 拦截，请求不被暂停，全部流量由 Network 域事件记录；
+// 观察模式隐含只读捕获与全量捕获，需在 SetCaptureOnly、SetFullCapture 之后调用
+func (m *Manager) SetObserveOnly(on bool) {
+	m.observeOnly = on
+	if on {
+		m.captureOnly = true
+		m.fullCapture = true
+	}
+}
+
+// SetShareRuleCache 设置是否使用跨会话共享的规则缓存，需在加载规则前调用
+func (m *Manager) SetShareRuleCache(on bool) {
+	if on {
+		m.ruleCache = rules.SharedCache()
+	} else {
+		m.ruleCache = rules.NewCache()
+	}
+}
+
+// SetRangePolicy 设置 Range 请求的 Body 改写策略
+func (m *Manager) SetRangePolicy(p model.RangePolicy) {
+	m.rangePolicy = p
+}
+
+// SetWaitForReady 设置启用拦截时是否要求所有已附加目标就绪，任一目标未能开始消费拦截事件时 Enable 返回错误
+func (m *Manager) SetWaitForReady(on bool) {
+	m.waitForReady = on
+}
+
+// SetSchemaTracker 设置 JSON 响应结构跟踪器，nil 表示关闭结构漂移检测
+func (m *Manager) SetSchemaTracker(t *analyzer.SchemaTracker) {
+	m.schemaTracker = t
+}
+
+// SetContentTracker 设置响应体哈希跟踪器，nil 表示关闭内容变化检测
+func (m *Manager) SetContentTracker(t *analyzer.ContentTracker) {
+	m.contentTracker = t
+}
+
+// SetDuplicateTracker 设置重复请求跟踪器，nil 表示关闭重复请求检测
+func (m *Manager) SetDuplicateTracker(t *analyzer.DuplicateTracker) {
+	m.duplicateTracker = t
+}
+
+// observesAllResponses 是否需要暂停所有响应以进行被动分析
+func (m *Manager) observesAllResponses() bool {
+	return m.schemaTracker != nil || m.contentTracker != nil
+}
+
 // GetStats 返回规则引擎的命中统计信息
 func (m *Manager) GetStats() model.EngineStats {
 	if m.engine == nil {
@@ -456,9 +985,11 @@ func (m *Manager) GetStats() model.EngineStats {
 	}
 
 	return model.EngineStats{
-		Total:   stats.Total,
-		Matched: stats.Matched,
-		ByRule:  byRule,
+		Total:          stats.Total,
+		Matched:        stats.Matched,
+		ByRule:         byRule,
+		ByTag:          stats.ByTag,
+		CachedPatterns: stats.CachedPatterns,
 	}
 }
 
@@ -0,0 +1,119 @@
+package cdp
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"time"
+
+	"github.com/mafredri/cdp/devtool"
+	"github.com/mafredri/cdp/protocol/page"
+
+	"cdpnetool/pkg/model"
+)
+
+// openTargetTimeout 新建标签页、发起导航和重新加载的超时
+const openTargetTimeout = 10 * time.Second
+
+// OpenTarget 新建空白标签页，附加并启用拦截后再导航到 rawURL，保证首个文档请求也经过拦截；
+// 会话尚未启用拦截时一并启用。拦截未能在新目标上生效时不导航，标签页保持附加状态
+func (m *Manager) OpenTarget(rawURL string) (model.TargetID, error) {
+	if u, err := url.Parse(rawURL); err != nil || !u.IsAbs() {
+		return "", fmt.Errorf("invalid url: %q", rawURL)
+	}
+	if m.devtoolsURL == "" {
+		return "", fmt.Errorf("devtools url empty")
+	}
+
+	createCtx, createCancel := context.WithTimeout(context.Background(), openTargetTimeout)
+	created, err := devtool.New(m.devtoolsURL).Create(createCtx)
+	createCancel()
+	if err != nil {
+		m.log.Err(err, "新建浏览器标签页失败")
+		return "", fmt.Errorf("create target: %w", err)
+	}
+
+	ts, err := m.attachCreated(created)
+	if err != nil {
+		return model.TargetID(created.ID), err
+	}
+
+	if err := navigate(ts, rawURL); err != nil {
+		return ts.id, err
+	}
+	m.log.Info("新标签页已在拦截生效后导航", "target", string(ts.id), "url", rawURL)
+	return ts.id, nil
+}
+
+// Navigate 让已附加的页面目标导航到 rawURL，target 为空时使用任一已附加的页面
+func (m *Manager) Navigate(target model.TargetID, rawURL string) error {
+	if u, err := url.Parse(rawURL); err != nil || !u.IsAbs() {
+		return fmt.Errorf("invalid url: %q", rawURL)
+	}
+	ts, err := m.pageTarget(target)
+	if err != nil {
+		return err
+	}
+	if err := navigate(ts, rawURL); err != nil {
+		return err
+	}
+	m.log.Info("页面已导航", "target", string(ts.id), "url", rawURL)
+	return nil
+}
+
+// Reload 重新加载已附加的页面目标，ignoreCache 为 true 时跳过缓存（等同于强制刷新）
+func (m *Manager) Reload(target model.TargetID, ignoreCache bool) error {
+	ts, err := m.pageTarget(target)
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(ts.ctx, openTargetTimeout)
+	defer cancel()
+	if err := ts.client.Page.Reload(ctx, page.NewReloadArgs().SetIgnoreCache(ignoreCache)); err != nil {
+		return fmt.Errorf("reload: %w", err)
+	}
+	m.log.Info("页面已重新加载", "target", string(ts.id), "ignoreCache", ignoreCache)
+	return nil
+}
+
+// navigate 通过 Page.navigate 发起导航，导航本身失败（如域名无法解析）时返回错误
+func navigate(ts *targetSession, rawURL string) error {
+	ctx, cancel := context.WithTimeout(ts.ctx, openTargetTimeout)
+	defer cancel()
+	reply, err := ts.client.Page.Navigate(ctx, page.NewNavigateArgs(rawURL))
+	if err != nil {
+		return fmt.Errorf("navigate: %w", err)
+	}
+	if reply.ErrorText != nil && *reply.ErrorText != "" {
+		return fmt.Errorf("navigate: %s", *reply.ErrorText)
+	}
+	return nil
+}
+
+// attachCreated 附加新建的标签页并确保其拦截已生效
+func (m *Manager) attachCreated(created *devtool.Target) (*targetSession, error) {
+	m.targetsMu.Lock()
+	defer m.targetsMu.Unlock()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	ts, err := dialTarget(ctx, cancel, created)
+	if err != nil {
+		m.log.Err(err, "连接浏览器 DevTools 失败")
+		return nil, err
+	}
+	m.registerTarget(ts)
+
+	if m.isEnabled() {
+		if err := m.enableTarget(ts); err != nil {
+			m.log.Err(err, "为新目标启用拦截失败", "target", string(ts.id))
+			return nil, fmt.Errorf("enable interception on new target: %w", err)
+		}
+		return ts, nil
+	}
+	for _, id := range m.enableAll() {
+		if id == string(ts.id) {
+			return nil, fmt.Errorf("enable interception on new target: %s", id)
+		}
+	}
+	return ts, nil
+}
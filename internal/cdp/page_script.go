@@ -0,0 +1,166 @@
+package cdp
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/mafredri/cdp/devtool"
+	"github.com/mafredri/cdp/protocol/page"
+
+	"cdpnetool/pkg/rulespec"
+)
+
+// injectScript 行为通过 Page.addScriptToEvaluateOnNewDocument 生效：所有启用规则中的脚本合并为一个新文档脚本，
+// 在每个新文档（含 iframe）中按 location.href 判断规则的 URL 条件，命中后在页面自身脚本之前执行。
+
+// pageScriptConditions 页面注入脚本支持的条件类型
+var pageScriptConditions = map[rulespec.ConditionType]bool{
+	rulespec.ConditionURLEquals:   true,
+	rulespec.ConditionURLPrefix:   true,
+	rulespec.ConditionURLSuffix:   true,
+	rulespec.ConditionURLContains: true,
+	rulespec.ConditionURLRegex:    true,
+}
+
+// pageScriptHeader 页面注入脚本的条件判断部分
+const pageScriptHeader = `(function () {
+  var url = location.href;
+  var test = function (c) {
+    try {
+      switch (c.type) {
+        case 'urlEquals': return url === c.value;
+        case 'urlPrefix': return url.indexOf(c.value) === 0;
+        case 'urlSuffix': return url.slice(-c.value.length) === c.value;
+        case 'urlContains': return url.indexOf(c.value) !== -1;
+        case 'urlRegex': return new RegExp(c.pattern).test(url);
+      }
+    } catch (e) {}
+    return false;
+  };
+  var matches = function (allOf, anyOf) {
+    for (var i = 0; i < allOf.length; i++) if (!test(allOf[i])) return false;
+    if (!anyOf.length) return true;
+    for (var j = 0; j < anyOf.length; j++) if (test(anyOf[j])) return true;
+    return false;
+  };
+`
+
+// pageScriptRule 单条规则的脚本片段：用户脚本包在函数中执行，异常只输出到控制台
+const pageScriptRule = `  if (matches(%s, %s)) {
+    try {
+      (function () {
+%s
+      })();
+    } catch (e) {
+      console.error('[cdpnetool] injectScript', %s, e);
+    }%s
+  }
+`
+
+// compilePageScript 将请求阶段规则中的 injectScript 行为合并为一个新文档脚本，没有可注入的脚本时返回空串；
+// 包含不支持的条件的规则不注入
+func compilePageScript(cfg *rulespec.Config) (string, []string) {
+	if cfg == nil {
+		return "", nil
+	}
+	var b strings.Builder
+	var skipped []string
+	for _, rule := range cfg.EvaluationOrder(rulespec.StageRequest) {
+		var sources []string
+		for _, a := range rule.Actions {
+			if a.Type != rulespec.ActionInjectScript {
+				continue
+			}
+			if src, _ := a.Value.(string); strings.TrimSpace(src) != "" {
+				sources = append(sources, src)
+			}
+		}
+		if len(sources) == 0 {
+			continue
+		}
+		allOf, ok1 := pageScriptConds(rule.Match.AllOf)
+		anyOf, ok2 := pageScriptConds(rule.Match.AnyOf)
+		if !ok1 || !ok2 {
+			skipped = append(skipped, rule.ID)
+			continue
+		}
+		allJSON, _ := json.Marshal(allOf)
+		anyJSON, _ := json.Marshal(anyOf)
+		ruleID, _ := json.Marshal(rule.ID)
+		stop := ""
+		if rule.StopProcessing {
+			stop = "\n    return;"
+		}
+		fmt.Fprintf(&b, pageScriptRule, allJSON, anyJSON, strings.Join(sources, "\n;\n"), ruleID, stop)
+	}
+	if b.Len() == 0 {
+		return "", skipped
+	}
+	return pageScriptHeader + b.String() + "})();", skipped
+}
+
+// pageScriptConds 转换条件列表，包含不支持的条件时返回 false
+func pageScriptConds(conds []rulespec.Condition) ([]wsShimCond, bool) {
+	out := []wsShimCond{}
+	for _, c := range conds {
+		if !pageScriptConditions[c.Type] {
+			return nil, false
+		}
+		out = append(out, wsShimCond{Type: c.Type, Value: c.Value, Pattern: c.Pattern})
+	}
+	return out, true
+}
+
+// installPageScript 向目标注入（或替换）页面脚本，cfg 为 nil 时移除脚本；脚本从下一次导航起生效
+func (m *Manager) installPageScript(ts *targetSession, cfg *rulespec.Config) {
+	if ts == nil || ts.client == nil || ts.kind != devtool.Page {
+		return
+	}
+	// 只读捕获模式下不修改页面
+	if m.captureOnly {
+		cfg = nil
+	}
+	script, skipped := compilePageScript(cfg)
+	if len(skipped) > 0 {
+		m.log.Warn("部分注入脚本的规则包含 URL 以外的条件，未注入", "target", string(ts.id), "rules", skipped)
+	}
+
+	ts.scriptMu.Lock()
+	defer ts.scriptMu.Unlock()
+	if script == ts.pageScript {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(ts.ctx, 2*time.Second)
+	defer cancel()
+	if ts.pageScriptID != "" {
+		_ = ts.client.Page.RemoveScriptToEvaluateOnNewDocument(ctx, page.NewRemoveScriptToEvaluateOnNewDocumentArgs(ts.pageScriptID))
+		ts.pageScriptID, ts.pageScript = "", ""
+	}
+	if script == "" {
+		return
+	}
+	reply, err := ts.client.Page.AddScriptToEvaluateOnNewDocument(ctx, page.NewAddScriptToEvaluateOnNewDocumentArgs(script))
+	if err != nil {
+		m.log.Err(err, "注入页面脚本失败", "target", string(ts.id))
+		return
+	}
+	ts.pageScriptID, ts.pageScript = reply.Identifier, script
+	m.log.Info("页面脚本已注入，下次导航起生效", "target", string(ts.id))
+}
+
+// refreshPageScripts 规则变化后更新所有已启用拦截目标的页面脚本
+func (m *Manager) refreshPageScripts() {
+	if !m.isEnabled() {
+		return
+	}
+	m.targetsMu.Lock()
+	defer m.targetsMu.Unlock()
+	cfg := m.currentConfig()
+	for _, ts := range m.targets {
+		m.installPageScript(ts, cfg)
+	}
+}
@@ -0,0 +1,294 @@
+package cdp
+
+import (
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/mafredri/cdp/protocol/fetch"
+	"github.com/mafredri/cdp/protocol/network"
+
+	"cdpnetool/internal/rules"
+	"cdpnetool/pkg/model"
+	"cdpnetool/pkg/rulespec"
+)
+
+// fetchResourceTypes CDP 资源类型，用于将规则中不区分大小写的资源类型还原为协议值
+var fetchResourceTypes = []network.ResourceType{
+	network.ResourceTypeDocument, network.ResourceTypeStylesheet, network.ResourceTypeImage,
+	network.ResourceTypeMedia, network.ResourceTypeFont, network.ResourceTypeScript,
+	network.ResourceTypeTextTrack, network.ResourceTypeXHR, network.ResourceTypeFetch,
+	network.ResourceTypePrefetch, network.ResourceTypeEventSource, network.ResourceTypeWebSocket,
+	network.ResourceTypeManifest, network.ResourceTypeSignedExchange, network.ResourceTypePing,
+	network.ResourceTypeCSPViolationReport, network.ResourceTypePreflight, network.ResourceTypeOther,
+}
+
+// pathParamPattern 匹配路径模板中的 {name} 参数
+var pathParamPattern = regexp.MustCompile(`\{[^/{}]*\}`)
+
+// patternKey 去重用的拦截模式标识
+type patternKey struct {
+	url   string
+	rt    network.ResourceType
+	stage fetch.RequestStage
+}
+
+// buildRequestPatterns 根据规则推导 Fetch.Enable 的拦截模式，使浏览器只暂停可能被规则命中的请求。
+// 推导结果总是规则实际匹配范围的超集；pauseAllResponses 为 true 时响应阶段始终全部拦截；
+// intercept 限制拦截的阶段，未启用阶段的规则不会生效
+func buildRequestPatterns(cfg *rulespec.Config, pauseAllResponses bool, intercept model.InterceptStage) []fetch.RequestPattern {
+	keys := make(map[patternKey]bool)
+	all := map[fetch.RequestStage]bool{}
+	if pauseAllResponses {
+		all[fetch.RequestStageResponse] = true
+	}
+	allowed := map[fetch.RequestStage]bool{
+		fetch.RequestStageRequest:  intercept != model.InterceptStageResponse,
+		fetch.RequestStageResponse: intercept != model.InterceptStageRequest,
+	}
+
+	if cfg != nil {
+		for i := range cfg.Rules {
+			rule := &cfg.Rules[i]
+			if !rule.Enabled {
+				continue
+			}
+			var stage fetch.RequestStage
+			switch rule.Stage {
+			case rulespec.StageRequest:
+				stage = fetch.RequestStageRequest
+			case rulespec.StageResponse:
+				stage = fetch.RequestStageResponse
+			default:
+				continue
+			}
+			if !allowed[stage] {
+				continue
+			}
+			urls := rulePatternURLs(&rule.Match)
+			if rule.URLNormalize.Enabled() {
+				// 规范化后的 URL 可能与条件字面值不同，无法推导出可靠的通配模式
+				urls = []string{"*"}
+			}
+			types := ruleResourceTypes(&rule.Match)
+			for _, u := range urls {
+				if u == "*" && len(types) == 0 {
+					all[stage] = true
+				}
+				if len(types) == 0 {
+					keys[patternKey{url: u, stage: stage}] = true
+					continue
+				}
+				for _, rt := range types {
+					keys[patternKey{url: u, rt: rt, stage: stage}] = true
+				}
+			}
+		}
+	}
+
+	out := make([]fetch.RequestPattern, 0, len(keys)+len(all))
+	for _, stage := range []fetch.RequestStage{fetch.RequestStageRequest, fetch.RequestStageResponse} {
+		if all[stage] && allowed[stage] {
+			out = append(out, fetch.RequestPattern{URLPattern: strPtr("*"), RequestStage: stage})
+		}
+	}
+
+	sorted := make([]patternKey, 0, len(keys))
+	for k := range keys {
+		if !all[k.stage] && allowed[k.stage] {
+			sorted = append(sorted, k)
+		}
+	}
+	sort.Slice(sorted, func(i, j int) bool {
+		a, b := sorted[i], sorted[j]
+		if a.stage != b.stage {
+			return a.stage < b.stage
+		}
+		if a.url != b.url {
+			return a.url < b.url
+		}
+		return a.rt < b.rt
+	})
+	for _, k := range sorted {
+		p := fetch.RequestPattern{URLPattern: strPtr(k.url), RequestStage: k.stage}
+		if k.rt != "" {
+			rt := k.rt
+			p.ResourceType = &rt
+		}
+		out = append(out, p)
+	}
+	return out
+}
+
+// restrictResourceTypes 将拦截模式限制在指定资源类型内：未限定类型的模式按每种类型展开，
+// 限定了其他类型的模式被移除；types 为空时原样返回
+func restrictResourceTypes(patterns []fetch.RequestPattern, types []network.ResourceType) []fetch.RequestPattern {
+	if len(types) == 0 {
+		return patterns
+	}
+	allowed := make(map[network.ResourceType]bool, len(types))
+	for _, rt := range types {
+		allowed[rt] = true
+	}
+	seen := make(map[patternKey]bool)
+	out := make([]fetch.RequestPattern, 0, len(patterns))
+	add := func(p fetch.RequestPattern, rt network.ResourceType) {
+		url := "*"
+		if p.URLPattern != nil {
+			url = *p.URLPattern
+		}
+		k := patternKey{url: url, rt: rt, stage: p.RequestStage}
+		if seen[k] {
+			return
+		}
+		seen[k] = true
+		p.ResourceType = &rt
+		out = append(out, p)
+	}
+	for _, p := range patterns {
+		if p.ResourceType != nil {
+			if allowed[*p.ResourceType] {
+				add(p, *p.ResourceType)
+			}
+			continue
+		}
+		for _, rt := range types {
+			add(p, rt)
+		}
+	}
+	return out
+}
+
+// rulePatternURLs 从匹配条件推导 URL 通配模式；allOf 中任一 URL 条件即可收窄，
+// 否则 anyOf 全部为可推导的 URL 条件时取并集，其余情况返回 "*"
+func rulePatternURLs(m *rulespec.Match) []string {
+	for _, c := range m.AllOf {
+		if p, ok := conditionURLPattern(&c); ok && p != "*" {
+			return []string{p}
+		}
+	}
+	if len(m.AnyOf) == 0 {
+		return []string{"*"}
+	}
+	var out []string
+	for _, c := range m.AnyOf {
+		p, ok := conditionURLPattern(&c)
+		if !ok {
+			return []string{"*"}
+		}
+		out = append(out, p)
+	}
+	return out
+}
+
+// conditionURLPattern 将单个 URL 条件转换为 Fetch 通配模式
+func conditionURLPattern(c *rulespec.Condition) (string, bool) {
+	switch c.Type {
+	case rulespec.ConditionURLEquals:
+		return escapeFetchPattern(rules.ASCIIHost(c.Value)), true
+	case rulespec.ConditionURLPrefix:
+		return escapeFetchPattern(rules.ASCIIHost(c.Value)) + "*", true
+	case rulespec.ConditionURLSuffix:
+		return "*" + escapeFetchPattern(rules.ASCIIHost(c.Value)), true
+	case rulespec.ConditionURLContains:
+		return "*" + escapeFetchPattern(rules.ASCIIHost(c.Value)) + "*", true
+	case rulespec.ConditionPathPattern:
+		// 参数段与 * 段放宽为任意字符，保证覆盖实际匹配范围
+		segs := strings.Split(c.Value, "/")
+		for i, seg := range segs {
+			if seg == "*" || pathParamPattern.MatchString(seg) {
+				segs[i] = "*"
+			} else {
+				segs[i] = escapeFetchPattern(seg)
+			}
+		}
+		return "*" + strings.Join(segs, "/") + "*", true
+	case rulespec.ConditionURLGlob:
+		return globFetchPattern(rules.ASCIIHost(c.Value)), true
+	case rulespec.ConditionURLRegex:
+		return "*", true
+	default:
+		return "", false
+	}
+}
+
+// ruleResourceTypes 返回 allOf 中资源类型条件对应的 CDP 资源类型，无法还原时不收窄
+func ruleResourceTypes(m *rulespec.Match) []network.ResourceType {
+	for _, c := range m.AllOf {
+		if c.Type != rulespec.ConditionResourceType || len(c.Values) == 0 {
+			continue
+		}
+		var out []network.ResourceType
+		for _, v := range c.Values {
+			rt, ok := lookupResourceType(v)
+			if !ok {
+				return nil
+			}
+			out = append(out, rt)
+		}
+		return out
+	}
+	return nil
+}
+
+// lookupResourceType 不区分大小写查找 CDP 资源类型
+func lookupResourceType(v string) (network.ResourceType, bool) {
+	for _, rt := range fetchResourceTypes {
+		if strings.EqualFold(string(rt), v) {
+			return rt, true
+		}
+	}
+	return "", false
+}
+
+// escapeFetchPattern 转义 Fetch 通配模式中的特殊字符
+func escapeFetchPattern(s string) string {
+	r := strings.NewReplacer(`\`, `\\`, `*`, `\*`, `?`, `\?`)
+	return r.Replace(s)
+}
+
+// globFetchPattern 将 urlGlob 放宽为 Fetch 通配模式：** 与 * 同为任意字符，
+// 未指定的协议、端口、路径和查询串均放宽为 *
+func globFetchPattern(pattern string) string {
+	if pattern == "" {
+		return "*"
+	}
+	g := rulespec.ParseURLGlob(pattern)
+	var b strings.Builder
+	if g.Scheme == "" {
+		b.WriteString("*")
+	} else {
+		b.WriteString(globFetchPart(strings.ToLower(g.Scheme)) + "://")
+	}
+	// 主机在浏览器中已转为小写；未指定端口时主机后可能带端口
+	b.WriteString(globFetchPart(strings.ToLower(g.Host)))
+	if g.Host != "" && !g.HostHasPort() {
+		b.WriteString("*")
+	}
+	// /** 可以匹配零个路径段，连同前面的 / 一起放宽
+	b.WriteString(globFetchPart(strings.ReplaceAll(g.Path, "/**", "*")))
+	if g.HasQuery {
+		b.WriteString(`\?` + globFetchPart(g.Query))
+	} else {
+		b.WriteString("*")
+	}
+	out := b.String()
+	for strings.Contains(out, "**") {
+		out = strings.ReplaceAll(out, "**", "*")
+	}
+	return out
+}
+
+// globFetchPart 转义 glob 中的字面量，保留 * 通配
+func globFetchPart(s string) string {
+	parts := strings.Split(strings.ReplaceAll(s, "**", "*"), "*")
+	for i, p := range parts {
+		parts[i] = escapeFetchPattern(p)
+	}
+	return strings.Join(parts, "*")
+}
+
+// strPtr 返回字符串指针
+func strPtr(s string) *string {
+	return &s
+}
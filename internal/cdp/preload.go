@@ -0,0 +1,113 @@
+package cdp
+
+import (
+	"encoding/json"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/mafredri/cdp/protocol/fetch"
+
+	"cdpnetool/internal/mutation"
+	"cdpnetool/pkg/model"
+)
+
+// earlyHintTTL 103 Early Hints 中预加载链接的保留时间，之后发出的同名请求不再视为由 Early Hints 触发
+const earlyHintTTL = 30 * time.Second
+
+// hintSet 目标最近收到的 103 Early Hints 预加载链接
+type hintSet struct {
+	mu      sync.Mutex
+	entries map[string]time.Time // 链接（可能为相对地址）-> 过期时间
+}
+
+// add 记录预加载链接，并清理已过期的条目
+func (s *hintSet) add(links []string) {
+	now := time.Now()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for k, exp := range s.entries {
+		if now.After(exp) {
+			delete(s.entries, k)
+		}
+	}
+	for _, l := range links {
+		s.entries[l] = now.Add(earlyHintTTL)
+	}
+}
+
+// contains 判断请求 URL 是否出现在未过期的预加载链接中，相对链接按请求 URL 解析
+func (s *hintSet) contains(rawURL string) bool {
+	base, err := url.Parse(rawURL)
+	if err != nil {
+		return false
+	}
+	now := time.Now()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for l, exp := range s.entries {
+		if now.After(exp) {
+			continue
+		}
+		if ref, err := url.Parse(l); err == nil && base.ResolveReference(ref).String() == rawURL {
+			return true
+		}
+	}
+	return false
+}
+
+// consumeEarlyHints 订阅 103 Early Hints，记录其中的预加载链接以便标记随后发出的预加载请求
+func (m *Manager) consumeEarlyHints(ts *targetSession) {
+	hints, err := ts.client.Network.ResponseReceivedEarlyHints(ts.ctx)
+	if err != nil {
+		m.log.Err(err, "订阅 Early Hints 事件失败", "target", string(ts.id))
+		return
+	}
+	set := &hintSet{entries: make(map[string]time.Time)}
+	m.earlyHints.Store(ts.id, set)
+
+	go func() {
+		defer hints.Close()
+		defer m.earlyHints.Delete(ts.id)
+		for {
+			ev, err := hints.Recv()
+			if err != nil {
+				return
+			}
+			var headers map[string]string
+			if err := json.Unmarshal(ev.Headers, &headers); err != nil {
+				continue
+			}
+			var links []string
+			for name, value := range headers {
+				if !strings.EqualFold(name, "Link") {
+					continue
+				}
+				// 同名头部在原始头部中以换行分隔
+				for _, v := range strings.Split(value, "\n") {
+					for _, entry := range mutation.SplitLinks(v) {
+						if mutation.IsPreloadLink(entry) {
+							links = append(links, mutation.LinkTarget(entry))
+						}
+					}
+				}
+			}
+			if len(links) > 0 {
+				set.add(links)
+				m.log.Debug("收到 Early Hints 预加载链接", "target", string(ts.id), "links", links)
+			}
+		}
+	}()
+}
+
+// preloadKind 判断请求是否由预加载触发：earlyHints 表示由 103 Early Hints 触发，link 表示由 Link 预加载触发
+func (m *Manager) preloadKind(target model.TargetID, ev *fetch.RequestPausedReply) string {
+	if v, ok := m.earlyHints.Load(target); ok && v.(*hintSet).contains(ev.Request.URL) {
+		return model.PreloadEarlyHints
+	}
+	if ev.Request.IsLinkPreload != nil && *ev.Request.IsLinkPreload {
+		return model.PreloadLink
+	}
+	return ""
+}
@@ -0,0 +1,206 @@
+package cdp
+
+import (
+	"crypto/tls"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/mafredri/cdp/protocol/network"
+
+	"cdpnetool/pkg/model"
+)
+
+// connMaxOrigins 每个目标记录连接信息的源数量上限，超出时清空重新记录
+const connMaxOrigins = 1000
+
+// connInfo 与源之间连接的协议元数据
+type connInfo struct {
+	protocol      string         // 协商的 HTTP 协议（h2 / h3 / http/1.1 等）
+	remoteAddress string         // 服务端地址（IP:端口）
+	tls           *model.TLSInfo // HTTPS 连接的 TLS 信息
+}
+
+// apply 将连接信息写入响应信息，已有的字段保持不变
+func (c connInfo) apply(info *model.ResponseInfo) {
+	if info.Protocol == "" {
+		info.Protocol = c.protocol
+	}
+	if info.RemoteAddress == "" {
+		info.RemoteAddress = c.remoteAddress
+	}
+	if info.TLS == nil && c.tls != nil {
+		t := *c.tls
+		info.TLS = &t
+	}
+}
+
+// connTable 目标各个源最近一次响应的连接信息。
+// 浏览器在请求完成后才通过 Network.responseReceived 上报协议和地址，响应阶段暂停时同一请求的信息尚不可知，
+// 因此按源（协议 + 主机 + 端口）记录，同一源的后续请求通常复用已建立的连接
+type connTable struct {
+	mu      sync.Mutex
+	origins map[string]connInfo // 源 -> 连接信息
+}
+
+// set 记录源的连接信息
+func (t *connTable) set(origin string, info connInfo) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if _, ok := t.origins[origin]; !ok && len(t.origins) >= connMaxOrigins {
+		t.origins = make(map[string]connInfo)
+	}
+	t.origins[origin] = info
+}
+
+// get 返回源最近一次的连接信息
+func (t *connTable) get(origin string) (connInfo, bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	info, ok := t.origins[origin]
+	return info, ok
+}
+
+// consumeConnections 订阅响应接收事件，按源记录浏览器上报的协议、服务端地址和 TLS 信息
+func (m *Manager) consumeConnections(ts *targetSession) {
+	received, err := ts.client.Network.ResponseReceived(ts.ctx)
+	if err != nil {
+		m.log.Err(err, "订阅响应接收事件失败", "target", string(ts.id))
+		return
+	}
+	table := m.connTable(ts.id)
+
+	go func() {
+		defer received.Close()
+		defer m.connections.Delete(ts.id)
+		for {
+			ev, err := received.Recv()
+			if err != nil {
+				return
+			}
+			// 缓存命中的响应没有实际建立连接，不更新源的信息
+			if ev.Response.Protocol == nil || isCachedResponse(ev.Response.FromDiskCache, ev.Response.FromPrefetchCache) {
+				continue
+			}
+			if origin := requestOrigin(ev.Response.URL); origin != "" {
+				table.set(origin, networkConnInfo(&ev.Response))
+			}
+		}
+	}()
+}
+
+// networkConnInfo 从 Network 域的响应中提取连接信息
+func networkConnInfo(r *network.Response) connInfo {
+	var info connInfo
+	if r.Protocol != nil {
+		info.protocol = strings.ToLower(*r.Protocol)
+	}
+	if r.RemoteIPAddress != nil && *r.RemoteIPAddress != "" {
+		port := 0
+		if r.RemotePort != nil {
+			port = *r.RemotePort
+		}
+		info.remoteAddress = net.JoinHostPort(strings.Trim(*r.RemoteIPAddress, "[]"), strconv.Itoa(port))
+	}
+	if d := r.SecurityDetails; d != nil {
+		info.tls = &model.TLSInfo{
+			Protocol:    d.Protocol,
+			Cipher:      d.Cipher,
+			SubjectName: d.SubjectName,
+			Issuer:      d.Issuer,
+			ValidTo:     d.ValidTo.Time().UnixMilli(),
+		}
+	}
+	return info
+}
+
+// connTable 返回目标的连接信息表，不存在时创建
+func (m *Manager) connTable(target model.TargetID) *connTable {
+	v, _ := m.connections.LoadOrStore(target, &connTable{origins: make(map[string]connInfo)})
+	return v.(*connTable)
+}
+
+// connectionOf 返回请求所属源最近一次的连接信息，尚未收到该源的响应时 ok 为 false
+func (m *Manager) connectionOf(target model.TargetID, rawURL string) (connInfo, bool) {
+	v, ok := m.connections.Load(target)
+	if !ok {
+		return connInfo{}, false
+	}
+	return v.(*connTable).get(requestOrigin(rawURL))
+}
+
+// protocolOf 返回请求所属源最近一次协商的协议，尚未收到该源的响应时为空
+func (m *Manager) protocolOf(target model.TargetID, rawURL string) string {
+	info, _ := m.connectionOf(target, rawURL)
+	return info.protocol
+}
+
+// recordProxyConnection 记录代理转发时与上游的连接信息；net/http 不提供服务端地址
+func (m *Manager) recordProxyConnection(rawURL string, resp *http.Response) {
+	origin := requestOrigin(rawURL)
+	if origin == "" {
+		return
+	}
+	info := connInfo{protocol: httpProtocol(resp)}
+	if cs := resp.TLS; cs != nil {
+		info.tls = &model.TLSInfo{Protocol: tlsVersionName(cs.Version), Cipher: tls.CipherSuiteName(cs.CipherSuite)}
+		if len(cs.PeerCertificates) > 0 {
+			cert := cs.PeerCertificates[0]
+			info.tls.SubjectName = cert.Subject.CommonName
+			info.tls.Issuer = cert.Issuer.CommonName
+			info.tls.ValidTo = cert.NotAfter.UnixMilli()
+		}
+	}
+	m.connTable(ProxyTarget).set(origin, info)
+}
+
+// httpProtocol 将 net/http 的协议版本转换为浏览器上报的协议名称
+func httpProtocol(resp *http.Response) string {
+	switch resp.ProtoMajor {
+	case 2:
+		return "h2"
+	case 3:
+		return "h3"
+	}
+	return strings.ToLower(resp.Proto)
+}
+
+// tlsVersionName 返回与浏览器上报一致的 TLS 版本名称
+func tlsVersionName(v uint16) string {
+	switch v {
+	case tls.VersionTLS10:
+		return "TLS 1.0"
+	case tls.VersionTLS11:
+		return "TLS 1.1"
+	case tls.VersionTLS12:
+		return "TLS 1.2"
+	case tls.VersionTLS13:
+		return "TLS 1.3"
+	}
+	return ""
+}
+
+// requestOrigin 返回 URL 的源，仅支持 HTTP(S)
+func requestOrigin(rawURL string) string {
+	if !isCapturableURL(rawURL) {
+		return ""
+	}
+	u, err := url.Parse(rawURL)
+	if err != nil || u.Host == "" {
+		return ""
+	}
+	return strings.ToLower(u.Scheme + "://" + u.Host)
+}
+
+// isCachedResponse 判断响应是否来自浏览器缓存
+func isCachedResponse(flags ...*bool) bool {
+	for _, f := range flags {
+		if f != nil && *f {
+			return true
+		}
+	}
+	return false
+}
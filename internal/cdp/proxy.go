@@ -0,0 +1,748 @@
+package cdp
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/mafredri/cdp/protocol/fetch"
+	"github.com/mafredri/cdp/protocol/network"
+
+	"cdpnetool/internal/mutation"
+	"cdpnetool/internal/rules"
+	"cdpnetool/pkg/model"
+	"cdpnetool/pkg/rulespec"
+)
+
+// ProxyTarget 内置转发代理捕获的请求在事件中使用的目标 ID
+const ProxyTarget model.TargetID = "proxy"
+
+// 内置转发代理的默认参数
+const (
+	defaultProxyAddr = "127.0.0.1:8899" // 默认监听地址
+	proxyDialTimeout = 10 * time.Second // 连接上游的超时
+)
+
+// proxyHopHeaders 逐跳头部，只对代理与客户端之间的连接有效，转发时移除
+var proxyHopHeaders = []string{
+	"Connection", "Proxy-Connection", "Keep-Alive", "Proxy-Authenticate", "Proxy-Authorization",
+	"Te", "Trailer", "Transfer-Encoding", "Upgrade",
+}
+
+// proxyServer 内置转发代理（HTTP 与 SOCKS5 共用端口），供浏览器之外的流量（原生应用、curl 等）复用会话的规则与事件流。
+// 明文 HTTP 请求按规则处理，HTTPS 经 CONNECT 或 SOCKS5 建立隧道原样转发，不解密也不记录
+type proxyServer struct {
+	m         *Manager
+	ln        net.Listener
+	srv       *http.Server
+	transport *http.Transport
+	ctx       context.Context
+	cancel    context.CancelFunc
+	seq       atomic.Uint64
+
+	mu       sync.Mutex
+	closed   bool
+	inflight sync.WaitGroup // 处理中的请求与隧道，停止时等待其结束，受 mu 保护
+}
+
+// StartProxy 在 addr 上启动内置转发代理，addr 为空时使用 127.0.0.1:8899，返回实际监听地址。
+// 同一端口同时接受 HTTP 代理和 SOCKS5 代理，addr 可带 http:// 或 socks5:// 前缀。
+// 代理与浏览器目标的拦截开关无关，启动后即按当前规则处理经过代理的请求
+func (m *Manager) StartProxy(addr string) (string, error) {
+	m.proxyMu.Lock()
+	defer m.proxyMu.Unlock()
+	if m.proxy != nil {
+		return "", fmt.Errorf("proxy already listening on %s", m.proxy.ln.Addr())
+	}
+	addr, err := parseProxyAddr(addr)
+	if err != nil {
+		return "", err
+	}
+	if addr == "" {
+		addr = defaultProxyAddr
+	}
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		return "", fmt.Errorf("proxy listen: %w", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	p := &proxyServer{
+		m:      m,
+		ln:     ln,
+		ctx:    ctx,
+		cancel: cancel,
+		// 不读取环境变量中的代理设置，避免请求绕回自身
+		transport: &http.Transport{
+			DialContext:         (&net.Dialer{Timeout: proxyDialTimeout}).DialContext,
+			TLSHandshakeTimeout: proxyDialTimeout,
+			IdleConnTimeout:     90 * time.Second,
+			MaxIdleConnsPerHost: 16,
+		},
+	}
+	p.srv = &http.Server{
+		Handler:           p,
+		ReadHeaderTimeout: 30 * time.Second,
+		BaseContext:       func(net.Listener) context.Context { return ctx },
+		ConnContext:       proxyConnContext,
+	}
+	pl := newProxyListener(p, ln)
+	go func() {
+		if err := p.srv.Serve(pl); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			m.log.Err(err, "内置代理停止服务", "addr", ln.Addr().String())
+		}
+	}()
+	m.proxy = p
+	m.log.Info("内置转发代理已启动", "addr", ln.Addr().String())
+	return ln.Addr().String(), nil
+}
+
+// StopProxy 停止内置转发代理，等待处理中的请求结束；代理未启动时不做任何事
+func (m *Manager) StopProxy() {
+	m.proxyMu.Lock()
+	p := m.proxy
+	m.proxy = nil
+	m.proxyMu.Unlock()
+	if p == nil {
+		return
+	}
+
+	p.mu.Lock()
+	p.closed = true
+	p.mu.Unlock()
+	p.cancel()
+	_ = p.srv.Close()
+	p.inflight.Wait()
+	p.transport.CloseIdleConnections()
+	m.log.Info("内置转发代理已停止", "addr", p.ln.Addr().String())
+}
+
+// ProxyAddr 返回内置转发代理的监听地址，未启动时为空
+func (m *Manager) ProxyAddr() string {
+	m.proxyMu.Lock()
+	defer m.proxyMu.Unlock()
+	if m.proxy == nil {
+		return ""
+	}
+	return m.proxy.ln.Addr().String()
+}
+
+// begin 登记一个处理中的请求或隧道，代理已停止时返回 false
+func (p *proxyServer) begin() bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.closed {
+		return false
+	}
+	p.inflight.Add(1)
+	return true
+}
+
+// ServeHTTP 处理一次代理请求
+func (p *proxyServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if !p.begin() {
+		http.Error(w, "cdpnetool proxy: stopped", http.StatusServiceUnavailable)
+		return
+	}
+	defer p.inflight.Done()
+
+	if r.Method == http.MethodConnect {
+		p.tunnel(w, r)
+		return
+	}
+	// 经 SOCKS5 隧道的请求是 origin-form，按 Host 头（缺失时按连接目标）补全为绝对 URL
+	if target, ok := r.Context().Value(socksTargetKey{}).(string); ok && !r.URL.IsAbs() {
+		r.URL.Scheme, r.URL.Host = "http", r.Host
+		if r.URL.Host == "" {
+			r.URL.Host = target
+		}
+	}
+	if !r.URL.IsAbs() {
+		http.Error(w, "cdpnetool proxy: absolute URL required", http.StatusBadRequest)
+		return
+	}
+	p.serve(w, r)
+}
+
+// tunnel 为 CONNECT 请求建立到目标主机的隧道并双向转发，代理停止时关闭
+func (p *proxyServer) tunnel(w http.ResponseWriter, r *http.Request) {
+	dst, err := (&net.Dialer{Timeout: proxyDialTimeout}).DialContext(r.Context(), "tcp", r.Host)
+	if err != nil {
+		http.Error(w, "cdpnetool proxy: "+err.Error(), http.StatusBadGateway)
+		return
+	}
+	hj, ok := w.(http.Hijacker)
+	if !ok {
+		_ = dst.Close()
+		http.Error(w, "cdpnetool proxy: hijacking not supported", http.StatusInternalServerError)
+		return
+	}
+	src, rw, err := hj.Hijack()
+	if err != nil {
+		_ = dst.Close()
+		return
+	}
+	p.m.log.Debug("建立代理隧道", "host", r.Host)
+	_, _ = src.Write([]byte("HTTP/1.1 200 Connection Established\r\n\r\n"))
+	p.pipe(src, rw.Reader, dst)
+}
+
+// pipe 在客户端与目标之间双向转发，任一方向结束或代理停止时关闭两端；r 为客户端连接上已缓冲的读取端
+func (p *proxyServer) pipe(src net.Conn, r io.Reader, dst net.Conn) {
+	done := make(chan struct{}, 2)
+	go func() {
+		_, _ = io.Copy(dst, r)
+		done <- struct{}{}
+	}()
+	go func() {
+		_, _ = io.Copy(src, dst)
+		done <- struct{}{}
+	}()
+	select {
+	case <-done:
+	case <-p.ctx.Done():
+	}
+	_ = src.Close()
+	_ = dst.Close()
+}
+
+// serve 以与浏览器拦截相同的规则、主机策略和事件处理一次明文 HTTP 请求：
+// 先按请求阶段规则改写或直接响应，转发后再按响应阶段规则改写响应
+func (p *proxyServer) serve(w http.ResponseWriter, r *http.Request) {
+	m := p.m
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		http.Error(w, "cdpnetool proxy: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	removeHopHeaders(r.Header)
+	ev := newProxyPaused("proxy-"+strconv.FormatUint(p.seq.Add(1), 10), r, body)
+	m.log.Debug("开始处理代理请求", "url", ev.Request.URL, "method", ev.Request.Method)
+
+	// 属于屏蔽的资源类别，不经规则处理直接失败
+	if !m.captureOnly && m.currentResourceBlock().blocks(ev) {
+		p.fail(w)
+		m.sendBlockedEvent(ProxyTarget, ev)
+		return
+	}
+
+	permitted := m.isHostPermitted(ev.Request.URL)
+	var mut *RequestMutation
+	if matched := p.evaluate(ev, rulespec.StageRequest, permitted); len(matched) > 0 {
+		var done bool
+		if mut, done = p.requestRules(w, r, ev, matched); done {
+			return
+		}
+	} else {
+		m.sendUnmatchedEvent(ProxyTarget, ev, rulespec.StageRequest, 0)
+		if permitted {
+			if cred := mutation.NewRequestMutation(); m.injectCredentials(ev, cred) {
+				mut = cred
+			}
+		}
+	}
+
+	resp, err := p.roundTrip(r.Context(), ev, mut)
+	if err != nil {
+		m.log.Warn("代理转发请求失败", "url", ev.Request.URL, "error", err)
+		http.Error(w, "cdpnetool proxy: "+err.Error(), http.StatusBadGateway)
+		return
+	}
+	defer resp.Body.Close()
+	m.recordProxyConnection(ev.Request.URL, resp)
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		m.log.Warn("读取代理响应失败", "url", ev.Request.URL, "error", err)
+		p.fail(w)
+		return
+	}
+
+	// 响应阶段沿用原始请求信息，与浏览器拦截的响应阶段一致
+	removeHopHeaders(resp.Header)
+	status := resp.StatusCode
+	ev.ResponseStatusCode = &status
+	ev.ResponseHeaders = proxyHeaderEntries(resp.Header)
+	if matched := p.evaluate(ev, rulespec.StageResponse, permitted); len(matched) > 0 {
+		p.responseRules(w, r, ev, matched, respBody)
+		return
+	}
+	if permitted && m.observesAllResponses() {
+		m.observeResponse(ProxyTarget, ev, string(respBody))
+	}
+	m.sendUnmatchedEvent(ProxyTarget, ev, rulespec.StageResponse, status)
+	if permitted {
+		p.respondWithPolicy(w, ev, respBody)
+		return
+	}
+	writeProxyResponse(w, status, ev.ResponseHeaders, respBody)
+}
+
+// evaluate 返回当前阶段命中的规则，主机不在允许范围内或未加载规则时为空
+func (p *proxyServer) evaluate(ev *fetch.RequestPausedReply, stage rulespec.Stage, permitted bool) []*rules.MatchedRule {
+	if !permitted || p.m.engine == nil {
+		return nil
+	}
+	return p.m.engine.EvalForStage(p.m.buildEvalContext(ProxyTarget, ev), stage)
+}
+
+// requestRules 执行请求阶段命中规则的行为，返回转发时应用的变更；已直接响应或失败时 done 为 true
+func (p *proxyServer) requestRules(w http.ResponseWriter, r *http.Request, ev *fetch.RequestPausedReply, matchedRules []*rules.MatchedRule) (*RequestMutation, bool) {
+	m := p.m
+	ctx, cancel := context.WithTimeout(r.Context(), m.processTimeout())
+	defer cancel()
+
+	requestInfo, responseInfo, _, _ := m.captureOriginalData(ctx, nil, ev, rulespec.StageRequest, matchedRules)
+	ruleMatches := buildRuleMatches(matchedRules)
+	if m.captureOnly {
+		m.sendMatchedEvent(ProxyTarget, ev, "passed", ruleMatches, requestInfo, responseInfo, nil)
+		return nil, false
+	}
+
+	var aggregatedMut *RequestMutation
+	var steps []model.BodyTransform
+	merger := mutation.NewMerger(m.currentConfig().ConflictPolicy())
+	requestBody := requestBodyContent(ev)
+
+	for _, matched := range matchedRules {
+		rule := matched.Rule
+		if len(rule.Actions) == 0 {
+			continue
+		}
+		mut := m.executor.ExecuteRequestActions(ctx, rule.Actions, ev, requestBody, m.templateVars(matched.Params))
+		if mut == nil {
+			continue
+		}
+		m.storeVars(ProxyTarget, ev.Request.URL, mut.Captures)
+		steps = append(steps, bodySteps(mut.BodySteps, rule)...)
+		if mut.Body != nil {
+			requestBody = *mut.Body
+		}
+		delay := mut.Delay
+		if aggregatedMut != nil {
+			delay += aggregatedMut.Delay
+		}
+
+		if mut.Block != nil {
+			if len(mut.Block.Body) > 0 && m.shouldBypassRange(ev) {
+				m.sendMatchedEvent(ProxyTarget, ev, "passed", ruleMatches, requestInfo, responseInfo, steps)
+				m.log.Info("Range 请求跳过 Body 改写", "rule", rule.ID, "url", ev.Request.URL)
+				return nil, false
+			}
+			if !sleepContext(r.Context(), delay) {
+				return nil, true
+			}
+			code, headers, body := mut.Block.StatusCode, toHeaderEntries(mut.Block.Headers), mut.Block.Body
+			if len(body) > 0 {
+				code, headers, body = applyRangePolicy(ev, code, headers, body)
+			}
+			writeProxyResponse(w, code, headers, body)
+			if mut.Block.Redirect {
+				m.sendMatchedEvent(ProxyTarget, ev, "modified", ruleMatches, requestInfo, model.ResponseInfo{
+					StatusCode: mut.Block.StatusCode,
+					Headers:    mut.Block.Headers,
+				}, steps)
+				m.log.Info("代理请求被重定向", "rule", rule.ID, "url", ev.Request.URL, "location", mut.Block.Headers["Location"])
+				return nil, true
+			}
+			m.sendMatchedEvent(ProxyTarget, ev, "blocked", ruleMatches, requestInfo, responseInfo, steps)
+			m.log.Info("代理请求被阻止", "rule", rule.ID, "url", ev.Request.URL)
+			return nil, true
+		}
+
+		if mut.Terminate != nil {
+			if sleepContext(r.Context(), mut.Terminate.After+delay) {
+				p.fail(w)
+			}
+			m.sendMatchedEvent(ProxyTarget, ev, "blocked", ruleMatches, requestInfo, responseInfo, steps)
+			m.log.Info("代理请求被终止", "rule", rule.ID, "url", ev.Request.URL, "after", mut.Terminate.After)
+			return nil, true
+		}
+
+		if aggregatedMut == nil {
+			aggregatedMut = mutation.NewRequestMutation()
+		}
+		merger.MergeRequest(aggregatedMut, mut, matched)
+	}
+
+	// 规则变更相互矛盾且策略为放弃时，原样转发
+	if m.reportConflicts(ProxyTarget, ev.Request.URL, merger) {
+		m.sendMatchedEvent(ProxyTarget, ev, "passed", ruleMatches, requestInfo, responseInfo, steps)
+		m.log.Info("规则变更冲突，放弃修改", "url", ev.Request.URL)
+		return nil, false
+	}
+	if aggregatedMut == nil {
+		aggregatedMut = mutation.NewRequestMutation()
+	}
+	if !sleepContext(r.Context(), aggregatedMut.Delay) {
+		return nil, true
+	}
+	if aggregatedMut.Body != nil && m.bodyOverLimit(ProxyTarget, ev.Request.URL, rulespec.StageRequest, len(aggregatedMut.Body.Data)) {
+		m.sendMatchedEvent(ProxyTarget, ev, "passed", ruleMatches, requestInfo, responseInfo, steps)
+		return nil, false
+	}
+
+	m.executor.signRequest(ev, aggregatedMut)
+	m.injectCredentials(ev, aggregatedMut)
+	if aggregatedMut.Pause > 0 {
+		m.log.Warn("代理请求不支持断点，已直接应用变更", "url", ev.Request.URL)
+	}
+	if !hasRequestMutation(aggregatedMut) {
+		m.sendMatchedEvent(ProxyTarget, ev, "passed", ruleMatches, requestInfo, responseInfo, steps)
+		return nil, false
+	}
+	m.sendMatchedEvent(ProxyTarget, ev, "modified", ruleMatches, m.captureModifiedRequestData(requestInfo, aggregatedMut), responseInfo, steps)
+	return aggregatedMut, false
+}
+
+// responseRules 执行响应阶段命中规则的行为并写回响应
+func (p *proxyServer) responseRules(w http.ResponseWriter, r *http.Request, ev *fetch.RequestPausedReply, matchedRules []*rules.MatchedRule, body []byte) {
+	m := p.m
+	ctx, cancel := context.WithTimeout(r.Context(), m.processTimeout())
+	defer cancel()
+
+	requestInfo, responseInfo, _, _ := m.captureOriginalData(ctx, nil, ev, rulespec.StageRequest, matchedRules)
+	responseInfo.StatusCode = getStatusCode(ev)
+	for _, h := range ev.ResponseHeaders {
+		responseInfo.Headers[h.Name] = h.Value
+	}
+	originalBody := mutation.NewBodyContent(body, responseContentType(ev))
+	if len(body) > 0 {
+		responseInfo.Body, responseInfo.BodyEncoding, responseInfo.BodyTruncated = m.eventBody(originalBody)
+	}
+	if !originalBody.IsBinary {
+		m.observeSchema(ProxyTarget, ev, originalBody.Text())
+	}
+	m.observeContent(ProxyTarget, ev, body)
+
+	ruleMatches := buildRuleMatches(matchedRules)
+	if m.captureOnly {
+		m.sendMatchedEvent(ProxyTarget, ev, "passed", ruleMatches, requestInfo, responseInfo, nil)
+		writeProxyResponse(w, getStatusCode(ev), ev.ResponseHeaders, body)
+		return
+	}
+
+	responseBody := originalBody
+	var aggregatedMut *ResponseMutation
+	var steps []model.BodyTransform
+	merger := mutation.NewMerger(m.currentConfig().ConflictPolicy())
+
+	for _, matched := range matchedRules {
+		rule := matched.Rule
+		if len(rule.Actions) == 0 {
+			continue
+		}
+		mut := m.executor.ExecuteResponseActions(ctx, rule.Actions, ev, responseBody, m.templateVars(matched.Params))
+		if mut == nil {
+			continue
+		}
+		m.storeVars(ProxyTarget, ev.Request.URL, mut.Captures)
+		steps = append(steps, bodySteps(mut.BodySteps, rule)...)
+
+		if mut.Terminate != nil {
+			after := mut.Terminate.After + mut.Delay
+			if aggregatedMut != nil {
+				after += aggregatedMut.Delay
+			}
+			if sleepContext(r.Context(), after) {
+				p.terminate(w, ev, mut.Terminate)
+			}
+			m.sendMatchedEvent(ProxyTarget, ev, "blocked", ruleMatches, requestInfo, responseInfo, steps)
+			m.log.Info("代理响应被终止", "rule", rule.ID, "url", ev.Request.URL, "truncated", mut.Terminate.Truncated)
+			return
+		}
+
+		if aggregatedMut == nil {
+			aggregatedMut = mutation.NewResponseMutation()
+		}
+		merger.MergeResponse(aggregatedMut, mut, matched)
+		if mut.Body != nil {
+			responseBody = *mut.Body
+		}
+	}
+
+	// 规则变更相互矛盾且策略为放弃时，原样返回
+	if m.reportConflicts(ProxyTarget, ev.Request.URL, merger) {
+		p.respondWithPolicy(w, ev, body)
+		m.sendMatchedEvent(ProxyTarget, ev, "passed", ruleMatches, requestInfo, responseInfo, steps)
+		m.log.Info("规则变更冲突，放弃修改", "url", ev.Request.URL)
+		return
+	}
+	if aggregatedMut == nil {
+		aggregatedMut = mutation.NewResponseMutation()
+	}
+
+	// Range 请求按策略跳过 Body 改写，仅保留状态码和头部修改
+	bypassBody := m.shouldBypassRange(ev)
+	if bypassBody && aggregatedMut.Body != nil {
+		m.log.Info("Range 请求跳过 Body 改写", "url", ev.Request.URL)
+		aggregatedMut.Body = nil
+		responseBody = originalBody
+	}
+
+	// 改写后的响应体过大时放弃全部修改
+	if aggregatedMut.Body != nil && m.bodyOverLimit(ProxyTarget, ev.Request.URL, rulespec.StageResponse, len(aggregatedMut.Body.Data)) {
+		if sleepContext(r.Context(), aggregatedMut.Delay) {
+			p.respondWithPolicy(w, ev, body)
+		}
+		m.sendMatchedEvent(ProxyTarget, ev, "passed", ruleMatches, requestInfo, responseInfo, steps)
+		return
+	}
+
+	if policy, ok := m.headerPolicyFor(ev); ok {
+		applyHeaderPolicy(policy, aggregatedMut)
+	}
+	if aggregatedMut.Pause > 0 {
+		m.log.Warn("代理响应不支持断点，已直接应用变更", "url", ev.Request.URL)
+	}
+
+	if !hasResponseMutation(aggregatedMut) {
+		delay := aggregatedMut.Delay + throttleDelay(ev, aggregatedMut, responseBody, bypassBody)
+		if sleepContext(r.Context(), delay) {
+			writeProxyResponse(w, getStatusCode(ev), ev.ResponseHeaders, body)
+		}
+		m.sendMatchedEvent(ProxyTarget, ev, "passed", ruleMatches, requestInfo, responseInfo, steps)
+		return
+	}
+	if aggregatedMut.Body == nil && len(responseBody.Data) > 0 && !bypassBody {
+		aggregatedMut.Body = &responseBody
+	}
+	m.compressResponse(ev, aggregatedMut)
+	delay := aggregatedMut.Delay + throttleDelay(ev, aggregatedMut, responseBody, bypassBody)
+	if sleepContext(r.Context(), delay) {
+		p.respond(w, ev, aggregatedMut, body)
+	}
+	m.sendMatchedEvent(ProxyTarget, ev, "modified", ruleMatches, requestInfo, m.captureModifiedResponseData(responseInfo, aggregatedMut, responseBody), steps)
+}
+
+// roundTrip 按请求变更将请求转发到上游；mut 为 nil 时原样转发
+func (p *proxyServer) roundTrip(ctx context.Context, ev *fetch.RequestPausedReply, mut *RequestMutation) (*http.Response, error) {
+	target, method, body := ev.Request.URL, ev.Request.Method, []byte(GetRequestBody(ev))
+	headers := make(map[string]string)
+	if mut != nil {
+		if u := mutation.BuildURL(target, mut); u != nil {
+			target = *u
+		}
+		if mut.Method != nil {
+			method = *mut.Method
+		}
+		if mut.Body != nil {
+			body = mut.Body.Data
+		}
+		headers = mutation.BuildRequestHeaders(requestHeaders(ev), mut)
+	} else {
+		_ = json.Unmarshal(ev.Request.Headers, &headers)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, target, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	for name, value := range headers {
+		// 由传输层协商压缩并自动解压，规则处理的响应体与浏览器拦截时一样是解压后的内容
+		if strings.EqualFold(name, "Content-Length") || strings.EqualFold(name, "Accept-Encoding") {
+			continue
+		}
+		req.Header.Set(name, value)
+	}
+	return p.transport.RoundTrip(req)
+}
+
+// respondWithPolicy 写回原始响应，适用时应用会话级响应头策略
+func (p *proxyServer) respondWithPolicy(w http.ResponseWriter, ev *fetch.RequestPausedReply, body []byte) {
+	if policy, ok := p.m.headerPolicyFor(ev); ok {
+		mut := mutation.NewResponseMutation()
+		if applyHeaderPolicy(policy, mut) {
+			p.respond(w, ev, mut, body)
+			return
+		}
+	}
+	writeProxyResponse(w, getStatusCode(ev), ev.ResponseHeaders, body)
+}
+
+// respond 应用响应变更后写回响应，body 为上游返回的原始响应体
+func (p *proxyServer) respond(w http.ResponseWriter, ev *fetch.RequestPausedReply, mut *ResponseMutation, body []byte) {
+	code := getStatusCode(ev)
+	if mut.StatusCode != nil {
+		code = *mut.StatusCode
+	}
+	headers := proxyResponseHeaders(ev, mut)
+	if mut.Body != nil {
+		headers = normalizeDecodedBody(headers, mut.Body.Data, setsContentEncoding(mut.Headers))
+		code, headers, body = applyRangePolicy(ev, code, headers, mut.Body.Data)
+	}
+	writeProxyResponse(w, code, headers, body)
+}
+
+// terminate 按终止参数结束响应：以截断的 Body 返回，或断开连接使请求失败
+func (p *proxyServer) terminate(w http.ResponseWriter, ev *fetch.RequestPausedReply, spec *TerminateSpec) {
+	if !spec.Truncated {
+		p.fail(w)
+		return
+	}
+	headers := make([]fetch.HeaderEntry, 0, len(ev.ResponseHeaders))
+	for _, h := range ev.ResponseHeaders {
+		if strings.EqualFold(h.Name, "Content-Length") || strings.EqualFold(h.Name, "Content-Encoding") {
+			continue
+		}
+		headers = append(headers, h)
+	}
+	writeProxyResponse(w, getStatusCode(ev), headers, spec.Body)
+}
+
+// fail 断开客户端连接以模拟网络错误，无法接管连接时返回 502
+func (p *proxyServer) fail(w http.ResponseWriter) {
+	if hj, ok := w.(http.Hijacker); ok {
+		if conn, _, err := hj.Hijack(); err == nil {
+			_ = conn.Close()
+			return
+		}
+	}
+	http.Error(w, "cdpnetool proxy: request failed", http.StatusBadGateway)
+}
+
+// processTimeout 返回单次拦截处理的超时时间
+func (m *Manager) processTimeout() time.Duration {
+	to := m.processTimeoutMS
+	if to <= 0 {
+		to = 3000
+	}
+	return time.Duration(to) * time.Millisecond
+}
+
+// newProxyPaused 将代理请求转换为拦截事件，使规则匹配、行为执行和事件记录与浏览器拦截共用同一套逻辑
+func newProxyPaused(id string, r *http.Request, body []byte) *fetch.RequestPausedReply {
+	headers := make(map[string]string, len(r.Header)+1)
+	for name, values := range r.Header {
+		sep := ", "
+		if strings.EqualFold(name, "Cookie") {
+			sep = "; "
+		}
+		headers[name] = strings.Join(values, sep)
+	}
+	if _, ok := headers["Host"]; !ok && r.Host != "" && r.Host != r.URL.Host {
+		headers["Host"] = r.Host
+	}
+	rawHeaders, _ := json.Marshal(headers)
+
+	nid := network.RequestID(id)
+	ev := &fetch.RequestPausedReply{
+		RequestID:    fetch.RequestID(id),
+		NetworkID:    &nid,
+		ResourceType: network.ResourceTypeOther,
+		Request: network.Request{
+			URL:     r.URL.String(),
+			Method:  r.Method,
+			Headers: network.Headers(rawHeaders),
+		},
+	}
+	if len(body) > 0 {
+		// 以 Base64 条目保存请求体，二进制内容不会被破坏
+		encoded := base64.StdEncoding.EncodeToString(body)
+		hasPostData := true
+		ev.Request.HasPostData = &hasPostData
+		ev.Request.PostDataEntries = []network.PostDataEntry{{Bytes: &encoded}}
+	}
+	return ev
+}
+
+// proxyHeaderEntries 将 HTTP 头部转换为头部条目，同名的多个值（如 Set-Cookie）分别保留
+func proxyHeaderEntries(h http.Header) []fetch.HeaderEntry {
+	out := make([]fetch.HeaderEntry, 0, len(h))
+	for name, values := range h {
+		for _, v := range values {
+			out = append(out, fetch.HeaderEntry{Name: name, Value: v})
+		}
+	}
+	return out
+}
+
+// proxyResponseHeaders 构建最终响应头，未被规则修改的同名多值头部（如 Set-Cookie）原样保留
+func proxyResponseHeaders(ev *fetch.RequestPausedReply, mut *ResponseMutation) []fetch.HeaderEntry {
+	replaced := func(name string) bool {
+		for _, n := range mut.RemoveHeaders {
+			if strings.EqualFold(n, name) {
+				return true
+			}
+		}
+		for n := range mut.Headers {
+			if strings.EqualFold(n, name) {
+				return true
+			}
+		}
+		return false
+	}
+	out := make([]fetch.HeaderEntry, 0, len(ev.ResponseHeaders)+len(mut.Headers))
+	for _, h := range ev.ResponseHeaders {
+		if !replaced(h.Name) {
+			out = append(out, h)
+		}
+	}
+	return append(out, toHeaderEntries(mut.Headers)...)
+}
+
+// removeHopHeaders 移除逐跳头部及 Connection 中声明的头部
+func removeHopHeaders(h http.Header) {
+	for _, v := range h.Values("Connection") {
+		for _, name := range strings.Split(v, ",") {
+			if name = strings.TrimSpace(name); name != "" {
+				h.Del(name)
+			}
+		}
+	}
+	for _, name := range proxyHopHeaders {
+		h.Del(name)
+	}
+}
+
+// writeProxyResponse 写回响应，Content-Length 按实际 Body 重写；Body 为空时保留原值（如 HEAD 响应）
+func writeProxyResponse(w http.ResponseWriter, code int, headers []fetch.HeaderEntry, body []byte) {
+	if code == 0 {
+		code = http.StatusOK
+	}
+	out := w.Header()
+	length := ""
+	for _, h := range headers {
+		if strings.EqualFold(h.Name, "Content-Length") {
+			length = h.Value
+			continue
+		}
+		if strings.EqualFold(h.Name, "Transfer-Encoding") {
+			continue
+		}
+		out.Add(h.Name, h.Value)
+	}
+	if len(body) > 0 || length == "" {
+		length = strconv.Itoa(len(body))
+	}
+	if code != http.StatusNoContent && code != http.StatusNotModified {
+		out.Set("Content-Length", length)
+	}
+	w.WriteHeader(code)
+	_, _ = w.Write(body)
+}
+
+// sleepContext 等待 d，ctx 先结束时返回 false
+func sleepContext(ctx context.Context, d time.Duration) bool {
+	if d <= 0 {
+		return ctx.Err() == nil
+	}
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-t.C:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
@@ -0,0 +1,258 @@
+package cdp
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// 内置代理在同一端口上同时提供 HTTP 代理和 SOCKS5 代理：按连接的首字节区分，
+// SOCKS5 握手完成后，隧道内是明文 HTTP 的连接交给 HTTP 代理按规则处理，其余（TLS 等）原样转发
+const (
+	socksVersion      = 0x05
+	proxySniffTimeout = 30 * time.Second       // 等待客户端首个字节的超时，与 HTTP 读取请求头的超时一致
+	socksPeekTimeout  = 500 * time.Millisecond // SOCKS5 隧道建立后等待客户端先发数据的时间，超时按服务端先发的协议原样转发
+)
+
+// SOCKS5 应答码（RFC 1928）
+const (
+	socksSucceeded           = 0x00
+	socksCommandNotSupported = 0x07
+	socksAddrNotSupported    = 0x08
+)
+
+// socksTargetKey 上下文中 SOCKS5 连接请求的目标地址
+type socksTargetKey struct{}
+
+// parseProxyAddr 校验内置代理的监听地址，允许带 http:// 或 socks5:// 前缀（两者共用同一端口）
+func parseProxyAddr(addr string) (string, error) {
+	scheme, host, ok := strings.Cut(addr, "://")
+	if !ok {
+		return addr, nil
+	}
+	switch strings.ToLower(scheme) {
+	case "http", "socks5", "socks5h":
+		return host, nil
+	case "socks", "socks4", "socks4a":
+		return "", fmt.Errorf("cdpnetool: proxy only supports SOCKS5, not %s", scheme)
+	}
+	return "", fmt.Errorf("cdpnetool: unsupported proxy scheme %q", scheme)
+}
+
+// peekConn 已预读部分数据的连接
+type peekConn struct {
+	net.Conn
+	r *bufio.Reader
+}
+
+func (c *peekConn) Read(b []byte) (int, error) { return c.r.Read(b) }
+
+// socksConn 经 SOCKS5 握手后交给 HTTP 代理处理的连接，target 为客户端请求连接的地址
+type socksConn struct {
+	*peekConn
+	target string
+}
+
+// proxyListener 内置代理的监听器：HTTP 连接直接交给 http.Server，SOCKS5 连接先完成握手再分流
+type proxyListener struct {
+	net.Listener
+	p      *proxyServer
+	conns  chan net.Conn
+	done   chan struct{}
+	failed chan struct{}
+	once   sync.Once
+	err    error // 底层 Accept 的错误，failed 关闭后可读
+}
+
+// newProxyListener 包装底层监听器并开始接受连接
+func newProxyListener(p *proxyServer, ln net.Listener) *proxyListener {
+	l := &proxyListener{
+		Listener: ln,
+		p:        p,
+		conns:    make(chan net.Conn),
+		done:     make(chan struct{}),
+		failed:   make(chan struct{}),
+	}
+	go l.run()
+	return l
+}
+
+// run 接受底层连接，在独立协程中识别协议，避免慢客户端阻塞其他连接
+func (l *proxyListener) run() {
+	for {
+		c, err := l.Listener.Accept()
+		if err != nil {
+			l.err = err
+			close(l.failed)
+			return
+		}
+		go l.dispatch(c)
+	}
+}
+
+// dispatch 按首字节区分 SOCKS5 与 HTTP
+func (l *proxyListener) dispatch(c net.Conn) {
+	br := bufio.NewReader(c)
+	_ = c.SetReadDeadline(time.Now().Add(proxySniffTimeout))
+	first, err := br.Peek(1)
+	_ = c.SetReadDeadline(time.Time{})
+	if err != nil {
+		_ = c.Close()
+		return
+	}
+	pc := &peekConn{Conn: c, r: br}
+	if first[0] == socksVersion {
+		l.p.serveSOCKS(l, pc)
+		return
+	}
+	l.deliver(pc)
+}
+
+// deliver 将连接交给 http.Server，监听器已关闭时关闭连接
+func (l *proxyListener) deliver(c net.Conn) {
+	select {
+	case l.conns <- c:
+	case <-l.done:
+		_ = c.Close()
+	}
+}
+
+// Accept 返回交给 http.Server 处理的连接
+func (l *proxyListener) Accept() (net.Conn, error) {
+	select {
+	case c := <-l.conns:
+		return c, nil
+	case <-l.failed:
+		return nil, l.err
+	case <-l.done:
+		return nil, net.ErrClosed
+	}
+}
+
+// Close 关闭监听器，之后识别出的连接直接关闭
+func (l *proxyListener) Close() error {
+	l.once.Do(func() { close(l.done) })
+	return l.Listener.Close()
+}
+
+// proxyConnContext 为 SOCKS5 转交的连接记录目标地址，供补全 origin-form 的请求 URL
+func proxyConnContext(ctx context.Context, c net.Conn) context.Context {
+	if sc, ok := c.(*socksConn); ok {
+		return context.WithValue(ctx, socksTargetKey{}, sc.target)
+	}
+	return ctx
+}
+
+// serveSOCKS 完成 SOCKS5 握手后分流：隧道内首先发送的是 HTTP 请求时交给 HTTP 代理按规则处理，否则原样转发。
+// 握手成功即应答，连接目标失败时直接关闭客户端连接
+func (p *proxyServer) serveSOCKS(l *proxyListener, c *peekConn) {
+	if !p.begin() {
+		_ = c.Close()
+		return
+	}
+	defer p.inflight.Done()
+
+	_ = c.SetDeadline(time.Now().Add(proxyDialTimeout))
+	target, err := socksHandshake(c)
+	if err != nil {
+		p.m.log.Debug("SOCKS5 握手失败", "remote", c.RemoteAddr().String(), "error", err)
+		_ = c.Close()
+		return
+	}
+	_ = c.SetReadDeadline(time.Now().Add(socksPeekTimeout))
+	first, err := c.r.Peek(1)
+	_ = c.SetDeadline(time.Time{})
+	if err == nil && first[0] >= 'A' && first[0] <= 'Z' {
+		l.deliver(&socksConn{peekConn: c, target: target})
+		return
+	}
+	var ne net.Error
+	if err != nil && !(errors.As(err, &ne) && ne.Timeout()) {
+		_ = c.Close()
+		return
+	}
+
+	dst, err := (&net.Dialer{Timeout: proxyDialTimeout}).DialContext(p.ctx, "tcp", target)
+	if err != nil {
+		p.m.log.Debug("SOCKS5 连接目标失败", "host", target, "error", err)
+		_ = c.Close()
+		return
+	}
+	p.m.log.Debug("建立 SOCKS5 隧道", "host", target)
+	p.pipe(c, c.r, dst)
+}
+
+// socksHandshake 按 RFC 1928 完成无认证的 SOCKS5 握手，只支持 CONNECT，返回目标地址
+func socksHandshake(rw io.ReadWriter) (string, error) {
+	hdr := make([]byte, 2)
+	if _, err := io.ReadFull(rw, hdr); err != nil {
+		return "", err
+	}
+	methods := make([]byte, hdr[1])
+	if _, err := io.ReadFull(rw, methods); err != nil {
+		return "", err
+	}
+	if !slices.Contains(methods, 0x00) {
+		_, _ = rw.Write([]byte{socksVersion, 0xFF})
+		return "", fmt.Errorf("no acceptable auth method")
+	}
+	if _, err := rw.Write([]byte{socksVersion, 0x00}); err != nil {
+		return "", err
+	}
+
+	req := make([]byte, 4)
+	if _, err := io.ReadFull(rw, req); err != nil {
+		return "", err
+	}
+	if req[0] != socksVersion {
+		return "", fmt.Errorf("unexpected version %d", req[0])
+	}
+	if req[1] != 0x01 {
+		socksReply(rw, socksCommandNotSupported)
+		return "", fmt.Errorf("unsupported command %d", req[1])
+	}
+	var host string
+	switch req[3] {
+	case 0x01, 0x04:
+		ip := make(net.IP, 4)
+		if req[3] == 0x04 {
+			ip = make(net.IP, 16)
+		}
+		if _, err := io.ReadFull(rw, ip); err != nil {
+			return "", err
+		}
+		host = ip.String()
+	case 0x03:
+		n := make([]byte, 1)
+		if _, err := io.ReadFull(rw, n); err != nil {
+			return "", err
+		}
+		name := make([]byte, n[0])
+		if _, err := io.ReadFull(rw, name); err != nil {
+			return "", err
+		}
+		host = string(name)
+	default:
+		socksReply(rw, socksAddrNotSupported)
+		return "", fmt.Errorf("unsupported address type %d", req[3])
+	}
+	port := make([]byte, 2)
+	if _, err := io.ReadFull(rw, port); err != nil {
+		return "", err
+	}
+	socksReply(rw, socksSucceeded)
+	return net.JoinHostPort(host, strconv.Itoa(int(port[0])<<8|int(port[1]))), nil
+}
+
+// socksReply 发送 SOCKS5 应答，绑定地址固定为 0.0.0.0:0
+func socksReply(w io.Writer, code byte) {
+	_, _ = w.Write([]byte{socksVersion, code, 0x00, 0x01, 0, 0, 0, 0, 0, 0})
+}
@@ -0,0 +1,168 @@
+package cdp
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/mafredri/cdp/protocol/fetch"
+
+	"cdpnetool/internal/rules"
+	"cdpnetool/pkg/model"
+	"cdpnetool/pkg/rulespec"
+)
+
+// quickMockDefaultTTL 临时 Mock 未指定有效期时的默认值
+const quickMockDefaultTTL = 10 * time.Minute
+
+// quickMock 已生效的临时 Mock
+type quickMock struct {
+	seq   uint64
+	info  model.QuickMock
+	rule  rulespec.Rule // 转换成的 block 规则，命中时与普通规则走同一条执行路径
+	timer *time.Timer
+}
+
+// AddQuickMock 为目标添加临时 Mock：该目标中请求 URL 与 mock.URL 一致的请求直接以给定响应应答，到期后自动移除
+func (m *Manager) AddQuickMock(mock model.QuickMock) (model.QuickMock, error) {
+	mock.URL = strings.TrimSpace(mock.URL)
+	if i := strings.IndexByte(mock.URL, '#'); i >= 0 {
+		mock.URL = mock.URL[:i]
+	}
+	if !isCapturableURL(mock.URL) {
+		return model.QuickMock{}, fmt.Errorf("invalid quick mock url: %q", mock.URL)
+	}
+	if mock.Target == "" {
+		return model.QuickMock{}, fmt.Errorf("quick mock target is required")
+	}
+	if _, err := m.pageTarget(mock.Target); err != nil {
+		return model.QuickMock{}, err
+	}
+	if !m.isHostPermitted(mock.URL) {
+		return model.QuickMock{}, fmt.Errorf("host not permitted by host policy: %s", mock.URL)
+	}
+	if mock.StatusCode == 0 {
+		mock.StatusCode = 200
+	}
+	if mock.StatusCode < 100 || mock.StatusCode > 599 {
+		return model.QuickMock{}, fmt.Errorf("invalid quick mock status code: %d", mock.StatusCode)
+	}
+	ttl := quickMockDefaultTTL
+	if mock.TTLMS > 0 {
+		ttl = time.Duration(mock.TTLMS) * time.Millisecond
+	}
+	mock.Method = strings.ToUpper(strings.TrimSpace(mock.Method))
+	seq := m.mockSeq.Add(1)
+	mock.ID = fmt.Sprintf("qm-%d", seq)
+	mock.TTLMS = int(ttl / time.Millisecond)
+	mock.ExpiresAt = time.Now().Add(ttl).UnixMilli()
+
+	headers := make(map[string]string, len(mock.Headers)+1)
+	for k, v := range mock.Headers {
+		headers[k] = v
+	}
+	if headerValue(headers, "Content-Type") == "" {
+		if body := strings.TrimSpace(mock.Body); strings.HasPrefix(body, "{") || strings.HasPrefix(body, "[") {
+			headers["Content-Type"] = "application/json; charset=utf-8"
+		}
+	}
+
+	qm := &quickMock{
+		seq:  seq,
+		info: mock,
+		rule: rulespec.Rule{
+			ID:      "quick-mock-" + mock.ID,
+			Name:    "临时 Mock",
+			Enabled: true,
+			Stage:   rulespec.StageRequest,
+			Actions: []rulespec.Action{{Type: rulespec.ActionBlock, StatusCode: mock.StatusCode, Headers: headers, Body: mock.Body}},
+		},
+	}
+	m.mockMu.Lock()
+	m.quickMocks[mock.ID] = qm
+	qm.timer = time.AfterFunc(ttl, func() { m.expireQuickMock(mock.ID) })
+	m.mockMu.Unlock()
+
+	m.refreshFetchPatterns()
+	m.log.Info("已添加临时 Mock", "id", mock.ID, "target", string(mock.Target), "url", mock.URL, "ttl", ttl.String())
+	return mock, nil
+}
+
+// RemoveQuickMock 移除临时 Mock，不存在时返回 false
+func (m *Manager) RemoveQuickMock(id string) bool {
+	m.mockMu.Lock()
+	qm, ok := m.quickMocks[id]
+	if ok {
+		qm.timer.Stop()
+		delete(m.quickMocks, id)
+	}
+	m.mockMu.Unlock()
+	if ok {
+		m.refreshFetchPatterns()
+		m.log.Info("已移除临时 Mock", "id", id)
+	}
+	return ok
+}
+
+// QuickMocks 返回生效中的临时 Mock，按创建顺序排序
+func (m *Manager) QuickMocks() []model.QuickMock {
+	m.mockMu.Lock()
+	list := make([]*quickMock, 0, len(m.quickMocks))
+	for _, qm := range m.quickMocks {
+		list = append(list, qm)
+	}
+	m.mockMu.Unlock()
+	sort.Slice(list, func(i, j int) bool { return list[i].seq < list[j].seq })
+	out := make([]model.QuickMock, len(list))
+	for i, qm := range list {
+		out[i] = qm.info
+	}
+	return out
+}
+
+// expireQuickMock 到期移除临时 Mock 并通知
+func (m *Manager) expireQuickMock(id string) {
+	m.mockMu.Lock()
+	qm, ok := m.quickMocks[id]
+	delete(m.quickMocks, id)
+	m.mockMu.Unlock()
+	if !ok {
+		return
+	}
+	m.refreshFetchPatterns()
+	m.sendNotice(qm.info.Target, model.NoticeQuickMockExpired, qm.info.URL, "临时 Mock 已到期移除", map[string]string{"id": id})
+	m.log.Info("临时 Mock 已到期移除", "id", id, "url", qm.info.URL)
+}
+
+// quickMockFor 返回命中请求的临时 Mock 规则，只在请求阶段生效
+func (m *Manager) quickMockFor(target model.TargetID, ev *fetch.RequestPausedReply, stage rulespec.Stage) []*rules.MatchedRule {
+	if stage != rulespec.StageRequest {
+		return nil
+	}
+	m.mockMu.Lock()
+	defer m.mockMu.Unlock()
+	for _, qm := range m.quickMocks {
+		if qm.info.Target != target || qm.info.URL != ev.Request.URL {
+			continue
+		}
+		if qm.info.Method != "" && !strings.EqualFold(qm.info.Method, ev.Request.Method) {
+			continue
+		}
+		return []*rules.MatchedRule{{Rule: &qm.rule, Params: map[string]string{}}}
+	}
+	return nil
+}
+
+// quickMockPatterns 返回目标的临时 Mock 所需的请求阶段拦截模式
+func (m *Manager) quickMockPatterns(target model.TargetID) []fetch.RequestPattern {
+	m.mockMu.Lock()
+	defer m.mockMu.Unlock()
+	var out []fetch.RequestPattern
+	for _, qm := range m.quickMocks {
+		if qm.info.Target == target {
+			out = append(out, fetch.RequestPattern{URLPattern: strPtr(escapeFetchPattern(qm.info.URL)), RequestStage: fetch.RequestStageRequest})
+		}
+	}
+	return out
+}
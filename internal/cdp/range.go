@@ -0,0 +1,167 @@
+package cdp
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/mafredri/cdp/protocol/fetch"
+
+	"cdpnetool/pkg/model"
+)
+
+// getRequestHeader 不区分大小写获取原始请求头
+func getRequestHeader(ev *fetch.RequestPausedReply, name string) (string, bool) {
+	var headers map[string]string
+	_ = json.Unmarshal(ev.Request.Headers, &headers)
+	for k, v := range headers {
+		if strings.EqualFold(k, name) {
+			return v, true
+		}
+	}
+	return "", false
+}
+
+// isRangeRequest 判断请求是否携带 Range 头
+func isRangeRequest(ev *fetch.RequestPausedReply) bool {
+	v, ok := getRequestHeader(ev, "Range")
+	return ok && strings.TrimSpace(v) != ""
+}
+
+// rangeResult Range 头的解析结果
+type rangeResult int
+
+const (
+	rangeIgnored       rangeResult = iota // 无法解析或多段范围：忽略 Range，返回完整内容
+	rangeUnsatisfiable                    // 格式正确但超出内容长度：返回 416
+	rangeSatisfiable                      // 可以满足：返回 206 片段
+)
+
+// parseByteRange 解析单段 Range 头（bytes=start-end / bytes=start- / bytes=-suffix），
+// 可以满足时返回闭区间 [start, end]。按 RFC 9110 §14.2，无法解析的 Range 应被忽略，
+// 只有格式正确但与内容不重叠的范围才是无法满足的
+func parseByteRange(h string, size int) (int, int, rangeResult) {
+	h = strings.TrimSpace(h)
+	if !strings.HasPrefix(h, "bytes=") {
+		return 0, 0, rangeIgnored
+	}
+	spec := strings.TrimSpace(strings.TrimPrefix(h, "bytes="))
+	if strings.Contains(spec, ",") {
+		return 0, 0, rangeIgnored
+	}
+	parts := strings.SplitN(spec, "-", 2)
+	if len(parts) != 2 {
+		return 0, 0, rangeIgnored
+	}
+	first, last := strings.TrimSpace(parts[0]), strings.TrimSpace(parts[1])
+
+	// 后缀范围：最后 N 个字节
+	if first == "" {
+		n, ok := rangePos(last)
+		if !ok {
+			return 0, 0, rangeIgnored
+		}
+		if n == 0 || size == 0 {
+			return 0, 0, rangeUnsatisfiable
+		}
+		if n > size {
+			n = size
+		}
+		return size - n, size - 1, rangeSatisfiable
+	}
+
+	start, ok := rangePos(first)
+	if !ok {
+		return 0, 0, rangeIgnored
+	}
+	end := size - 1
+	if last != "" {
+		e, ok := rangePos(last)
+		if !ok || e < start {
+			return 0, 0, rangeIgnored
+		}
+		if e < end {
+			end = e
+		}
+	}
+	if start >= size {
+		return 0, 0, rangeUnsatisfiable
+	}
+	return start, end, rangeSatisfiable
+}
+
+// rangePos 解析 Range 中的字节位置，只接受十进制数字
+func rangePos(s string) (int, bool) {
+	if s == "" || strings.TrimLeft(s, "0123456789") != "" {
+		return 0, false
+	}
+	n, err := strconv.Atoi(s)
+	return n, err == nil
+}
+
+// ifRangeMatches 判断 If-Range 是否与响应的校验值一致：实体标签需与 ETag 强匹配，日期需与 Last-Modified 相同。
+// 未携带 If-Range 时视为一致
+func ifRangeMatches(ev *fetch.RequestPausedReply, headers []fetch.HeaderEntry) bool {
+	cond, ok := getRequestHeader(ev, "If-Range")
+	cond = strings.TrimSpace(cond)
+	if !ok || cond == "" {
+		return true
+	}
+	isTag := strings.HasPrefix(cond, `"`) || strings.HasPrefix(cond, "W/")
+	for _, h := range headers {
+		switch {
+		case isTag && strings.EqualFold(h.Name, "ETag"):
+			// 弱实体标签不能用于 Range 请求
+			v := strings.TrimSpace(h.Value)
+			return !strings.HasPrefix(cond, "W/") && !strings.HasPrefix(v, "W/") && v == cond
+		case !isTag && strings.EqualFold(h.Name, "Last-Modified"):
+			return strings.TrimSpace(h.Value) == cond
+		}
+	}
+	return false
+}
+
+// applyRangePolicy 按 Range 语义切片即将 fulfill 的完整 Body，返回新的状态码、头部和 Body。
+// 非 Range 请求原样返回；范围无法解析或 If-Range 与响应不一致时返回完整 Body，范围无法满足时返回 416
+func applyRangePolicy(ev *fetch.RequestPausedReply, code int, headers []fetch.HeaderEntry, body []byte) (int, []fetch.HeaderEntry, []byte) {
+	rangeHeader, ok := getRequestHeader(ev, "Range")
+	if !ok {
+		return code, headers, body
+	}
+
+	size := len(body)
+	out := make([]fetch.HeaderEntry, 0, len(headers)+2)
+	for _, h := range headers {
+		if strings.EqualFold(h.Name, "Content-Range") || strings.EqualFold(h.Name, "Content-Length") {
+			continue
+		}
+		out = append(out, h)
+	}
+
+	// 校验值不一致或范围无法解析时忽略 Range，按完整内容返回
+	start, end, res := parseByteRange(rangeHeader, size)
+	if !ifRangeMatches(ev, headers) || res == rangeIgnored {
+		if code == 206 {
+			code = 200
+		}
+		out = append(out, fetch.HeaderEntry{Name: "Content-Length", Value: strconv.Itoa(size)})
+		return code, out, body
+	}
+	if res == rangeUnsatisfiable {
+		out = append(out, fetch.HeaderEntry{Name: "Content-Range", Value: fmt.Sprintf("bytes */%d", size)})
+		return 416, out, nil
+	}
+
+	part := body[start : end+1]
+	out = append(out,
+		fetch.HeaderEntry{Name: "Content-Range", Value: fmt.Sprintf("bytes %d-%d/%d", start, end, size)},
+		fetch.HeaderEntry{Name: "Content-Length", Value: strconv.Itoa(len(part))},
+	)
+	return 206, out, part
+}
+
+// shouldBypassRange 判断 Range 请求是否应跳过 Body 改写
+func (m *Manager) shouldBypassRange(ev *fetch.RequestPausedReply) bool {
+	return m.rangePolicy == model.RangePolicyBypass && isRangeRequest(ev)
+}
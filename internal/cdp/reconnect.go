@@ -0,0 +1,138 @@
+package cdp
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	"github.com/mafredri/cdp/devtool"
+
+	"cdpnetool/pkg/model"
+)
+
+const (
+	reconnectInitialDelay = 500 * time.Millisecond // 首次重连等待时间
+	reconnectMaxDelay     = 30 * time.Second       // 单次重连最长等待时间
+	reconnectMaxAttempts  = 10                     // 放弃前的最大重连次数
+)
+
+// 连接状态（NoticeConnection 通知的 state 字段）
+const (
+	connStateConnected    = "connected"
+	connStateReconnecting = "reconnecting"
+	connStateLost         = "lost"
+)
+
+// sendConnectionState 发送目标连接状态通知
+func (m *Manager) sendConnectionState(target model.TargetID, state, message string, details map[string]string) {
+	if details == nil {
+		details = map[string]string{}
+	}
+	details["state"] = state
+	m.sendNotice(target, model.NoticeConnection, "", message, details)
+}
+
+// reconnectTarget 目标连接中断后按指数退避重新附加。原目标已不存在时，
+// 若期间浏览器曾不可达（视为浏览器重启）则改为附加第一个 page 目标，否则视为页面已关闭
+func (m *Manager) reconnectTarget(old *targetSession) {
+	m.sendConnectionState(old.id, connStateReconnecting, "目标连接中断，正在重连", nil)
+
+	delay := reconnectInitialDelay
+	browserDown := false
+	for attempt := 1; attempt <= reconnectMaxAttempts; attempt++ {
+		time.Sleep(delay)
+		if delay *= 2; delay > reconnectMaxDelay {
+			delay = reconnectMaxDelay
+		}
+		if !m.isEnabled() {
+			m.log.Info("拦截已禁用，停止重连", "target", string(old.id))
+			return
+		}
+
+		ctx, cancel := context.WithCancel(context.Background())
+		selected, err := m.findReconnectTarget(ctx, old.id, browserDown)
+		if err != nil {
+			cancel()
+			browserDown = true
+			m.log.Warn("重连目标失败", "target", string(old.id), "attempt", attempt, "error", err.Error())
+			continue
+		}
+		if selected == nil {
+			cancel()
+			if !browserDown {
+				break
+			}
+			continue
+		}
+
+		ts, err := m.attachReconnected(ctx, cancel, selected)
+		if err != nil {
+			m.log.Warn("重连目标失败", "target", string(old.id), "attempt", attempt, "error", err.Error())
+			continue
+		}
+		if ts == nil {
+			// 已被其他流程重新附加
+			return
+		}
+		m.log.Info("目标已重连", "target", string(ts.id), "previous", string(old.id), "attempt", attempt)
+		m.sendConnectionState(ts.id, connStateConnected, "目标已重连", map[string]string{
+			"previousTarget": string(old.id),
+			"attempt":        strconv.Itoa(attempt),
+		})
+		return
+	}
+
+	m.log.Warn("目标重连失败，已放弃", "target", string(old.id))
+	m.sendConnectionState(old.id, connStateLost, "目标连接已丢失，请重新附加", nil)
+}
+
+// findReconnectTarget 查找重连的目标：优先原目标，允许回退时选择第一个未附加的 page 目标
+func (m *Manager) findReconnectTarget(ctx context.Context, id model.TargetID, fallback bool) (*devtool.Target, error) {
+	targets, err := devtool.New(m.devtoolsURL).List(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for _, t := range targets {
+		if t != nil && model.TargetID(t.ID) == id {
+			return t, nil
+		}
+	}
+	if !fallback {
+		return nil, nil
+	}
+
+	m.targetsMu.Lock()
+	defer m.targetsMu.Unlock()
+	for _, t := range targets {
+		if t == nil || t.Type != devtool.Page {
+			continue
+		}
+		if _, attached := m.targets[model.TargetID(t.ID)]; !attached {
+			return t, nil
+		}
+	}
+	return nil, nil
+}
+
+// attachReconnected 连接重连目标并在拦截启用时为其启用拦截；目标已被附加时返回 nil
+func (m *Manager) attachReconnected(ctx context.Context, cancel context.CancelFunc, t *devtool.Target) (*targetSession, error) {
+	m.targetsMu.Lock()
+	defer m.targetsMu.Unlock()
+
+	if _, ok := m.targets[model.TargetID(t.ID)]; ok {
+		cancel()
+		return nil, nil
+	}
+	ts, err := dialTarget(ctx, cancel, t)
+	if err != nil {
+		return nil, err
+	}
+	m.targets[ts.id] = ts
+	m.applyTargetOverrides(ts)
+	if m.isEnabled() {
+		if err := m.enableTarget(ts); err != nil {
+			m.log.Err(err, "为重连目标启用拦截失败", "target", string(ts.id))
+		}
+	}
+	return ts, nil
+}
@@ -0,0 +1,117 @@
+package cdp
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/mafredri/cdp/protocol/runtime"
+
+	"cdpnetool/internal/mutation"
+	"cdpnetool/pkg/model"
+)
+
+// replayTimeout 重放请求的超时时间
+const replayTimeout = 30 * time.Second
+
+// replayScript 在页面上下文中用 fetch 发出请求的脚本，请求体与响应体以 Base64 传递以保证二进制安全
+const replayScript = `(async (p) => {
+	const init = {method: p.method, headers: p.headers, credentials: 'include', cache: 'no-store'};
+	if (p.body) init.body = Uint8Array.from(atob(p.body), c => c.charCodeAt(0));
+	const r = await fetch(p.url, init);
+	const buf = new Uint8Array(await r.arrayBuffer());
+	let s = '';
+	for (let i = 0; i < buf.length; i += 0x8000) s += String.fromCharCode.apply(null, buf.subarray(i, i + 0x8000));
+	return {status: r.status, headers: Object.fromEntries(r.headers), body: btoa(s)};
+})(%s)`
+
+// replayParams 传入重放脚本的参数
+type replayParams struct {
+	URL     string            `json:"url"`
+	Method  string            `json:"method"`
+	Headers map[string]string `json:"headers"`
+	Body    string            `json:"body,omitempty"`
+}
+
+// replayReply 重放脚本的返回值
+type replayReply struct {
+	Status  int               `json:"status"`
+	Headers map[string]string `json:"headers"`
+	Body    string            `json:"body"`
+}
+
+// ReplayRequest 在目标页面上下文中通过 fetch 重新发出请求并返回响应；请求携带页面所在浏览器上下文的 Cookie，
+// 受页面的跨域策略约束，拦截启用时与普通请求一样经过规则处理
+func (m *Manager) ReplayRequest(target model.TargetID, req model.RequestInfo) (model.ResponseInfo, error) {
+	if req.URL == "" {
+		return model.ResponseInfo{}, fmt.Errorf("request url empty")
+	}
+	ts, err := m.pageTarget(target)
+	if err != nil {
+		return model.ResponseInfo{}, err
+	}
+
+	params := replayParams{URL: req.URL, Method: req.Method, Headers: make(map[string]string, len(req.Headers))}
+	if params.Method == "" {
+		params.Method = "GET"
+	}
+	for k, v := range req.Headers {
+		// HTTP/2 伪首部不是合法的请求头名称，fetch 会直接报错
+		if strings.HasPrefix(k, ":") {
+			continue
+		}
+		params.Headers[k] = v
+	}
+	if req.Body != "" {
+		if req.BodyEncoding == model.BodyEncodingBase64 {
+			params.Body = req.Body
+		} else {
+			params.Body = base64.StdEncoding.EncodeToString([]byte(req.Body))
+		}
+	}
+	raw, err := json.Marshal(params)
+	if err != nil {
+		return model.ResponseInfo{}, err
+	}
+
+	ctx, cancel := context.WithTimeout(ts.ctx, replayTimeout)
+	defer cancel()
+	start := time.Now().UnixMilli()
+	args := runtime.NewEvaluateArgs(fmt.Sprintf(replayScript, raw)).SetAwaitPromise(true).SetReturnByValue(true)
+	reply, err := ts.client.Runtime.Evaluate(ctx, args)
+	if err != nil {
+		return model.ResponseInfo{}, err
+	}
+	if ex := reply.ExceptionDetails; ex != nil {
+		msg := ex.Text
+		if ex.Exception != nil && ex.Exception.Description != nil {
+			msg = *ex.Exception.Description
+		}
+		return model.ResponseInfo{}, fmt.Errorf("replay failed: %s", msg)
+	}
+
+	var out replayReply
+	if err := json.Unmarshal(reply.Result.Value, &out); err != nil {
+		return model.ResponseInfo{}, fmt.Errorf("invalid replay result: %w", err)
+	}
+	data, err := base64.StdEncoding.DecodeString(out.Body)
+	if err != nil {
+		return model.ResponseInfo{}, fmt.Errorf("invalid replay body: %w", err)
+	}
+
+	info := model.ResponseInfo{
+		StatusCode: out.Status,
+		Headers:    out.Headers,
+		Timing:     model.ResponseTiming{StartTime: start, EndTime: time.Now().UnixMilli()},
+	}
+	// fetch 返回的响应头名称均为小写
+	info.Body, info.BodyEncoding, info.BodyTruncated = m.eventBody(mutation.NewBodyContent(data, out.Headers["content-type"]))
+	if r := m.currentRedactor(); r != nil {
+		info = redactResponseInfo(r, info)
+	}
+	m.log.Info("已重放请求", "target", string(ts.id), "method", params.Method, "url", req.URL, "status", out.Status)
+	return info, nil
+}
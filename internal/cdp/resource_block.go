@@ -0,0 +1,165 @@
+package cdp
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/mafredri/cdp/protocol/fetch"
+	"github.com/mafredri/cdp/protocol/network"
+
+	"cdpnetool/pkg/model"
+	"cdpnetool/pkg/rulespec"
+)
+
+// blockClassTypes 按资源类型屏蔽的类别
+var blockClassTypes = map[string][]network.ResourceType{
+	model.BlockImages: {network.ResourceTypeImage},
+	model.BlockFonts:  {network.ResourceTypeFont},
+	model.BlockMedia:  {network.ResourceTypeMedia, network.ResourceTypeTextTrack},
+}
+
+// analyticsHosts 常见统计分析与广告追踪服务的主机，同时匹配其子域名
+var analyticsHosts = []string{
+	"google-analytics.com", "analytics.google.com", "googletagmanager.com", "googlesyndication.com",
+	"doubleclick.net", "connect.facebook.net", "segment.io", "segment.com", "mixpanel.com",
+	"amplitude.com", "hotjar.com", "clarity.ms", "fullstory.com", "newrelic.com", "nr-data.net",
+	"hm.baidu.com", "cnzz.com", "umeng.com", "growingio.com", "sensorsdata.cn",
+}
+
+// resourceBlock 会话级的一键资源屏蔽
+type resourceBlock struct {
+	types     map[network.ResourceType]bool
+	analytics bool
+}
+
+// newResourceBlock 按类别创建资源屏蔽，返回无法识别的类别；没有可屏蔽的类别时返回 nil
+func newResourceBlock(classes []string) (*resourceBlock, []string) {
+	b := &resourceBlock{types: make(map[network.ResourceType]bool)}
+	var unknown []string
+	for _, c := range classes {
+		if c == model.BlockAnalytics {
+			b.analytics = true
+			continue
+		}
+		types, ok := blockClassTypes[c]
+		if !ok {
+			unknown = append(unknown, c)
+			continue
+		}
+		for _, rt := range types {
+			b.types[rt] = true
+		}
+	}
+	if len(b.types) == 0 && !b.analytics {
+		return nil, unknown
+	}
+	return b, unknown
+}
+
+// blocks 判断请求是否被屏蔽
+func (b *resourceBlock) blocks(ev *fetch.RequestPausedReply) bool {
+	if b == nil {
+		return false
+	}
+	if b.types[ev.ResourceType] {
+		return true
+	}
+	if b.analytics {
+		host := hostOf(ev.Request.URL)
+		for _, h := range analyticsHosts {
+			if matchHostPattern(host, "*."+h) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// patterns 返回请求阶段的拦截模式，统计分析服务按主机放宽为通配
+func (b *resourceBlock) patterns() []fetch.RequestPattern {
+	if b == nil {
+		return nil
+	}
+	var out []fetch.RequestPattern
+	for _, rt := range fetchResourceTypes {
+		if b.types[rt] {
+			rt := rt
+			out = append(out, fetch.RequestPattern{URLPattern: strPtr("*"), ResourceType: &rt, RequestStage: fetch.RequestStageRequest})
+		}
+	}
+	if b.analytics {
+		for _, h := range analyticsHosts {
+			out = append(out, fetch.RequestPattern{URLPattern: strPtr("*" + escapeFetchPattern(h) + "/*"), RequestStage: fetch.RequestStageRequest})
+		}
+	}
+	return out
+}
+
+// SetBlockResourceTypes 设置一键屏蔽的资源类别（images/fonts/media/analytics），为空时不屏蔽；
+// 命中的请求不经规则处理直接失败，并按需更新拦截模式
+func (m *Manager) SetBlockResourceTypes(classes []string) {
+	b, unknown := newResourceBlock(classes)
+	if len(unknown) > 0 {
+		m.log.Warn("忽略无法识别的屏蔽资源类别", "classes", unknown)
+	}
+	m.stateMu.Lock()
+	m.blocked = b
+	m.stateMu.Unlock()
+	m.refreshFetchPatterns()
+}
+
+// currentResourceBlock 返回当前的资源屏蔽设置
+func (m *Manager) currentResourceBlock() *resourceBlock {
+	m.stateMu.RLock()
+	defer m.stateMu.RUnlock()
+	return m.blocked
+}
+
+// resourceBlockPatterns 返回资源屏蔽所需的拦截模式，只读捕获模式下不屏蔽
+func (m *Manager) resourceBlockPatterns() []fetch.RequestPattern {
+	if m.captureOnly {
+		return nil
+	}
+	return m.currentResourceBlock().patterns()
+}
+
+// blockResource 请求属于屏蔽的资源类别时以 BlockedByClient 使其失败并记录事件，返回是否已处理
+func (m *Manager) blockResource(ctx context.Context, ts *targetSession, ev *fetch.RequestPausedReply) bool {
+	if m.captureOnly || ev.ResponseStatusCode != nil || !m.currentResourceBlock().blocks(ev) {
+		return false
+	}
+	m.executor.FailRequest(ctx, ts, ev, string(network.ErrorReasonBlockedByClient))
+	m.log.Debug("请求属于屏蔽的资源类别，已直接失败", "url", ev.Request.URL, "type", string(ev.ResourceType))
+
+	// 全量捕获模式下由 Network 事件记录
+	if !m.fullCapture {
+		m.markReported(ts, ev)
+		m.sendBlockedEvent(ts.id, ev)
+	}
+	return true
+}
+
+// sendBlockedEvent 发送因资源屏蔽而失败的请求事件
+func (m *Manager) sendBlockedEvent(target model.TargetID, ev *fetch.RequestPausedReply) {
+	requestInfo := model.RequestInfo{
+		URL:          ev.Request.URL,
+		Method:       ev.Request.Method,
+		Headers:      make(map[string]string),
+		ResourceType: string(ev.ResourceType),
+	}
+	_ = json.Unmarshal(ev.Request.Headers, &requestInfo.Headers)
+	m.emit(model.InterceptEvent{
+		Unmatched: &model.UnmatchedEvent{
+			NetworkEvent: model.NetworkEvent{
+				Target:      target,
+				Timestamp:   time.Now().UnixMilli(),
+				NetworkID:   string(networkID(ev)),
+				Stage:       string(rulespec.StageRequest),
+				Request:     requestInfo,
+				Response:    model.ResponseInfo{Headers: map[string]string{}},
+				FinalResult: "blocked",
+			},
+		},
+	})
+}
@@ -0,0 +1,35 @@
+package cdp
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/mafredri/cdp/protocol/page"
+
+	"cdpnetool/pkg/model"
+)
+
+// screenshotTimeout 截图的超时时间
+const screenshotTimeout = 15 * time.Second
+
+// CaptureScreenshot 通过 Page.captureScreenshot 截取页面目标当前视口的 PNG 图片，target 为空时使用任一已附加的页面
+func (m *Manager) CaptureScreenshot(target model.TargetID) (model.Screenshot, error) {
+	ts, err := m.pageTarget(target)
+	if err != nil {
+		return model.Screenshot{}, err
+	}
+	ctx, cancel := context.WithTimeout(ts.ctx, screenshotTimeout)
+	defer cancel()
+	reply, err := ts.client.Page.CaptureScreenshot(ctx, page.NewCaptureScreenshotArgs().SetFormat("png"))
+	if err != nil {
+		return model.Screenshot{}, fmt.Errorf("capture screenshot: %w", err)
+	}
+	m.log.Info("页面截图完成", "target", string(ts.id), "size", len(reply.Data))
+	return model.Screenshot{
+		Target:    ts.id,
+		Timestamp: time.Now().UnixMilli(),
+		MimeType:  "image/png",
+		Data:      reply.Data,
+	}, nil
+}
@@ -0,0 +1,104 @@
+package cdp
+
+import (
+	"sort"
+	"strings"
+
+	"cdpnetool/internal/mutation"
+	"cdpnetool/pkg/model"
+)
+
+// secretVarPrefix 行为模板中引用密钥的变量前缀，如 {{secret.apiKey}}
+const secretVarPrefix = "secret."
+
+// secretRedactMinLen 参与事件脱敏的最短密钥长度，过短的值替换会误伤正常内容
+const secretRedactMinLen = 4
+
+// secretMaskValue 事件中密钥值的替换文本
+const secretMaskValue = "******"
+
+// SetSecrets 设置行为模板可引用的命名密钥
+func (m *Manager) SetSecrets(secrets map[string]string) {
+	vars := make(map[string]string, len(secrets))
+	values := make([]string, 0, len(secrets))
+	for name, value := range secrets {
+		vars[secretVarPrefix+name] = value
+		if len(value) >= secretRedactMinLen {
+			values = append(values, value)
+		}
+	}
+	// 较长的值优先替换，避免一个密钥是另一个密钥的子串时只替换了一部分
+	sort.Slice(values, func(i, j int) bool { return len(values[i]) > len(values[j]) })
+	var redactor *strings.Replacer
+	if len(values) > 0 {
+		pairs := make([]string, 0, len(values)*2)
+		for _, v := range values {
+			pairs = append(pairs, v, secretMaskValue)
+		}
+		redactor = strings.NewReplacer(pairs...)
+	}
+
+	m.stateMu.Lock()
+	m.secretVars = vars
+	m.secretRedactor = redactor
+	m.stateMu.Unlock()
+}
+
+// templateVars 返回密钥、会话变量与规则匹配变量合并后的模板变量
+func (m *Manager) templateVars(params map[string]string) map[string]string {
+	m.stateMu.RLock()
+	secrets, sessionVars := m.secretVars, m.sessionVars
+	m.stateMu.RUnlock()
+	if len(secrets) == 0 && len(sessionVars) == 0 {
+		return params
+	}
+	vars := make(map[string]string, len(params)+len(secrets)+len(sessionVars))
+	for k, v := range secrets {
+		vars[k] = v
+	}
+	for k, v := range sessionVars {
+		vars[mutation.VarPrefix+k] = v
+	}
+	for k, v := range params {
+		vars[k] = v
+	}
+	return vars
+}
+
+// currentRedactor 返回密钥脱敏替换器，未配置密钥时返回 nil
+func (m *Manager) currentRedactor() *strings.Replacer {
+	m.stateMu.RLock()
+	defer m.stateMu.RUnlock()
+	return m.secretRedactor
+}
+
+// redactRequestInfo 将请求信息中出现的密钥值替换为掩码
+func redactRequestInfo(r *strings.Replacer, info model.RequestInfo) model.RequestInfo {
+	info.URL = r.Replace(info.URL)
+	info.Headers = redactHeaders(r, info.Headers)
+	if info.BodyEncoding != model.BodyEncodingBase64 {
+		info.Body = r.Replace(info.Body)
+	}
+	return info
+}
+
+// redactResponseInfo 将响应信息中出现的密钥值替换为掩码
+func redactResponseInfo(r *strings.Replacer, info model.ResponseInfo) model.ResponseInfo {
+	info.Headers = redactHeaders(r, info.Headers)
+	if info.BodyEncoding != model.BodyEncodingBase64 {
+		info.Body = r.Replace(info.Body)
+	}
+	return info
+}
+
+// redactHeaders 返回脱敏后的头部副本
+func redactHeaders(r *strings.Replacer, headers map[string]string) map[string]string {
+	if len(headers) == 0 {
+		return headers
+	}
+	out := make(map[string]string, len(headers))
+	for k, v := range headers {
+		out[k] = r.Replace(v)
+	}
+	return out
+}
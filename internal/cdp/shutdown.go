@@ -0,0 +1,58 @@
+package cdp
+
+import (
+	"time"
+)
+
+// 关闭会话时事件通道由调用方关闭：管理器先标记为已关闭，停止尚未触发的延迟任务并等待处理中的请求结束，
+// 推送事件与断点通知均在 emitMu 读锁下检查关闭标记，关闭之后不再写入通道。
+
+// track 登记一个可能推送事件的处理任务，管理器已关闭时返回 false；返回 true 时调用方结束后需调用 m.work.Done
+func (m *Manager) track() bool {
+	m.emitMu.RLock()
+	defer m.emitMu.RUnlock()
+	if m.closed {
+		return false
+	}
+	m.work.Add(1)
+	return true
+}
+
+// afterFunc 等待 d 后执行 fn，关闭管理器时尚未触发的任务被停止；管理器已关闭时不执行
+func (m *Manager) afterFunc(d time.Duration, fn func()) {
+	m.emitMu.Lock()
+	defer m.emitMu.Unlock()
+	if m.closed {
+		return
+	}
+	m.work.Add(1)
+	var t *time.Timer
+	t = time.AfterFunc(d, func() {
+		defer m.work.Done()
+		m.emitMu.Lock()
+		delete(m.timers, t)
+		m.emitMu.Unlock()
+		fn()
+	})
+	if m.timers == nil {
+		m.timers = make(map[*time.Timer]struct{})
+	}
+	m.timers[t] = struct{}{}
+}
+
+// Close 停止推送事件：停止尚未触发的延迟任务并等待处理中的请求结束，之后调用方可以安全地关闭事件通道。
+// 应在 Disable 与 DetachAll 之后调用，此时暂停中的请求和断点均已释放
+func (m *Manager) Close() {
+	m.emitMu.Lock()
+	m.closed = true
+	timers := m.timers
+	m.timers = nil
+	m.emitMu.Unlock()
+
+	for t := range timers {
+		if t.Stop() {
+			m.work.Done()
+		}
+	}
+	m.work.Wait()
+}
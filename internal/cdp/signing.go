@@ -0,0 +1,320 @@
+package cdp
+
+import (
+	"crypto/hmac"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/mafredri/cdp/protocol/fetch"
+
+	"cdpnetool/internal/mutation"
+	"cdpnetool/pkg/rulespec"
+)
+
+// signaturePlaceholder signHmac 的 value 中代表签名结果的占位符
+const signaturePlaceholder = "{{signature}}"
+
+// signingRequest 全部修改完成后的最终请求，签名基于它计算
+type signingRequest struct {
+	Method  string
+	URL     *url.URL
+	Headers map[string]string
+	Body    []byte
+}
+
+// header 不区分大小写读取请求头
+func (r *signingRequest) header(name string) string {
+	for k, v := range r.Headers {
+		if strings.EqualFold(k, name) {
+			return v
+		}
+	}
+	return ""
+}
+
+// signRequest 按全部修改后的最终请求依次计算签名行为，并将签名写入请求头修改；
+// 签名失败时跳过该行为并记录日志，请求按其余修改继续
+func (e *ActionExecutor) signRequest(ev *fetch.RequestPausedReply, mut *RequestMutation) {
+	if len(mut.Signers) == 0 {
+		return
+	}
+	finalURL := ev.Request.URL
+	if u := mutation.BuildURL(ev.Request.URL, mut); u != nil {
+		finalURL = *u
+	}
+	u, err := url.Parse(finalURL)
+	if err != nil {
+		e.m.log.Err(err, "解析签名 URL 失败", "url", finalURL)
+		return
+	}
+	req := &signingRequest{
+		Method:  ev.Request.Method,
+		URL:     u,
+		Headers: mutation.BuildRequestHeaders(requestHeaders(ev), mut),
+		Body:    []byte(GetRequestBody(ev)),
+	}
+	if mut.Method != nil {
+		req.Method = *mut.Method
+	}
+	if mut.Body != nil {
+		req.Body = mut.Body.Data
+	}
+
+	for i := range mut.Signers {
+		a := &mut.Signers[i]
+		var headers map[string]string
+		switch a.Type {
+		case rulespec.ActionSignHMAC:
+			headers, err = signHMAC(a, req)
+		case rulespec.ActionSignAWSV4:
+			headers, err = signAWSV4(a, req, time.Now().UTC())
+		}
+		if err != nil {
+			e.m.log.Warn("计算请求签名失败，跳过签名", "type", string(a.Type), "url", finalURL, "error", err)
+			continue
+		}
+		// 后续签名可以覆盖前序签名写入的请求头
+		for name, value := range headers {
+			for k := range req.Headers {
+				if strings.EqualFold(k, name) {
+					delete(req.Headers, k)
+				}
+			}
+			req.Headers[name] = value
+			mutation.SetRequestHeader(mut, name, value)
+		}
+	}
+	mut.Signers = nil
+}
+
+// signHMAC 按 signFields 拼接待签名字符串并计算 HMAC，返回需要写入的请求头
+func signHMAC(a *rulespec.Action, req *signingRequest) (map[string]string, error) {
+	if a.Name == "" {
+		return nil, fmt.Errorf("未指定签名请求头")
+	}
+	if a.SigningKey == "" {
+		return nil, fmt.Errorf("未指定签名密钥")
+	}
+	newHash, err := hmacHash(a.Algorithm)
+	if err != nil {
+		return nil, err
+	}
+	fields := a.SignFields
+	if len(fields) == 0 {
+		fields = []string{"method", "path", "body"}
+	}
+	sep := "\n"
+	if a.Separator != nil {
+		sep = *a.Separator
+	}
+
+	parts := make([]string, 0, len(fields))
+	for _, f := range fields {
+		v, err := signField(f, req)
+		if err != nil {
+			return nil, err
+		}
+		parts = append(parts, v)
+	}
+	mac := hmac.New(newHash, []byte(a.SigningKey))
+	mac.Write([]byte(strings.Join(parts, sep)))
+	sum := mac.Sum(nil)
+
+	var sig string
+	switch strings.ToLower(a.SignatureEncoding) {
+	case "", "hex":
+		sig = hex.EncodeToString(sum)
+	case "base64":
+		sig = base64.StdEncoding.EncodeToString(sum)
+	default:
+		return nil, fmt.Errorf("不支持的签名编码: %s", a.SignatureEncoding)
+	}
+
+	value := sig
+	if tmpl, ok := a.Value.(string); ok && tmpl != "" {
+		value = strings.ReplaceAll(tmpl, signaturePlaceholder, sig)
+	}
+	return map[string]string{a.Name: value}, nil
+}
+
+// hmacHash 返回签名算法对应的哈希函数
+func hmacHash(alg string) (func() hash.Hash, error) {
+	switch strings.ToLower(alg) {
+	case "", "sha256":
+		return sha256.New, nil
+	case "sha512":
+		return sha512.New, nil
+	case "sha1":
+		return sha1.New, nil
+	}
+	return nil, fmt.Errorf("不支持的签名算法: %s", alg)
+}
+
+// signField 读取参与签名的字段值：method、url、host、path、query、pathAndQuery、body、bodySha256、header:名称
+func signField(field string, req *signingRequest) (string, error) {
+	if name, ok := strings.CutPrefix(field, "header:"); ok {
+		return req.header(name), nil
+	}
+	switch field {
+	case "method":
+		return strings.ToUpper(req.Method), nil
+	case "url":
+		return req.URL.String(), nil
+	case "host":
+		return req.URL.Host, nil
+	case "path":
+		return req.URL.EscapedPath(), nil
+	case "query":
+		return req.URL.RawQuery, nil
+	case "pathAndQuery":
+		return req.URL.RequestURI(), nil
+	case "body":
+		return string(req.Body), nil
+	case "bodySha256":
+		sum := sha256.Sum256(req.Body)
+		return hex.EncodeToString(sum[:]), nil
+	}
+	return "", fmt.Errorf("不支持的签名字段: %s", field)
+}
+
+// signAWSV4 按 AWS Signature Version 4 计算签名，返回需要写入的请求头；
+// 签名覆盖 host、content-type 和全部 x-amz-* 请求头，浏览器在发出请求前追加的其他请求头不参与签名
+func signAWSV4(a *rulespec.Action, req *signingRequest, now time.Time) (map[string]string, error) {
+	if a.AccessKeyID == "" || a.SecretAccessKey == "" {
+		return nil, fmt.Errorf("未指定访问密钥")
+	}
+	if a.Region == "" || a.Service == "" {
+		return nil, fmt.Errorf("未指定区域或服务名")
+	}
+	amzDate := now.Format("20060102T150405Z")
+	date := now.Format("20060102")
+	payloadHash := sha256Hex(req.Body)
+
+	out := map[string]string{"X-Amz-Date": amzDate}
+	if a.SessionToken != "" {
+		out["X-Amz-Security-Token"] = a.SessionToken
+	}
+	if a.Service == "s3" {
+		out["X-Amz-Content-Sha256"] = payloadHash
+	}
+
+	// 规范请求头：已有的 x-amz-* 与 content-type，加上本次写入的请求头和 host
+	canon := map[string]string{"host": req.URL.Host}
+	for k, v := range req.Headers {
+		lk := strings.ToLower(k)
+		if strings.HasPrefix(lk, "x-amz-") || lk == "content-type" {
+			canon[lk] = strings.Join(strings.Fields(v), " ")
+		}
+	}
+	for k, v := range out {
+		canon[strings.ToLower(k)] = v
+	}
+	names := make([]string, 0, len(canon))
+	for k := range canon {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+	var canonHeaders strings.Builder
+	for _, k := range names {
+		canonHeaders.WriteString(k + ":" + canon[k] + "\n")
+	}
+	signedHeaders := strings.Join(names, ";")
+
+	canonicalRequest := strings.Join([]string{
+		strings.ToUpper(req.Method),
+		awsCanonicalPath(req.URL, a.Service != "s3"),
+		awsCanonicalQuery(req.URL),
+		canonHeaders.String(),
+		signedHeaders,
+		payloadHash,
+	}, "\n")
+
+	scope := date + "/" + a.Region + "/" + a.Service + "/aws4_request"
+	stringToSign := "AWS4-HMAC-SHA256\n" + amzDate + "\n" + scope + "\n" + sha256Hex([]byte(canonicalRequest))
+
+	key := hmacSHA256([]byte("AWS4"+a.SecretAccessKey), date)
+	key = hmacSHA256(key, a.Region)
+	key = hmacSHA256(key, a.Service)
+	key = hmacSHA256(key, "aws4_request")
+	signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+
+	out["Authorization"] = "AWS4-HMAC-SHA256 Credential=" + a.AccessKeyID + "/" + scope +
+		", SignedHeaders=" + signedHeaders + ", Signature=" + signature
+	return out, nil
+}
+
+// awsCanonicalPath 返回规范 URI；除 S3 外的服务需要对已编码的路径再编码一次
+func awsCanonicalPath(u *url.URL, doubleEncode bool) string {
+	p := u.EscapedPath()
+	if p == "" {
+		return "/"
+	}
+	if !doubleEncode {
+		return p
+	}
+	segments := strings.Split(p, "/")
+	for i, s := range segments {
+		segments[i] = awsURIEncode(s)
+	}
+	return strings.Join(segments, "/")
+}
+
+// awsCanonicalQuery 返回按参数名和值排序的规范查询字符串
+func awsCanonicalQuery(u *url.URL) string {
+	q := u.Query()
+	keys := make([]string, 0, len(q))
+	encoded := make(map[string][]string, len(q))
+	for k, vs := range q {
+		ek := awsURIEncode(k)
+		keys = append(keys, ek)
+		for _, v := range vs {
+			encoded[ek] = append(encoded[ek], awsURIEncode(v))
+		}
+	}
+	sort.Strings(keys)
+	pairs := make([]string, 0, len(keys))
+	for _, k := range keys {
+		vs := encoded[k]
+		sort.Strings(vs)
+		for _, v := range vs {
+			pairs = append(pairs, k+"="+v)
+		}
+	}
+	return strings.Join(pairs, "&")
+}
+
+// awsURIEncode 按 SigV4 规则编码：仅保留 RFC 3986 非保留字符
+func awsURIEncode(s string) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if 'A' <= c && c <= 'Z' || 'a' <= c && c <= 'z' || '0' <= c && c <= '9' || c == '-' || c == '_' || c == '.' || c == '~' {
+			b.WriteByte(c)
+			continue
+		}
+		fmt.Fprintf(&b, "%%%02X", c)
+	}
+	return b.String()
+}
+
+// sha256Hex 返回数据 SHA-256 的十六进制摘要
+func sha256Hex(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// hmacSHA256 计算 HMAC-SHA256
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
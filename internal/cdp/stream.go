@@ -0,0 +1,123 @@
+package cdp
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/mafredri/cdp/protocol/fetch"
+	cdpio "github.com/mafredri/cdp/protocol/io"
+
+	"cdpnetool/internal/mutation"
+)
+
+const (
+	streamChunkSize   = 1 << 20          // 每次 IO.read 读取的字节数
+	streamReadTimeout = 30 * time.Second // 流式读取整个响应体的超时
+)
+
+// errStreamNotTaken 未能取得响应体的流，请求仍可原样放行
+var errStreamNotTaken = errors.New("cdpnetool: response body stream not taken")
+
+// SetStreamBodyLimit 设置以流方式读取响应体的上限，超过阈值但不超过上限的响应体在规则需要改写时分块读取。
+// 读取的响应体完整保存在内存中，上限同时约束单个请求占用的内存；未声明 Content-Length 的响应读取超过上限时请求失败
+func (m *Manager) SetStreamBodyLimit(limit int64) {
+	m.streamBodyLimit = limit
+}
+
+// responseContentLength 获取响应头中的 Content-Length，未声明时返回 0
+func responseContentLength(ev *fetch.RequestPausedReply) int64 {
+	for _, h := range ev.ResponseHeaders {
+		if strings.EqualFold(h.Name, "Content-Length") {
+			if n, err := parseInt64(h.Value); err == nil {
+				return n
+			}
+		}
+	}
+	return 0
+}
+
+// isLargeBody 判断响应体是否超过 Body 大小阈值
+func (m *Manager) isLargeBody(ev *fetch.RequestPausedReply) bool {
+	return m.bodySizeThreshold > 0 && responseContentLength(ev) > m.bodySizeThreshold
+}
+
+// canStreamBody 判断大响应体能否以流方式读取，调用方需先确认命中规则需要读取 Body
+func (m *Manager) canStreamBody(ev *fetch.RequestPausedReply) bool {
+	if m.captureOnly || !m.Capabilities().TakeResponseBodyAsStream {
+		return false
+	}
+	if m.streamBodyLimit > 0 && responseContentLength(ev) > m.streamBodyLimit {
+		return false
+	}
+	// Range 跳过策略需要原样放行响应，而取走的响应体只能通过 FulfillRequest 返回
+	return !m.shouldBypassRange(ev)
+}
+
+// TakeResponseBody 通过 Fetch.takeResponseBodyAsStream 分块读取响应体，避免单次传输超大的 Base64 数据。
+// 分块读取只降低单次传输的大小，整个响应体仍缓存在内存中，limit 为可缓存的上限（0 表示不限制）。
+// 取走后请求只能通过 FulfillRequest 结束，原始 Body 记录在目标会话中供放行时使用；
+// 未能取得流时返回 errStreamNotTaken，由调用方放行；读取失败时请求已无法放行，会直接使其失败
+func (e *ActionExecutor) TakeResponseBody(ts *targetSession, ev *fetch.RequestPausedReply, limit int64) (BodyContent, error) {
+	if ts == nil || ts.client == nil {
+		return BodyContent{}, fmt.Errorf("目标会话不可用")
+	}
+	ctx, cancel := context.WithTimeout(ts.ctx, streamReadTimeout)
+	defer cancel()
+
+	reply, err := ts.client.Fetch.TakeResponseBodyAsStream(ctx, fetch.NewTakeResponseBodyAsStreamArgs(ev.RequestID))
+	if err != nil {
+		return BodyContent{}, fmt.Errorf("%w: %v", errStreamNotTaken, err)
+	}
+	r := cdpio.NewStreamReader(ctx, ts.client.IO, reply.Stream)
+	defer r.Close()
+
+	var buf bytes.Buffer
+	if n := responseContentLength(ev); n > 0 {
+		buf.Grow(int(n))
+	}
+	chunk := make([]byte, streamChunkSize)
+	for {
+		n, rerr := r.Read(chunk)
+		buf.Write(chunk[:n])
+		if rerr == io.EOF {
+			break
+		}
+		if rerr == nil && limit > 0 && int64(buf.Len()) > limit {
+			rerr = fmt.Errorf("响应体超过流式读取上限 %d 字节", limit)
+		}
+		if rerr != nil {
+			// 读取超时后 ctx 已失效，使请求失败需使用目标会话的上下文
+			e.FailRequest(ts.ctx, ts, ev, "Failed")
+			return BodyContent{}, rerr
+		}
+	}
+
+	body := mutation.NewBodyContent(buf.Bytes(), responseContentType(ev))
+	ts.takenBodies.Store(ev.RequestID, body)
+	return body, nil
+}
+
+// fulfillTaken 响应体已被取走时以原始内容结束请求，返回是否已处理
+func (e *ActionExecutor) fulfillTaken(ctx context.Context, ts *targetSession, ev *fetch.RequestPausedReply) bool {
+	v, ok := ts.takenBodies.LoadAndDelete(ev.RequestID)
+	if !ok {
+		return false
+	}
+	body := v.(BodyContent)
+	code := getStatusCode(ev)
+	if code == 0 {
+		code = 200
+	}
+	_ = ts.client.Fetch.FulfillRequest(ctx, &fetch.FulfillRequestArgs{
+		RequestID:       ev.RequestID,
+		ResponseCode:    code,
+		ResponseHeaders: normalizeDecodedBody(ev.ResponseHeaders, body.Data, false),
+		Body:            body.Data,
+	})
+	return true
+}
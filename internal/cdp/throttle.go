@@ -0,0 +1,27 @@
+package cdp
+
+import (
+	"time"
+
+	"github.com/mafredri/cdp/protocol/fetch"
+)
+
+// throttleDelay 按限速计算传输响应体所需的时间。
+// Fetch 域只能一次性下发完整响应体，因此以等效的延迟模拟慢速传输：
+// Body 已读取时按最终 Body 长度计算，否则按 Content-Length，两者都未知时不限速
+func throttleDelay(ev *fetch.RequestPausedReply, mut *ResponseMutation, responseBody BodyContent, bypassBody bool) time.Duration {
+	if mut.Throttle <= 0 {
+		return 0
+	}
+	size := responseContentLength(ev)
+	switch {
+	case mut.Body != nil:
+		size = int64(len(mut.Body.Data))
+	case !bypassBody && len(responseBody.Data) > 0:
+		size = int64(len(responseBody.Data))
+	}
+	if size <= 0 {
+		return 0
+	}
+	return time.Duration(size * int64(time.Second) / int64(mut.Throttle))
+}
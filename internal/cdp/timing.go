@@ -0,0 +1,310 @@
+package cdp
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/mafredri/cdp"
+	"github.com/mafredri/cdp/protocol/network"
+
+	"cdpnetool/pkg/model"
+	"cdpnetool/pkg/rulespec"
+)
+
+const (
+	timingMaxPending  = 2000             // 同时跟踪耗时的未完成请求上限，超出后新请求不再记录
+	timingHoldTimeout = 30 * time.Second // 事件等待请求加载完成的最长时间，超时后附带已知的耗时发送
+	timingLinger      = 5 * time.Second  // 请求完成后保留耗时的时间，供稍后产生的事件（如全量捕获）使用
+)
+
+// requestTiming 一次请求的耗时记录
+type requestTiming struct {
+	wallStart float64                 // 请求开始的墙上时间（秒）
+	start     network.MonotonicTime   // 请求开始的单调时间
+	end       network.MonotonicTime   // 加载完成或失败的单调时间
+	resource  *network.ResourceTiming // 浏览器上报的各阶段耗时
+	conn      *connInfo               // 浏览器上报的本次请求的连接信息
+	done      bool
+	held      []model.InterceptEvent // 等待请求完成的事件
+}
+
+// timingTracker 跟踪单个目标中请求的耗时
+type timingTracker struct {
+	mu   sync.Mutex
+	ctx  context.Context
+	reqs map[network.RequestID]*requestTiming
+}
+
+// SetCaptureTiming 设置是否记录请求耗时；开启后带网络请求 ID 的事件在请求加载完成后才发送
+func (m *Manager) SetCaptureTiming(on bool) {
+	m.captureTiming = on
+}
+
+// consumeTiming 订阅 Network 域的请求生命周期事件，记录每个请求的开始、响应头和完成时间
+func (m *Manager) consumeTiming(ts *targetSession) {
+	sent, err := ts.client.Network.RequestWillBeSent(ts.ctx)
+	if err != nil {
+		m.log.Err(err, "订阅请求发送事件失败", "target", string(ts.id))
+		return
+	}
+	received, err := ts.client.Network.ResponseReceived(ts.ctx)
+	if err != nil {
+		sent.Close()
+		m.log.Err(err, "订阅响应接收事件失败", "target", string(ts.id))
+		return
+	}
+	finished, err := ts.client.Network.LoadingFinished(ts.ctx)
+	if err != nil {
+		sent.Close()
+		received.Close()
+		m.log.Err(err, "订阅加载完成事件失败", "target", string(ts.id))
+		return
+	}
+	failed, err := ts.client.Network.LoadingFailed(ts.ctx)
+	if err != nil {
+		sent.Close()
+		received.Close()
+		finished.Close()
+		m.log.Err(err, "订阅加载失败事件失败", "target", string(ts.id))
+		return
+	}
+	if err := cdp.Sync(sent, received, finished, failed); err != nil {
+		m.log.Err(err, "同步 Network 事件流失败", "target", string(ts.id))
+	}
+
+	t := &timingTracker{ctx: ts.ctx, reqs: make(map[network.RequestID]*requestTiming)}
+	m.timings.Store(ts.id, t)
+
+	go func() {
+		defer sent.Close()
+		defer received.Close()
+		defer finished.Close()
+		defer failed.Close()
+		defer m.timings.Delete(ts.id)
+
+		for {
+			select {
+			case <-ts.ctx.Done():
+				return
+			case <-sent.Ready():
+				ev, err := sent.Recv()
+				if err != nil {
+					return
+				}
+				t.started(ev)
+			case <-received.Ready():
+				ev, err := received.Recv()
+				if err != nil {
+					return
+				}
+				t.received(ev)
+			case <-finished.Ready():
+				ev, err := finished.Recv()
+				if err != nil {
+					return
+				}
+				m.releaseTiming(t, ev.RequestID, ev.Timestamp)
+			case <-failed.Ready():
+				ev, err := failed.Recv()
+				if err != nil {
+					return
+				}
+				m.releaseTiming(t, ev.RequestID, ev.Timestamp)
+			}
+		}
+	}()
+}
+
+// started 记录请求开始；重定向沿用同一个 RequestID，保留最初的开始时间
+func (t *timingTracker) started(ev *network.RequestWillBeSentReply) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	rt, ok := t.reqs[ev.RequestID]
+	if !ok {
+		if len(t.reqs) >= timingMaxPending {
+			return
+		}
+		rt = &requestTiming{}
+		t.reqs[ev.RequestID] = rt
+	}
+	if rt.start == 0 {
+		rt.wallStart = float64(ev.WallTime)
+		if rt.wallStart == 0 {
+			rt.wallStart = float64(time.Now().UnixMilli()) / 1000
+		}
+		rt.start = ev.Timestamp
+	}
+}
+
+// received 记录响应头到达时浏览器上报的各阶段耗时
+func (t *timingTracker) received(ev *network.ResponseReceivedReply) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if rt, ok := t.reqs[ev.RequestID]; ok {
+		rt.resource = ev.Response.Timing
+		if ev.Response.Protocol != nil && !isCachedResponse(ev.Response.FromDiskCache, ev.Response.FromPrefetchCache) {
+			conn := networkConnInfo(&ev.Response)
+			rt.conn = &conn
+		}
+	}
+}
+
+// releaseTiming 请求加载完成或失败：为等待中的事件补充耗时并发送，耗时记录保留一段时间后删除
+func (m *Manager) releaseTiming(t *timingTracker, id network.RequestID, end network.MonotonicTime) {
+	t.mu.Lock()
+	rt, ok := t.reqs[id]
+	if !ok {
+		t.mu.Unlock()
+		return
+	}
+	rt.end, rt.done = end, true
+	held := rt.held
+	rt.held = nil
+	timing, conn := rt.timing(), rt.conn
+	t.mu.Unlock()
+
+	for _, evt := range held {
+		m.emitNow(withConnection(withTiming(evt, timing), conn))
+	}
+	time.AfterFunc(timingLinger, func() {
+		t.mu.Lock()
+		delete(t.reqs, id)
+		t.mu.Unlock()
+	})
+}
+
+// holdForTiming 暂存带网络请求 ID 的事件，等请求完成后附带耗时发送；返回是否已接管事件
+func (m *Manager) holdForTiming(evt model.InterceptEvent) bool {
+	var e *model.NetworkEvent
+	switch {
+	case evt.Matched != nil:
+		e = &evt.Matched.NetworkEvent
+	case evt.Unmatched != nil:
+		e = &evt.Unmatched.NetworkEvent
+	}
+	if e == nil || e.NetworkID == "" {
+		return false
+	}
+	v, ok := m.timings.Load(e.Target)
+	if !ok {
+		return false
+	}
+	t := v.(*timingTracker)
+	id := network.RequestID(e.NetworkID)
+
+	t.mu.Lock()
+	rt, ok := t.reqs[id]
+	if !ok {
+		// 拦截事件可能先于 Network 事件处理，先登记等待请求开始
+		if len(t.reqs) >= timingMaxPending {
+			t.mu.Unlock()
+			return false
+		}
+		rt = &requestTiming{}
+		t.reqs[id] = rt
+	}
+	if rt.done {
+		timing, conn := rt.timing(), rt.conn
+		t.mu.Unlock()
+		m.emitNow(withConnection(withTiming(evt, timing), conn))
+		return true
+	}
+	first := len(rt.held) == 0
+	rt.held = append(rt.held, evt)
+	t.mu.Unlock()
+
+	if first {
+		m.afterFunc(timingHoldTimeout, func() { m.flushTiming(t, id) })
+	}
+	return true
+}
+
+// flushTiming 等待超时：附带已知的耗时发送暂存的事件，并停止跟踪仍未完成的请求
+func (m *Manager) flushTiming(t *timingTracker, id network.RequestID) {
+	if t.ctx.Err() != nil {
+		return
+	}
+	t.mu.Lock()
+	rt, ok := t.reqs[id]
+	if !ok || len(rt.held) == 0 {
+		t.mu.Unlock()
+		return
+	}
+	held := rt.held
+	rt.held = nil
+	timing, conn := rt.timing(), rt.conn
+	if !rt.done {
+		delete(t.reqs, id)
+	}
+	t.mu.Unlock()
+
+	for _, evt := range held {
+		m.emitNow(withConnection(withTiming(evt, timing), conn))
+	}
+}
+
+// timing 根据记录计算响应时间信息，单位为毫秒；未完成的请求没有总耗时，未记录开始时间时返回零值
+func (rt *requestTiming) timing() model.ResponseTiming {
+	if rt.start == 0 {
+		return model.ResponseTiming{}
+	}
+	out := model.ResponseTiming{StartTime: int64(rt.wallStart * 1000)}
+	if rt.done && rt.end >= rt.start {
+		out.Total = float64(rt.end-rt.start) * 1000
+		out.EndTime = out.StartTime + int64(out.Total)
+	}
+	if r := rt.resource; r != nil {
+		out.DNS = span(r.DNSStart, r.DNSEnd)
+		out.Connect = span(r.ConnectStart, r.ConnectEnd)
+		out.SSL = span(r.SSLStart, r.SSLEnd)
+		out.TTFB = span(r.SendEnd, r.ReceiveHeadersEnd)
+	}
+	return out
+}
+
+// span 计算 ResourceTiming 中两个时间点的间隔，未发生的阶段（值为 -1）返回 0
+func span(start, end float64) float64 {
+	if start < 0 || end < start {
+		return 0
+	}
+	return end - start
+}
+
+// withTiming 为事件的响应信息填充耗时，没有耗时记录时保留原值
+func withTiming(evt model.InterceptEvent, timing model.ResponseTiming) model.InterceptEvent {
+	if timing == (model.ResponseTiming{}) {
+		return evt
+	}
+	switch {
+	case evt.Matched != nil:
+		matched := *evt.Matched
+		matched.Response.Timing = timing
+		evt.Matched = &matched
+	case evt.Unmatched != nil:
+		unmatched := *evt.Unmatched
+		unmatched.Response.Timing = timing
+		evt.Unmatched = &unmatched
+	}
+	return evt
+}
+
+// withConnection 用本次请求的连接信息替换按源推断的连接信息，仅处理响应阶段的事件
+func withConnection(evt model.InterceptEvent, conn *connInfo) model.InterceptEvent {
+	if conn == nil {
+		return evt
+	}
+	switch {
+	case evt.Matched != nil && evt.Matched.Stage != string(rulespec.StageRequest):
+		matched := *evt.Matched
+		matched.Response.Protocol, matched.Response.RemoteAddress, matched.Response.TLS = "", "", nil
+		conn.apply(&matched.Response)
+		evt.Matched = &matched
+	case evt.Unmatched != nil && evt.Unmatched.Stage != string(rulespec.StageRequest):
+		unmatched := *evt.Unmatched
+		unmatched.Response.Protocol, unmatched.Response.RemoteAddress, unmatched.Response.TLS = "", "", nil
+		conn.apply(&unmatched.Response)
+		evt.Unmatched = &unmatched
+	}
+	return evt
+}
@@ -0,0 +1,101 @@
+package cdp
+
+import (
+	"context"
+	"time"
+
+	"github.com/mafredri/cdp/devtool"
+	"github.com/mafredri/cdp/protocol/emulation"
+
+	"cdpnetool/pkg/model"
+)
+
+// SetUserAgentOverride 设置会话级 User-Agent 覆盖，并立即应用到所有已附加的页面目标；
+// 覆盖由浏览器统一生效，无需暂停请求，与拦截开关无关
+func (m *Manager) SetUserAgentOverride(o model.UserAgentOverride) {
+	m.stateMu.Lock()
+	m.userAgent = o
+	m.stateMu.Unlock()
+
+	m.targetsMu.Lock()
+	defer m.targetsMu.Unlock()
+	for _, ts := range m.targets {
+		m.applyUserAgent(ts)
+	}
+}
+
+// currentUserAgent 返回当前 User-Agent 覆盖
+func (m *Manager) currentUserAgent() model.UserAgentOverride {
+	m.stateMu.RLock()
+	defer m.stateMu.RUnlock()
+	return m.userAgent
+}
+
+// applyUserAgent 将 User-Agent 覆盖应用到目标；Emulation 域仅页面目标可用，
+// UserAgent 为空时以空值下发以恢复浏览器默认值
+func (m *Manager) applyUserAgent(ts *targetSession) {
+	if ts == nil || ts.client == nil || ts.client.Emulation == nil || ts.kind != devtool.Page {
+		return
+	}
+	o := m.currentUserAgent()
+	if o.UserAgent == "" && !ts.uaApplied {
+		return
+	}
+
+	// 旧版浏览器不支持客户端提示，仅覆盖 User-Agent 字符串
+	if o.ClientHints != nil && !m.Capabilities().UserAgentMetadata {
+		m.log.Warn("浏览器不支持客户端提示覆盖，已忽略 clientHints", "target", string(ts.id))
+		o.ClientHints = nil
+	}
+
+	ctx, cancel := context.WithTimeout(ts.ctx, 2*time.Second)
+	defer cancel()
+	if err := ts.client.Emulation.SetUserAgentOverride(ctx, userAgentOverrideArgs(o)); err != nil {
+		m.log.Err(err, "设置 User-Agent 覆盖失败", "target", string(ts.id))
+		return
+	}
+	ts.uaApplied = o.UserAgent != ""
+	m.log.Debug("已应用 User-Agent 覆盖", "target", string(ts.id), "userAgent", o.UserAgent)
+}
+
+// userAgentOverrideArgs 将配置转换为 Emulation.setUserAgentOverride 参数
+func userAgentOverrideArgs(o model.UserAgentOverride) *emulation.SetUserAgentOverrideArgs {
+	args := emulation.NewSetUserAgentOverrideArgs(o.UserAgent)
+	if o.UserAgent == "" {
+		return args
+	}
+	if o.AcceptLanguage != "" {
+		args.SetAcceptLanguage(o.AcceptLanguage)
+	}
+	if o.Platform != "" {
+		args.SetPlatform(o.Platform)
+	}
+	if h := o.ClientHints; h != nil {
+		meta := emulation.UserAgentMetadata{
+			Brands:          brandVersions(h.Brands),
+			FullVersionList: brandVersions(h.FullVersionList),
+			Platform:        h.Platform,
+			PlatformVersion: h.PlatformVersion,
+			Architecture:    h.Architecture,
+			Model:           h.Model,
+			Mobile:          h.Mobile,
+		}
+		if h.Bitness != "" {
+			meta.Bitness = strPtr(h.Bitness)
+		}
+		args.SetUserAgentMetadata(meta)
+	}
+	return args
+}
+
+// brandVersions 转换客户端提示品牌列表
+func brandVersions(in []model.UserAgentBrand) []emulation.UserAgentBrandVersion {
+	if len(in) == 0 {
+		return nil
+	}
+	out := make([]emulation.UserAgentBrandVersion, 0, len(in))
+	for _, b := range in {
+		out = append(out, emulation.UserAgentBrandVersion{Brand: b.Brand, Version: b.Version})
+	}
+	return out
+}
@@ -6,19 +6,6 @@ import (
 	"strings"
 )
 
-// parseCookie 解析Cookie头为键值对映射
-func parseCookie(s string) map[string]string {
-	out := make(map[string]string)
-	parts := strings.Split(s, ";")
-	for _, p := range parts {
-		kv := strings.SplitN(strings.TrimSpace(p), "=", 2)
-		if len(kv) == 2 {
-			out[kv[0]] = kv[1]
-		}
-	}
-	return out
-}
-
 // parseSetCookie 解析Set-Cookie头，返回cookie名和值
 func parseSetCookie(s string) (string, string) {
 	// CookieName=CookieValue; Attr=...
@@ -49,22 +36,12 @@ func urlParse(raw string, qpatch map[string]*string) (*url.URL, error) {
 	return u, nil
 }
 
-// shouldGetBody 判断是否应该获取Body内容（基于Content-Type和大小）
-func shouldGetBody(ctype string, clen int64, thr int64) bool {
-	if thr <= 0 {
-		thr = 4 * 1024 * 1024
-	}
-	if clen > 0 && clen > thr {
+// shouldGetBody 判断是否应该获取Body内容（基于Content-Type和大小），条件来自响应体读取计划
+func shouldGetBody(ctype string, clen int64, need bodyNeed) bool {
+	if need.maxSize > 0 && clen > need.maxSize {
 		return false
 	}
-	lc := strings.ToLower(ctype)
-	if strings.HasPrefix(lc, "text/") {
-		return true
-	}
-	if strings.HasPrefix(lc, "application/json") {
-		return true
-	}
-	return false
+	return need.accepts(ctype)
 }
 
 // parseInt64 简单的正整数解析
@@ -0,0 +1,45 @@
+package cdp
+
+import (
+	"cdpnetool/pkg/model"
+)
+
+// storeVars 保存 captureVar 行为提取的会话变量，同名变量以最新的值为准
+func (m *Manager) storeVars(target model.TargetID, url string, captures map[string]string) {
+	if len(captures) == 0 {
+		return
+	}
+	m.stateMu.Lock()
+	// 写时复制，已取出的模板变量不受影响
+	vars := make(map[string]string, len(m.sessionVars)+len(captures))
+	for k, v := range m.sessionVars {
+		vars[k] = v
+	}
+	for k, v := range captures {
+		vars[k] = v
+	}
+	m.sessionVars = vars
+	m.stateMu.Unlock()
+
+	for name := range captures {
+		m.log.Debug("捕获会话变量", "target", string(target), "url", url, "name", name)
+	}
+}
+
+// Variables 返回 captureVar 行为保存的会话变量副本
+func (m *Manager) Variables() map[string]string {
+	m.stateMu.RLock()
+	defer m.stateMu.RUnlock()
+	out := make(map[string]string, len(m.sessionVars))
+	for k, v := range m.sessionVars {
+		out[k] = v
+	}
+	return out
+}
+
+// ClearVariables 清空会话变量，引用它们的模板恢复为原样输出占位符
+func (m *Manager) ClearVariables() {
+	m.stateMu.Lock()
+	m.sessionVars = nil
+	m.stateMu.Unlock()
+}
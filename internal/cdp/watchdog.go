@@ -0,0 +1,182 @@
+package cdp
+
+import (
+	"context"
+	"io"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/mafredri/cdp/protocol/runtime"
+
+	"cdpnetool/pkg/model"
+)
+
+const (
+	watchdogInterval     = 5 * time.Second  // 看门狗检查间隔
+	watchdogMinThreshold = 10 * time.Second // 判定停滞的最短空闲时间
+)
+
+// pipelineState 单个目标拦截流水线的心跳状态
+type pipelineState struct {
+	inflight     atomic.Int32 // 已暂停但尚未处理完成的请求数
+	lastActivity atomic.Int64 // 最近一次收到或处理完请求的时间（毫秒时间戳）
+	stalled      atomic.Bool  // 是否已上报停滞
+	restarting   atomic.Bool  // 看门狗正在重启拦截流
+
+	streamMu sync.Mutex
+	stream   io.Closer // 当前 RequestPaused 事件流
+}
+
+// begin 记录收到一个暂停请求
+func (p *pipelineState) begin() {
+	p.inflight.Add(1)
+	p.lastActivity.Store(time.Now().UnixMilli())
+}
+
+// end 记录一个请求处理完成
+func (p *pipelineState) end() {
+	if p.inflight.Add(-1) < 0 {
+		p.inflight.Store(0)
+	}
+	p.lastActivity.Store(time.Now().UnixMilli())
+}
+
+// setStream 记录当前事件流
+func (p *pipelineState) setStream(s io.Closer) {
+	p.streamMu.Lock()
+	p.stream = s
+	p.streamMu.Unlock()
+}
+
+// closeStream 关闭当前事件流，使阻塞的 Recv 返回
+func (p *pipelineState) closeStream() {
+	p.streamMu.Lock()
+	defer p.streamMu.Unlock()
+	if p.stream != nil {
+		_ = p.stream.Close()
+		p.stream = nil
+	}
+}
+
+// SetWatchdogRecover 设置看门狗发现停滞且连接正常时是否自动重启拦截流
+func (m *Manager) SetWatchdogRecover(on bool) {
+	m.watchdogRecover = on
+}
+
+// startWatchdog 启动流水线看门狗，调用方需持有 targetsMu
+func (m *Manager) startWatchdog() {
+	if m.watchdogCancel != nil {
+		return
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	m.watchdogCancel = cancel
+	go m.runWatchdog(ctx)
+}
+
+// stopWatchdog 停止流水线看门狗，调用方需持有 targetsMu
+func (m *Manager) stopWatchdog() {
+	if m.watchdogCancel != nil {
+		m.watchdogCancel()
+		m.watchdogCancel = nil
+	}
+}
+
+// stallThreshold 判定停滞的空闲时间，取处理超时的 3 倍且不少于 watchdogMinThreshold
+func (m *Manager) stallThreshold() time.Duration {
+	d := 3 * time.Duration(m.processTimeoutMS) * time.Millisecond
+	if d < watchdogMinThreshold {
+		d = watchdogMinThreshold
+	}
+	return d
+}
+
+// runWatchdog 定期检查各目标的流水线状态
+func (m *Manager) runWatchdog(ctx context.Context) {
+	ticker := time.NewTicker(watchdogInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		m.targetsMu.Lock()
+		targets := make([]*targetSession, 0, len(m.targets))
+		for _, ts := range m.targets {
+			targets = append(targets, ts)
+		}
+		m.targetsMu.Unlock()
+
+		for _, ts := range targets {
+			if ctx.Err() != nil || !m.isEnabled() {
+				return
+			}
+			m.checkPipeline(ts)
+		}
+	}
+}
+
+// checkPipeline 有请求长时间未处理完成时上报停滞，并按设置尝试恢复
+func (m *Manager) checkPipeline(ts *targetSession) {
+	p := &ts.pipe
+	inflight := p.inflight.Load()
+	idle := time.Since(time.UnixMilli(p.lastActivity.Load()))
+	if inflight == 0 || idle < m.stallThreshold() {
+		if p.stalled.Swap(false) {
+			m.log.Info("拦截流水线已恢复", "target", string(ts.id))
+		}
+		return
+	}
+
+	if !p.stalled.Swap(true) {
+		m.log.Warn("拦截流水线停滞", "target", string(ts.id), "inflight", inflight, "idle", idle)
+		m.sendNotice(ts.id, model.NoticePipelineStalled, "", "拦截处理停滞，暂停的请求长时间未被放行", map[string]string{
+			"inflight": strconv.Itoa(int(inflight)),
+			"idleMs":   strconv.FormatInt(idle.Milliseconds(), 10),
+		})
+	}
+
+	if !m.watchdogRecover {
+		return
+	}
+	// 连接已断开时由事件流关闭逻辑处理，不在此重启
+	ctx, cancel := context.WithTimeout(ts.ctx, 2*time.Second)
+	defer cancel()
+	if _, err := ts.client.Runtime.Evaluate(ctx, runtime.NewEvaluateArgs("1")); err != nil {
+		m.log.Warn("目标连接无响应，跳过自动恢复", "target", string(ts.id), "error", err.Error())
+		return
+	}
+	m.restartPipeline(ts)
+}
+
+// restartPipeline 重新订阅拦截流并重新启用 Fetch；停用 Fetch 会放行所有已暂停的请求
+func (m *Manager) restartPipeline(ts *targetSession) {
+	m.targetsMu.Lock()
+	defer m.targetsMu.Unlock()
+	if cur, ok := m.targets[ts.id]; !ok || cur != ts || !m.isEnabled() {
+		return
+	}
+
+	ts.pipe.restarting.Store(true)
+	ts.pipe.closeStream()
+	if err := ts.client.Fetch.Disable(ts.ctx); err != nil {
+		m.log.Err(err, "重启拦截流时停用 Fetch 失败", "target", string(ts.id))
+	}
+	ts.pipe.inflight.Store(0)
+	ts.pipe.lastActivity.Store(time.Now().UnixMilli())
+	rp, err := m.subscribePaused(ts)
+	if err != nil {
+		return
+	}
+	if err := m.applyFetchPatterns(ts); err != nil {
+		m.log.Err(err, "重启拦截流时启用 Fetch 失败", "target", string(ts.id))
+		ts.pipe.closeStream()
+		return
+	}
+	go m.consume(ts, rp)
+
+	m.log.Warn("已自动重启拦截流", "target", string(ts.id))
+	m.sendNotice(ts.id, model.NoticePipelineStalled, "", "已自动重启拦截流，停滞期间暂停的请求已放行", map[string]string{"restarted": "true"})
+}
@@ -0,0 +1,434 @@
+package cdp
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/mafredri/cdp/devtool"
+	"github.com/mafredri/cdp/protocol/network"
+	"github.com/mafredri/cdp/protocol/page"
+	"github.com/mafredri/cdp/protocol/runtime"
+
+	"cdpnetool/internal/rules"
+	"cdpnetool/pkg/model"
+	"cdpnetool/pkg/rulespec"
+)
+
+// CDP 的 WebSocket 帧事件只能观察不能修改，因此帧改写通过注入页面的 WebSocket 包装脚本完成：
+// Go 侧负责将 wsSend/wsReceive 阶段的规则编译为脚本可执行的子集，并通过 Network 事件记录帧流量。
+
+// wsShimRule 注入脚本可执行的规则
+type wsShimRule struct {
+	ID      string         `json:"id"`
+	Stage   rulespec.Stage `json:"stage"`
+	AllOf   []wsShimCond   `json:"allOf"`
+	AnyOf   []wsShimCond   `json:"anyOf"`
+	Actions []wsShimAction `json:"actions"`
+	Stop    bool           `json:"stop"`
+}
+
+// wsShimCond 注入脚本可执行的条件
+type wsShimCond struct {
+	Type    rulespec.ConditionType `json:"type"`
+	Value   string                 `json:"value,omitempty"`
+	Pattern string                 `json:"pattern,omitempty"`
+}
+
+// wsShimAction 注入脚本可执行的行为
+type wsShimAction struct {
+	Type       rulespec.ActionType `json:"type"`
+	Value      string              `json:"value,omitempty"`
+	Base64     bool                `json:"base64,omitempty"`
+	Search     string              `json:"search,omitempty"`
+	Replace    string              `json:"replace,omitempty"`
+	ReplaceAll bool                `json:"replaceAll,omitempty"`
+}
+
+// wsShimHosts 注入脚本使用的主机策略，规则与 HTTP 请求一样只改写允许修改的主机上的帧
+type wsShimHosts struct {
+	Allow []string `json:"allow"`
+	Deny  []string `json:"deny"`
+}
+
+// wsShimConditions 注入脚本支持的条件类型
+var wsShimConditions = map[rulespec.ConditionType]bool{
+	rulespec.ConditionURLEquals:    true,
+	rulespec.ConditionURLPrefix:    true,
+	rulespec.ConditionURLSuffix:    true,
+	rulespec.ConditionURLContains:  true,
+	rulespec.ConditionURLRegex:     true,
+	rulespec.ConditionBodyContains: true,
+	rulespec.ConditionBodyRegex:    true,
+}
+
+// wsShimScript WebSocket 包装脚本，重复执行时只更新规则
+const wsShimScript = `(function () {
+  var state = window.__cdpnetoolWS;
+  if (!state) {
+    state = window.__cdpnetoolWS = { rules: [], hosts: { allow: [], deny: [] } };
+    var Native = window.WebSocket;
+    if (!Native) return;
+    var rewritten = new WeakSet();
+    var hostMatches = function (host, p) {
+      if (p === '*') return true;
+      if (p.indexOf('*.') === 0) {
+        var base = p.slice(2);
+        return host === base || host.slice(-base.length - 1) === '.' + base;
+      }
+      return host === p;
+    };
+    var permitted = function (url) {
+      var hosts = state.hosts;
+      if (!hosts.allow.length && !hosts.deny.length) return true;
+      var host = '';
+      try { host = new URL(url).hostname.toLowerCase().replace(/^\[|\]$/g, ''); } catch (e) {}
+      if (!host) return false;
+      for (var i = 0; i < hosts.deny.length; i++) if (hostMatches(host, hosts.deny[i])) return false;
+      if (!hosts.allow.length) return true;
+      for (var j = 0; j < hosts.allow.length; j++) if (hostMatches(host, hosts.allow[j])) return true;
+      return false;
+    };
+    var test = function (c, url, data) {
+      var text = typeof data === 'string' ? data : null;
+      try {
+        switch (c.type) {
+          case 'urlEquals': return url === c.value;
+          case 'urlPrefix': return url.indexOf(c.value) === 0;
+          case 'urlSuffix': return url.slice(-c.value.length) === c.value;
+          case 'urlContains': return url.indexOf(c.value) !== -1;
+          case 'urlRegex': return new RegExp(c.pattern).test(url);
+          case 'bodyContains': return text !== null && text.indexOf(c.value) !== -1;
+          case 'bodyRegex': return text !== null && new RegExp(c.pattern).test(text);
+        }
+      } catch (e) {}
+      return false;
+    };
+    var matches = function (r, url, data) {
+      for (var i = 0; i < r.allOf.length; i++) if (!test(r.allOf[i], url, data)) return false;
+      if (!r.anyOf.length) return true;
+      for (var j = 0; j < r.anyOf.length; j++) if (test(r.anyOf[j], url, data)) return true;
+      return false;
+    };
+    var decode = function (b64) {
+      var s = atob(b64), u = new Uint8Array(s.length);
+      for (var i = 0; i < s.length; i++) u[i] = s.charCodeAt(i);
+      return u.buffer;
+    };
+    var apply = function (stage, url, data) {
+      var res = { data: data, changed: false, drop: false };
+      if (!permitted(url)) return res;
+      for (var i = 0; i < state.rules.length; i++) {
+        var r = state.rules[i];
+        if (r.stage !== stage || !matches(r, url, res.data)) continue;
+        for (var k = 0; k < r.actions.length; k++) {
+          var a = r.actions[k];
+          if (a.type === 'block') { res.drop = true; res.changed = true; return res; }
+          if (a.type === 'setBody') { res.data = a.base64 ? decode(a.value || '') : (a.value || ''); res.changed = true; }
+          if (a.type === 'replaceBodyText' && typeof res.data === 'string') {
+            res.data = a.replaceAll ? res.data.split(a.search).join(a.replace) : res.data.replace(a.search, a.replace);
+            res.changed = true;
+          }
+        }
+        if (r.stop) return res;
+      }
+      return res;
+    };
+    var send = Native.prototype.send;
+    Native.prototype.send = function (data) {
+      var res = apply('wsSend', this.url, data);
+      if (res.drop) return;
+      return send.call(this, res.data);
+    };
+    var Wrapped = function (url, protocols) {
+      var ws = protocols === undefined ? new Native(url) : new Native(url, protocols);
+      ws.addEventListener('message', function (e) {
+        if (rewritten.has(e)) return;
+        var res = apply('wsReceive', ws.url, e.data);
+        if (!res.changed) return;
+        e.stopImmediatePropagation();
+        if (res.drop) return;
+        var data = res.data;
+        if (data instanceof ArrayBuffer && ws.binaryType === 'blob') data = new Blob([data]);
+        var ne = new MessageEvent('message', { data: data, origin: e.origin, lastEventId: e.lastEventId });
+        rewritten.add(ne);
+        ws.dispatchEvent(ne);
+      });
+      return ws;
+    };
+    Wrapped.prototype = Native.prototype;
+    ['CONNECTING', 'OPEN', 'CLOSING', 'CLOSED'].forEach(function (k) { Wrapped[k] = Native[k]; });
+    window.WebSocket = Wrapped;
+  }
+  state.rules = %s;
+  state.hosts = %s;
+})();`
+
+// compileWebSocketRules 将 WebSocket 阶段的规则编译为注入脚本可执行的子集，
+// 包含脚本不支持的条件的规则只做观察不做改写
+func compileWebSocketRules(cfg *rulespec.Config) ([]wsShimRule, []string) {
+	if cfg == nil {
+		return nil, nil
+	}
+	// 与 HTTP 规则一致，按生效顺序依次作用于帧内容
+	var out []wsShimRule
+	var skipped []string
+	for _, rule := range cfg.EvaluationOrder("") {
+		if rule.Stage != rulespec.StageWSSend && rule.Stage != rulespec.StageWSReceive {
+			continue
+		}
+		sr := wsShimRule{ID: rule.ID, Stage: rule.Stage, AllOf: []wsShimCond{}, AnyOf: []wsShimCond{}, Stop: rule.StopProcessing}
+		supported := true
+		for _, c := range rule.Match.AllOf {
+			if !wsShimConditions[c.Type] {
+				supported = false
+				break
+			}
+			sr.AllOf = append(sr.AllOf, wsShimCond{Type: c.Type, Value: c.Value, Pattern: c.Pattern})
+		}
+		for _, c := range rule.Match.AnyOf {
+			if !wsShimConditions[c.Type] {
+				supported = false
+				break
+			}
+			sr.AnyOf = append(sr.AnyOf, wsShimCond{Type: c.Type, Value: c.Value, Pattern: c.Pattern})
+		}
+		if !supported {
+			skipped = append(skipped, rule.ID)
+			continue
+		}
+		for _, a := range rule.Actions {
+			switch a.Type {
+			case rulespec.ActionSetBody:
+				v, _ := a.Value.(string)
+				sr.Actions = append(sr.Actions, wsShimAction{Type: a.Type, Value: v, Base64: a.GetEncoding() == rulespec.BodyEncodingBase64})
+			case rulespec.ActionReplaceBodyText:
+				sr.Actions = append(sr.Actions, wsShimAction{Type: a.Type, Search: a.Search, Replace: a.Replace, ReplaceAll: a.ReplaceAll})
+			case rulespec.ActionBlock:
+				sr.Actions = append(sr.Actions, wsShimAction{Type: a.Type})
+			}
+		}
+		if len(sr.Actions) > 0 {
+			out = append(out, sr)
+		}
+	}
+	return out, skipped
+}
+
+// installWebSocketShim 向目标注入（或更新）WebSocket 包装脚本与主机策略，cfg 为 nil 时清空规则并移除脚本
+func (m *Manager) installWebSocketShim(ts *targetSession, cfg *rulespec.Config) {
+	// 包装脚本依赖 Page 域，仅注入页面目标
+	if ts == nil || ts.client == nil || ts.kind != devtool.Page {
+		return
+	}
+	// 只读捕获模式下不改写帧
+	if m.captureOnly {
+		cfg = nil
+	}
+	shimRules, skipped := compileWebSocketRules(cfg)
+	if len(skipped) > 0 {
+		m.log.Warn("部分 WebSocket 规则包含不支持改写的条件，仅记录不改写", "target", string(ts.id), "rules", skipped)
+	}
+
+	ts.wsMu.Lock()
+	defer ts.wsMu.Unlock()
+
+	// 从未注入且没有需要改写的规则，无需注入
+	if len(shimRules) == 0 && ts.wsScriptID == "" {
+		return
+	}
+	if shimRules == nil {
+		shimRules = []wsShimRule{}
+	}
+	rulesJSON, err := json.Marshal(shimRules)
+	if err != nil {
+		m.log.Err(err, "序列化 WebSocket 规则失败", "target", string(ts.id))
+		return
+	}
+	hostsJSON, err := json.Marshal(m.webSocketShimHosts())
+	if err != nil {
+		m.log.Err(err, "序列化 WebSocket 主机策略失败", "target", string(ts.id))
+		return
+	}
+	script := fmt.Sprintf(wsShimScript, string(rulesJSON), string(hostsJSON))
+
+	ctx, cancel := context.WithTimeout(ts.ctx, 2*time.Second)
+	defer cancel()
+
+	// 替换新文档注入脚本
+	if ts.wsScriptID != "" {
+		_ = ts.client.Page.RemoveScriptToEvaluateOnNewDocument(ctx, page.NewRemoveScriptToEvaluateOnNewDocumentArgs(ts.wsScriptID))
+		ts.wsScriptID = ""
+	}
+	if len(shimRules) > 0 {
+		reply, err := ts.client.Page.AddScriptToEvaluateOnNewDocument(ctx, page.NewAddScriptToEvaluateOnNewDocumentArgs(script))
+		if err != nil {
+			m.log.Err(err, "注入 WebSocket 包装脚本失败", "target", string(ts.id))
+			return
+		}
+		ts.wsScriptID = reply.Identifier
+	}
+
+	// 更新当前文档（已建立的连接在包装前创建时无法改写接收帧）
+	if _, err := ts.client.Runtime.Evaluate(ctx, runtime.NewEvaluateArgs(script)); err != nil {
+		m.log.Err(err, "更新当前页面 WebSocket 规则失败", "target", string(ts.id))
+	}
+}
+
+// webSocketShimHosts 返回注入脚本使用的主机策略
+func (m *Manager) webSocketShimHosts() wsShimHosts {
+	m.stateMu.RLock()
+	defer m.stateMu.RUnlock()
+	hosts := wsShimHosts{Allow: []string{}, Deny: []string{}}
+	if m.hosts != nil {
+		hosts.Allow = append(hosts.Allow, m.hosts.allow...)
+		hosts.Deny = append(hosts.Deny, m.hosts.deny...)
+	}
+	return hosts
+}
+
+// refreshWebSocketShims 规则或主机策略变化后更新所有已启用拦截目标的 WebSocket 包装脚本
+func (m *Manager) refreshWebSocketShims() {
+	if !m.isEnabled() {
+		return
+	}
+	m.targetsMu.Lock()
+	defer m.targetsMu.Unlock()
+	cfg := m.currentConfig()
+	for _, ts := range m.targets {
+		m.installWebSocketShim(ts, cfg)
+	}
+}
+
+// currentConfig 返回当前引擎中的规则配置
+func (m *Manager) currentConfig() *rulespec.Config {
+	if m.engine == nil {
+		return nil
+	}
+	return m.engine.GetConfig()
+}
+
+// consumeWebSocket 订阅 WebSocket 连接和帧事件，按规则评估并记录事件
+func (m *Manager) consumeWebSocket(ts *targetSession) {
+	created, err := ts.client.Network.WebSocketCreated(ts.ctx)
+	if err != nil {
+		m.log.Err(err, "订阅 WebSocket 创建事件失败", "target", string(ts.id))
+		return
+	}
+	sent, err := ts.client.Network.WebSocketFrameSent(ts.ctx)
+	if err != nil {
+		created.Close()
+		m.log.Err(err, "订阅 WebSocket 发送帧事件失败", "target", string(ts.id))
+		return
+	}
+	received, err := ts.client.Network.WebSocketFrameReceived(ts.ctx)
+	if err != nil {
+		created.Close()
+		sent.Close()
+		m.log.Err(err, "订阅 WebSocket 接收帧事件失败", "target", string(ts.id))
+		return
+	}
+
+	go func() {
+		defer created.Close()
+		for {
+			ev, err := created.Recv()
+			if err != nil {
+				return
+			}
+			ts.wsURLs.Store(ev.RequestID, ev.URL)
+		}
+	}()
+	go func() {
+		defer sent.Close()
+		for {
+			ev, err := sent.Recv()
+			if err != nil {
+				return
+			}
+			m.handleWebSocketFrame(ts, ev.RequestID, ev.Response, rulespec.StageWSSend)
+		}
+	}()
+	go func() {
+		defer received.Close()
+		for {
+			ev, err := received.Recv()
+			if err != nil {
+				return
+			}
+			m.handleWebSocketFrame(ts, ev.RequestID, ev.Response, rulespec.StageWSReceive)
+		}
+	}()
+}
+
+// handleWebSocketFrame 评估单个 WebSocket 帧并发送事件（帧内容为网络层数据）
+func (m *Manager) handleWebSocketFrame(ts *targetSession, id network.RequestID, frame network.WebSocketFrame, stage rulespec.Stage) {
+	if !m.isEnabled() {
+		return
+	}
+	wsURL := ""
+	if v, ok := ts.wsURLs.Load(id); ok {
+		wsURL = v.(string)
+	}
+	method := "WS_SEND"
+	if stage == rulespec.StageWSReceive {
+		method = "WS_RECV"
+	}
+	requestInfo := model.RequestInfo{
+		URL:          wsURL,
+		Method:       method,
+		Headers:      map[string]string{},
+		Body:         frame.PayloadData,
+		ResourceType: string(rulespec.ResourceTypeWebSocket),
+	}
+
+	var matched []*rules.MatchedRule
+	if m.engine != nil && m.isHostPermitted(wsURL) {
+		matched = m.engine.EvalForStage(&rules.EvalContext{
+			URL:          wsURL,
+			Method:       method,
+			Headers:      map[string]string{},
+			Query:        map[string]string{},
+			Cookies:      map[string]string{},
+			Body:         frame.PayloadData,
+			ResourceType: string(rulespec.ResourceTypeWebSocket),
+		}, stage)
+	}
+
+	if len(matched) == 0 {
+		m.sendWebSocketUnmatchedEvent(ts.id, requestInfo)
+		return
+	}
+
+	finalResult := "modified"
+	if m.captureOnly {
+		finalResult = "passed"
+	}
+	for _, mr := range matched {
+		for _, a := range mr.Rule.Actions {
+			if a.Type == rulespec.ActionBlock && !m.captureOnly {
+				finalResult = "blocked"
+			}
+		}
+	}
+	m.sendMatchedEvent(ts.id, nil, finalResult, buildRuleMatches(matched), requestInfo, model.ResponseInfo{Headers: map[string]string{}}, nil)
+}
+
+// sendWebSocketUnmatchedEvent 发送未匹配的 WebSocket 帧事件
+func (m *Manager) sendWebSocketUnmatchedEvent(target model.TargetID, requestInfo model.RequestInfo) {
+	evt := model.InterceptEvent{
+		IsMatched: false,
+		Unmatched: &model.UnmatchedEvent{
+			NetworkEvent: model.NetworkEvent{
+				Target:    target,
+				Timestamp: time.Now().UnixMilli(),
+				IsMatched: false,
+				Request:   requestInfo,
+				Response:  model.ResponseInfo{Headers: map[string]string{}},
+			},
+		},
+	}
+
+	m.emit(evt)
+}
@@ -19,6 +19,16 @@ type workerPool struct {
 	totalDrop   int64
 	mu          sync.Mutex
 	stopMonitor chan struct{}
+
+	hostLimit int                  // 单个主机同时占用的 worker 上限，0 表示不限制
+	hostMu    sync.Mutex           // 保护 hosts
+	hosts     map[string]*hostSlot // 主机 -> 占用情况
+}
+
+// hostSlot 单个主机占用 worker 的情况
+type hostSlot struct {
+	running int      // 正在执行或已入队的任务数
+	waiting []func() // 超出主机上限、等待同主机任务完成的任务
 }
 
 // newWorkerPool 创建工作池，size 为 0 表示无限制
@@ -35,6 +45,16 @@ func newWorkerPool(size int) *workerPool {
 	}
 }
 
+// setHostLimit 设置单个主机同时占用的 worker 上限，需在提交任务前调用；n 不小于工作池大小时视为不限制
+func (p *workerPool) setHostLimit(n int) {
+	if p.sem == nil || n <= 0 || n >= cap(p.sem) {
+		p.hostLimit, p.hosts = 0, nil
+		return
+	}
+	p.hostLimit = n
+	p.hosts = make(map[string]*hostSlot)
+}
+
 // setLogger 设置日志记录器
 func (p *workerPool) setLogger(l logger.Logger) {
 	p.log = l
@@ -119,6 +139,72 @@ func (p *workerPool) submit(fn func()) bool {
 	}
 }
 
+// submitHost 提交属于指定主机的任务：主机已占满上限时任务在主机队列中等待，
+// 由同主机任务完成后的 worker 接着执行，避免单个慢主机占用全部 worker；返回是否成功入队
+func (p *workerPool) submitHost(host string, fn func()) bool {
+	if p.hostLimit <= 0 || host == "" {
+		return p.submit(fn)
+	}
+
+	p.hostMu.Lock()
+	slot, ok := p.hosts[host]
+	if !ok {
+		slot = &hostSlot{}
+		p.hosts[host] = slot
+	}
+	if slot.running >= p.hostLimit {
+		if len(slot.waiting) >= p.queueCap {
+			p.hostMu.Unlock()
+			p.mu.Lock()
+			p.totalSubmit++
+			p.totalDrop++
+			p.mu.Unlock()
+			if p.log != nil {
+				p.log.Warn("主机等待队列已满，任务被丢弃", "host", host, "hostLimit", p.hostLimit)
+			}
+			return false
+		}
+		slot.waiting = append(slot.waiting, fn)
+		p.hostMu.Unlock()
+		return true
+	}
+	slot.running++
+	p.hostMu.Unlock()
+
+	ok = p.submit(func() {
+		for task := fn; task != nil; task = p.nextForHost(host) {
+			task()
+		}
+	})
+	if !ok {
+		p.hostMu.Lock()
+		slot.running--
+		if slot.running == 0 && len(slot.waiting) == 0 {
+			delete(p.hosts, host)
+		}
+		p.hostMu.Unlock()
+	}
+	return ok
+}
+
+// nextForHost 取出主机的下一个等待任务；没有等待任务时释放占用并返回 nil
+func (p *workerPool) nextForHost(host string) func() {
+	p.hostMu.Lock()
+	defer p.hostMu.Unlock()
+	slot := p.hosts[host]
+	if len(slot.waiting) > 0 {
+		next := slot.waiting[0]
+		slot.waiting[0] = nil
+		slot.waiting = slot.waiting[1:]
+		return next
+	}
+	slot.running--
+	if slot.running == 0 {
+		delete(p.hosts, host)
+	}
+	return nil
+}
+
 // stats 返回工作池统计信息
 func (p *workerPool) stats() (queueLen, queueCap, totalSubmit, totalDrop int64) {
 	if p.sem == nil {
@@ -0,0 +1,113 @@
+package cdp
+
+import (
+	"context"
+	"net/url"
+	"time"
+
+	"github.com/mafredri/cdp/devtool"
+
+	"cdpnetool/pkg/model"
+)
+
+// workerPollInterval worker 目标发现的轮询间隔
+const workerPollInterval = 2 * time.Second
+
+// workerTypes 自动附加的 worker 目标类型。专用 worker（dedicated worker）不出现在
+// DevTools 目标列表中，需要 flatten 会话支持，当前不在自动附加范围内
+var workerTypes = map[devtool.Type]bool{
+	devtool.ServiceWorker:         true,
+	devtool.Type("shared_worker"): true,
+}
+
+// SetInterceptWorkers 设置是否自动附加与已附加页面同源的 service worker / shared worker
+func (m *Manager) SetInterceptWorkers(on bool) {
+	m.interceptWorkers = on
+}
+
+// startWorkerWatch 启动 worker 目标发现，调用方需持有 targetsMu
+func (m *Manager) startWorkerWatch() {
+	if m.workerCancel != nil {
+		return
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	m.workerCancel = cancel
+	go m.watchWorkers(ctx)
+}
+
+// stopWorkerWatch 停止 worker 目标发现，调用方需持有 targetsMu
+func (m *Manager) stopWorkerWatch() {
+	if m.workerCancel != nil {
+		m.workerCancel()
+		m.workerCancel = nil
+	}
+}
+
+// watchWorkers 定期发现并附加新的 worker 目标
+func (m *Manager) watchWorkers(ctx context.Context) {
+	ticker := time.NewTicker(workerPollInterval)
+	defer ticker.Stop()
+	for {
+		m.attachWorkers(ctx)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// attachWorkers 附加与已附加页面同源且尚未附加的 worker 目标
+func (m *Manager) attachWorkers(ctx context.Context) {
+	listCtx, cancel := context.WithTimeout(ctx, 2*time.Second)
+	defer cancel()
+	targets, err := devtool.New(m.devtoolsURL).List(listCtx)
+	if err != nil {
+		m.log.Debug("获取 worker 目标列表失败", "error", err)
+		return
+	}
+
+	m.targetsMu.Lock()
+	defer m.targetsMu.Unlock()
+	if ctx.Err() != nil || !m.isEnabled() {
+		return
+	}
+
+	// 已附加页面的源
+	origins := make(map[string]bool)
+	for _, t := range targets {
+		if t.Type == devtool.Page && m.targets[model.TargetID(t.ID)] != nil {
+			origins[originOf(t.URL)] = true
+		}
+	}
+
+	for _, t := range targets {
+		if !workerTypes[t.Type] || m.targets[model.TargetID(t.ID)] != nil || !origins[originOf(t.URL)] {
+			continue
+		}
+		tctx, tcancel := context.WithCancel(context.Background())
+		ts, err := dialTarget(tctx, tcancel, t)
+		if err != nil {
+			m.log.Err(err, "连接 worker 目标失败", "target", t.ID, "url", t.URL)
+			continue
+		}
+		m.targets[ts.id] = ts
+		m.applyTargetOverrides(ts)
+		if err := m.enableTarget(ts); err != nil {
+			m.log.Err(err, "为 worker 目标启用拦截失败", "target", t.ID)
+			m.closeTargetSession(ts)
+			delete(m.targets, ts.id)
+			continue
+		}
+		m.log.Info("已自动附加 worker 目标", "target", t.ID, "type", string(t.Type), "url", t.URL)
+	}
+}
+
+// originOf 返回 URL 的源（scheme://host）
+func originOf(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil || u.Host == "" {
+		return ""
+	}
+	return u.Scheme + "://" + u.Host
+}
@@ -0,0 +1,200 @@
+// Package cdpfake 提供内存中的假浏览器，用于在没有真实 Chrome 的环境中测试拦截流程。
+//
+// 假浏览器对外暴露与 Chrome 相同的 DevTools HTTP 接口（/json/version、/json/list、/json/new）和目标 WebSocket，
+// 实现 Manager 用到的 Fetch、Network、Page、Runtime、IO、Emulation 域子集（含浏览器级 Cookie 存储）；目标的发现与关闭通过 Browser 的方法模拟。
+// 页面请求由 Target.Fetch 发起，向启用了 Network 域的连接推送请求生命周期事件，按已启用的拦截模式依次触发请求阶段和响应阶段的 Fetch.requestPaused 事件，
+// 并等待客户端放行、改写或终止后返回页面最终看到的结果。
+package cdpfake
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+
+	"github.com/gorilla/websocket"
+	"github.com/mafredri/cdp/devtool"
+	"github.com/mafredri/cdp/protocol/network"
+)
+
+// Origin 模拟源站：根据最终发出的请求返回响应
+type Origin func(req Request) Response
+
+// Browser 假浏览器
+type Browser struct {
+	srv      *http.Server
+	ln       net.Listener
+	upgrader websocket.Upgrader
+	origin   Origin
+
+	mu      sync.Mutex
+	targets []*Target
+	nextID  int
+	cookies cookieJar // 受 mu 保护
+}
+
+// Cookies 返回浏览器中的全部 Cookie
+func (b *Browser) Cookies() []network.Cookie {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return append([]network.Cookie(nil), b.cookies.cookies...)
+}
+
+// New 启动一个监听本机随机端口的假浏览器，origin 为 nil 时所有请求返回 200 空响应
+func New(origin Origin) (*Browser, error) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return nil, err
+	}
+	if origin == nil {
+		origin = func(Request) Response { return Response{StatusCode: 200} }
+	}
+	b := &Browser{ln: ln, origin: origin}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/json/version", b.handleVersion)
+	mux.HandleFunc("/json/new", b.handleNew)
+	mux.HandleFunc("/json/list", b.handleList)
+	mux.HandleFunc("/json", b.handleList)
+	mux.HandleFunc("/devtools/", b.handleWebSocket)
+	b.srv = &http.Server{Handler: mux}
+	go func() { _ = b.srv.Serve(ln) }()
+	return b, nil
+}
+
+// URL 返回 DevTools HTTP 地址，可直接作为 SessionConfig.DevToolsURL
+func (b *Browser) URL() string {
+	return "http://" + b.ln.Addr().String()
+}
+
+// Close 关闭假浏览器及所有目标连接
+func (b *Browser) Close() error {
+	b.mu.Lock()
+	targets := b.targets
+	b.targets = nil
+	b.mu.Unlock()
+	for _, t := range targets {
+		t.Disconnect()
+	}
+	return b.srv.Close()
+}
+
+// AddPage 新建一个页面目标
+func (b *Browser) AddPage(url string) *Target {
+	return b.AddTarget(devtool.Page, url)
+}
+
+// AddTarget 新建指定类型的目标（page / service_worker / shared_worker）
+func (b *Browser) AddTarget(kind devtool.Type, url string) *Target {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.nextID++
+	t := newTarget(b, fmt.Sprintf("FAKE%04d", b.nextID), kind, url)
+	b.targets = append(b.targets, t)
+	return t
+}
+
+// Target 按 ID 查找目标
+func (b *Browser) Target(id string) *Target {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for _, t := range b.targets {
+		if t.id == id {
+			return t
+		}
+	}
+	return nil
+}
+
+// CloseTarget 关闭目标：从目标列表移除并断开其所有连接，模拟标签页被关闭
+func (b *Browser) CloseTarget(id string) {
+	b.mu.Lock()
+	var closed *Target
+	for i, t := range b.targets {
+		if t.id == id {
+			closed = t
+			b.targets = append(b.targets[:i], b.targets[i+1:]...)
+			break
+		}
+	}
+	b.mu.Unlock()
+	if closed != nil {
+		closed.Disconnect()
+	}
+}
+
+// handleVersion 返回浏览器版本信息
+func (b *Browser) handleVersion(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, map[string]string{
+		"Browser":              "cdpnetool-fake/1.0",
+		"Protocol-Version":     "1.3",
+		"User-Agent":           "cdpnetool-fake",
+		"webSocketDebuggerUrl": b.wsURL("browser", "fake"),
+	})
+}
+
+// handleNew 新建页面目标，URL 查询串为空时打开 about:blank
+func (b *Browser) handleNew(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPut {
+		http.Error(w, "Using unsafe HTTP verb GET to invoke /json/new", http.StatusMethodNotAllowed)
+		return
+	}
+	open := "about:blank"
+	if q, err := url.QueryUnescape(r.URL.RawQuery); err == nil && q != "" {
+		open = q
+	}
+	t := b.AddPage(open)
+	writeJSON(w, devtool.Target{
+		ID:                   t.id,
+		Type:                 t.kind,
+		Title:                t.url,
+		URL:                  t.url,
+		WebSocketDebuggerURL: b.wsURL(string(t.kind), t.id),
+	})
+}
+
+// handleList 返回目标列表
+func (b *Browser) handleList(w http.ResponseWriter, r *http.Request) {
+	b.mu.Lock()
+	out := make([]devtool.Target, 0, len(b.targets))
+	for _, t := range b.targets {
+		out = append(out, devtool.Target{
+			ID:                   t.id,
+			Type:                 t.kind,
+			Title:                t.url,
+			URL:                  t.url,
+			WebSocketDebuggerURL: b.wsURL(string(t.kind), t.id),
+		})
+	}
+	b.mu.Unlock()
+	writeJSON(w, out)
+}
+
+// handleWebSocket 将目标调试连接升级为 WebSocket 并交由目标处理
+func (b *Browser) handleWebSocket(w http.ResponseWriter, r *http.Request) {
+	id := r.URL.Path[strings.LastIndex(r.URL.Path, "/")+1:]
+	t := b.Target(id)
+	if t == nil {
+		http.Error(w, "No such target id: "+id, http.StatusNotFound)
+		return
+	}
+	ws, err := b.upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		return
+	}
+	t.serve(ws)
+}
+
+// wsURL 返回目标的调试地址
+func (b *Browser) wsURL(kind, id string) string {
+	return fmt.Sprintf("ws://%s/devtools/%s/%s", b.ln.Addr().String(), kind, id)
+}
+
+// writeJSON 以 JSON 格式写出响应
+func writeJSON(w http.ResponseWriter, v any) {
+	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+	_ = json.NewEncoder(w).Encode(v)
+}
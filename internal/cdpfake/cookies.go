@@ -0,0 +1,99 @@
+package cdpfake
+
+import (
+	"net/url"
+	"strings"
+
+	"github.com/mafredri/cdp/protocol/network"
+)
+
+// cookieJar 浏览器级 Cookie 存储，所有目标共享
+type cookieJar struct {
+	cookies []network.Cookie
+}
+
+// set 新增或替换同名、同域、同路径的 Cookie
+func (j *cookieJar) set(args network.SetCookieArgs) *rpcError {
+	c := network.Cookie{Name: args.Name, Value: args.Value, Path: "/", SameSite: args.SameSite, Session: true, Expires: -1}
+	if args.URL != nil {
+		u, err := url.Parse(*args.URL)
+		if err != nil || u.Host == "" {
+			return &rpcError{Code: -32602, Message: "Invalid cookie fields"}
+		}
+		c.Domain = u.Hostname()
+	}
+	if args.Domain != nil {
+		c.Domain = *args.Domain
+	}
+	if c.Domain == "" {
+		return &rpcError{Code: -32602, Message: "Invalid cookie fields"}
+	}
+	if args.Path != nil {
+		c.Path = *args.Path
+	}
+	if args.Secure != nil {
+		c.Secure = *args.Secure
+	}
+	if args.HTTPOnly != nil {
+		c.HTTPOnly = *args.HTTPOnly
+	}
+	if args.Expires > 0 {
+		c.Expires = float64(args.Expires)
+		c.Session = false
+	}
+	for i, old := range j.cookies {
+		if old.Name == c.Name && old.Domain == c.Domain && old.Path == c.Path {
+			j.cookies[i] = c
+			return nil
+		}
+	}
+	j.cookies = append(j.cookies, c)
+	return nil
+}
+
+// get 返回适用于任一 URL 的 Cookie
+func (j *cookieJar) get(urls []string) []network.Cookie {
+	out := []network.Cookie{}
+	for _, c := range j.cookies {
+		for _, raw := range urls {
+			if cookieMatchesURL(c, raw) {
+				out = append(out, c)
+				break
+			}
+		}
+	}
+	return out
+}
+
+// delete 删除匹配条件的 Cookie
+func (j *cookieJar) delete(args network.DeleteCookiesArgs) {
+	kept := j.cookies[:0]
+	for _, c := range j.cookies {
+		match := c.Name == args.Name &&
+			(args.URL == nil || cookieMatchesURL(c, *args.URL)) &&
+			(args.Domain == nil || c.Domain == *args.Domain) &&
+			(args.Path == nil || c.Path == *args.Path)
+		if !match {
+			kept = append(kept, c)
+		}
+	}
+	j.cookies = kept
+}
+
+// cookieMatchesURL 按域名和路径判断 Cookie 是否适用于 URL
+func cookieMatchesURL(c network.Cookie, raw string) bool {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return false
+	}
+	host := strings.ToLower(u.Hostname())
+	domain := strings.ToLower(strings.TrimPrefix(c.Domain, "."))
+	if host != domain && !strings.HasSuffix(host, "."+domain) {
+		return false
+	}
+	path := u.Path
+	if path == "" {
+		path = "/"
+	}
+	return strings.HasPrefix(path, c.Path)
+}
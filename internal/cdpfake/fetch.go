@@ -0,0 +1,661 @@
+package cdpfake
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+	"unicode/utf8"
+
+	"github.com/mafredri/cdp/protocol/emulation"
+	"github.com/mafredri/cdp/protocol/fetch"
+	"github.com/mafredri/cdp/protocol/io"
+	"github.com/mafredri/cdp/protocol/network"
+)
+
+// methodDetached 调试连接断开导致的放行
+const methodDetached = "detached"
+
+// redeliverAfter 已暂停请求在客户端没有任何相关调用时重新推送事件的间隔。
+// 客户端可能在 Fetch.enable 之后才订阅事件流，订阅前推送的事件会被丢弃
+const redeliverAfter = 500 * time.Millisecond
+
+// maxAuthAttempts 同一请求推送认证质询的次数上限，与浏览器在凭据被拒绝后不再重试的行为一致
+const maxAuthAttempts = 3
+
+// readChunkSize IO.read 未指定 size 时每次返回的字节数
+const readChunkSize = 64 << 10
+
+// Request 页面发出的请求
+type Request struct {
+	URL          string
+	Method       string // 为空时使用 GET
+	Headers      map[string]string
+	Body         []byte
+	ResourceType network.ResourceType // 为空时使用 Fetch
+	Username     string               // 应答认证质询时提供的用户名，源站据此判断是否放行
+	Password     string               // 应答认证质询时提供的密码
+}
+
+// Response 源站或拦截方返回的响应
+type Response struct {
+	StatusCode int
+	Headers    map[string]string
+	Body       []byte
+	Protocol   string // Network.responseReceived 上报的协议，为空时不上报
+	RemoteIP   string // Network.responseReceived 上报的服务端 IP，为空时不上报
+	RemotePort int
+	Security   *network.SecurityDetails // Network.responseReceived 上报的 TLS 信息
+	// BlockedCookies Network.responseReceivedExtraInfo 上报的被拒绝保存的 Set-Cookie
+	BlockedCookies []network.BlockedSetCookieWithReason
+	// AuthChallenge 非空时表示 401/407 认证质询，启用了 handleAuthRequests 的连接会收到 Fetch.authRequired
+	AuthChallenge *fetch.AuthChallenge
+	// NetError 非空时模拟网络层失败（如 NameNotResolved），页面只收到 Network.loadingFailed，响应阶段不暂停
+	NetError network.ErrorReason
+}
+
+// Result 页面最终看到的请求结果
+type Result struct {
+	Paused      []fetch.RequestStage // 依次暂停过的阶段
+	Challenges  int                  // 推送的 Fetch.authRequired 次数
+	Sent        *Request             // 实际发往源站的请求；在请求阶段被应答或终止时为 nil
+	Response    Response             // 页面收到的响应
+	Fulfilled   bool                 // 响应由 Fetch.fulfillRequest 提供
+	Failed      bool                 // 请求被 Fetch.failRequest 终止或在网络层失败
+	ErrorReason network.ErrorReason  // 终止原因
+}
+
+// decision 客户端对暂停请求的处理
+type decision struct {
+	method string
+	params json.RawMessage
+}
+
+// pending 一个等待客户端处理的暂停请求
+type pending struct {
+	conn     *conn
+	response bool   // 暂停在响应阶段
+	body     []byte // 响应阶段的原始响应体
+	once     sync.Once
+	done     chan decision
+	touched  atomic.Bool // 客户端已对该请求发起过调用
+}
+
+// resolve 记录处理结果，只有第一次生效
+func (p *pending) resolve(d decision) bool {
+	ok := false
+	p.once.Do(func() {
+		p.done <- d
+		ok = true
+	})
+	return ok
+}
+
+// Fetch 模拟页面发起一次请求：按已启用的拦截模式在请求阶段和响应阶段暂停，
+// 等待客户端处理后返回页面最终看到的结果；未被拦截的阶段直接经过
+func (t *Target) Fetch(ctx context.Context, req Request) (*Result, error) {
+	if req.Method == "" {
+		req.Method = "GET"
+	}
+	if req.ResourceType == "" {
+		req.ResourceType = network.ResourceTypeFetch
+	}
+
+	t.mu.Lock()
+	t.nextReq++
+	seq := t.nextReq
+	ua := t.userAgent
+	extra := t.extra
+	t.mu.Unlock()
+	req = applyUserAgent(req, ua)
+	req = applyExtraHeaders(req, extra)
+
+	netID := network.RequestID(fmt.Sprint(seq))
+	t.emitNetwork("Network.requestWillBeSent", network.RequestWillBeSentReply{
+		RequestID:   netID,
+		LoaderID:    "FAKELOADER",
+		DocumentURL: t.url,
+		Request:     networkRequest(req),
+		Timestamp:   network.MonotonicTime(time.Now().UnixNano()) / 1e9,
+		Type:        req.ResourceType,
+	})
+	res, err := t.intercept(ctx, seq, netID, req)
+	if err != nil {
+		return nil, err
+	}
+	t.finishNetwork(netID, req, res)
+	return res, nil
+}
+
+// intercept 按已启用的拦截模式依次暂停请求阶段和响应阶段
+func (t *Target) intercept(ctx context.Context, seq int, netID network.RequestID, req Request) (*Result, error) {
+	res := &Result{}
+	interceptResponse := false
+	if c := t.interceptor(req, fetch.RequestStageRequest); c != nil {
+		res.Paused = append(res.Paused, fetch.RequestStageRequest)
+		d, err := t.pause(ctx, c, fetch.RequestID(fmt.Sprintf("interception-job-%d.0", seq)), netID, req, nil)
+		if err != nil {
+			return nil, err
+		}
+		switch d.method {
+		case "Fetch.continueRequest":
+			var args fetch.ContinueRequestArgs
+			_ = json.Unmarshal(d.params, &args)
+			req = continueRequest(req, &args)
+			interceptResponse = args.InterceptResponse != nil && *args.InterceptResponse
+		case "Fetch.fulfillRequest":
+			res.Response, res.Fulfilled = fulfill(d.params, Response{}), true
+			return res, nil
+		case "Fetch.failRequest":
+			res.Failed, res.ErrorReason = true, failReason(d.params)
+			return res, nil
+		}
+	}
+
+	sent := req
+	res.Sent = &sent
+	t.emitNetwork("Network.requestWillBeSentExtraInfo", network.RequestWillBeSentExtraInfoReply{
+		RequestID:         netID,
+		AssociatedCookies: []network.AssociatedCookie{},
+		Headers:           networkRequest(req).Headers,
+	})
+	resp := t.b.origin(req)
+	// 客户端提供凭据时带上凭据重新请求源站，最多应答 maxAuthAttempts 次
+	for resp.AuthChallenge != nil && res.Challenges < maxAuthAttempts {
+		c := t.authInterceptor(req)
+		if c == nil {
+			break
+		}
+		res.Challenges++
+		// 与浏览器一致，同一请求的多次质询使用相同的拦截 ID
+		d, err := t.challenge(ctx, c, fetch.RequestID(fmt.Sprintf("interception-job-%d.0", seq)), req, resp.AuthChallenge)
+		if err != nil {
+			return nil, err
+		}
+		var args fetch.ContinueWithAuthArgs
+		_ = json.Unmarshal(d.params, &args)
+		if args.AuthChallengeResponse.Response != "ProvideCredentials" {
+			break
+		}
+		if u := args.AuthChallengeResponse.Username; u != nil {
+			req.Username = *u
+		}
+		if p := args.AuthChallengeResponse.Password; p != nil {
+			req.Password = *p
+		}
+		sent = req
+		resp = t.b.origin(req)
+	}
+	if resp.NetError != "" {
+		res.Failed, res.ErrorReason = true, resp.NetError
+		return res, nil
+	}
+	if resp.StatusCode == 0 {
+		resp.StatusCode = 200
+	}
+	respHeaders, _ := json.Marshal(resp.Headers)
+	if resp.Headers == nil {
+		respHeaders = []byte("{}")
+	}
+	blocked := resp.BlockedCookies
+	if blocked == nil {
+		blocked = []network.BlockedSetCookieWithReason{}
+	}
+	t.emitNetwork("Network.responseReceivedExtraInfo", network.ResponseReceivedExtraInfoReply{
+		RequestID:      netID,
+		BlockedCookies: blocked,
+		Headers:        respHeaders,
+		StatusCode:     resp.StatusCode,
+	})
+
+	c := t.interceptor(req, fetch.RequestStageResponse)
+	if c == nil && interceptResponse {
+		c = t.anyInterceptor()
+	}
+	if c != nil {
+		res.Paused = append(res.Paused, fetch.RequestStageResponse)
+		d, err := t.pause(ctx, c, fetch.RequestID(fmt.Sprintf("interception-job-%d.1", seq)), netID, req, &resp)
+		if err != nil {
+			return nil, err
+		}
+		switch d.method {
+		case "Fetch.continueResponse":
+			var args fetch.ContinueResponseArgs
+			_ = json.Unmarshal(d.params, &args)
+			if args.ResponseCode != nil {
+				resp.StatusCode = *args.ResponseCode
+			}
+			if args.ResponseHeaders != nil {
+				resp.Headers = headerMap(args.ResponseHeaders)
+			}
+		case "Fetch.fulfillRequest":
+			resp, res.Fulfilled = fulfill(d.params, resp), true
+		case "Fetch.failRequest":
+			res.Failed, res.ErrorReason = true, failReason(d.params)
+			return res, nil
+		}
+	}
+	res.Response = resp
+	return res, nil
+}
+
+// finishNetwork 推送请求结束的 Network 事件，响应体保留供 Network.getResponseBody 读取
+func (t *Target) finishNetwork(id network.RequestID, req Request, res *Result) {
+	typ := req.ResourceType
+	now := network.MonotonicTime(time.Now().UnixNano()) / 1e9
+	if res.Failed {
+		t.emitNetwork("Network.loadingFailed", network.LoadingFailedReply{
+			RequestID: id,
+			Timestamp: now,
+			Type:      typ,
+			ErrorText: "net::ERR_" + strings.ToUpper(string(res.ErrorReason)),
+		})
+		return
+	}
+	headers, _ := json.Marshal(res.Response.Headers)
+	if res.Response.Headers == nil {
+		headers = []byte("{}")
+	}
+	mimeType := ""
+	for k, v := range res.Response.Headers {
+		if strings.EqualFold(k, "Content-Type") {
+			mimeType = v
+		}
+	}
+	t.mu.Lock()
+	t.bodies[id] = res.Response.Body
+	t.mu.Unlock()
+	resp := network.Response{
+		URL:      req.URL,
+		Status:   res.Response.StatusCode,
+		Headers:  headers,
+		MimeType: mimeType,
+	}
+	if res.Response.Protocol != "" {
+		resp.Protocol = &res.Response.Protocol
+	}
+	if res.Response.RemoteIP != "" {
+		resp.RemoteIPAddress, resp.RemotePort = &res.Response.RemoteIP, &res.Response.RemotePort
+	}
+	resp.SecurityDetails = res.Response.Security
+	t.emitNetwork("Network.responseReceived", network.ResponseReceivedReply{
+		RequestID: id,
+		LoaderID:  "FAKELOADER",
+		Timestamp: now,
+		Type:      typ,
+		Response:  resp,
+	})
+	t.emitNetwork("Network.loadingFinished", network.LoadingFinishedReply{
+		RequestID:         id,
+		Timestamp:         now,
+		EncodedDataLength: float64(len(res.Response.Body)),
+	})
+}
+
+// interceptor 返回拦截指定阶段请求的连接
+func (t *Target) interceptor(req Request, stage fetch.RequestStage) *conn {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for _, c := range t.conns {
+		if !c.fetchOn {
+			continue
+		}
+		for _, p := range c.patterns {
+			if patternMatches(p, req, stage) {
+				return c
+			}
+		}
+	}
+	return nil
+}
+
+// anyInterceptor 返回任一启用了 Fetch 的连接
+func (t *Target) anyInterceptor() *conn {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for _, c := range t.conns {
+		if c.fetchOn {
+			return c
+		}
+	}
+	return nil
+}
+
+// authInterceptor 返回处理认证质询的连接，与浏览器一致只为命中拦截模式的请求上报质询
+func (t *Target) authInterceptor(req Request) *conn {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for _, c := range t.conns {
+		if !c.fetchOn || !c.authOn {
+			continue
+		}
+		for _, p := range c.patterns {
+			if patternMatches(p, req, fetch.RequestStageRequest) || patternMatches(p, req, fetch.RequestStageResponse) {
+				return c
+			}
+		}
+	}
+	return nil
+}
+
+// challenge 推送 Fetch.authRequired 事件并等待客户端调用 Fetch.continueWithAuth
+func (t *Target) challenge(ctx context.Context, c *conn, id fetch.RequestID, req Request, ch *fetch.AuthChallenge) (decision, error) {
+	params, err := json.Marshal(fetch.AuthRequiredReply{
+		RequestID:     id,
+		Request:       networkRequest(req),
+		FrameID:       "FAKEFRAME",
+		ResourceType:  req.ResourceType,
+		AuthChallenge: *ch,
+	})
+	if err != nil {
+		return decision{}, err
+	}
+	return t.await(ctx, c, id, &pending{conn: c, done: make(chan decision, 1)}, rpcMessage{Method: "Fetch.authRequired", Params: params})
+}
+
+// pause 推送 Fetch.requestPaused 事件并等待客户端处理
+func (t *Target) pause(ctx context.Context, c *conn, id fetch.RequestID, netID network.RequestID, req Request, resp *Response) (decision, error) {
+	p := &pending{conn: c, done: make(chan decision, 1)}
+	ev := fetch.RequestPausedReply{
+		RequestID:    id,
+		NetworkID:    &netID,
+		Request:      networkRequest(req),
+		FrameID:      "FAKEFRAME",
+		ResourceType: req.ResourceType,
+	}
+	if resp != nil {
+		code := resp.StatusCode
+		ev.ResponseStatusCode = &code
+		ev.ResponseHeaders = headerEntries(resp.Headers)
+		p.response, p.body = true, resp.Body
+	}
+	params, err := json.Marshal(ev)
+	if err != nil {
+		return decision{}, err
+	}
+	return t.await(ctx, c, id, p, rpcMessage{Method: "Fetch.requestPaused", Params: params})
+}
+
+// await 推送事件并等待客户端处理，客户端未响应时定期重发
+func (t *Target) await(ctx context.Context, c *conn, id fetch.RequestID, p *pending, msg rpcMessage) (decision, error) {
+	t.mu.Lock()
+	t.pending[id] = p
+	t.mu.Unlock()
+	defer func() {
+		t.mu.Lock()
+		delete(t.pending, id)
+		t.mu.Unlock()
+	}()
+
+	if err := c.write(msg); err != nil {
+		return decision{method: methodDetached}, nil
+	}
+	ticker := time.NewTicker(redeliverAfter)
+	defer ticker.Stop()
+	for {
+		select {
+		case d := <-p.done:
+			return d, nil
+		case <-ctx.Done():
+			return decision{}, ctx.Err()
+		case <-ticker.C:
+			if !p.touched.Load() {
+				_ = c.write(msg)
+			}
+		}
+	}
+}
+
+// lookup 查找暂停中的请求并标记客户端已处理过它
+func (t *Target) lookup(raw json.RawMessage) (*pending, *rpcError) {
+	var args struct {
+		RequestID fetch.RequestID `json:"requestId"`
+	}
+	if err := decodeParams(raw, &args); err != nil {
+		return nil, err
+	}
+	t.mu.Lock()
+	p := t.pending[args.RequestID]
+	t.mu.Unlock()
+	if p == nil {
+		return nil, &rpcError{Code: -32602, Message: "Invalid InterceptionId."}
+	}
+	p.touched.Store(true)
+	return p, nil
+}
+
+// decide 处理放行、应答或终止调用
+func (t *Target) decide(msg rpcMessage) *rpcError {
+	p, err := t.lookup(msg.Params)
+	if err != nil {
+		return err
+	}
+	if msg.Method == "Fetch.continueResponse" && !p.response {
+		return &rpcError{Code: -32602, Message: "Can only use continueResponse for requests intercepted at response stage"}
+	}
+	if !p.resolve(decision{method: msg.Method, params: msg.Params}) {
+		return &rpcError{Code: -32602, Message: "Invalid InterceptionId."}
+	}
+	return nil
+}
+
+// getResponseBody 返回响应阶段的原始响应体，非 UTF-8 内容以 base64 返回
+func (t *Target) getResponseBody(raw json.RawMessage) (any, *rpcError) {
+	p, err := t.lookup(raw)
+	if err != nil {
+		return nil, err
+	}
+	if !p.response {
+		return nil, &rpcError{Code: -32000, Message: "Can only get response body on requests captured after headers received."}
+	}
+	if utf8.Valid(p.body) {
+		return fetch.GetResponseBodyReply{Body: string(p.body)}, nil
+	}
+	return fetch.GetResponseBodyReply{Body: base64.StdEncoding.EncodeToString(p.body), Base64Encoded: true}, nil
+}
+
+// takeResponseBody 以流的形式交出响应阶段的原始响应体
+func (t *Target) takeResponseBody(raw json.RawMessage) (any, *rpcError) {
+	p, err := t.lookup(raw)
+	if err != nil {
+		return nil, err
+	}
+	if !p.response {
+		return nil, &rpcError{Code: -32000, Message: "Can only take response body on requests captured after headers received."}
+	}
+	t.mu.Lock()
+	t.nextStream++
+	h := io.StreamHandle(fmt.Sprint(t.nextStream))
+	t.streams[h] = p.body
+	t.mu.Unlock()
+	return fetch.TakeResponseBodyAsStreamReply{Stream: h}, nil
+}
+
+// readStream 读取响应体流的下一段
+func (t *Target) readStream(raw json.RawMessage) (any, *rpcError) {
+	var args io.ReadArgs
+	if err := decodeParams(raw, &args); err != nil {
+		return nil, err
+	}
+	size := readChunkSize
+	if args.Size != nil && *args.Size > 0 {
+		size = *args.Size
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	data, ok := t.streams[args.Handle]
+	if !ok {
+		return nil, &rpcError{Code: -32000, Message: "Invalid stream handle"}
+	}
+	if size > len(data) {
+		size = len(data)
+	}
+	t.streams[args.Handle] = data[size:]
+	b64 := true
+	return io.ReadReply{
+		Base64Encoded: &b64,
+		Data:          base64.StdEncoding.EncodeToString(data[:size]),
+		EOF:           size == len(data),
+	}, nil
+}
+
+// continueRequest 应用放行请求时的覆盖参数
+func continueRequest(req Request, args *fetch.ContinueRequestArgs) Request {
+	if args.URL != nil {
+		req.URL = *args.URL
+	}
+	if args.Method != nil {
+		req.Method = *args.Method
+	}
+	if args.PostData != nil {
+		req.Body = args.PostData
+	}
+	if args.Headers != nil {
+		req.Headers = headerMap(args.Headers)
+	}
+	return req
+}
+
+// fulfill 解析 Fetch.fulfillRequest 提供的响应，未提供 Body 时沿用原响应体，连接信息沿用原响应
+func fulfill(raw json.RawMessage, orig Response) Response {
+	var args fetch.FulfillRequestArgs
+	_ = json.Unmarshal(raw, &args)
+	body := args.Body
+	if body == nil {
+		body = orig.Body
+	}
+	out := orig
+	out.StatusCode, out.Headers, out.Body = args.ResponseCode, headerMap(args.ResponseHeaders), body
+	return out
+}
+
+// failReason 解析 Fetch.failRequest 的错误原因
+func failReason(raw json.RawMessage) network.ErrorReason {
+	var args fetch.FailRequestArgs
+	_ = json.Unmarshal(raw, &args)
+	return args.ErrorReason
+}
+
+// networkRequest 构造事件中的请求信息
+func networkRequest(req Request) network.Request {
+	headers, _ := json.Marshal(req.Headers)
+	if req.Headers == nil {
+		headers = []byte("{}")
+	}
+	out := network.Request{URL: req.URL, Method: req.Method, Headers: headers}
+	if len(req.Body) > 0 {
+		has := true
+		out.HasPostData = &has
+		b64 := base64.StdEncoding.EncodeToString(req.Body)
+		out.PostDataEntries = []network.PostDataEntry{{Bytes: &b64}}
+	}
+	return out
+}
+
+// headerEntries 将头部映射转换为按名称排序的头部列表
+func headerEntries(h map[string]string) []fetch.HeaderEntry {
+	out := make([]fetch.HeaderEntry, 0, len(h))
+	for k, v := range h {
+		out = append(out, fetch.HeaderEntry{Name: k, Value: v})
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Name < out[j].Name })
+	return out
+}
+
+// applyUserAgent 像浏览器一样按 User-Agent 覆盖改写页面请求的 User-Agent 与 Accept-Language
+func applyUserAgent(req Request, ua emulation.SetUserAgentOverrideArgs) Request {
+	if ua.UserAgent == "" {
+		return req
+	}
+	headers := make(map[string]string, len(req.Headers)+2)
+	for k, v := range req.Headers {
+		if strings.EqualFold(k, "User-Agent") || (ua.AcceptLanguage != nil && strings.EqualFold(k, "Accept-Language")) {
+			continue
+		}
+		headers[k] = v
+	}
+	headers["User-Agent"] = ua.UserAgent
+	if ua.AcceptLanguage != nil {
+		headers["Accept-Language"] = *ua.AcceptLanguage
+	}
+	req.Headers = headers
+	return req
+}
+
+// applyExtraHeaders 像浏览器一样把 Network.setExtraHTTPHeaders 设置的请求头附加到页面请求，同名时覆盖
+func applyExtraHeaders(req Request, extra map[string]string) Request {
+	if len(extra) == 0 {
+		return req
+	}
+	headers := make(map[string]string, len(req.Headers)+len(extra))
+	for k, v := range req.Headers {
+		headers[k] = v
+	}
+	for name, value := range extra {
+		for k := range headers {
+			if strings.EqualFold(k, name) {
+				delete(headers, k)
+			}
+		}
+		headers[name] = value
+	}
+	req.Headers = headers
+	return req
+}
+
+// headerMap 将头部列表转换为映射
+func headerMap(entries []fetch.HeaderEntry) map[string]string {
+	out := make(map[string]string, len(entries))
+	for _, e := range entries {
+		out[e.Name] = e.Value
+	}
+	return out
+}
+
+// patternMatches 判断拦截模式是否覆盖请求的指定阶段
+func patternMatches(p fetch.RequestPattern, req Request, stage fetch.RequestStage) bool {
+	ps := p.RequestStage
+	if ps == "" {
+		ps = fetch.RequestStageRequest
+	}
+	if ps != stage {
+		return false
+	}
+	if p.ResourceType != nil && *p.ResourceType != req.ResourceType {
+		return false
+	}
+	return p.URLPattern == nil || wildcardMatch(*p.URLPattern, req.URL)
+}
+
+// wildcardMatch 按 CDP 规则匹配 URL 模式：'*' 匹配任意长度，'?' 匹配单个字符，反斜杠转义
+func wildcardMatch(pattern, s string) bool {
+	if pattern == "" {
+		return s == ""
+	}
+	if pattern[0] == '*' {
+		rest := strings.TrimLeft(pattern, "*")
+		for i := 0; i <= len(s); i++ {
+			if wildcardMatch(rest, s[i:]) {
+				return true
+			}
+		}
+		return false
+	}
+	if s == "" {
+		return false
+	}
+	switch pattern[0] {
+	case '?':
+		return wildcardMatch(pattern[1:], s[1:])
+	case '\\':
+		if len(pattern) > 1 {
+			return pattern[1] == s[0] && wildcardMatch(pattern[2:], s[1:])
+		}
+	}
+	return pattern[0] == s[0] && wildcardMatch(pattern[1:], s[1:])
+}
@@ -0,0 +1,541 @@
+package cdpfake
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"unicode/utf8"
+
+	"github.com/gorilla/websocket"
+	"github.com/mafredri/cdp/devtool"
+	"github.com/mafredri/cdp/protocol/emulation"
+	"github.com/mafredri/cdp/protocol/fetch"
+	"github.com/mafredri/cdp/protocol/io"
+	"github.com/mafredri/cdp/protocol/network"
+	"github.com/mafredri/cdp/protocol/page"
+)
+
+// Call 客户端发来的一次协议调用
+type Call struct {
+	Method string
+	Params json.RawMessage
+}
+
+// Target 假浏览器中的一个调试目标
+type Target struct {
+	b    *Browser
+	id   string
+	kind devtool.Type
+	url  string
+
+	mu         sync.Mutex
+	conns      []*conn
+	calls      []Call
+	pending    map[fetch.RequestID]*pending
+	streams    map[io.StreamHandle][]byte
+	bodies     map[network.RequestID][]byte
+	scripts    map[page.ScriptIdentifier]string
+	userAgent  emulation.SetUserAgentOverrideArgs
+	geo        *emulation.SetGeolocationOverrideArgs
+	timezone   string
+	locale     string
+	extra      map[string]string
+	noCache    bool
+	nextReq    int
+	nextScript int
+	nextStream int
+	navigated  chan *Result
+	location   string // 最近一次 Page.navigate 的地址
+}
+
+// conn 一条目标调试连接
+type conn struct {
+	ws       *websocket.Conn
+	wmu      sync.Mutex
+	fetchOn  bool
+	authOn   bool // Fetch.enable 时设置了 handleAuthRequests
+	netOn    bool
+	patterns []fetch.RequestPattern
+}
+
+// rpcMessage 协议请求
+type rpcMessage struct {
+	ID     uint64          `json:"id"`
+	Method string          `json:"method"`
+	Params json.RawMessage `json:"params"`
+}
+
+// rpcError 协议错误
+type rpcError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+// screenshotPNG Page.captureScreenshot 返回的 1x1 透明 PNG
+var screenshotPNG = []byte{
+	0x89, 0x50, 0x4e, 0x47, 0x0d, 0x0a, 0x1a, 0x0a, 0x00, 0x00, 0x00, 0x0d, 0x49, 0x48, 0x44, 0x52,
+	0x00, 0x00, 0x00, 0x01, 0x00, 0x00, 0x00, 0x01, 0x08, 0x06, 0x00, 0x00, 0x00, 0x1f, 0x15, 0xc4,
+	0x89, 0x00, 0x00, 0x00, 0x12, 0x49, 0x44, 0x41, 0x54, 0x78, 0x9c, 0x00, 0x05, 0x00, 0xfa, 0xff,
+	0x02, 0x00, 0x00, 0x00, 0x00, 0x03, 0x00, 0x00, 0x0f, 0x00, 0x03, 0x42, 0xa7, 0xf5, 0x0e, 0x00,
+	0x00, 0x00, 0x00, 0x49, 0x45, 0x4e, 0x44, 0xae, 0x42, 0x60, 0x82,
+}
+
+// newTarget 创建目标
+func newTarget(b *Browser, id string, kind devtool.Type, url string) *Target {
+	return &Target{
+		b:         b,
+		id:        id,
+		kind:      kind,
+		url:       url,
+		pending:   make(map[fetch.RequestID]*pending),
+		streams:   make(map[io.StreamHandle][]byte),
+		bodies:    make(map[network.RequestID][]byte),
+		scripts:   make(map[page.ScriptIdentifier]string),
+		navigated: make(chan *Result, 16),
+	}
+}
+
+// ID 返回目标 ID
+func (t *Target) ID() string { return t.id }
+
+// URL 返回目标 URL
+func (t *Target) URL() string { return t.url }
+
+// Calls 返回客户端迄今发来的全部协议调用
+func (t *Target) Calls() []Call {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return append([]Call(nil), t.calls...)
+}
+
+// CallCount 返回指定方法被调用的次数
+func (t *Target) CallCount(method string) int {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	n := 0
+	for _, c := range t.calls {
+		if c.Method == method {
+			n++
+		}
+	}
+	return n
+}
+
+// navigate 与浏览器一致，在导航命令应答之后异步发出文档请求
+func (t *Target) navigate(url string) {
+	res, err := t.Fetch(context.Background(), Request{URL: url, ResourceType: network.ResourceTypeDocument})
+	if err != nil {
+		return
+	}
+	select {
+	case t.navigated <- res:
+	default:
+	}
+}
+
+// Navigated 返回 Page.navigate / Page.reload 发起的文档请求结果，每次导航推送一次
+func (t *Target) Navigated() <-chan *Result { return t.navigated }
+
+// Connected 返回当前调试连接数
+func (t *Target) Connected() int {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return len(t.conns)
+}
+
+// FetchEnabled 返回是否有连接启用了 Fetch 拦截
+func (t *Target) FetchEnabled() bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for _, c := range t.conns {
+		if c.fetchOn {
+			return true
+		}
+	}
+	return false
+}
+
+// Scripts 返回通过 Page.addScriptToEvaluateOnNewDocument 注入且未移除的脚本
+func (t *Target) Scripts() []string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	out := make([]string, 0, len(t.scripts))
+	for _, s := range t.scripts {
+		out = append(out, s)
+	}
+	return out
+}
+
+// UserAgent 返回通过 Emulation.setUserAgentOverride 设置的覆盖，未设置时 UserAgent 为空
+func (t *Target) UserAgent() emulation.SetUserAgentOverrideArgs {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.userAgent
+}
+
+// Geolocation 返回通过 Emulation.setGeolocationOverride 设置的位置，未设置或已清除时为 nil
+func (t *Target) Geolocation() *emulation.SetGeolocationOverrideArgs {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.geo
+}
+
+// Timezone 返回通过 Emulation.setTimezoneOverride 设置的时区，未设置或已恢复时为空
+func (t *Target) Timezone() string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.timezone
+}
+
+// Locale 返回通过 Emulation.setLocaleOverride 设置的区域，未设置或已恢复时为空
+func (t *Target) Locale() string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.locale
+}
+
+// ExtraHeaders 返回通过 Network.setExtraHTTPHeaders 设置的请求头
+func (t *Target) ExtraHeaders() map[string]string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	out := make(map[string]string, len(t.extra))
+	for k, v := range t.extra {
+		out[k] = v
+	}
+	return out
+}
+
+// CacheDisabled 返回是否通过 Network.setCacheDisabled 禁用了缓存
+func (t *Target) CacheDisabled() bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.noCache
+}
+
+// Emit 向目标的所有连接推送协议事件，用于模拟 Network.webSocketFrameReceived 等未内置的事件
+func (t *Target) Emit(method string, params any) error {
+	raw, err := json.Marshal(params)
+	if err != nil {
+		return err
+	}
+	t.mu.Lock()
+	conns := append([]*conn(nil), t.conns...)
+	t.mu.Unlock()
+	for _, c := range conns {
+		_ = c.write(rpcMessage{Method: method, Params: raw})
+	}
+	return nil
+}
+
+// emitNetwork 向启用了 Network 域的连接推送事件
+func (t *Target) emitNetwork(method string, params any) {
+	raw, err := json.Marshal(params)
+	if err != nil {
+		return
+	}
+	t.mu.Lock()
+	var conns []*conn
+	for _, c := range t.conns {
+		if c.netOn {
+			conns = append(conns, c)
+		}
+	}
+	t.mu.Unlock()
+	for _, c := range conns {
+		_ = c.write(rpcMessage{Method: method, Params: raw})
+	}
+}
+
+// Disconnect 断开目标的所有调试连接，模拟渲染进程崩溃或连接中断；目标仍保留在目标列表中
+func (t *Target) Disconnect() {
+	t.mu.Lock()
+	conns := t.conns
+	t.conns = nil
+	t.mu.Unlock()
+	for _, c := range conns {
+		_ = c.ws.Close()
+	}
+}
+
+// serve 处理一条调试连接直至断开
+func (t *Target) serve(ws *websocket.Conn) {
+	c := &conn{ws: ws}
+	t.mu.Lock()
+	t.conns = append(t.conns, c)
+	t.mu.Unlock()
+
+	defer t.release(c)
+	for {
+		var msg rpcMessage
+		if err := ws.ReadJSON(&msg); err != nil {
+			return
+		}
+		result, err := t.dispatch(c, msg)
+		reply := map[string]any{"id": msg.ID}
+		if err != nil {
+			reply["error"] = err
+		} else {
+			if result == nil {
+				result = struct{}{}
+			}
+			reply["result"] = result
+		}
+		if werr := c.write(reply); werr != nil {
+			return
+		}
+	}
+}
+
+// release 移除断开的连接；浏览器在调试会话断开后会放行其暂停的请求
+func (t *Target) release(c *conn) {
+	_ = c.ws.Close()
+	t.mu.Lock()
+	for i, cc := range t.conns {
+		if cc == c {
+			t.conns = append(t.conns[:i], t.conns[i+1:]...)
+			break
+		}
+	}
+	var orphans []*pending
+	for _, p := range t.pending {
+		if p.conn == c {
+			orphans = append(orphans, p)
+		}
+	}
+	t.mu.Unlock()
+	for _, p := range orphans {
+		p.resolve(decision{method: methodDetached})
+	}
+}
+
+// write 串行写出一条消息
+func (c *conn) write(v any) error {
+	c.wmu.Lock()
+	defer c.wmu.Unlock()
+	return c.ws.WriteJSON(v)
+}
+
+// dispatch 执行一次协议调用
+func (t *Target) dispatch(c *conn, msg rpcMessage) (any, *rpcError) {
+	t.mu.Lock()
+	t.calls = append(t.calls, Call{Method: msg.Method, Params: msg.Params})
+	t.mu.Unlock()
+
+	switch msg.Method {
+	case "Page.enable", "Runtime.enable":
+		return nil, nil
+	case "Network.enable", "Network.disable":
+		t.mu.Lock()
+		c.netOn = msg.Method == "Network.enable"
+		t.mu.Unlock()
+		return nil, nil
+	case "Network.getResponseBody":
+		var args network.GetResponseBodyArgs
+		if err := decodeParams(msg.Params, &args); err != nil {
+			return nil, err
+		}
+		t.mu.Lock()
+		body, ok := t.bodies[args.RequestID]
+		t.mu.Unlock()
+		if !ok {
+			return nil, &rpcError{Code: -32000, Message: "No resource with given identifier found"}
+		}
+		if utf8.Valid(body) {
+			return network.GetResponseBodyReply{Body: string(body)}, nil
+		}
+		return network.GetResponseBodyReply{Body: base64.StdEncoding.EncodeToString(body), Base64Encoded: true}, nil
+	case "Fetch.enable":
+		var args fetch.EnableArgs
+		if err := decodeParams(msg.Params, &args); err != nil {
+			return nil, err
+		}
+		patterns := args.Patterns
+		if len(patterns) == 0 {
+			patterns = []fetch.RequestPattern{{}}
+		}
+		t.mu.Lock()
+		c.fetchOn, c.patterns = true, patterns
+		c.authOn = args.HandleAuthRequests != nil && *args.HandleAuthRequests
+		t.mu.Unlock()
+		return nil, nil
+	case "Fetch.disable":
+		t.mu.Lock()
+		c.fetchOn, c.authOn, c.patterns = false, false, nil
+		t.mu.Unlock()
+		return nil, nil
+	case "Fetch.continueRequest", "Fetch.continueResponse", "Fetch.fulfillRequest", "Fetch.failRequest":
+		return nil, t.decide(msg)
+	case "Fetch.continueWithAuth":
+		return nil, t.decide(msg)
+	case "Fetch.getResponseBody":
+		return t.getResponseBody(msg.Params)
+	case "Fetch.takeResponseBodyAsStream":
+		return t.takeResponseBody(msg.Params)
+	case "IO.read":
+		return t.readStream(msg.Params)
+	case "IO.close":
+		var args struct {
+			Handle io.StreamHandle `json:"handle"`
+		}
+		if err := decodeParams(msg.Params, &args); err != nil {
+			return nil, err
+		}
+		t.mu.Lock()
+		delete(t.streams, args.Handle)
+		t.mu.Unlock()
+		return nil, nil
+	case "Page.navigate":
+		var args page.NavigateArgs
+		if err := decodeParams(msg.Params, &args); err != nil {
+			return nil, err
+		}
+		t.mu.Lock()
+		t.location = args.URL
+		t.mu.Unlock()
+		go t.navigate(args.URL)
+		return page.NavigateReply{FrameID: page.FrameID(t.id)}, nil
+	case "Page.reload":
+		t.mu.Lock()
+		location := t.location
+		t.mu.Unlock()
+		if location == "" {
+			location = t.url
+		}
+		go t.navigate(location)
+		return nil, nil
+	case "Page.captureScreenshot":
+		return page.CaptureScreenshotReply{Data: screenshotPNG}, nil
+	case "Page.addScriptToEvaluateOnNewDocument":
+		var args page.AddScriptToEvaluateOnNewDocumentArgs
+		if err := decodeParams(msg.Params, &args); err != nil {
+			return nil, err
+		}
+		t.mu.Lock()
+		t.nextScript++
+		id := page.ScriptIdentifier(fmt.Sprint(t.nextScript))
+		t.scripts[id] = args.Source
+		t.mu.Unlock()
+		return page.AddScriptToEvaluateOnNewDocumentReply{Identifier: id}, nil
+	case "Page.removeScriptToEvaluateOnNewDocument":
+		var args page.RemoveScriptToEvaluateOnNewDocumentArgs
+		if err := decodeParams(msg.Params, &args); err != nil {
+			return nil, err
+		}
+		t.mu.Lock()
+		delete(t.scripts, args.Identifier)
+		t.mu.Unlock()
+		return nil, nil
+	case "Network.setExtraHTTPHeaders":
+		var args struct {
+			Headers map[string]string `json:"headers"`
+		}
+		if err := decodeParams(msg.Params, &args); err != nil {
+			return nil, err
+		}
+		t.mu.Lock()
+		t.extra = args.Headers
+		t.mu.Unlock()
+		return nil, nil
+	case "Network.getCookies":
+		var args network.GetCookiesArgs
+		if err := decodeParams(msg.Params, &args); err != nil {
+			return nil, err
+		}
+		urls := args.URLs
+		if len(urls) == 0 {
+			urls = []string{t.url}
+		}
+		t.b.mu.Lock()
+		defer t.b.mu.Unlock()
+		return network.GetCookiesReply{Cookies: t.b.cookies.get(urls)}, nil
+	case "Network.setCookie":
+		var args network.SetCookieArgs
+		if err := decodeParams(msg.Params, &args); err != nil {
+			return nil, err
+		}
+		t.b.mu.Lock()
+		defer t.b.mu.Unlock()
+		if err := t.b.cookies.set(args); err != nil {
+			return nil, err
+		}
+		return map[string]bool{"success": true}, nil
+	case "Network.deleteCookies":
+		var args network.DeleteCookiesArgs
+		if err := decodeParams(msg.Params, &args); err != nil {
+			return nil, err
+		}
+		t.b.mu.Lock()
+		defer t.b.mu.Unlock()
+		t.b.cookies.delete(args)
+		return nil, nil
+	case "Network.setCacheDisabled":
+		var args network.SetCacheDisabledArgs
+		if err := decodeParams(msg.Params, &args); err != nil {
+			return nil, err
+		}
+		t.mu.Lock()
+		t.noCache = args.CacheDisabled
+		t.mu.Unlock()
+		return nil, nil
+	case "Emulation.setUserAgentOverride":
+		var args emulation.SetUserAgentOverrideArgs
+		if err := decodeParams(msg.Params, &args); err != nil {
+			return nil, err
+		}
+		t.mu.Lock()
+		t.userAgent = args
+		t.mu.Unlock()
+		return nil, nil
+	case "Emulation.setGeolocationOverride":
+		var args emulation.SetGeolocationOverrideArgs
+		if err := decodeParams(msg.Params, &args); err != nil {
+			return nil, err
+		}
+		t.mu.Lock()
+		t.geo = &args
+		t.mu.Unlock()
+		return nil, nil
+	case "Emulation.clearGeolocationOverride":
+		t.mu.Lock()
+		t.geo = nil
+		t.mu.Unlock()
+		return nil, nil
+	case "Emulation.setTimezoneOverride":
+		var args emulation.SetTimezoneOverrideArgs
+		if err := decodeParams(msg.Params, &args); err != nil {
+			return nil, err
+		}
+		t.mu.Lock()
+		t.timezone = args.TimezoneID
+		t.mu.Unlock()
+		return nil, nil
+	case "Emulation.setLocaleOverride":
+		var args emulation.SetLocaleOverrideArgs
+		if err := decodeParams(msg.Params, &args); err != nil {
+			return nil, err
+		}
+		t.mu.Lock()
+		t.locale = ""
+		if args.Locale != nil {
+			t.locale = *args.Locale
+		}
+		t.mu.Unlock()
+		return nil, nil
+	case "Runtime.evaluate":
+		return map[string]any{"result": map[string]string{"type": "undefined"}}, nil
+	}
+	return nil, &rpcError{Code: -32601, Message: fmt.Sprintf("'%s' wasn't found", msg.Method)}
+}
+
+// decodeParams 解析调用参数
+func decodeParams(raw json.RawMessage, v any) *rpcError {
+	if len(raw) == 0 {
+		return nil
+	}
+	if err := json.Unmarshal(raw, v); err != nil {
+		return &rpcError{Code: -32602, Message: "Invalid parameters: " + err.Error()}
+	}
+	return nil
+}
@@ -0,0 +1,123 @@
+package export
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"cdpnetool/internal/storage"
+	"cdpnetool/pkg/model"
+)
+
+// eventColumn 事件导出列定义
+type eventColumn struct {
+	header  string
+	extract func(r *storage.MatchedEventRecord, rules []model.RuleMatch) string
+}
+
+// eventColumns 可导出的事件列，键为前端传入的列标识
+var eventColumns = map[string]eventColumn{
+	"time": {"时间", func(r *storage.MatchedEventRecord, _ []model.RuleMatch) string {
+		return time.UnixMilli(r.Timestamp).Format("2006-01-02 15:04:05.000")
+	}},
+	"session":      {"会话", func(r *storage.MatchedEventRecord, _ []model.RuleMatch) string { return r.SessionID }},
+	"sessionLabel": {"会话名称", func(r *storage.MatchedEventRecord, _ []model.RuleMatch) string { return r.SessionLabel }},
+	"sessionMeta":  {"会话元数据", func(r *storage.MatchedEventRecord, _ []model.RuleMatch) string { return metaText(r.SessionMetaJSON) }},
+	"target":       {"目标", func(r *storage.MatchedEventRecord, _ []model.RuleMatch) string { return r.TargetID }},
+	"method":       {"方法", func(r *storage.MatchedEventRecord, _ []model.RuleMatch) string { return r.Method }},
+	"url":          {"URL", func(r *storage.MatchedEventRecord, _ []model.RuleMatch) string { return r.URL }},
+	"statusCode":   {"状态码", func(r *storage.MatchedEventRecord, _ []model.RuleMatch) string { return statusText(r.StatusCode) }},
+	"operation":    {"API 操作", func(r *storage.MatchedEventRecord, _ []model.RuleMatch) string { return r.APIOperation }},
+	"result":       {"处理结果", func(r *storage.MatchedEventRecord, _ []model.RuleMatch) string { return resultText(r.FinalResult) }},
+	"rules": {"匹配规则", func(_ *storage.MatchedEventRecord, rules []model.RuleMatch) string {
+		names := make([]string, 0, len(rules))
+		for _, m := range rules {
+			names = append(names, m.RuleName)
+		}
+		return strings.Join(names, "; ")
+	}},
+	"actions": {"执行行为", func(_ *storage.MatchedEventRecord, rules []model.RuleMatch) string {
+		var actions []string
+		for _, m := range rules {
+			actions = append(actions, m.Actions...)
+		}
+		return strings.Join(actions, ", ")
+	}},
+}
+
+// DefaultEventColumns 默认导出列顺序
+var DefaultEventColumns = []string{"time", "method", "url", "statusCode", "result", "rules", "actions"}
+
+// EventTable 将事件记录转换为表格，columns 为空时使用默认列
+func EventTable(records []storage.MatchedEventRecord, columns []string) (*Table, error) {
+	if len(columns) == 0 {
+		columns = DefaultEventColumns
+	}
+	t := &Table{Sheet: "事件记录", Rows: make([][]string, 0, len(records))}
+	cols := make([]eventColumn, 0, len(columns))
+	for _, key := range columns {
+		col, ok := eventColumns[key]
+		if !ok {
+			return nil, fmt.Errorf("未知的导出列: %s", key)
+		}
+		cols = append(cols, col)
+		t.Columns = append(t.Columns, col.header)
+	}
+
+	for i := range records {
+		r := &records[i]
+		var rules []model.RuleMatch
+		_ = json.Unmarshal([]byte(r.MatchedRulesJSON), &rules)
+		row := make([]string, len(cols))
+		for j, col := range cols {
+			row[j] = col.extract(r, rules)
+		}
+		t.Rows = append(t.Rows, row)
+	}
+	return t, nil
+}
+
+// statusText 状态码为 0（请求阶段）时留空
+func statusText(code int) string {
+	if code == 0 {
+		return ""
+	}
+	return strconv.Itoa(code)
+}
+
+// metaText 将会话元数据格式化为按键排序的 key=value 列表
+func metaText(raw string) string {
+	var meta map[string]string
+	if raw == "" || json.Unmarshal([]byte(raw), &meta) != nil {
+		return ""
+	}
+	keys := make([]string, 0, len(meta))
+	for k := range meta {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	pairs := make([]string, len(keys))
+	for i, k := range keys {
+		pairs[i] = k + "=" + meta[k]
+	}
+	return strings.Join(pairs, "; ")
+}
+
+// resultText 处理结果的中文描述
+func resultText(result string) string {
+	switch result {
+	case "blocked":
+		return "已阻止"
+	case "modified":
+		return "已修改"
+	case "passed":
+		return "已放行"
+	case "failed":
+		return "已失败"
+	default:
+		return result
+	}
+}
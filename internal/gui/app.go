@@ -2,14 +2,22 @@ package gui
 
 import (
 	"context"
+	"encoding/base64"
 	"encoding/json"
 	"fmt"
 	"os"
+	"os/user"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
 	"time"
 
 	"cdpnetool/internal/browser"
 	"cdpnetool/internal/config"
+	"cdpnetool/internal/export"
 	"cdpnetool/internal/logger"
+	"cdpnetool/internal/pcap"
 	"cdpnetool/internal/storage"
 	"cdpnetool/pkg/api"
 	"cdpnetool/pkg/model"
@@ -30,6 +38,9 @@ type App struct {
 	settingsRepo   *storage.SettingsRepo
 	configRepo     *storage.ConfigRepo
 	eventRepo      *storage.EventRepo
+	sessionRepo    *storage.SessionRepo
+	pins           pinTracker // 重点关注的接口及计数
+	bodies         bodyStore  // 推送到前端时被截断的事件的完整 Body
 	isDirty        bool
 }
 
@@ -63,7 +74,32 @@ func (a *App) Startup(ctx context.Context) {
 	a.settingsRepo = storage.NewSettingsRepo(db)
 	a.configRepo = storage.NewConfigRepo(db)
 	a.eventRepo = storage.NewEventRepo(db)
+	a.sessionRepo = storage.NewSessionRepo(db)
 	a.log.Debug("事件仓库初始化完成")
+	a.service.SetConfigResolver(a.configRepo.Resolve)
+	a.pins.set(a.settingsRepo.GetStringList(storage.SettingKeyPinnedEndpoints))
+
+	// 恢复上次记录的 JSON 响应结构指纹
+	if raw := a.settingsRepo.GetWithDefault(storage.SettingKeySchemaFingerprints, ""); raw != "" {
+		var shapes map[string]map[string]string
+		if err := json.Unmarshal([]byte(raw), &shapes); err != nil {
+			a.log.Warn("解析结构指纹失败", "error", err)
+		} else {
+			a.service.ImportSchemaFingerprints(shapes)
+		}
+	}
+	// 恢复上次记录的响应体哈希
+	if raw := a.settingsRepo.GetWithDefault(storage.SettingKeyContentHashes, ""); raw != "" {
+		var hashes map[string]string
+		if err := json.Unmarshal([]byte(raw), &hashes); err != nil {
+			a.log.Warn("解析响应体哈希失败", "error", err)
+		} else {
+			a.service.ImportContentHashes(hashes)
+		}
+	}
+
+	// 按设置定期自动整理数据库
+	go a.autoVacuumLoop()
 }
 
 // Shutdown 在应用关闭时由 Wails 框架调用，负责清理会话、浏览器和数据库资源。
@@ -83,6 +119,24 @@ func (a *App) Shutdown(ctx context.Context) {
 		}
 	}
 
+	// 保存 JSON 响应结构指纹，供下次启动比较
+	if a.settingsRepo != nil {
+		if shapes := a.service.ExportSchemaFingerprints(); len(shapes) > 0 {
+			if raw, err := json.Marshal(shapes); err == nil {
+				if err := a.settingsRepo.Set(storage.SettingKeySchemaFingerprints, string(raw)); err != nil {
+					a.log.Err(err, "保存结构指纹失败")
+				}
+			}
+		}
+		if hashes := a.service.ExportContentHashes(); len(hashes) > 0 {
+			if raw, err := json.Marshal(hashes); err == nil {
+				if err := a.settingsRepo.Set(storage.SettingKeyContentHashes, string(raw)); err != nil {
+					a.log.Err(err, "保存响应体哈希失败")
+				}
+			}
+		}
+	}
+
 	// 停止事件异步写入
 	if a.eventRepo != nil {
 		a.eventRepo.Stop()
@@ -112,6 +166,86 @@ func (a *App) StartSession(devToolsURL string) SessionResult {
 	cfg := model.SessionConfig{
 		DevToolsURL: devToolsURL,
 	}
+	if a.settingsRepo != nil {
+		cfg.SchemaDriftDetection = a.settingsRepo.GetWithDefault(storage.SettingKeySchemaDriftDetection, "") == "true"
+		cfg.ContentChangeDetection = a.settingsRepo.GetWithDefault(storage.SettingKeyContentChangeDetection, "") == "true"
+		cfg.DuplicateDetection = a.settingsRepo.GetWithDefault(storage.SettingKeyDuplicateDetection, "") == "true"
+		if raw := a.settingsRepo.GetWithDefault(storage.SettingKeyDuplicateWindowMS, ""); raw != "" {
+			if n, err := strconv.Atoi(raw); err == nil {
+				cfg.DuplicateWindowMS = n
+			} else {
+				a.log.Warn("解析重复请求判定窗口失败", "value", raw)
+			}
+		}
+		cfg.AllowedHosts = a.settingsRepo.GetStringList(storage.SettingKeyAllowedHosts)
+		cfg.DeniedHosts = a.settingsRepo.GetStringList(storage.SettingKeyDeniedHosts)
+		if creds, err := a.loadCredentials(); err != nil {
+			a.log.Warn("读取源认证信息失败", "error", err)
+		} else {
+			cfg.Credentials = creds
+		}
+		if proxyAuth, err := a.loadProxyAuth(); err != nil {
+			a.log.Warn("读取上游代理认证信息失败", "error", err)
+		} else {
+			cfg.ProxyAuth = proxyAuth
+		}
+		if secrets, err := a.loadSecrets(); err != nil {
+			a.log.Warn("读取命名密钥失败", "error", err)
+		} else {
+			cfg.Secrets = secrets
+		}
+		if raw := a.settingsRepo.GetWithDefault(storage.SettingKeyHeaderPolicy, ""); raw != "" {
+			if err := json.Unmarshal([]byte(raw), &cfg.HeaderPolicy); err != nil {
+				a.log.Warn("解析响应头策略失败", "error", err)
+			}
+		}
+		if raw := a.settingsRepo.GetWithDefault(storage.SettingKeyUserAgentOverride, ""); raw != "" {
+			if err := json.Unmarshal([]byte(raw), &cfg.UserAgent); err != nil {
+				a.log.Warn("解析 User-Agent 覆盖失败", "error", err)
+			}
+		}
+		if raw := a.settingsRepo.GetWithDefault(storage.SettingKeyGeolocation, ""); raw != "" {
+			if err := json.Unmarshal([]byte(raw), &cfg.Geolocation); err != nil {
+				a.log.Warn("解析地理位置覆盖失败", "error", err)
+			}
+		}
+		if raw := a.settingsRepo.GetWithDefault(storage.SettingKeyLocaleOverride, ""); raw != "" {
+			if err := json.Unmarshal([]byte(raw), &cfg.Locale); err != nil {
+				a.log.Warn("解析时区与区域覆盖失败", "error", err)
+			}
+		}
+		if raw := a.settingsRepo.GetWithDefault(storage.SettingKeyExtraHeaders, ""); raw != "" {
+			if err := json.Unmarshal([]byte(raw), &cfg.ExtraHeaders); err != nil {
+				a.log.Warn("解析注入请求头失败", "error", err)
+			}
+		}
+		cfg.CaptureOnly = a.settingsRepo.GetWithDefault(storage.SettingKeyCaptureOnly, "") == "true"
+		cfg.FullCapture = a.settingsRepo.GetWithDefault(storage.SettingKeyFullCapture, "") == "true"
+		cfg.ObserveOnly = a.settingsRepo.GetWithDefault(storage.SettingKeyObserveOnly, "") == "true"
+		cfg.CaptureTiming = a.settingsRepo.GetWithDefault(storage.SettingKeyCaptureTiming, "") == "true"
+		cfg.ShareRuleCache = a.settingsRepo.GetWithDefault(storage.SettingKeyShareRuleCache, "") == "true"
+		cfg.InterceptStage = model.InterceptStage(a.settingsRepo.GetWithDefault(storage.SettingKeyInterceptStage, ""))
+		cfg.InterceptResourceTypes = a.settingsRepo.GetStringList(storage.SettingKeyInterceptResourceTypes)
+		cfg.BlockResourceTypes = a.settingsRepo.GetStringList(storage.SettingKeyBlockResourceTypes)
+		cfg.PinnedEndpoints = a.pins.patterns()
+		cfg.InterceptWorkers = a.settingsRepo.GetWithDefault(storage.SettingKeyInterceptWorkers, "") == "true"
+		cfg.WatchdogRecover = a.settingsRepo.GetWithDefault(storage.SettingKeyWatchdogRecover, "") == "true"
+		cfg.CacheBypass = a.settingsRepo.GetWithDefault(storage.SettingKeyCacheBypass, "") == "true"
+		if raw := a.settingsRepo.GetWithDefault(storage.SettingKeyHostConcurrency, ""); raw != "" {
+			if n, err := strconv.Atoi(raw); err == nil {
+				cfg.HostConcurrency = n
+			} else {
+				a.log.Warn("解析主机并发上限失败", "value", raw)
+			}
+		}
+		if raw := a.settingsRepo.GetWithDefault(storage.SettingKeyLogShippers, ""); raw != "" {
+			if err := json.Unmarshal([]byte(raw), &cfg.Shippers); err != nil {
+				a.log.Warn("解析事件推送配置失败", "error", err)
+			}
+		}
+		cfg.RequireSignedConfig = a.settingsRepo.GetWithDefault(storage.SettingKeyRequireSignedConfig, "") == "true"
+		cfg.ConfigVerifyKey = a.settingsRepo.GetWithDefault(storage.SettingKeyConfigVerifyKey, "")
+	}
 	sid, err := a.service.StartSession(cfg)
 	if err != nil {
 		a.log.Err(err, "启动会话失败")
@@ -119,8 +253,10 @@ func (a *App) StartSession(devToolsURL string) SessionResult {
 	}
 
 	a.currentSession = sid
+	a.recordSession(sid)
 	// 启动事件订阅
 	go a.subscribeEvents(sid)
+	go a.subscribePending(sid)
 
 	a.log.Info("会话启动成功", "sessionID", sid)
 	return SessionResult{SessionID: string(sid), Success: true}
@@ -147,6 +283,23 @@ func (a *App) GetCurrentSession() string {
 	return string(a.currentSession)
 }
 
+// SessionInfoResult 表示会话信息查询结果。
+type SessionInfoResult struct {
+	Info    model.SessionInfo `json:"info"`
+	Success bool              `json:"success"`
+	Error   string            `json:"error,omitempty"`
+}
+
+// GetSessionInfo 获取会话信息，包括浏览器版本与特性支持情况。
+func (a *App) GetSessionInfo(sessionID string) SessionInfoResult {
+	info, err := a.service.GetSessionInfo(model.SessionID(sessionID))
+	if err != nil {
+		a.log.Err(err, "获取会话信息失败", "sessionID", sessionID)
+		return SessionInfoResult{Success: false, Error: err.Error()}
+	}
+	return SessionInfoResult{Info: info, Success: true}
+}
+
 // TargetListResult 表示返回给前端的目标列表结果。
 type TargetListResult struct {
 	Targets []model.TargetInfo `json:"targets"`
@@ -182,6 +335,45 @@ func (a *App) AttachTarget(sessionID, targetID string) OperationResult {
 	return OperationResult{Success: true}
 }
 
+// OpenTargetResult 表示新建标签页的结果。
+type OpenTargetResult struct {
+	TargetID string `json:"targetId"`
+	Success  bool   `json:"success"`
+	Error    string `json:"error,omitempty"`
+}
+
+// OpenTarget 新建标签页并在拦截生效后导航到指定 URL，保证页面的首个请求也被拦截。
+func (a *App) OpenTarget(sessionID, url string) OpenTargetResult {
+	target, err := a.service.OpenTarget(model.SessionID(sessionID), url)
+	if err != nil {
+		a.log.Err(err, "打开新标签页失败", "sessionID", sessionID, "url", url)
+		return OpenTargetResult{TargetID: string(target), Success: false, Error: err.Error()}
+	}
+
+	a.log.Debug("已打开并拦截新标签页", "targetID", string(target), "url", url)
+	return OpenTargetResult{TargetID: string(target), Success: true}
+}
+
+// Navigate 让会话中已附加的页面导航到指定 URL，targetID 为空时使用任一已附加的页面。
+func (a *App) Navigate(sessionID, targetID, url string) OperationResult {
+	err := a.service.Navigate(model.SessionID(sessionID), model.TargetID(targetID), url)
+	if err != nil {
+		a.log.Err(err, "页面导航失败", "sessionID", sessionID, "targetID", targetID, "url", url)
+		return OperationResult{Success: false, Error: err.Error()}
+	}
+	return OperationResult{Success: true}
+}
+
+// Reload 重新加载会话中已附加的页面，ignoreCache 为 true 时跳过缓存，便于修改规则后立即验证。
+func (a *App) Reload(sessionID, targetID string, ignoreCache bool) OperationResult {
+	err := a.service.Reload(model.SessionID(sessionID), model.TargetID(targetID), ignoreCache)
+	if err != nil {
+		a.log.Err(err, "重新加载页面失败", "sessionID", sessionID, "targetID", targetID)
+		return OperationResult{Success: false, Error: err.Error()}
+	}
+	return OperationResult{Success: true}
+}
+
 // DetachTarget 从会话中移除指定页面目标。
 func (a *App) DetachTarget(sessionID, targetID string) OperationResult {
 	err := a.service.DetachTarget(model.SessionID(sessionID), model.TargetID(targetID))
@@ -194,218 +386,980 @@ func (a *App) DetachTarget(sessionID, targetID string) OperationResult {
 	return OperationResult{Success: true}
 }
 
-// SetDirty 供前端更新未保存状态
-func (a *App) SetDirty(dirty bool) {
-	a.isDirty = dirty
+// CookieListResult 表示 Cookie 列表结果。
+type CookieListResult struct {
+	Cookies []model.Cookie `json:"cookies"`
+	Success bool           `json:"success"`
+	Error   string         `json:"error,omitempty"`
 }
 
-// BeforeClose 在窗口关闭前调用，如果有未保存更改则弹出确认框
-func (a *App) BeforeClose(ctx context.Context) bool {
-	if !a.isDirty {
-		return false
-	}
+// CookieDeleteResult 表示删除 Cookie 的结果。
+type CookieDeleteResult struct {
+	Deleted int    `json:"deleted"`
+	Success bool   `json:"success"`
+	Error   string `json:"error,omitempty"`
+}
 
-	result, err := runtime.MessageDialog(ctx, runtime.MessageDialogOptions{
-		Type:          runtime.QuestionDialog,
-		Title:         "提醒",
-		Message:       "当前有未保存的规则更改，确定要退出吗？",
-		DefaultButton: "否",
-		Buttons:       []string{"是", "否"},
-	})
+// ProxyResult 启动内置转发代理的结果
+type ProxyResult struct {
+	Addr    string `json:"addr"`
+	Success bool   `json:"success"`
+	Error   string `json:"error,omitempty"`
+}
 
+// StartProxy 为会话启动内置转发代理，addr 为空时使用上次的监听地址；启动成功后记住该地址。
+func (a *App) StartProxy(sessionID, addr string) ProxyResult {
+	if addr == "" {
+		addr = a.settingsRepo.GetWithDefault(storage.SettingKeyProxyAddr, "")
+	}
+	listen, err := a.service.StartProxy(model.SessionID(sessionID), addr)
 	if err != nil {
-		a.log.Warn("关闭确认对话框出错", "error", err)
-		return true
+		a.log.Err(err, "启动内置转发代理失败", "sessionID", sessionID)
+		return ProxyResult{Success: false, Error: err.Error()}
 	}
-
-	// 用户选"是"(要退出) -> 允许关闭(返回false)
-	// 用户选"否"(不退出) -> 阻止关闭(返回true)
-	a.log.Debug("用户选择", "result", result)
-	return result == "否"
+	if addr != "" {
+		if err := a.settingsRepo.Set(storage.SettingKeyProxyAddr, addr); err != nil {
+			a.log.Warn("保存代理监听地址失败", "error", err)
+		}
+	}
+	return ProxyResult{Addr: listen, Success: true}
 }
 
-// ExportConfig 弹出原生保存对话框导出配置
-func (a *App) ExportConfig(name, rulesJSON string) OperationResult {
-	path, err := runtime.SaveFileDialog(a.ctx, runtime.SaveDialogOptions{
-		DefaultFilename: name + ".json",
-		Title:           "导出配置",
-		Filters: []runtime.FileFilter{
-			{DisplayName: "JSON Files (*.json)", Pattern: "*.json"},
-		},
-	})
-
-	if err != nil {
+// StopProxy 停止会话的内置转发代理。
+func (a *App) StopProxy(sessionID string) OperationResult {
+	if err := a.service.StopProxy(model.SessionID(sessionID)); err != nil {
+		a.log.Err(err, "停止内置转发代理失败", "sessionID", sessionID)
 		return OperationResult{Success: false, Error: err.Error()}
 	}
+	return OperationResult{Success: true}
+}
 
-	if path == "" {
-		return OperationResult{Success: true} // 用户取消
-	}
-
-	err = os.WriteFile(path, []byte(rulesJSON), 0644)
+// GetCookies 获取目标中适用于指定 URL 的 Cookie，urls 为空时返回当前页面的 Cookie，targetID 为空时使用任一已附加目标。
+func (a *App) GetCookies(sessionID, targetID string, urls []string) CookieListResult {
+	cookies, err := a.service.GetCookies(model.SessionID(sessionID), model.TargetID(targetID), urls)
 	if err != nil {
-		return OperationResult{Success: false, Error: "文件写入失败: " + err.Error()}
+		a.log.Err(err, "获取 Cookie 失败", "sessionID", sessionID, "targetID", targetID)
+		return CookieListResult{Success: false, Error: err.Error()}
 	}
-
-	return OperationResult{Success: true}
+	return CookieListResult{Cookies: cookies, Success: true}
 }
 
-// EnableInterception 启用指定会话的网络拦截功能。
-func (a *App) EnableInterception(sessionID string) OperationResult {
-	// 检查是否已经附加了目标
-	targets, err := a.service.ListTargets(model.SessionID(sessionID))
-	hasAttached := false
-	if err == nil {
-		for _, t := range targets {
-			if t.IsCurrent {
-				hasAttached = true
-				break
-			}
-		}
+// SetCookie 设置 Cookie，cookieJSON 需包含 name、value 以及 url 或 domain。
+func (a *App) SetCookie(sessionID, targetID, cookieJSON string) OperationResult {
+	var c model.Cookie
+	if err := json.Unmarshal([]byte(cookieJSON), &c); err != nil {
+		return OperationResult{Success: false, Error: "JSON 解析失败: " + err.Error()}
 	}
-
-	if !hasAttached {
-		return OperationResult{Success: false, Error: "请先在 Targets 标签页附加至少一个目标"}
+	if err := a.service.SetCookie(model.SessionID(sessionID), model.TargetID(targetID), c); err != nil {
+		return OperationResult{Success: false, Error: err.Error()}
 	}
+	return OperationResult{Success: true}
+}
 
-	err = a.service.EnableInterception(model.SessionID(sessionID))
+// DeleteCookies 删除匹配条件的 Cookie，filterJSON 中 name 为空时删除 url（或当前页面）下的全部 Cookie。
+func (a *App) DeleteCookies(sessionID, targetID, filterJSON string) CookieDeleteResult {
+	var f model.CookieFilter
+	if err := json.Unmarshal([]byte(filterJSON), &f); err != nil {
+		return CookieDeleteResult{Success: false, Error: "JSON 解析失败: " + err.Error()}
+	}
+	n, err := a.service.DeleteCookies(model.SessionID(sessionID), model.TargetID(targetID), f)
 	if err != nil {
-		a.log.Err(err, "启用拦截失败", "sessionID", sessionID)
-		return OperationResult{Success: false, Error: err.Error()}
+		return CookieDeleteResult{Deleted: n, Success: false, Error: err.Error()}
 	}
+	return CookieDeleteResult{Deleted: n, Success: true}
+}
 
-	a.log.Info("已启用拦截", "sessionID", sessionID)
-	return OperationResult{Success: true}
+// ReplayResult 表示重放请求的结果。
+type ReplayResult struct {
+	Request  model.RequestInfo  `json:"request"`
+	Response model.ResponseInfo `json:"response"`
+	Success  bool               `json:"success"`
+	Error    string             `json:"error,omitempty"`
 }
 
-// DisableInterception 停用指定会话的网络拦截功能。
-func (a *App) DisableInterception(sessionID string) OperationResult {
-	err := a.service.DisableInterception(model.SessionID(sessionID))
+// ReplayRequest 重放历史中的匹配事件，editsJSON 可为空或包含 method、url、headers、removeHeaders、body 修改；
+// targetID 为空时使用任一已附加目标。
+func (a *App) ReplayRequest(sessionID string, eventID uint, targetID, editsJSON string) ReplayResult {
+	if a.eventRepo == nil {
+		return ReplayResult{Success: false, Error: "事件仓库未初始化"}
+	}
+	record, err := a.eventRepo.GetByID(eventID)
 	if err != nil {
-		a.log.Err(err, "停用拦截失败", "sessionID", sessionID)
-		return OperationResult{Success: false, Error: err.Error()}
+		a.log.Err(err, "获取事件失败", "eventID", eventID)
+		return ReplayResult{Success: false, Error: err.Error()}
 	}
-
-	a.log.Info("已停用拦截", "sessionID", sessionID)
-	return OperationResult{Success: true}
+	var req model.RequestInfo
+	if err := json.Unmarshal([]byte(record.RequestJSON), &req); err != nil {
+		return ReplayResult{Success: false, Error: "JSON 解析失败: " + err.Error()}
+	}
+	return a.replay(sessionID, targetID, req, editsJSON)
 }
 
-// LoadRules 从 JSON 字符串加载规则配置到指定会话。
-func (a *App) LoadRules(sessionID string, rulesJSON string) OperationResult {
-	var cfg rulespec.Config
-	if err := json.Unmarshal([]byte(rulesJSON), &cfg); err != nil {
-		a.log.Err(err, "JSON 解析失败")
-		return OperationResult{Success: false, Error: "JSON 解析失败: " + err.Error()}
+// ReplayRequestInfo 重放实时事件中的请求，requestJSON 为事件的 request 字段，editsJSON 同 ReplayRequest。
+func (a *App) ReplayRequestInfo(sessionID, targetID, requestJSON, editsJSON string) ReplayResult {
+	var req model.RequestInfo
+	if err := json.Unmarshal([]byte(requestJSON), &req); err != nil {
+		return ReplayResult{Success: false, Error: "JSON 解析失败: " + err.Error()}
 	}
+	return a.replay(sessionID, targetID, req, editsJSON)
+}
 
-	err := a.service.LoadRules(model.SessionID(sessionID), &cfg)
+// replay 应用修改后重放请求
+func (a *App) replay(sessionID, targetID string, req model.RequestInfo, editsJSON string) ReplayResult {
+	if editsJSON != "" {
+		var edits model.ReplayEdits
+		if err := json.Unmarshal([]byte(editsJSON), &edits); err != nil {
+			return ReplayResult{Success: false, Error: "JSON 解析失败: " + err.Error()}
+		}
+		req = edits.Apply(req)
+	}
+	resp, err := a.service.ReplayRequest(model.SessionID(sessionID), model.TargetID(targetID), req)
 	if err != nil {
-		a.log.Err(err, "加载规则失败", "sessionID", sessionID)
-		return OperationResult{Success: false, Error: err.Error()}
+		return ReplayResult{Request: req, Success: false, Error: err.Error()}
 	}
+	return ReplayResult{Request: req, Response: resp, Success: true}
+}
 
-	a.log.Info("规则加载成功", "sessionID", sessionID, "ruleCount", len(cfg.Rules))
-	return OperationResult{Success: true}
+// QuickMockResult 表示添加临时 Mock 的结果。
+type QuickMockResult struct {
+	Mock    model.QuickMock `json:"mock"`
+	Success bool            `json:"success"`
+	Error   string          `json:"error,omitempty"`
 }
 
-// StatsResult 表示规则统计结果。
-type StatsResult struct {
-	Stats   model.EngineStats `json:"stats"`
+// QuickMockListResult 表示临时 Mock 列表结果。
+type QuickMockListResult struct {
+	Mocks   []model.QuickMock `json:"mocks"`
 	Success bool              `json:"success"`
 	Error   string            `json:"error,omitempty"`
 }
 
-// GetRuleStats 获取指定会话的规则命中统计信息。
-func (a *App) GetRuleStats(sessionID string) StatsResult {
-	stats, err := a.service.GetRuleStats(model.SessionID(sessionID))
+// AddQuickMock 为目标中失败的请求添加临时 Mock，mockJSON 需包含 url，可选 method、statusCode、headers、body 与 ttlMs。
+func (a *App) AddQuickMock(sessionID, targetID, mockJSON string) QuickMockResult {
+	var mock model.QuickMock
+	if err := json.Unmarshal([]byte(mockJSON), &mock); err != nil {
+		return QuickMockResult{Success: false, Error: "JSON 解析失败: " + err.Error()}
+	}
+	mock.Target = model.TargetID(targetID)
+	added, err := a.service.AddQuickMock(model.SessionID(sessionID), mock)
 	if err != nil {
-		a.log.Err(err, "获取规则统计失败", "sessionID", sessionID)
-		return StatsResult{Success: false, Error: err.Error()}
+		return QuickMockResult{Success: false, Error: err.Error()}
 	}
-	return StatsResult{Stats: stats, Success: true}
+	return QuickMockResult{Mock: added, Success: true}
 }
 
-// subscribeEvents 订阅拦截事件并通过 Wails 事件系统推送到前端。
-func (a *App) subscribeEvents(sessionID model.SessionID) {
-	ch, err := a.service.SubscribeEvents(sessionID)
+// RemoveQuickMock 提前移除临时 Mock。
+func (a *App) RemoveQuickMock(sessionID, mockID string) OperationResult {
+	if err := a.service.RemoveQuickMock(model.SessionID(sessionID), mockID); err != nil {
+		return OperationResult{Success: false, Error: err.Error()}
+	}
+	return OperationResult{Success: true}
+}
+
+// ListQuickMocks 列出会话中生效的临时 Mock。
+func (a *App) ListQuickMocks(sessionID string) QuickMockListResult {
+	mocks, err := a.service.ListQuickMocks(model.SessionID(sessionID))
 	if err != nil {
-		a.log.Err(err, "订阅事件失败", "sessionID", sessionID)
-		return
+		return QuickMockListResult{Success: false, Error: err.Error()}
 	}
+	return QuickMockListResult{Mocks: mocks, Success: true}
+}
 
-	a.log.Debug("开始订阅事件", "sessionID", sessionID)
-	for evt := range ch {
-		// 通过 Wails 事件系统推送到前端
-		runtime.EventsEmit(a.ctx, "intercept-event", evt)
-		// 只有匹配的事件才写入数据库
-		if evt.IsMatched && evt.Matched != nil && a.eventRepo != nil {
-			evt.Matched.Session = sessionID
-			a.eventRepo.RecordMatched(evt.Matched)
-		}
+// VariablesResult 表示会话变量查询结果。
+type VariablesResult struct {
+	Variables map[string]string `json:"variables"`
+	Success   bool              `json:"success"`
+	Error     string            `json:"error,omitempty"`
+}
+
+// GetVariables 返回规则通过 captureVar 行为保存的会话变量。
+func (a *App) GetVariables(sessionID string) VariablesResult {
+	vars, err := a.service.Variables(model.SessionID(sessionID))
+	if err != nil {
+		return VariablesResult{Success: false, Error: err.Error()}
 	}
-	a.log.Debug("事件订阅已结束", "sessionID", sessionID)
+	return VariablesResult{Variables: vars, Success: true}
 }
 
-// LaunchBrowserResult 表示启动浏览器的结果。
-type LaunchBrowserResult struct {
-	DevToolsURL string `json:"devToolsUrl"`
-	Success     bool   `json:"success"`
-	Error       string `json:"error,omitempty"`
+// ClearVariables 清空会话变量。
+func (a *App) ClearVariables(sessionID string) OperationResult {
+	if err := a.service.ClearVariables(model.SessionID(sessionID)); err != nil {
+		return OperationResult{Success: false, Error: err.Error()}
+	}
+	return OperationResult{Success: true}
 }
 
-// LaunchBrowser 启动新的浏览器实例，如果已有浏览器运行则先关闭。
-func (a *App) LaunchBrowser(headless bool) LaunchBrowserResult {
-	a.log.Info("启动浏览器", "headless", headless)
+// SetHostPolicy 设置允许/禁止规则修改的主机列表，保存到设置并立即应用到当前会话。
+func (a *App) SetHostPolicy(allow, deny []string) OperationResult {
+	if err := a.settingsRepo.SetStringList(storage.SettingKeyAllowedHosts, allow); err != nil {
+		a.log.Err(err, "保存主机白名单失败")
+		return OperationResult{Success: false, Error: err.Error()}
+	}
+	if err := a.settingsRepo.SetStringList(storage.SettingKeyDeniedHosts, deny); err != nil {
+		a.log.Err(err, "保存主机黑名单失败")
+		return OperationResult{Success: false, Error: err.Error()}
+	}
 
-	// 如果已有浏览器运行，先关闭
-	if a.browser != nil {
-		if err := a.browser.Stop(2 * time.Second); err != nil {
-			a.log.Warn("关闭旧浏览器实例失败", "error", err)
+	if a.currentSession != "" {
+		if err := a.service.SetHostPolicy(a.currentSession, allow, deny); err != nil {
+			a.log.Err(err, "应用主机策略失败", "sessionID", a.currentSession)
+			return OperationResult{Success: false, Error: err.Error()}
 		}
-		a.browser = nil
 	}
 
-	opts := browser.Options{
-		Headless: headless,
+	a.log.Info("主机策略已更新", "allow", len(allow), "deny", len(deny))
+	return OperationResult{Success: true}
+}
+
+// SetBlockResourceTypes 设置一键屏蔽的资源类别（images/fonts/media/analytics），保存到设置并立即应用到当前会话。
+func (a *App) SetBlockResourceTypes(classes []string) OperationResult {
+	if err := a.settingsRepo.SetStringList(storage.SettingKeyBlockResourceTypes, classes); err != nil {
+		a.log.Err(err, "保存屏蔽资源类别失败")
+		return OperationResult{Success: false, Error: err.Error()}
 	}
 
-	b, err := browser.Start(opts)
-	if err != nil {
-		a.log.Err(err, "启动浏览器失败")
-		return LaunchBrowserResult{Success: false, Error: err.Error()}
+	if a.currentSession != "" {
+		if err := a.service.SetBlockResourceTypes(a.currentSession, classes); err != nil {
+			a.log.Err(err, "应用屏蔽资源类别失败", "sessionID", a.currentSession)
+			return OperationResult{Success: false, Error: err.Error()}
+		}
 	}
 
-	a.browser = b
-	a.log.Info("浏览器启动成功", "devToolsURL", b.DevToolsURL)
-	return LaunchBrowserResult{DevToolsURL: b.DevToolsURL, Success: true}
+	a.log.Info("屏蔽资源类别已更新", "classes", classes)
+	return OperationResult{Success: true}
 }
 
-// CloseBrowser 关闭已启动的浏览器实例。
-func (a *App) CloseBrowser() OperationResult {
-	if a.browser == nil {
-		return OperationResult{Success: false, Error: "没有正在运行的浏览器"}
+// SetHeaderPolicy 设置会话级响应头策略，保存到设置并立即应用到当前会话。
+func (a *App) SetHeaderPolicy(policyJSON string) OperationResult {
+	var policy model.HeaderPolicy
+	if err := json.Unmarshal([]byte(policyJSON), &policy); err != nil {
+		return OperationResult{Success: false, Error: "JSON 解析失败: " + err.Error()}
 	}
-
-	err := a.browser.Stop(2 * time.Second)
-	a.browser = nil
-	if err != nil {
-		a.log.Err(err, "关闭浏览器失败")
+	if err := a.settingsRepo.Set(storage.SettingKeyHeaderPolicy, policyJSON); err != nil {
+		a.log.Err(err, "保存响应头策略失败")
 		return OperationResult{Success: false, Error: err.Error()}
 	}
 
-	a.log.Info("浏览器已关闭")
+	if a.currentSession != "" {
+		if err := a.service.SetHeaderPolicy(a.currentSession, policy); err != nil {
+			a.log.Err(err, "应用响应头策略失败", "sessionID", a.currentSession)
+			return OperationResult{Success: false, Error: err.Error()}
+		}
+	}
+
+	a.log.Info("响应头策略已更新", "set", len(policy.Set), "remove", len(policy.Remove))
 	return OperationResult{Success: true}
 }
 
-// GetBrowserStatus 获取当前浏览器的运行状态。
-func (a *App) GetBrowserStatus() LaunchBrowserResult {
-	if a.browser == nil {
-		return LaunchBrowserResult{Success: false}
+// SetUserAgentOverride 设置 User-Agent 与客户端提示覆盖，保存到设置并立即应用到当前会话。
+func (a *App) SetUserAgentOverride(overrideJSON string) OperationResult {
+	var o model.UserAgentOverride
+	if err := json.Unmarshal([]byte(overrideJSON), &o); err != nil {
+		return OperationResult{Success: false, Error: "JSON 解析失败: " + err.Error()}
+	}
+	if err := a.settingsRepo.Set(storage.SettingKeyUserAgentOverride, overrideJSON); err != nil {
+		a.log.Err(err, "保存 User-Agent 覆盖失败")
+		return OperationResult{Success: false, Error: err.Error()}
 	}
-	return LaunchBrowserResult{DevToolsURL: a.browser.DevToolsURL, Success: true}
-}
 
-// SettingsResult 表示设置操作的结果。
+	if a.currentSession != "" {
+		if err := a.service.SetUserAgentOverride(a.currentSession, o); err != nil {
+			a.log.Err(err, "应用 User-Agent 覆盖失败", "sessionID", a.currentSession)
+			return OperationResult{Success: false, Error: err.Error()}
+		}
+	}
+
+	a.log.Info("User-Agent 覆盖已更新", "userAgent", o.UserAgent)
+	return OperationResult{Success: true}
+}
+
+// SetLocaleOverride 设置时区（如 Asia/Shanghai）与区域（如 zh_CN）覆盖，保存到设置并立即应用到当前会话；
+// 参数为空时恢复浏览器默认值。
+func (a *App) SetLocaleOverride(timezone, locale string) OperationResult {
+	o := model.LocaleOverride{Timezone: strings.TrimSpace(timezone), Locale: strings.TrimSpace(locale)}
+	raw, _ := json.Marshal(o)
+	if err := a.settingsRepo.Set(storage.SettingKeyLocaleOverride, string(raw)); err != nil {
+		a.log.Err(err, "保存时区与区域覆盖失败")
+		return OperationResult{Success: false, Error: err.Error()}
+	}
+
+	if a.currentSession != "" {
+		if err := a.service.SetLocaleOverride(a.currentSession, o); err != nil {
+			a.log.Err(err, "应用时区与区域覆盖失败", "sessionID", a.currentSession)
+			return OperationResult{Success: false, Error: err.Error()}
+		}
+	}
+
+	a.log.Info("时区与区域覆盖已更新", "timezone", o.Timezone, "locale", o.Locale)
+	return OperationResult{Success: true}
+}
+
+// SetExtraHeaders 设置轻量注入的请求头，保存到设置并立即应用到当前会话。
+func (a *App) SetExtraHeaders(headersJSON string) OperationResult {
+	var headers map[string]string
+	if err := json.Unmarshal([]byte(headersJSON), &headers); err != nil {
+		return OperationResult{Success: false, Error: "JSON 解析失败: " + err.Error()}
+	}
+	for name := range headers {
+		if strings.TrimSpace(name) == "" {
+			return OperationResult{Success: false, Error: "请求头名称不能为空"}
+		}
+	}
+	if err := a.settingsRepo.Set(storage.SettingKeyExtraHeaders, headersJSON); err != nil {
+		a.log.Err(err, "保存注入请求头失败")
+		return OperationResult{Success: false, Error: err.Error()}
+	}
+
+	if a.currentSession != "" {
+		if err := a.service.SetExtraHeaders(a.currentSession, headers); err != nil {
+			a.log.Err(err, "应用注入请求头失败", "sessionID", a.currentSession)
+			return OperationResult{Success: false, Error: err.Error()}
+		}
+	}
+
+	a.log.Info("轻量请求头注入已更新", "count", len(headers))
+	return OperationResult{Success: true}
+}
+
+// secretMask 返回给前端的敏感值掩码，保存时原样传回表示沿用已保存的值
+const secretMask = "******"
+
+// CredentialListResult 表示源认证信息列表结果，密码和令牌以掩码返回。
+type CredentialListResult struct {
+	Credentials []model.OriginCredential `json:"credentials"`
+	Success     bool                     `json:"success"`
+	Error       string                   `json:"error,omitempty"`
+}
+
+// loadCredentials 读取并解密已保存的源认证信息
+func (a *App) loadCredentials() ([]model.OriginCredential, error) {
+	raw, err := a.settingsRepo.GetSecret(storage.SettingKeyCredentials)
+	if err != nil || raw == "" {
+		return nil, err
+	}
+	var creds []model.OriginCredential
+	if err := json.Unmarshal([]byte(raw), &creds); err != nil {
+		return nil, err
+	}
+	return creds, nil
+}
+
+// GetCredentials 获取按源注入的认证信息，密码和令牌以掩码返回。
+func (a *App) GetCredentials() CredentialListResult {
+	creds, err := a.loadCredentials()
+	if err != nil {
+		a.log.Err(err, "读取源认证信息失败")
+		return CredentialListResult{Success: false, Error: err.Error()}
+	}
+	for i := range creds {
+		if creds[i].Password != "" {
+			creds[i].Password = secretMask
+		}
+		if creds[i].Token != "" {
+			creds[i].Token = secretMask
+		}
+	}
+	return CredentialListResult{Credentials: creds, Success: true}
+}
+
+// SetCredentials 加密保存按源注入的认证信息并立即应用到当前会话；密码或令牌为掩码时沿用同一源已保存的值。
+func (a *App) SetCredentials(credentialsJSON string) OperationResult {
+	var creds []model.OriginCredential
+	if err := json.Unmarshal([]byte(credentialsJSON), &creds); err != nil {
+		return OperationResult{Success: false, Error: "JSON 解析失败: " + err.Error()}
+	}
+
+	prev, err := a.loadCredentials()
+	if err != nil {
+		a.log.Warn("读取已保存的源认证信息失败", "error", err)
+	}
+	for i := range creds {
+		for _, p := range prev {
+			if p.Origin != creds[i].Origin {
+				continue
+			}
+			if creds[i].Password == secretMask {
+				creds[i].Password = p.Password
+			}
+			if creds[i].Token == secretMask {
+				creds[i].Token = p.Token
+			}
+		}
+	}
+
+	raw, err := json.Marshal(creds)
+	if err != nil {
+		return OperationResult{Success: false, Error: err.Error()}
+	}
+	if err := a.settingsRepo.SetSecret(storage.SettingKeyCredentials, string(raw)); err != nil {
+		a.log.Err(err, "保存源认证信息失败")
+		return OperationResult{Success: false, Error: err.Error()}
+	}
+
+	if a.currentSession != "" {
+		if err := a.service.SetCredentials(a.currentSession, creds); err != nil {
+			a.log.Err(err, "应用源认证信息失败", "sessionID", a.currentSession)
+			return OperationResult{Success: false, Error: err.Error()}
+		}
+	}
+
+	a.log.Info("源认证信息已更新", "count", len(creds))
+	return OperationResult{Success: true}
+}
+
+// ProxyAuthResult 表示上游代理认证信息结果，密码以掩码返回；未配置时 ProxyAuth 为空。
+type ProxyAuthResult struct {
+	ProxyAuth *model.ProxyCredential `json:"proxyAuth"`
+	Success   bool                   `json:"success"`
+	Error     string                 `json:"error,omitempty"`
+}
+
+// loadProxyAuth 读取并解密已保存的上游代理认证信息
+func (a *App) loadProxyAuth() (*model.ProxyCredential, error) {
+	raw, err := a.settingsRepo.GetSecret(storage.SettingKeyProxyAuth)
+	if err != nil || raw == "" {
+		return nil, err
+	}
+	var cred model.ProxyCredential
+	if err := json.Unmarshal([]byte(raw), &cred); err != nil {
+		return nil, err
+	}
+	return &cred, nil
+}
+
+// GetProxyAuth 获取上游代理认证信息，密码以掩码返回。
+func (a *App) GetProxyAuth() ProxyAuthResult {
+	cred, err := a.loadProxyAuth()
+	if err != nil {
+		a.log.Err(err, "读取上游代理认证信息失败")
+		return ProxyAuthResult{Success: false, Error: err.Error()}
+	}
+	if cred != nil && cred.Password != "" {
+		cred.Password = secretMask
+	}
+	return ProxyAuthResult{ProxyAuth: cred, Success: true}
+}
+
+// SetProxyAuth 加密保存上游代理认证信息并立即应用到当前会话；用户名为空表示清除，密码为掩码时沿用已保存的值。
+func (a *App) SetProxyAuth(username, password string) OperationResult {
+	var cred *model.ProxyCredential
+	if username != "" {
+		cred = &model.ProxyCredential{Username: username, Password: password}
+		if password == secretMask {
+			prev, err := a.loadProxyAuth()
+			if err != nil {
+				a.log.Warn("读取已保存的上游代理认证信息失败", "error", err)
+			}
+			cred.Password = ""
+			if prev != nil {
+				cred.Password = prev.Password
+			}
+		}
+	}
+
+	save := func() error { return a.settingsRepo.Set(storage.SettingKeyProxyAuth, "") }
+	if cred != nil {
+		raw, err := json.Marshal(cred)
+		if err != nil {
+			return OperationResult{Success: false, Error: err.Error()}
+		}
+		save = func() error { return a.settingsRepo.SetSecret(storage.SettingKeyProxyAuth, string(raw)) }
+	}
+	if err := save(); err != nil {
+		a.log.Err(err, "保存上游代理认证信息失败")
+		return OperationResult{Success: false, Error: err.Error()}
+	}
+
+	if a.currentSession != "" {
+		if err := a.service.SetProxyAuth(a.currentSession, cred); err != nil {
+			a.log.Err(err, "应用上游代理认证信息失败", "sessionID", a.currentSession)
+			return OperationResult{Success: false, Error: err.Error()}
+		}
+	}
+
+	a.log.Info("上游代理认证信息已更新", "configured", cred != nil)
+	return OperationResult{Success: true}
+}
+
+// secretNamePattern 命名密钥的合法名称，需能被 {{secret.name}} 模板引用
+var secretNamePattern = regexp.MustCompile(`^[a-zA-Z0-9_\-]+$`)
+
+// SecretListResult 表示命名密钥列表结果，值以掩码返回。
+type SecretListResult struct {
+	Secrets map[string]string `json:"secrets"`
+	Success bool              `json:"success"`
+	Error   string            `json:"error,omitempty"`
+}
+
+// loadSecrets 读取并解密已保存的命名密钥
+func (a *App) loadSecrets() (map[string]string, error) {
+	raw, err := a.settingsRepo.GetSecret(storage.SettingKeySecrets)
+	if err != nil || raw == "" {
+		return nil, err
+	}
+	var secrets map[string]string
+	if err := json.Unmarshal([]byte(raw), &secrets); err != nil {
+		return nil, err
+	}
+	return secrets, nil
+}
+
+// GetSecrets 获取行为模板可引用的命名密钥，值以掩码返回。
+func (a *App) GetSecrets() SecretListResult {
+	secrets, err := a.loadSecrets()
+	if err != nil {
+		a.log.Err(err, "读取命名密钥失败")
+		return SecretListResult{Success: false, Error: err.Error()}
+	}
+	masked := make(map[string]string, len(secrets))
+	for name := range secrets {
+		masked[name] = secretMask
+	}
+	return SecretListResult{Secrets: masked, Success: true}
+}
+
+// SetSecrets 加密保存命名密钥并立即应用到当前会话；值为掩码时沿用同名密钥已保存的值。
+func (a *App) SetSecrets(secretsJSON string) OperationResult {
+	var secrets map[string]string
+	if err := json.Unmarshal([]byte(secretsJSON), &secrets); err != nil {
+		return OperationResult{Success: false, Error: "JSON 解析失败: " + err.Error()}
+	}
+	for name := range secrets {
+		if !secretNamePattern.MatchString(name) {
+			return OperationResult{Success: false, Error: "密钥名称只能包含字母、数字、下划线和连字符: " + name}
+		}
+	}
+
+	prev, err := a.loadSecrets()
+	if err != nil {
+		a.log.Warn("读取已保存的命名密钥失败", "error", err)
+	}
+	for name, value := range secrets {
+		if value == secretMask {
+			secrets[name] = prev[name]
+		}
+	}
+
+	raw, err := json.Marshal(secrets)
+	if err != nil {
+		return OperationResult{Success: false, Error: err.Error()}
+	}
+	if err := a.settingsRepo.SetSecret(storage.SettingKeySecrets, string(raw)); err != nil {
+		a.log.Err(err, "保存命名密钥失败")
+		return OperationResult{Success: false, Error: err.Error()}
+	}
+
+	if a.currentSession != "" {
+		if err := a.service.SetSecrets(a.currentSession, secrets); err != nil {
+			a.log.Err(err, "应用命名密钥失败", "sessionID", a.currentSession)
+			return OperationResult{Success: false, Error: err.Error()}
+		}
+	}
+
+	a.log.Info("命名密钥已更新", "count", len(secrets))
+	return OperationResult{Success: true}
+}
+
+// SetDirty 供前端更新未保存状态
+func (a *App) SetDirty(dirty bool) {
+	a.isDirty = dirty
+}
+
+// BeforeClose 在窗口关闭前调用，如果有未保存更改则弹出确认框
+func (a *App) BeforeClose(ctx context.Context) bool {
+	if !a.isDirty {
+		return false
+	}
+
+	result, err := runtime.MessageDialog(ctx, runtime.MessageDialogOptions{
+		Type:          runtime.QuestionDialog,
+		Title:         "提醒",
+		Message:       "当前有未保存的规则更改，确定要退出吗？",
+		DefaultButton: "否",
+		Buttons:       []string{"是", "否"},
+	})
+
+	if err != nil {
+		a.log.Warn("关闭确认对话框出错", "error", err)
+		return true
+	}
+
+	// 用户选"是"(要退出) -> 允许关闭(返回false)
+	// 用户选"否"(不退出) -> 阻止关闭(返回true)
+	a.log.Debug("用户选择", "result", result)
+	return result == "否"
+}
+
+// ExportConfig 弹出原生保存对话框导出配置
+func (a *App) ExportConfig(name, rulesJSON string) OperationResult {
+	path, err := runtime.SaveFileDialog(a.ctx, runtime.SaveDialogOptions{
+		DefaultFilename: name + ".json",
+		Title:           "导出配置",
+		Filters: []runtime.FileFilter{
+			{DisplayName: "JSON Files (*.json)", Pattern: "*.json"},
+		},
+	})
+
+	if err != nil {
+		return OperationResult{Success: false, Error: err.Error()}
+	}
+
+	if path == "" {
+		return OperationResult{Success: true} // 用户取消
+	}
+
+	// 配置了签名私钥时导出签名后的配置
+	if key := a.settingsRepo.GetWithDefault(storage.SettingKeyConfigSigningKey, ""); key != "" {
+		signed, err := signConfigJSON(rulesJSON, key)
+		if err != nil {
+			return OperationResult{Success: false, Error: "配置签名失败: " + err.Error()}
+		}
+		rulesJSON = signed
+	}
+
+	err = os.WriteFile(path, []byte(rulesJSON), 0644)
+	if err != nil {
+		return OperationResult{Success: false, Error: "文件写入失败: " + err.Error()}
+	}
+
+	return OperationResult{Success: true}
+}
+
+// signConfigJSON 使用 base64 私钥为配置 JSON 签名，返回带签名的格式化 JSON
+func signConfigJSON(rulesJSON, key string) (string, error) {
+	priv, err := rulespec.ParsePrivateKey(key)
+	if err != nil {
+		return "", err
+	}
+	var cfg rulespec.Config
+	if err := json.Unmarshal([]byte(rulesJSON), &cfg); err != nil {
+		return "", err
+	}
+	if err := cfg.Sign(priv); err != nil {
+		return "", err
+	}
+	out, err := json.MarshalIndent(&cfg, "", "  ")
+	if err != nil {
+		return "", err
+	}
+	return string(out), nil
+}
+
+// SigningKeyPairResult 表示生成配置签名密钥对的结果。
+type SigningKeyPairResult struct {
+	PublicKey  string `json:"publicKey"`
+	PrivateKey string `json:"privateKey"`
+	Success    bool   `json:"success"`
+	Error      string `json:"error,omitempty"`
+}
+
+// GenerateSigningKeyPair 生成配置签名密钥对，私钥保存到本机设置用于导出签名，公钥需分发给团队成员。
+func (a *App) GenerateSigningKeyPair() SigningKeyPairResult {
+	pub, priv, err := rulespec.GenerateSigningKeyPair()
+	if err != nil {
+		a.log.Err(err, "生成签名密钥失败")
+		return SigningKeyPairResult{Success: false, Error: err.Error()}
+	}
+	if err := a.settingsRepo.Set(storage.SettingKeyConfigSigningKey, priv); err != nil {
+		a.log.Err(err, "保存签名私钥失败")
+		return SigningKeyPairResult{Success: false, Error: err.Error()}
+	}
+	a.log.Info("已生成配置签名密钥对")
+	return SigningKeyPairResult{PublicKey: pub, PrivateKey: priv, Success: true}
+}
+
+// EnableInterception 启用指定会话的网络拦截功能。
+func (a *App) EnableInterception(sessionID string) OperationResult {
+	// 检查是否已经附加了目标
+	targets, err := a.service.ListTargets(model.SessionID(sessionID))
+	hasAttached := false
+	if err == nil {
+		for _, t := range targets {
+			if t.IsCurrent {
+				hasAttached = true
+				break
+			}
+		}
+	}
+
+	if !hasAttached {
+		return OperationResult{Success: false, Error: "请先在 Targets 标签页附加至少一个目标"}
+	}
+
+	err = a.service.EnableInterception(model.SessionID(sessionID))
+	if err != nil {
+		a.log.Err(err, "启用拦截失败", "sessionID", sessionID)
+		return OperationResult{Success: false, Error: err.Error()}
+	}
+
+	a.log.Info("已启用拦截", "sessionID", sessionID)
+	return OperationResult{Success: true}
+}
+
+// DisableInterception 停用指定会话的网络拦截功能。
+func (a *App) DisableInterception(sessionID string) OperationResult {
+	err := a.service.DisableInterception(model.SessionID(sessionID))
+	if err != nil {
+		a.log.Err(err, "停用拦截失败", "sessionID", sessionID)
+		return OperationResult{Success: false, Error: err.Error()}
+	}
+
+	a.log.Info("已停用拦截", "sessionID", sessionID)
+	return OperationResult{Success: true}
+}
+
+// EnableCacheBypass 为指定会话禁用浏览器缓存，并记住该选项供之后的会话使用。
+func (a *App) EnableCacheBypass(sessionID string) OperationResult {
+	return a.setCacheBypass(sessionID, true)
+}
+
+// DisableCacheBypass 恢复指定会话的浏览器缓存。
+func (a *App) DisableCacheBypass(sessionID string) OperationResult {
+	return a.setCacheBypass(sessionID, false)
+}
+
+// setCacheBypass 保存缓存开关并应用到会话
+func (a *App) setCacheBypass(sessionID string, on bool) OperationResult {
+	var err error
+	if on {
+		err = a.service.EnableCacheBypass(model.SessionID(sessionID))
+	} else {
+		err = a.service.DisableCacheBypass(model.SessionID(sessionID))
+	}
+	if err != nil {
+		a.log.Err(err, "设置浏览器缓存开关失败", "sessionID", sessionID)
+		return OperationResult{Success: false, Error: err.Error()}
+	}
+	if err := a.settingsRepo.Set(storage.SettingKeyCacheBypass, strconv.FormatBool(on)); err != nil {
+		a.log.Warn("保存浏览器缓存开关失败", "error", err)
+	}
+
+	a.log.Info("浏览器缓存开关已更新", "sessionID", sessionID, "disabled", on)
+	return OperationResult{Success: true}
+}
+
+// SetGeolocation 覆盖指定会话页面的地理位置（纬度、经度、精度米），并记住该位置供之后的会话使用。
+func (a *App) SetGeolocation(sessionID string, lat, lon, accuracy float64) OperationResult {
+	g := &model.Geolocation{Latitude: lat, Longitude: lon, Accuracy: accuracy}
+	if err := a.service.SetGeolocation(model.SessionID(sessionID), g); err != nil {
+		a.log.Err(err, "设置地理位置覆盖失败", "sessionID", sessionID)
+		return OperationResult{Success: false, Error: err.Error()}
+	}
+	raw, _ := json.Marshal(g)
+	if err := a.settingsRepo.Set(storage.SettingKeyGeolocation, string(raw)); err != nil {
+		a.log.Warn("保存地理位置覆盖失败", "error", err)
+	}
+
+	a.log.Info("地理位置覆盖已更新", "sessionID", sessionID, "latitude", lat, "longitude", lon)
+	return OperationResult{Success: true}
+}
+
+// ClearGeolocation 清除指定会话的地理位置覆盖，恢复浏览器实际位置。
+func (a *App) ClearGeolocation(sessionID string) OperationResult {
+	if err := a.service.SetGeolocation(model.SessionID(sessionID), nil); err != nil {
+		a.log.Err(err, "清除地理位置覆盖失败", "sessionID", sessionID)
+		return OperationResult{Success: false, Error: err.Error()}
+	}
+	if err := a.settingsRepo.Set(storage.SettingKeyGeolocation, ""); err != nil {
+		a.log.Warn("保存地理位置覆盖失败", "error", err)
+	}
+
+	a.log.Info("地理位置覆盖已清除", "sessionID", sessionID)
+	return OperationResult{Success: true}
+}
+
+// LoadRules 从 JSON 字符串加载规则配置到指定会话。
+func (a *App) LoadRules(sessionID string, rulesJSON string) OperationResult {
+	var cfg rulespec.Config
+	if err := json.Unmarshal([]byte(rulesJSON), &cfg); err != nil {
+		a.log.Err(err, "JSON 解析失败")
+		return OperationResult{Success: false, Error: "JSON 解析失败: " + err.Error()}
+	}
+
+	err := a.service.LoadRules(model.SessionID(sessionID), &cfg)
+	if err != nil {
+		a.log.Err(err, "加载规则失败", "sessionID", sessionID)
+		return OperationResult{Success: false, Error: err.Error()}
+	}
+
+	a.log.Info("规则加载成功", "sessionID", sessionID, "ruleCount", len(cfg.Rules))
+	return OperationResult{Success: true}
+}
+
+// StatsResult 表示规则统计结果。
+type StatsResult struct {
+	Stats   model.EngineStats `json:"stats"`
+	Success bool              `json:"success"`
+	Error   string            `json:"error,omitempty"`
+}
+
+// GetRuleStats 获取指定会话的规则命中统计信息。
+func (a *App) GetRuleStats(sessionID string) StatsResult {
+	stats, err := a.service.GetRuleStats(model.SessionID(sessionID))
+	if err != nil {
+		a.log.Err(err, "获取规则统计失败", "sessionID", sessionID)
+		return StatsResult{Success: false, Error: err.Error()}
+	}
+	return StatsResult{Stats: stats, Success: true}
+}
+
+// DuplicateReportResult 表示重复请求汇总结果。
+type DuplicateReportResult struct {
+	Duplicates []model.DuplicateRequestStat `json:"duplicates"`
+	Success    bool                         `json:"success"`
+	Error      string                       `json:"error,omitempty"`
+}
+
+// GetDuplicateReport 获取指定会话中重复发送的请求汇总。
+func (a *App) GetDuplicateReport(sessionID string) DuplicateReportResult {
+	dups, err := a.service.DuplicateReport(model.SessionID(sessionID))
+	if err != nil {
+		return DuplicateReportResult{Success: false, Error: err.Error()}
+	}
+	return DuplicateReportResult{Duplicates: dups, Success: true}
+}
+
+// ClearDuplicateReport 清空指定会话的重复请求记录。
+func (a *App) ClearDuplicateReport(sessionID string) OperationResult {
+	if err := a.service.ClearDuplicateReport(model.SessionID(sessionID)); err != nil {
+		return OperationResult{Success: false, Error: err.Error()}
+	}
+	return OperationResult{Success: true}
+}
+
+// subscribeEvents 订阅拦截事件并通过 Wails 事件系统批量推送到前端。
+func (a *App) subscribeEvents(sessionID model.SessionID) {
+	ch, err := a.service.SubscribeEvents(sessionID)
+	if err != nil {
+		a.log.Err(err, "订阅事件失败", "sessionID", sessionID)
+		return
+	}
+
+	interval, size := a.eventBatchOptions()
+	bodyLimit := a.eventBodyLimit()
+	batcher := newEventBatcher(interval, size, func(batch EventBatch) {
+		if batch.Dropped > 0 {
+			a.log.Warn("前端事件推送积压，已丢弃部分事件", "sessionID", sessionID, "dropped", batch.Dropped)
+		}
+		runtime.EventsEmit(a.ctx, "intercept-events", batch)
+	})
+	defer batcher.close()
+
+	a.log.Debug("开始订阅事件", "sessionID", sessionID, "flushInterval", interval, "batchSize", size)
+	for evt := range ch {
+		// 通知事件单独推送，不写入数据库
+		if evt.Notice != nil {
+			evt.Notice.Session = sessionID
+			runtime.EventsEmit(a.ctx, "notice-event", evt.Notice)
+			continue
+		}
+		// 只有匹配的事件才写入数据库
+		if evt.IsMatched && evt.Matched != nil && a.eventRepo != nil {
+			evt.Matched.Session = sessionID
+			a.eventRepo.RecordMatched(evt.Matched)
+		}
+		// 合并后通过 Wails 事件系统推送到前端，重点关注接口的事件不会因积压被丢弃
+		batcher.add(trimEventBodies(evt, bodyLimit, &a.bodies), a.pins.record(evt))
+	}
+	a.log.Debug("事件订阅已结束", "sessionID", sessionID)
+}
+
+// subscribePending 订阅断点通知并推送到前端，由用户放行或拒绝
+func (a *App) subscribePending(sessionID model.SessionID) {
+	ch, err := a.service.SubscribePending(sessionID)
+	if err != nil {
+		a.log.Err(err, "订阅断点失败", "sessionID", sessionID)
+		return
+	}
+	for item := range ch {
+		item.Session = sessionID
+		runtime.EventsEmit(a.ctx, "pending-item", item)
+	}
+}
+
+// ApprovePending 放行断点暂停的请求，editsJSON 为空时按规则修改放行。
+func (a *App) ApprovePending(sessionID, itemID, editsJSON string) OperationResult {
+	var edits *model.PendingEdits
+	if editsJSON != "" {
+		edits = &model.PendingEdits{}
+		if err := json.Unmarshal([]byte(editsJSON), edits); err != nil {
+			return OperationResult{Success: false, Error: "JSON 解析失败: " + err.Error()}
+		}
+	}
+	if err := a.service.ApprovePending(model.SessionID(sessionID), itemID, edits); err != nil {
+		return OperationResult{Success: false, Error: err.Error()}
+	}
+	return OperationResult{Success: true}
+}
+
+// RejectPending 拒绝断点暂停的请求。
+func (a *App) RejectPending(sessionID, itemID string) OperationResult {
+	if err := a.service.RejectPending(model.SessionID(sessionID), itemID); err != nil {
+		return OperationResult{Success: false, Error: err.Error()}
+	}
+	return OperationResult{Success: true}
+}
+
+// LaunchBrowserResult 表示启动浏览器的结果。
+type LaunchBrowserResult struct {
+	DevToolsURL string `json:"devToolsUrl"`
+	Success     bool   `json:"success"`
+	Error       string `json:"error,omitempty"`
+}
+
+// LaunchBrowser 启动新的浏览器实例，如果已有浏览器运行则先关闭。
+func (a *App) LaunchBrowser(headless bool) LaunchBrowserResult {
+	a.log.Info("启动浏览器", "headless", headless)
+
+	// 如果已有浏览器运行，先关闭
+	if a.browser != nil {
+		if err := a.browser.Stop(2 * time.Second); err != nil {
+			a.log.Warn("关闭旧浏览器实例失败", "error", err)
+		}
+		a.browser = nil
+	}
+
+	opts := browser.Options{
+		Headless: headless,
+	}
+
+	b, err := browser.Start(opts)
+	if err != nil {
+		a.log.Err(err, "启动浏览器失败")
+		return LaunchBrowserResult{Success: false, Error: err.Error()}
+	}
+
+	a.browser = b
+	a.log.Info("浏览器启动成功", "devToolsURL", b.DevToolsURL)
+	return LaunchBrowserResult{DevToolsURL: b.DevToolsURL, Success: true}
+}
+
+// CloseBrowser 关闭已启动的浏览器实例。
+func (a *App) CloseBrowser() OperationResult {
+	if a.browser == nil {
+		return OperationResult{Success: false, Error: "没有正在运行的浏览器"}
+	}
+
+	err := a.browser.Stop(2 * time.Second)
+	a.browser = nil
+	if err != nil {
+		a.log.Err(err, "关闭浏览器失败")
+		return OperationResult{Success: false, Error: err.Error()}
+	}
+
+	a.log.Info("浏览器已关闭")
+	return OperationResult{Success: true}
+}
+
+// GetBrowserStatus 获取当前浏览器的运行状态。
+func (a *App) GetBrowserStatus() LaunchBrowserResult {
+	if a.browser == nil {
+		return LaunchBrowserResult{Success: false}
+	}
+	return LaunchBrowserResult{DevToolsURL: a.browser.DevToolsURL, Success: true}
+}
+
+// SettingsResult 表示设置操作的结果。
 type SettingsResult struct {
 	Settings map[string]string `json:"settings"`
 	Success  bool              `json:"success"`
@@ -473,84 +1427,261 @@ func (a *App) ListConfigs() ConfigListResult {
 		a.log.Err(err, "列出配置失败")
 		return ConfigListResult{Success: false, Error: err.Error()}
 	}
-	return ConfigListResult{Configs: configs, Success: true}
+	return ConfigListResult{Configs: configs, Success: true}
+}
+
+// GetConfig 根据 ID 获取指定配置。
+func (a *App) GetConfig(id uint) ConfigResult {
+	config, err := a.configRepo.GetByID(id)
+	if err != nil {
+		a.log.Err(err, "获取配置失败", "id", id)
+		return ConfigResult{Success: false, Error: err.Error()}
+	}
+	return ConfigResult{Config: config, Success: true}
+}
+
+// NewConfigResult 表示创建新配置的结果（含完整 JSON）。
+type NewConfigResult struct {
+	Config     *storage.ConfigRecord `json:"config"`
+	ConfigJSON string                `json:"configJson"` // 完整的 rulespec.Config JSON
+	Success    bool                  `json:"success"`
+	Error      string                `json:"error,omitempty"`
+}
+
+// CreateNewConfig 创建一个新的空配置并保存到数据库。
+func (a *App) CreateNewConfig(name string) NewConfigResult {
+	// 使用 rulespec.NewConfig 创建标准配置
+	cfg := rulespec.NewConfig(name)
+
+	// 保存到数据库
+	config, err := a.configRepo.Create(cfg)
+	if err != nil {
+		a.log.Err(err, "创建配置失败", "name", name)
+		return NewConfigResult{Success: false, Error: err.Error()}
+	}
+
+	// 序列化配置 JSON
+	configJSON, err := json.Marshal(cfg)
+	if err != nil {
+		a.log.Err(err, "序列化配置失败")
+		return NewConfigResult{Success: false, Error: err.Error()}
+	}
+
+	a.log.Info("新配置已创建", "id", config.ID, "name", name, "configId", cfg.ID)
+	return NewConfigResult{Config: config, ConfigJSON: string(configJSON), Success: true}
+}
+
+// NewRuleResult 表示创建新规则的结果。
+type NewRuleResult struct {
+	RuleJSON string `json:"ruleJson"` // 完整的 rulespec.Rule JSON
+	Success  bool   `json:"success"`
+	Error    string `json:"error,omitempty"`
+}
+
+// GenerateNewRule 生成一个新的空规则，existingCount 为当前规则列表中的规则数量。
+func (a *App) GenerateNewRule(name string, existingCount int) NewRuleResult {
+	rule := rulespec.NewRule(name, existingCount)
+	ruleJSON, err := json.Marshal(rule)
+	if err != nil {
+		a.log.Err(err, "序列化规则失败")
+		return NewRuleResult{Success: false, Error: err.Error()}
+	}
+	return NewRuleResult{RuleJSON: string(ruleJSON), Success: true}
+}
+
+// SaveConfig 保存配置（创建或更新），dbID 为 0 时创建新配置。
+func (a *App) SaveConfig(dbID uint, configJSON string) ConfigResult {
+	var cfg rulespec.Config
+	if err := json.Unmarshal([]byte(configJSON), &cfg); err != nil {
+		a.log.Err(err, "保存配置 JSON 解析失败")
+		return ConfigResult{Success: false, Error: "JSON 解析失败: " + err.Error()}
+	}
+
+	config, err := a.configRepo.Save(dbID, &cfg, a.currentAuthor())
+	if err != nil {
+		a.log.Err(err, "保存配置失败", "dbID", dbID, "configID", cfg.ID)
+		return ConfigResult{Success: false, Error: err.Error()}
+	}
+
+	a.log.Info("配置已保存", "dbID", config.ID, "configID", cfg.ID, "name", cfg.Name)
+	return ConfigResult{Config: config, Success: true}
+}
+
+// currentAuthor 返回规则变更记录使用的作者名，未设置时使用系统用户名
+func (a *App) currentAuthor() string {
+	if a.settingsRepo != nil {
+		if name := a.settingsRepo.GetWithDefault(storage.SettingKeyAuthorName, ""); name != "" {
+			return name
+		}
+	}
+	if u, err := user.Current(); err == nil {
+		return u.Username
+	}
+	return ""
+}
+
+// RuleEditResult 表示规则编辑操作的结果。
+type RuleEditResult struct {
+	Config  *storage.ConfigRecord `json:"config"`
+	RuleID  string                `json:"ruleId,omitempty"` // 复制得到的新规则 ID
+	Changed int                   `json:"changed"`          // 发生变化的规则数
+	Success bool                  `json:"success"`
+	Error   string                `json:"error,omitempty"`
+}
+
+// CloneRule 复制配置中的规则并插入到原规则之后，保存后返回更新后的配置。
+func (a *App) CloneRule(dbID uint, ruleID string) RuleEditResult {
+	var newID string
+	config, err := a.configRepo.Edit(dbID, a.currentAuthor(), func(cfg *rulespec.Config) error {
+		clone, err := cfg.CloneRule(ruleID)
+		if err != nil {
+			return err
+		}
+		newID = clone.ID
+		return nil
+	})
+	if err != nil {
+		a.log.Err(err, "复制规则失败", "dbID", dbID, "ruleID", ruleID)
+		return RuleEditResult{Success: false, Error: err.Error()}
+	}
+	a.log.Info("规则已复制", "dbID", dbID, "ruleID", ruleID, "newRuleID", newID)
+	return RuleEditResult{Config: config, RuleID: newID, Changed: 1, Success: true}
+}
+
+// BulkUpdateRules 对配置中的多条规则应用部分修改（rulespec.RulePatch JSON），ruleIDs 为空时作用于全部规则。
+func (a *App) BulkUpdateRules(dbID uint, ruleIDs []string, patchJSON string) RuleEditResult {
+	var patch rulespec.RulePatch
+	if err := json.Unmarshal([]byte(patchJSON), &patch); err != nil {
+		return RuleEditResult{Success: false, Error: "JSON 解析失败: " + err.Error()}
+	}
+
+	changed := 0
+	config, err := a.configRepo.Edit(dbID, a.currentAuthor(), func(cfg *rulespec.Config) error {
+		n, err := cfg.BulkUpdateRules(ruleIDs, patch)
+		changed = n
+		return err
+	})
+	if err != nil {
+		a.log.Err(err, "批量修改规则失败", "dbID", dbID)
+		return RuleEditResult{Success: false, Error: err.Error()}
+	}
+	a.log.Info("规则已批量修改", "dbID", dbID, "changed", changed)
+	return RuleEditResult{Config: config, Changed: changed, Success: true}
+}
+
+// SetTagEnabled 启用或停用激活配置中带有指定标签的全部规则，保存后同步到当前会话。
+func (a *App) SetTagEnabled(tag string, enabled bool) RuleEditResult {
+	active, err := a.configRepo.GetActive()
+	if err != nil {
+		a.log.Err(err, "获取激活配置失败")
+		return RuleEditResult{Success: false, Error: err.Error()}
+	}
+	if active == nil {
+		return RuleEditResult{Success: false, Error: "没有激活的配置"}
+	}
+
+	changed := 0
+	config, err := a.configRepo.Edit(active.ID, a.currentAuthor(), func(cfg *rulespec.Config) error {
+		n, err := cfg.SetTagEnabled(tag, enabled)
+		changed = n
+		return err
+	})
+	if err != nil {
+		a.log.Err(err, "按标签切换规则失败", "dbID", active.ID, "tag", tag)
+		return RuleEditResult{Success: false, Error: err.Error()}
+	}
+
+	if a.currentSession != "" {
+		if _, err := a.service.SetTagEnabled(a.currentSession, tag, enabled); err != nil {
+			a.log.Warn("同步标签状态到会话失败", "sessionID", a.currentSession, "tag", tag, "error", err)
+		}
+	}
+	a.log.Info("已按标签切换规则", "dbID", active.ID, "tag", tag, "enabled", enabled, "changed", changed)
+	return RuleEditResult{Config: config, Changed: changed, Success: true}
 }
 
-// GetConfig 根据 ID 获取指定配置。
-func (a *App) GetConfig(id uint) ConfigResult {
-	config, err := a.configRepo.GetByID(id)
+// ReorderRules 按给定的规则 ID 顺序重新排列配置中的规则，ruleIDs 需包含全部规则。
+func (a *App) ReorderRules(dbID uint, ruleIDs []string) RuleEditResult {
+	config, err := a.configRepo.Edit(dbID, a.currentAuthor(), func(cfg *rulespec.Config) error {
+		return cfg.ReorderRules(ruleIDs)
+	})
 	if err != nil {
-		a.log.Err(err, "获取配置失败", "id", id)
-		return ConfigResult{Success: false, Error: err.Error()}
+		a.log.Err(err, "调整规则顺序失败", "dbID", dbID)
+		return RuleEditResult{Success: false, Error: err.Error()}
 	}
-	return ConfigResult{Config: config, Success: true}
+	return RuleEditResult{Config: config, Success: true}
 }
 
-// NewConfigResult 表示创建新配置的结果（含完整 JSON）。
-type NewConfigResult struct {
-	Config     *storage.ConfigRecord `json:"config"`
-	ConfigJSON string                `json:"configJson"` // 完整的 rulespec.Config JSON
-	Success    bool                  `json:"success"`
-	Error      string                `json:"error,omitempty"`
+// RuleListResult 表示返回给前端的规则列表结果。
+type RuleListResult struct {
+	Rules   []rulespec.Rule `json:"rules"`
+	Success bool            `json:"success"`
+	Error   string          `json:"error,omitempty"`
 }
 
-// CreateNewConfig 创建一个新的空配置并保存到数据库。
-func (a *App) CreateNewConfig(name string) NewConfigResult {
-	// 使用 rulespec.NewConfig 创建标准配置
-	cfg := rulespec.NewConfig(name)
-
-	// 保存到数据库
-	config, err := a.configRepo.Create(cfg)
+// GetRulesChangedSince 查询指定配置中在 since（毫秒时间戳）之后有变更的规则。
+func (a *App) GetRulesChangedSince(id uint, since int64) RuleListResult {
+	rules, err := a.configRepo.RulesChangedSince(id, since)
 	if err != nil {
-		a.log.Err(err, "创建配置失败", "name", name)
-		return NewConfigResult{Success: false, Error: err.Error()}
+		a.log.Err(err, "查询变更规则失败", "id", id)
+		return RuleListResult{Success: false, Error: err.Error()}
 	}
+	return RuleListResult{Rules: rules, Success: true}
+}
 
-	// 序列化配置 JSON
-	configJSON, err := json.Marshal(cfg)
+// RuleSearchResult 表示按说明搜索规则的结果。
+type RuleSearchResult struct {
+	Hits    []storage.RuleSearchHit `json:"hits"`
+	Success bool                    `json:"success"`
+	Error   string                  `json:"error,omitempty"`
+}
+
+// SearchRules 在全部配置中搜索说明（Markdown 文本）包含指定文字的规则。
+func (a *App) SearchRules(text string) RuleSearchResult {
+	hits, err := a.configRepo.SearchRules(text)
 	if err != nil {
-		a.log.Err(err, "序列化配置失败")
-		return NewConfigResult{Success: false, Error: err.Error()}
+		a.log.Err(err, "搜索规则失败", "text", text)
+		return RuleSearchResult{Success: false, Error: err.Error()}
 	}
-
-	a.log.Info("新配置已创建", "id", config.ID, "name", name, "configId", cfg.ID)
-	return NewConfigResult{Config: config, ConfigJSON: string(configJSON), Success: true}
+	return RuleSearchResult{Hits: hits, Success: true}
 }
 
-// NewRuleResult 表示创建新规则的结果。
-type NewRuleResult struct {
-	RuleJSON string `json:"ruleJson"` // 完整的 rulespec.Rule JSON
-	Success  bool   `json:"success"`
-	Error    string `json:"error,omitempty"`
+// RuleOrderItem 表示规则在生效顺序中的位置。
+type RuleOrderItem struct {
+	Position       int            `json:"position"`
+	RuleID         string         `json:"ruleId"`
+	Name           string         `json:"name"`
+	Stage          rulespec.Stage `json:"stage"`
+	Priority       int            `json:"priority"`
+	StopProcessing bool           `json:"stopProcessing"`
 }
 
-// GenerateNewRule 生成一个新的空规则，existingCount 为当前规则列表中的规则数量。
-func (a *App) GenerateNewRule(name string, existingCount int) NewRuleResult {
-	rule := rulespec.NewRule(name, existingCount)
-	ruleJSON, err := json.Marshal(rule)
-	if err != nil {
-		a.log.Err(err, "序列化规则失败")
-		return NewRuleResult{Success: false, Error: err.Error()}
-	}
-	return NewRuleResult{RuleJSON: string(ruleJSON), Success: true}
+// EvaluationOrderResult 表示返回给前端的规则生效顺序结果。
+type EvaluationOrderResult struct {
+	Rules   []RuleOrderItem `json:"rules"`
+	Success bool            `json:"success"`
+	Error   string          `json:"error,omitempty"`
 }
 
-// SaveConfig 保存配置（创建或更新），dbID 为 0 时创建新配置。
-func (a *App) SaveConfig(dbID uint, configJSON string) ConfigResult {
+// GetEvaluationOrder 返回配置中已启用规则的实际生效顺序（按优先级从大到小，相同时按配置顺序）。
+func (a *App) GetEvaluationOrder(configJSON string) EvaluationOrderResult {
 	var cfg rulespec.Config
 	if err := json.Unmarshal([]byte(configJSON), &cfg); err != nil {
-		a.log.Err(err, "保存配置 JSON 解析失败")
-		return ConfigResult{Success: false, Error: "JSON 解析失败: " + err.Error()}
+		return EvaluationOrderResult{Success: false, Error: "JSON 解析失败: " + err.Error()}
 	}
-
-	config, err := a.configRepo.Save(dbID, &cfg)
-	if err != nil {
-		a.log.Err(err, "保存配置失败", "dbID", dbID, "configID", cfg.ID)
-		return ConfigResult{Success: false, Error: err.Error()}
+	ordered := cfg.EvaluationOrder("")
+	items := make([]RuleOrderItem, len(ordered))
+	for i, r := range ordered {
+		items[i] = RuleOrderItem{
+			Position:       i + 1,
+			RuleID:         r.ID,
+			Name:           r.Name,
+			Stage:          r.Stage,
+			Priority:       r.Priority,
+			StopProcessing: r.StopProcessing,
+		}
 	}
-
-	a.log.Info("配置已保存", "dbID", config.ID, "configID", cfg.ID, "name", cfg.Name)
-	return ConfigResult{Config: config, Success: true}
+	return EvaluationOrderResult{Rules: items, Success: true}
 }
 
 // DeleteConfig 删除指定 ID 的配置。
@@ -607,7 +1738,7 @@ func (a *App) ImportConfig(configJSON string) ConfigResult {
 		return ConfigResult{Success: false, Error: "JSON 解析失败: " + err.Error()}
 	}
 
-	config, err := a.configRepo.Upsert(&cfg)
+	config, err := a.configRepo.Upsert(&cfg, a.currentAuthor())
 	if err != nil {
 		a.log.Err(err, "导入配置失败", "configID", cfg.ID)
 		return ConfigResult{Success: false, Error: err.Error()}
@@ -680,6 +1811,346 @@ func (a *App) QueryMatchedEventHistory(sessionID, finalResult, url, method strin
 	return MatchedEventHistoryResult{Events: events, Total: total, Success: true}
 }
 
+// GetTransactionEvents 查询同一网络请求的请求阶段与响应阶段事件，用于展示完整的请求事务。
+func (a *App) GetTransactionEvents(sessionID, networkID string) MatchedEventHistoryResult {
+	if a.eventRepo == nil {
+		a.log.Error("查询请求事务失败: 事件仓库未初始化")
+		return MatchedEventHistoryResult{Success: false, Error: "事件仓库未初始化"}
+	}
+	if networkID == "" {
+		return MatchedEventHistoryResult{Success: false, Error: "networkId 不能为空"}
+	}
+
+	events, err := a.eventRepo.QueryTransaction(sessionID, networkID)
+	if err != nil {
+		a.log.Err(err, "查询请求事务失败", "sessionID", sessionID, "networkID", networkID)
+		return MatchedEventHistoryResult{Success: false, Error: err.Error()}
+	}
+
+	return MatchedEventHistoryResult{Events: events, Total: int64(len(events)), Success: true}
+}
+
+// OperationGroupsResult 表示按 API 逻辑操作分组的统计结果。
+type OperationGroupsResult struct {
+	Groups  []storage.OperationGroup `json:"groups"`
+	Success bool                     `json:"success"`
+	Error   string                   `json:"error,omitempty"`
+}
+
+// GetOperationGroups 按 API 逻辑操作（GraphQL 操作名、gRPC 方法等）统计会话中的匹配事件。
+func (a *App) GetOperationGroups(sessionID string) OperationGroupsResult {
+	if a.eventRepo == nil {
+		a.log.Error("统计操作分组失败: 事件仓库未初始化")
+		return OperationGroupsResult{Success: false, Error: "事件仓库未初始化"}
+	}
+
+	groups, err := a.eventRepo.OperationGroups(sessionID)
+	if err != nil {
+		a.log.Err(err, "统计操作分组失败", "sessionID", sessionID)
+		return OperationGroupsResult{Success: false, Error: err.Error()}
+	}
+	return OperationGroupsResult{Groups: groups, Success: true}
+}
+
+// QueryOperationEvents 查询属于指定 API 逻辑操作的匹配事件。
+func (a *App) QueryOperationEvents(sessionID, operation string, offset, limit int) MatchedEventHistoryResult {
+	if a.eventRepo == nil {
+		a.log.Error("查询操作事件失败: 事件仓库未初始化")
+		return MatchedEventHistoryResult{Success: false, Error: "事件仓库未初始化"}
+	}
+	if operation == "" {
+		return MatchedEventHistoryResult{Success: false, Error: "operation 不能为空"}
+	}
+
+	events, total, err := a.eventRepo.Query(storage.QueryOptions{
+		SessionID: sessionID,
+		Operation: operation,
+		Offset:    offset,
+		Limit:     limit,
+	})
+	if err != nil {
+		a.log.Err(err, "查询操作事件失败", "sessionID", sessionID, "operation", operation)
+		return MatchedEventHistoryResult{Success: false, Error: err.Error()}
+	}
+	return MatchedEventHistoryResult{Events: events, Total: total, Success: true}
+}
+
+// PinnedEndpointsResult 表示重点关注的接口列表及其计数。
+type PinnedEndpointsResult struct {
+	Endpoints []PinnedEndpoint `json:"endpoints"`
+	Success   bool             `json:"success"`
+	Error     string           `json:"error,omitempty"`
+}
+
+// GetPinnedEndpoints 获取重点关注的接口及其事件计数。
+func (a *App) GetPinnedEndpoints() PinnedEndpointsResult {
+	return PinnedEndpointsResult{Endpoints: a.pins.snapshot(), Success: true}
+}
+
+// PinEndpoint 固定一个重点关注的接口 URL 模式（* 匹配任意字符），保存到设置并立即应用到当前会话。
+func (a *App) PinEndpoint(pattern string) PinnedEndpointsResult {
+	pattern = strings.TrimSpace(pattern)
+	if strings.Trim(pattern, "*") == "" {
+		return PinnedEndpointsResult{Success: false, Error: "URL 模式不能为空"}
+	}
+	patterns := a.pins.patterns()
+	for _, p := range patterns {
+		if p == pattern {
+			return a.GetPinnedEndpoints()
+		}
+	}
+	return a.savePins(append(patterns, pattern))
+}
+
+// UnpinEndpoint 取消固定重点关注的接口，其计数一并清除。
+func (a *App) UnpinEndpoint(pattern string) PinnedEndpointsResult {
+	patterns := a.pins.patterns()
+	kept := patterns[:0]
+	for _, p := range patterns {
+		if p != pattern {
+			kept = append(kept, p)
+		}
+	}
+	return a.savePins(kept)
+}
+
+// savePins 保存重点关注的接口并应用到当前会话
+func (a *App) savePins(patterns model.EndpointPins) PinnedEndpointsResult {
+	if err := a.settingsRepo.SetStringList(storage.SettingKeyPinnedEndpoints, patterns); err != nil {
+		a.log.Err(err, "保存重点关注接口失败")
+		return PinnedEndpointsResult{Success: false, Error: err.Error()}
+	}
+	a.pins.set(patterns)
+
+	if a.currentSession != "" {
+		if err := a.service.SetPinnedEndpoints(a.currentSession, patterns); err != nil {
+			a.log.Err(err, "应用重点关注接口失败", "sessionID", a.currentSession)
+			return PinnedEndpointsResult{Success: false, Error: err.Error()}
+		}
+	}
+
+	a.log.Info("重点关注接口已更新", "count", len(patterns))
+	return a.GetPinnedEndpoints()
+}
+
+// QueryPinnedEvents 查询 URL 符合重点关注接口模式的匹配事件，用于快速筛选。
+func (a *App) QueryPinnedEvents(sessionID, pattern string, offset, limit int) MatchedEventHistoryResult {
+	if a.eventRepo == nil {
+		a.log.Error("查询重点关注接口事件失败: 事件仓库未初始化")
+		return MatchedEventHistoryResult{Success: false, Error: "事件仓库未初始化"}
+	}
+	if strings.Trim(pattern, "*") == "" {
+		return MatchedEventHistoryResult{Success: false, Error: "URL 模式不能为空"}
+	}
+
+	events, total, err := a.eventRepo.Query(storage.QueryOptions{
+		SessionID:  sessionID,
+		URLPattern: pattern,
+		Offset:     offset,
+		Limit:      limit,
+	})
+	if err != nil {
+		a.log.Err(err, "查询重点关注接口事件失败", "sessionID", sessionID, "pattern", pattern)
+		return MatchedEventHistoryResult{Success: false, Error: err.Error()}
+	}
+	return MatchedEventHistoryResult{Events: events, Total: total, Success: true}
+}
+
+// ScreenshotResult 表示页面截图结果，图片数据为 base64 编码。
+type ScreenshotResult struct {
+	ID        uint   `json:"id,omitempty"` // 截图记录 ID，事件仓库不可用时为 0
+	TargetID  string `json:"targetId"`
+	MimeType  string `json:"mimeType"`
+	Data      string `json:"data"`
+	Timestamp int64  `json:"timestamp"`
+	Success   bool   `json:"success"`
+	Error     string `json:"error,omitempty"`
+}
+
+// CaptureScreenshot 截取会话中页面的当前视口（PNG），并与事件历史一同保存为测试证据。
+func (a *App) CaptureScreenshot(sessionID, targetID string) ScreenshotResult {
+	shot, err := a.service.CaptureScreenshot(model.SessionID(sessionID), model.TargetID(targetID))
+	if err != nil {
+		a.log.Err(err, "页面截图失败", "sessionID", sessionID, "targetID", targetID)
+		return ScreenshotResult{Success: false, Error: err.Error()}
+	}
+
+	result := ScreenshotResult{
+		TargetID:  string(shot.Target),
+		MimeType:  shot.MimeType,
+		Data:      base64.StdEncoding.EncodeToString(shot.Data),
+		Timestamp: shot.Timestamp,
+		Success:   true,
+	}
+	if a.eventRepo != nil {
+		record, err := a.eventRepo.SaveScreenshot(sessionID, shot)
+		if err != nil {
+			a.log.Err(err, "保存截图失败", "sessionID", sessionID)
+		} else {
+			result.ID = record.ID
+		}
+	}
+	return result
+}
+
+// ScreenshotListResult 表示截图列表查询结果，不含图片数据。
+type ScreenshotListResult struct {
+	Screenshots []storage.ScreenshotRecord `json:"screenshots"`
+	Success     bool                       `json:"success"`
+	Error       string                     `json:"error,omitempty"`
+}
+
+// ListScreenshots 列出会话中保存的截图。
+func (a *App) ListScreenshots(sessionID string) ScreenshotListResult {
+	if a.eventRepo == nil {
+		return ScreenshotListResult{Success: false, Error: "事件仓库未初始化"}
+	}
+	records, err := a.eventRepo.ListScreenshots(sessionID)
+	if err != nil {
+		a.log.Err(err, "查询截图列表失败", "sessionID", sessionID)
+		return ScreenshotListResult{Success: false, Error: err.Error()}
+	}
+	return ScreenshotListResult{Screenshots: records, Success: true}
+}
+
+// GetScreenshot 获取保存的截图。
+func (a *App) GetScreenshot(id uint) ScreenshotResult {
+	if a.eventRepo == nil {
+		return ScreenshotResult{Success: false, Error: "事件仓库未初始化"}
+	}
+	record, err := a.eventRepo.GetScreenshot(id)
+	if err != nil {
+		a.log.Err(err, "获取截图失败", "id", id)
+		return ScreenshotResult{Success: false, Error: err.Error()}
+	}
+	return ScreenshotResult{
+		ID:        record.ID,
+		TargetID:  record.TargetID,
+		MimeType:  record.MimeType,
+		Data:      base64.StdEncoding.EncodeToString(record.Data),
+		Timestamp: record.Timestamp,
+		Success:   true,
+	}
+}
+
+// maxExportEvents 单次导出的事件数量上限
+const maxExportEvents = 100000
+
+// ExportEventHistory 按查询条件导出匹配事件为 CSV 或 XLSX 表格，columns 为空时导出默认列。
+func (a *App) ExportEventHistory(format string, columns []string, sessionID, finalResult, url, method string, startTime, endTime int64) OperationResult {
+	if a.eventRepo == nil {
+		return OperationResult{Success: false, Error: "事件仓库未初始化"}
+	}
+	format = strings.ToLower(format)
+	if format != "csv" && format != "xlsx" {
+		return OperationResult{Success: false, Error: "不支持的导出格式: " + format}
+	}
+
+	records, err := a.eventRepo.QueryAll(storage.QueryOptions{
+		SessionID:   sessionID,
+		FinalResult: finalResult,
+		URL:         url,
+		Method:      method,
+		StartTime:   startTime,
+		EndTime:     endTime,
+	}, maxExportEvents)
+	if err != nil {
+		a.log.Err(err, "查询导出事件失败")
+		return OperationResult{Success: false, Error: err.Error()}
+	}
+	table, err := export.EventTable(records, columns)
+	if err != nil {
+		return OperationResult{Success: false, Error: err.Error()}
+	}
+
+	path, err := runtime.SaveFileDialog(a.ctx, runtime.SaveDialogOptions{
+		DefaultFilename: "events-" + time.Now().Format("20060102-150405") + "." + format,
+		Title:           "导出事件",
+		Filters: []runtime.FileFilter{
+			{DisplayName: strings.ToUpper(format) + " Files (*." + format + ")", Pattern: "*." + format},
+		},
+	})
+	if err != nil {
+		return OperationResult{Success: false, Error: err.Error()}
+	}
+	if path == "" {
+		return OperationResult{Success: true} // 用户取消
+	}
+
+	f, err := os.Create(path)
+	if err != nil {
+		return OperationResult{Success: false, Error: "文件写入失败: " + err.Error()}
+	}
+	if format == "csv" {
+		err = export.WriteCSV(f, table)
+	} else {
+		err = export.WriteXLSX(f, table)
+	}
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		a.log.Err(err, "导出事件失败", "path", path)
+		return OperationResult{Success: false, Error: "文件写入失败: " + err.Error()}
+	}
+
+	a.log.Info("事件已导出", "path", path, "format", format, "count", len(table.Rows))
+	return OperationResult{Success: true}
+}
+
+// ImportCaptureResult 导入抓包文件的结果
+type ImportCaptureResult struct {
+	SessionID string `json:"sessionId"` // 导入记录所属的会话 ID，用于查询、统计和导出
+	Count     int    `json:"count"`     // 导入的请求数
+	Success   bool   `json:"success"`
+	Error     string `json:"error,omitempty"`
+}
+
+// ImportPcap 选择 pcap/pcapng 抓包文件，将其中的明文 HTTP 请求导入事件历史用于离线分析；
+// 导入的记录归属独立的只读会话，HTTPS 等加密流量无法还原
+func (a *App) ImportPcap() ImportCaptureResult {
+	if a.eventRepo == nil {
+		return ImportCaptureResult{Success: false, Error: "事件仓库未初始化"}
+	}
+	path, err := runtime.OpenFileDialog(a.ctx, runtime.OpenDialogOptions{
+		Title: "导入抓包文件",
+		Filters: []runtime.FileFilter{
+			{DisplayName: "Packet Captures (*.pcap;*.pcapng)", Pattern: "*.pcap;*.pcapng;*.cap"},
+		},
+	})
+	if err != nil {
+		return ImportCaptureResult{Success: false, Error: err.Error()}
+	}
+	if path == "" {
+		return ImportCaptureResult{Success: true} // 用户取消
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return ImportCaptureResult{Success: false, Error: "文件读取失败: " + err.Error()}
+	}
+	exchanges, err := pcap.Read(f)
+	f.Close()
+	if err != nil {
+		a.log.Err(err, "解析抓包文件失败", "path", path)
+		return ImportCaptureResult{Success: false, Error: err.Error()}
+	}
+	if len(exchanges) == 0 {
+		return ImportCaptureResult{Success: false, Error: "抓包中没有可识别的明文 HTTP 请求"}
+	}
+
+	sessionID := model.SessionID("pcap-" + time.Now().Format("20060102-150405"))
+	events := pcap.Events(exchanges, sessionID, model.TargetID(filepath.Base(path)))
+	if err := a.eventRepo.ImportEvents(events); err != nil {
+		a.log.Err(err, "导入抓包事件失败", "path", path)
+		return ImportCaptureResult{Success: false, Error: err.Error()}
+	}
+
+	a.recordSession(sessionID)
+	a.log.Info("抓包已导入", "path", path, "session", string(sessionID), "count", len(events))
+	return ImportCaptureResult{SessionID: string(sessionID), Count: len(events), Success: true}
+}
+
 // CleanupEventHistory 清理指定天数之前的旧事件记录。
 func (a *App) CleanupEventHistory(retentionDays int) OperationResult {
 	if a.eventRepo == nil {
@@ -696,3 +2167,95 @@ func (a *App) CleanupEventHistory(retentionDays int) OperationResult {
 	a.log.Info("已清理旧事件", "retentionDays", retentionDays, "deletedCount", deleted)
 	return OperationResult{Success: true}
 }
+
+// DBMaintenanceResult 表示数据库维护操作的结果。
+type DBMaintenanceResult struct {
+	Report  *storage.MaintenanceReport `json:"report,omitempty"`
+	Success bool                       `json:"success"`
+	Error   string                     `json:"error,omitempty"`
+}
+
+// DBMaintenanceProgress 表示推送给前端的维护进度。
+type DBMaintenanceProgress struct {
+	Operation string `json:"operation"`
+	Step      string `json:"step"`
+	Percent   int    `json:"percent"`
+}
+
+// VacuumDatabase 整理数据库文件，回收碎片和 WAL 占用的空间，进度通过 db-maintenance-progress 事件推送。
+func (a *App) VacuumDatabase() DBMaintenanceResult {
+	return a.runDBMaintenance(storage.MaintenanceVacuum, a.db.Vacuum)
+}
+
+// AnalyzeDatabase 更新数据库查询统计信息。
+func (a *App) AnalyzeDatabase() DBMaintenanceResult {
+	return a.runDBMaintenance(storage.MaintenanceAnalyze, a.db.Analyze)
+}
+
+// CheckDatabaseIntegrity 检查数据库完整性，发现的问题在结果的 problems 中返回。
+func (a *App) CheckDatabaseIntegrity() DBMaintenanceResult {
+	return a.runDBMaintenance(storage.MaintenanceIntegrity, a.db.IntegrityCheck)
+}
+
+// SetAutoVacuum 设置自动整理数据库的间隔天数，0 表示关闭。
+func (a *App) SetAutoVacuum(intervalDays int) OperationResult {
+	if intervalDays < 0 {
+		return OperationResult{Success: false, Error: "间隔天数不能为负数"}
+	}
+	if err := a.settingsRepo.Set(storage.SettingKeyAutoVacuumDays, fmt.Sprintf("%d", intervalDays)); err != nil {
+		a.log.Err(err, "保存自动整理设置失败")
+		return OperationResult{Success: false, Error: err.Error()}
+	}
+	a.log.Info("自动整理数据库设置已更新", "intervalDays", intervalDays)
+	return OperationResult{Success: true}
+}
+
+// runDBMaintenance 执行维护操作并推送进度
+func (a *App) runDBMaintenance(op string, fn func(storage.MaintenanceProgress) (*storage.MaintenanceReport, error)) DBMaintenanceResult {
+	if a.db == nil {
+		return DBMaintenanceResult{Success: false, Error: "数据库未初始化"}
+	}
+
+	report, err := fn(func(step string, percent int) {
+		runtime.EventsEmit(a.ctx, "db-maintenance-progress", DBMaintenanceProgress{Operation: op, Step: step, Percent: percent})
+	})
+	if err != nil {
+		a.log.Err(err, "数据库维护失败", "operation", op)
+		return DBMaintenanceResult{Success: false, Error: err.Error()}
+	}
+
+	if op == storage.MaintenanceVacuum {
+		_ = a.settingsRepo.Set(storage.SettingKeyLastVacuumAt, fmt.Sprintf("%d", time.Now().UnixMilli()))
+	}
+	a.log.Info("数据库维护完成", "operation", op, "sizeBefore", report.SizeBefore, "sizeAfter", report.SizeAfter,
+		"durationMs", report.DurationMS, "problems", len(report.Problems))
+	return DBMaintenanceResult{Report: report, Success: true}
+}
+
+// autoVacuumLoop 定期检查是否到达自动整理时间
+func (a *App) autoVacuumLoop() {
+	ticker := time.NewTicker(time.Hour)
+	defer ticker.Stop()
+	for {
+		a.autoVacuumIfDue()
+		select {
+		case <-a.ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// autoVacuumIfDue 距上次整理超过设置的间隔时执行整理
+func (a *App) autoVacuumIfDue() {
+	days, err := strconv.Atoi(a.settingsRepo.GetWithDefault(storage.SettingKeyAutoVacuumDays, "0"))
+	if err != nil || days <= 0 {
+		return
+	}
+	last, _ := strconv.ParseInt(a.settingsRepo.GetWithDefault(storage.SettingKeyLastVacuumAt, "0"), 10, 64)
+	if time.Since(time.UnixMilli(last)) < time.Duration(days)*24*time.Hour {
+		return
+	}
+	a.log.Info("开始自动整理数据库", "intervalDays", days)
+	a.runDBMaintenance(storage.MaintenanceVacuum, a.db.Vacuum)
+}
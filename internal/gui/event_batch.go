@@ -0,0 +1,142 @@
+package gui
+
+import (
+	"strconv"
+	"sync"
+	"time"
+
+	"cdpnetool/internal/storage"
+	"cdpnetool/pkg/model"
+)
+
+// 事件批量推送的默认节奏
+const (
+	defaultEventFlushMS   = 100 // 推送间隔毫秒数
+	defaultEventBatchSize = 50  // 单批事件数，达到后立即推送
+	eventBacklogBatches   = 20  // 待推送事件的积压上限（按批数计），超过后丢弃新事件（重点关注接口的事件除外）
+)
+
+// EventBatch 批量推送到前端的拦截事件
+type EventBatch struct {
+	Events  []model.InterceptEvent `json:"events"`
+	Dropped int                    `json:"dropped"` // 自上一批以来因推送积压而丢弃的事件数，丢弃的匹配事件仍会写入数据库
+}
+
+// eventBatcher 合并高频拦截事件，按间隔或数量批量推送，避免逐条推送占满前端桥接
+type eventBatcher struct {
+	interval time.Duration
+	size     int
+	emit     func(EventBatch)
+
+	mu      sync.Mutex
+	pending []model.InterceptEvent
+	dropped int
+	full    chan struct{}
+	done    chan struct{}
+	wg      sync.WaitGroup
+}
+
+// newEventBatcher 创建并启动批量推送，interval 或 size 不大于 0 时使用默认值
+func newEventBatcher(interval time.Duration, size int, emit func(EventBatch)) *eventBatcher {
+	if interval <= 0 {
+		interval = defaultEventFlushMS * time.Millisecond
+	}
+	if size <= 0 {
+		size = defaultEventBatchSize
+	}
+	b := &eventBatcher{
+		interval: interval,
+		size:     size,
+		emit:     emit,
+		full:     make(chan struct{}, 1),
+		done:     make(chan struct{}),
+	}
+	b.wg.Add(1)
+	go b.loop()
+	return b
+}
+
+// add 加入一个待推送事件，积压超过上限时丢弃并计数；keep 为 true 的事件始终保留
+func (b *eventBatcher) add(evt model.InterceptEvent, keep bool) {
+	b.mu.Lock()
+	if len(b.pending) >= b.size*eventBacklogBatches && !keep {
+		b.dropped++
+		b.mu.Unlock()
+		return
+	}
+	b.pending = append(b.pending, evt)
+	reached := len(b.pending) >= b.size
+	b.mu.Unlock()
+
+	if reached {
+		select {
+		case b.full <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// loop 按间隔或批满时推送
+func (b *eventBatcher) loop() {
+	defer b.wg.Done()
+	ticker := time.NewTicker(b.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+		case <-b.full:
+		case <-b.done:
+			b.flush()
+			return
+		}
+		b.flush()
+	}
+}
+
+// flush 按批大小推送全部待推送事件
+func (b *eventBatcher) flush() {
+	for {
+		b.mu.Lock()
+		n := min(len(b.pending), b.size)
+		if n == 0 && b.dropped == 0 {
+			b.mu.Unlock()
+			return
+		}
+		batch := EventBatch{Events: make([]model.InterceptEvent, n), Dropped: b.dropped}
+		copy(batch.Events, b.pending)
+		b.pending = b.pending[n:]
+		b.dropped = 0
+		b.mu.Unlock()
+
+		b.emit(batch)
+	}
+}
+
+// close 推送剩余事件并停止
+func (b *eventBatcher) close() {
+	close(b.done)
+	b.wg.Wait()
+}
+
+// eventBatchOptions 读取事件推送节奏设置
+func (a *App) eventBatchOptions() (time.Duration, int) {
+	interval, size := defaultEventFlushMS, defaultEventBatchSize
+	if a.settingsRepo == nil {
+		return time.Duration(interval) * time.Millisecond, size
+	}
+	if raw := a.settingsRepo.GetWithDefault(storage.SettingKeyEventFlushMS, ""); raw != "" {
+		if n, err := strconv.Atoi(raw); err == nil && n > 0 {
+			interval = n
+		} else {
+			a.log.Warn("解析事件推送间隔失败", "value", raw)
+		}
+	}
+	if raw := a.settingsRepo.GetWithDefault(storage.SettingKeyEventBatchSize, ""); raw != "" {
+		if n, err := strconv.Atoi(raw); err == nil && n > 0 {
+			size = n
+		} else {
+			a.log.Warn("解析事件批大小失败", "value", raw)
+		}
+	}
+	return time.Duration(interval) * time.Millisecond, size
+}
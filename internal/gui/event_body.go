@@ -0,0 +1,161 @@
+package gui
+
+import (
+	"strconv"
+	"sync"
+	"unicode/utf8"
+
+	"cdpnetool/internal/storage"
+	"cdpnetool/pkg/model"
+)
+
+const (
+	defaultEventBodyLimit = 256 << 10 // 推送到前端的单个 Body 默认上限（字节）
+	fullBodyCacheBytes    = 64 << 20  // 保留的完整 Body 总量上限，超出后淘汰最早的记录
+)
+
+// fullBody 推送时被截断的事件的完整 Body
+type fullBody struct {
+	request          string
+	requestEncoding  string
+	response         string
+	responseEncoding string
+}
+
+// size 返回占用的字节数
+func (b fullBody) size() int {
+	return len(b.request) + len(b.response)
+}
+
+// bodyStore 按引用保存被截断事件的完整 Body，总量超过上限时淘汰最早的记录
+type bodyStore struct {
+	mu     sync.Mutex
+	next   uint64
+	bodies map[string]fullBody
+	order  []string
+	total  int
+}
+
+// put 保存完整 Body 并返回引用
+func (s *bodyStore) put(b fullBody) string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.bodies == nil {
+		s.bodies = make(map[string]fullBody)
+	}
+	s.next++
+	ref := strconv.FormatUint(s.next, 10)
+	s.bodies[ref] = b
+	s.order = append(s.order, ref)
+	s.total += b.size()
+	for s.total > fullBodyCacheBytes && len(s.order) > 1 {
+		old := s.order[0]
+		s.order = s.order[1:]
+		s.total -= s.bodies[old].size()
+		delete(s.bodies, old)
+	}
+	return ref
+}
+
+// get 按引用读取完整 Body
+func (s *bodyStore) get(ref string) (fullBody, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	b, ok := s.bodies[ref]
+	return b, ok
+}
+
+// trimEventBodies 返回推送到前端的事件副本：请求体或响应体超过 limit 时截断并标记 isTruncated，
+// 完整 Body 保存在 bodies 中，通过 bodyRef 获取；limit 不大于 0 时不截断
+func trimEventBodies(evt model.InterceptEvent, limit int, bodies *bodyStore) model.InterceptEvent {
+	if limit <= 0 {
+		return evt
+	}
+	var e *model.NetworkEvent
+	switch {
+	case evt.Matched != nil:
+		if len(evt.Matched.Request.Body) <= limit && len(evt.Matched.Response.Body) <= limit {
+			return evt
+		}
+		matched := *evt.Matched
+		evt.Matched = &matched
+		e = &matched.NetworkEvent
+	case evt.Unmatched != nil:
+		if len(evt.Unmatched.Request.Body) <= limit && len(evt.Unmatched.Response.Body) <= limit {
+			return evt
+		}
+		unmatched := *evt.Unmatched
+		evt.Unmatched = &unmatched
+		e = &unmatched.NetworkEvent
+	default:
+		return evt
+	}
+
+	e.BodyRef = bodies.put(fullBody{
+		request:          e.Request.Body,
+		requestEncoding:  e.Request.BodyEncoding,
+		response:         e.Response.Body,
+		responseEncoding: e.Response.BodyEncoding,
+	})
+	e.IsTruncated = true
+	e.Request.Body = truncateBody(e.Request.Body, e.Request.BodyEncoding, limit)
+	e.Response.Body = truncateBody(e.Response.Body, e.Response.BodyEncoding, limit)
+	return evt
+}
+
+// truncateBody 截断到不超过 limit 字节：base64 按 4 字节对齐以保持可解码，文本不截断在多字节字符中间
+func truncateBody(body, encoding string, limit int) string {
+	if len(body) <= limit {
+		return body
+	}
+	if encoding == model.BodyEncodingBase64 {
+		return body[:limit-limit%4]
+	}
+	n := limit
+	for n > 0 && !utf8.RuneStart(body[n]) {
+		n--
+	}
+	return body[:n]
+}
+
+// eventBodyLimit 读取推送到前端的单个 Body 上限设置
+func (a *App) eventBodyLimit() int {
+	if a.settingsRepo == nil {
+		return defaultEventBodyLimit
+	}
+	raw := a.settingsRepo.GetWithDefault(storage.SettingKeyEventBodyLimit, "")
+	if raw == "" {
+		return defaultEventBodyLimit
+	}
+	n, err := strconv.Atoi(raw)
+	if err != nil {
+		a.log.Warn("解析事件 Body 推送上限失败", "value", raw)
+		return defaultEventBodyLimit
+	}
+	return n
+}
+
+// FullBodyResult 表示事件完整 Body 的查询结果。
+type FullBodyResult struct {
+	RequestBody          string `json:"requestBody"`
+	RequestBodyEncoding  string `json:"requestBodyEncoding,omitempty"`
+	ResponseBody         string `json:"responseBody"`
+	ResponseBodyEncoding string `json:"responseBodyEncoding,omitempty"`
+	Success              bool   `json:"success"`
+	Error                string `json:"error,omitempty"`
+}
+
+// FetchFullBody 按事件的 bodyRef 获取推送时被截断的完整请求体和响应体，在打开事件详情时调用。
+func (a *App) FetchFullBody(eventRef string) FullBodyResult {
+	b, ok := a.bodies.get(eventRef)
+	if !ok {
+		return FullBodyResult{Success: false, Error: "完整 Body 已过期或不存在，匹配的事件可在事件历史中查看"}
+	}
+	return FullBodyResult{
+		RequestBody:          b.request,
+		RequestBodyEncoding:  b.requestEncoding,
+		ResponseBody:         b.response,
+		ResponseBodyEncoding: b.responseEncoding,
+		Success:              true,
+	}
+}
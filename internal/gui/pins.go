@@ -0,0 +1,89 @@
+package gui
+
+import (
+	"sync"
+	"time"
+
+	"cdpnetool/pkg/model"
+)
+
+// PinnedEndpoint 重点关注的接口及其专用计数，计数自固定或应用启动起累计
+type PinnedEndpoint struct {
+	Pattern  string `json:"pattern"`            // URL 模式，* 匹配任意字符
+	Total    int64  `json:"total"`              // 事件数
+	Matched  int64  `json:"matched"`            // 命中规则的事件数
+	Blocked  int64  `json:"blocked"`            // 被拦截或屏蔽的事件数
+	LastSeen int64  `json:"lastSeen,omitempty"` // 最近一次事件的时间（毫秒时间戳）
+}
+
+// pinTracker 维护重点关注的接口与计数
+type pinTracker struct {
+	mu   sync.Mutex
+	pins []*PinnedEndpoint
+}
+
+// set 替换关注的接口列表，保留仍在列表中的接口的计数
+func (t *pinTracker) set(patterns []string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	old := make(map[string]*PinnedEndpoint, len(t.pins))
+	for _, p := range t.pins {
+		old[p.Pattern] = p
+	}
+	t.pins = t.pins[:0:0]
+	for _, pattern := range patterns {
+		if p, ok := old[pattern]; ok {
+			t.pins = append(t.pins, p)
+			continue
+		}
+		t.pins = append(t.pins, &PinnedEndpoint{Pattern: pattern})
+	}
+}
+
+// patterns 返回关注的接口模式
+func (t *pinTracker) patterns() model.EndpointPins {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	out := make(model.EndpointPins, 0, len(t.pins))
+	for _, p := range t.pins {
+		out = append(out, p.Pattern)
+	}
+	return out
+}
+
+// record 为事件命中的每个接口计数，返回事件是否属于关注的接口
+func (t *pinTracker) record(evt model.InterceptEvent) bool {
+	url := evt.RequestURL()
+	if url == "" {
+		return false
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	pinned := false
+	for _, p := range t.pins {
+		if !model.MatchURLPattern(p.Pattern, url) {
+			continue
+		}
+		pinned = true
+		p.Total++
+		p.LastSeen = time.Now().UnixMilli()
+		if evt.IsMatched {
+			p.Matched++
+		}
+		if (evt.Matched != nil && evt.Matched.FinalResult == "blocked") || (evt.Unmatched != nil && evt.Unmatched.FinalResult == "blocked") {
+			p.Blocked++
+		}
+	}
+	return pinned
+}
+
+// snapshot 返回关注的接口及计数的副本
+func (t *pinTracker) snapshot() []PinnedEndpoint {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	out := make([]PinnedEndpoint, 0, len(t.pins))
+	for _, p := range t.pins {
+		out = append(out, *p)
+	}
+	return out
+}
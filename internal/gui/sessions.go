@@ -0,0 +1,135 @@
+package gui
+
+import (
+	"encoding/json"
+	"errors"
+
+	"cdpnetool/internal/storage"
+	"cdpnetool/pkg/model"
+
+	"gorm.io/gorm"
+)
+
+// recordSession 在会话记录表中登记会话，便于之后命名和在事件历史中筛选
+func (a *App) recordSession(sid model.SessionID) {
+	if a.sessionRepo == nil {
+		return
+	}
+	if err := a.sessionRepo.Ensure(string(sid)); err != nil {
+		a.log.Warn("登记会话记录失败", "sessionID", sid, "error", err)
+	}
+}
+
+// parseSessionMeta 解析前端传入的会话元数据 JSON 对象，空字符串表示没有元数据
+func parseSessionMeta(metadataJSON string) (map[string]string, error) {
+	if metadataJSON == "" {
+		return nil, nil
+	}
+	var meta map[string]string
+	if err := json.Unmarshal([]byte(metadataJSON), &meta); err != nil {
+		return nil, errors.New("JSON 解析失败: " + err.Error())
+	}
+	return meta, nil
+}
+
+// SessionLabelResult 表示会话名称与元数据的查询或修改结果。
+type SessionLabelResult struct {
+	Session *storage.SessionRecord `json:"session,omitempty"`
+	Success bool                   `json:"success"`
+	Error   string                 `json:"error,omitempty"`
+}
+
+// SetSessionLabel 为会话命名并设置元数据（JSON 对象，键和值均为字符串），
+// 正在进行和已结束的会话（包括导入的抓包）均可修改，事件历史按会话关联，修改后对已记录的事件同样生效。
+func (a *App) SetSessionLabel(sessionID, label, metadataJSON string) SessionLabelResult {
+	if a.sessionRepo == nil {
+		return SessionLabelResult{Success: false, Error: "会话仓库未初始化"}
+	}
+	if sessionID == "" {
+		return SessionLabelResult{Success: false, Error: "sessionId 不能为空"}
+	}
+	meta, err := parseSessionMeta(metadataJSON)
+	if err != nil {
+		return SessionLabelResult{Success: false, Error: err.Error()}
+	}
+
+	record, err := a.sessionRepo.SetLabel(sessionID, label, meta)
+	if err != nil {
+		a.log.Err(err, "保存会话名称失败", "sessionID", sessionID)
+		return SessionLabelResult{Success: false, Error: err.Error()}
+	}
+	a.log.Info("会话名称已更新", "sessionID", sessionID, "label", record.Label, "metaKeys", len(meta))
+	return SessionLabelResult{Session: record, Success: true}
+}
+
+// GetSessionLabel 获取会话的名称与元数据，会话未命名时返回空记录。
+func (a *App) GetSessionLabel(sessionID string) SessionLabelResult {
+	if a.sessionRepo == nil {
+		return SessionLabelResult{Success: false, Error: "会话仓库未初始化"}
+	}
+	record, err := a.sessionRepo.Get(sessionID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return SessionLabelResult{Session: &storage.SessionRecord{SessionID: sessionID}, Success: true}
+	}
+	if err != nil {
+		a.log.Err(err, "查询会话名称失败", "sessionID", sessionID)
+		return SessionLabelResult{Success: false, Error: err.Error()}
+	}
+	return SessionLabelResult{Session: record, Success: true}
+}
+
+// SessionListResult 表示会话记录列表。
+type SessionListResult struct {
+	Sessions []storage.SessionRecord `json:"sessions"`
+	Success  bool                    `json:"success"`
+	Error    string                  `json:"error,omitempty"`
+}
+
+// ListSessions 列出已记录的会话及其名称与元数据，最近开始的排在前面，用于事件历史的会话筛选。
+func (a *App) ListSessions() SessionListResult {
+	if a.sessionRepo == nil {
+		return SessionListResult{Success: false, Error: "会话仓库未初始化"}
+	}
+	sessions, err := a.sessionRepo.List()
+	if err != nil {
+		a.log.Err(err, "查询会话列表失败")
+		return SessionListResult{Success: false, Error: err.Error()}
+	}
+	return SessionListResult{Sessions: sessions, Success: true}
+}
+
+// QueryLabeledEventHistory 按会话名称（包含匹配）和元数据（JSON 对象，所有键值都相等）筛选匹配事件历史，
+// 其余条件与 QueryMatchedEventHistory 相同，用于跨会话查找某一轮测试的记录。
+func (a *App) QueryLabeledEventHistory(label, metadataJSON, finalResult, url, method string, startTime, endTime int64, offset, limit int) MatchedEventHistoryResult {
+	if a.eventRepo == nil {
+		a.log.Error("查询事件历史失败: 事件仓库未初始化")
+		return MatchedEventHistoryResult{Success: false, Error: "事件仓库未初始化"}
+	}
+	meta, err := parseSessionMeta(metadataJSON)
+	if err != nil {
+		return MatchedEventHistoryResult{Success: false, Error: err.Error()}
+	}
+	if err := storage.ValidateSessionLabel(label, meta); err != nil {
+		return MatchedEventHistoryResult{Success: false, Error: err.Error()}
+	}
+	if label == "" && len(meta) == 0 {
+		return MatchedEventHistoryResult{Success: false, Error: "会话名称和元数据不能都为空"}
+	}
+
+	events, total, err := a.eventRepo.Query(storage.QueryOptions{
+		SessionLabel: label,
+		SessionMeta:  meta,
+		FinalResult:  finalResult,
+		URL:          url,
+		Method:       method,
+		StartTime:    startTime,
+		EndTime:      endTime,
+		Offset:       offset,
+		Limit:        limit,
+	})
+	if err != nil {
+		a.log.Err(err, "查询事件历史失败", "label", label)
+		return MatchedEventHistoryResult{Success: false, Error: err.Error()}
+	}
+	return MatchedEventHistoryResult{Events: events, Total: total, Success: true}
+}
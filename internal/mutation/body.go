@@ -0,0 +1,209 @@
+package mutation
+
+import (
+	"bytes"
+	"encoding/base64"
+	"net/url"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/tidwall/sjson"
+
+	"cdpnetool/pkg/model"
+	"cdpnetool/pkg/rulespec"
+)
+
+// BodyContent 二进制安全的 Body 内容
+type BodyContent struct {
+	Data     []byte
+	IsBinary bool // 非文本内容：文本类改写行为不作用于它，事件中以 Base64 记录
+	Unread   bool // 响应体未读取（超过阈值或读取计划跳过）：依赖原始内容的行为跳过，避免以空内容覆盖真实响应体
+}
+
+// NewBodyContent 根据内容和 Content-Type 构建 Body
+func NewBodyContent(data []byte, contentType string) BodyContent {
+	return BodyContent{Data: data, IsBinary: len(data) > 0 && !IsTextualBody(data, contentType)}
+}
+
+// Text 以字符串形式返回 Body
+func (b BodyContent) Text() string {
+	return string(b.Data)
+}
+
+// EventBody 返回事件中记录的 Body 及其编码，二进制内容使用 Base64
+func (b BodyContent) EventBody() (string, string) {
+	if b.IsBinary {
+		return base64.StdEncoding.EncodeToString(b.Data), model.BodyEncodingBase64
+	}
+	return string(b.Data), ""
+}
+
+// IsTextualBody 判断 Body 是否为文本类型，以便安全展示或匹配
+func IsTextualBody(data []byte, contentType string) bool {
+	lc := strings.ToLower(contentType)
+	// 常见的文本类型
+	if strings.HasPrefix(lc, "text/") ||
+		strings.Contains(lc, "json") ||
+		strings.Contains(lc, "xml") ||
+		strings.Contains(lc, "javascript") ||
+		strings.Contains(lc, "x-www-form-urlencoded") {
+		return true
+	}
+
+	// 启发式检测：如果是有效的 UTF-8 且不包含过多的控制字符
+	return utf8.Valid(data)
+}
+
+// IsBodyAction 判断行为是否改写 Body
+func IsBodyAction(t rulespec.ActionType) bool {
+	switch t {
+	case rulespec.ActionSetBody, rulespec.ActionReplaceBodyText, rulespec.ActionPatchBodyJson,
+		rulespec.ActionSetFormField, rulespec.ActionRemoveFormField:
+		return true
+	}
+	return false
+}
+
+// transformBody 对当前 Body 执行一个改写行为，返回新 Body；无法执行时返回跳过原因。
+// contentType 为请求的 Content-Type，表单字段行为据此判断表单格式
+func transformBody(action *rulespec.Action, body BodyContent, contentType string) (BodyContent, string) {
+	if action.Type == rulespec.ActionSetBody {
+		v, ok := action.Value.(string)
+		if !ok {
+			return body, "value 不是字符串"
+		}
+		return decodeSetBody(v, action.GetEncoding()), ""
+	}
+
+	// 其余均为文本类改写
+	if body.Unread {
+		return body, "未读取响应体"
+	}
+	if body.IsBinary {
+		return body, "二进制 Body 不支持文本改写"
+	}
+	text := body.Text()
+	switch action.Type {
+	case rulespec.ActionReplaceBodyText:
+		if action.ReplaceAll {
+			text = strings.ReplaceAll(text, action.Search, action.Replace)
+		} else {
+			text = strings.Replace(text, action.Search, action.Replace, 1)
+		}
+	case rulespec.ActionPatchBodyJson:
+		patched, ok := applyJSONPatches(text, action.Patches)
+		if !ok {
+			return body, "JSON Patch 应用失败"
+		}
+		text = patched
+	case rulespec.ActionSetFormField:
+		v, ok := action.Value.(string)
+		if !ok {
+			return body, "value 不是字符串"
+		}
+		text = setFormField(text, action.Name, v, contentType)
+	case rulespec.ActionRemoveFormField:
+		text = removeFormField(text, action.Name, contentType)
+	}
+	return BodyContent{Data: []byte(text)}, ""
+}
+
+// newBodyStep 构建一次 Body 变换的记录，规则信息由调用方填充
+func newBodyStep(action rulespec.ActionType, before, after BodyContent, skipped string) model.BodyTransform {
+	return model.BodyTransform{
+		Action:     string(action),
+		Changed:    skipped == "" && !bytes.Equal(before.Data, after.Data),
+		Skipped:    skipped,
+		SizeBefore: len(before.Data),
+		SizeAfter:  len(after.Data),
+	}
+}
+
+// decodeSetBody 解码 setBody 的值，Base64 解码失败时按原文处理
+func decodeSetBody(v string, encoding rulespec.BodyEncoding) BodyContent {
+	if encoding == rulespec.BodyEncodingBase64 {
+		if decoded, err := base64.StdEncoding.DecodeString(v); err == nil {
+			return NewBodyContent(decoded, "")
+		}
+	}
+	return BodyContent{Data: []byte(v)}
+}
+
+// applyJSONPatches 应用 JSON Patch 操作，使用 sjson 实现高性能修改
+func applyJSONPatches(body string, patches []rulespec.JSONPatchOp) (string, bool) {
+	if body == "" || len(patches) == 0 {
+		return body, false
+	}
+
+	currentBody := body
+	modified := false
+
+	for _, patch := range patches {
+		if patch.Path == "" {
+			continue
+		}
+
+		// 将 JSON Patch 路径 (/a/b/c) 转换为 sjson 路径 (a.b.c)
+		path := patch.Path
+		path = strings.TrimPrefix(path, "/")
+		path = strings.ReplaceAll(path, "/", ".")
+
+		var err error
+		switch patch.Op {
+		case "add", "replace":
+			currentBody, err = sjson.Set(currentBody, path, patch.Value)
+			if err == nil {
+				modified = true
+			}
+		case "remove":
+			currentBody, err = sjson.Delete(currentBody, path)
+			if err == nil {
+				modified = true
+			}
+		}
+	}
+
+	return currentBody, modified
+}
+
+// setFormField 设置表单字段
+func setFormField(body, name, value, contentType string) string {
+	if strings.Contains(contentType, "application/x-www-form-urlencoded") {
+		return setURLEncodedField(body, name, value)
+	}
+
+	if strings.Contains(contentType, "multipart/form-data") {
+		// TODO: 实现 multipart 表单修改
+		return body
+	}
+
+	return body
+}
+
+// removeFormField 移除表单字段
+func removeFormField(body, name, contentType string) string {
+	if strings.Contains(contentType, "application/x-www-form-urlencoded") {
+		return removeURLEncodedField(body, name)
+	}
+
+	if strings.Contains(contentType, "multipart/form-data") {
+		// TODO: 实现 multipart 表单修改
+		return body
+	}
+
+	return body
+}
+
+// setURLEncodedField 设置 URL 编码表单字段
+func setURLEncodedField(body, name, value string) string {
+	values, _ := url.ParseQuery(body)
+	values.Set(name, value)
+	return values.Encode()
+}
+
+// removeURLEncodedField 移除 URL 编码表单字段
+func removeURLEncodedField(body, name string) string {
+	values, _ := url.ParseQuery(body)
+	values.Del(name)
+	return values.Encode()
+}
@@ -0,0 +1,152 @@
+package mutation
+
+import (
+	"net/url"
+	"strings"
+)
+
+// BuildURL 构建最终 URL，URL 与 Query 均未修改时返回 nil
+func BuildURL(originalURL string, mut *RequestMutation) *string {
+	if mut.URL == nil && len(mut.Query) == 0 && len(mut.RemoveQuery) == 0 {
+		return nil
+	}
+
+	baseURL := originalURL
+	if mut.URL != nil {
+		baseURL = *mut.URL
+	}
+
+	// 如果没有 Query 修改，直接返回
+	if len(mut.Query) == 0 && len(mut.RemoveQuery) == 0 {
+		return &baseURL
+	}
+
+	// 解析并修改 Query
+	u, err := url.Parse(baseURL)
+	if err != nil {
+		return &baseURL
+	}
+
+	q := u.Query()
+	// 移除参数
+	for _, name := range mut.RemoveQuery {
+		q.Del(name)
+	}
+	// 设置参数
+	for name, value := range mut.Query {
+		q.Set(name, value)
+	}
+	u.RawQuery = q.Encode()
+
+	result := u.String()
+	return &result
+}
+
+// BuildRequestHeaders 在原始请求头上应用修改，返回最终请求头
+func BuildRequestHeaders(original map[string]string, mut *RequestMutation) map[string]string {
+	headers := make(map[string]string, len(original))
+	for k, v := range original {
+		headers[k] = v
+	}
+
+	// 1. 移除头部
+	for _, name := range mut.RemoveHeaders {
+		delete(headers, name)
+		// 不区分大小写删除
+		for k := range headers {
+			if strings.EqualFold(k, name) {
+				delete(headers, k)
+			}
+		}
+	}
+
+	// 2. 设置头部
+	for name, value := range mut.Headers {
+		headers[name] = value
+	}
+	for name, value := range mut.SecretHeaders {
+		for k := range headers {
+			if strings.EqualFold(k, name) {
+				delete(headers, k)
+			}
+		}
+		headers[name] = value
+	}
+
+	// 3. 处理 Cookie 修改
+	if len(mut.Cookies) > 0 || len(mut.RemoveCookies) > 0 {
+		cookieStr := ""
+		for k, v := range headers {
+			if strings.EqualFold(k, "cookie") {
+				cookieStr = v
+				break
+			}
+		}
+		if cookie := RewriteCookie(cookieStr, mut); cookie != "" {
+			headers["Cookie"] = cookie
+		} else {
+			delete(headers, "Cookie")
+			delete(headers, "cookie")
+		}
+	}
+
+	return headers
+}
+
+// BuildResponseHeaders 在原始响应头上应用修改，返回最终响应头；同名的原始头部只保留最后一个
+func BuildResponseHeaders(original []Header, mut *ResponseMutation) map[string]string {
+	headers := make(map[string]string, len(original))
+	for _, h := range original {
+		headers[h.Name] = h.Value
+	}
+
+	// 移除头部
+	for _, name := range mut.RemoveHeaders {
+		delete(headers, name)
+		for k := range headers {
+			if strings.EqualFold(k, name) {
+				delete(headers, k)
+			}
+		}
+	}
+
+	// 设置头部
+	for name, value := range mut.Headers {
+		headers[name] = value
+	}
+
+	return headers
+}
+
+// RewriteCookie 在原始 Cookie 头上应用 Cookie 修改，返回新的 Cookie 头，为空表示不再携带 Cookie
+func RewriteCookie(cookie string, mut *RequestMutation) string {
+	cookies := ParseCookie(cookie)
+	// 移除 Cookie
+	for _, name := range mut.RemoveCookies {
+		delete(cookies, name)
+	}
+	// 设置 Cookie
+	for name, value := range mut.Cookies {
+		cookies[name] = value
+	}
+
+	// 重新构建 Cookie 字符串
+	var parts []string
+	for k, v := range cookies {
+		parts = append(parts, k+"="+v)
+	}
+	return strings.Join(parts, "; ")
+}
+
+// ParseCookie 解析 Cookie 头为键值对映射
+func ParseCookie(s string) map[string]string {
+	out := make(map[string]string)
+	parts := strings.Split(s, ";")
+	for _, p := range parts {
+		kv := strings.SplitN(strings.TrimSpace(p), "=", 2)
+		if len(kv) == 2 {
+			out[kv[0]] = kv[1]
+		}
+	}
+	return out
+}
@@ -0,0 +1,83 @@
+package mutation
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/tidwall/gjson"
+
+	"cdpnetool/pkg/rulespec"
+)
+
+// VarPrefix 行为模板中引用会话变量的前缀，如 {{var.token}}
+const VarPrefix = "var."
+
+// captureValue 按 captureVar 行为提取值；header 查找当前阶段的头部，body 为当前阶段的 Body
+func captureValue(action *rulespec.Action, rawURL string, header func(string) string, body BodyContent) (string, bool) {
+	if action.Name == "" {
+		return "", false
+	}
+
+	var v string
+	switch action.From {
+	case rulespec.CaptureFromHeader:
+		v = header(action.Header)
+	case rulespec.CaptureFromURL:
+		v = rawURL
+	default:
+		if body.IsBinary {
+			return "", false
+		}
+		v = body.Text()
+		if action.Pointer != "" {
+			res := gjson.Get(v, pointerPath(action.Pointer))
+			if !res.Exists() {
+				return "", false
+			}
+			v = res.String()
+		}
+	}
+
+	if action.Pattern != "" {
+		re, err := regexp.Compile(action.Pattern)
+		if err != nil {
+			return "", false
+		}
+		m := re.FindStringSubmatch(v)
+		if m == nil {
+			return "", false
+		}
+		v = m[0]
+		if len(m) > 1 {
+			v = m[1]
+		}
+	}
+	if v == "" {
+		return "", false
+	}
+	return v, true
+}
+
+// pointerPath 将 JSON Pointer（/a/b/0）转换为 gjson 路径（a.b.0）
+func pointerPath(pointer string) string {
+	parts := strings.Split(strings.TrimPrefix(pointer, "/"), "/")
+	for i, p := range parts {
+		p = strings.ReplaceAll(strings.ReplaceAll(p, "~1", "/"), "~0", "~")
+		// gjson 路径中的特殊字符需要转义
+		for _, c := range []string{".", "*", "?", "|", "#", "@"} {
+			p = strings.ReplaceAll(p, c, `\`+c)
+		}
+		parts[i] = p
+	}
+	return strings.Join(parts, ".")
+}
+
+// withVar 返回加入了变量的模板变量副本，不修改传入的映射
+func withVar(vars map[string]string, name, value string) map[string]string {
+	out := make(map[string]string, len(vars)+1)
+	for k, v := range vars {
+		out[k] = v
+	}
+	out[VarPrefix+name] = value
+	return out
+}
@@ -0,0 +1,142 @@
+package mutation
+
+import (
+	"bytes"
+	"compress/gzip"
+	"compress/zlib"
+	"fmt"
+	"strings"
+)
+
+// 支持的压缩编码
+const (
+	encodingGzip    = "gzip"
+	encodingDeflate = "deflate"
+	encodingBrotli  = "br"
+)
+
+// brotliBlockSize br 编码中单个未压缩元块的最大长度
+const brotliBlockSize = 1 << 16
+
+// CompressEncoding 规范化 compressBody 的编码名称，不支持时返回 false
+func CompressEncoding(v any) (string, bool) {
+	s, ok := v.(string)
+	if !ok {
+		return "", false
+	}
+	switch enc := strings.ToLower(strings.TrimSpace(s)); enc {
+	case encodingGzip, encodingDeflate, encodingBrotli:
+		return enc, true
+	}
+	return "", false
+}
+
+// ApplyCompression 按 compressBody 行为压缩最终下发的响应体，并设置 Content-Encoding 与 Vary；
+// 没有可用的 Body（未读取或 Range 跳过改写）时不压缩
+func ApplyCompression(resp *Response, mut *ResponseMutation) error {
+	if mut.Compress == "" || mut.Body == nil {
+		return nil
+	}
+	data, err := compressBody(mut.Compress, mut.Body.Data)
+	if err != nil {
+		return err
+	}
+	body := *mut.Body
+	body.Data = data
+	body.IsBinary = true
+	mut.Body = &body
+
+	vary := resp.Header("Vary")
+	for k, v := range mut.Headers {
+		if strings.EqualFold(k, "Content-Encoding") {
+			delete(mut.Headers, k)
+		}
+		if strings.EqualFold(k, "Vary") {
+			vary = v
+			delete(mut.Headers, k)
+		}
+	}
+	if !strings.Contains(strings.ToLower(vary), "accept-encoding") && strings.TrimSpace(vary) != "*" {
+		if strings.TrimSpace(vary) == "" {
+			vary = "Accept-Encoding"
+		} else {
+			vary += ", Accept-Encoding"
+		}
+	}
+	mut.RemoveHeaders = append(mut.RemoveHeaders, "Content-Encoding", "Vary")
+	mut.Headers["Content-Encoding"] = mut.Compress
+	mut.Headers["Vary"] = vary
+	return nil
+}
+
+// compressBody 按指定编码压缩数据
+func compressBody(encoding string, data []byte) ([]byte, error) {
+	var buf bytes.Buffer
+	switch encoding {
+	case encodingGzip:
+		w := gzip.NewWriter(&buf)
+		if _, err := w.Write(data); err != nil {
+			return nil, err
+		}
+		if err := w.Close(); err != nil {
+			return nil, err
+		}
+	case encodingDeflate:
+		// HTTP 的 deflate 编码为 zlib 格式
+		w := zlib.NewWriter(&buf)
+		if _, err := w.Write(data); err != nil {
+			return nil, err
+		}
+		if err := w.Close(); err != nil {
+			return nil, err
+		}
+	case encodingBrotli:
+		return brotliStored(data), nil
+	default:
+		return nil, fmt.Errorf("unsupported content encoding: %s", encoding)
+	}
+	return buf.Bytes(), nil
+}
+
+// brotliStored 生成由未压缩元块组成的 brotli 流。标准库没有 brotli 编码器，
+// 输出是合法的 br 编码但不缩小体积，适合验证客户端对该编码的处理
+func brotliStored(data []byte) []byte {
+	var bw bitWriter
+	bw.write(0, 1) // WBITS = 16
+	for len(data) > 0 {
+		n := min(len(data), brotliBlockSize)
+		bw.write(0, 1)            // ISLAST
+		bw.write(0, 2)            // MNIBBLES = 4
+		bw.write(uint32(n-1), 16) // MLEN - 1
+		bw.write(1, 1)            // ISUNCOMPRESSED
+		bw.align()
+		bw.buf = append(bw.buf, data[:n]...)
+		data = data[n:]
+	}
+	bw.write(1, 1) // ISLAST
+	bw.write(1, 1) // ISLASTEMPTY
+	bw.align()
+	return bw.buf
+}
+
+// bitWriter 按 brotli 规定的低位在前顺序写入比特
+type bitWriter struct {
+	buf   []byte
+	nbits uint // 最后一个字节中已使用的比特数，0 表示需要新字节
+}
+
+// write 写入 v 的低 n 位
+func (w *bitWriter) write(v uint32, n uint) {
+	for i := uint(0); i < n; i++ {
+		if w.nbits == 0 {
+			w.buf = append(w.buf, 0)
+		}
+		w.buf[len(w.buf)-1] |= byte(v>>i&1) << w.nbits
+		w.nbits = (w.nbits + 1) % 8
+	}
+}
+
+// align 补齐到字节边界
+func (w *bitWriter) align() {
+	w.nbits = 0
+}
@@ -0,0 +1,271 @@
+package mutation
+
+import (
+	"context"
+	"encoding/base64"
+	"strings"
+	"time"
+
+	"cdpnetool/pkg/rulespec"
+)
+
+// ExecuteRequest 执行请求阶段的行为，返回修改结果；body 为前序规则改写后的请求体，
+// vars 为行为模板可引用的变量，captureVar 提取的变量对同一规则中后续的行为立即可见。ctx 超时后不再执行后续行为，由调用方负责降级
+func ExecuteRequest(ctx context.Context, actions []rulespec.Action, req *Request, body BodyContent, vars map[string]string) *RequestMutation {
+	mut := NewRequestMutation()
+
+	currentBody := body
+
+	for _, action := range actions {
+		if ctx.Err() != nil {
+			return mut
+		}
+		action = renderAction(action, requestLookup(vars, req))
+		if IsBodyAction(action.Type) {
+			next, skipped := transformBody(&action, currentBody, req.ContentType())
+			mut.BodySteps = append(mut.BodySteps, newBodyStep(action.Type, currentBody, next, skipped))
+			if skipped == "" {
+				currentBody = next
+				mut.Body = &currentBody
+			}
+			continue
+		}
+		switch action.Type {
+		case rulespec.ActionSetUrl:
+			if v, ok := action.Value.(string); ok {
+				mut.URL = &v
+			}
+
+		case rulespec.ActionSetMethod:
+			if v, ok := action.Value.(string); ok {
+				mut.Method = &v
+			}
+
+		case rulespec.ActionSetHeader:
+			if v, ok := action.Value.(string); ok {
+				mut.Headers[action.Name] = v
+			}
+
+		case rulespec.ActionRemoveHeader:
+			mut.RemoveHeaders = append(mut.RemoveHeaders, action.Name)
+
+		case rulespec.ActionSetQueryParam:
+			if v, ok := action.Value.(string); ok {
+				mut.Query[action.Name] = v
+			}
+
+		case rulespec.ActionRemoveQueryParam:
+			mut.RemoveQuery = append(mut.RemoveQuery, action.Name)
+
+		case rulespec.ActionSetCookie:
+			if v, ok := action.Value.(string); ok {
+				mut.Cookies[action.Name] = v
+			}
+
+		case rulespec.ActionRemoveCookie:
+			mut.RemoveCookies = append(mut.RemoveCookies, action.Name)
+
+		case rulespec.ActionBlock:
+			// 终结性行为
+			mut.Block = &BlockResponse{
+				StatusCode: action.StatusCode,
+				Headers:    action.Headers,
+			}
+			if action.Body != "" {
+				body := action.Body
+				if action.GetBodyEncoding() == rulespec.BodyEncodingBase64 {
+					if decoded, err := base64.StdEncoding.DecodeString(action.Body); err == nil {
+						mut.Block.Body = decoded
+					} else {
+						mut.Block.Body = []byte(body)
+					}
+				} else {
+					mut.Block.Body = []byte(body)
+				}
+			}
+			return mut // 终结性行为，立即返回
+
+		case rulespec.ActionNotModified:
+			if etag, ok := matchIfNoneMatch(req, action.ETag); ok {
+				mut.Block = &BlockResponse{
+					StatusCode: 304,
+					Headers:    map[string]string{"ETag": etag},
+				}
+				return mut // 命中时为终结性行为
+			}
+
+		case rulespec.ActionStripValidators:
+			mut.RemoveHeaders = append(mut.RemoveHeaders, "If-None-Match", "If-Modified-Since")
+
+		case rulespec.ActionSpoofOrigin:
+			spoofOrigin(req, mut, &action)
+
+		case rulespec.ActionSignHMAC, rulespec.ActionSignAWSV4:
+			mut.Signers = append(mut.Signers, action)
+
+		case rulespec.ActionDelay:
+			mut.Delay += SampleDelay(&action)
+
+		case rulespec.ActionPause:
+			mut.Pause = max(mut.Pause, PauseTimeout(&action))
+
+		case rulespec.ActionRedirect:
+			if v, ok := action.Value.(string); ok && v != "" {
+				mut.Block = newRedirectResponse(req, v, action.StatusCode)
+				return mut // 终结性行为，立即返回
+			}
+
+		case rulespec.ActionCaptureVar:
+			header := func(name string) string { v, _ := req.Header(name); return v }
+			if v, ok := captureValue(&action, req.URL, header, currentBody); ok {
+				mut.Captures[action.Name] = v
+				vars = withVar(vars, action.Name, v)
+			}
+
+		case rulespec.ActionTerminate:
+			mut.Terminate = newTerminateSpec(&action)
+			return mut // 终结性行为，立即返回
+		}
+	}
+
+	return mut
+}
+
+// ExecuteResponse 执行响应阶段的行为，返回修改结果；body 为前序规则改写后的响应体，
+// vars 为行为模板可引用的变量，captureVar 提取的变量对同一规则中后续的行为立即可见。ctx 超时后不再执行后续行为，由调用方负责降级
+func ExecuteResponse(ctx context.Context, actions []rulespec.Action, req *Request, resp *Response, body BodyContent, vars map[string]string) *ResponseMutation {
+	mut := NewResponseMutation()
+
+	currentBody := body
+
+	for _, action := range actions {
+		if ctx.Err() != nil {
+			return mut
+		}
+		action = renderAction(action, requestLookup(vars, req))
+		if IsBodyAction(action.Type) {
+			next, skipped := transformBody(&action, currentBody, req.ContentType())
+			mut.BodySteps = append(mut.BodySteps, newBodyStep(action.Type, currentBody, next, skipped))
+			if skipped == "" {
+				currentBody = next
+				mut.Body = &currentBody
+				if action.Type == rulespec.ActionSetBody {
+					mut.BodyReplaced = true
+				}
+			}
+			continue
+		}
+		switch action.Type {
+		case rulespec.ActionSetStatus:
+			if v, ok := action.Value.(float64); ok {
+				code := int(v)
+				mut.StatusCode = &code
+			} else if v, ok := action.Value.(int); ok {
+				mut.StatusCode = &v
+			}
+
+		case rulespec.ActionSetHeader:
+			if v, ok := action.Value.(string); ok {
+				mut.Headers[action.Name] = v
+			}
+
+		case rulespec.ActionRemoveHeader:
+			mut.RemoveHeaders = append(mut.RemoveHeaders, action.Name)
+
+		case rulespec.ActionStripValidators:
+			mut.RemoveHeaders = append(mut.RemoveHeaders, "ETag", "Last-Modified")
+
+		case rulespec.ActionDelay:
+			mut.Delay += SampleDelay(&action)
+
+		case rulespec.ActionPause:
+			mut.Pause = max(mut.Pause, PauseTimeout(&action))
+
+		case rulespec.ActionThrottle:
+			mut.Throttle = ThrottleRate(mut.Throttle, action.BytesPerSecond)
+
+		case rulespec.ActionRewriteLocation:
+			status := resp.StatusCode
+			if mut.StatusCode != nil {
+				status = *mut.StatusCode
+			}
+			if name, loc, ok := rewriteLocation(resp, status, action.Search, action.Replace); ok {
+				mut.Headers[name] = loc
+			}
+
+		case rulespec.ActionStripPreload:
+			stripPreloadLinks(resp, mut, action.Search)
+
+		case rulespec.ActionRewritePreload:
+			rewritePreloadLinks(resp, mut, action.Search, action.Replace)
+
+		case rulespec.ActionCompressBody:
+			if currentBody.Unread {
+				continue
+			}
+			if enc, ok := CompressEncoding(action.Value); ok {
+				mut.Compress = enc
+			}
+
+		case rulespec.ActionCaptureVar:
+			if v, ok := captureValue(&action, req.URL, resp.Header, currentBody); ok {
+				mut.Captures[action.Name] = v
+				vars = withVar(vars, action.Name, v)
+			}
+
+		case rulespec.ActionTerminate:
+			mut.Terminate = newTerminateSpec(&action)
+			// 未读取响应体时无法截断，直接终止
+			if action.AfterBytes > 0 && !currentBody.Unread {
+				body := currentBody.Data
+				if len(body) > action.AfterBytes {
+					body = body[:action.AfterBytes]
+				}
+				mut.Terminate.Truncated = true
+				mut.Terminate.Body = body
+			}
+			return mut // 终结性行为，立即返回
+		}
+	}
+
+	return mut
+}
+
+// newTerminateSpec 根据行为定义构建终止参数
+func newTerminateSpec(action *rulespec.Action) *TerminateSpec {
+	reason := action.ErrorReason
+	if reason == "" {
+		reason = defaultTerminateReason
+	}
+	return &TerminateSpec{
+		After:  time.Duration(action.AfterMS) * time.Millisecond,
+		Reason: reason,
+	}
+}
+
+// matchIfNoneMatch 判断请求的 If-None-Match 是否命中配置的 ETag（弱比较），返回规范化后的 ETag
+func matchIfNoneMatch(req *Request, etag string) (string, bool) {
+	want := normalizeETag(etag)
+	if want == "" {
+		return "", false
+	}
+	header, ok := req.Header("If-None-Match")
+	if !ok {
+		return "", false
+	}
+	quoted := `"` + want + `"`
+	for _, tag := range strings.Split(header, ",") {
+		tag = strings.TrimSpace(tag)
+		if tag == "*" || normalizeETag(tag) == want {
+			return quoted, true
+		}
+	}
+	return "", false
+}
+
+// normalizeETag 去掉弱校验前缀和引号
+func normalizeETag(tag string) string {
+	tag = strings.TrimSpace(tag)
+	tag = strings.TrimPrefix(tag, "W/")
+	return strings.Trim(tag, `"`)
+}
@@ -0,0 +1,152 @@
+package mutation
+
+import (
+	"strconv"
+	"strings"
+
+	"cdpnetool/internal/rules"
+	"cdpnetool/pkg/rulespec"
+)
+
+// fieldOwner 聚合变更中某个字段当前取值的来源
+type fieldOwner struct {
+	rule  *rules.MatchedRule
+	key   string // 字段在变更中的原始键（如 Header 原始大小写）
+	value string
+}
+
+// Conflict 两条规则对同一字段设置了不同的值
+type Conflict struct {
+	Field        string
+	Kept         *rules.MatchedRule
+	Dropped      *rules.MatchedRule
+	KeptValue    string
+	DroppedValue string
+}
+
+// Merger 按冲突策略聚合多条规则的变更，并记录发生的冲突
+type Merger struct {
+	policy    rulespec.ConflictPolicy
+	owners    map[string]fieldOwner
+	conflicts []Conflict
+}
+
+// NewMerger 创建变更聚合器
+func NewMerger(policy rulespec.ConflictPolicy) *Merger {
+	return &Merger{policy: policy, owners: make(map[string]fieldOwner)}
+}
+
+// claim 记录规则对字段的取值，返回是否采用该值；被替换的旧取值的原始键通过 prevKey 返回
+func (mm *Merger) claim(field, key, value string, mr *rules.MatchedRule) (bool, string) {
+	prev, ok := mm.owners[field]
+	if !ok {
+		mm.owners[field] = fieldOwner{rule: mr, key: key, value: value}
+		return true, ""
+	}
+	if prev.value == value {
+		return false, ""
+	}
+
+	c := Conflict{Field: field}
+	keep := mm.prefer(mr, prev.rule)
+	if keep {
+		c.Kept, c.KeptValue, c.Dropped, c.DroppedValue = mr, value, prev.rule, prev.value
+		mm.owners[field] = fieldOwner{rule: mr, key: key, value: value}
+	} else {
+		c.Kept, c.KeptValue, c.Dropped, c.DroppedValue = prev.rule, prev.value, mr, value
+	}
+	mm.conflicts = append(mm.conflicts, c)
+	return keep, prev.key
+}
+
+// prefer 判断后到规则的取值是否优先于已有取值。规则按优先级从大到小到达，
+// 因此优先级相同时保留先到（配置中靠前）的值
+func (mm *Merger) prefer(cur, prev *rules.MatchedRule) bool {
+	if mm.policy == rulespec.ConflictFirstWins {
+		return cur.Index < prev.Index
+	}
+	return cur.Rule.Priority > prev.Rule.Priority
+}
+
+// MergeRequest 将单条规则的请求变更合并到 dst
+func (mm *Merger) MergeRequest(dst, src *RequestMutation, mr *rules.MatchedRule) {
+	if src.URL != nil {
+		if ok, _ := mm.claim("url", "", *src.URL, mr); ok {
+			dst.URL = src.URL
+		}
+	}
+	if src.Method != nil {
+		if ok, _ := mm.claim("method", "", *src.Method, mr); ok {
+			dst.Method = src.Method
+		}
+	}
+	mm.mergeMap("header:", true, dst.Headers, src.Headers, mr)
+	mm.mergeMap("query:", false, dst.Query, src.Query, mr)
+	mm.mergeMap("cookie:", false, dst.Cookies, src.Cookies, mr)
+	dst.RemoveHeaders = append(dst.RemoveHeaders, src.RemoveHeaders...)
+	dst.RemoveQuery = append(dst.RemoveQuery, src.RemoveQuery...)
+	dst.RemoveCookies = append(dst.RemoveCookies, src.RemoveCookies...)
+	dst.Signers = append(dst.Signers, src.Signers...)
+	dst.Delay += src.Delay
+	dst.Pause = max(dst.Pause, src.Pause)
+	// Body 由变换流水线依次处理，最后一次结果即为最终 Body
+	if src.Body != nil {
+		dst.Body = src.Body
+	}
+}
+
+// MergeResponse 将单条规则的响应变更合并到 dst
+func (mm *Merger) MergeResponse(dst, src *ResponseMutation, mr *rules.MatchedRule) {
+	if src.StatusCode != nil {
+		if ok, _ := mm.claim("status", "", strconv.Itoa(*src.StatusCode), mr); ok {
+			dst.StatusCode = src.StatusCode
+		}
+	}
+	mm.mergeMap("header:", true, dst.Headers, src.Headers, mr)
+	dst.RemoveHeaders = append(dst.RemoveHeaders, src.RemoveHeaders...)
+	dst.Delay += src.Delay
+	dst.Pause = max(dst.Pause, src.Pause)
+	dst.Throttle = ThrottleRate(dst.Throttle, src.Throttle)
+	if src.Compress != "" {
+		if ok, _ := mm.claim("compress", "", src.Compress, mr); ok {
+			dst.Compress = src.Compress
+		}
+	}
+	if src.Body != nil {
+		dst.Body = src.Body
+	}
+	dst.BodyReplaced = dst.BodyReplaced || src.BodyReplaced
+}
+
+// mergeMap 合并键值类变更，foldCase 为 true 时键不区分大小写
+func (mm *Merger) mergeMap(prefix string, foldCase bool, dst, src map[string]string, mr *rules.MatchedRule) {
+	for k, v := range src {
+		field := k
+		if foldCase {
+			field = strings.ToLower(k)
+		}
+		ok, prevKey := mm.claim(prefix+field, k, v, mr)
+		if !ok {
+			continue
+		}
+		if prevKey != "" {
+			delete(dst, prevKey)
+		}
+		dst[k] = v
+	}
+}
+
+// Policy 返回聚合器使用的冲突策略
+func (mm *Merger) Policy() rulespec.ConflictPolicy {
+	return mm.policy
+}
+
+// Conflicts 返回聚合过程中记录的冲突
+func (mm *Merger) Conflicts() []Conflict {
+	return mm.conflicts
+}
+
+// Aborted 判断是否存在冲突且策略要求放弃所有修改
+func (mm *Merger) Aborted() bool {
+	return len(mm.conflicts) > 0 && mm.policy == rulespec.ConflictAbort
+}
@@ -0,0 +1,145 @@
+// Package mutation 执行规则行为，将命中规则的行为转换为对请求和响应的修改。
+// 只依赖规则格式本身，不依赖 CDP，浏览器拦截、内置代理与独立的规则引擎共用同一套行为语义
+package mutation
+
+import (
+	"strings"
+	"time"
+
+	"cdpnetool/pkg/model"
+	"cdpnetool/pkg/rulespec"
+)
+
+// defaultTerminateReason terminate 行为未指定错误原因时使用的网络错误
+const defaultTerminateReason = "ConnectionClosed"
+
+// Request 行为执行时读取的原始请求信息
+type Request struct {
+	Method  string
+	URL     string
+	Headers map[string]string // 原始请求头，名称保持原样
+}
+
+// Header 响应头条目，同名头部可以出现多次
+type Header struct {
+	Name  string
+	Value string
+}
+
+// Response 行为执行时读取的原始响应信息
+type Response struct {
+	StatusCode int
+	Headers    []Header
+}
+
+// RequestMutation 请求修改结果
+type RequestMutation struct {
+	URL           *string
+	Method        *string
+	Headers       map[string]string
+	RemoveHeaders []string
+	Query         map[string]string
+	RemoveQuery   []string
+	Cookies       map[string]string
+	RemoveCookies []string
+	Body          *BodyContent
+	BodySteps     []model.BodyTransform // Body 变换记录
+	SecretHeaders map[string]string     // 注入的敏感请求头，事件中以掩码记录
+	Signers       []rulespec.Action     // 签名行为，在全部修改完成后按最终请求计算
+	Captures      map[string]string     // captureVar 提取的会话变量（不含 var. 前缀）
+	Delay         time.Duration         // 放行前的等待时间，多个 delay 行为累加
+	Pause         time.Duration         // 断点等待人工处理的超时，大于 0 表示放行前需要人工确认
+	Block         *BlockResponse        // 终结性行为
+	Terminate     *TerminateSpec        // 终结性行为
+}
+
+// BlockResponse 拦截响应
+type BlockResponse struct {
+	StatusCode int
+	Headers    map[string]string
+	Body       []byte
+	Redirect   bool // 由 redirect 行为生成的重定向响应
+}
+
+// TerminateSpec 终止行为参数
+type TerminateSpec struct {
+	After     time.Duration // 终止前等待时间
+	Reason    string        // 网络错误原因
+	Truncated bool          // 是否以截断 Body 结束响应（否则使请求失败）
+	Body      []byte        // 截断后的 Body
+}
+
+// ResponseMutation 响应修改结果
+type ResponseMutation struct {
+	StatusCode    *int
+	Headers       map[string]string
+	RemoveHeaders []string
+	Body          *BodyContent
+	BodyReplaced  bool                  // Body 由 setBody 或人工修改整体替换，不再是上游返回的内容（Range 请求据此切片）
+	BodySteps     []model.BodyTransform // Body 变换记录
+	Delay         time.Duration         // 放行前的等待时间，多个 delay 行为累加
+	Pause         time.Duration         // 断点等待人工处理的超时，大于 0 表示放行前需要人工确认
+	Throttle      int                   // 响应体传输速率（字节/秒），多个 throttle 行为取最慢的，0 表示不限速
+	Compress      string                // 下发前对最终 Body 使用的 Content-Encoding，为空表示不压缩
+	Captures      map[string]string     // captureVar 提取的会话变量（不含 var. 前缀）
+	Terminate     *TerminateSpec        // 终结性行为
+}
+
+// NewRequestMutation 创建空的请求修改结果
+func NewRequestMutation() *RequestMutation {
+	return &RequestMutation{
+		Headers:       make(map[string]string),
+		Query:         make(map[string]string),
+		Cookies:       make(map[string]string),
+		Captures:      make(map[string]string),
+		RemoveHeaders: []string{},
+		RemoveQuery:   []string{},
+		RemoveCookies: []string{},
+	}
+}
+
+// NewResponseMutation 创建空的响应修改结果
+func NewResponseMutation() *ResponseMutation {
+	return &ResponseMutation{
+		Headers:       make(map[string]string),
+		Captures:      make(map[string]string),
+		RemoveHeaders: []string{},
+	}
+}
+
+// SetRequestHeader 设置请求头修改，并移除大小写不同的同名请求头
+func SetRequestHeader(mut *RequestMutation, name, value string) {
+	for k := range mut.Headers {
+		if strings.EqualFold(k, name) {
+			delete(mut.Headers, k)
+		}
+	}
+	mut.RemoveHeaders = append(mut.RemoveHeaders, name)
+	mut.Headers[name] = value
+}
+
+// Header 不区分大小写地查找请求头
+func (r *Request) Header(name string) (string, bool) {
+	for k, v := range r.Headers {
+		if strings.EqualFold(k, name) {
+			return v, true
+		}
+	}
+	return "", false
+}
+
+// ContentType 返回请求的 Content-Type
+func (r *Request) ContentType() string {
+	v, _ := r.Header("Content-Type")
+	return v
+}
+
+// Header 不区分大小写地查找响应头，同名头部出现多次时返回第一个
+func (r *Response) Header(name string) string {
+	for _, h := range r.Headers {
+		if strings.EqualFold(h.Name, name) {
+			return h.Value
+		}
+	}
+	return ""
+}
@@ -0,0 +1,67 @@
+package mutation
+
+import (
+	"net/url"
+	"strings"
+
+	"golang.org/x/net/publicsuffix"
+
+	"cdpnetool/pkg/rulespec"
+)
+
+// spoofOrigin 按 spoofOrigin 行为一并改写或移除 Origin 与 Referer。
+// value 为页面地址时 Referer 设为该地址，Origin 设为其源；value 为空时两者都移除。
+// 启用 secFetch 时同步改写 Sec-Fetch-Site，使其与伪造的来源一致，避免后端的来源校验发现矛盾
+func spoofOrigin(req *Request, mut *RequestMutation, action *rulespec.Action) {
+	value, _ := action.Value.(string)
+	value = strings.TrimSpace(value)
+	if value == "" {
+		mut.RemoveHeaders = append(mut.RemoveHeaders, "Origin", "Referer")
+		if action.SecFetch && hasHeader(req, "Sec-Fetch-Site") {
+			SetRequestHeader(mut, "Sec-Fetch-Site", "none")
+		}
+		return
+	}
+
+	ref, err := url.Parse(value)
+	if err != nil || ref.Scheme == "" || ref.Host == "" {
+		return
+	}
+	origin := ref.Scheme + "://" + ref.Host
+
+	SetRequestHeader(mut, "Referer", value)
+	// 浏览器只在跨源请求和非 GET/HEAD 请求上携带 Origin，保持与原请求一致
+	method := strings.ToUpper(req.Method)
+	if hasHeader(req, "Origin") || (method != "GET" && method != "HEAD") {
+		SetRequestHeader(mut, "Origin", origin)
+	}
+	if action.SecFetch && hasHeader(req, "Sec-Fetch-Site") {
+		SetRequestHeader(mut, "Sec-Fetch-Site", fetchSite(ref, req.URL))
+	}
+}
+
+// fetchSite 按发起页面与请求地址计算 Sec-Fetch-Site 的取值
+func fetchSite(initiator *url.URL, rawURL string) string {
+	target, err := url.Parse(rawURL)
+	if err != nil {
+		return "cross-site"
+	}
+	if strings.EqualFold(initiator.Scheme, target.Scheme) && strings.EqualFold(initiator.Host, target.Host) {
+		return "same-origin"
+	}
+	if !strings.EqualFold(initiator.Scheme, target.Scheme) {
+		return "cross-site"
+	}
+	a, errA := publicsuffix.EffectiveTLDPlusOne(strings.ToLower(initiator.Hostname()))
+	b, errB := publicsuffix.EffectiveTLDPlusOne(strings.ToLower(target.Hostname()))
+	if errA == nil && errB == nil && a == b {
+		return "same-site"
+	}
+	return "cross-site"
+}
+
+// hasHeader 判断请求是否携带非空的请求头
+func hasHeader(req *Request, name string) bool {
+	v, _ := req.Header(name)
+	return v != ""
+}
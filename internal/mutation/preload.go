@@ -0,0 +1,132 @@
+package mutation
+
+import "strings"
+
+// linkHeader 返回当前的 Link 头：前序规则设置过时使用设置值，否则合并原始响应中的全部 Link 头
+func linkHeader(resp *Response, mut *ResponseMutation) (string, string, bool) {
+	for name, v := range mut.Headers {
+		if strings.EqualFold(name, "Link") {
+			return name, v, true
+		}
+	}
+	var name string
+	var values []string
+	for _, h := range resp.Headers {
+		if strings.EqualFold(h.Name, "Link") {
+			name = h.Name
+			values = append(values, h.Value)
+		}
+	}
+	return name, strings.Join(values, ", "), name != ""
+}
+
+// editPreloadLinks 对 Link 头中的预加载条目执行 edit，edit 返回空字符串表示移除该条目；
+// 其他条目原样保留，全部移除时删除 Link 头
+func editPreloadLinks(resp *Response, mut *ResponseMutation, edit func(entry, target string) string) {
+	name, value, ok := linkHeader(resp, mut)
+	if !ok {
+		return
+	}
+	changed := false
+	var kept []string
+	for _, entry := range SplitLinks(value) {
+		if IsPreloadLink(entry) {
+			next := edit(entry, LinkTarget(entry))
+			if next != entry {
+				changed = true
+			}
+			if next == "" {
+				continue
+			}
+			entry = next
+		}
+		kept = append(kept, entry)
+	}
+	if !changed {
+		return
+	}
+	if len(kept) == 0 {
+		delete(mut.Headers, name)
+		mut.RemoveHeaders = append(mut.RemoveHeaders, name)
+		return
+	}
+	mut.Headers[name] = strings.Join(kept, ", ")
+}
+
+// stripPreloadLinks 移除目标地址包含 search 的预加载条目，search 为空时移除全部预加载条目
+func stripPreloadLinks(resp *Response, mut *ResponseMutation, search string) {
+	editPreloadLinks(resp, mut, func(entry, target string) string {
+		if search == "" || strings.Contains(target, search) {
+			return ""
+		}
+		return entry
+	})
+}
+
+// rewritePreloadLinks 对预加载条目的目标地址执行字符串替换（替换首个匹配）
+func rewritePreloadLinks(resp *Response, mut *ResponseMutation, search, replace string) {
+	if search == "" {
+		return
+	}
+	editPreloadLinks(resp, mut, func(entry, target string) string {
+		if !strings.Contains(target, search) {
+			return entry
+		}
+		return "<" + strings.Replace(target, search, replace, 1) + ">" + entry[strings.Index(entry, ">")+1:]
+	})
+}
+
+// SplitLinks 按逗号拆分 Link 头，忽略尖括号和引号内的逗号
+func SplitLinks(v string) []string {
+	var out []string
+	depth, quoted, start := 0, false, 0
+	for i := 0; i < len(v); i++ {
+		switch c := v[i]; {
+		case c == '"':
+			quoted = !quoted
+		case quoted:
+		case c == '<':
+			depth++
+		case c == '>' && depth > 0:
+			depth--
+		case c == ',' && depth == 0:
+			if s := strings.TrimSpace(v[start:i]); s != "" {
+				out = append(out, s)
+			}
+			start = i + 1
+		}
+	}
+	if s := strings.TrimSpace(v[start:]); s != "" {
+		out = append(out, s)
+	}
+	return out
+}
+
+// LinkTarget 返回 Link 条目尖括号中的目标地址
+func LinkTarget(entry string) string {
+	start, end := strings.Index(entry, "<"), strings.Index(entry, ">")
+	if start < 0 || end < start {
+		return ""
+	}
+	return strings.TrimSpace(entry[start+1 : end])
+}
+
+// IsPreloadLink 判断 Link 条目的 rel 是否包含 preload 或 modulepreload
+func IsPreloadLink(entry string) bool {
+	if LinkTarget(entry) == "" {
+		return false
+	}
+	params := strings.Split(entry[strings.Index(entry, ">")+1:], ";")
+	for _, p := range params {
+		k, v, ok := strings.Cut(strings.TrimSpace(p), "=")
+		if !ok || !strings.EqualFold(strings.TrimSpace(k), "rel") {
+			continue
+		}
+		for _, rel := range strings.Fields(strings.Trim(strings.TrimSpace(v), `"`)) {
+			if strings.EqualFold(rel, "preload") || strings.EqualFold(rel, "modulepreload") {
+				return true
+			}
+		}
+	}
+	return false
+}
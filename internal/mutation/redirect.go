@@ -0,0 +1,40 @@
+package mutation
+
+import "strings"
+
+// defaultRedirectStatus redirect 行为默认使用 307，浏览器会保留原请求方法和 Body
+const defaultRedirectStatus = 307
+
+// newRedirectResponse 构建重定向到 location 的响应；跨域请求附带 CORS 头，
+// 以便 XHR/Fetch 能跟随重定向
+func newRedirectResponse(req *Request, location string, status int) *BlockResponse {
+	switch status {
+	case 301, 302, 303, 307, 308:
+	default:
+		status = defaultRedirectStatus
+	}
+	headers := map[string]string{"Location": location}
+	if origin, ok := req.Header("Origin"); ok && origin != "" {
+		headers["Access-Control-Allow-Origin"] = origin
+		headers["Access-Control-Allow-Credentials"] = "true"
+		headers["Vary"] = "Origin"
+	}
+	return &BlockResponse{StatusCode: status, Headers: headers, Redirect: true}
+}
+
+// rewriteLocation 对 3xx 响应的 Location 头执行字符串替换，返回头部原始名称和新值
+func rewriteLocation(resp *Response, status int, search, replace string) (string, string, bool) {
+	if status < 300 || status >= 400 || search == "" {
+		return "", "", false
+	}
+	for _, h := range resp.Headers {
+		if !strings.EqualFold(h.Name, "Location") {
+			continue
+		}
+		if !strings.Contains(h.Value, search) {
+			return "", "", false
+		}
+		return h.Name, strings.Replace(h.Value, search, replace, 1), true
+	}
+	return "", "", false
+}
@@ -0,0 +1,127 @@
+package mutation
+
+import (
+	"net/url"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+
+	"cdpnetool/pkg/rulespec"
+)
+
+// templatePattern 匹配 {{name}} 形式的模板占位符
+var templatePattern = regexp.MustCompile(`\{\{\s*([a-zA-Z0-9_.\-]+)\s*\}\}`)
+
+// templateLookup 按名称查找模板变量的值
+type templateLookup func(name string) (string, bool)
+
+// RenderTemplate 使用变量替换模板占位符，未定义的占位符保持原样
+func RenderTemplate(s string, vars map[string]string) string {
+	if len(vars) == 0 {
+		return s
+	}
+	return renderTemplate(s, mapLookup(vars))
+}
+
+// RenderAction 返回渲染模板后的行为副本
+func RenderAction(action rulespec.Action, vars map[string]string) rulespec.Action {
+	if len(vars) == 0 {
+		return action
+	}
+	return renderAction(action, mapLookup(vars))
+}
+
+// mapLookup 返回在变量映射中查找的 templateLookup
+func mapLookup(vars map[string]string) templateLookup {
+	return func(name string) (string, bool) {
+		v, ok := vars[name]
+		return v, ok
+	}
+}
+
+// requestLookup 返回行为执行时使用的 templateLookup：先查找 vars，再查找内置变量
+//   - request.url、request.method、request.header.<名称>（不区分大小写）
+//   - query.<名称>：请求 URL 中的查询参数，同名参数取第一个
+//   - now（RFC 3339，UTC）、now.unix（秒）、now.ms（毫秒）
+//   - uuid：每个占位符生成一个新的随机 UUID
+//   - env.<名称>：进程的环境变量，未设置时保持原样
+func requestLookup(vars map[string]string, req *Request) templateLookup {
+	var query url.Values
+	return func(name string) (string, bool) {
+		if v, ok := vars[name]; ok {
+			return v, true
+		}
+		switch {
+		case name == "request.url":
+			return req.URL, true
+		case name == "request.method":
+			return req.Method, true
+		case strings.HasPrefix(name, "request.header."):
+			return req.Header(strings.TrimPrefix(name, "request.header."))
+		case strings.HasPrefix(name, "query."):
+			if query == nil {
+				query = url.Values{}
+				if u, err := url.Parse(req.URL); err == nil {
+					query = u.Query()
+				}
+			}
+			key := strings.TrimPrefix(name, "query.")
+			if !query.Has(key) {
+				return "", false
+			}
+			return query.Get(key), true
+		case name == "now":
+			return time.Now().UTC().Format(time.RFC3339), true
+		case name == "now.unix":
+			return strconv.FormatInt(time.Now().Unix(), 10), true
+		case name == "now.ms":
+			return strconv.FormatInt(time.Now().UnixMilli(), 10), true
+		case name == "uuid":
+			return uuid.NewString(), true
+		case strings.HasPrefix(name, "env."):
+			return os.LookupEnv(strings.TrimPrefix(name, "env."))
+		}
+		return "", false
+	}
+}
+
+// renderTemplate 替换模板占位符，未定义的占位符保持原样
+func renderTemplate(s string, lookup templateLookup) string {
+	if !strings.Contains(s, "{{") {
+		return s
+	}
+	return templatePattern.ReplaceAllStringFunc(s, func(tok string) string {
+		name := templatePattern.FindStringSubmatch(tok)[1]
+		if v, ok := lookup(name); ok {
+			return v
+		}
+		return tok
+	})
+}
+
+// renderAction 返回渲染模板后的行为副本
+func renderAction(action rulespec.Action, lookup templateLookup) rulespec.Action {
+	if v, ok := action.Value.(string); ok {
+		action.Value = renderTemplate(v, lookup)
+	}
+	action.Body = renderTemplate(action.Body, lookup)
+	action.Replace = renderTemplate(action.Replace, lookup)
+	action.Username = renderTemplate(action.Username, lookup)
+	action.Password = renderTemplate(action.Password, lookup)
+	action.SigningKey = renderTemplate(action.SigningKey, lookup)
+	action.AccessKeyID = renderTemplate(action.AccessKeyID, lookup)
+	action.SecretAccessKey = renderTemplate(action.SecretAccessKey, lookup)
+	action.SessionToken = renderTemplate(action.SessionToken, lookup)
+	if len(action.Headers) > 0 {
+		headers := make(map[string]string, len(action.Headers))
+		for k, v := range action.Headers {
+			headers[k] = renderTemplate(v, lookup)
+		}
+		action.Headers = headers
+	}
+	return action
+}
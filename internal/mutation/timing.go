@@ -0,0 +1,68 @@
+package mutation
+
+import (
+	"math"
+	"math/rand"
+	"time"
+
+	"cdpnetool/pkg/rulespec"
+)
+
+// defaultPauseTimeout 断点等待人工处理的默认超时
+const defaultPauseTimeout = 60 * time.Second
+
+// defaultParetoAlpha pareto 分布的默认形状参数
+const defaultParetoAlpha = 1.5
+
+// SampleDelay 按行为配置的分布抽取一次延迟，结果限制在 [minMs, maxMs] 内
+func SampleDelay(a *rulespec.Action) time.Duration {
+	var ms float64
+	switch a.Distribution {
+	case rulespec.DelayUniform:
+		ms = float64(a.MinMS)
+		if a.MaxMS > a.MinMS {
+			ms += rand.Float64() * float64(a.MaxMS-a.MinMS)
+		}
+	case rulespec.DelayNormal:
+		ms = float64(a.DelayMS) + rand.NormFloat64()*float64(a.StdDevMS)
+	case rulespec.DelayPareto:
+		alpha := a.Alpha
+		if alpha <= 0 {
+			alpha = defaultParetoAlpha
+		}
+		// 逆变换采样：x = xm / U^(1/alpha)，U ∈ (0, 1]
+		ms = float64(a.DelayMS) / math.Pow(1-rand.Float64(), 1/alpha)
+	default:
+		ms = float64(a.DelayMS)
+	}
+
+	if a.MaxMS > 0 && ms > float64(a.MaxMS) {
+		ms = float64(a.MaxMS)
+	}
+	if ms < float64(a.MinMS) {
+		ms = float64(a.MinMS)
+	}
+	if ms <= 0 {
+		return 0
+	}
+	return time.Duration(ms * float64(time.Millisecond))
+}
+
+// PauseTimeout 返回 pause 行为的等待超时
+func PauseTimeout(a *rulespec.Action) time.Duration {
+	if a.TimeoutMS <= 0 {
+		return defaultPauseTimeout
+	}
+	return time.Duration(a.TimeoutMS) * time.Millisecond
+}
+
+// ThrottleRate 合并限速：取更慢的速率，0 表示不限速
+func ThrottleRate(a, b int) int {
+	if a <= 0 {
+		return b
+	}
+	if b <= 0 {
+		return a
+	}
+	return min(a, b)
+}
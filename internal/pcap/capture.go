@@ -0,0 +1,201 @@
+package pcap
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"time"
+)
+
+// 支持的链路层类型
+const (
+	linkNull      = 0   // BSD 回环，4 字节主机字节序的协议族
+	linkEthernet  = 1   // 以太网
+	linkRaw       = 101 // 原始 IP
+	linkLoop      = 108 // OpenBSD 回环，4 字节网络字节序的协议族
+	linkLinuxSLL  = 113 // Linux cooked capture v1
+	linkIPv4      = 228 // 原始 IPv4
+	linkIPv6      = 229 // 原始 IPv6
+	linkLinuxSLL2 = 276 // Linux cooked capture v2
+)
+
+// maxBlockSize 单个记录或块的大小上限，超过时视为文件损坏
+const maxBlockSize = 64 << 20
+
+// errNotCapture 文件不是 pcap/pcapng 格式
+var errNotCapture = errors.New("不是有效的 pcap/pcapng 文件")
+
+// packet 抓包中的一个数据包
+type packet struct {
+	ts   time.Time
+	link uint16
+	data []byte
+}
+
+// readPackets 按文件头识别 pcap 或 pcapng 格式并读取全部数据包；文件末尾不完整时返回已读取的部分
+func readPackets(r io.Reader) ([]packet, error) {
+	br := bufio.NewReader(r)
+	magic, err := br.Peek(4)
+	if err != nil {
+		return nil, errNotCapture
+	}
+	switch {
+	case magic[0] == 0x0a && magic[1] == 0x0d && magic[2] == 0x0d && magic[3] == 0x0a:
+		return readPcapNG(br)
+	case binary.LittleEndian.Uint32(magic) == 0xa1b2c3d4:
+		return readPcap(br, binary.LittleEndian, time.Microsecond)
+	case binary.BigEndian.Uint32(magic) == 0xa1b2c3d4:
+		return readPcap(br, binary.BigEndian, time.Microsecond)
+	case binary.LittleEndian.Uint32(magic) == 0xa1b23c4d:
+		return readPcap(br, binary.LittleEndian, time.Nanosecond)
+	case binary.BigEndian.Uint32(magic) == 0xa1b23c4d:
+		return readPcap(br, binary.BigEndian, time.Nanosecond)
+	}
+	return nil, errNotCapture
+}
+
+// readPcap 读取经典 pcap 格式，unit 为时间戳小数部分的单位
+func readPcap(br *bufio.Reader, order binary.ByteOrder, unit time.Duration) ([]packet, error) {
+	var hdr [24]byte
+	if _, err := io.ReadFull(br, hdr[:]); err != nil {
+		return nil, errNotCapture
+	}
+	link := uint16(order.Uint32(hdr[20:24]))
+
+	var out []packet
+	var rec [16]byte
+	for {
+		if _, err := io.ReadFull(br, rec[:]); err != nil {
+			return out, nil
+		}
+		n := order.Uint32(rec[8:12])
+		if n > maxBlockSize {
+			return out, fmt.Errorf("数据包长度异常: %d", n)
+		}
+		data := make([]byte, n)
+		if _, err := io.ReadFull(br, data); err != nil {
+			return out, nil
+		}
+		ts := time.Unix(int64(order.Uint32(rec[0:4])), int64(order.Uint32(rec[4:8]))*int64(unit))
+		out = append(out, packet{ts: ts, link: link, data: data})
+	}
+}
+
+// ngInterface pcapng 接口描述
+type ngInterface struct {
+	link  uint16
+	tsRes byte // if_tsresol 选项：最高位为 0 时单位是 10^-v 秒，否则是 2^-v 秒
+}
+
+// readPcapNG 读取 pcapng 格式，支持多个节和接口
+func readPcapNG(br *bufio.Reader) ([]packet, error) {
+	var out []packet
+	var order binary.ByteOrder = binary.LittleEndian
+	var ifaces []ngInterface
+	var head [8]byte
+	for {
+		if _, err := io.ReadFull(br, head[:]); err != nil {
+			return out, nil
+		}
+		if binary.LittleEndian.Uint32(head[0:4]) == 0x0a0d0d0a {
+			// 节头块：字节序由块内的字节序标记决定
+			bom, err := br.Peek(4)
+			if err != nil {
+				return out, nil
+			}
+			switch {
+			case binary.LittleEndian.Uint32(bom) == 0x1a2b3c4d:
+				order = binary.LittleEndian
+			case binary.BigEndian.Uint32(bom) == 0x1a2b3c4d:
+				order = binary.BigEndian
+			default:
+				return out, errNotCapture
+			}
+			ifaces = nil
+		}
+		blockType := order.Uint32(head[0:4])
+		total := order.Uint32(head[4:8])
+		if total < 12 || total > maxBlockSize {
+			return out, fmt.Errorf("pcapng 块长度异常: %d", total)
+		}
+		body := make([]byte, total-8)
+		if _, err := io.ReadFull(br, body); err != nil {
+			return out, nil
+		}
+		body = body[:len(body)-4] // 去掉块尾的重复长度
+
+		switch blockType {
+		case 1: // 接口描述块
+			if len(body) < 8 {
+				continue
+			}
+			iface := ngInterface{link: order.Uint16(body[0:2]), tsRes: 6}
+			if res, ok := ngOption(body[8:], order, 9); ok && len(res) > 0 {
+				iface.tsRes = res[0]
+			}
+			ifaces = append(ifaces, iface)
+		case 6: // 增强数据包块
+			if len(body) < 20 {
+				continue
+			}
+			id := order.Uint32(body[0:4])
+			capLen := order.Uint32(body[12:16])
+			if int(id) >= len(ifaces) || int(capLen) > len(body)-20 {
+				continue
+			}
+			iface := ifaces[id]
+			ts := uint64(order.Uint32(body[4:8]))<<32 | uint64(order.Uint32(body[8:12]))
+			out = append(out, packet{
+				ts:   ngTime(ts, iface.tsRes),
+				link: iface.link,
+				data: body[20 : 20+capLen],
+			})
+		case 3: // 简单数据包块：没有时间戳，属于第一个接口
+			if len(body) < 4 || len(ifaces) == 0 {
+				continue
+			}
+			capLen := min(int(order.Uint32(body[0:4])), len(body)-4)
+			out = append(out, packet{link: ifaces[0].link, data: body[4 : 4+capLen]})
+		}
+	}
+}
+
+// ngOption 查找 pcapng 选项的值
+func ngOption(opts []byte, order binary.ByteOrder, code uint16) ([]byte, bool) {
+	for len(opts) >= 4 {
+		c, n := order.Uint16(opts[0:2]), int(order.Uint16(opts[2:4]))
+		if c == 0 || 4+n > len(opts) {
+			break
+		}
+		if c == code {
+			return opts[4 : 4+n], true
+		}
+		opts = opts[4+(n+3)&^3:]
+	}
+	return nil, false
+}
+
+// ngTime 按接口的时间戳精度将 pcapng 时间戳换算为时间
+func ngTime(ts uint64, res byte) time.Time {
+	if res&0x80 != 0 {
+		shift := res & 0x7f
+		if shift > 63 {
+			return time.Time{}
+		}
+		sec := ts >> shift
+		frac := ts & (1<<shift - 1)
+		return time.Unix(int64(sec), int64(float64(frac)/float64(uint64(1)<<shift)*1e9))
+	}
+	if res > 19 {
+		return time.Time{}
+	}
+	unit := uint64(math.Pow10(int(res)))
+	sec, frac := ts/unit, ts%unit
+	if res <= 9 {
+		return time.Unix(int64(sec), int64(frac*uint64(math.Pow10(9-int(res)))))
+	}
+	return time.Unix(int64(sec), int64(frac/uint64(math.Pow10(int(res)-9))))
+}
@@ -0,0 +1,276 @@
+// Package pcap 从 pcap/pcapng 抓包文件中还原明文 HTTP/1.x 请求与响应，用于导入事件历史做离线分析
+package pcap
+
+import (
+	"bufio"
+	"bytes"
+	"compress/flate"
+	"compress/gzip"
+	"compress/zlib"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"cdpnetool/internal/analyzer"
+	"cdpnetool/internal/mutation"
+	"cdpnetool/pkg/model"
+)
+
+// maxBodySize 导入事件中记录的 Body 大小上限，超过时仅记录前缀
+const maxBodySize = 1 << 20
+
+// requestMethods 用于识别连接中哪一方是客户端
+var requestMethods = []string{"GET ", "POST ", "PUT ", "DELETE ", "HEAD ", "OPTIONS ", "PATCH ", "TRACE ", "CONNECT "}
+
+// Exchange 从抓包中还原的一次 HTTP 请求及其响应
+type Exchange struct {
+	Timestamp int64 // 请求开始时间（毫秒时间戳）
+	Request   model.RequestInfo
+	Response  model.ResponseInfo // 抓包中没有对应响应时状态码为 0
+}
+
+// Read 读取 pcap/pcapng 抓包并还原其中的明文 HTTP/1.x 交互，按请求时间排列；
+// TLS 加密流量、HTTP/2 及不完整的连接会被跳过
+func Read(r io.Reader) ([]Exchange, error) {
+	packets, err := readPackets(r)
+	if err != nil && len(packets) == 0 {
+		return nil, err
+	}
+	table := newTCPTable()
+	for _, p := range packets {
+		table.add(p)
+	}
+
+	var out []Exchange
+	for _, conn := range table.connections() {
+		a, b := conn[0].assemble(), conn[1].assemble()
+		switch {
+		case looksLikeRequest(a.data):
+			out = append(out, parseConn(conn[0].key, a, b)...)
+		case looksLikeRequest(b.data):
+			out = append(out, parseConn(conn[1].key, b, a)...)
+		}
+	}
+	sort.SliceStable(out, func(i, j int) bool { return out[i].Timestamp < out[j].Timestamp })
+	return out, nil
+}
+
+// Events 将还原的交互转换为可写入事件历史的事件，网络 ID 按顺序编号以便关联
+func Events(xs []Exchange, session model.SessionID, target model.TargetID) []*model.MatchedEvent {
+	out := make([]*model.MatchedEvent, 0, len(xs))
+	for i, x := range xs {
+		evt := &model.MatchedEvent{NetworkEvent: model.NetworkEvent{
+			Session:      session,
+			Target:       target,
+			Timestamp:    x.Timestamp,
+			IsMatched:    true,
+			NetworkID:    fmt.Sprintf("pcap-%d", i+1),
+			Stage:        "request",
+			Request:      x.Request,
+			Response:     x.Response,
+			FinalResult:  "passed",
+			MatchedRules: []model.RuleMatch{},
+		}}
+		if x.Response.StatusCode != 0 {
+			evt.Stage = "response"
+		}
+		evt.Operation = operation(x)
+		out = append(out, evt)
+	}
+	return out
+}
+
+// looksLikeRequest 判断字节流是否以 HTTP 请求行开头
+func looksLikeRequest(data []byte) bool {
+	for _, m := range requestMethods {
+		if bytes.HasPrefix(data, []byte(m)) {
+			return true
+		}
+	}
+	return false
+}
+
+// streamReader 在重组后的流上逐条解析 HTTP 消息，并能给出当前消息在流中的位置
+type streamReader struct {
+	s  *stream
+	rd *bytes.Reader
+	br *bufio.Reader
+}
+
+// newStreamReader 创建流读取器
+func newStreamReader(s *stream) *streamReader {
+	rd := bytes.NewReader(s.data)
+	return &streamReader{s: s, rd: rd, br: bufio.NewReader(rd)}
+}
+
+// time 返回下一条消息起始数据的到达时间
+func (r *streamReader) time() time.Time {
+	return r.s.at(len(r.s.data) - r.rd.Len() - r.br.Buffered())
+}
+
+// parseConn 依次解析客户端发出的请求，并按顺序与服务端的响应配对
+func parseConn(client flowKey, c, s *stream) []Exchange {
+	var reqs []*http.Request
+	var out []Exchange
+	cr := newStreamReader(c)
+	for {
+		ts := cr.time()
+		req, err := http.ReadRequest(cr.br)
+		if err != nil {
+			break
+		}
+		// CONNECT 之后是隧道内的加密数据，无法继续解析
+		if req.Method == http.MethodConnect {
+			break
+		}
+		body, err := io.ReadAll(req.Body)
+		out = append(out, Exchange{
+			Timestamp: ts.UnixMilli(),
+			Request: model.RequestInfo{
+				URL:          requestURL(req, client.dst),
+				Method:       req.Method,
+				Headers:      headerMap(req.Header, req.Host),
+				ResourceType: "Other",
+			},
+		})
+		x := &out[len(out)-1]
+		x.Request.Body, x.Request.BodyEncoding, _ = encodeBody(body, req.Header)
+		reqs = append(reqs, req)
+		if err != nil {
+			break
+		}
+	}
+
+	sr := newStreamReader(s)
+	for i, req := range reqs {
+		resp, body, ok := readResponse(sr, req)
+		if !ok {
+			break
+		}
+		x := &out[i]
+		x.Response = model.ResponseInfo{
+			StatusCode:    resp.StatusCode,
+			Protocol:      strings.ToLower(resp.Proto),
+			RemoteAddress: net.JoinHostPort(client.dst.addr.String(), strconv.Itoa(int(client.dst.port))),
+			Headers:       headerMap(resp.Header, ""),
+			Timing:        model.ResponseTiming{StartTime: x.Timestamp, EndTime: sr.time().UnixMilli()},
+		}
+		x.Response.Body, x.Response.BodyEncoding, x.Response.BodyTruncated = encodeBody(decodeBody(body, resp.Header), resp.Header)
+		// 协议升级（如 WebSocket）后不再是 HTTP 消息
+		if resp.StatusCode == http.StatusSwitchingProtocols {
+			break
+		}
+	}
+	return out
+}
+
+// readResponse 读取请求对应的最终响应，跳过 100 Continue 等中间响应
+func readResponse(sr *streamReader, req *http.Request) (*http.Response, []byte, bool) {
+	for {
+		resp, err := http.ReadResponse(sr.br, req)
+		if err != nil {
+			return nil, nil, false
+		}
+		body, _ := io.ReadAll(resp.Body)
+		if resp.StatusCode >= 100 && resp.StatusCode < 200 && resp.StatusCode != http.StatusSwitchingProtocols {
+			continue
+		}
+		return resp, body, true
+	}
+}
+
+// requestURL 还原请求的完整 URL；代理形式的请求行已包含完整 URL，否则由 Host 头或服务端地址拼接
+func requestURL(req *http.Request, server endpoint) string {
+	if req.URL.IsAbs() {
+		return req.URL.String()
+	}
+	host := req.Host
+	if host == "" {
+		host = net.JoinHostPort(server.addr.String(), strconv.Itoa(int(server.port)))
+	}
+	return "http://" + host + req.URL.RequestURI()
+}
+
+// headerMap 将头部转换为事件使用的映射，同名头部的多个值以逗号合并；请求的 Host 头被 net/http 单独存放，需补回
+func headerMap(h http.Header, host string) map[string]string {
+	out := make(map[string]string, len(h)+1)
+	for k, v := range h {
+		out[k] = strings.Join(v, ", ")
+	}
+	if host != "" {
+		out["Host"] = host
+	}
+	return out
+}
+
+// decodeBody 按 Content-Encoding 解压响应体，与浏览器上报的已解码内容保持一致；不支持的编码或解压失败时保留原始数据
+func decodeBody(body []byte, h http.Header) []byte {
+	var rd io.Reader
+	var err error
+	switch strings.ToLower(strings.TrimSpace(h.Get("Content-Encoding"))) {
+	case "gzip", "x-gzip":
+		rd, err = gzip.NewReader(bytes.NewReader(body))
+	case "deflate":
+		// deflate 可能带 zlib 头，也可能是裸流
+		if rd, err = zlib.NewReader(bytes.NewReader(body)); err != nil {
+			rd, err = flate.NewReader(bytes.NewReader(body)), nil
+		}
+	default:
+		return body
+	}
+	if err != nil {
+		return body
+	}
+	decoded, err := io.ReadAll(rd)
+	if err != nil && len(decoded) == 0 {
+		return body
+	}
+	return decoded
+}
+
+// encodeBody 将 Body 转换为事件中的表示：文本原样记录，二进制内容使用 base64；超过上限时仅记录前缀
+func encodeBody(body []byte, h http.Header) (string, string, bool) {
+	truncated := len(body) > maxBodySize
+	if truncated {
+		body = body[:maxBodySize]
+	}
+	if len(body) == 0 {
+		return "", "", truncated
+	}
+	if mutation.IsTextualBody(body, h.Get("Content-Type")) {
+		return string(body), "", truncated
+	}
+	return base64.StdEncoding.EncodeToString(body), model.BodyEncodingBase64, truncated
+}
+
+// operation 识别交互中的 API 封装格式，用于按逻辑操作分组
+func operation(x Exchange) *model.APIOperation {
+	env, ok := analyzer.DetectEnvelope(analyzer.Exchange{
+		Method:       x.Request.Method,
+		URL:          x.Request.URL,
+		RequestType:  x.Request.Headers["Content-Type"],
+		RequestBody:  rawBody(x.Request.Body, x.Request.BodyEncoding),
+		ResponseType: x.Response.Headers["Content-Type"],
+		ResponseBody: rawBody(x.Response.Body, x.Response.BodyEncoding),
+		SOAPAction:   x.Request.Headers["Soapaction"],
+	})
+	if !ok {
+		return nil
+	}
+	return &model.APIOperation{Format: env.Format, Resource: env.Resource, Operation: env.Operation, Key: env.Key()}
+}
+
+// rawBody 还原事件 Body 的原始字节
+func rawBody(body, encoding string) []byte {
+	if encoding == model.BodyEncodingBase64 {
+		b, _ := base64.StdEncoding.DecodeString(body)
+		return b
+	}
+	return []byte(body)
+}
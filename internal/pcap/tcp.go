@@ -0,0 +1,239 @@
+package pcap
+
+import (
+	"encoding/binary"
+	"net/netip"
+	"sort"
+	"time"
+)
+
+// endpoint TCP 连接的一端
+type endpoint struct {
+	addr netip.Addr
+	port uint16
+}
+
+// flowKey 单向 TCP 流的标识
+type flowKey struct {
+	src, dst endpoint
+}
+
+// segment 携带数据的 TCP 报文段
+type segment struct {
+	ts      time.Time
+	seq     uint32
+	payload []byte
+}
+
+// halfStream 单向 TCP 流
+type halfStream struct {
+	key    flowKey
+	isn    uint32 // 初始序列号，未捕获到 SYN 时以最小序列号代替
+	hasISN bool
+	segs   []segment
+}
+
+// stream 重组后的单向字节流，marks 记录各段数据在流中的起始位置与时间
+type stream struct {
+	data  []byte
+	marks []streamMark
+}
+
+// streamMark 流中某一位置的数据到达时间
+type streamMark struct {
+	offset int
+	ts     time.Time
+}
+
+// at 返回流中 offset 处数据的到达时间
+func (s *stream) at(offset int) time.Time {
+	i := sort.Search(len(s.marks), func(i int) bool { return s.marks[i].offset > offset })
+	if i == 0 {
+		if len(s.marks) == 0 {
+			return time.Time{}
+		}
+		return s.marks[0].ts
+	}
+	return s.marks[i-1].ts
+}
+
+// tcpTable 按四元组收集 TCP 流，同一四元组上先后建立的多个连接按 SYN 区分
+type tcpTable struct {
+	flows map[flowKey][]*halfStream
+	order []*halfStream
+}
+
+// newTCPTable 创建空的 TCP 流表
+func newTCPTable() *tcpTable {
+	return &tcpTable{flows: make(map[flowKey][]*halfStream)}
+}
+
+// add 解析数据包中的 TCP 报文段并加入对应的流，非 TCP 或无法解析的数据包被忽略
+func (t *tcpTable) add(p packet) {
+	ip, ok := networkLayer(p.link, p.data)
+	if !ok {
+		return
+	}
+	src, dst, tcp, ok := transportLayer(ip)
+	if !ok || len(tcp) < 20 {
+		return
+	}
+	off := int(tcp[12]>>4) * 4
+	if off < 20 || off > len(tcp) {
+		return
+	}
+	seq := binary.BigEndian.Uint32(tcp[4:8])
+	syn := tcp[13]&0x02 != 0
+	key := flowKey{
+		src: endpoint{src, binary.BigEndian.Uint16(tcp[0:2])},
+		dst: endpoint{dst, binary.BigEndian.Uint16(tcp[2:4])},
+	}
+
+	list := t.flows[key]
+	var hs *halfStream
+	if n := len(list); n > 0 && !(syn && len(list[n-1].segs) > 0) {
+		hs = list[n-1]
+	} else {
+		hs = &halfStream{key: key}
+		t.flows[key] = append(list, hs)
+		t.order = append(t.order, hs)
+	}
+	if syn {
+		hs.isn, hs.hasISN = seq+1, true
+	}
+	if payload := tcp[off:]; len(payload) > 0 {
+		hs.segs = append(hs.segs, segment{ts: p.ts, seq: seq, payload: payload})
+	}
+}
+
+// connections 按首次出现的先后返回双向连接，每个连接的两个方向以相同的先后次序配对
+func (t *tcpTable) connections() [][2]*halfStream {
+	var out [][2]*halfStream
+	seen := make(map[*halfStream]bool)
+	for _, hs := range t.order {
+		if seen[hs] {
+			continue
+		}
+		seen[hs] = true
+		var peer *halfStream
+		list := t.flows[hs.key]
+		rev := t.flows[flowKey{src: hs.key.dst, dst: hs.key.src}]
+		for i, h := range list {
+			if h == hs && i < len(rev) {
+				peer = rev[i]
+				seen[peer] = true
+			}
+		}
+		out = append(out, [2]*halfStream{hs, peer})
+	}
+	return out
+}
+
+// assemble 按序列号重组单向流，重传的数据被去重，遇到缺失的数据时截止
+func (hs *halfStream) assemble() *stream {
+	s := &stream{}
+	if hs == nil || len(hs.segs) == 0 {
+		return s
+	}
+	base := hs.isn
+	if !hs.hasISN {
+		base = hs.segs[0].seq
+		for _, seg := range hs.segs[1:] {
+			if int32(seg.seq-base) < 0 {
+				base = seg.seq
+			}
+		}
+	}
+	segs := append([]segment(nil), hs.segs...)
+	sort.SliceStable(segs, func(i, j int) bool { return int32(segs[i].seq-base) < int32(segs[j].seq-base) })
+
+	next := 0
+	for _, seg := range segs {
+		off := int(int32(seg.seq - base))
+		end := off + len(seg.payload)
+		if off < 0 || end <= next {
+			continue
+		}
+		if off > next {
+			break
+		}
+		s.marks = append(s.marks, streamMark{offset: len(s.data), ts: seg.ts})
+		s.data = append(s.data, seg.payload[next-off:]...)
+		next = end
+	}
+	return s
+}
+
+// networkLayer 去掉链路层头部，返回 IP 数据报
+func networkLayer(link uint16, data []byte) ([]byte, bool) {
+	switch link {
+	case linkEthernet:
+		if len(data) < 14 {
+			return nil, false
+		}
+		etherType, rest := binary.BigEndian.Uint16(data[12:14]), data[14:]
+		// 跳过 VLAN 标签
+		for (etherType == 0x8100 || etherType == 0x88a8) && len(rest) >= 4 {
+			etherType, rest = binary.BigEndian.Uint16(rest[2:4]), rest[4:]
+		}
+		return rest, etherType == 0x0800 || etherType == 0x86dd
+	case linkNull, linkLoop:
+		if len(data) < 4 {
+			return nil, false
+		}
+		return data[4:], true
+	case linkLinuxSLL:
+		if len(data) < 16 {
+			return nil, false
+		}
+		return data[16:], true
+	case linkLinuxSLL2:
+		if len(data) < 20 {
+			return nil, false
+		}
+		return data[20:], true
+	case linkRaw, linkIPv4, linkIPv6:
+		return data, true
+	}
+	return nil, false
+}
+
+// transportLayer 解析 IPv4/IPv6 头部，返回源地址、目的地址和 TCP 报文；分片的数据报被忽略
+func transportLayer(ip []byte) (netip.Addr, netip.Addr, []byte, bool) {
+	if len(ip) < 1 {
+		return netip.Addr{}, netip.Addr{}, nil, false
+	}
+	switch ip[0] >> 4 {
+	case 4:
+		if len(ip) < 20 {
+			break
+		}
+		ihl := int(ip[0]&0x0f) * 4
+		total := int(binary.BigEndian.Uint16(ip[2:4]))
+		frag := binary.BigEndian.Uint16(ip[6:8])
+		if ip[9] != 6 || ihl < 20 || frag&0x3fff != 0 {
+			break
+		}
+		// 以太网帧可能带有填充，按总长度截取；抓包长度不足时使用实际长度
+		if total >= ihl && total < len(ip) {
+			ip = ip[:total]
+		}
+		if ihl > len(ip) {
+			break
+		}
+		src, _ := netip.AddrFromSlice(ip[12:16])
+		dst, _ := netip.AddrFromSlice(ip[16:20])
+		return src, dst, ip[ihl:], true
+	case 6:
+		if len(ip) < 40 || ip[6] != 6 {
+			break
+		}
+		if n := 40 + int(binary.BigEndian.Uint16(ip[4:6])); n < len(ip) {
+			ip = ip[:n]
+		}
+		src, _ := netip.AddrFromSlice(ip[8:24])
+		dst, _ := netip.AddrFromSlice(ip[24:40])
+		return src, dst, ip[40:], true
+	}
+	return netip.Addr{}, netip.Addr{}, nil, false
+}
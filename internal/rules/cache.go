@@ -0,0 +1,121 @@
+package rules
+
+import (
+	"crypto"
+	"regexp"
+	"sync"
+)
+
+// maxCachedPatterns 单个缓存保留的正则数量上限，超出后清空重建，避免动态生成的模式无限增长
+const maxCachedPatterns = 1024
+
+// Cache 规则求值使用的正则、表达式、JSONPath 与公钥缓存。每个会话默认持有独立的缓存，
+// 一个会话中的规则不会影响另一个会话的内存占用；需要共享时使用 SharedCache
+type Cache struct {
+	mu      sync.Mutex
+	regexps map[string]*regexp.Regexp
+	exprs   map[string]exprNode // 表达式原文 -> 语法树
+	paths   map[string]jsonPath // JSONPath 原文 -> 解析结果
+	keys    sync.Map            // PEM 原文 -> 已解析的公钥
+}
+
+// sharedCache 跨会话共享的缓存
+var sharedCache = NewCache()
+
+// NewCache 创建独立的缓存
+func NewCache() *Cache {
+	return &Cache{
+		regexps: make(map[string]*regexp.Regexp),
+		exprs:   make(map[string]exprNode),
+		paths:   make(map[string]jsonPath),
+	}
+}
+
+// SharedCache 返回进程内跨会话共享的缓存
+func SharedCache() *Cache {
+	return sharedCache
+}
+
+// Regexp 返回缓存中的正则或编译后加入缓存
+func (c *Cache) Regexp(p string) (*regexp.Regexp, error) {
+	c.mu.Lock()
+	re, ok := c.regexps[p]
+	c.mu.Unlock()
+	if ok {
+		return re, nil
+	}
+	compiled, err := regexp.Compile(p)
+	if err != nil {
+		return nil, err
+	}
+	c.mu.Lock()
+	if len(c.regexps) >= maxCachedPatterns {
+		c.regexps = make(map[string]*regexp.Regexp)
+	}
+	c.regexps[p] = compiled
+	c.mu.Unlock()
+	return compiled, nil
+}
+
+// Expression 返回缓存中的表达式语法树或解析后加入缓存
+func (c *Cache) Expression(src string) (exprNode, error) {
+	c.mu.Lock()
+	node, ok := c.exprs[src]
+	c.mu.Unlock()
+	if ok {
+		return node, nil
+	}
+	parsed, err := parseExpr(src)
+	if err != nil {
+		return nil, err
+	}
+	c.mu.Lock()
+	if len(c.exprs) >= maxCachedPatterns {
+		c.exprs = make(map[string]exprNode)
+	}
+	c.exprs[src] = parsed
+	c.mu.Unlock()
+	return parsed, nil
+}
+
+// JSONPath 返回缓存中的 JSONPath 或解析后加入缓存
+func (c *Cache) JSONPath(src string) (jsonPath, error) {
+	c.mu.Lock()
+	path, ok := c.paths[src]
+	c.mu.Unlock()
+	if ok {
+		return path, nil
+	}
+	parsed, err := parseJSONPath(src)
+	if err != nil {
+		return nil, err
+	}
+	c.mu.Lock()
+	if len(c.paths) >= maxCachedPatterns {
+		c.paths = make(map[string]jsonPath)
+	}
+	c.paths[src] = parsed
+	c.mu.Unlock()
+	return parsed, nil
+}
+
+// Len 返回缓存的正则数量
+func (c *Cache) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return len(c.regexps)
+}
+
+// publicKey 返回缓存中已解析的公钥
+func (c *Cache) publicKey(key string) (crypto.PublicKey, bool) {
+	v, ok := c.keys.Load(key)
+	if !ok {
+		return nil, false
+	}
+	return v.(crypto.PublicKey), true
+}
+
+// storePublicKey 缓存已解析的公钥
+func (c *Cache) storePublicKey(key string, pub crypto.PublicKey) {
+	c.keys.Store(key, pub)
+}
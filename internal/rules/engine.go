@@ -5,34 +5,56 @@ import (
 	"sort"
 	"strings"
 	"sync"
+	"time"
 
 	"cdpnetool/pkg/rulespec"
-
-	"github.com/tidwall/gjson"
 )
 
 // Engine 规则引擎
 type Engine struct {
 	config  *rulespec.Config
+	cache   *Cache
 	mu      sync.RWMutex
 	total   int64
 	matched int64
 	byRule  map[string]int64
+	byTag   map[string]int64 // 小写标签 -> 命中次数
+
+	counters map[string]int64                    // 规则 ID -> 条件已满足的次数，供 count 计数条件使用；更新配置时清零
+	windows  map[*rulespec.Condition][]time.Time // rateLimit 条件 -> 窗口内的请求时间；更新配置时清空
 }
 
-// New 创建规则引擎
-func New(config *rulespec.Config) *Engine {
+// New 创建规则引擎，cache 为 nil 时使用独立的缓存
+func New(config *rulespec.Config, cache *Cache) *Engine {
+	if cache == nil {
+		cache = NewCache()
+	}
 	return &Engine{
 		config: config,
+		cache:  cache,
 		byRule: make(map[string]int64),
+		byTag:  make(map[string]int64),
+
+		counters: make(map[string]int64),
+		windows:  make(map[*rulespec.Condition][]time.Time),
 	}
 }
 
-// Update 更新配置
+// Update 更新配置，规则的计数与频率窗口从头开始
 func (e *Engine) Update(config *rulespec.Config) {
 	e.mu.Lock()
 	defer e.mu.Unlock()
 	e.config = config
+	e.counters = make(map[string]int64)
+	e.windows = make(map[*rulespec.Condition][]time.Time)
+}
+
+// Replace 替换配置但保留规则计数与频率窗口，用于按标签启停等不改变规则条件的修改；
+// 频率窗口按条件地址记录，新配置需与原配置共用匹配条件
+func (e *Engine) Replace(config *rulespec.Config) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.config = config
 }
 
 // GetConfig 获取当前配置
@@ -44,30 +66,61 @@ func (e *Engine) GetConfig() *rulespec.Config {
 
 // EvalContext 评估上下文（基于请求信息）
 type EvalContext struct {
-	URL          string            // 请求 URL
-	Method       string            // HTTP 方法
-	Headers      map[string]string // 请求头
-	Query        map[string]string // 查询参数
-	Cookies      map[string]string // Cookie
-	Body         string            // 请求体
-	ResourceType string            // 资源类型
+	URL            string              // 请求 URL
+	Method         string              // HTTP 方法
+	Headers        map[string]string   // 请求头
+	Query          map[string]string   // 查询参数
+	Cookies        map[string]string   // Cookie
+	Body           string              // 请求体
+	ResourceType   string              // 资源类型
+	Protocol       string              // 请求所属源最近一次协商的协议（h2 / h3 / http/1.1），未知时为空
+	BlockedCookies map[string][]string // 被浏览器阻止的 Cookie（小写名称 -> 原因），含未随请求发送的 Cookie 与被拒绝保存的 Set-Cookie
+
+	StatusCode      int               // 响应状态码，仅响应阶段有值
+	ResponseHeaders map[string]string // 响应头（名称小写），仅响应阶段有值
+
+	urlNormalize *rulespec.URLNormalize // 当前规则的 URL 规范化选项，URL 已按此规范化
+	reevaluate   bool                   // 再次评估，rateLimit 条件不记录本次请求
 }
 
 // MatchedRule 匹配的规则
 type MatchedRule struct {
-	Rule *rulespec.Rule // 规则引用
+	Rule   *rulespec.Rule    // 规则引用
+	Params map[string]string // 匹配过程中绑定的变量（如 path.id），供行为模板使用
+	Index  int               // 规则在配置中的位置
 }
 
 // EvalForStage 评估指定阶段的匹配规则，返回按优先级排序的规则列表
 func (e *Engine) EvalForStage(ctx *EvalContext, stage rulespec.Stage) []*MatchedRule {
+	return e.eval(ctx, stage, true)
+}
+
+// Reevaluate 对已经评估过的请求再次评估（如收到认证质询时），不计入统计，也不推进规则计数
+func (e *Engine) Reevaluate(ctx *EvalContext, stage rulespec.Stage) []*MatchedRule {
+	return e.eval(ctx, stage, false)
+}
+
+// eval 评估指定阶段的匹配规则，record 为 false 时不计入统计和规则计数
+func (e *Engine) eval(ctx *EvalContext, stage rulespec.Stage, record bool) []*MatchedRule {
 	e.mu.Lock()
-	e.total++
+	if record {
+		e.total++
+	}
 	config := e.config
 	e.mu.Unlock()
 
 	if config == nil || len(config.Rules) == 0 {
 		return nil
 	}
+	// 国际化域名统一按 Punycode 形式匹配
+	if !isASCII(ctx.URL) || !record {
+		c := *ctx
+		if !isASCII(c.URL) {
+			c.URL = ASCIIHost(c.URL)
+		}
+		c.reevaluate = !record
+		ctx = &c
+	}
 
 	var matched []*MatchedRule
 	for i := range config.Rules {
@@ -80,9 +133,20 @@ func (e *Engine) EvalForStage(ctx *EvalContext, stage rulespec.Stage) []*Matched
 		if rule.Stage != stage {
 			continue
 		}
-		// 评估匹配条件
-		if matchRule(ctx, &rule.Match) {
-			matched = append(matched, &MatchedRule{Rule: rule})
+		// 评估匹配条件，规则启用了 URL 规范化时基于规范化后的 URL
+		ruleCtx := ctx
+		if rule.URLNormalize.Enabled() {
+			c := *ctx
+			c.URL = normalizeURL(ctx.URL, rule.URLNormalize)
+			c.urlNormalize = rule.URLNormalize
+			ruleCtx = &c
+		}
+		if !e.requirementsMet(rule) {
+			continue
+		}
+		params := make(map[string]string)
+		if e.matchRule(ruleCtx, &rule.Match, params) && e.countAllows(rule, record) {
+			matched = append(matched, &MatchedRule{Rule: rule, Params: params, Index: i})
 		}
 	}
 
@@ -90,28 +154,73 @@ func (e *Engine) EvalForStage(ctx *EvalContext, stage rulespec.Stage) []*Matched
 		return nil
 	}
 
-	// 按优先级从大到小排序
-	sort.Slice(matched, func(i, j int) bool {
+	// 按优先级从大到小排序，优先级相同时保持配置中的顺序（与 Config.EvaluationOrder 一致）
+	sort.SliceStable(matched, func(i, j int) bool {
 		return matched[i].Rule.Priority > matched[j].Rule.Priority
 	})
 
+	// 设置了 stopProcessing 的规则之后的低优先级规则不再生效
+	for i, m := range matched {
+		if m.Rule.StopProcessing {
+			matched = matched[:i+1]
+			break
+		}
+	}
+
+	if !record {
+		return matched
+	}
+
 	// 更新统计
 	e.mu.Lock()
 	e.matched++
 	for _, m := range matched {
 		e.byRule[m.Rule.ID]++
+		for _, tag := range m.Rule.Tags {
+			e.byTag[strings.ToLower(tag)]++
+		}
 	}
 	e.mu.Unlock()
 
 	return matched
 }
 
-// matchRule 评估匹配规则
-func matchRule(ctx *EvalContext, m *rulespec.Match) bool {
+// countAllows 记录规则条件又一次满足，并按规则的计数条件判断本次是否生效；record 为 false 时只按当前次数判断
+func (e *Engine) countAllows(rule *rulespec.Rule, record bool) bool {
+	if rule.Count == nil {
+		return true
+	}
+	e.mu.Lock()
+	n := e.counters[rule.ID]
+	if record {
+		n++
+		e.counters[rule.ID] = n
+	}
+	e.mu.Unlock()
+	return rule.Count.Allows(n)
+}
+
+// requirementsMet 判断规则依赖的规则是否都已在会话中命中过；同一请求中先命中的依赖要到下一个请求才生效
+func (e *Engine) requirementsMet(rule *rulespec.Rule) bool {
+	if len(rule.RequiresRuleMatched) == 0 {
+		return true
+	}
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	for _, id := range rule.RequiresRuleMatched {
+		if e.byRule[id] == 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// matchRule 评估匹配规则，params 用于收集条件绑定的变量
+func (e *Engine) matchRule(ctx *EvalContext, m *rulespec.Match, params map[string]string) bool {
 	// allOf: 所有条件都必须满足
 	if len(m.AllOf) > 0 {
 		for i := range m.AllOf {
-			if !evalCondition(ctx, &m.AllOf[i]) {
+			if !e.evalCondition(ctx, &m.AllOf[i], params) {
 				return false
 			}
 		}
@@ -120,7 +229,7 @@ func matchRule(ctx *EvalContext, m *rulespec.Match) bool {
 	if len(m.AnyOf) > 0 {
 		anyMatch := false
 		for i := range m.AnyOf {
-			if evalCondition(ctx, &m.AnyOf[i]) {
+			if e.evalCondition(ctx, &m.AnyOf[i], params) {
 				anyMatch = true
 				break
 			}
@@ -133,19 +242,23 @@ func matchRule(ctx *EvalContext, m *rulespec.Match) bool {
 }
 
 // evalCondition 评估单个条件
-func evalCondition(ctx *EvalContext, c *rulespec.Condition) bool {
+func (e *Engine) evalCondition(ctx *EvalContext, c *rulespec.Condition, params map[string]string) bool {
 	switch c.Type {
-	// URL 条件
+	// URL 条件（条件值中的国际化域名按 Punycode 形式比较）
 	case rulespec.ConditionURLEquals:
-		return ctx.URL == c.Value
+		return ctx.URL == normalizeURL(ASCIIHost(c.Value), ctx.urlNormalize)
 	case rulespec.ConditionURLPrefix:
-		return strings.HasPrefix(ctx.URL, c.Value)
+		return strings.HasPrefix(ctx.URL, ASCIIHost(c.Value))
 	case rulespec.ConditionURLSuffix:
-		return strings.HasSuffix(ctx.URL, c.Value)
+		return strings.HasSuffix(ctx.URL, ASCIIHost(c.Value))
 	case rulespec.ConditionURLContains:
-		return strings.Contains(ctx.URL, c.Value)
+		return strings.Contains(ctx.URL, ASCIIHost(c.Value))
 	case rulespec.ConditionURLRegex:
-		return matchRegex(ctx.URL, c.Pattern)
+		return e.matchRegex(ctx.URL, c.Pattern)
+	case rulespec.ConditionPathPattern:
+		return matchPathPattern(ctx.URL, c.Value, params)
+	case rulespec.ConditionURLGlob:
+		return e.matchURLGlob(ctx.URL, ASCIIHost(c.Value))
 
 	// Method 条件
 	case rulespec.ConditionMethod:
@@ -165,6 +278,10 @@ func evalCondition(ctx *EvalContext, c *rulespec.Condition) bool {
 		}
 		return false
 
+	// Protocol 条件
+	case rulespec.ConditionProtocolEquals:
+		return ctx.Protocol != "" && strings.EqualFold(ctx.Protocol, strings.TrimSpace(c.Value))
+
 	// Header 条件
 	case rulespec.ConditionHeaderExists:
 		_, ok := getHeaderCaseInsensitive(ctx.Headers, c.Name)
@@ -180,7 +297,7 @@ func evalCondition(ctx *EvalContext, c *rulespec.Condition) bool {
 		return ok && strings.Contains(v, c.Value)
 	case rulespec.ConditionHeaderRegex:
 		v, ok := getHeaderCaseInsensitive(ctx.Headers, c.Name)
-		return ok && matchRegex(v, c.Pattern)
+		return ok && e.matchRegex(v, c.Pattern)
 
 	// Query 条件（key 统一小写匹配）
 	case rulespec.ConditionQueryExists:
@@ -197,7 +314,7 @@ func evalCondition(ctx *EvalContext, c *rulespec.Condition) bool {
 		return ok && strings.Contains(v, c.Value)
 	case rulespec.ConditionQueryRegex:
 		v, ok := ctx.Query[strings.ToLower(c.Name)]
-		return ok && matchRegex(v, c.Pattern)
+		return ok && e.matchRegex(v, c.Pattern)
 
 	// Cookie 条件（name 统一小写匹配）
 	case rulespec.ConditionCookieExists:
@@ -214,22 +331,61 @@ func evalCondition(ctx *EvalContext, c *rulespec.Condition) bool {
 		return ok && strings.Contains(v, c.Value)
 	case rulespec.ConditionCookieRegex:
 		v, ok := ctx.Cookies[strings.ToLower(c.Name)]
-		return ok && matchRegex(v, c.Pattern)
+		return ok && e.matchRegex(v, c.Pattern)
+	case rulespec.ConditionCookieBlocked:
+		return cookieBlocked(ctx.BlockedCookies, c.Name, c.Value)
 
 	// Body 条件
 	case rulespec.ConditionBodyContains:
 		return strings.Contains(ctx.Body, c.Value)
 	case rulespec.ConditionBodyRegex:
-		return matchRegex(ctx.Body, c.Pattern)
+		return e.matchRegex(ctx.Body, c.Pattern)
 	case rulespec.ConditionBodyJsonPath:
-		val, ok := evalJsonPath(ctx.Body, c.Path)
-		return ok && val == c.Value
+		return e.matchBodyJSONPath(ctx.Body, c)
+
+	// JWT 条件
+	case rulespec.ConditionJWTClaim:
+		return e.matchJWTClaim(ctx, c, params)
+
+	// 表达式条件
+	case rulespec.ConditionExpression:
+		return e.matchExpression(ctx, c.Value)
+
+	// 频率条件
+	case rulespec.ConditionRateLimit:
+		return e.rateExceeded(ctx, c)
+
+	// 时间条件
+	case rulespec.ConditionTimeWindow:
+		return inTimeWindow(c, time.Now())
+
+	// 概率条件
+	case rulespec.ConditionProbability:
+		return sampled(c.Percent)
 
 	default:
 		return false
 	}
 }
 
+// cookieBlocked 判断是否有 Cookie 被浏览器阻止；name 不为空时只看该 Cookie，reason 不为空时要求原因匹配（不区分大小写）
+func cookieBlocked(blocked map[string][]string, name, reason string) bool {
+	for n, reasons := range blocked {
+		if name != "" && n != strings.ToLower(name) {
+			continue
+		}
+		if reason == "" {
+			return true
+		}
+		for _, r := range reasons {
+			if strings.EqualFold(r, reason) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
 // getHeaderCaseInsensitive 不区分大小写获取 Header
 func getHeaderCaseInsensitive(headers map[string]string, name string) (string, bool) {
 	// 先尝试精确匹配
@@ -246,28 +402,9 @@ func getHeaderCaseInsensitive(headers map[string]string, name string) (string, b
 	return "", false
 }
 
-// evalJsonPath 评估 JSON Path，使用 gjson 支持完整语法
-func evalJsonPath(body, path string) (string, bool) {
-	if body == "" || path == "" {
-		return "", false
-	}
-	// 处理 $. 前缀以保持对标准 JSONPath 的兼容性感官，gjson 默认直接从根开始
-	searchPath := path
-	if strings.HasPrefix(path, "$.") {
-		searchPath = path[2:]
-	}
-
-	result := gjson.Get(body, searchPath)
-	if !result.Exists() {
-		return "", false
-	}
-
-	return result.String(), true
-}
-
-// matchRegex 使用缓存的正则进行匹配
-func matchRegex(s, pattern string) bool {
-	re, err := regexCache.Get(pattern)
+// matchRegex 使用会话缓存的正则进行匹配
+func (e *Engine) matchRegex(s, pattern string) bool {
+	re, err := e.cache.Regexp(pattern)
 	if err != nil {
 		return false
 	}
@@ -276,9 +413,11 @@ func matchRegex(s, pattern string) bool {
 
 // Stats 返回统计信息
 type Stats struct {
-	Total   int64
-	Matched int64
-	ByRule  map[string]int64
+	Total          int64
+	Matched        int64
+	ByRule         map[string]int64
+	ByTag          map[string]int64
+	CachedPatterns int // 引擎所用缓存中的正则数量
 }
 
 // GetStats 获取统计信息
@@ -289,10 +428,16 @@ func (e *Engine) GetStats() Stats {
 	for k, v := range e.byRule {
 		byRule[k] = v
 	}
+	byTag := make(map[string]int64, len(e.byTag))
+	for k, v := range e.byTag {
+		byTag[k] = v
+	}
 	return Stats{
-		Total:   e.total,
-		Matched: e.matched,
-		ByRule:  byRule,
+		Total:          e.total,
+		Matched:        e.matched,
+		ByRule:         byRule,
+		ByTag:          byTag,
+		CachedPatterns: e.cache.Len(),
 	}
 }
 
@@ -303,4 +448,5 @@ func (e *Engine) ResetStats() {
 	e.total = 0
 	e.matched = 0
 	e.byRule = make(map[string]int64)
+	e.byTag = make(map[string]int64)
 }
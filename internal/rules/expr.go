@@ -0,0 +1,810 @@
+package rules
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"reflect"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// expression 条件使用的表达式语言，语法取自 CEL 的常用子集：
+//   - 字面量：字符串（单/双引号）、数字、true / false / null、列表 [a, b]
+//   - 运算：! - * / % + - < <= > >= == != in && || 以及三元 a ? b : c
+//   - 访问：a.b、a["b"]、a[0]；访问不存在的键得到 null
+//   - 函数：size(x)、has(a.b)、int(x)、string(x)
+//   - 方法：s.contains(x)、s.startsWith(x)、s.endsWith(x)、s.matches(re)、s.lowerAscii()、s.upperAscii()、s.trim()、x.size()
+// 类型不匹配、调用未知函数等求值错误时条件不成立
+
+// exprNode 表达式语法树节点
+type exprNode interface{}
+
+type (
+	litNode    struct{ v any }
+	identNode  struct{ name string }
+	listNode   struct{ items []exprNode }
+	memberNode struct {
+		x    exprNode
+		name string
+	}
+	indexNode struct{ x, index exprNode }
+	callNode  struct {
+		recv exprNode // 方法调用的接收者，全局函数为 nil
+		name string
+		args []exprNode
+	}
+	unaryNode struct {
+		op string
+		x  exprNode
+	}
+	binaryNode struct {
+		op   string
+		l, r exprNode
+	}
+	condNode struct{ cond, then, els exprNode }
+)
+
+// exprToken 词法单元
+type exprToken struct {
+	kind byte // 'i' 标识符，'n' 数字，'s' 字符串，'o' 运算符，0 结束
+	text string
+	pos  int
+}
+
+// exprOperators 运算符，双字符的排在前面以便优先匹配
+var exprOperators = []string{"&&", "||", "==", "!=", "<=", ">=", "<", ">", "!", "+", "-", "*", "/", "%", "(", ")", "[", "]", ".", ",", "?", ":"}
+
+// lexExpr 将表达式切分为词法单元
+func lexExpr(src string) ([]exprToken, error) {
+	var out []exprToken
+	for i := 0; i < len(src); {
+		r, size := utf8.DecodeRuneInString(src[i:])
+		switch {
+		case unicode.IsSpace(r):
+			i += size
+		case r == '_' || unicode.IsLetter(r):
+			start := i
+			for i < len(src) {
+				r, size := utf8.DecodeRuneInString(src[i:])
+				if r != '_' && !unicode.IsLetter(r) && !unicode.IsDigit(r) {
+					break
+				}
+				i += size
+			}
+			out = append(out, exprToken{kind: 'i', text: src[start:i], pos: start})
+		case r >= '0' && r <= '9':
+			start := i
+			for i < len(src) && (src[i] >= '0' && src[i] <= '9' || src[i] == '.') {
+				i++
+			}
+			out = append(out, exprToken{kind: 'n', text: src[start:i], pos: start})
+		case r == '"' || r == '\'':
+			start := i
+			i++
+			var b strings.Builder
+			for {
+				if i >= len(src) {
+					return nil, fmt.Errorf("字符串未结束（位置 %d）", start)
+				}
+				c := src[i]
+				if c == byte(r) {
+					i++
+					break
+				}
+				if c == '\\' && i+1 < len(src) {
+					i++
+					switch src[i] {
+					case 'n':
+						b.WriteByte('\n')
+					case 't':
+						b.WriteByte('\t')
+					case 'r':
+						b.WriteByte('\r')
+					default:
+						b.WriteByte(src[i])
+					}
+					i++
+					continue
+				}
+				b.WriteByte(c)
+				i++
+			}
+			out = append(out, exprToken{kind: 's', text: b.String(), pos: start})
+		default:
+			matched := false
+			for _, op := range exprOperators {
+				if strings.HasPrefix(src[i:], op) {
+					out = append(out, exprToken{kind: 'o', text: op, pos: i})
+					i += len(op)
+					matched = true
+					break
+				}
+			}
+			if !matched {
+				return nil, fmt.Errorf("无法识别的字符 %q（位置 %d）", r, i)
+			}
+		}
+	}
+	return append(out, exprToken{pos: len(src)}), nil
+}
+
+// exprParser 递归下降解析器，优先级从低到高：三元、||、&&、关系运算、加减、乘除、一元、成员访问
+type exprParser struct {
+	toks []exprToken
+	pos  int
+}
+
+// parseExpr 解析表达式
+func parseExpr(src string) (exprNode, error) {
+	toks, err := lexExpr(src)
+	if err != nil {
+		return nil, err
+	}
+	p := &exprParser{toks: toks}
+	n, err := p.ternary()
+	if err != nil {
+		return nil, err
+	}
+	if t := p.peek(); t.kind != 0 {
+		return nil, fmt.Errorf("多余的内容 %q（位置 %d）", t.text, t.pos)
+	}
+	return n, nil
+}
+
+func (p *exprParser) peek() exprToken { return p.toks[p.pos] }
+
+func (p *exprParser) next() exprToken {
+	t := p.toks[p.pos]
+	if t.kind != 0 {
+		p.pos++
+	}
+	return t
+}
+
+// accept 当前词法单元是指定运算符时消费它
+func (p *exprParser) accept(op string) bool {
+	if t := p.peek(); t.kind == 'o' && t.text == op {
+		p.pos++
+		return true
+	}
+	return false
+}
+
+// expect 消费指定运算符，不匹配时报错
+func (p *exprParser) expect(op string) error {
+	if !p.accept(op) {
+		t := p.peek()
+		return fmt.Errorf("缺少 %q（位置 %d）", op, t.pos)
+	}
+	return nil
+}
+
+func (p *exprParser) ternary() (exprNode, error) {
+	cond, err := p.binary(0)
+	if err != nil || !p.accept("?") {
+		return cond, err
+	}
+	then, err := p.ternary()
+	if err != nil {
+		return nil, err
+	}
+	if err := p.expect(":"); err != nil {
+		return nil, err
+	}
+	els, err := p.ternary()
+	if err != nil {
+		return nil, err
+	}
+	return condNode{cond, then, els}, nil
+}
+
+// exprLevels 二元运算符按优先级从低到高分组
+var exprLevels = [][]string{
+	{"||"},
+	{"&&"},
+	{"==", "!=", "<", "<=", ">", ">=", "in"},
+	{"+", "-"},
+	{"*", "/", "%"},
+}
+
+func (p *exprParser) binary(level int) (exprNode, error) {
+	if level == len(exprLevels) {
+		return p.unary()
+	}
+	l, err := p.binary(level + 1)
+	if err != nil {
+		return nil, err
+	}
+	for {
+		t := p.peek()
+		op := ""
+		for _, candidate := range exprLevels[level] {
+			if (t.kind == 'o' || t.kind == 'i') && t.text == candidate {
+				op = candidate
+				break
+			}
+		}
+		if op == "" {
+			return l, nil
+		}
+		p.next()
+		r, err := p.binary(level + 1)
+		if err != nil {
+			return nil, err
+		}
+		l = binaryNode{op, l, r}
+	}
+}
+
+func (p *exprParser) unary() (exprNode, error) {
+	for _, op := range []string{"!", "-"} {
+		if p.accept(op) {
+			x, err := p.unary()
+			if err != nil {
+				return nil, err
+			}
+			return unaryNode{op, x}, nil
+		}
+	}
+	return p.postfix()
+}
+
+func (p *exprParser) postfix() (exprNode, error) {
+	x, err := p.primary()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		switch {
+		case p.accept("."):
+			t := p.next()
+			if t.kind != 'i' {
+				return nil, fmt.Errorf("'.' 之后需要名称（位置 %d）", t.pos)
+			}
+			if p.accept("(") {
+				args, err := p.args()
+				if err != nil {
+					return nil, err
+				}
+				x = callNode{recv: x, name: t.text, args: args}
+			} else {
+				x = memberNode{x, t.text}
+			}
+		case p.accept("["):
+			index, err := p.ternary()
+			if err != nil {
+				return nil, err
+			}
+			if err := p.expect("]"); err != nil {
+				return nil, err
+			}
+			x = indexNode{x, index}
+		default:
+			return x, nil
+		}
+	}
+}
+
+// args 解析调用参数，左括号已消费
+func (p *exprParser) args() ([]exprNode, error) {
+	var out []exprNode
+	if p.accept(")") {
+		return out, nil
+	}
+	for {
+		a, err := p.ternary()
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, a)
+		if p.accept(")") {
+			return out, nil
+		}
+		if err := p.expect(","); err != nil {
+			return nil, err
+		}
+	}
+}
+
+func (p *exprParser) primary() (exprNode, error) {
+	t := p.next()
+	switch t.kind {
+	case 'n':
+		v, err := strconv.ParseFloat(t.text, 64)
+		if err != nil {
+			return nil, fmt.Errorf("无效的数字 %q（位置 %d）", t.text, t.pos)
+		}
+		return litNode{v}, nil
+	case 's':
+		return litNode{t.text}, nil
+	case 'i':
+		switch t.text {
+		case "true":
+			return litNode{true}, nil
+		case "false":
+			return litNode{false}, nil
+		case "null":
+			return litNode{nil}, nil
+		}
+		if p.accept("(") {
+			args, err := p.args()
+			if err != nil {
+				return nil, err
+			}
+			return callNode{name: t.text, args: args}, nil
+		}
+		return identNode{t.text}, nil
+	case 'o':
+		switch t.text {
+		case "(":
+			x, err := p.ternary()
+			if err != nil {
+				return nil, err
+			}
+			return x, p.expect(")")
+		case "[":
+			var items []exprNode
+			if p.accept("]") {
+				return listNode{items}, nil
+			}
+			for {
+				item, err := p.ternary()
+				if err != nil {
+					return nil, err
+				}
+				items = append(items, item)
+				if p.accept("]") {
+					return listNode{items}, nil
+				}
+				if err := p.expect(","); err != nil {
+					return nil, err
+				}
+			}
+		}
+	case 0:
+		return nil, fmt.Errorf("表达式不完整")
+	}
+	return nil, fmt.Errorf("意外的 %q（位置 %d）", t.text, t.pos)
+}
+
+// exprEnv 表达式求值环境，jsonBody 在首次访问时解析
+type exprEnv struct {
+	ctx      *EvalContext
+	cache    *Cache
+	json     any
+	jsonDone bool
+}
+
+// lookup 返回变量的值，未知变量报错
+func (env *exprEnv) lookup(name string) (any, error) {
+	c := env.ctx
+	switch name {
+	case "url":
+		return c.URL, nil
+	case "host", "path":
+		u, err := url.Parse(c.URL)
+		if err != nil {
+			return nil, nil
+		}
+		if name == "host" {
+			return u.Hostname(), nil
+		}
+		return u.Path, nil
+	case "method":
+		return c.Method, nil
+	case "headers":
+		return c.Headers, nil
+	case "query":
+		return c.Query, nil
+	case "cookies":
+		return c.Cookies, nil
+	case "body":
+		return c.Body, nil
+	case "jsonBody":
+		if !env.jsonDone {
+			env.jsonDone = true
+			_ = json.Unmarshal([]byte(c.Body), &env.json)
+		}
+		return env.json, nil
+	case "resourceType":
+		return c.ResourceType, nil
+	case "protocol":
+		return c.Protocol, nil
+	case "status":
+		if c.StatusCode == 0 {
+			return nil, nil
+		}
+		return float64(c.StatusCode), nil
+	case "responseHeaders":
+		return c.ResponseHeaders, nil
+	}
+	return nil, fmt.Errorf("未知变量 %s", name)
+}
+
+// eval 对语法树求值
+func (env *exprEnv) eval(n exprNode) (any, error) {
+	switch n := n.(type) {
+	case litNode:
+		return n.v, nil
+	case identNode:
+		return env.lookup(n.name)
+	case listNode:
+		out := make([]any, len(n.items))
+		for i, item := range n.items {
+			v, err := env.eval(item)
+			if err != nil {
+				return nil, err
+			}
+			out[i] = v
+		}
+		return out, nil
+	case memberNode:
+		x, err := env.eval(n.x)
+		if err != nil {
+			return nil, err
+		}
+		v, _, err := index(x, n.name)
+		return v, err
+	case indexNode:
+		x, err := env.eval(n.x)
+		if err != nil {
+			return nil, err
+		}
+		i, err := env.eval(n.index)
+		if err != nil {
+			return nil, err
+		}
+		v, _, err := index(x, i)
+		return v, err
+	case unaryNode:
+		x, err := env.eval(n.x)
+		if err != nil {
+			return nil, err
+		}
+		if n.op == "!" {
+			b, ok := x.(bool)
+			if !ok {
+				return nil, fmt.Errorf("'!' 需要布尔值")
+			}
+			return !b, nil
+		}
+		f, ok := x.(float64)
+		if !ok {
+			return nil, fmt.Errorf("'-' 需要数字")
+		}
+		return -f, nil
+	case condNode:
+		c, err := env.eval(n.cond)
+		if err != nil {
+			return nil, err
+		}
+		b, ok := c.(bool)
+		if !ok {
+			return nil, fmt.Errorf("三元运算的条件需要布尔值")
+		}
+		if b {
+			return env.eval(n.then)
+		}
+		return env.eval(n.els)
+	case binaryNode:
+		return env.binary(n)
+	case callNode:
+		return env.call(n)
+	}
+	return nil, fmt.Errorf("无法求值的节点 %T", n)
+}
+
+// binary 二元运算，&& 和 || 短路求值
+func (env *exprEnv) binary(n binaryNode) (any, error) {
+	l, err := env.eval(n.l)
+	if err != nil {
+		return nil, err
+	}
+	if n.op == "&&" || n.op == "||" {
+		lb, ok := l.(bool)
+		if !ok {
+			return nil, fmt.Errorf("'%s' 需要布尔值", n.op)
+		}
+		if (n.op == "&&" && !lb) || (n.op == "||" && lb) {
+			return lb, nil
+		}
+		r, err := env.eval(n.r)
+		if err != nil {
+			return nil, err
+		}
+		rb, ok := r.(bool)
+		if !ok {
+			return nil, fmt.Errorf("'%s' 需要布尔值", n.op)
+		}
+		return rb, nil
+	}
+	r, err := env.eval(n.r)
+	if err != nil {
+		return nil, err
+	}
+	switch n.op {
+	case "==":
+		return exprEqual(l, r), nil
+	case "!=":
+		return !exprEqual(l, r), nil
+	case "in":
+		switch c := r.(type) {
+		case []any:
+			for _, item := range c {
+				if exprEqual(l, item) {
+					return true, nil
+				}
+			}
+			return false, nil
+		case map[string]any, map[string]string:
+			_, ok, err := index(c, l)
+			return ok, err
+		}
+		return nil, fmt.Errorf("'in' 的右侧需要列表或映射")
+	case "+":
+		switch lv := l.(type) {
+		case string:
+			if rv, ok := r.(string); ok {
+				return lv + rv, nil
+			}
+		case []any:
+			if rv, ok := r.([]any); ok {
+				return append(append([]any{}, lv...), rv...), nil
+			}
+		}
+	case "<", "<=", ">", ">=":
+		cmp, err := exprCompare(l, r)
+		if err != nil {
+			return nil, err
+		}
+		switch n.op {
+		case "<":
+			return cmp < 0, nil
+		case "<=":
+			return cmp <= 0, nil
+		case ">":
+			return cmp > 0, nil
+		}
+		return cmp >= 0, nil
+	}
+	lf, lok := l.(float64)
+	rf, rok := r.(float64)
+	if !lok || !rok {
+		return nil, fmt.Errorf("'%s' 的操作数类型不匹配", n.op)
+	}
+	switch n.op {
+	case "+":
+		return lf + rf, nil
+	case "-":
+		return lf - rf, nil
+	case "*":
+		return lf * rf, nil
+	case "/":
+		if rf == 0 {
+			return nil, fmt.Errorf("除数为 0")
+		}
+		return lf / rf, nil
+	case "%":
+		if int64(rf) == 0 {
+			return nil, fmt.Errorf("除数为 0")
+		}
+		return float64(int64(lf) % int64(rf)), nil
+	}
+	return nil, fmt.Errorf("未知运算符 %s", n.op)
+}
+
+// call 调用全局函数或方法
+func (env *exprEnv) call(n callNode) (any, error) {
+	// has 判断成员是否存在，参数不求值到底
+	if n.recv == nil && n.name == "has" {
+		if len(n.args) != 1 {
+			return nil, fmt.Errorf("has 需要 1 个参数")
+		}
+		var base, key exprNode
+		switch a := n.args[0].(type) {
+		case memberNode:
+			base, key = a.x, litNode{a.name}
+		case indexNode:
+			base, key = a.x, a.index
+		default:
+			return nil, fmt.Errorf("has 的参数需要是成员访问，如 has(headers.authorization)")
+		}
+		x, err := env.eval(base)
+		if err != nil {
+			return nil, err
+		}
+		k, err := env.eval(key)
+		if err != nil {
+			return nil, err
+		}
+		_, ok, err := index(x, k)
+		return ok, err
+	}
+
+	var args []any
+	if n.recv != nil {
+		recv, err := env.eval(n.recv)
+		if err != nil {
+			return nil, err
+		}
+		args = append(args, recv)
+	}
+	for _, a := range n.args {
+		v, err := env.eval(a)
+		if err != nil {
+			return nil, err
+		}
+		args = append(args, v)
+	}
+	if len(args) == 0 {
+		return nil, fmt.Errorf("%s 缺少参数", n.name)
+	}
+
+	switch n.name {
+	case "size":
+		if len(args) != 1 {
+			return nil, fmt.Errorf("size 需要 1 个参数")
+		}
+		switch v := args[0].(type) {
+		case string:
+			return float64(utf8.RuneCountInString(v)), nil
+		case []any:
+			return float64(len(v)), nil
+		case map[string]any:
+			return float64(len(v)), nil
+		case map[string]string:
+			return float64(len(v)), nil
+		}
+		return nil, fmt.Errorf("size 不支持该类型")
+	case "int":
+		switch v := args[0].(type) {
+		case float64:
+			return float64(int64(v)), nil
+		case string:
+			i, err := strconv.ParseInt(strings.TrimSpace(v), 10, 64)
+			if err != nil {
+				return nil, fmt.Errorf("无法转换为整数: %q", v)
+			}
+			return float64(i), nil
+		}
+		return nil, fmt.Errorf("int 不支持该类型")
+	case "string":
+		switch v := args[0].(type) {
+		case string:
+			return v, nil
+		case float64:
+			return strconv.FormatFloat(v, 'f', -1, 64), nil
+		case bool:
+			return strconv.FormatBool(v), nil
+		case nil:
+			return "null", nil
+		}
+		return nil, fmt.Errorf("string 不支持该类型")
+	}
+
+	// 以下为字符串方法
+	s, ok := args[0].(string)
+	if !ok {
+		return nil, fmt.Errorf("%s 需要字符串", n.name)
+	}
+	str := func(i int) (string, error) {
+		if len(args) != i+1 {
+			return "", fmt.Errorf("%s 需要 %d 个参数", n.name, i)
+		}
+		v, ok := args[i].(string)
+		if !ok {
+			return "", fmt.Errorf("%s 的参数需要字符串", n.name)
+		}
+		return v, nil
+	}
+	switch n.name {
+	case "contains", "startsWith", "endsWith", "matches":
+		arg, err := str(1)
+		if err != nil {
+			return nil, err
+		}
+		switch n.name {
+		case "contains":
+			return strings.Contains(s, arg), nil
+		case "startsWith":
+			return strings.HasPrefix(s, arg), nil
+		case "endsWith":
+			return strings.HasSuffix(s, arg), nil
+		}
+		re, err := env.cache.Regexp(arg)
+		if err != nil {
+			return nil, err
+		}
+		return re.MatchString(s), nil
+	case "lowerAscii":
+		return strings.ToLower(s), nil
+	case "upperAscii":
+		return strings.ToUpper(s), nil
+	case "trim":
+		return strings.TrimSpace(s), nil
+	}
+	return nil, fmt.Errorf("未知函数 %s", n.name)
+}
+
+// index 按键或下标取值，返回是否存在；映射中不存在的键得到 null
+func index(x, key any) (any, bool, error) {
+	switch c := x.(type) {
+	case nil:
+		return nil, false, nil
+	case map[string]string:
+		k, ok := key.(string)
+		if !ok {
+			return nil, false, fmt.Errorf("映射的键需要字符串")
+		}
+		v, ok := c[k]
+		if !ok {
+			return nil, false, nil
+		}
+		return v, true, nil
+	case map[string]any:
+		k, ok := key.(string)
+		if !ok {
+			return nil, false, fmt.Errorf("映射的键需要字符串")
+		}
+		v, ok := c[k]
+		return v, ok, nil
+	case []any:
+		f, ok := key.(float64)
+		if !ok {
+			return nil, false, fmt.Errorf("列表的下标需要数字")
+		}
+		i := int(f)
+		if i < 0 || i >= len(c) {
+			return nil, false, nil
+		}
+		return c[i], true, nil
+	}
+	return nil, false, fmt.Errorf("不能对该类型取成员")
+}
+
+// exprEqual 判断两个值相等，数字按数值比较
+func exprEqual(a, b any) bool {
+	if af, ok := a.(float64); ok {
+		bf, ok := b.(float64)
+		return ok && af == bf
+	}
+	return reflect.DeepEqual(a, b)
+}
+
+// exprCompare 比较两个数字或两个字符串
+func exprCompare(a, b any) (int, error) {
+	switch av := a.(type) {
+	case float64:
+		if bv, ok := b.(float64); ok {
+			switch {
+			case av < bv:
+				return -1, nil
+			case av > bv:
+				return 1, nil
+			}
+			return 0, nil
+		}
+	case string:
+		if bv, ok := b.(string); ok {
+			return strings.Compare(av, bv), nil
+		}
+	}
+	return 0, fmt.Errorf("只能比较两个数字或两个字符串")
+}
+
+// matchExpression 对匹配上下文求值表达式，结果为 true 时条件成立；语法或求值错误时不成立
+func (e *Engine) matchExpression(ctx *EvalContext, src string) bool {
+	node, err := e.cache.Expression(src)
+	if err != nil {
+		return false
+	}
+	v, err := (&exprEnv{ctx: ctx, cache: e.cache}).eval(node)
+	if err != nil {
+		return false
+	}
+	b, ok := v.(bool)
+	return ok && b
+}
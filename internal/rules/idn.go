@@ -0,0 +1,84 @@
+package rules
+
+import (
+	"net/url"
+	"strings"
+
+	"golang.org/x/net/idna"
+)
+
+// ASCIIHost 将 URL 或 URL 片段中的国际化域名转换为 Punycode 形式，与浏览器上报的 URL 一致。
+// 主机部分为 :// 之后、或片段开头（需包含 .）到第一个 /、?、# 之前的内容；
+// 通配符标签原样保留，无法转换的标签保持不变
+func ASCIIHost(v string) string {
+	if isASCII(v) {
+		return v
+	}
+	start := 0
+	if i := strings.Index(v, "://"); i >= 0 {
+		start = i + 3
+	}
+	end := len(v)
+	if j := strings.IndexAny(v[start:], "/?#"); j >= 0 {
+		end = start + j
+	}
+	host := v[start:end]
+	if isASCII(host) || (start == 0 && !strings.Contains(host, ".")) {
+		return v
+	}
+
+	port := ""
+	if i := strings.LastIndexByte(host, ':'); i >= 0 && isPort(host[i+1:]) {
+		host, port = host[:i], host[i:]
+	}
+	labels := strings.Split(host, ".")
+	for i, l := range labels {
+		if isASCII(l) {
+			continue
+		}
+		if a, err := idna.Lookup.ToASCII(l); err == nil {
+			labels[i] = a
+		}
+	}
+	return v[:start] + strings.Join(labels, ".") + port + v[end:]
+}
+
+// UnicodeURL 返回主机为国际化域名时 URL 的 Unicode 显示形式，不是国际化域名时返回 false
+func UnicodeURL(raw string) (string, bool) {
+	if !strings.Contains(raw, "xn--") {
+		return "", false
+	}
+	u, err := url.Parse(raw)
+	if err != nil || !strings.Contains(u.Hostname(), "xn--") {
+		return "", false
+	}
+	host, err := idna.Display.ToUnicode(u.Hostname())
+	if err != nil || host == u.Hostname() {
+		return "", false
+	}
+	// url.URL.String 会转义非 ASCII 主机，直接替换原始 URL 中的主机
+	return strings.Replace(raw, u.Hostname(), host, 1), true
+}
+
+// isASCII 判断字符串是否只包含 ASCII 字符
+func isASCII(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if s[i] >= 0x80 {
+			return false
+		}
+	}
+	return true
+}
+
+// isPort 判断字符串是否为端口号（允许通配符 *）
+func isPort(s string) bool {
+	if s == "" {
+		return false
+	}
+	for _, c := range s {
+		if (c < '0' || c > '9') && c != '*' {
+			return false
+		}
+	}
+	return true
+}
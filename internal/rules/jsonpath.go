@@ -0,0 +1,820 @@
+package rules
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"cdpnetool/pkg/rulespec"
+
+	"github.com/tidwall/gjson"
+)
+
+// bodyJsonPath 条件使用的 JSONPath，语法取自 RFC 9535 的常用部分：
+//   - 成员：$、.name、['name']、.*、[*]；..name、..* 递归查找所有层级
+//   - 数组：[0]、[-1]（倒数）、[0,2]（多选）、[1:3]、[::-1]（切片 start:end:step）
+//   - 过滤：[?(@.price < 10)]、[?@.tags]（存在即成立），支持 == != < <= > >= =~（正则）、&& || ! 与括号；
+//     @ 为当前元素，$ 为文档根，字面量为字符串（单/双引号）、数字、true / false / null
+// 不以 $ 开头或无法按上述语法解析的路径按 gjson 语法求值，兼容 user.id、items.#.id 等写法
+
+// jsonPath 解析后的 JSONPath，由依次作用的段组成
+type jsonPath []jpSegment
+
+// jpSegment 路径中的一段，recursive 为 true 时（..）作用于当前节点及其所有后代
+type jpSegment struct {
+	recursive bool
+	selectors []jpSelector
+}
+
+// jpSelector 段中的一个选择器
+type jpSelector struct {
+	kind             byte // 'n' 成员名，'*' 通配，'i' 下标，':' 切片，'?' 过滤
+	name             string
+	index            int
+	start, end, step *int
+	filter           jpExpr
+}
+
+// jpExpr 过滤表达式语法树节点
+type jpExpr interface{}
+
+type (
+	jpLogical struct {
+		op   string // && 或 ||
+		l, r jpExpr
+	}
+	jpNot     struct{ x jpExpr }
+	jpExists  struct{ q *jpQuery }
+	jpCompare struct {
+		op   string
+		l, r jpOperand
+	}
+)
+
+// jpQuery 过滤中的路径，relative 为 true 时以 @ 开头
+type jpQuery struct {
+	relative bool
+	path     jsonPath
+}
+
+// jpOperand 比较运算的操作数：路径或字面量
+type jpOperand struct {
+	query *jpQuery
+	lit   gjson.Result
+}
+
+// jpParser 逐字符解析 JSONPath
+type jpParser struct {
+	src string
+	pos int
+}
+
+// parseJSONPath 解析以 $ 开头的 JSONPath
+func parseJSONPath(src string) (jsonPath, error) {
+	p := &jpParser{src: strings.TrimSpace(src)}
+	if !p.consume("$") {
+		return nil, fmt.Errorf("JSONPath 需要以 $ 开头")
+	}
+	path, err := p.segments()
+	if err != nil {
+		return nil, err
+	}
+	if p.pos < len(p.src) {
+		return nil, fmt.Errorf("多余的内容 %q（位置 %d）", p.src[p.pos:], p.pos)
+	}
+	return path, nil
+}
+
+// consume 当前位置以 s 开头时跳过 s
+func (p *jpParser) consume(s string) bool {
+	if strings.HasPrefix(p.src[p.pos:], s) {
+		p.pos += len(s)
+		return true
+	}
+	return false
+}
+
+// skipSpace 跳过空白，只在括号与过滤表达式内部使用
+func (p *jpParser) skipSpace() {
+	for p.pos < len(p.src) && (p.src[p.pos] == ' ' || p.src[p.pos] == '\t' || p.src[p.pos] == '\n' || p.src[p.pos] == '\r') {
+		p.pos++
+	}
+}
+
+// segments 解析 $ 或 @ 之后的各段，遇到不能开始新段的字符时停止
+func (p *jpParser) segments() (jsonPath, error) {
+	var path jsonPath
+	for p.pos < len(p.src) {
+		switch {
+		case p.consume(".."):
+			seg, err := p.dotSegment()
+			if err != nil {
+				return nil, err
+			}
+			seg.recursive = true
+			path = append(path, seg)
+		case p.consume("."):
+			if p.pos < len(p.src) && p.src[p.pos] == '[' {
+				return nil, fmt.Errorf("'.' 之后需要名称（位置 %d）", p.pos)
+			}
+			seg, err := p.dotSegment()
+			if err != nil {
+				return nil, err
+			}
+			path = append(path, seg)
+		case p.src[p.pos] == '[':
+			seg, err := p.bracketSegment()
+			if err != nil {
+				return nil, err
+			}
+			path = append(path, seg)
+		default:
+			return path, nil
+		}
+	}
+	return path, nil
+}
+
+// dotSegment 解析 . 或 .. 之后的 *、名称或（仅 .. 之后）方括号
+func (p *jpParser) dotSegment() (jpSegment, error) {
+	if p.consume("*") {
+		return jpSegment{selectors: []jpSelector{{kind: '*'}}}, nil
+	}
+	if p.pos < len(p.src) && p.src[p.pos] == '[' {
+		return p.bracketSegment()
+	}
+	start := p.pos
+	for p.pos < len(p.src) {
+		r, size := utf8.DecodeRuneInString(p.src[p.pos:])
+		if r != '_' && !unicode.IsLetter(r) && !(p.pos > start && unicode.IsDigit(r)) {
+			break
+		}
+		p.pos += size
+	}
+	if p.pos == start {
+		return jpSegment{}, fmt.Errorf("'.' 之后需要名称（位置 %d）", p.pos)
+	}
+	return jpSegment{selectors: []jpSelector{{kind: 'n', name: p.src[start:p.pos]}}}, nil
+}
+
+// bracketSegment 解析方括号中以逗号分隔的选择器
+func (p *jpParser) bracketSegment() (jpSegment, error) {
+	p.pos++ // [
+	var seg jpSegment
+	for {
+		p.skipSpace()
+		sel, err := p.selector()
+		if err != nil {
+			return jpSegment{}, err
+		}
+		seg.selectors = append(seg.selectors, sel)
+		p.skipSpace()
+		if p.consume("]") {
+			return seg, nil
+		}
+		if !p.consume(",") {
+			return jpSegment{}, fmt.Errorf("缺少 \"]\"（位置 %d）", p.pos)
+		}
+	}
+}
+
+// selector 解析方括号中的单个选择器
+func (p *jpParser) selector() (jpSelector, error) {
+	if p.pos >= len(p.src) {
+		return jpSelector{}, fmt.Errorf("路径不完整")
+	}
+	switch c := p.src[p.pos]; {
+	case c == '\'' || c == '"':
+		s, err := p.stringLit()
+		if err != nil {
+			return jpSelector{}, err
+		}
+		return jpSelector{kind: 'n', name: s}, nil
+	case c == '*':
+		p.pos++
+		return jpSelector{kind: '*'}, nil
+	case c == '?':
+		p.pos++
+		expr, err := p.orExpr()
+		if err != nil {
+			return jpSelector{}, err
+		}
+		return jpSelector{kind: '?', filter: expr}, nil
+	}
+
+	start, hasStart := p.intLit()
+	p.skipSpace()
+	if !p.consume(":") {
+		if !hasStart {
+			return jpSelector{}, fmt.Errorf("无效的选择器（位置 %d）", p.pos)
+		}
+		return jpSelector{kind: 'i', index: start}, nil
+	}
+	sel := jpSelector{kind: ':'}
+	if hasStart {
+		sel.start = &start
+	}
+	p.skipSpace()
+	if end, ok := p.intLit(); ok {
+		sel.end = &end
+	}
+	p.skipSpace()
+	if p.consume(":") {
+		p.skipSpace()
+		if step, ok := p.intLit(); ok {
+			sel.step = &step
+		}
+	}
+	return sel, nil
+}
+
+// intLit 解析可带负号的整数
+func (p *jpParser) intLit() (int, bool) {
+	start := p.pos
+	if p.pos < len(p.src) && p.src[p.pos] == '-' {
+		p.pos++
+	}
+	digits := p.pos
+	for p.pos < len(p.src) && p.src[p.pos] >= '0' && p.src[p.pos] <= '9' {
+		p.pos++
+	}
+	if p.pos == digits {
+		p.pos = start
+		return 0, false
+	}
+	n, err := strconv.Atoi(p.src[start:p.pos])
+	if err != nil {
+		p.pos = start
+		return 0, false
+	}
+	return n, true
+}
+
+// stringLit 解析单引号或双引号字符串
+func (p *jpParser) stringLit() (string, error) {
+	quote := p.src[p.pos]
+	start := p.pos
+	p.pos++
+	var b strings.Builder
+	for {
+		if p.pos >= len(p.src) {
+			return "", fmt.Errorf("字符串未结束（位置 %d）", start)
+		}
+		c := p.src[p.pos]
+		if c == quote {
+			p.pos++
+			return b.String(), nil
+		}
+		if c == '\\' && p.pos+1 < len(p.src) {
+			p.pos++
+			switch p.src[p.pos] {
+			case 'n':
+				b.WriteByte('\n')
+			case 't':
+				b.WriteByte('\t')
+			case 'r':
+				b.WriteByte('\r')
+			default:
+				b.WriteByte(p.src[p.pos])
+			}
+			p.pos++
+			continue
+		}
+		b.WriteByte(c)
+		p.pos++
+	}
+}
+
+// orExpr 解析过滤表达式，优先级从低到高：||、&&、!、比较
+func (p *jpParser) orExpr() (jpExpr, error) {
+	l, err := p.andExpr()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		p.skipSpace()
+		if !p.consume("||") {
+			return l, nil
+		}
+		r, err := p.andExpr()
+		if err != nil {
+			return nil, err
+		}
+		l = jpLogical{op: "||", l: l, r: r}
+	}
+}
+
+func (p *jpParser) andExpr() (jpExpr, error) {
+	l, err := p.unaryExpr()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		p.skipSpace()
+		if !p.consume("&&") {
+			return l, nil
+		}
+		r, err := p.unaryExpr()
+		if err != nil {
+			return nil, err
+		}
+		l = jpLogical{op: "&&", l: l, r: r}
+	}
+}
+
+func (p *jpParser) unaryExpr() (jpExpr, error) {
+	p.skipSpace()
+	if strings.HasPrefix(p.src[p.pos:], "!") && !strings.HasPrefix(p.src[p.pos:], "!=") {
+		p.pos++
+		x, err := p.unaryExpr()
+		if err != nil {
+			return nil, err
+		}
+		return jpNot{x: x}, nil
+	}
+	if p.consume("(") {
+		x, err := p.orExpr()
+		if err != nil {
+			return nil, err
+		}
+		p.skipSpace()
+		if !p.consume(")") {
+			return nil, fmt.Errorf("缺少 \")\"（位置 %d）", p.pos)
+		}
+		return x, nil
+	}
+	return p.comparison()
+}
+
+// jpCompareOps 比较运算符，双字符的排在前面以便优先匹配
+var jpCompareOps = []string{"==", "!=", "<=", ">=", "=~", "<", ">"}
+
+// comparison 解析比较运算；只有路径而没有运算符时为存在性判断
+func (p *jpParser) comparison() (jpExpr, error) {
+	l, err := p.operand()
+	if err != nil {
+		return nil, err
+	}
+	p.skipSpace()
+	op := ""
+	for _, o := range jpCompareOps {
+		if p.consume(o) {
+			op = o
+			break
+		}
+	}
+	if op == "" {
+		if l.query == nil {
+			return nil, fmt.Errorf("字面量需要参与比较（位置 %d）", p.pos)
+		}
+		return jpExists{q: l.query}, nil
+	}
+	p.skipSpace()
+	if op == "=~" && p.pos < len(p.src) && p.src[p.pos] == '/' {
+		re, err := p.regexLit()
+		if err != nil {
+			return nil, err
+		}
+		return jpCompare{op: op, l: l, r: jpOperand{lit: gjson.Result{Type: gjson.String, Str: re}}}, nil
+	}
+	r, err := p.operand()
+	if err != nil {
+		return nil, err
+	}
+	return jpCompare{op: op, l: l, r: r}, nil
+}
+
+// regexLit 解析 /.../ 形式的正则，末尾的 i 表示不区分大小写
+func (p *jpParser) regexLit() (string, error) {
+	start := p.pos
+	p.pos++
+	var b strings.Builder
+	for {
+		if p.pos >= len(p.src) {
+			return "", fmt.Errorf("正则未结束（位置 %d）", start)
+		}
+		c := p.src[p.pos]
+		p.pos++
+		if c == '/' {
+			break
+		}
+		if c == '\\' && p.pos < len(p.src) && p.src[p.pos] == '/' {
+			c = '/'
+			p.pos++
+		}
+		b.WriteByte(c)
+	}
+	if p.consume("i") {
+		return "(?i)" + b.String(), nil
+	}
+	return b.String(), nil
+}
+
+// operand 解析 @ / $ 开头的路径或字面量
+func (p *jpParser) operand() (jpOperand, error) {
+	p.skipSpace()
+	if p.pos >= len(p.src) {
+		return jpOperand{}, fmt.Errorf("过滤表达式不完整")
+	}
+	switch c := p.src[p.pos]; {
+	case c == '@' || c == '$':
+		p.pos++
+		path, err := p.segments()
+		if err != nil {
+			return jpOperand{}, err
+		}
+		return jpOperand{query: &jpQuery{relative: c == '@', path: path}}, nil
+	case c == '\'' || c == '"':
+		s, err := p.stringLit()
+		if err != nil {
+			return jpOperand{}, err
+		}
+		return jpOperand{lit: gjson.Result{Type: gjson.String, Str: s}}, nil
+	case c == '-' || c >= '0' && c <= '9':
+		start := p.pos
+		p.pos++
+		for p.pos < len(p.src) && strings.IndexByte("0123456789.eE+-", p.src[p.pos]) >= 0 {
+			p.pos++
+		}
+		text := p.src[start:p.pos]
+		n, err := strconv.ParseFloat(text, 64)
+		if err != nil {
+			return jpOperand{}, fmt.Errorf("无效的数字 %q（位置 %d）", text, start)
+		}
+		return jpOperand{lit: gjson.Result{Type: gjson.Number, Num: n, Raw: text}}, nil
+	}
+	for _, lit := range []string{"true", "false", "null"} {
+		if p.consume(lit) {
+			return jpOperand{lit: gjson.Parse(lit)}, nil
+		}
+	}
+	return jpOperand{}, fmt.Errorf("意外的 %q（位置 %d）", p.src[p.pos:], p.pos)
+}
+
+// jpEvaluator 对一个文档求值 JSONPath，过滤中的 $ 指向 root
+type jpEvaluator struct {
+	e    *Engine
+	root gjson.Result
+}
+
+// selectPath 依次应用各段，返回选中的节点
+func (ev *jpEvaluator) selectPath(start gjson.Result, path jsonPath) []gjson.Result {
+	nodes := []gjson.Result{start}
+	for _, seg := range path {
+		var next []gjson.Result
+		for _, n := range nodes {
+			if seg.recursive {
+				walkJSON(n, func(d gjson.Result) {
+					next = ev.applySelectors(next, d, seg.selectors)
+				})
+			} else {
+				next = ev.applySelectors(next, n, seg.selectors)
+			}
+		}
+		if len(next) == 0 {
+			return nil
+		}
+		nodes = next
+	}
+	return nodes
+}
+
+// walkJSON 先序遍历节点及其所有后代
+func walkJSON(n gjson.Result, fn func(gjson.Result)) {
+	fn(n)
+	if n.IsObject() || n.IsArray() {
+		n.ForEach(func(_, v gjson.Result) bool {
+			walkJSON(v, fn)
+			return true
+		})
+	}
+}
+
+// applySelectors 将选择器依次作用于节点，结果追加到 out
+func (ev *jpEvaluator) applySelectors(out []gjson.Result, n gjson.Result, sels []jpSelector) []gjson.Result {
+	for _, sel := range sels {
+		switch sel.kind {
+		case 'n':
+			if !n.IsObject() {
+				continue
+			}
+			n.ForEach(func(k, v gjson.Result) bool {
+				if k.Str == sel.name {
+					out = append(out, v)
+					return false
+				}
+				return true
+			})
+		case '*':
+			if n.IsObject() || n.IsArray() {
+				n.ForEach(func(_, v gjson.Result) bool {
+					out = append(out, v)
+					return true
+				})
+			}
+		case 'i':
+			if !n.IsArray() {
+				continue
+			}
+			items := n.Array()
+			i := sel.index
+			if i < 0 {
+				i += len(items)
+			}
+			if i >= 0 && i < len(items) {
+				out = append(out, items[i])
+			}
+		case ':':
+			if n.IsArray() {
+				out = appendSlice(out, n.Array(), sel)
+			}
+		case '?':
+			if n.IsObject() || n.IsArray() {
+				n.ForEach(func(_, v gjson.Result) bool {
+					if ev.test(sel.filter, v) {
+						out = append(out, v)
+					}
+					return true
+				})
+			}
+		}
+	}
+	return out
+}
+
+// appendSlice 按 RFC 9535 的切片规则选取数组元素，step 为 0 时不选取
+func appendSlice(out []gjson.Result, items []gjson.Result, sel jpSelector) []gjson.Result {
+	n := len(items)
+	step := 1
+	if sel.step != nil {
+		step = *sel.step
+	}
+	if step == 0 {
+		return out
+	}
+	norm := func(i int) int {
+		if i < 0 {
+			return i + n
+		}
+		return i
+	}
+	if step > 0 {
+		start, end := 0, n
+		if sel.start != nil {
+			start = min(max(norm(*sel.start), 0), n)
+		}
+		if sel.end != nil {
+			end = min(max(norm(*sel.end), 0), n)
+		}
+		for i := start; i < end; i += step {
+			out = append(out, items[i])
+		}
+		return out
+	}
+	start, end := n-1, -1
+	if sel.start != nil {
+		start = min(max(norm(*sel.start), -1), n-1)
+	}
+	if sel.end != nil {
+		end = min(max(norm(*sel.end), -1), n-1)
+	}
+	for i := start; i > end; i += step {
+		out = append(out, items[i])
+	}
+	return out
+}
+
+// test 对当前元素求值过滤表达式
+func (ev *jpEvaluator) test(x jpExpr, cur gjson.Result) bool {
+	switch n := x.(type) {
+	case jpLogical:
+		if n.op == "&&" {
+			return ev.test(n.l, cur) && ev.test(n.r, cur)
+		}
+		return ev.test(n.l, cur) || ev.test(n.r, cur)
+	case jpNot:
+		return !ev.test(n.x, cur)
+	case jpExists:
+		return len(ev.query(n.q, cur)) > 0
+	case jpCompare:
+		l, lok := ev.value(n.l, cur)
+		r, rok := ev.value(n.r, cur)
+		return ev.compare(n.op, l, lok, r, rok)
+	}
+	return false
+}
+
+// query 求值过滤中的路径
+func (ev *jpEvaluator) query(q *jpQuery, cur gjson.Result) []gjson.Result {
+	if q.relative {
+		return ev.selectPath(cur, q.path)
+	}
+	return ev.selectPath(ev.root, q.path)
+}
+
+// value 取操作数的值；路径没有选中或选中多个节点时视为不存在
+func (ev *jpEvaluator) value(o jpOperand, cur gjson.Result) (gjson.Result, bool) {
+	if o.query == nil {
+		return o.lit, true
+	}
+	nodes := ev.query(o.query, cur)
+	if len(nodes) != 1 {
+		return gjson.Result{}, false
+	}
+	return nodes[0], true
+}
+
+// compare 按 RFC 9535 比较两个值：不存在的值只与不存在的值相等，
+// 大小比较只在两侧同为数字或同为字符串时成立
+func (ev *jpEvaluator) compare(op string, l gjson.Result, lok bool, r gjson.Result, rok bool) bool {
+	switch op {
+	case "==":
+		return lok == rok && (!lok || jsonEqual(l, r))
+	case "!=":
+		return !(lok == rok && (!lok || jsonEqual(l, r)))
+	case "=~":
+		return lok && rok && l.Type == gjson.String && r.Type == gjson.String && ev.e.matchRegex(l.Str, r.Str)
+	}
+	if !lok || !rok {
+		return false
+	}
+	var c int
+	switch {
+	case l.Type == gjson.Number && r.Type == gjson.Number:
+		c = compareFloat(l.Num, r.Num)
+	case l.Type == gjson.String && r.Type == gjson.String:
+		c = strings.Compare(l.Str, r.Str)
+	default:
+		return false
+	}
+	switch op {
+	case "<":
+		return c < 0
+	case "<=":
+		return c <= 0
+	case ">":
+		return c > 0
+	case ">=":
+		return c >= 0
+	}
+	return false
+}
+
+// jsonEqual 比较两个 JSON 值是否相等，对象与数组按内容比较
+func jsonEqual(a, b gjson.Result) bool {
+	if a.Type != b.Type {
+		return false
+	}
+	switch a.Type {
+	case gjson.Number:
+		return a.Num == b.Num
+	case gjson.String:
+		return a.Str == b.Str
+	case gjson.JSON:
+		var av, bv any
+		if json.Unmarshal([]byte(a.Raw), &av) != nil || json.Unmarshal([]byte(b.Raw), &bv) != nil {
+			return false
+		}
+		return reflect.DeepEqual(av, bv)
+	}
+	return true
+}
+
+// selectJSON 求值路径并返回选中的节点：以 $ 开头且可按 JSONPath 解析时按 JSONPath 求值，
+// 否则去掉 $. 前缀后按 gjson 语法求值
+func (e *Engine) selectJSON(doc, path string) []gjson.Result {
+	if doc == "" || path == "" {
+		return nil
+	}
+	if strings.HasPrefix(strings.TrimSpace(path), "$") {
+		if p, err := e.cache.JSONPath(path); err == nil {
+			root := gjson.Parse(doc)
+			if !root.Exists() {
+				return nil
+			}
+			ev := &jpEvaluator{e: e, root: root}
+			return ev.selectPath(root, p)
+		}
+	}
+	result := gjson.Get(doc, strings.TrimPrefix(path, "$."))
+	if !result.Exists() {
+		return nil
+	}
+	return []gjson.Result{result}
+}
+
+// matchBodyJSONPath 评估 bodyJsonPath 条件：路径选中多个值时任一值满足即成立，
+// notEquals 要求所有值都不相等；路径没有选中任何值时不成立
+func (e *Engine) matchBodyJSONPath(body string, c *rulespec.Condition) bool {
+	nodes := e.selectJSON(body, c.Path)
+	if len(nodes) == 0 {
+		return false
+	}
+	op := c.Op
+	if op == "" {
+		op = rulespec.CompareEquals
+		if c.Pattern != "" {
+			op = rulespec.CompareRegex
+		}
+	}
+	if op == rulespec.CompareNotEquals {
+		for _, n := range nodes {
+			if jsonText(n) == c.Value {
+				return false
+			}
+		}
+		return true
+	}
+	for _, n := range nodes {
+		if e.compareJSONValue(op, n, c) {
+			return true
+		}
+	}
+	return false
+}
+
+// compareJSONValue 按运算符比较单个节点与条件中的期望值
+func (e *Engine) compareJSONValue(op rulespec.CompareOp, n gjson.Result, c *rulespec.Condition) bool {
+	switch op {
+	case rulespec.CompareExists:
+		return true
+	case rulespec.CompareEquals:
+		return jsonText(n) == c.Value
+	case rulespec.CompareContains:
+		if n.IsArray() {
+			found := false
+			n.ForEach(func(_, v gjson.Result) bool {
+				found = jsonText(v) == c.Value
+				return !found
+			})
+			return found
+		}
+		return strings.Contains(jsonText(n), c.Value)
+	case rulespec.CompareRegex:
+		pattern := c.Pattern
+		if pattern == "" {
+			pattern = c.Value
+		}
+		return e.matchRegex(jsonText(n), pattern)
+	case rulespec.CompareGt, rulespec.CompareGte, rulespec.CompareLt, rulespec.CompareLte:
+		cmp, ok := compareOrdered(n, c.Value)
+		if !ok {
+			return false
+		}
+		switch op {
+		case rulespec.CompareGt:
+			return cmp > 0
+		case rulespec.CompareGte:
+			return cmp >= 0
+		case rulespec.CompareLt:
+			return cmp < 0
+		default:
+			return cmp <= 0
+		}
+	}
+	return false
+}
+
+// compareOrdered 比较节点与期望值的大小：两侧都能解析为数字时按数值比较，
+// 节点为字符串时按字符串比较（适用于 ISO 8601 时间等），其他情况不可比较
+func compareOrdered(n gjson.Result, want string) (int, bool) {
+	w, werr := strconv.ParseFloat(strings.TrimSpace(want), 64)
+	switch n.Type {
+	case gjson.Number:
+		if werr != nil {
+			return 0, false
+		}
+		return compareFloat(n.Num, w), true
+	case gjson.String:
+		if werr == nil {
+			if v, err := strconv.ParseFloat(strings.TrimSpace(n.Str), 64); err == nil {
+				return compareFloat(v, w), true
+			}
+		}
+		return strings.Compare(n.Str, want), true
+	}
+	return 0, false
+}
+
+// compareFloat 返回 -1、0 或 1
+func compareFloat(a, b float64) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
+
+// jsonText 返回节点的文本：字符串为原始值，其他类型为 JSON 文本
+func jsonText(n gjson.Result) string {
+	if n.Type == gjson.String {
+		return n.Str
+	}
+	return n.Raw
+}
@@ -0,0 +1,214 @@
+package rules
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/hmac"
+	"crypto/rsa"
+	_ "crypto/sha256"
+	_ "crypto/sha512"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"math/big"
+	"strings"
+	"time"
+
+	"cdpnetool/pkg/rulespec"
+
+	"github.com/tidwall/gjson"
+)
+
+// jwtParamPrefix JWT 声明绑定到行为模板的变量前缀，如 {{jwt.sub}}
+const jwtParamPrefix = "jwt."
+
+// matchJWTClaim 从请求头或 Cookie 中提取 JWT 并按声明匹配，
+// 匹配成功时将顶层声明以 jwt.<name> 写入 params
+func (e *Engine) matchJWTClaim(ctx *EvalContext, c *rulespec.Condition, params map[string]string) bool {
+	token, ok := extractJWT(ctx, c)
+	if !ok {
+		return false
+	}
+	claims, ok := e.decodeJWT(token, c.Key)
+	if !ok {
+		return false
+	}
+
+	if c.Path != "" && !e.anyClaimMatches(e.selectJSON(claims, c.Path), c) {
+		return false
+	}
+
+	gjson.Parse(claims).ForEach(func(k, v gjson.Result) bool {
+		if v.Type == gjson.String {
+			params[jwtParamPrefix+k.String()] = v.Str
+		} else {
+			params[jwtParamPrefix+k.String()] = v.Raw
+		}
+		return true
+	})
+	return true
+}
+
+// anyClaimMatches 判断路径选中的声明中是否有满足条件的，都未指定比较方式时只要求声明存在
+func (e *Engine) anyClaimMatches(claims []gjson.Result, c *rulespec.Condition) bool {
+	for _, claim := range claims {
+		switch {
+		case c.ExpiresWithin != nil:
+			if claim.Type == gjson.Number && claim.Int()-time.Now().Unix() <= int64(*c.ExpiresWithin) {
+				return true
+			}
+		case c.Pattern != "":
+			if e.matchRegex(claim.String(), c.Pattern) {
+				return true
+			}
+		case c.Value != "":
+			if claim.String() == c.Value {
+				return true
+			}
+		default:
+			return true
+		}
+	}
+	return false
+}
+
+// extractJWT 按来源读取 JWT，请求头默认为 Authorization 并去掉 Bearer 前缀
+func extractJWT(ctx *EvalContext, c *rulespec.Condition) (string, bool) {
+	var raw string
+	switch strings.ToLower(c.Source) {
+	case "", "header":
+		name := c.Name
+		if name == "" {
+			name = "Authorization"
+		}
+		v, ok := getHeaderCaseInsensitive(ctx.Headers, name)
+		if !ok {
+			return "", false
+		}
+		raw = v
+	case "cookie":
+		v, ok := ctx.Cookies[strings.ToLower(c.Name)]
+		if !ok {
+			return "", false
+		}
+		raw = v
+	default:
+		return "", false
+	}
+
+	raw = strings.TrimSpace(raw)
+	if len(raw) > 7 && strings.EqualFold(raw[:7], "bearer ") {
+		raw = strings.TrimSpace(raw[7:])
+	}
+	return raw, raw != ""
+}
+
+// decodeJWT 解码 JWT 并返回声明 JSON；key 不为空时校验签名，校验失败视为不匹配
+func (e *Engine) decodeJWT(token, key string) (string, bool) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return "", false
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil || !gjson.ValidBytes(payload) {
+		return "", false
+	}
+	claims := gjson.ParseBytes(payload)
+	if !claims.IsObject() {
+		return "", false
+	}
+
+	if key != "" {
+		header, err := base64.RawURLEncoding.DecodeString(parts[0])
+		if err != nil {
+			return "", false
+		}
+		sig, err := base64.RawURLEncoding.DecodeString(parts[2])
+		if err != nil {
+			return "", false
+		}
+		alg := gjson.GetBytes(header, "alg").String()
+		if !e.verifyJWT(alg, parts[0]+"."+parts[1], sig, key) {
+			return "", false
+		}
+	}
+	return claims.Raw, true
+}
+
+// verifyJWT 按 alg 校验签名，支持 HS256/384/512、RS256/384/512、ES256/384/512
+func (e *Engine) verifyJWT(alg, signingInput string, sig []byte, key string) bool {
+	var hash crypto.Hash
+	switch alg[min(2, len(alg)):] {
+	case "256":
+		hash = crypto.SHA256
+	case "384":
+		hash = crypto.SHA384
+	case "512":
+		hash = crypto.SHA512
+	default:
+		return false
+	}
+
+	switch alg[:2] {
+	case "HS":
+		mac := hmac.New(hash.New, []byte(key))
+		mac.Write([]byte(signingInput))
+		return hmac.Equal(sig, mac.Sum(nil))
+	case "RS", "ES":
+		pub, ok := e.parsePublicKey(key)
+		if !ok {
+			return false
+		}
+		h := hash.New()
+		h.Write([]byte(signingInput))
+		digest := h.Sum(nil)
+		switch k := pub.(type) {
+		case *rsa.PublicKey:
+			return alg[:2] == "RS" && rsa.VerifyPKCS1v15(k, hash, digest, sig) == nil
+		case *ecdsa.PublicKey:
+			// JWS 的 ECDSA 签名为定长 r||s 拼接
+			size := (k.Curve.Params().BitSize + 7) / 8
+			if alg[:2] != "ES" || len(sig) != 2*size {
+				return false
+			}
+			r := new(big.Int).SetBytes(sig[:size])
+			s := new(big.Int).SetBytes(sig[size:])
+			return ecdsa.Verify(k, digest, r, s)
+		}
+	}
+	return false
+}
+
+// parsePublicKey 解析 PEM 格式的公钥或证书，结果按原文缓存
+func (e *Engine) parsePublicKey(key string) (crypto.PublicKey, bool) {
+	if pub, ok := e.cache.publicKey(key); ok {
+		return pub, true
+	}
+	block, _ := pem.Decode([]byte(key))
+	if block == nil {
+		return nil, false
+	}
+	var pub crypto.PublicKey
+	switch block.Type {
+	case "CERTIFICATE":
+		cert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return nil, false
+		}
+		pub = cert.PublicKey
+	case "RSA PUBLIC KEY":
+		k, err := x509.ParsePKCS1PublicKey(block.Bytes)
+		if err != nil {
+			return nil, false
+		}
+		pub = k
+	default:
+		k, err := x509.ParsePKIXPublicKey(block.Bytes)
+		if err != nil {
+			return nil, false
+		}
+		pub = k
+	}
+	e.cache.storePublicKey(key, pub)
+	return pub, true
+}
@@ -0,0 +1,93 @@
+package rules
+
+import (
+	"reflect"
+	"testing"
+
+	"cdpnetool/pkg/rulespec"
+)
+
+// orderRule 构建请求阶段按 URL 前缀匹配的规则
+func orderRule(id string, priority int, stop bool, prefix string) rulespec.Rule {
+	return rulespec.Rule{
+		ID:             id,
+		Enabled:        true,
+		Priority:       priority,
+		Stage:          rulespec.StageRequest,
+		StopProcessing: stop,
+		Match:          rulespec.Match{AllOf: []rulespec.Condition{{Type: rulespec.ConditionURLPrefix, Value: prefix}}},
+	}
+}
+
+func TestEvaluationOrder(t *testing.T) {
+	const url = "https://app.example.com/api"
+	disabled := orderRule("d", 10, true, "https://")
+	disabled.Enabled = false
+	response := orderRule("resp", 10, false, "https://")
+	response.Stage = rulespec.StageResponse
+
+	tests := []struct {
+		name      string
+		rules     []rulespec.Rule
+		wantOrder []string // EvaluationOrder(StageRequest)
+		wantEval  []string // EvalForStage 命中的规则
+	}{
+		{
+			name:      "equal priority keeps config order",
+			rules:     []rulespec.Rule{orderRule("a", 0, false, "https://"), orderRule("b", 0, false, "https://"), orderRule("c", 0, false, "https://")},
+			wantOrder: []string{"a", "b", "c"},
+			wantEval:  []string{"a", "b", "c"},
+		},
+		{
+			name:      "higher priority first, ties stable",
+			rules:     []rulespec.Rule{orderRule("a", 1, false, "https://"), orderRule("b", 5, false, "https://"), orderRule("c", 1, false, "https://"), orderRule("d", 5, false, "https://")},
+			wantOrder: []string{"b", "d", "a", "c"},
+			wantEval:  []string{"b", "d", "a", "c"},
+		},
+		{
+			name:      "stopProcessing drops lower priority",
+			rules:     []rulespec.Rule{orderRule("low", 0, false, "https://"), orderRule("stop", 5, true, "https://")},
+			wantOrder: []string{"stop", "low"},
+			wantEval:  []string{"stop"},
+		},
+		{
+			name:      "stopProcessing among equal priority keeps earlier rules",
+			rules:     []rulespec.Rule{orderRule("a", 1, false, "https://"), orderRule("stop", 1, true, "https://"), orderRule("b", 1, false, "https://")},
+			wantOrder: []string{"a", "stop", "b"},
+			wantEval:  []string{"a", "stop"},
+		},
+		{
+			name:      "unmatched stopProcessing rule does not stop",
+			rules:     []rulespec.Rule{orderRule("stop", 5, true, "https://other.example.com"), orderRule("a", 0, false, "https://")},
+			wantOrder: []string{"stop", "a"},
+			wantEval:  []string{"a"},
+		},
+		{
+			name:      "disabled and other-stage rules are skipped",
+			rules:     []rulespec.Rule{disabled, response, orderRule("a", 0, false, "https://")},
+			wantOrder: []string{"a"},
+			wantEval:  []string{"a"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &rulespec.Config{Rules: tt.rules}
+
+			var order []string
+			for _, r := range cfg.EvaluationOrder(rulespec.StageRequest) {
+				order = append(order, r.ID)
+			}
+			if !reflect.DeepEqual(order, tt.wantOrder) {
+				t.Errorf("EvaluationOrder = %v, want %v", order, tt.wantOrder)
+			}
+
+			var eval []string
+			for _, mr := range New(cfg, nil).EvalForStage(&EvalContext{URL: url, Method: "GET"}, rulespec.StageRequest) {
+				eval = append(eval, mr.Rule.ID)
+			}
+			if !reflect.DeepEqual(eval, tt.wantEval) {
+				t.Errorf("EvalForStage = %v, want %v", eval, tt.wantEval)
+			}
+		})
+	}
+}
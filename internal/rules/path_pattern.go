@@ -0,0 +1,56 @@
+package rules
+
+import (
+	"net/url"
+	"strings"
+)
+
+// matchPathPattern 按路径模板匹配 URL 路径（如 /users/{id}/orders/{orderId}），
+// 匹配成功时将路径参数以 path.<name> 写入 params
+func matchPathPattern(rawURL, pattern string, params map[string]string) bool {
+	if pattern == "" {
+		return false
+	}
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return false
+	}
+
+	want := splitPath(pattern)
+	got := splitPath(u.EscapedPath())
+	if len(want) != len(got) {
+		return false
+	}
+
+	bound := make(map[string]string)
+	for i, seg := range want {
+		v, err := url.PathUnescape(got[i])
+		if err != nil {
+			v = got[i]
+		}
+		if strings.HasPrefix(seg, "{") && strings.HasSuffix(seg, "}") && len(seg) > 2 {
+			if v == "" {
+				return false
+			}
+			bound[seg[1:len(seg)-1]] = v
+			continue
+		}
+		if seg != "*" && seg != v {
+			return false
+		}
+	}
+
+	for k, v := range bound {
+		params["path."+k] = v
+	}
+	return true
+}
+
+// splitPath 拆分路径段，忽略首尾斜杠
+func splitPath(p string) []string {
+	p = strings.Trim(p, "/")
+	if p == "" {
+		return nil
+	}
+	return strings.Split(p, "/")
+}
@@ -0,0 +1,15 @@
+package rules
+
+import "math/rand"
+
+// sampled 评估 probability 条件：以 percent% 的概率成立，每次评估独立抽样；
+// 不大于 0 时从不成立，不小于 100 时总是成立
+func sampled(percent float64) bool {
+	switch {
+	case percent <= 0:
+		return false
+	case percent >= 100:
+		return true
+	}
+	return rand.Float64()*100 < percent
+}
@@ -0,0 +1,40 @@
+package rules
+
+import (
+	"time"
+
+	"cdpnetool/pkg/rulespec"
+)
+
+// rateExceeded 评估 rateLimit 条件：value 为 urlGlob 模式（为空时统计所有走到该条件的请求），
+// 记录本次请求后，若 windowMs 内的请求数超过 limit 则成立。窗口按条件独立统计
+func (e *Engine) rateExceeded(ctx *EvalContext, c *rulespec.Condition) bool {
+	if c.Limit < 0 || c.WindowMS <= 0 {
+		return false
+	}
+	if c.Value != "" && !e.matchURLGlob(ctx.URL, ASCIIHost(c.Value)) {
+		return false
+	}
+
+	now := time.Now()
+	since := now.Add(-time.Duration(c.WindowMS) * time.Millisecond)
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	times := e.windows[c]
+	i := 0
+	for i < len(times) && !times[i].After(since) {
+		i++
+	}
+	times = times[i:]
+	if ctx.reevaluate {
+		e.windows[c] = times
+		return len(times) > c.Limit
+	}
+	times = append(times, now)
+	// 只需判断是否超过上限，保留最近的 limit+1 次即可
+	if len(times) > c.Limit+1 {
+		times = times[len(times)-c.Limit-1:]
+	}
+	e.windows[c] = times
+	return len(times) > c.Limit
+}
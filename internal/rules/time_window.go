@@ -0,0 +1,80 @@
+package rules
+
+import (
+	"strconv"
+	"strings"
+	"time"
+
+	"cdpnetool/pkg/rulespec"
+)
+
+// weekdayNames 星期名称（小写）-> time.Weekday，支持缩写与全称
+var weekdayNames = map[string]time.Weekday{
+	"sun": time.Sunday, "sunday": time.Sunday,
+	"mon": time.Monday, "monday": time.Monday,
+	"tue": time.Tuesday, "tuesday": time.Tuesday,
+	"wed": time.Wednesday, "wednesday": time.Wednesday,
+	"thu": time.Thursday, "thursday": time.Thursday,
+	"fri": time.Friday, "friday": time.Friday,
+	"sat": time.Saturday, "saturday": time.Saturday,
+}
+
+// inTimeWindow 评估 timeWindow 条件：value 为 HH:MM-HH:MM（按本地时间，含起点不含终点），
+// 起点晚于终点时跨越午夜，起点等于终点时表示全天；days 限定窗口开始的那一天，跨午夜的后半段属于前一天。
+// 格式错误时不成立
+func inTimeWindow(c *rulespec.Condition, now time.Time) bool {
+	from, to, ok := parseTimeRange(c.Value)
+	if !ok {
+		return false
+	}
+	mins := now.Hour()*60 + now.Minute()
+	day := now.Weekday()
+	switch {
+	case from == to:
+	case from < to:
+		if mins < from || mins >= to {
+			return false
+		}
+	default:
+		if mins < to {
+			day = (day + 6) % 7
+		} else if mins < from {
+			return false
+		}
+	}
+	if len(c.Days) == 0 {
+		return true
+	}
+	for _, d := range c.Days {
+		wd, ok := weekdayNames[strings.ToLower(strings.TrimSpace(d))]
+		if ok && wd == day {
+			return true
+		}
+	}
+	return false
+}
+
+// parseTimeRange 解析 HH:MM-HH:MM，返回两端距零点的分钟数
+func parseTimeRange(s string) (int, int, bool) {
+	a, b, ok := strings.Cut(s, "-")
+	if !ok {
+		return 0, 0, false
+	}
+	from, ok1 := parseClock(a)
+	to, ok2 := parseClock(b)
+	return from, to, ok1 && ok2
+}
+
+// parseClock 解析 HH:MM（24 小时制），24:00 视为次日零点
+func parseClock(s string) (int, bool) {
+	h, m, ok := strings.Cut(strings.TrimSpace(s), ":")
+	if !ok {
+		return 0, false
+	}
+	hh, err1 := strconv.Atoi(h)
+	mm, err2 := strconv.Atoi(m)
+	if err1 != nil || err2 != nil || hh < 0 || mm < 0 || mm > 59 || hh > 24 || (hh == 24 && mm != 0) {
+		return 0, false
+	}
+	return (hh*60 + mm) % (24 * 60), true
+}
@@ -0,0 +1,94 @@
+package rules
+
+import (
+	"net/url"
+	"regexp"
+	"strings"
+
+	"cdpnetool/pkg/rulespec"
+)
+
+// matchURLGlob 按协议、主机、路径分别匹配 urlGlob 模式：
+// 主机中 * 匹配任意字符；路径中 * 匹配单个路径段内的字符，** 跨越多个路径段；
+// 模式不含 ? 时忽略查询串
+func (e *Engine) matchURLGlob(rawURL, pattern string) bool {
+	if pattern == "" {
+		return false
+	}
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return false
+	}
+	g := rulespec.ParseURLGlob(pattern)
+
+	if g.Scheme != "" && !e.matchRegex(u.Scheme, "(?i)^"+globRegexp(g.Scheme, false)+"$") {
+		return false
+	}
+	if g.Host != "" {
+		host := u.Hostname()
+		if g.HostHasPort() {
+			host = u.Host
+		}
+		if !e.matchRegex(host, "(?i)^"+globRegexp(g.Host, false)+"$") {
+			return false
+		}
+	}
+	if g.Path != "" {
+		path := u.EscapedPath()
+		if path == "" {
+			path = "/"
+		}
+		if !e.matchRegex(path, "^"+globRegexp(g.Path, true)+"$") {
+			return false
+		}
+	}
+	if g.HasQuery && !e.matchRegex(u.RawQuery, "^"+globRegexp(g.Query, false)+"$") {
+		return false
+	}
+	return true
+}
+
+// globRegexp 将 glob 转换为正则表达式。segmented 为 true 时 * 不跨越 /，
+// ** 跨越多个路径段，且 /** 可以匹配零个路径段（/api/** 同时匹配 /api）
+func globRegexp(glob string, segmented bool) string {
+	var b strings.Builder
+	for i := 0; i < len(glob); {
+		switch {
+		case strings.HasPrefix(glob[i:], "**"):
+			i += 2
+			if !segmented {
+				b.WriteString(".*")
+				continue
+			}
+			if strings.HasSuffix(b.String(), "/") && (i == len(glob) || glob[i] == '/') {
+				// /** 或 /**/：整体可选
+				s := strings.TrimSuffix(b.String(), "/")
+				b.Reset()
+				b.WriteString(s)
+				if i < len(glob) {
+					i++
+					b.WriteString("/(?:.*/)?")
+				} else {
+					b.WriteString("(?:/.*)?")
+				}
+				continue
+			}
+			b.WriteString(".*")
+		case glob[i] == '*':
+			i++
+			if segmented {
+				b.WriteString("[^/]*")
+			} else {
+				b.WriteString(".*")
+			}
+		default:
+			j := strings.IndexByte(glob[i:], '*')
+			if j < 0 {
+				j = len(glob) - i
+			}
+			b.WriteString(regexp.QuoteMeta(glob[i : i+j]))
+			i += j
+		}
+	}
+	return b.String()
+}
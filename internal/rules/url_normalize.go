@@ -0,0 +1,51 @@
+package rules
+
+import (
+	"net/url"
+	"sort"
+	"strings"
+
+	"cdpnetool/pkg/rulespec"
+)
+
+// normalizeURL 按规则的规范化选项改写 URL，无法解析时原样返回
+func normalizeURL(raw string, n *rulespec.URLNormalize) string {
+	if !n.Enabled() {
+		return raw
+	}
+	u, err := url.Parse(raw)
+	if err != nil || u.Host == "" {
+		return raw
+	}
+
+	if n.CaseInsensitiveHost {
+		u.Host = strings.ToLower(u.Host)
+	}
+	if n.IgnoreDefaultPort {
+		scheme := strings.ToLower(u.Scheme)
+		if port := u.Port(); (port == "80" && (scheme == "http" || scheme == "ws")) || (port == "443" && (scheme == "https" || scheme == "wss")) {
+			u.Host = strings.TrimSuffix(u.Host, ":"+port)
+		}
+	}
+	if n.IgnoreTrailingSlash {
+		u.Path = strings.TrimRight(u.Path, "/")
+		u.RawPath = strings.TrimRight(u.RawPath, "/")
+	}
+	if n.SortQuery && u.RawQuery != "" {
+		// 保留参数原有编码，仅按名称稳定排序
+		pairs := strings.Split(u.RawQuery, "&")
+		sort.SliceStable(pairs, func(i, j int) bool {
+			return queryKey(pairs[i]) < queryKey(pairs[j])
+		})
+		u.RawQuery = strings.Join(pairs, "&")
+	}
+	return u.String()
+}
+
+// queryKey 返回查询参数片段的名称部分
+func queryKey(pair string) string {
+	if i := strings.IndexByte(pair, '='); i >= 0 {
+		return pair[:i]
+	}
+	return pair
+}
@@ -7,8 +7,10 @@ import (
 	"sync"
 	"time"
 
+	"cdpnetool/internal/analyzer"
 	"cdpnetool/internal/cdp"
 	"cdpnetool/internal/logger"
+	"cdpnetool/internal/shipper"
 	"cdpnetool/pkg/model"
 	"cdpnetool/pkg/rulespec"
 
@@ -19,14 +21,28 @@ type svc struct {
 	mu       sync.Mutex
 	sessions map[model.SessionID]*session
 	log      logger.Logger
+	schema   *analyzer.SchemaTracker
+	content  *analyzer.ContentTracker
+	resolve  rulespec.ConfigResolver // 展开配置 includes 引用
+	hookMu   sync.Mutex
+	hooks    []hook // 注册的中间件，按注册顺序应用到每个会话，受 hookMu 保护
+}
+
+// hook 注册在某个挂载点上的中间件
+type hook struct {
+	point model.HookPoint
+	mw    model.Middleware
 }
 
 type session struct {
-	id     model.SessionID
-	cfg    model.SessionConfig
-	config *rulespec.Config
-	events chan model.InterceptEvent
-	mgr    *cdp.Manager
+	id      model.SessionID
+	cfg     model.SessionConfig
+	config  *rulespec.Config
+	events  chan model.InterceptEvent
+	pending chan model.PendingItem // 断点通知，管理器重建后沿用
+	mgr     *cdp.Manager
+	ship    *shipper.Dispatcher        // 事件推送，未配置时为 nil
+	dups    *analyzer.DuplicateTracker // 重复请求跟踪，未开启检测时为 nil；管理器重建后沿用
 }
 
 // New 创建并返回服务层实例
@@ -34,7 +50,66 @@ func New(l logger.Logger) *svc {
 	if l == nil {
 		l = logger.NewNoopLogger()
 	}
-	return &svc{sessions: make(map[model.SessionID]*session), log: l}
+	return &svc{
+		sessions: make(map[model.SessionID]*session),
+		log:      l,
+		schema:   analyzer.NewSchemaTracker(),
+		content:  analyzer.NewContentTracker(),
+	}
+}
+
+// newManager 按会话配置创建管理器
+func (s *svc) newManager(ses *session) *cdp.Manager {
+	mgr := cdp.New(ses.cfg.DevToolsURL, ses.events, s.log)
+	mgr.SetPendingChannel(ses.pending)
+	mgr.SetConcurrency(ses.cfg.Concurrency)
+	mgr.SetHostConcurrency(ses.cfg.HostConcurrency)
+	mgr.SetRuntime(ses.cfg.BodySizeThreshold, ses.cfg.ProcessTimeoutMS)
+	mgr.SetStreamBodyLimit(ses.cfg.StreamBodyLimit)
+	mgr.SetMaxFulfillBody(ses.cfg.MaxFulfillBody)
+	mgr.SetRangePolicy(ses.cfg.RangePolicy)
+	mgr.SetHostPolicy(ses.cfg.AllowedHosts, ses.cfg.DeniedHosts)
+	mgr.SetBlockResourceTypes(ses.cfg.BlockResourceTypes)
+	mgr.SetPinnedEndpoints(ses.cfg.PinnedEndpoints)
+	mgr.SetHeaderPolicy(ses.cfg.HeaderPolicy)
+	mgr.SetCredentials(ses.cfg.Credentials)
+	mgr.SetProxyAuth(ses.cfg.ProxyAuth)
+	mgr.SetUserAgentOverride(ses.cfg.UserAgent)
+	mgr.SetGeolocation(ses.cfg.Geolocation)
+	mgr.SetLocaleOverride(ses.cfg.Locale)
+	mgr.SetExtraHeaders(ses.cfg.ExtraHeaders)
+	mgr.SetSecrets(ses.cfg.Secrets)
+	mgr.SetCacheBypass(ses.cfg.CacheBypass)
+	mgr.SetCaptureOnly(ses.cfg.CaptureOnly)
+	mgr.SetFullCapture(ses.cfg.FullCapture)
+	mgr.SetObserveOnly(ses.cfg.ObserveOnly)
+	mgr.SetShareRuleCache(ses.cfg.ShareRuleCache)
+	mgr.SetInterceptStage(ses.cfg.InterceptStage)
+	mgr.SetInterceptResourceTypes(ses.cfg.InterceptResourceTypes)
+	mgr.SetCaptureTiming(ses.cfg.CaptureTiming)
+	mgr.SetInterceptWorkers(ses.cfg.InterceptWorkers)
+	mgr.SetWatchdogRecover(ses.cfg.WatchdogRecover)
+	mgr.SetWaitForReady(ses.cfg.WaitForReady)
+	s.hookMu.Lock()
+	for _, h := range s.hooks {
+		mgr.Use(h.point, sessionMiddleware(ses.id, h.mw))
+	}
+	s.hookMu.Unlock()
+	if ses.ship != nil {
+		mgr.SetEventSink(ses.ship.Publish)
+	}
+	if ses.cfg.SchemaDriftDetection {
+		// 结构跟踪器在会话之间共享，以便发现跨会话的结构变化
+		mgr.SetSchemaTracker(s.schema)
+	}
+	if ses.cfg.ContentChangeDetection {
+		// 与结构跟踪器相同，在会话之间共享以便比较多次运行的结果
+		mgr.SetContentTracker(s.content)
+	}
+	if ses.dups != nil {
+		mgr.SetDuplicateTracker(ses.dups)
+	}
+	return mgr
 }
 
 // StartSession 创建新会话并初始化管理器
@@ -52,19 +127,45 @@ func (s *svc) StartSession(cfg model.SessionConfig) (model.SessionID, error) {
 	if cfg.ProcessTimeoutMS <= 0 {
 		cfg.ProcessTimeoutMS = 3000
 	}
+	if cfg.StreamBodyLimit <= 0 {
+		cfg.StreamBodyLimit = 64 << 20 // 64MB
+	}
+	if cfg.MaxFulfillBody <= 0 {
+		cfg.MaxFulfillBody = 64 << 20 // 64MB
+	}
 	if cfg.PendingCapacity <= 0 {
 		cfg.PendingCapacity = 64
 	}
+	if cfg.InterceptStage == "" {
+		cfg.InterceptStage = model.InterceptStageBoth
+	}
+	if cfg.RangePolicy == "" {
+		cfg.RangePolicy = model.RangePolicySlice
+	}
+	if cfg.RequireSignedConfig {
+		if _, err := rulespec.ParsePublicKey(cfg.ConfigVerifyKey); err != nil {
+			return "", fmt.Errorf("签名配置模式需要有效的公钥: %w", err)
+		}
+	}
 
 	id := model.SessionID(uuid.New().String())
 	ses := &session{
-		id:     id,
-		cfg:    cfg,
-		events: make(chan model.InterceptEvent, 128),
+		id:      id,
+		cfg:     cfg,
+		events:  make(chan model.InterceptEvent, 128),
+		pending: make(chan model.PendingItem, 64),
 	}
-	ses.mgr = cdp.New(cfg.DevToolsURL, ses.events, s.log)
-	ses.mgr.SetConcurrency(cfg.Concurrency)
-	ses.mgr.SetRuntime(cfg.BodySizeThreshold, cfg.ProcessTimeoutMS)
+	if cfg.DuplicateDetection {
+		ses.dups = analyzer.NewDuplicateTracker(time.Duration(cfg.DuplicateWindowMS) * time.Millisecond)
+	}
+	if len(cfg.Shippers) > 0 {
+		d, err := shipper.NewDispatcher(id, cfg.Shippers, s.log)
+		if err != nil {
+			return "", fmt.Errorf("事件推送配置无效: %w", err)
+		}
+		ses.ship = d
+	}
+	ses.mgr = s.newManager(ses)
 
 	// 验证连接是否有效：尝试获取目标列表
 	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
@@ -73,12 +174,15 @@ func (s *svc) StartSession(cfg model.SessionConfig) (model.SessionID, error) {
 	_, err := ses.mgr.ListTargets(ctx)
 	if err != nil {
 		s.log.Err(err, "连接 DevTools 失败", "devtools", cfg.DevToolsURL)
+		if ses.ship != nil {
+			ses.ship.Close()
+		}
 		return "", fmt.Errorf("无法连接到 DevTools: %w", err)
 	}
 
 	s.sessions[id] = ses
 	s.log.Info("创建会话成功", "session", string(id), "devtools", cfg.DevToolsURL,
-		"concurrency", cfg.Concurrency, "pending", cfg.PendingCapacity)
+		"concurrency", cfg.Concurrency, "pending", cfg.PendingCapacity, "captureOnly", cfg.CaptureOnly, "observeOnly", cfg.ObserveOnly)
 	return id, nil
 }
 
@@ -94,10 +198,18 @@ func (s *svc) StopSession(id model.SessionID) error {
 		return errors.New("cdpnetool: session not found")
 	}
 	if ses.mgr != nil {
+		// 先停止代理并等待处理中的请求结束，之后不再有事件写入通道
+		ses.mgr.StopProxy()
 		_ = ses.mgr.Disable()
 		_ = ses.mgr.DetachAll()
+		// 等待处理中的请求与延迟任务结束，之后关闭通道不会与推送竞争
+		ses.mgr.Close()
+	}
+	if ses.ship != nil {
+		ses.ship.Close()
 	}
 	close(ses.events)
+	close(ses.pending)
 	s.log.Info("会话已停止", "session", string(id))
 	return nil
 }
@@ -112,9 +224,7 @@ func (s *svc) AttachTarget(id model.SessionID, target model.TargetID) error {
 	}
 
 	if ses.mgr == nil {
-		ses.mgr = cdp.New(ses.cfg.DevToolsURL, ses.events, s.log)
-		ses.mgr.SetConcurrency(ses.cfg.Concurrency)
-		ses.mgr.SetRuntime(ses.cfg.BodySizeThreshold, ses.cfg.ProcessTimeoutMS)
+		ses.mgr = s.newManager(ses)
 	}
 
 	err := ses.mgr.AttachTarget(target)
@@ -127,6 +237,69 @@ func (s *svc) AttachTarget(id model.SessionID, target model.TargetID) error {
 	return nil
 }
 
+// OpenTarget 为指定会话新建标签页，附加并启用拦截后再导航到 url，返回新目标 ID
+func (s *svc) OpenTarget(id model.SessionID, url string) (model.TargetID, error) {
+	s.mu.Lock()
+	ses, ok := s.sessions[id]
+	s.mu.Unlock()
+	if !ok {
+		return "", errors.New("cdpnetool: session not found")
+	}
+
+	if ses.mgr == nil {
+		ses.mgr = s.newManager(ses)
+	}
+
+	target, err := ses.mgr.OpenTarget(url)
+	if err != nil {
+		s.log.Err(err, "打开并拦截新标签页失败", "session", string(id), "url", url)
+		return target, err
+	}
+
+	s.log.Info("打开并拦截新标签页成功", "session", string(id), "target", string(target), "url", url)
+	return target, nil
+}
+
+// Navigate 让会话中已附加的页面导航到 url
+func (s *svc) Navigate(id model.SessionID, target model.TargetID, url string) error {
+	mgr, err := s.sessionManager(id)
+	if err != nil {
+		return err
+	}
+	if err := mgr.Navigate(target, url); err != nil {
+		s.log.Err(err, "页面导航失败", "session", string(id), "target", string(target), "url", url)
+		return err
+	}
+	return nil
+}
+
+// Reload 重新加载会话中已附加的页面，ignoreCache 为 true 时跳过缓存
+func (s *svc) Reload(id model.SessionID, target model.TargetID, ignoreCache bool) error {
+	mgr, err := s.sessionManager(id)
+	if err != nil {
+		return err
+	}
+	if err := mgr.Reload(target, ignoreCache); err != nil {
+		s.log.Err(err, "重新加载页面失败", "session", string(id), "target", string(target))
+		return err
+	}
+	return nil
+}
+
+// CaptureScreenshot 截取会话中已附加页面的当前视口
+func (s *svc) CaptureScreenshot(id model.SessionID, target model.TargetID) (model.Screenshot, error) {
+	mgr, err := s.sessionManager(id)
+	if err != nil {
+		return model.Screenshot{}, err
+	}
+	shot, err := mgr.CaptureScreenshot(target)
+	if err != nil {
+		s.log.Err(err, "页面截图失败", "session", string(id), "target", string(target))
+		return model.Screenshot{}, err
+	}
+	return shot, nil
+}
+
 // DetachTarget 为指定会话断开目标连接
 func (s *svc) DetachTarget(id model.SessionID, target model.TargetID) error {
 	s.mu.Lock()
@@ -150,15 +323,257 @@ func (s *svc) ListTargets(id model.SessionID) ([]model.TargetInfo, error) {
 		return nil, errors.New("cdpnetool: session not found")
 	}
 	if ses.mgr == nil {
-		ses.mgr = cdp.New(ses.cfg.DevToolsURL, ses.events, s.log)
-		ses.mgr.SetConcurrency(ses.cfg.Concurrency)
-		ses.mgr.SetRuntime(ses.cfg.BodySizeThreshold, ses.cfg.ProcessTimeoutMS)
+		ses.mgr = s.newManager(ses)
 	}
 	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
 	defer cancel()
 	return ses.mgr.ListTargets(ctx)
 }
 
+// GetSessionInfo 获取会话信息，包括已附加的目标和浏览器版本与特性支持情况
+func (s *svc) GetSessionInfo(id model.SessionID) (model.SessionInfo, error) {
+	s.mu.Lock()
+	ses, ok := s.sessions[id]
+	s.mu.Unlock()
+	if !ok {
+		return model.SessionInfo{}, errors.New("cdpnetool: session not found")
+	}
+	if ses.mgr == nil {
+		ses.mgr = s.newManager(ses)
+	}
+	return model.SessionInfo{
+		ID:           ses.id,
+		DevToolsURL:  ses.cfg.DevToolsURL,
+		Intercepting: ses.mgr.Intercepting(),
+		Targets:      ses.mgr.AttachedTargets(),
+		Capabilities: ses.mgr.Capabilities(),
+		ProxyAddr:    ses.mgr.ProxyAddr(),
+	}, nil
+}
+
+// sessionManager 返回已初始化的会话管理器
+func (s *svc) sessionManager(id model.SessionID) (*cdp.Manager, error) {
+	s.mu.Lock()
+	ses, ok := s.sessions[id]
+	s.mu.Unlock()
+	if !ok {
+		return nil, errors.New("cdpnetool: session not found")
+	}
+	if ses.mgr == nil {
+		return nil, errors.New("cdpnetool: manager not initialized")
+	}
+	return ses.mgr, nil
+}
+
+// StartProxy 为会话启动内置转发代理，返回实际监听地址
+func (s *svc) StartProxy(id model.SessionID, addr string) (string, error) {
+	mgr, err := s.sessionManager(id)
+	if err != nil {
+		return "", err
+	}
+	listen, err := mgr.StartProxy(addr)
+	if err != nil {
+		s.log.Err(err, "启动内置转发代理失败", "session", string(id), "addr", addr)
+		return "", err
+	}
+	s.log.Info("启动内置转发代理成功", "session", string(id), "addr", listen)
+	return listen, nil
+}
+
+// StopProxy 停止会话的内置转发代理
+func (s *svc) StopProxy(id model.SessionID) error {
+	mgr, err := s.sessionManager(id)
+	if err != nil {
+		return err
+	}
+	mgr.StopProxy()
+	return nil
+}
+
+// GetCookies 获取目标中适用于指定 URL 的 Cookie
+func (s *svc) GetCookies(id model.SessionID, target model.TargetID, urls []string) ([]model.Cookie, error) {
+	mgr, err := s.sessionManager(id)
+	if err != nil {
+		return nil, err
+	}
+	return mgr.GetCookies(target, urls)
+}
+
+// SetCookie 在目标所在的浏览器上下文中设置 Cookie
+func (s *svc) SetCookie(id model.SessionID, target model.TargetID, c model.Cookie) error {
+	mgr, err := s.sessionManager(id)
+	if err != nil {
+		return err
+	}
+	if err := mgr.SetCookie(target, c); err != nil {
+		s.log.Err(err, "设置 Cookie 失败", "session", string(id), "name", c.Name)
+		return err
+	}
+	return nil
+}
+
+// DeleteCookies 删除目标所在浏览器上下文中匹配条件的 Cookie，返回删除的数量
+func (s *svc) DeleteCookies(id model.SessionID, target model.TargetID, f model.CookieFilter) (int, error) {
+	mgr, err := s.sessionManager(id)
+	if err != nil {
+		return 0, err
+	}
+	n, err := mgr.DeleteCookies(target, f)
+	if err != nil {
+		s.log.Err(err, "删除 Cookie 失败", "session", string(id), "name", f.Name)
+	}
+	return n, err
+}
+
+// ReplayRequest 在目标页面上下文中重新发出请求并返回响应
+func (s *svc) ReplayRequest(id model.SessionID, target model.TargetID, req model.RequestInfo) (model.ResponseInfo, error) {
+	mgr, err := s.sessionManager(id)
+	if err != nil {
+		return model.ResponseInfo{}, err
+	}
+	resp, err := mgr.ReplayRequest(target, req)
+	if err != nil {
+		s.log.Err(err, "重放请求失败", "session", string(id), "url", req.URL)
+	}
+	return resp, err
+}
+
+// AddQuickMock 为目标添加自动到期的临时 Mock；只接受签名配置的会话不允许添加
+func (s *svc) AddQuickMock(id model.SessionID, mock model.QuickMock) (model.QuickMock, error) {
+	s.mu.Lock()
+	ses, ok := s.sessions[id]
+	s.mu.Unlock()
+	if !ok {
+		return model.QuickMock{}, errors.New("cdpnetool: session not found")
+	}
+	if ses.cfg.RequireSignedConfig {
+		s.log.Warn("拒绝在只接受签名配置的会话中添加临时 Mock", "session", string(id), "url", mock.URL)
+		return model.QuickMock{}, errors.New("cdpnetool: session only accepts signed configs")
+	}
+	mgr, err := s.sessionManager(id)
+	if err != nil {
+		return model.QuickMock{}, err
+	}
+	added, err := mgr.AddQuickMock(mock)
+	if err != nil {
+		s.log.Err(err, "添加临时 Mock 失败", "session", string(id), "url", mock.URL)
+	}
+	return added, err
+}
+
+// RemoveQuickMock 移除临时 Mock
+func (s *svc) RemoveQuickMock(id model.SessionID, mockID string) error {
+	mgr, err := s.sessionManager(id)
+	if err != nil {
+		return err
+	}
+	if !mgr.RemoveQuickMock(mockID) {
+		return errors.New("cdpnetool: quick mock not found")
+	}
+	return nil
+}
+
+// ListQuickMocks 列出会话中生效的临时 Mock
+func (s *svc) ListQuickMocks(id model.SessionID) ([]model.QuickMock, error) {
+	mgr, err := s.sessionManager(id)
+	if err != nil {
+		return nil, err
+	}
+	return mgr.QuickMocks(), nil
+}
+
+// Use 在拦截流水线的挂载点注册中间件，应用到已有的和之后创建的所有会话
+func (s *svc) Use(point model.HookPoint, mw model.Middleware) error {
+	switch point {
+	case model.HookBeforeEval, model.HookAfterEval, model.HookBeforeApply:
+	default:
+		return fmt.Errorf("cdpnetool: unknown hook point %q", point)
+	}
+	if mw == nil {
+		return errors.New("cdpnetool: middleware is nil")
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.hookMu.Lock()
+	s.hooks = append(s.hooks, hook{point: point, mw: mw})
+	s.hookMu.Unlock()
+	for _, ses := range s.sessions {
+		if ses.mgr != nil {
+			ses.mgr.Use(point, sessionMiddleware(ses.id, mw))
+		}
+	}
+	s.log.Info("注册中间件完成", "point", string(point))
+	return nil
+}
+
+// sessionMiddleware 包装中间件，调用前填充请求所属的会话 ID
+func sessionMiddleware(id model.SessionID, mw model.Middleware) model.Middleware {
+	return func(next model.HookHandler) model.HookHandler {
+		h := mw(next)
+		return func(ctx context.Context, ex *model.Exchange) {
+			ex.Session = id
+			h(ctx, ex)
+		}
+	}
+}
+
+// Variables 返回会话中 captureVar 行为保存的变量
+func (s *svc) Variables(id model.SessionID) (map[string]string, error) {
+	mgr, err := s.sessionManager(id)
+	if err != nil {
+		return nil, err
+	}
+	return mgr.Variables(), nil
+}
+
+// ClearVariables 清空会话变量
+func (s *svc) ClearVariables(id model.SessionID) error {
+	mgr, err := s.sessionManager(id)
+	if err != nil {
+		return err
+	}
+	mgr.ClearVariables()
+	s.log.Info("清空会话变量完成", "session", string(id))
+	return nil
+}
+
+// SubscribePending 订阅会话中命中 pause 行为、等待人工处理的请求
+func (s *svc) SubscribePending(id model.SessionID) (<-chan model.PendingItem, error) {
+	s.mu.Lock()
+	ses, ok := s.sessions[id]
+	s.mu.Unlock()
+	if !ok {
+		return nil, errors.New("cdpnetool: session not found")
+	}
+	return ses.pending, nil
+}
+
+// ApprovePending 放行断点暂停的请求，edits 不为 nil 时先应用修改
+func (s *svc) ApprovePending(id model.SessionID, itemID string, edits *model.PendingEdits) error {
+	mgr, err := s.sessionManager(id)
+	if err != nil {
+		return err
+	}
+	if err := mgr.ApprovePending(itemID, edits); err != nil {
+		s.log.Err(err, "放行断点失败", "session", string(id), "item", itemID)
+		return err
+	}
+	return nil
+}
+
+// RejectPending 拒绝断点暂停的请求
+func (s *svc) RejectPending(id model.SessionID, itemID string) error {
+	mgr, err := s.sessionManager(id)
+	if err != nil {
+		return err
+	}
+	if err := mgr.RejectPending(itemID); err != nil {
+		s.log.Err(err, "拒绝断点失败", "session", string(id), "item", itemID)
+		return err
+	}
+	return nil
+}
+
 // EnableInterception 启用会话的拦截功能
 func (s *svc) EnableInterception(id model.SessionID) error {
 	s.mu.Lock()
@@ -179,6 +594,32 @@ func (s *svc) EnableInterception(id model.SessionID) error {
 	return err
 }
 
+// EnableCacheBypass 为会话禁用浏览器缓存
+func (s *svc) EnableCacheBypass(id model.SessionID) error {
+	return s.setCacheBypass(id, true)
+}
+
+// DisableCacheBypass 恢复会话的浏览器缓存
+func (s *svc) DisableCacheBypass(id model.SessionID) error {
+	return s.setCacheBypass(id, false)
+}
+
+// setCacheBypass 更新会话的浏览器缓存开关
+func (s *svc) setCacheBypass(id model.SessionID, on bool) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	ses, ok := s.sessions[id]
+	if !ok {
+		return errors.New("cdpnetool: session not found")
+	}
+	ses.cfg.CacheBypass = on
+	if ses.mgr != nil {
+		ses.mgr.SetCacheBypass(on)
+	}
+	s.log.Info("更新浏览器缓存开关完成", "session", string(id), "disabled", on)
+	return nil
+}
+
 // DisableInterception 停用会话的拦截功能
 func (s *svc) DisableInterception(id model.SessionID) error {
 	s.mu.Lock()
@@ -207,6 +648,31 @@ func (s *svc) LoadRules(id model.SessionID, cfg *rulespec.Config) error {
 	if !ok {
 		return errors.New("cdpnetool: session not found")
 	}
+	var check func(*rulespec.Config) error
+	if ses.cfg.RequireSignedConfig {
+		pub, err := rulespec.ParsePublicKey(ses.cfg.ConfigVerifyKey)
+		if err != nil {
+			return err
+		}
+		check = func(c *rulespec.Config) error { return c.VerifySignature(pub) }
+		if err := check(cfg); err != nil {
+			s.log.Warn("拒绝加载未通过签名校验的配置", "session", string(id), "config", cfg.ID, "error", err.Error())
+			return fmt.Errorf("cdpnetool: %w", err)
+		}
+	}
+	// 被引用的配置同样需要通过签名校验
+	composed, err := cfg.Compose(s.resolve, check)
+	if err != nil {
+		s.log.Warn("展开配置引用失败", "session", string(id), "config", cfg.ID, "error", err.Error())
+		return fmt.Errorf("cdpnetool: %w", err)
+	}
+	cfg = composed
+	for i := range cfg.Rules {
+		if err := cfg.Rules[i].ValidateActions(); err != nil {
+			s.log.Warn("规则行为参数无效", "session", string(id), "rule", cfg.Rules[i].ID, "error", err.Error())
+			return fmt.Errorf("cdpnetool: rule %s: %w", cfg.Rules[i].ID, err)
+		}
+	}
 	ses.config = cfg
 	s.log.Info("加载规则配置完成", "session", string(id), "count", len(cfg.Rules), "version", cfg.Version)
 	if ses.mgr != nil {
@@ -215,6 +681,37 @@ func (s *svc) LoadRules(id model.SessionID, cfg *rulespec.Config) error {
 	return nil
 }
 
+// SetTagEnabled 启用或停用会话当前配置中带有指定标签的全部规则，返回状态发生变化的规则数
+func (s *svc) SetTagEnabled(id model.SessionID, tag string, enabled bool) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	ses, ok := s.sessions[id]
+	if !ok {
+		return 0, errors.New("cdpnetool: session not found")
+	}
+	if ses.config == nil {
+		return 0, errors.New("cdpnetool: no config loaded")
+	}
+	if ses.cfg.RequireSignedConfig {
+		s.log.Warn("拒绝在只接受签名配置的会话中按标签切换规则", "session", string(id), "tag", tag)
+		return 0, errors.New("cdpnetool: session only accepts signed configs")
+	}
+	// 在副本上修改，避免与引擎正在读取的规则列表竞争
+	cfg := *ses.config
+	cfg.Rules = append([]rulespec.Rule(nil), ses.config.Rules...)
+	n, err := cfg.SetTagEnabled(tag, enabled)
+	if err != nil {
+		return 0, fmt.Errorf("cdpnetool: %w", err)
+	}
+	ses.config = &cfg
+	if ses.mgr != nil {
+		// 只改变启用状态，保留计数条件与频率窗口
+		ses.mgr.ReplaceRules(&cfg)
+	}
+	s.log.Info("按标签切换规则完成", "session", string(id), "tag", tag, "enabled", enabled, "changed", n)
+	return n, nil
+}
+
 // GetRuleStats 返回会话内规则引擎的命中统计
 func (s *svc) GetRuleStats(id model.SessionID) (model.EngineStats, error) {
 	s.mu.Lock()
@@ -229,6 +726,47 @@ func (s *svc) GetRuleStats(id model.SessionID) (model.EngineStats, error) {
 	return ses.mgr.GetStats(), nil
 }
 
+// DuplicateReport 返回会话中重复发送的请求汇总，按重复次数从多到少排序；未开启重复请求检测时为空
+func (s *svc) DuplicateReport(id model.SessionID) ([]model.DuplicateRequestStat, error) {
+	s.mu.Lock()
+	ses, ok := s.sessions[id]
+	s.mu.Unlock()
+	if !ok {
+		return nil, errors.New("cdpnetool: session not found")
+	}
+	out := []model.DuplicateRequestStat{}
+	if ses.dups == nil {
+		return out, nil
+	}
+	for _, d := range ses.dups.Report() {
+		out = append(out, model.DuplicateRequestStat{
+			Method:        d.Method,
+			URL:           d.URL,
+			BodyHash:      d.BodyHash,
+			Duplicates:    d.Duplicates,
+			MaxBurst:      d.MaxBurst,
+			MinIntervalMS: d.MinInterval.Milliseconds(),
+			FirstSeen:     d.First.UnixMilli(),
+			LastSeen:      d.Last.UnixMilli(),
+		})
+	}
+	return out, nil
+}
+
+// ClearDuplicateReport 清空会话的重复请求记录
+func (s *svc) ClearDuplicateReport(id model.SessionID) error {
+	s.mu.Lock()
+	ses, ok := s.sessions[id]
+	s.mu.Unlock()
+	if !ok {
+		return errors.New("cdpnetool: session not found")
+	}
+	if ses.dups != nil {
+		ses.dups.Reset()
+	}
+	return nil
+}
+
 // SubscribeEvents 订阅会话事件流
 func (s *svc) SubscribeEvents(id model.SessionID) (<-chan model.InterceptEvent, error) {
 	s.mu.Lock()
@@ -239,3 +777,222 @@ func (s *svc) SubscribeEvents(id model.SessionID) (<-chan model.InterceptEvent,
 	}
 	return ses.events, nil
 }
+
+// SetHostPolicy 更新会话允许/禁止规则修改的主机列表
+func (s *svc) SetHostPolicy(id model.SessionID, allow, deny []string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	ses, ok := s.sessions[id]
+	if !ok {
+		return errors.New("cdpnetool: session not found")
+	}
+	ses.cfg.AllowedHosts = allow
+	ses.cfg.DeniedHosts = deny
+	if ses.mgr != nil {
+		ses.mgr.SetHostPolicy(allow, deny)
+	}
+	s.log.Info("更新主机策略完成", "session", string(id), "allow", len(allow), "deny", len(deny))
+	return nil
+}
+
+// SetBlockResourceTypes 更新会话一键屏蔽的资源类别
+func (s *svc) SetBlockResourceTypes(id model.SessionID, classes []string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	ses, ok := s.sessions[id]
+	if !ok {
+		return errors.New("cdpnetool: session not found")
+	}
+	ses.cfg.BlockResourceTypes = classes
+	if ses.mgr != nil {
+		ses.mgr.SetBlockResourceTypes(classes)
+	}
+	s.log.Info("更新屏蔽资源类别完成", "session", string(id), "classes", classes)
+	return nil
+}
+
+// SetPinnedEndpoints 更新会话重点关注的接口
+func (s *svc) SetPinnedEndpoints(id model.SessionID, pins model.EndpointPins) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	ses, ok := s.sessions[id]
+	if !ok {
+		return errors.New("cdpnetool: session not found")
+	}
+	ses.cfg.PinnedEndpoints = pins
+	if ses.mgr != nil {
+		ses.mgr.SetPinnedEndpoints(pins)
+	}
+	s.log.Info("更新重点关注接口完成", "session", string(id), "count", len(pins))
+	return nil
+}
+
+// SetHeaderPolicy 更新会话的响应头策略
+func (s *svc) SetHeaderPolicy(id model.SessionID, policy model.HeaderPolicy) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	ses, ok := s.sessions[id]
+	if !ok {
+		return errors.New("cdpnetool: session not found")
+	}
+	ses.cfg.HeaderPolicy = policy
+	if ses.mgr != nil {
+		ses.mgr.SetHeaderPolicy(policy)
+	}
+	s.log.Info("更新响应头策略完成", "session", string(id), "set", len(policy.Set), "remove", len(policy.Remove))
+	return nil
+}
+
+// SetCredentials 更新会话按源注入的认证信息
+func (s *svc) SetCredentials(id model.SessionID, creds []model.OriginCredential) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	ses, ok := s.sessions[id]
+	if !ok {
+		return errors.New("cdpnetool: session not found")
+	}
+	ses.cfg.Credentials = creds
+	if ses.mgr != nil {
+		ses.mgr.SetCredentials(creds)
+	}
+	s.log.Info("更新源认证信息完成", "session", string(id), "count", len(creds))
+	return nil
+}
+
+// SetProxyAuth 更新会话的上游代理认证信息
+func (s *svc) SetProxyAuth(id model.SessionID, cred *model.ProxyCredential) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	ses, ok := s.sessions[id]
+	if !ok {
+		return errors.New("cdpnetool: session not found")
+	}
+	ses.cfg.ProxyAuth = cred
+	if ses.mgr != nil {
+		ses.mgr.SetProxyAuth(cred)
+	}
+	s.log.Info("更新上游代理认证信息完成", "session", string(id), "configured", cred != nil)
+	return nil
+}
+
+// SetUserAgentOverride 更新会话的 User-Agent 与客户端提示覆盖
+func (s *svc) SetUserAgentOverride(id model.SessionID, o model.UserAgentOverride) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	ses, ok := s.sessions[id]
+	if !ok {
+		return errors.New("cdpnetool: session not found")
+	}
+	ses.cfg.UserAgent = o
+	if ses.mgr != nil {
+		ses.mgr.SetUserAgentOverride(o)
+	}
+	s.log.Info("更新 User-Agent 覆盖完成", "session", string(id), "userAgent", o.UserAgent)
+	return nil
+}
+
+// SetGeolocation 更新会话的地理位置覆盖，g 为 nil 时清除覆盖
+func (s *svc) SetGeolocation(id model.SessionID, g *model.Geolocation) error {
+	if g != nil {
+		if g.Latitude < -90 || g.Latitude > 90 {
+			return fmt.Errorf("纬度超出范围 [-90, 90]: %v", g.Latitude)
+		}
+		if g.Longitude < -180 || g.Longitude > 180 {
+			return fmt.Errorf("经度超出范围 [-180, 180]: %v", g.Longitude)
+		}
+		if g.Accuracy < 0 {
+			return fmt.Errorf("精度不能为负数: %v", g.Accuracy)
+		}
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	ses, ok := s.sessions[id]
+	if !ok {
+		return errors.New("cdpnetool: session not found")
+	}
+	ses.cfg.Geolocation = g
+	if ses.mgr != nil {
+		ses.mgr.SetGeolocation(g)
+	}
+	if g == nil {
+		s.log.Info("已清除地理位置覆盖", "session", string(id))
+	} else {
+		s.log.Info("更新地理位置覆盖完成", "session", string(id), "latitude", g.Latitude, "longitude", g.Longitude)
+	}
+	return nil
+}
+
+// SetLocaleOverride 更新会话的时区与区域覆盖
+func (s *svc) SetLocaleOverride(id model.SessionID, o model.LocaleOverride) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	ses, ok := s.sessions[id]
+	if !ok {
+		return errors.New("cdpnetool: session not found")
+	}
+	ses.cfg.Locale = o
+	if ses.mgr != nil {
+		ses.mgr.SetLocaleOverride(o)
+	}
+	s.log.Info("更新时区与区域覆盖完成", "session", string(id), "timezone", o.Timezone, "locale", o.Locale)
+	return nil
+}
+
+// SetExtraHeaders 更新会话轻量注入的请求头
+func (s *svc) SetExtraHeaders(id model.SessionID, headers map[string]string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	ses, ok := s.sessions[id]
+	if !ok {
+		return errors.New("cdpnetool: session not found")
+	}
+	ses.cfg.ExtraHeaders = headers
+	if ses.mgr != nil {
+		ses.mgr.SetExtraHeaders(headers)
+	}
+	s.log.Info("更新轻量请求头注入完成", "session", string(id), "count", len(headers))
+	return nil
+}
+
+// SetSecrets 更新会话行为模板可引用的命名密钥
+func (s *svc) SetSecrets(id model.SessionID, secrets map[string]string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	ses, ok := s.sessions[id]
+	if !ok {
+		return errors.New("cdpnetool: session not found")
+	}
+	ses.cfg.Secrets = secrets
+	if ses.mgr != nil {
+		ses.mgr.SetSecrets(secrets)
+	}
+	s.log.Info("更新命名密钥完成", "session", string(id), "count", len(secrets))
+	return nil
+}
+
+// ExportSchemaFingerprints 导出已记录的 JSON 响应结构指纹
+func (s *svc) ExportSchemaFingerprints() map[string]map[string]string {
+	return s.schema.Snapshot()
+}
+
+// ImportSchemaFingerprints 导入之前保存的 JSON 响应结构指纹
+func (s *svc) ImportSchemaFingerprints(shapes map[string]map[string]string) {
+	s.schema.Restore(shapes)
+}
+
+// ExportContentHashes 导出已记录的响应体哈希
+func (s *svc) ExportContentHashes() map[string]string {
+	return s.content.Snapshot()
+}
+
+// SetConfigResolver 设置展开配置 includes 引用时按配置 ID 查找已保存配置的函数
+func (s *svc) SetConfigResolver(r rulespec.ConfigResolver) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.resolve = r
+}
+
+// ImportContentHashes 导入之前保存的响应体哈希
+func (s *svc) ImportContentHashes(hashes map[string]string) {
+	s.content.Restore(hashes)
+}
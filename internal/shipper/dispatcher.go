@@ -0,0 +1,113 @@
+package shipper
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"cdpnetool/internal/logger"
+	"cdpnetool/pkg/model"
+)
+
+const (
+	dispatchQueueSize = 4096            // 待推送队列容量，满时丢弃
+	dispatchBatchSize = 200             // 单批最大记录数
+	dispatchInterval  = 2 * time.Second // 最长攒批时间
+)
+
+// Dispatcher 异步批量推送事件到一个或多个推送目标，推送失败只记录日志不影响拦截
+type Dispatcher struct {
+	session          model.SessionID
+	shippers         []Shipper
+	includeUnmatched bool
+	log              logger.Logger
+	queue            chan Record
+	done             chan struct{}
+	closeOnce        sync.Once
+}
+
+// NewDispatcher 按配置创建推送分发器
+func NewDispatcher(session model.SessionID, cfgs []model.ShipperConfig, l logger.Logger) (*Dispatcher, error) {
+	d := &Dispatcher{
+		session: session,
+		log:     l,
+		queue:   make(chan Record, dispatchQueueSize),
+		done:    make(chan struct{}),
+	}
+	for _, cfg := range cfgs {
+		s, err := New(cfg)
+		if err != nil {
+			for _, created := range d.shippers {
+				_ = created.Close()
+			}
+			return nil, err
+		}
+		d.shippers = append(d.shippers, s)
+		if cfg.IncludeUnmatched {
+			d.includeUnmatched = true
+		}
+	}
+	go d.run()
+	return d, nil
+}
+
+// Publish 投递事件，不阻塞
+func (d *Dispatcher) Publish(evt model.InterceptEvent) {
+	r, ok := FromEvent(d.session, evt, d.includeUnmatched)
+	if !ok {
+		return
+	}
+	select {
+	case d.queue <- r:
+	default:
+	}
+}
+
+// Close 推送剩余记录并关闭所有推送目标
+func (d *Dispatcher) Close() {
+	d.closeOnce.Do(func() {
+		close(d.queue)
+		<-d.done
+		for _, s := range d.shippers {
+			_ = s.Close()
+		}
+	})
+}
+
+// run 攒批并推送
+func (d *Dispatcher) run() {
+	defer close(d.done)
+	ticker := time.NewTicker(dispatchInterval)
+	defer ticker.Stop()
+
+	batch := make([]Record, 0, dispatchBatchSize)
+	flush := func() {
+		if len(batch) == 0 {
+			return
+		}
+		for _, s := range d.shippers {
+			ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+			if err := s.Ship(ctx, batch); err != nil {
+				d.log.Warn("推送事件失败", "shipper", s.Name(), "count", len(batch), "error", err.Error())
+			}
+			cancel()
+		}
+		batch = batch[:0]
+	}
+
+	for {
+		select {
+		case r, ok := <-d.queue:
+			if !ok {
+				flush()
+				return
+			}
+			batch = append(batch, r)
+			if len(batch) >= dispatchBatchSize {
+				flush()
+			}
+		case <-ticker.C:
+			flush()
+		}
+	}
+}
@@ -0,0 +1,128 @@
+package shipper
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"cdpnetool/pkg/model"
+)
+
+// httpClient 推送使用的 HTTP 客户端
+var httpClient = &http.Client{Timeout: 10 * time.Second}
+
+// post 发送请求并检查状态码
+func post(ctx context.Context, cfg *model.ShipperConfig, endpoint, contentType string, body []byte) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", contentType)
+	switch {
+	case cfg.APIKey != "":
+		req.Header.Set("Authorization", "ApiKey "+cfg.APIKey)
+	case cfg.Username != "":
+		req.SetBasicAuth(cfg.Username, cfg.Password)
+	}
+	for k, v := range cfg.Headers {
+		req.Header.Set(k, v)
+	}
+
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("推送失败: HTTP %d %s", resp.StatusCode, strings.TrimSpace(string(msg)))
+	}
+	_, _ = io.Copy(io.Discard, resp.Body)
+	return nil
+}
+
+// elasticShipper 通过 Bulk API 推送到 Elasticsearch
+type elasticShipper struct {
+	cfg model.ShipperConfig
+}
+
+func newElastic(cfg model.ShipperConfig) Shipper {
+	if cfg.Index == "" {
+		cfg.Index = "cdpnetool-events"
+	}
+	return &elasticShipper{cfg: cfg}
+}
+
+func (e *elasticShipper) Name() string { return TypeElasticsearch }
+
+func (e *elasticShipper) Ship(ctx context.Context, records []Record) error {
+	var buf bytes.Buffer
+	action, _ := json.Marshal(map[string]any{"index": map[string]string{"_index": e.cfg.Index}})
+	for _, r := range records {
+		doc, err := json.Marshal(r)
+		if err != nil {
+			continue
+		}
+		buf.Write(action)
+		buf.WriteByte('\n')
+		buf.Write(doc)
+		buf.WriteByte('\n')
+	}
+	endpoint := strings.TrimRight(e.cfg.Endpoint, "/") + "/_bulk"
+	return post(ctx, &e.cfg, endpoint, "application/x-ndjson", buf.Bytes())
+}
+
+func (e *elasticShipper) Close() error { return nil }
+
+// lokiShipper 通过 Push API 推送到 Loki
+type lokiShipper struct {
+	cfg model.ShipperConfig
+}
+
+func newLoki(cfg model.ShipperConfig) Shipper {
+	return &lokiShipper{cfg: cfg}
+}
+
+func (l *lokiShipper) Name() string { return TypeLoki }
+
+// Ship 按事件类型分流，附加配置中的静态标签
+func (l *lokiShipper) Ship(ctx context.Context, records []Record) error {
+	streams := make(map[string][][2]string)
+	for _, r := range records {
+		line, err := json.Marshal(r)
+		if err != nil {
+			continue
+		}
+		ts := strconv.FormatInt(r.Timestamp*int64(time.Millisecond), 10)
+		streams[r.Kind] = append(streams[r.Kind], [2]string{ts, string(line)})
+	}
+
+	type stream struct {
+		Stream map[string]string `json:"stream"`
+		Values [][2]string       `json:"values"`
+	}
+	payload := struct {
+		Streams []stream `json:"streams"`
+	}{}
+	for kind, values := range streams {
+		labels := map[string]string{"app": "cdpnetool", "kind": kind}
+		for k, v := range l.cfg.Labels {
+			labels[k] = v
+		}
+		payload.Streams = append(payload.Streams, stream{Stream: labels, Values: values})
+	}
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+	endpoint := strings.TrimRight(l.cfg.Endpoint, "/") + "/loki/api/v1/push"
+	return post(ctx, &l.cfg, endpoint, "application/json", body)
+}
+
+func (l *lokiShipper) Close() error { return nil }
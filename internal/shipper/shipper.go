@@ -0,0 +1,94 @@
+// Package shipper 将拦截事件元数据批量推送到外部日志系统（syslog / Elasticsearch / Loki）
+package shipper
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"cdpnetool/pkg/model"
+)
+
+// 推送目标类型
+const (
+	TypeSyslog        = "syslog"
+	TypeElasticsearch = "elasticsearch"
+	TypeLoki          = "loki"
+)
+
+// Record 推送的事件元数据（不含请求/响应 Body）
+type Record struct {
+	Timestamp   int64    `json:"timestamp"`
+	Session     string   `json:"session"`
+	Target      string   `json:"target"`
+	Kind        string   `json:"kind"` // matched / unmatched / notice
+	URL         string   `json:"url,omitempty"`
+	Method      string   `json:"method,omitempty"`
+	StatusCode  int      `json:"statusCode,omitempty"`
+	FinalResult string   `json:"finalResult,omitempty"`
+	Rules       []string `json:"rules,omitempty"`
+	Message     string   `json:"message,omitempty"`
+}
+
+// Shipper 推送目标
+type Shipper interface {
+	Name() string
+	Ship(ctx context.Context, records []Record) error
+	Close() error
+}
+
+// New 按配置创建推送目标
+func New(cfg model.ShipperConfig) (Shipper, error) {
+	if cfg.Endpoint == "" {
+		return nil, fmt.Errorf("%s 推送地址不能为空", cfg.Type)
+	}
+	switch strings.ToLower(cfg.Type) {
+	case TypeSyslog:
+		return newSyslog(cfg)
+	case TypeElasticsearch:
+		return newElastic(cfg), nil
+	case TypeLoki:
+		return newLoki(cfg), nil
+	default:
+		return nil, fmt.Errorf("不支持的推送类型: %s", cfg.Type)
+	}
+}
+
+// FromEvent 从拦截事件提取推送记录，includeUnmatched 为 false 时忽略未匹配事件
+func FromEvent(session model.SessionID, evt model.InterceptEvent, includeUnmatched bool) (Record, bool) {
+	switch {
+	case evt.Notice != nil:
+		n := evt.Notice
+		return Record{
+			Timestamp: n.Timestamp,
+			Session:   string(session),
+			Target:    string(n.Target),
+			Kind:      "notice",
+			URL:       n.URL,
+			Message:   string(n.Kind) + ": " + n.Message,
+		}, true
+	case evt.Matched != nil:
+		r := fromNetworkEvent(session, &evt.Matched.NetworkEvent, "matched")
+		for _, m := range evt.Matched.MatchedRules {
+			r.Rules = append(r.Rules, m.RuleName)
+		}
+		return r, true
+	case evt.Unmatched != nil && includeUnmatched:
+		return fromNetworkEvent(session, &evt.Unmatched.NetworkEvent, "unmatched"), true
+	}
+	return Record{}, false
+}
+
+// fromNetworkEvent 提取网络事件的公共字段
+func fromNetworkEvent(session model.SessionID, e *model.NetworkEvent, kind string) Record {
+	return Record{
+		Timestamp:   e.Timestamp,
+		Session:     string(session),
+		Target:      string(e.Target),
+		Kind:        kind,
+		URL:         e.Request.URL,
+		Method:      e.Request.Method,
+		StatusCode:  e.Response.StatusCode,
+		FinalResult: e.FinalResult,
+	}
+}
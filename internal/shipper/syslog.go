@@ -0,0 +1,90 @@
+package shipper
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/url"
+	"os"
+	"sync"
+	"time"
+
+	"cdpnetool/pkg/model"
+)
+
+// syslogShipper 以 RFC 5424 格式推送到 syslog（udp://host:514 或 tcp://host:514）
+type syslogShipper struct {
+	network  string
+	addr     string
+	hostname string
+	mu       sync.Mutex
+	conn     net.Conn
+}
+
+func newSyslog(cfg model.ShipperConfig) (Shipper, error) {
+	u, err := url.Parse(cfg.Endpoint)
+	if err != nil || u.Host == "" {
+		return nil, fmt.Errorf("无效的 syslog 地址: %s", cfg.Endpoint)
+	}
+	network := u.Scheme
+	if network != "udp" && network != "tcp" {
+		return nil, fmt.Errorf("syslog 仅支持 udp/tcp: %s", cfg.Endpoint)
+	}
+	host, _ := os.Hostname()
+	if host == "" {
+		host = "-"
+	}
+	return &syslogShipper{network: network, addr: u.Host, hostname: host}, nil
+}
+
+func (s *syslogShipper) Name() string { return TypeSyslog }
+
+// Ship 每条记录作为一条 syslog 消息，消息体为 JSON
+func (s *syslogShipper) Ship(ctx context.Context, records []Record) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.conn == nil {
+		d := net.Dialer{Timeout: 3 * time.Second}
+		conn, err := d.DialContext(ctx, s.network, s.addr)
+		if err != nil {
+			return err
+		}
+		s.conn = conn
+	}
+	if dl, ok := ctx.Deadline(); ok {
+		_ = s.conn.SetWriteDeadline(dl)
+	}
+
+	for _, r := range records {
+		body, err := json.Marshal(r)
+		if err != nil {
+			continue
+		}
+		// PRI 134 = facility local0 + severity info
+		msg := fmt.Sprintf("<134>1 %s %s cdpnetool - %s - %s",
+			time.UnixMilli(r.Timestamp).UTC().Format(time.RFC3339Nano), s.hostname, r.Kind, body)
+		if s.network == "tcp" {
+			// RFC 6587 八位组计数分帧
+			msg = fmt.Sprintf("%d %s", len(msg), msg)
+		}
+		if _, err := s.conn.Write([]byte(msg)); err != nil {
+			_ = s.conn.Close()
+			s.conn = nil
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *syslogShipper) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.conn == nil {
+		return nil
+	}
+	err := s.conn.Close()
+	s.conn = nil
+	return err
+}
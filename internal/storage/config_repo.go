@@ -142,6 +142,22 @@ func (r *ConfigRepo) GetActive() (*ConfigRecord, error) {
 	return &record, nil
 }
 
+// Resolve 按配置业务 ID 读取已保存的配置，用于展开配置引用
+func (r *ConfigRepo) Resolve(configID string) (*rulespec.Config, error) {
+	record, err := r.GetByConfigID(configID)
+	if err != nil {
+		return nil, err
+	}
+	cfg, err := r.ToRulespecConfig(record)
+	if err != nil {
+		return nil, err
+	}
+	if cfg == nil {
+		return nil, fmt.Errorf("配置不存在: %s", configID)
+	}
+	return cfg, nil
+}
+
 // ToRulespecConfig 将记录转换为 rulespec.Config
 func (r *ConfigRepo) ToRulespecConfig(record *ConfigRecord) (*rulespec.Config, error) {
 	if record == nil || record.ConfigJSON == "" {
@@ -155,8 +171,20 @@ func (r *ConfigRepo) ToRulespecConfig(record *ConfigRecord) (*rulespec.Config, e
 	return &cfg, nil
 }
 
-// Save 保存配置（根据数据库 ID 判断新增或更新）
-func (r *ConfigRepo) Save(dbID uint, cfg *rulespec.Config) (*ConfigRecord, error) {
+// Save 保存配置（根据数据库 ID 判断新增或更新），author 为本次修改人
+func (r *ConfigRepo) Save(dbID uint, cfg *rulespec.Config, author string) (*ConfigRecord, error) {
+	var prev *rulespec.Config
+	if dbID != 0 {
+		record, err := r.GetByID(dbID)
+		if err != nil {
+			return nil, err
+		}
+		if prev, err = r.ToRulespecConfig(record); err != nil {
+			return nil, err
+		}
+	}
+	rulespec.StampRules(prev, cfg, author, time.Now())
+
 	if dbID == 0 {
 		// 创建新记录
 		return r.Create(cfg)
@@ -168,8 +196,8 @@ func (r *ConfigRepo) Save(dbID uint, cfg *rulespec.Config) (*ConfigRecord, error
 	return r.GetByID(dbID)
 }
 
-// Upsert 导入配置（根据配置业务 ID 判断覆盖或新增）
-func (r *ConfigRepo) Upsert(cfg *rulespec.Config) (*ConfigRecord, error) {
+// Upsert 导入配置（根据配置业务 ID 判断覆盖或新增），导入规则自带的元数据予以保留
+func (r *ConfigRepo) Upsert(cfg *rulespec.Config, author string) (*ConfigRecord, error) {
 	// 校验配置 ID
 	if err := rulespec.ValidateConfigID(cfg.ID); err != nil {
 		return nil, err
@@ -185,6 +213,7 @@ func (r *ConfigRepo) Upsert(cfg *rulespec.Config) (*ConfigRecord, error) {
 	if err != nil {
 		return nil, err
 	}
+	rulespec.StampRules(nil, cfg, author, time.Now())
 
 	if existing != nil {
 		// 存在则更新
@@ -198,6 +227,73 @@ func (r *ConfigRepo) Upsert(cfg *rulespec.Config) (*ConfigRecord, error) {
 	return r.Create(cfg)
 }
 
+// RulesChangedSince 返回指定配置中在 since（毫秒时间戳）之后有变更的规则
+func (r *ConfigRepo) RulesChangedSince(id uint, since int64) ([]rulespec.Rule, error) {
+	record, err := r.GetByID(id)
+	if err != nil {
+		return nil, err
+	}
+	cfg, err := r.ToRulespecConfig(record)
+	if err != nil {
+		return nil, err
+	}
+	if cfg == nil {
+		return []rulespec.Rule{}, nil
+	}
+	return cfg.RulesChangedSince(since), nil
+}
+
+// Edit 读取配置并由 fn 修改后保存，规则元数据按 Save 的规则维护；fn 返回错误时不保存
+func (r *ConfigRepo) Edit(id uint, author string, fn func(cfg *rulespec.Config) error) (*ConfigRecord, error) {
+	record, err := r.GetByID(id)
+	if err != nil {
+		return nil, err
+	}
+	cfg, err := r.ToRulespecConfig(record)
+	if err != nil {
+		return nil, err
+	}
+	if cfg == nil {
+		return nil, fmt.Errorf("配置内容为空")
+	}
+	if err := fn(cfg); err != nil {
+		return nil, err
+	}
+	return r.Save(id, cfg, author)
+}
+
+// RuleSearchHit 规则搜索结果，附带规则所在的配置
+type RuleSearchHit struct {
+	ConfigDBID uint          `json:"configDbId"` // 配置数据库 ID
+	ConfigID   string        `json:"configId"`   // 配置业务 ID
+	ConfigName string        `json:"configName"` // 配置名称
+	Rule       rulespec.Rule `json:"rule"`
+}
+
+// SearchRules 在全部配置中搜索说明包含 text 的规则，按配置更新时间倒序排列
+func (r *ConfigRepo) SearchRules(text string) ([]RuleSearchHit, error) {
+	records, err := r.List()
+	if err != nil {
+		return nil, err
+	}
+	hits := []RuleSearchHit{}
+	for i := range records {
+		cfg, err := r.ToRulespecConfig(&records[i])
+		if err != nil || cfg == nil {
+			continue
+		}
+		for _, rule := range cfg.SearchRules(text) {
+			hits = append(hits, RuleSearchHit{
+				ConfigDBID: records[i].ID,
+				ConfigID:   records[i].ConfigID,
+				ConfigName: records[i].Name,
+				Rule:       rule,
+			})
+		}
+	}
+	return hits, nil
+}
+
 // Rename 重命名配置（同时更新 ConfigJSON 中的 name）
 func (r *ConfigRepo) Rename(id uint, newName string) error {
 	// 获取现有记录
@@ -228,7 +324,7 @@ func (r *ConfigRepo) Rename(id uint, newName string) error {
 	}).Error
 }
 
-// validateRuleIDs 校验规则 ID 格式和唯一性
+// validateRuleIDs 校验规则 ID 格式、唯一性、行为参数以及依赖的规则是否存在
 func (r *ConfigRepo) validateRuleIDs(rules []rulespec.Rule) error {
 	seen := make(map[string]bool)
 	for _, rule := range rules {
@@ -236,11 +332,24 @@ func (r *ConfigRepo) validateRuleIDs(rules []rulespec.Rule) error {
 		if err := rulespec.ValidateRuleID(rule.ID); err != nil {
 			return fmt.Errorf("规则 '%s': %w", rule.Name, err)
 		}
+		if err := rule.ValidateActions(); err != nil {
+			return fmt.Errorf("规则 '%s': %w", rule.ID, err)
+		}
 		// 校验唯一性
 		if seen[rule.ID] {
 			return fmt.Errorf("规则 ID '%s' 重复", rule.ID)
 		}
 		seen[rule.ID] = true
 	}
+	for _, rule := range rules {
+		for _, dep := range rule.RequiresRuleMatched {
+			if dep == rule.ID {
+				return fmt.Errorf("规则 '%s' 不能依赖自身", rule.ID)
+			}
+			if !seen[dep] {
+				return fmt.Errorf("规则 '%s' 依赖的规则 '%s' 不存在", rule.ID, dep)
+			}
+		}
+	}
 	return nil
 }
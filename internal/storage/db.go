@@ -5,6 +5,7 @@ import (
 	"os"
 	"path/filepath"
 	"runtime"
+	"sync"
 
 	"gorm.io/driver/sqlite"
 	"gorm.io/gorm"
@@ -14,7 +15,11 @@ import (
 
 // DB 数据库连接管理器
 type DB struct {
-	gormDB *gorm.DB
+	gormDB  *gorm.DB
+	path    string     // 数据库文件路径
+	maintMu sync.Mutex // 串行化维护操作
+	keyMu   sync.Mutex
+	key     []byte // 敏感设置加密密钥，首次使用时加载
 }
 
 // NewDB 创建新的数据库连接实例并执行迁移
@@ -41,7 +46,7 @@ func NewDB(cfg *config.Config, l logger.Interface) (*DB, error) {
 		return nil, err
 	}
 
-	db := &DB{gormDB: gormDB}
+	db := &DB{gormDB: gormDB, path: dbPath}
 
 	// 自动迁移
 	if err := db.autoMigrate(); err != nil {
@@ -107,5 +112,7 @@ func (d *DB) autoMigrate() error {
 		&Setting{},
 		&ConfigRecord{},
 		&MatchedEventRecord{},
+		&ScreenshotRecord{},
+		&SessionRecord{},
 	)
 }
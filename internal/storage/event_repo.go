@@ -2,6 +2,7 @@ package storage
 
 import (
 	"encoding/json"
+	"strings"
 	"sync"
 	"time"
 
@@ -80,24 +81,7 @@ func (r *EventRepo) Stop() {
 
 // RecordMatched 记录匹配事件（异步写入数据库）
 func (r *EventRepo) RecordMatched(evt *model.MatchedEvent) {
-	// 序列化规则列表
-	matchedRulesJSON, _ := json.Marshal(evt.MatchedRules)
-	requestJSON, _ := json.Marshal(evt.Request)
-	responseJSON, _ := json.Marshal(evt.Response)
-
-	record := MatchedEventRecord{
-		SessionID:        string(evt.Session),
-		TargetID:         string(evt.Target),
-		URL:              evt.Request.URL,
-		Method:           evt.Request.Method,
-		StatusCode:       evt.Response.StatusCode,
-		FinalResult:      evt.FinalResult,
-		MatchedRulesJSON: string(matchedRulesJSON),
-		RequestJSON:      string(requestJSON),
-		ResponseJSON:     string(responseJSON),
-		Timestamp:        evt.Timestamp,
-		CreatedAt:        time.Now(),
-	}
+	record := newMatchedEventRecord(evt)
 
 	r.bufferMu.Lock()
 	r.buffer = append(r.buffer, record)
@@ -112,16 +96,68 @@ func (r *EventRepo) RecordMatched(evt *model.MatchedEvent) {
 	}
 }
 
+// newMatchedEventRecord 将事件转换为数据库记录
+func newMatchedEventRecord(evt *model.MatchedEvent) MatchedEventRecord {
+	// 序列化规则列表
+	matchedRulesJSON, _ := json.Marshal(evt.MatchedRules)
+	requestJSON, _ := json.Marshal(evt.Request)
+	responseJSON, _ := json.Marshal(evt.Response)
+	var transformsJSON []byte
+	if len(evt.BodyTransforms) > 0 {
+		transformsJSON, _ = json.Marshal(evt.BodyTransforms)
+	}
+	var operation string
+	if evt.Operation != nil {
+		operation = evt.Operation.Key
+	}
+
+	return MatchedEventRecord{
+		SessionID:          string(evt.Session),
+		TargetID:           string(evt.Target),
+		NetworkID:          evt.NetworkID,
+		Stage:              evt.Stage,
+		APIOperation:       operation,
+		URL:                evt.Request.URL,
+		Method:             evt.Request.Method,
+		StatusCode:         evt.Response.StatusCode,
+		FinalResult:        evt.FinalResult,
+		MatchedRulesJSON:   string(matchedRulesJSON),
+		RequestJSON:        string(requestJSON),
+		ResponseJSON:       string(responseJSON),
+		BodyTransformsJSON: string(transformsJSON),
+		Timestamp:          evt.Timestamp,
+		CreatedAt:          time.Now(),
+	}
+}
+
+// ImportEvents 同步批量写入导入的事件（如离线抓包），不经过异步缓冲，便于导入后立即查询
+func (r *EventRepo) ImportEvents(evts []*model.MatchedEvent) error {
+	records := make([]MatchedEventRecord, 0, len(evts))
+	for _, evt := range evts {
+		records = append(records, newMatchedEventRecord(evt))
+	}
+	if len(records) == 0 {
+		return nil
+	}
+	return r.db.GormDB().CreateInBatches(records, 100).Error
+}
+
 // QueryOptions 查询选项
 type QueryOptions struct {
 	SessionID   string
+	NetworkID   string
+	Operation   string // API 封装的逻辑操作分组标识
 	FinalResult string // blocked / modified / passed
 	URL         string
+	URLPattern  string // URL 通配模式，* 匹配任意字符，与 model.MatchURLPattern 一致
 	Method      string
 	StartTime   int64
 	EndTime     int64
 	Offset      int
 	Limit       int
+
+	SessionLabel string            // 会话名称，包含匹配（不区分大小写）
+	SessionMeta  map[string]string // 会话元数据，所有键值都相等的会话才匹配
 }
 
 // Query 查询匹配事件历史
@@ -132,12 +168,22 @@ func (r *EventRepo) Query(opts QueryOptions) ([]MatchedEventRecord, int64, error
 	if opts.SessionID != "" {
 		query = query.Where("session_id = ?", opts.SessionID)
 	}
+	if opts.NetworkID != "" {
+		query = query.Where("network_id = ?", opts.NetworkID)
+	}
+	if opts.Operation != "" {
+		query = query.Where("api_operation = ?", opts.Operation)
+	}
 	if opts.FinalResult != "" {
 		query = query.Where("final_result = ?", opts.FinalResult)
 	}
 	if opts.URL != "" {
 		query = query.Where("url LIKE ?", "%"+opts.URL+"%")
 	}
+	if opts.URLPattern != "" {
+		like := strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`, "*", "%").Replace(opts.URLPattern)
+		query = query.Where(`url LIKE ? ESCAPE '\'`, "%"+like+"%")
+	}
 	if opts.Method != "" {
 		query = query.Where("method = ?", opts.Method)
 	}
@@ -147,6 +193,17 @@ func (r *EventRepo) Query(opts QueryOptions) ([]MatchedEventRecord, int64, error
 	if opts.EndTime > 0 {
 		query = query.Where("timestamp <= ?", opts.EndTime)
 	}
+	if opts.SessionLabel != "" || len(opts.SessionMeta) > 0 {
+		sessions := r.db.GormDB().Model(&SessionRecord{}).Select("session_id")
+		if opts.SessionLabel != "" {
+			like := strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(opts.SessionLabel)
+			sessions = sessions.Where(`label LIKE ? ESCAPE '\'`, "%"+like+"%")
+		}
+		for k, v := range opts.SessionMeta {
+			sessions = sessions.Where("json_extract(NULLIF(metadata_json, ''), ?) = ?", sessionMetaPath(k), v)
+		}
+		query = query.Where("session_id IN (?)", sessions)
+	}
 
 	// 计算总数
 	var total int64
@@ -167,8 +224,65 @@ func (r *EventRepo) Query(opts QueryOptions) ([]MatchedEventRecord, int64, error
 		Offset(opts.Offset).
 		Limit(opts.Limit).
 		Find(&records).Error
+	if err != nil {
+		return nil, 0, err
+	}
 
-	return records, total, err
+	return records, total, fillSessionLabels(r.db.GormDB(), records)
+}
+
+// QueryAll 按条件分批查询全部匹配事件（忽略分页参数），最多返回 max 条
+func (r *EventRepo) QueryAll(opts QueryOptions, max int) ([]MatchedEventRecord, error) {
+	var out []MatchedEventRecord
+	opts.Offset = 0
+	opts.Limit = 1000
+	for len(out) < max {
+		batch, total, err := r.Query(opts)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, batch...)
+		if len(batch) < opts.Limit || int64(len(out)) >= total {
+			break
+		}
+		opts.Offset += len(batch)
+	}
+	if len(out) > max {
+		out = out[:max]
+	}
+	return out, nil
+}
+
+// QueryTransaction 查询同一网络请求在各阶段的事件，按时间先后排列
+func (r *EventRepo) QueryTransaction(sessionID, networkID string) ([]MatchedEventRecord, error) {
+	// 先写入缓冲区，刚发生的请求也能查询到
+	r.flush()
+	var records []MatchedEventRecord
+	err := r.db.GormDB().
+		Where("session_id = ? AND network_id = ?", sessionID, networkID).
+		Order("timestamp ASC, id ASC").
+		Find(&records).Error
+	return records, err
+}
+
+// OperationGroup 按 API 逻辑操作分组的事件统计
+type OperationGroup struct {
+	Operation string `json:"operation"`
+	Count     int64  `json:"count"`
+	LastSeen  int64  `json:"lastSeen"`
+}
+
+// OperationGroups 统计会话中各 API 逻辑操作的事件数，按事件数降序排列
+func (r *EventRepo) OperationGroups(sessionID string) ([]OperationGroup, error) {
+	r.flush()
+	var groups []OperationGroup
+	err := r.db.GormDB().Model(&MatchedEventRecord{}).
+		Select("api_operation AS operation, COUNT(*) AS count, MAX(timestamp) AS last_seen").
+		Where("session_id = ? AND api_operation <> ''", sessionID).
+		Group("api_operation").
+		Order("count DESC, operation ASC").
+		Scan(&groups).Error
+	return groups, err
 }
 
 // GetByID 根据ID获取事件
@@ -184,12 +298,68 @@ func (r *EventRepo) GetByID(id uint) (*MatchedEventRecord, error) {
 // DeleteOldEvents 删除旧事件（数据清理）
 func (r *EventRepo) DeleteOldEvents(beforeTimestamp int64) (int64, error) {
 	result := r.db.GormDB().Where("timestamp < ?", beforeTimestamp).Delete(&MatchedEventRecord{})
-	return result.RowsAffected, result.Error
+	if result.Error != nil {
+		return 0, result.Error
+	}
+	if err := r.db.GormDB().Where("timestamp < ?", beforeTimestamp).Delete(&ScreenshotRecord{}).Error; err != nil {
+		return result.RowsAffected, err
+	}
+	// 会话记录在其事件全部清理后一并删除
+	remaining := r.db.GormDB().Model(&MatchedEventRecord{}).Distinct("session_id")
+	if err := r.db.GormDB().
+		Where("created_at < ? AND session_id NOT IN (?)", time.UnixMilli(beforeTimestamp), remaining).
+		Delete(&SessionRecord{}).Error; err != nil {
+		return result.RowsAffected, err
+	}
+	return result.RowsAffected, nil
 }
 
-// DeleteBySession 删除指定会话的事件
+// DeleteBySession 删除指定会话的事件、截图和会话记录
 func (r *EventRepo) DeleteBySession(sessionID string) error {
-	return r.db.GormDB().Where("session_id = ?", sessionID).Delete(&MatchedEventRecord{}).Error
+	if err := r.db.GormDB().Where("session_id = ?", sessionID).Delete(&MatchedEventRecord{}).Error; err != nil {
+		return err
+	}
+	if err := r.db.GormDB().Where("session_id = ?", sessionID).Delete(&ScreenshotRecord{}).Error; err != nil {
+		return err
+	}
+	return r.db.GormDB().Where("session_id = ?", sessionID).Delete(&SessionRecord{}).Error
+}
+
+// SaveScreenshot 保存页面截图
+func (r *EventRepo) SaveScreenshot(sessionID string, shot model.Screenshot) (*ScreenshotRecord, error) {
+	record := ScreenshotRecord{
+		SessionID: sessionID,
+		TargetID:  string(shot.Target),
+		MimeType:  shot.MimeType,
+		Data:      shot.Data,
+		Size:      len(shot.Data),
+		Timestamp: shot.Timestamp,
+		CreatedAt: time.Now(),
+	}
+	if err := r.db.GormDB().Create(&record).Error; err != nil {
+		return nil, err
+	}
+	return &record, nil
+}
+
+// ListScreenshots 列出会话的截图（不含图片数据），按时间先后排列
+func (r *EventRepo) ListScreenshots(sessionID string) ([]ScreenshotRecord, error) {
+	var records []ScreenshotRecord
+	err := r.db.GormDB().
+		Omit("data").
+		Where("session_id = ?", sessionID).
+		Order("timestamp ASC, id ASC").
+		Find(&records).Error
+	return records, err
+}
+
+// GetScreenshot 根据 ID 获取截图（含图片数据）
+func (r *EventRepo) GetScreenshot(id uint) (*ScreenshotRecord, error) {
+	var record ScreenshotRecord
+	if err := r.db.GormDB().First(&record, id).Error; err != nil {
+		return nil, err
+	}
+	return &record, nil
 }
 
 // CleanupOldEvents 根据保留天数清理旧事件
@@ -201,7 +371,13 @@ func (r *EventRepo) CleanupOldEvents(retentionDays int) (int64, error) {
 	return r.DeleteOldEvents(cutoff)
 }
 
-// ClearAll 清空所有事件
+// ClearAll 清空所有事件、截图和会话记录
 func (r *EventRepo) ClearAll() error {
-	return r.db.GormDB().Where("1 = 1").Delete(&MatchedEventRecord{}).Error
+	if err := r.db.GormDB().Where("1 = 1").Delete(&MatchedEventRecord{}).Error; err != nil {
+		return err
+	}
+	if err := r.db.GormDB().Where("1 = 1").Delete(&ScreenshotRecord{}).Error; err != nil {
+		return err
+	}
+	return r.db.GormDB().Where("1 = 1").Delete(&SessionRecord{}).Error
 }
@@ -0,0 +1,107 @@
+package storage
+
+import (
+	"errors"
+	"os"
+	"time"
+)
+
+// 数据库维护操作类型
+const (
+	MaintenanceVacuum    = "vacuum"
+	MaintenanceAnalyze   = "analyze"
+	MaintenanceIntegrity = "integrity"
+)
+
+// ErrMaintenanceRunning 已有维护操作在执行
+var ErrMaintenanceRunning = errors.New("数据库维护正在进行中")
+
+// MaintenanceProgress 维护进度回调，percent 取值 0-100
+type MaintenanceProgress func(step string, percent int)
+
+// MaintenanceReport 数据库维护结果
+type MaintenanceReport struct {
+	Operation  string   `json:"operation"`          // 操作类型
+	SizeBefore int64    `json:"sizeBefore"`         // 操作前数据库文件大小（含 WAL）
+	SizeAfter  int64    `json:"sizeAfter"`          // 操作后数据库文件大小（含 WAL）
+	DurationMS int64    `json:"durationMs"`         // 耗时
+	Problems   []string `json:"problems,omitempty"` // 完整性检查发现的问题
+}
+
+// Vacuum 合并 WAL 并重建数据库文件，回收碎片空间
+func (d *DB) Vacuum(progress MaintenanceProgress) (*MaintenanceReport, error) {
+	return d.runMaintenance(MaintenanceVacuum, progress, func(r *MaintenanceReport) error {
+		report(progress, "checkpoint", 10)
+		if err := d.gormDB.Exec("PRAGMA wal_checkpoint(TRUNCATE)").Error; err != nil {
+			return err
+		}
+		report(progress, "vacuum", 40)
+		return d.gormDB.Exec("VACUUM").Error
+	})
+}
+
+// Analyze 更新查询优化器统计信息
+func (d *DB) Analyze(progress MaintenanceProgress) (*MaintenanceReport, error) {
+	return d.runMaintenance(MaintenanceAnalyze, progress, func(r *MaintenanceReport) error {
+		report(progress, "analyze", 30)
+		if err := d.gormDB.Exec("ANALYZE").Error; err != nil {
+			return err
+		}
+		report(progress, "optimize", 80)
+		return d.gormDB.Exec("PRAGMA optimize").Error
+	})
+}
+
+// IntegrityCheck 执行完整性检查，问题记录在结果的 Problems 中
+func (d *DB) IntegrityCheck(progress MaintenanceProgress) (*MaintenanceReport, error) {
+	return d.runMaintenance(MaintenanceIntegrity, progress, func(r *MaintenanceReport) error {
+		report(progress, "integrity_check", 20)
+		var rows []string
+		if err := d.gormDB.Raw("PRAGMA integrity_check").Scan(&rows).Error; err != nil {
+			return err
+		}
+		for _, row := range rows {
+			if row != "ok" {
+				r.Problems = append(r.Problems, row)
+			}
+		}
+		return nil
+	})
+}
+
+// runMaintenance 串行执行维护操作并统计文件大小与耗时
+func (d *DB) runMaintenance(op string, progress MaintenanceProgress, fn func(r *MaintenanceReport) error) (*MaintenanceReport, error) {
+	if !d.maintMu.TryLock() {
+		return nil, ErrMaintenanceRunning
+	}
+	defer d.maintMu.Unlock()
+
+	start := time.Now()
+	r := &MaintenanceReport{Operation: op, SizeBefore: d.fileSize()}
+	report(progress, "start", 0)
+	if err := fn(r); err != nil {
+		return nil, err
+	}
+	r.SizeAfter = d.fileSize()
+	r.DurationMS = time.Since(start).Milliseconds()
+	report(progress, "done", 100)
+	return r, nil
+}
+
+// fileSize 返回数据库文件及 WAL 文件的总大小
+func (d *DB) fileSize() int64 {
+	var total int64
+	for _, p := range []string{d.path, d.path + "-wal"} {
+		if fi, err := os.Stat(p); err == nil {
+			total += fi.Size()
+		}
+	}
+	return total
+}
+
+// report 调用进度回调
+func report(progress MaintenanceProgress, step string, percent int) {
+	if progress != nil {
+		progress(step, percent)
+	}
+}
@@ -17,6 +17,47 @@ const (
 	SettingKeyTheme        = "theme"          // 主题
 	SettingKeyWindowBounds = "window_bounds"  // 窗口大小和位置
 	SettingKeyLastConfigID = "last_config_id" // 上次使用的配置 ID
+
+	SettingKeySchemaDriftDetection   = "schema_drift_detection"   // 是否启用 JSON 响应结构漂移检测
+	SettingKeySchemaFingerprints     = "schema_fingerprints"      // 已记录的 JSON 响应结构指纹
+	SettingKeyContentChangeDetection = "content_change_detection" // 是否启用响应内容变化检测
+	SettingKeyContentHashes          = "content_hashes"           // 已记录的响应体哈希
+	SettingKeyDuplicateDetection     = "duplicate_detection"      // 是否检测重复发送的相同请求
+	SettingKeyDuplicateWindowMS      = "duplicate_window_ms"      // 重复请求判定窗口（毫秒）
+	SettingKeyAllowedHosts           = "allowed_hosts"            // 允许规则修改的主机（JSON 数组）
+	SettingKeyDeniedHosts            = "denied_hosts"             // 禁止规则修改的主机（JSON 数组）
+	SettingKeyHeaderPolicy           = "header_policy"            // 会话级响应头策略（JSON 对象）
+	SettingKeyCredentials            = "credentials"              // 按源注入的认证信息（加密的 JSON 数组）
+	SettingKeySecrets                = "secrets"                  // 行为模板引用的命名密钥（加密的 JSON 对象）
+	SettingKeyProxyAuth              = "proxy_auth"               // 上游代理认证信息（加密的 JSON 对象）
+	SettingKeyUserAgentOverride      = "user_agent_override"      // User-Agent 与客户端提示覆盖（JSON 对象）
+	SettingKeyGeolocation            = "geolocation"              // 地理位置覆盖（JSON 对象，为空表示不覆盖）
+	SettingKeyLocaleOverride         = "locale_override"          // 时区与区域覆盖（JSON 对象）
+	SettingKeyExtraHeaders           = "extra_headers"            // 轻量注入的请求头（JSON 对象）
+	SettingKeyCaptureOnly            = "capture_only"             // 是否以只读捕获模式启动会话
+	SettingKeyFullCapture            = "full_capture"             // 是否记录未匹配请求的完整响应
+	SettingKeyObserveOnly            = "observe_only"             // 是否以观察模式启动会话（不暂停请求，仅记录流量）
+	SettingKeyCaptureTiming          = "capture_timing"           // 是否记录请求各阶段耗时
+	SettingKeyShareRuleCache         = "share_rule_cache"         // 是否与其他会话共享规则正则缓存
+	SettingKeyInterceptStage         = "intercept_stage"          // 会话默认拦截阶段（both/request/response）
+	SettingKeyInterceptResourceTypes = "intercept_resource_types" // 会话默认拦截的资源类型（JSON 数组）
+	SettingKeyInterceptWorkers       = "intercept_workers"        // 是否拦截 service worker / shared worker 请求
+	SettingKeyBlockResourceTypes     = "block_resource_types"     // 一键屏蔽的资源类别（JSON 数组）
+	SettingKeyPinnedEndpoints        = "pinned_endpoints"         // 重点关注的接口 URL 模式（JSON 数组）
+	SettingKeyCacheBypass            = "cache_bypass"             // 是否在会话中禁用浏览器缓存
+	SettingKeyProxyAddr              = "proxy_addr"               // 内置转发代理的监听地址（为空时使用 127.0.0.1:8899）
+	SettingKeyHostConcurrency        = "host_concurrency"         // 单个主机的并发处理上限（0 表示不限制）
+	SettingKeyLogShippers            = "log_shippers"             // 事件推送目标（JSON 数组）
+	SettingKeyEventFlushMS           = "event_flush_ms"           // 拦截事件批量推送到前端的间隔毫秒数（默认 100）
+	SettingKeyEventBatchSize         = "event_batch_size"         // 拦截事件单批推送的数量，达到后立即推送（默认 50）
+	SettingKeyEventBodyLimit         = "event_body_limit"         // 推送到前端的单个 Body 上限字节数，超过时截断（默认 262144，0 表示不截断）
+	SettingKeyWatchdogRecover        = "watchdog_recover"         // 拦截处理停滞时是否自动重启拦截流
+	SettingKeyRequireSignedConfig    = "require_signed_config"    // 会话是否仅接受签名配置
+	SettingKeyConfigVerifyKey        = "config_verify_key"        // 校验配置签名的公钥
+	SettingKeyConfigSigningKey       = "config_signing_key"       // 导出时签名配置的私钥（仅负责人本机）
+	SettingKeyAuthorName             = "author_name"              // 规则变更记录中的作者名
+	SettingKeyAutoVacuumDays         = "auto_vacuum_days"         // 自动整理数据库的间隔天数（0 表示关闭）
+	SettingKeyLastVacuumAt           = "last_vacuum_at"           // 上次整理数据库的时间（毫秒时间戳）
 )
 
 // ConfigRecord 配置表（存储规则配置）
@@ -33,16 +74,46 @@ type ConfigRecord struct {
 
 // MatchedEventRecord 匹配事件记录表（只存储匹配的请求）
 type MatchedEventRecord struct {
-	ID               uint      `gorm:"primaryKey" json:"id"`
-	SessionID        string    `gorm:"index" json:"sessionId"`
-	TargetID         string    `json:"targetId"`
-	URL              string    `json:"url"`
-	Method           string    `json:"method"`
-	StatusCode       int       `json:"statusCode"`                        // 状态码
-	FinalResult      string    `gorm:"index" json:"finalResult"`          // blocked / modified / passed
-	MatchedRulesJSON string    `gorm:"type:text" json:"matchedRulesJson"` // 匹配规则 JSON 数组
-	RequestJSON      string    `gorm:"type:text" json:"requestJson"`      // 请求信息 JSON
-	ResponseJSON     string    `gorm:"type:text" json:"responseJson"`     // 响应信息 JSON
-	Timestamp        int64     `gorm:"index" json:"timestamp"`
-	CreatedAt        time.Time `json:"createdAt"`
+	ID                 uint      `gorm:"primaryKey" json:"id"`
+	SessionID          string    `gorm:"index" json:"sessionId"`
+	TargetID           string    `json:"targetId"`
+	NetworkID          string    `gorm:"index" json:"networkId"`    // 浏览器网络请求 ID，用于关联同一请求的两个阶段
+	Stage              string    `json:"stage"`                     // request / response
+	APIOperation       string    `gorm:"index" json:"apiOperation"` // API 封装的逻辑操作分组标识（format:resource/operation）
+	URL                string    `json:"url"`
+	Method             string    `json:"method"`
+	StatusCode         int       `json:"statusCode"`                          // 状态码
+	FinalResult        string    `gorm:"index" json:"finalResult"`            // blocked / modified / passed
+	MatchedRulesJSON   string    `gorm:"type:text" json:"matchedRulesJson"`   // 匹配规则 JSON 数组
+	RequestJSON        string    `gorm:"type:text" json:"requestJson"`        // 请求信息 JSON
+	ResponseJSON       string    `gorm:"type:text" json:"responseJson"`       // 响应信息 JSON
+	BodyTransformsJSON string    `gorm:"type:text" json:"bodyTransformsJson"` // Body 变换步骤 JSON 数组
+	Timestamp          int64     `gorm:"index" json:"timestamp"`
+	CreatedAt          time.Time `json:"createdAt"`
+
+	// 所属会话的名称与元数据，取自会话记录表，查询时填充
+	SessionLabel    string `gorm:"-" json:"sessionLabel,omitempty"`
+	SessionMetaJSON string `gorm:"-" json:"sessionMetaJson,omitempty"`
+}
+
+// SessionRecord 会话记录表，保存会话名称与元数据，用于在事件历史中区分不同的测试轮次
+type SessionRecord struct {
+	ID           uint      `gorm:"primaryKey" json:"id"`
+	SessionID    string    `gorm:"uniqueIndex;not null" json:"sessionId"`
+	Label        string    `gorm:"index" json:"label"`            // 会话名称，如 "release 3.2 regression run"
+	MetadataJSON string    `gorm:"type:text" json:"metadataJson"` // 元数据 JSON 对象，键和值均为字符串
+	CreatedAt    time.Time `json:"createdAt"`                     // 会话开始（或首次记录）的时间
+	UpdatedAt    time.Time `json:"updatedAt"`                     // 最近一次修改名称或元数据的时间
+}
+
+// ScreenshotRecord 页面截图记录表，与匹配事件一同作为测试证据保存
+type ScreenshotRecord struct {
+	ID        uint      `gorm:"primaryKey" json:"id"`
+	SessionID string    `gorm:"index" json:"sessionId"`
+	TargetID  string    `json:"targetId"`
+	MimeType  string    `json:"mimeType"`
+	Data      []byte    `json:"data,omitempty"` // 图片数据，列表查询时不返回
+	Size      int       `json:"size"`           // 图片字节数
+	Timestamp int64     `gorm:"index" json:"timestamp"`
+	CreatedAt time.Time `json:"createdAt"`
 }
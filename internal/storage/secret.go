@@ -0,0 +1,123 @@
+package storage
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// secretKeyFile 加密敏感设置的密钥文件，与数据库分开存放，仅复制数据库文件无法解密
+const secretKeyFile = "secret.key"
+
+// secretPrefix 加密设置值的前缀
+const secretPrefix = "enc:v1:"
+
+// secretKey 读取设置加密密钥，首次使用时生成
+func (d *DB) secretKey() ([]byte, error) {
+	d.keyMu.Lock()
+	defer d.keyMu.Unlock()
+	if d.key != nil {
+		return d.key, nil
+	}
+
+	path := filepath.Join(filepath.Dir(d.path), secretKeyFile)
+	data, err := os.ReadFile(path)
+	switch {
+	case err == nil:
+		key, derr := base64.StdEncoding.DecodeString(strings.TrimSpace(string(data)))
+		if derr != nil || len(key) != 32 {
+			return nil, fmt.Errorf("密钥文件无效: %s", path)
+		}
+		d.key = key
+	case errors.Is(err, os.ErrNotExist):
+		key := make([]byte, 32)
+		if _, err := rand.Read(key); err != nil {
+			return nil, err
+		}
+		if err := os.WriteFile(path, []byte(base64.StdEncoding.EncodeToString(key)), 0o600); err != nil {
+			return nil, err
+		}
+		d.key = key
+	default:
+		return nil, err
+	}
+	return d.key, nil
+}
+
+// sealSecret 使用 AES-GCM 加密明文
+func sealSecret(key []byte, plaintext string) (string, error) {
+	gcm, err := newGCM(key)
+	if err != nil {
+		return "", err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+	sealed := gcm.Seal(nonce, nonce, []byte(plaintext), nil)
+	return secretPrefix + base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+// openSecret 解密 sealSecret 生成的密文
+func openSecret(key []byte, value string) (string, error) {
+	if !strings.HasPrefix(value, secretPrefix) {
+		return "", errors.New("设置值未加密")
+	}
+	sealed, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(value, secretPrefix))
+	if err != nil {
+		return "", err
+	}
+	gcm, err := newGCM(key)
+	if err != nil {
+		return "", err
+	}
+	if len(sealed) < gcm.NonceSize() {
+		return "", errors.New("密文长度无效")
+	}
+	plain, err := gcm.Open(nil, sealed[:gcm.NonceSize()], sealed[gcm.NonceSize():], nil)
+	if err != nil {
+		return "", fmt.Errorf("解密失败: %w", err)
+	}
+	return string(plain), nil
+}
+
+// newGCM 创建 AES-GCM 实例
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// SetSecret 加密后保存敏感设置值
+func (r *SettingsRepo) SetSecret(key, value string) error {
+	k, err := r.db.secretKey()
+	if err != nil {
+		return err
+	}
+	sealed, err := sealSecret(k, value)
+	if err != nil {
+		return err
+	}
+	return r.Set(key, sealed)
+}
+
+// GetSecret 读取并解密敏感设置值，不存在时返回空字符串
+func (r *SettingsRepo) GetSecret(key string) (string, error) {
+	value := r.GetWithDefault(key, "")
+	if value == "" {
+		return "", nil
+	}
+	k, err := r.db.secretKey()
+	if err != nil {
+		return "", err
+	}
+	return openSecret(k, value)
+}
@@ -0,0 +1,132 @@
+package storage
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"gorm.io/gorm"
+)
+
+const (
+	maxSessionLabelLen = 200 // 会话名称的最大长度（字符）
+	maxSessionMetaKeys = 50  // 单个会话元数据的最大键数
+)
+
+// SessionRepo 会话记录仓库
+type SessionRepo struct {
+	db *DB
+}
+
+// NewSessionRepo 创建会话记录仓库实例
+func NewSessionRepo(db *DB) *SessionRepo {
+	return &SessionRepo{db: db}
+}
+
+// Ensure 记录会话，已存在时不做修改
+func (r *SessionRepo) Ensure(sessionID string) error {
+	record := SessionRecord{SessionID: sessionID}
+	return r.db.GormDB().Where("session_id = ?", sessionID).FirstOrCreate(&record).Error
+}
+
+// SetLabel 设置会话名称与元数据，会话记录不存在时创建；metadata 为空时清空元数据
+func (r *SessionRepo) SetLabel(sessionID, label string, metadata map[string]string) (*SessionRecord, error) {
+	label = strings.TrimSpace(label)
+	if err := ValidateSessionLabel(label, metadata); err != nil {
+		return nil, err
+	}
+	var metaJSON string
+	if len(metadata) > 0 {
+		raw, err := json.Marshal(metadata)
+		if err != nil {
+			return nil, fmt.Errorf("序列化会话元数据失败: %w", err)
+		}
+		metaJSON = string(raw)
+	}
+
+	var record SessionRecord
+	err := r.db.GormDB().Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("session_id = ?", sessionID).FirstOrInit(&record).Error; err != nil {
+			return err
+		}
+		record.SessionID = sessionID
+		record.Label = label
+		record.MetadataJSON = metaJSON
+		return tx.Save(&record).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &record, nil
+}
+
+// Get 获取会话记录
+func (r *SessionRepo) Get(sessionID string) (*SessionRecord, error) {
+	var record SessionRecord
+	if err := r.db.GormDB().Where("session_id = ?", sessionID).First(&record).Error; err != nil {
+		return nil, err
+	}
+	return &record, nil
+}
+
+// List 列出所有会话记录，最近开始的排在前面
+func (r *SessionRepo) List() ([]SessionRecord, error) {
+	var records []SessionRecord
+	err := r.db.GormDB().Order("created_at DESC, id DESC").Find(&records).Error
+	return records, err
+}
+
+// ValidateSessionLabel 校验会话名称与元数据：名称不超过 200 个字符，
+// 元数据最多 50 个键，键不能为空且不能包含双引号
+func ValidateSessionLabel(label string, metadata map[string]string) error {
+	if n := len([]rune(label)); n > maxSessionLabelLen {
+		return fmt.Errorf("会话名称过长: %d 个字符，最多 %d 个", n, maxSessionLabelLen)
+	}
+	if len(metadata) > maxSessionMetaKeys {
+		return fmt.Errorf("会话元数据过多: %d 个键，最多 %d 个", len(metadata), maxSessionMetaKeys)
+	}
+	for k := range metadata {
+		if strings.TrimSpace(k) == "" {
+			return fmt.Errorf("会话元数据的键不能为空")
+		}
+		if strings.Contains(k, `"`) {
+			return fmt.Errorf("会话元数据的键不能包含双引号: %s", k)
+		}
+	}
+	return nil
+}
+
+// sessionMetaPath 返回元数据键在 json_extract 中的路径
+func sessionMetaPath(key string) string {
+	return `$."` + key + `"`
+}
+
+// fillSessionLabels 按会话 ID 为事件记录填充会话名称与元数据
+func fillSessionLabels(db *gorm.DB, records []MatchedEventRecord) error {
+	if len(records) == 0 {
+		return nil
+	}
+	seen := make(map[string]bool)
+	var ids []string
+	for _, rec := range records {
+		if !seen[rec.SessionID] {
+			seen[rec.SessionID] = true
+			ids = append(ids, rec.SessionID)
+		}
+	}
+	var sessions []SessionRecord
+	if err := db.Where("session_id IN ?", ids).Find(&sessions).Error; err != nil {
+		return err
+	}
+	byID := make(map[string]*SessionRecord, len(sessions))
+	for i := range sessions {
+		byID[sessions[i].SessionID] = &sessions[i]
+	}
+	for i := range records {
+		if s, ok := byID[records[i].SessionID]; ok {
+			records[i].SessionLabel = s.Label
+			records[i].SessionMetaJSON = s.MetadataJSON
+		}
+	}
+	return nil
+}
@@ -1,6 +1,7 @@
 package storage
 
 import (
+	"encoding/json"
 	"time"
 
 	"gorm.io/gorm"
@@ -92,6 +93,28 @@ func (r *SettingsRepo) SetDevToolsURL(url string) error {
 	return r.Set(SettingKeyDevToolsURL, url)
 }
 
+// GetStringList 获取 JSON 数组格式的设置值，不存在或解析失败时返回 nil
+func (r *SettingsRepo) GetStringList(key string) []string {
+	raw := r.GetWithDefault(key, "")
+	if raw == "" {
+		return nil
+	}
+	var list []string
+	if err := json.Unmarshal([]byte(raw), &list); err != nil {
+		return nil
+	}
+	return list
+}
+
+// SetStringList 以 JSON 数组格式保存设置值
+func (r *SettingsRepo) SetStringList(key string, list []string) error {
+	raw, err := json.Marshal(list)
+	if err != nil {
+		return err
+	}
+	return r.Set(key, string(raw))
+}
+
 // GetTheme 获取主题
 func (r *SettingsRepo) GetTheme() string {
 	return r.GetWithDefault(SettingKeyTheme, "system")
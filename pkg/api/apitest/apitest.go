@@ -0,0 +1,44 @@
+// Package apitest 提供无需真实浏览器即可测试 api.Service 的假浏览器。
+//
+// 示例：
+//
+//	b, _ := apitest.NewBrowser(nil)
+//	defer b.Close()
+//	page := b.AddPage("https://app.example.com/")
+//
+//	svc := api.NewService(nil)
+//	id, _ := svc.StartSession(model.SessionConfig{DevToolsURL: b.URL()})
+//	_ = svc.AttachTarget(id, model.TargetID(page.ID()))
+//	_ = svc.LoadRules(id, cfg)
+//	_ = svc.EnableInterception(id)
+//
+//	res, _ := page.Fetch(ctx, apitest.Request{URL: "https://app.example.com/api/user"})
+package apitest
+
+import "cdpnetool/internal/cdpfake"
+
+// Browser 假浏览器，提供 DevTools HTTP 接口与目标调试连接
+type Browser = cdpfake.Browser
+
+// Target 假浏览器中的调试目标，通过 Fetch 模拟页面请求
+type Target = cdpfake.Target
+
+// Origin 模拟源站：根据最终发出的请求返回响应
+type Origin = cdpfake.Origin
+
+// Request 页面发出的请求
+type Request = cdpfake.Request
+
+// Response 源站或拦截方返回的响应
+type Response = cdpfake.Response
+
+// Result 页面最终看到的请求结果
+type Result = cdpfake.Result
+
+// Call 客户端发来的一次协议调用
+type Call = cdpfake.Call
+
+// NewBrowser 启动一个监听本机随机端口的假浏览器，origin 为 nil 时所有请求返回 200 空响应
+func NewBrowser(origin Origin) (*Browser, error) {
+	return cdpfake.New(origin)
+}
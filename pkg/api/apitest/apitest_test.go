@@ -0,0 +1,412 @@
+package apitest_test
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"cdpnetool/pkg/api"
+	"cdpnetool/pkg/api/apitest"
+	"cdpnetool/pkg/model"
+	"cdpnetool/pkg/rulespec"
+
+	"github.com/mafredri/cdp/protocol/fetch"
+	"golang.org/x/net/proxy"
+)
+
+// origin 模拟源站：/video 按 Range 返回 206 片段，/plain 返回不带 Content-Type 的文本，
+// /proxied 在未提供代理凭据时返回 407 质询，其余路径返回 JSON
+func origin(req apitest.Request) apitest.Response {
+	if req.URL == "https://app.example.com/proxied" && (req.Username != "proxy-user" || req.Password != "proxy-pass") {
+		source := "Proxy"
+		return apitest.Response{StatusCode: 407, AuthChallenge: &fetch.AuthChallenge{Source: &source, Origin: "http://proxy.example.com:3128", Scheme: "basic", Realm: "proxy"}}
+	}
+	if req.URL == "https://app.example.com/plain" {
+		return apitest.Response{StatusCode: 200, Body: []byte("hello world")}
+	}
+	if req.URL == "https://app.example.com/video" {
+		if r := req.Headers["Range"]; r == "bytes=100-199" {
+			body := make([]byte, 100)
+			return apitest.Response{
+				StatusCode: 206,
+				Headers:    map[string]string{"Content-Range": "bytes 100-199/1000", "Content-Length": "100"},
+				Body:       body,
+			}
+		}
+	}
+	return apitest.Response{
+		StatusCode: 200,
+		Headers:    map[string]string{"Content-Type": "application/json"},
+		Body:       []byte(`{"ok":true}`),
+	}
+}
+
+// startSession 启动假浏览器和会话，加载规则并启用拦截
+func startSession(t *testing.T, rules []rulespec.Rule) *apitest.Target {
+	t.Helper()
+	b, err := apitest.NewBrowser(origin)
+	if err != nil {
+		t.Fatalf("NewBrowser: %v", err)
+	}
+	t.Cleanup(func() { _ = b.Close() })
+	page := b.AddPage("https://app.example.com/")
+
+	svc := api.NewService(nil)
+	id, err := svc.StartSession(model.SessionConfig{DevToolsURL: b.URL()})
+	if err != nil {
+		t.Fatalf("StartSession: %v", err)
+	}
+	t.Cleanup(func() { _ = svc.StopSession(id) })
+	if err := svc.AttachTarget(id, model.TargetID(page.ID())); err != nil {
+		t.Fatalf("AttachTarget: %v", err)
+	}
+	cfg := rulespec.NewConfig("apitest")
+	cfg.Rules = rules
+	if err := svc.LoadRules(id, cfg); err != nil {
+		t.Fatalf("LoadRules: %v", err)
+	}
+	if err := svc.EnableInterception(id); err != nil {
+		t.Fatalf("EnableInterception: %v", err)
+	}
+	return page
+}
+
+func TestInterception(t *testing.T) {
+	page := startSession(t, []rulespec.Rule{
+		{
+			ID: "block", Name: "block", Enabled: true, Stage: rulespec.StageRequest,
+			Match: rulespec.Match{AllOf: []rulespec.Condition{{Type: rulespec.ConditionURLSuffix, Value: "/blocked"}}},
+			Actions: []rulespec.Action{{
+				Type: rulespec.ActionBlock, StatusCode: 403, Body: "denied",
+				Headers: map[string]string{"Content-Type": "text/plain"},
+			}},
+		},
+		{
+			ID: "mock", Name: "mock", Enabled: true, Stage: rulespec.StageRequest,
+			Match:   rulespec.Match{AllOf: []rulespec.Condition{{Type: rulespec.ConditionURLSuffix, Value: "/mock"}}},
+			Actions: []rulespec.Action{{Type: rulespec.ActionBlock, StatusCode: 200, Body: "0123456789"}},
+		},
+		{
+			ID: "header", Name: "header", Enabled: true, Stage: rulespec.StageResponse,
+			Match: rulespec.Match{AnyOf: []rulespec.Condition{
+				{Type: rulespec.ConditionURLSuffix, Value: "/api"},
+				{Type: rulespec.ConditionURLSuffix, Value: "/video"},
+			}},
+			Actions: []rulespec.Action{{Type: rulespec.ActionSetHeader, Name: "X-Test", Value: "1"}},
+		},
+		{
+			ID: "replace", Name: "replace", Enabled: true, Stage: rulespec.StageResponse,
+			Match:   rulespec.Match{AllOf: []rulespec.Condition{{Type: rulespec.ConditionURLSuffix, Value: "/plain"}}},
+			Actions: []rulespec.Action{{Type: rulespec.ActionReplaceBodyText, Search: "hello", Replace: "bye"}},
+		},
+	})
+
+	tests := []struct {
+		name       string
+		req        apitest.Request
+		wantStatus int
+		wantBody   string
+		wantHeader map[string]string
+		wantSent   bool
+	}{
+		{
+			name:       "block",
+			req:        apitest.Request{URL: "https://app.example.com/blocked"},
+			wantStatus: 403,
+			wantBody:   "denied",
+		},
+		{
+			name:       "setHeader",
+			req:        apitest.Request{URL: "https://app.example.com/api"},
+			wantStatus: 200,
+			wantBody:   `{"ok":true}`,
+			wantHeader: map[string]string{"X-Test": "1"},
+			wantSent:   true,
+		},
+		{
+			name:       "range mock is sliced",
+			req:        apitest.Request{URL: "https://app.example.com/mock", Headers: map[string]string{"Range": "bytes=2-4"}},
+			wantStatus: 206,
+			wantBody:   "234",
+			wantHeader: map[string]string{"Content-Range": "bytes 2-4/10"},
+		},
+		{
+			name:       "range mock with stale If-Range returns full body",
+			req:        apitest.Request{URL: "https://app.example.com/mock", Headers: map[string]string{"Range": "bytes=2-4", "If-Range": `"v1"`}},
+			wantStatus: 200,
+			wantBody:   "0123456789",
+		},
+		{
+			name:       "range mock with malformed Range returns full body",
+			req:        apitest.Request{URL: "https://app.example.com/mock", Headers: map[string]string{"Range": "bytes=abc"}},
+			wantStatus: 200,
+			wantBody:   "0123456789",
+		},
+		{
+			name:       "range mock with reversed Range returns full body",
+			req:        apitest.Request{URL: "https://app.example.com/mock", Headers: map[string]string{"Range": "bytes=5-2"}},
+			wantStatus: 200,
+			wantBody:   "0123456789",
+		},
+		{
+			name:       "range mock with multiple ranges returns full body",
+			req:        apitest.Request{URL: "https://app.example.com/mock", Headers: map[string]string{"Range": "bytes=0-1,4-5"}},
+			wantStatus: 200,
+			wantBody:   "0123456789",
+		},
+		{
+			name:       "range mock beyond body is unsatisfiable",
+			req:        apitest.Request{URL: "https://app.example.com/mock", Headers: map[string]string{"Range": "bytes=20-30"}},
+			wantStatus: 416,
+			wantHeader: map[string]string{"Content-Range": "bytes */10"},
+		},
+		{
+			name:       "range mock with zero suffix is unsatisfiable",
+			req:        apitest.Request{URL: "https://app.example.com/mock", Headers: map[string]string{"Range": "bytes=-0"}},
+			wantStatus: 416,
+			wantHeader: map[string]string{"Content-Range": "bytes */10"},
+		},
+		{
+			name:       "upstream 206 passes through",
+			req:        apitest.Request{URL: "https://app.example.com/video", Headers: map[string]string{"Range": "bytes=100-199"}},
+			wantStatus: 206,
+			wantBody:   string(make([]byte, 100)),
+			wantHeader: map[string]string{"Content-Range": "bytes 100-199/1000", "X-Test": "1"},
+			wantSent:   true,
+		},
+		{
+			name:       "replaceBodyText without Content-Type reads the body",
+			req:        apitest.Request{URL: "https://app.example.com/plain"},
+			wantStatus: 200,
+			wantBody:   "bye world",
+			wantSent:   true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+			res, err := page.Fetch(ctx, tt.req)
+			if err != nil {
+				t.Fatalf("Fetch: %v", err)
+			}
+			if res.Failed {
+				t.Fatalf("request failed: %s", res.ErrorReason)
+			}
+			if res.Response.StatusCode != tt.wantStatus {
+				t.Errorf("status = %d, want %d", res.Response.StatusCode, tt.wantStatus)
+			}
+			if string(res.Response.Body) != tt.wantBody {
+				t.Errorf("body = %q, want %q", res.Response.Body, tt.wantBody)
+			}
+			for k, v := range tt.wantHeader {
+				if got := res.Response.Headers[k]; got != v {
+					t.Errorf("header %s = %q, want %q", k, got, v)
+				}
+			}
+			if (res.Sent != nil) != tt.wantSent {
+				t.Errorf("sent to origin = %v, want %v", res.Sent != nil, tt.wantSent)
+			}
+		})
+	}
+}
+
+func TestLoadRulesRejectsInvalidErrorReason(t *testing.T) {
+	b, err := apitest.NewBrowser(origin)
+	if err != nil {
+		t.Fatalf("NewBrowser: %v", err)
+	}
+	t.Cleanup(func() { _ = b.Close() })
+
+	svc := api.NewService(nil)
+	id, err := svc.StartSession(model.SessionConfig{DevToolsURL: b.URL()})
+	if err != nil {
+		t.Fatalf("StartSession: %v", err)
+	}
+	t.Cleanup(func() { _ = svc.StopSession(id) })
+
+	cfg := rulespec.NewConfig("apitest")
+	cfg.Rules = []rulespec.Rule{{
+		ID: "terminate", Name: "terminate", Enabled: true, Stage: rulespec.StageRequest,
+		Actions: []rulespec.Action{{Type: rulespec.ActionTerminate, ErrorReason: "Reset"}},
+	}}
+	if err := svc.LoadRules(id, cfg); err == nil {
+		t.Fatal("LoadRules accepted an unknown errorReason")
+	}
+	cfg.Rules[0].Actions[0].ErrorReason = "ConnectionReset"
+	if err := svc.LoadRules(id, cfg); err != nil {
+		t.Fatalf("LoadRules: %v", err)
+	}
+}
+
+func TestProxyAuthWithoutRules(t *testing.T) {
+	b, err := apitest.NewBrowser(origin)
+	if err != nil {
+		t.Fatalf("NewBrowser: %v", err)
+	}
+	t.Cleanup(func() { _ = b.Close() })
+	page := b.AddPage("https://app.example.com/")
+
+	svc := api.NewService(nil)
+	id, err := svc.StartSession(model.SessionConfig{DevToolsURL: b.URL()})
+	if err != nil {
+		t.Fatalf("StartSession: %v", err)
+	}
+	t.Cleanup(func() { _ = svc.StopSession(id) })
+	if err := svc.AttachTarget(id, model.TargetID(page.ID())); err != nil {
+		t.Fatalf("AttachTarget: %v", err)
+	}
+	if err := svc.EnableInterception(id); err != nil {
+		t.Fatalf("EnableInterception: %v", err)
+	}
+	// 拦截启用后再设置代理认证，同样需要更新拦截模式
+	if err := svc.SetProxyAuth(id, &model.ProxyCredential{Username: "proxy-user", Password: "proxy-pass"}); err != nil {
+		t.Fatalf("SetProxyAuth: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	res, err := page.Fetch(ctx, apitest.Request{URL: "https://app.example.com/proxied"})
+	if err != nil {
+		t.Fatalf("Fetch: %v", err)
+	}
+	if res.Challenges != 1 {
+		t.Errorf("challenges = %d, want 1", res.Challenges)
+	}
+	if res.Response.StatusCode != 200 {
+		t.Errorf("status = %d, want 200", res.Response.StatusCode)
+	}
+}
+
+func TestExtraHeadersRespectHostPolicy(t *testing.T) {
+	b, err := apitest.NewBrowser(origin)
+	if err != nil {
+		t.Fatalf("NewBrowser: %v", err)
+	}
+	t.Cleanup(func() { _ = b.Close() })
+	page := b.AddPage("https://app.example.com/")
+
+	svc := api.NewService(nil)
+	id, err := svc.StartSession(model.SessionConfig{
+		DevToolsURL:  b.URL(),
+		DeniedHosts:  []string{"ads.example.com"},
+		ExtraHeaders: map[string]string{"X-Env": "staging"},
+	})
+	if err != nil {
+		t.Fatalf("StartSession: %v", err)
+	}
+	t.Cleanup(func() { _ = svc.StopSession(id) })
+	if err := svc.AttachTarget(id, model.TargetID(page.ID())); err != nil {
+		t.Fatalf("AttachTarget: %v", err)
+	}
+	if err := svc.EnableInterception(id); err != nil {
+		t.Fatalf("EnableInterception: %v", err)
+	}
+
+	tests := []struct {
+		url  string
+		want string
+	}{
+		{url: "https://app.example.com/api", want: "staging"},
+		{url: "https://ads.example.com/pixel", want: ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.url, func(t *testing.T) {
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+			res, err := page.Fetch(ctx, apitest.Request{URL: tt.url})
+			if err != nil {
+				t.Fatalf("Fetch: %v", err)
+			}
+			if res.Sent == nil {
+				t.Fatal("request was not sent to origin")
+			}
+			if got := res.Sent.Headers["X-Env"]; got != tt.want {
+				t.Errorf("X-Env = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestProxyRules(t *testing.T) {
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.WriteString(w, "upstream")
+	}))
+	t.Cleanup(upstream.Close)
+
+	svc := api.NewService(nil)
+	b, err := apitest.NewBrowser(origin)
+	if err != nil {
+		t.Fatalf("NewBrowser: %v", err)
+	}
+	t.Cleanup(func() { _ = b.Close() })
+	id, err := svc.StartSession(model.SessionConfig{DevToolsURL: b.URL()})
+	if err != nil {
+		t.Fatalf("StartSession: %v", err)
+	}
+	t.Cleanup(func() { _ = svc.StopSession(id) })
+	cfg := rulespec.NewConfig("apitest")
+	cfg.Rules = []rulespec.Rule{{
+		ID: "header", Name: "header", Enabled: true, Stage: rulespec.StageResponse,
+		Match:   rulespec.Match{AllOf: []rulespec.Condition{{Type: rulespec.ConditionURLSuffix, Value: "/hello"}}},
+		Actions: []rulespec.Action{{Type: rulespec.ActionSetHeader, Name: "X-Test", Value: "1"}},
+	}}
+	if err := svc.LoadRules(id, cfg); err != nil {
+		t.Fatalf("LoadRules: %v", err)
+	}
+	if _, err := svc.StartProxy(id, "socks4://127.0.0.1:0"); err == nil {
+		t.Fatal("StartProxy accepted a SOCKS4 address")
+	}
+	addr, err := svc.StartProxy(id, "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("StartProxy: %v", err)
+	}
+
+	socks, err := proxy.SOCKS5("tcp", addr, nil, proxy.Direct)
+	if err != nil {
+		t.Fatalf("SOCKS5: %v", err)
+	}
+	tests := []struct {
+		name      string
+		transport *http.Transport
+	}{
+		{name: "http", transport: &http.Transport{Proxy: http.ProxyURL(&url.URL{Scheme: "http", Host: addr})}},
+		{name: "socks5", transport: &http.Transport{DialContext: socks.(proxy.ContextDialer).DialContext}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			defer tt.transport.CloseIdleConnections()
+			resp, err := (&http.Client{Transport: tt.transport, Timeout: 5 * time.Second}).Get(upstream.URL + "/hello")
+			if err != nil {
+				t.Fatalf("Get: %v", err)
+			}
+			defer resp.Body.Close()
+			body, _ := io.ReadAll(resp.Body)
+			if string(body) != "upstream" {
+				t.Errorf("body = %q, want %q", body, "upstream")
+			}
+			if got := resp.Header.Get("X-Test"); got != "1" {
+				t.Errorf("X-Test = %q, want %q", got, "1")
+			}
+		})
+	}
+
+	// HTTPS 经 SOCKS5 隧道原样转发
+	tlsUpstream := httptest.NewTLSServer(upstream.Config.Handler)
+	t.Cleanup(tlsUpstream.Close)
+	tr := tlsUpstream.Client().Transport.(*http.Transport).Clone()
+	tr.DialContext = socks.(proxy.ContextDialer).DialContext
+	defer tr.CloseIdleConnections()
+	resp, err := (&http.Client{Transport: tr, Timeout: 5 * time.Second}).Get(tlsUpstream.URL + "/hello")
+	if err != nil {
+		t.Fatalf("Get over SOCKS5 tunnel: %v", err)
+	}
+	defer resp.Body.Close()
+	if got := resp.Header.Get("X-Test"); got != "" {
+		t.Errorf("tunneled response was modified: X-Test = %q", got)
+	}
+}
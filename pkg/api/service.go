@@ -15,9 +15,24 @@ type Service interface {
 	// StopSession 停止会话
 	StopSession(id model.SessionID) error
 
+	// GetSessionInfo 获取会话信息，包括已附加的目标和浏览器版本与特性支持情况
+	GetSessionInfo(id model.SessionID) (model.SessionInfo, error)
+
 	// AttachTarget 附加目标
 	AttachTarget(id model.SessionID, target model.TargetID) error
 
+	// OpenTarget 新建标签页，附加并启用拦截后再导航到 url，保证首个文档请求被拦截
+	OpenTarget(id model.SessionID, url string) (model.TargetID, error)
+
+	// Navigate 让已附加的页面导航到 url，target 为空时使用任一已附加的页面
+	Navigate(id model.SessionID, target model.TargetID, url string) error
+
+	// Reload 重新加载已附加的页面，ignoreCache 为 true 时跳过缓存
+	Reload(id model.SessionID, target model.TargetID, ignoreCache bool) error
+
+	// CaptureScreenshot 截取已附加页面当前视口的 PNG 图片，target 为空时使用任一已附加的页面
+	CaptureScreenshot(id model.SessionID, target model.TargetID) (model.Screenshot, error)
+
 	// DetachTarget 分离目标
 	DetachTarget(id model.SessionID, target model.TargetID) error
 
@@ -30,14 +45,124 @@ type Service interface {
 	// DisableInterception 禁用拦截
 	DisableInterception(id model.SessionID) error
 
+	// EnableCacheBypass 禁用浏览器缓存，使改写后的响应不被缓存掩盖
+	EnableCacheBypass(id model.SessionID) error
+
+	// DisableCacheBypass 恢复浏览器缓存
+	DisableCacheBypass(id model.SessionID) error
+
+	// StartProxy 启动内置转发代理（同一端口接受 HTTP 和 SOCKS5），经代理的明文请求与浏览器请求使用同一套规则并产生同样的事件；
+	// addr 为空时监听 127.0.0.1:8899，可带 http:// 或 socks5:// 前缀，其他协议返回错误；返回实际监听地址
+	StartProxy(id model.SessionID, addr string) (string, error)
+
+	// StopProxy 停止内置转发代理
+	StopProxy(id model.SessionID) error
+
+	// GetCookies 获取适用于指定 URL 的 Cookie，urls 为空时返回目标当前页面的 Cookie；target 为空时使用任一已附加的页面目标
+	GetCookies(id model.SessionID, target model.TargetID, urls []string) ([]model.Cookie, error)
+
+	// SetCookie 设置 Cookie，需指定 URL 或 Domain
+	SetCookie(id model.SessionID, target model.TargetID, c model.Cookie) error
+
+	// DeleteCookies 删除匹配条件的 Cookie，返回删除的数量
+	DeleteCookies(id model.SessionID, target model.TargetID, f model.CookieFilter) (int, error)
+
+	// ReplayRequest 在目标页面上下文中重新发出请求并返回响应，请求携带页面的 Cookie；target 为空时使用任一已附加的页面目标
+	ReplayRequest(id model.SessionID, target model.TargetID, req model.RequestInfo) (model.ResponseInfo, error)
+
+	// AddQuickMock 为目标中的某个 URL 添加临时 Mock，仅对该目标生效，以给定的状态码（默认 200）和响应体直接应答；
+	// 到期（TTLMS，默认 10 分钟）后自动移除并发出 quick_mock_expired 通知，返回补全了 ID 与到期时间的 Mock
+	AddQuickMock(id model.SessionID, mock model.QuickMock) (model.QuickMock, error)
+
+	// RemoveQuickMock 提前移除临时 Mock
+	RemoveQuickMock(id model.SessionID, mockID string) error
+
+	// ListQuickMocks 列出生效中的临时 Mock
+	ListQuickMocks(id model.SessionID) ([]model.QuickMock, error)
+
+	// Variables 返回 captureVar 行为保存的会话变量，行为模板通过 {{var.name}} 引用
+	Variables(id model.SessionID) (map[string]string, error)
+
+	// ClearVariables 清空会话变量
+	ClearVariables(id model.SessionID) error
+
+	// Use 在拦截流水线的挂载点（beforeEval、afterEval、beforeApply）注册中间件，应用到已有的和之后创建的所有会话；
+	// 同一挂载点上先注册的中间件位于外层。中间件只作用于浏览器拦截的请求，内置代理的请求不经过中间件
+	Use(point model.HookPoint, mw model.Middleware) error
+
+	// SubscribePending 订阅命中 pause 行为、等待人工放行的请求（断点）
+	SubscribePending(id model.SessionID) (<-chan model.PendingItem, error)
+
+	// ApprovePending 放行断点暂停的请求，edits 不为 nil 时在规则修改的基础上再应用人工修改
+	ApprovePending(id model.SessionID, itemID string, edits *model.PendingEdits) error
+
+	// RejectPending 拒绝断点暂停的请求，请求以 Aborted 失败
+	RejectPending(id model.SessionID, itemID string) error
+
 	// LoadRules 加载规则配置
 	LoadRules(id model.SessionID, cfg *rulespec.Config) error
 
-	// GetRuleStats 获取规则统计信息
+	// SetTagEnabled 启用或停用当前配置中带有指定标签（不区分大小写）的全部规则，返回状态发生变化的规则数
+	SetTagEnabled(id model.SessionID, tag string, enabled bool) (int, error)
+
+	// GetRuleStats 获取规则统计信息，ByTag 为按标签汇总的命中次数
 	GetRuleStats(id model.SessionID) (model.EngineStats, error)
 
+	// DuplicateReport 返回会话中重复发送的请求汇总，需在会话配置中开启 DuplicateDetection
+	DuplicateReport(id model.SessionID) ([]model.DuplicateRequestStat, error)
+
+	// ClearDuplicateReport 清空会话的重复请求记录
+	ClearDuplicateReport(id model.SessionID) error
+
 	// SubscribeEvents 订阅事件
 	SubscribeEvents(id model.SessionID) (<-chan model.InterceptEvent, error)
+
+	// SetHostPolicy 设置允许/禁止规则修改的主机列表
+	SetHostPolicy(id model.SessionID, allow, deny []string) error
+	// SetBlockResourceTypes 设置一键屏蔽的资源类别（images/fonts/media/analytics）
+	SetBlockResourceTypes(id model.SessionID, classes []string) error
+	// SetPinnedEndpoints 设置重点关注的接口 URL 模式，其事件在推送积压时等待而不是丢弃
+	SetPinnedEndpoints(id model.SessionID, pins model.EndpointPins) error
+
+	// SetHeaderPolicy 设置对所有 HTML/JSON 响应统一注入或移除的响应头
+	SetHeaderPolicy(id model.SessionID, policy model.HeaderPolicy) error
+
+	// SetCredentials 设置按源自动注入的 Authorization 认证信息
+	SetCredentials(id model.SessionID, creds []model.OriginCredential) error
+
+	// SetProxyAuth 设置浏览器所用上游代理的认证信息，拦截启用期间所有请求遇到 407 质询时自动应答；cred 为 nil 时交由浏览器处理
+	SetProxyAuth(id model.SessionID, cred *model.ProxyCredential) error
+
+	// SetUserAgentOverride 设置 User-Agent 与 Sec-CH-UA-* 客户端提示覆盖，UserAgent 为空时恢复浏览器默认值
+	SetUserAgentOverride(id model.SessionID, o model.UserAgentOverride) error
+
+	// SetGeolocation 设置页面地理位置覆盖，g 为 nil 时恢复实际位置
+	SetGeolocation(id model.SessionID, g *model.Geolocation) error
+
+	// SetLocaleOverride 设置页面时区与区域覆盖，字段为空时恢复浏览器默认值
+	SetLocaleOverride(id model.SessionID, o model.LocaleOverride) error
+
+	// SetExtraHeaders 设置由浏览器直接附加到所有请求的请求头，请求不经过拦截暂停，空集合表示移除；
+	// 浏览器级注入无法按主机区分，会话设置了主机白名单/黑名单时改为在拦截的请求阶段只为允许修改的主机注入，仅在拦截启用期间生效
+	SetExtraHeaders(id model.SessionID, headers map[string]string) error
+
+	// SetSecrets 设置行为模板通过 {{secret.name}} 引用的命名密钥，事件中出现的密钥值会被脱敏
+	SetSecrets(id model.SessionID, secrets map[string]string) error
+
+	// ExportSchemaFingerprints 导出 JSON 响应结构指纹
+	ExportSchemaFingerprints() map[string]map[string]string
+
+	// ImportSchemaFingerprints 导入 JSON 响应结构指纹
+	ImportSchemaFingerprints(shapes map[string]map[string]string)
+
+	// ExportContentHashes 导出按端点记录的响应体哈希
+	ExportContentHashes() map[string]string
+
+	// ImportContentHashes 导入按端点记录的响应体哈希
+	ImportContentHashes(hashes map[string]string)
+
+	// SetConfigResolver 设置展开配置 includes 引用时查找已保存配置的函数
+	SetConfigResolver(r rulespec.ConfigResolver)
 }
 
 // NewService 创建并返回服务接口实现
@@ -1,5 +1,11 @@
 package model
 
+import (
+	"context"
+	"encoding/base64"
+	"strings"
+)
+
 // SessionID 会话ID
 type SessionID string
 
@@ -13,16 +19,240 @@ type RuleID string
 type SessionConfig struct {
 	DevToolsURL       string `json:"devToolsURL"`
 	Concurrency       int    `json:"concurrency"`
+	HostConcurrency   int    `json:"hostConcurrency"` // 单个主机同时占用的工作协程上限，0 表示不限制
 	BodySizeThreshold int64  `json:"bodySizeThreshold"`
 	PendingCapacity   int    `json:"pendingCapacity"`
 	ProcessTimeoutMS  int    `json:"processTimeoutMS"`
+	StreamBodyLimit   int64  `json:"streamBodyLimit"` // 超过 BodySizeThreshold 的响应体以流方式读取改写的上限
+	MaxFulfillBody    int64  `json:"maxFulfillBody"`  // 规则生成的 Body（mock 应答、改写后的请求体或响应体）的上限，超过时放弃修改
+
+	SchemaDriftDetection   bool               `json:"schemaDriftDetection"`   // 是否启用 JSON 响应结构漂移检测
+	ContentChangeDetection bool               `json:"contentChangeDetection"` // 是否按端点记录响应体哈希并检测内容变化
+	DuplicateDetection     bool               `json:"duplicateDetection"`     // 是否检测窗口内重复发送的相同请求（方法 + URL + 请求体）
+	DuplicateWindowMS      int                `json:"duplicateWindowMS"`      // 重复请求判定窗口（毫秒），不大于 0 时为 2000
+	RangePolicy            RangePolicy        `json:"rangePolicy"`            // Range 请求的 Body 改写策略
+	AllowedHosts           []string           `json:"allowedHosts"`           // 允许规则修改的主机（空表示不限制），支持 *.example.com
+	DeniedHosts            []string           `json:"deniedHosts"`            // 禁止规则修改的主机，优先于 AllowedHosts
+	HeaderPolicy           HeaderPolicy       `json:"headerPolicy"`           // 对所有 HTML/JSON 响应统一注入或移除的响应头
+	Credentials            []OriginCredential `json:"credentials"`            // 按源自动注入的 Authorization 认证信息
+	ProxyAuth              *ProxyCredential   `json:"proxyAuth"`              // 浏览器所用上游代理的认证信息，用于自动应答 407 质询；为空时交由浏览器处理
+	UserAgent              UserAgentOverride  `json:"userAgent"`              // User-Agent 与客户端提示覆盖
+	Geolocation            *Geolocation       `json:"geolocation"`            // 地理位置覆盖，为空时使用浏览器的实际位置
+	Locale                 LocaleOverride     `json:"locale"`                 // 时区与区域覆盖
+	ExtraHeaders           map[string]string  `json:"extraHeaders"`           // 由浏览器直接附加到所有请求的请求头，不暂停请求
+	Secrets                map[string]string  `json:"secrets"`                // 行为模板通过 {{secret.name}} 引用的命名密钥
+	CacheBypass            bool               `json:"cacheBypass"`            // 禁用浏览器缓存，避免改写后的响应被缓存掩盖
+	CaptureOnly            bool               `json:"captureOnly"`            // 只读捕获模式：仅记录匹配结果，不执行任何修改行为
+	FullCapture            bool               `json:"fullCapture"`            // 全量捕获：通过 Network 域记录所有请求（含未匹配请求）的完整响应
+	ObserveOnly            bool               `json:"observeOnly"`            // 观察模式：不启用 Fetch 拦截、不暂停请求，仅通过 Network 域记录全部流量，规则不生效
+	ShareRuleCache         bool               `json:"shareRuleCache"`         // 与其他同样开启该选项的会话共享规则正则/公钥缓存
+	InterceptStage         InterceptStage     `json:"interceptStage"`         // 拦截阶段，配置中的 interceptStage 设置优先
+	InterceptResourceTypes []string           `json:"interceptResourceTypes"` // 只拦截这些资源类型（如 XHR、Fetch、Document），为空时不限制；配置中的 interceptResourceTypes 设置优先
+	BlockResourceTypes     []string           `json:"blockResourceTypes"`     // 一键屏蔽的资源类别（images/fonts/media/analytics），命中的请求直接失败
+	PinnedEndpoints        EndpointPins       `json:"pinnedEndpoints"`        // 重点关注的接口，其事件在推送积压时也不会被丢弃
+	InterceptWorkers       bool               `json:"interceptWorkers"`       // 自动附加与页面同源的 service worker / shared worker
+	Shippers               []ShipperConfig    `json:"shippers"`               // 事件元数据推送目标
+	WatchdogRecover        bool               `json:"watchdogRecover"`        // 拦截处理停滞且连接正常时自动重启拦截流
+	CaptureTiming          bool               `json:"captureTiming"`          // 记录请求耗时（DNS/连接/TTFB/总耗时），事件在请求加载完成后发送
+	WaitForReady           bool               `json:"waitForReady"`           // 启用拦截时要求所有已附加目标开始消费拦截事件，任一目标失败时返回错误（其他目标保持启用）
+	RequireSignedConfig    bool               `json:"requireSignedConfig"`    // 仅接受签名有效的规则配置
+	ConfigVerifyKey        string             `json:"configVerifyKey"`        // 校验配置签名的 Ed25519 公钥（base64）
+}
+
+// ShipperConfig 事件推送目标配置
+type ShipperConfig struct {
+	Type             string            `json:"type"`                       // syslog / elasticsearch / loki
+	Endpoint         string            `json:"endpoint"`                   // 推送地址（syslog 为 udp://host:514 或 tcp://host:514）
+	Index            string            `json:"index,omitempty"`            // Elasticsearch 索引名
+	Labels           map[string]string `json:"labels,omitempty"`           // Loki 附加标签
+	Headers          map[string]string `json:"headers,omitempty"`          // 附加 HTTP 头（如 Loki 租户 X-Scope-OrgID）
+	Username         string            `json:"username,omitempty"`         // Basic 认证用户名
+	Password         string            `json:"password,omitempty"`         // Basic 认证密码
+	APIKey           string            `json:"apiKey,omitempty"`           // Elasticsearch API Key
+	IncludeUnmatched bool              `json:"includeUnmatched,omitempty"` // 是否推送未匹配的请求
+}
+
+// InterceptStage 会话拦截的生命周期阶段
+type InterceptStage string
+
+const (
+	InterceptStageBoth     InterceptStage = "both"     // 请求和响应阶段都拦截（默认）
+	InterceptStageRequest  InterceptStage = "request"  // 仅拦截请求阶段
+	InterceptStageResponse InterceptStage = "response" // 仅拦截响应阶段
+)
+
+// 一键屏蔽的资源类别
+const (
+	BlockImages    = "images"    // 图片
+	BlockFonts     = "fonts"     // 字体
+	BlockMedia     = "media"     // 音视频和字幕
+	BlockAnalytics = "analytics" // 常见统计分析与广告追踪服务的请求
+)
+
+// QuickMock 限定在单个目标、到期自动移除的临时 Mock，用于让当前页面中失败的接口先返回可用的响应
+type QuickMock struct {
+	ID         string            `json:"id"`                // 创建时生成
+	Target     TargetID          `json:"target"`            // 生效的目标
+	URL        string            `json:"url"`               // 精确匹配的请求 URL（# 之后的片段被忽略）
+	Method     string            `json:"method,omitempty"`  // 只匹配该方法，为空时不限制
+	StatusCode int               `json:"statusCode"`        // 响应状态码，默认 200
+	Headers    map[string]string `json:"headers,omitempty"` // 响应头，未指定 Content-Type 且 Body 为 JSON 时使用 application/json
+	Body       string            `json:"body"`              // 响应体
+	TTLMS      int               `json:"ttlMs,omitempty"`   // 有效期（毫秒），默认 10 分钟
+	ExpiresAt  int64             `json:"expiresAt"`         // 到期时间（毫秒时间戳），创建时计算
+}
+
+// EndpointPins 重点关注的接口 URL 模式：* 匹配任意字符，模式可出现在 URL 的任意位置（不含 * 时即为包含匹配）
+type EndpointPins []string
+
+// Match 返回 URL 命中的第一个模式
+func (p EndpointPins) Match(url string) (string, bool) {
+	for _, pattern := range p {
+		if MatchURLPattern(pattern, url) {
+			return pattern, true
+		}
+	}
+	return "", false
+}
+
+// MatchURLPattern 判断 URL 是否包含通配模式，* 匹配任意字符，空模式不匹配
+func MatchURLPattern(pattern, url string) bool {
+	parts := strings.Split(pattern, "*")
+	matched := false
+	for _, part := range parts {
+		if part == "" {
+			continue
+		}
+		i := strings.Index(url, part)
+		if i < 0 {
+			return false
+		}
+		url = url[i+len(part):]
+		matched = true
+	}
+	return matched
+}
+
+// RangePolicy Range 请求的 Body 改写策略。只有规则整体替换的 Body（block、setBody）按 Range 切片，
+// 基于上游响应的改写和上游返回的 206 原样下发；If-Range 与响应校验值不一致时返回完整 Body
+type RangePolicy string
+
+const (
+	RangePolicySlice  RangePolicy = "slice"  // 按 Range 切片替换 Body 并返回 206（默认）
+	RangePolicyBypass RangePolicy = "bypass" // Range 请求跳过 Body 改写，原样放行
+)
+
+// HeaderPolicy 会话级响应头策略：无需编写规则即可对所有 HTML/JSON 响应注入或移除响应头，
+// 规则对同名响应头的修改优先
+type HeaderPolicy struct {
+	Set    map[string]string `json:"set,omitempty"`    // 注入的响应头，已存在时覆盖
+	Remove []string          `json:"remove,omitempty"` // 移除的响应头
+}
+
+// IsEmpty 判断策略是否未配置任何响应头
+func (p HeaderPolicy) IsEmpty() bool {
+	return len(p.Set) == 0 && len(p.Remove) == 0
+}
+
+// CredentialScheme Authorization 认证方式
+type CredentialScheme string
+
+const (
+	CredentialBasic  CredentialScheme = "basic"  // Basic 认证，使用用户名和密码
+	CredentialBearer CredentialScheme = "bearer" // Bearer 令牌
+)
+
+// OriginCredential 按源自动注入的认证信息，使规则中无需包含明文凭据
+type OriginCredential struct {
+	Origin   string           `json:"origin"`             // 源（scheme://host[:port]）
+	Scheme   CredentialScheme `json:"scheme"`             // 认证方式
+	Username string           `json:"username,omitempty"` // 用户名（basic）
+	Password string           `json:"password,omitempty"` // 密码（basic）
+	Token    string           `json:"token,omitempty"`    // 令牌（bearer）
+}
+
+// ProxyCredential 浏览器所用上游代理（如企业网络代理）的认证信息
+type ProxyCredential struct {
+	Username string `json:"username"` // 用户名
+	Password string `json:"password"` // 密码
+}
+
+// Authorization 返回 Authorization 请求头的值，信息不完整时返回 false
+func (c OriginCredential) Authorization() (string, bool) {
+	switch c.Scheme {
+	case CredentialBasic:
+		if c.Username == "" {
+			return "", false
+		}
+		return "Basic " + base64.StdEncoding.EncodeToString([]byte(c.Username+":"+c.Password)), true
+	case CredentialBearer:
+		if c.Token == "" {
+			return "", false
+		}
+		return "Bearer " + c.Token, true
+	}
+	return "", false
+}
+
+// UserAgentOverride 会话级 User-Agent 覆盖，由浏览器在所有请求和 navigator 中统一生效，
+// 包括 Sec-CH-UA-* 客户端提示；UserAgent 为空表示不覆盖
+type UserAgentOverride struct {
+	UserAgent      string                `json:"userAgent"`                // User-Agent 请求头与 navigator.userAgent
+	AcceptLanguage string                `json:"acceptLanguage,omitempty"` // Accept-Language 请求头与 navigator.language
+	Platform       string                `json:"platform,omitempty"`       // navigator.platform
+	ClientHints    *UserAgentClientHints `json:"clientHints,omitempty"`    // Sec-CH-UA-* 请求头与 navigator.userAgentData
+}
+
+// LocaleOverride 会话级时区与区域覆盖，影响页面中的 Date、Intl 等本地化结果；字段为空表示不覆盖该项
+type LocaleOverride struct {
+	Timezone string `json:"timezone,omitempty"` // IANA 时区，如 Asia/Shanghai、America/New_York
+	Locale   string `json:"locale,omitempty"`   // ICU 区域，如 zh_CN、en_US
+}
+
+// Geolocation 会话级地理位置覆盖，页面通过 navigator.geolocation 获取到该位置
+type Geolocation struct {
+	Latitude  float64 `json:"latitude"`  // 纬度，-90 ~ 90
+	Longitude float64 `json:"longitude"` // 经度，-180 ~ 180
+	Accuracy  float64 `json:"accuracy"`  // 精度，米
+}
+
+// UserAgentClientHints 用户代理客户端提示
+type UserAgentClientHints struct {
+	Brands          []UserAgentBrand `json:"brands,omitempty"`          // Sec-CH-UA
+	FullVersionList []UserAgentBrand `json:"fullVersionList,omitempty"` // Sec-CH-UA-Full-Version-List
+	Platform        string           `json:"platform"`                  // Sec-CH-UA-Platform，如 Windows / Android
+	PlatformVersion string           `json:"platformVersion,omitempty"` // Sec-CH-UA-Platform-Version
+	Architecture    string           `json:"architecture,omitempty"`    // Sec-CH-UA-Arch，如 x86 / arm
+	Model           string           `json:"model,omitempty"`           // Sec-CH-UA-Model
+	Mobile          bool             `json:"mobile"`                    // Sec-CH-UA-Mobile
+	Bitness         string           `json:"bitness,omitempty"`         // Sec-CH-UA-Bitness
+}
+
+// UserAgentBrand 客户端提示中的品牌与版本
+type UserAgentBrand struct {
+	Brand   string `json:"brand"`
+	Version string `json:"version"`
 }
 
 // EngineStats 引擎统计信息
 type EngineStats struct {
-	Total   int64            `json:"total"`
-	Matched int64            `json:"matched"`
-	ByRule  map[RuleID]int64 `json:"byRule"`
+	Total          int64            `json:"total"`
+	Matched        int64            `json:"matched"`
+	ByRule         map[RuleID]int64 `json:"byRule"`
+	ByTag          map[string]int64 `json:"byTag"`          // 按规则标签（小写）汇总的命中次数，带多个标签的规则分别计入
+	CachedPatterns int              `json:"cachedPatterns"` // 会话正则缓存中的条目数（共享缓存时为全局数量）
+}
+
+// DuplicateRequestStat 会话中重复发送的一组相同请求的汇总
+type DuplicateRequestStat struct {
+	Method        string `json:"method"`
+	URL           string `json:"url"`
+	BodyHash      string `json:"bodyHash,omitempty"` // 请求体 SHA-256，没有请求体时为空
+	Duplicates    int    `json:"duplicates"`         // 判定为重复的次数（不含每轮的首次）
+	MaxBurst      int    `json:"maxBurst"`           // 单轮连续出现的最多次数
+	MinIntervalMS int64  `json:"minIntervalMs"`      // 相邻两次的最短间隔
+	FirstSeen     int64  `json:"firstSeen"`          // 首次判定为重复的时间（Unix 毫秒）
+	LastSeen      int64  `json:"lastSeen"`           // 最近一次判定为重复的时间（Unix 毫秒）
 }
 
 // TargetInfo 目标信息
@@ -34,16 +264,129 @@ type TargetInfo struct {
 	IsCurrent bool     `json:"isCurrent"`
 }
 
+// SessionInfo 会话信息
+type SessionInfo struct {
+	ID           SessionID           `json:"id"`
+	DevToolsURL  string              `json:"devToolsURL"`
+	Intercepting bool                `json:"intercepting"`
+	Targets      []TargetID          `json:"targets"` // 已附加的目标
+	Capabilities BrowserCapabilities `json:"capabilities"`
+	ProxyAddr    string              `json:"proxyAddr,omitempty"` // 内置转发代理的监听地址，未启动时为空
+}
+
+// BrowserCapabilities 连接的浏览器版本与可选特性支持情况，附加首个目标时探测；
+// 不支持的特性会被自动停用
+type BrowserCapabilities struct {
+	Detected        bool   `json:"detected"`                  // 是否已完成探测，未探测时按支持全部特性处理
+	Browser         string `json:"browser,omitempty"`         // 如 Chrome/120.0.6099.71
+	ProtocolVersion string `json:"protocolVersion,omitempty"` // CDP 协议版本
+	UserAgent       string `json:"userAgent,omitempty"`
+	V8Version       string `json:"v8Version,omitempty"`
+	MajorVersion    int    `json:"majorVersion,omitempty"` // Chromium 主版本，无法识别时为 0
+
+	PostDataEntries          bool `json:"postDataEntries"`          // 请求体以 postDataEntries 分段提供，二进制内容不会被破坏
+	TakeResponseBodyAsStream bool `json:"takeResponseBodyAsStream"` // 超过阈值的响应体可以流方式读取并改写
+	UserAgentMetadata        bool `json:"userAgentMetadata"`        // User-Agent 覆盖支持 Sec-CH-UA-* 客户端提示
+}
+
+// Unsupported 返回不受支持的特性名称
+func (c BrowserCapabilities) Unsupported() []string {
+	var out []string
+	if !c.PostDataEntries {
+		out = append(out, "postDataEntries")
+	}
+	if !c.TakeResponseBodyAsStream {
+		out = append(out, "takeResponseBodyAsStream")
+	}
+	if !c.UserAgentMetadata {
+		out = append(out, "userAgentMetadata")
+	}
+	return out
+}
+
+// Cookie 浏览器 Cookie
+type Cookie struct {
+	Name     string `json:"name"`
+	Value    string `json:"value"`
+	Domain   string `json:"domain,omitempty"`
+	Path     string `json:"path,omitempty"`
+	URL      string `json:"url,omitempty"`      // 设置时关联的 URL，未指定 domain/path 时据此推导（仅 SetCookie）
+	Expires  int64  `json:"expires,omitempty"`  // 过期时间（Unix 秒），0 表示会话 Cookie
+	HTTPOnly bool   `json:"httpOnly,omitempty"` // 仅 HTTP 可访问
+	Secure   bool   `json:"secure,omitempty"`   // 仅 HTTPS 发送
+	SameSite string `json:"sameSite,omitempty"` // Strict / Lax / None
+}
+
+// CookieFilter 删除 Cookie 的条件，Name 为空时删除 URL（未指定时为当前页面）下匹配 Domain/Path 的全部 Cookie
+type CookieFilter struct {
+	Name   string `json:"name,omitempty"`
+	URL    string `json:"url,omitempty"`
+	Domain string `json:"domain,omitempty"`
+	Path   string `json:"path,omitempty"`
+}
+
+// Screenshot 页面截图
+type Screenshot struct {
+	Target    TargetID `json:"target"`
+	Timestamp int64    `json:"timestamp"`
+	MimeType  string   `json:"mimeType"` // 图片类型，目前为 image/png
+	Data      []byte   `json:"data"`     // 图片数据，JSON 中为 base64
+}
+
 // NetworkEvent 网络请求事件
 type NetworkEvent struct {
-	Session      SessionID    `json:"session"`
-	Target       TargetID     `json:"target"`
-	Timestamp    int64        `json:"timestamp"`
-	IsMatched    bool         `json:"isMatched"`
-	Request      RequestInfo  `json:"request"`
-	Response     ResponseInfo `json:"response,omitempty"`
-	FinalResult  string       `json:"finalResult,omitempty"`
-	MatchedRules []RuleMatch  `json:"matchedRules,omitempty"`
+	Session      SessionID     `json:"session"`
+	Target       TargetID      `json:"target"`
+	Timestamp    int64         `json:"timestamp"`
+	IsMatched    bool          `json:"isMatched"`
+	NetworkID    string        `json:"networkId,omitempty"` // 浏览器网络请求 ID，同一请求的请求阶段与响应阶段事件相同
+	Stage        string        `json:"stage,omitempty"`     // 产生事件的拦截阶段（request/response）
+	Preload      string        `json:"preload,omitempty"`   // 由预加载触发的请求：link / earlyHints
+	Operation    *APIOperation `json:"operation,omitempty"` // 从 API 封装中解析出的逻辑操作
+	Request      RequestInfo   `json:"request"`
+	DisplayURL   string        `json:"displayUrl,omitempty"` // 主机为国际化域名时 URL 的 Unicode 形式
+	Response     ResponseInfo  `json:"response,omitempty"`
+	FinalResult  string        `json:"finalResult,omitempty"`
+	Failure      *LoadFailure  `json:"failure,omitempty"` // 网络层失败的原因，finalResult 为 failed 时填充
+	MatchedRules []RuleMatch   `json:"matchedRules,omitempty"`
+	// BodyTransforms 按执行顺序记录的 Body 变换步骤
+	BodyTransforms []BodyTransform `json:"bodyTransforms,omitempty"`
+	// IsTruncated 推送到界面时请求体或响应体超过上限被截断，完整内容通过 BodyRef 获取
+	IsTruncated bool   `json:"isTruncated,omitempty"`
+	BodyRef     string `json:"bodyRef,omitempty"`
+}
+
+// LoadFailure 浏览器报告的网络层失败（Network.loadingFailed）
+type LoadFailure struct {
+	ErrorText     string `json:"errorText"`               // 错误信息，如 net::ERR_NAME_NOT_RESOLVED
+	Canceled      bool   `json:"canceled,omitempty"`      // 请求被页面或浏览器取消
+	BlockedReason string `json:"blockedReason,omitempty"` // 被浏览器阻止的原因，如 mixed-content、inspector
+	CORSError     string `json:"corsError,omitempty"`     // 被 CORS 阻止时的错误类型
+}
+
+// 事件中预加载请求的来源
+const (
+	PreloadLink       = "link"       // 由页面或响应头中的 Link 预加载触发
+	PreloadEarlyHints = "earlyHints" // 由 103 Early Hints 中的 Link 预加载触发，可能没有请求阶段的暂停
+)
+
+// APIOperation 从常见 API 封装（JSON:API、GraphQL、gRPC-web、SOAP）中解析出的逻辑操作
+type APIOperation struct {
+	Format    string `json:"format"`              // jsonapi / graphql / grpc-web / soap
+	Resource  string `json:"resource,omitempty"`  // 资源：JSON:API 资源类型、GraphQL 操作类型、gRPC 服务、SOAP 服务
+	Operation string `json:"operation,omitempty"` // 操作：JSON:API 动作、GraphQL 操作名、gRPC 方法、SOAP 操作
+	Key       string `json:"key"`                 // 分组标识，格式 format:resource/operation
+}
+
+// BodyTransform 一次 Body 变换步骤
+type BodyTransform struct {
+	RuleID     string `json:"ruleId"`
+	RuleName   string `json:"ruleName"`
+	Action     string `json:"action"`
+	Changed    bool   `json:"changed"`           // Body 是否发生变化
+	Skipped    string `json:"skipped,omitempty"` // 未执行的原因
+	SizeBefore int    `json:"sizeBefore"`
+	SizeAfter  int    `json:"sizeAfter"`
 }
 
 // RequestInfo 请求信息
@@ -52,23 +395,169 @@ type RequestInfo struct {
 	Method       string            `json:"method"`
 	Headers      map[string]string `json:"headers"`
 	Body         string            `json:"body"`
+	BodyEncoding string            `json:"bodyEncoding,omitempty"` // Body 编码，二进制内容为 base64
 	ResourceType string            `json:"resourceType,omitempty"` // document/xhr/script/image等
+	// RawHeaders 浏览器实际发送的原始请求头行（含 Cookie 等由网络层添加的头部），认证头以掩码记录
+	RawHeaders string `json:"rawHeaders,omitempty"`
+	// BlockedCookies 与请求 URL 关联但未随请求发送的 Cookie
+	BlockedCookies []BlockedCookie `json:"blockedCookies,omitempty"`
+}
+
+// BlockedCookie 被浏览器阻止的 Cookie 及原因（如 SameSiteLax、SecureOnly），用于排查 SameSite 等问题
+type BlockedCookie struct {
+	Name    string   `json:"name"`
+	Reasons []string `json:"reasons"`
+	Line    string   `json:"line,omitempty"` // 响应中被拒绝的原始 Set-Cookie 内容
 }
 
 // ResponseInfo 响应信息
 type ResponseInfo struct {
-	StatusCode int               `json:"statusCode"`
-	Headers    map[string]string `json:"headers"`
-	Body       string            `json:"body"`
-	Timing     ResponseTiming    `json:"timing,omitempty"` // 响应时间信息
+	StatusCode    int               `json:"statusCode"`
+	Protocol      string            `json:"protocol,omitempty"`      // 协商的 HTTP 协议（h2 / h3 / http/1.1），未知时为空
+	RemoteAddress string            `json:"remoteAddress,omitempty"` // 服务端地址（IP:端口）
+	TLS           *TLSInfo          `json:"tls,omitempty"`           // HTTPS 连接的 TLS 与证书信息
+	Headers       map[string]string `json:"headers"`
+	Body          string            `json:"body"`
+	BodyEncoding  string            `json:"bodyEncoding,omitempty"`  // Body 编码，二进制内容为 base64
+	BodyTruncated bool              `json:"bodyTruncated,omitempty"` // 超过 Body 大小阈值，仅记录前缀或未记录
+	Timing        ResponseTiming    `json:"timing,omitempty"`        // 响应时间信息
+	// RawHeaders 浏览器实际接收的原始响应头；HTTP/1.x 为原始文本，HTTP/2、HTTP/3 按头部逐行还原
+	RawHeaders string `json:"rawHeaders,omitempty"`
+	// BlockedCookies 响应中被浏览器拒绝保存的 Set-Cookie
+	BlockedCookies []BlockedCookie `json:"blockedCookies,omitempty"`
+}
+
+// TLSInfo HTTPS 连接的 TLS 版本、加密套件与服务端证书信息
+type TLSInfo struct {
+	Protocol    string `json:"protocol"`              // TLS 版本，如 TLS 1.3、QUIC
+	Cipher      string `json:"cipher,omitempty"`      // 加密套件
+	SubjectName string `json:"subjectName,omitempty"` // 证书主体
+	Issuer      string `json:"issuer,omitempty"`      // 证书签发者
+	ValidTo     int64  `json:"validTo,omitempty"`     // 证书到期时间（毫秒时间戳）
 }
 
-// ResponseTiming 响应时间信息
+// BodyEncodingBase64 事件中二进制 Body 的编码
+const BodyEncodingBase64 = "base64"
+
+// ResponseTiming 响应时间信息；开启耗时记录时包含浏览器上报的各阶段耗时（毫秒），未发生的阶段为 0
 type ResponseTiming struct {
-	StartTime int64 `json:"startTime"` // 开始时间
-	EndTime   int64 `json:"endTime"`   // 结束时间
+	StartTime int64   `json:"startTime"`         // 开始时间
+	EndTime   int64   `json:"endTime"`           // 结束时间
+	DNS       float64 `json:"dns,omitempty"`     // DNS 解析
+	Connect   float64 `json:"connect,omitempty"` // 建立连接（含 TLS 握手）
+	SSL       float64 `json:"ssl,omitempty"`     // TLS 握手
+	TTFB      float64 `json:"ttfb,omitempty"`    // 请求发送完成到收到响应头
+	Total     float64 `json:"total,omitempty"`   // 请求开始到加载完成或失败
+}
+
+// ReplayEdits 重放请求前对原始请求的修改
+type ReplayEdits struct {
+	Method        string            `json:"method,omitempty"`
+	URL           string            `json:"url,omitempty"`
+	Headers       map[string]string `json:"headers,omitempty"`       // 设置的请求头，不区分大小写覆盖同名请求头
+	RemoveHeaders []string          `json:"removeHeaders,omitempty"` // 移除的请求头
+	Body          *string           `json:"body,omitempty"`          // 替换的文本请求体，空字符串表示清空
+}
+
+// Apply 返回应用修改后的请求副本
+func (e ReplayEdits) Apply(req RequestInfo) RequestInfo {
+	if e.Method != "" {
+		req.Method = e.Method
+	}
+	if e.URL != "" {
+		req.URL = e.URL
+	}
+	headers := make(map[string]string, len(req.Headers)+len(e.Headers))
+	for k, v := range req.Headers {
+		headers[k] = v
+	}
+	for _, name := range e.RemoveHeaders {
+		for k := range headers {
+			if strings.EqualFold(k, name) {
+				delete(headers, k)
+			}
+		}
+	}
+	for name, v := range e.Headers {
+		for k := range headers {
+			if strings.EqualFold(k, name) {
+				delete(headers, k)
+			}
+		}
+		headers[name] = v
+	}
+	req.Headers = headers
+	if e.Body != nil {
+		req.Body = *e.Body
+		req.BodyEncoding = ""
+	}
+	return req
+}
+
+// PendingItem 命中 pause 行为、等待人工放行的请求（断点）
+type PendingItem struct {
+	ID        string       `json:"id"`
+	Session   SessionID    `json:"session"`
+	Target    TargetID     `json:"target"`
+	Stage     string       `json:"stage"`     // request / response
+	Timestamp int64        `json:"timestamp"` // 暂停时间（毫秒时间戳）
+	ExpiresAt int64        `json:"expiresAt"` // 超时后按原样放行的时间（毫秒时间戳）
+	Request   RequestInfo  `json:"request"`   // 请求阶段为应用规则修改后的请求
+	Response  ResponseInfo `json:"response"`  // 响应阶段为应用规则修改后的响应
+	Rules     []RuleMatch  `json:"rules"`
+}
+
+// PendingEdits 放行断点前对请求或响应的修改，在规则修改的基础上应用
+type PendingEdits struct {
+	Method        string            `json:"method,omitempty"`        // 请求阶段：替换请求方法
+	URL           string            `json:"url,omitempty"`           // 请求阶段：替换 URL
+	StatusCode    int               `json:"statusCode,omitempty"`    // 响应阶段：替换状态码
+	Headers       map[string]string `json:"headers,omitempty"`       // 设置的头部，不区分大小写覆盖同名头部
+	RemoveHeaders []string          `json:"removeHeaders,omitempty"` // 移除的头部
+	Body          *string           `json:"body,omitempty"`          // 替换的文本 Body，空字符串表示清空
+}
+
+// HookPoint 拦截流水线中中间件的挂载点
+type HookPoint string
+
+const (
+	HookBeforeEval  HookPoint = "beforeEval"  // 规则评估前，所有允许修改的主机上被拦截的请求与响应
+	HookAfterEval   HookPoint = "afterEval"   // 规则评估后、执行行为前，含未命中任何规则的请求
+	HookBeforeApply HookPoint = "beforeApply" // 命中规则的修改聚合完成（含断点放行）、提交给浏览器前
+)
+
+// HookDecision 中间件对请求的处理决定
+type HookDecision string
+
+const (
+	HookContinue HookDecision = ""     // 按流水线继续处理
+	HookPass     HookDecision = "pass" // 跳过规则原样放行（beforeApply 时放弃全部规则修改）
+	HookFail     HookDecision = "fail" // 以 Aborted 使请求失败，记录为 blocked
+)
+
+// Exchange 中间件处理的一次请求或响应，中间件通过修改其中标注为可修改的字段影响后续处理
+type Exchange struct {
+	Point    HookPoint
+	Session  SessionID
+	Target   TargetID
+	Stage    string       // request / response
+	Request  RequestInfo  // beforeApply 请求阶段为应用规则修改后的请求
+	Response ResponseInfo // 仅响应阶段有值，beforeEval 与 afterEval 时不含 Body；beforeApply 时为应用规则修改后的响应
+
+	// Rules 命中的规则（afterEval、beforeApply）；afterEval 时可移除其中的条目，被移除的规则不再执行
+	Rules []RuleMatch
+	// Edits 在规则修改基础上叠加的修改（仅 beforeApply），语义与断点放行时的修改相同
+	Edits *PendingEdits
+	// Decision 处理决定，默认按流水线继续
+	Decision HookDecision
 }
 
+// HookHandler 处理一次请求或响应
+type HookHandler func(ctx context.Context, ex *Exchange)
+
+// Middleware 包装 HookHandler：可在调用 next 前后执行自定义逻辑，不调用 next 时跳过之后注册的中间件
+type Middleware func(next HookHandler) HookHandler
+
 // RuleMatch 规则匹配信息
 type RuleMatch struct {
 	RuleID   string   `json:"ruleId"`
@@ -86,9 +575,48 @@ type UnmatchedEvent struct {
 	NetworkEvent
 }
 
+// NoticeKind 通知事件类型
+type NoticeKind string
+
+const (
+	NoticeSchemaDrift        NoticeKind = "schema_drift"        // JSON 响应结构发生变化
+	NoticePipelineStalled    NoticeKind = "pipeline_stalled"    // 拦截处理停滞
+	NoticeRuleConflict       NoticeKind = "rule_conflict"       // 多条规则的变更相互矛盾
+	NoticeConnection         NoticeKind = "connection"          // 目标连接状态变化（details.state：connected/reconnecting/lost）
+	NoticeBodyTooLarge       NoticeKind = "body_too_large"      // 规则生成的 Body 超过会话上限，修改被放弃
+	NoticeContentChanged     NoticeKind = "content_changed"     // 端点的响应体与上次记录不同
+	NoticeRulesApplied       NoticeKind = "rules_applied"       // 当前规则的拦截模式已在目标生效（details：patterns/config/rules）
+	NoticeInterceptionActive NoticeKind = "interception_active" // 目标已开始消费拦截事件，此后暂停的请求都会经过规则处理
+	NoticeQuickMockExpired   NoticeKind = "quick_mock_expired"  // 临时 Mock 已到期移除（details.id）
+	NoticeDuplicateRequest   NoticeKind = "duplicate_request"   // 窗口内再次发送了相同的请求（details：method/url/bodyHash/count/intervalMs）
+)
+
+// NoticeEvent 会话级通知事件（分析告警、状态变化等，仅内存，不存数据库）
+type NoticeEvent struct {
+	Session   SessionID         `json:"session"`
+	Target    TargetID          `json:"target,omitempty"`
+	Timestamp int64             `json:"timestamp"`
+	Kind      NoticeKind        `json:"kind"`
+	URL       string            `json:"url,omitempty"`
+	Message   string            `json:"message"`
+	Details   map[string]string `json:"details,omitempty"`
+}
+
 // InterceptEvent 统一事件接口（用于通道传输）
 type InterceptEvent struct {
 	IsMatched bool            `json:"isMatched"`
 	Matched   *MatchedEvent   `json:"matched,omitempty"`
 	Unmatched *UnmatchedEvent `json:"unmatched,omitempty"`
+	Notice    *NoticeEvent    `json:"notice,omitempty"`
+}
+
+// RequestURL 返回事件的请求 URL，通知事件返回空串
+func (e InterceptEvent) RequestURL() string {
+	switch {
+	case e.Matched != nil:
+		return e.Matched.Request.URL
+	case e.Unmatched != nil:
+		return e.Unmatched.Request.URL
+	}
+	return ""
 }
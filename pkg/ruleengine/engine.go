@@ -0,0 +1,416 @@
+// Package ruleengine 以不依赖 CDP 的形式提供 cdpnetool 的规则引擎，供其他 Go 程序
+// （httptest 中间件、自研代理等）复用同一套规则格式与语义。
+//
+// 规则的匹配条件、优先级、冲突策略与行为执行均与浏览器拦截一致；依赖浏览器或会话的能力
+// 不适用：pause 断点被忽略，signHmac、signAwsV4、provideCredentials 与 injectScript 不执行，
+// cookieBlocked 条件始终不满足，会话级密钥、响应头策略与快速 Mock 不生效。
+// captureVar 保存的变量属于 Engine，在同一 Engine 处理的请求之间共享
+package ruleengine
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"cdpnetool/internal/mutation"
+	"cdpnetool/internal/rules"
+	"cdpnetool/pkg/rulespec"
+)
+
+// Request 待评估的 HTTP 请求
+type Request struct {
+	Method       string
+	URL          string
+	Headers      http.Header
+	Body         []byte
+	ResourceType string // 资源类型（如 XHR、Document），供 resourceType 条件使用，可为空
+	Protocol     string // 协商的协议（h2 / h3 / http/1.1），供 protocol 条件使用，可为空
+}
+
+// Response 待评估的 HTTP 响应
+type Response struct {
+	StatusCode int
+	Headers    http.Header
+	Body       []byte
+}
+
+// RequestResult 请求阶段的处理结果
+type RequestResult struct {
+	Request  *Request      // 应用修改后的请求，未修改时为原请求
+	Response *Response     // 规则直接给出的响应（block、redirect、notModified），非 nil 时不应再转发请求
+	Abort    string        // terminate 行为给出的网络错误原因，非空时应使请求失败
+	Delay    time.Duration // 放行、响应或失败前应等待的时间
+	Matched  []string      // 命中的规则 ID，按优先级排序
+	Modified bool          // 是否对请求做了修改或直接给出了响应
+}
+
+// ResponseResult 响应阶段的处理结果
+type ResponseResult struct {
+	Response  *Response     // 应用修改后的响应，未修改时为原响应
+	Abort     string        // terminate 行为给出的网络错误原因，非空时应中断连接
+	Truncated bool          // Response.Body 已按 terminate 行为截断，写出后应中断连接
+	Delay     time.Duration // 下发前应等待的时间，含 throttle 行为折算的传输时间
+	Matched   []string      // 命中的规则 ID，按优先级排序
+	Modified  bool          // 是否对响应做了修改
+}
+
+// Stats 规则命中统计
+type Stats struct {
+	Total   int64            // 评估的请求数
+	Matched int64            // 命中至少一条规则的请求数
+	ByRule  map[string]int64 // 规则 ID -> 命中次数
+	ByTag   map[string]int64 // 标签（小写） -> 命中次数
+}
+
+// Engine 独立的规则引擎，可并发使用
+type Engine struct {
+	rules *rules.Engine
+
+	mu   sync.RWMutex
+	vars map[string]string // captureVar 行为保存的变量，行为模板通过 {{var.name}} 引用
+}
+
+// New 使用规则配置创建引擎，config 可为 nil
+func New(config *rulespec.Config) *Engine {
+	return &Engine{rules: rules.New(config, nil)}
+}
+
+// Update 替换规则配置，规则的计数与频率窗口从头开始
+func (e *Engine) Update(config *rulespec.Config) {
+	e.rules.Update(config)
+}
+
+// Config 返回当前规则配置
+func (e *Engine) Config() *rulespec.Config {
+	return e.rules.GetConfig()
+}
+
+// Stats 返回规则命中统计
+func (e *Engine) Stats() Stats {
+	s := e.rules.GetStats()
+	return Stats{Total: s.Total, Matched: s.Matched, ByRule: s.ByRule, ByTag: s.ByTag}
+}
+
+// ResetStats 清空规则命中统计
+func (e *Engine) ResetStats() {
+	e.rules.ResetStats()
+}
+
+// Variables 返回 captureVar 行为保存的变量副本
+func (e *Engine) Variables() map[string]string {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	out := make(map[string]string, len(e.vars))
+	for k, v := range e.vars {
+		out[k] = v
+	}
+	return out
+}
+
+// ClearVariables 清空 captureVar 行为保存的变量
+func (e *Engine) ClearVariables() {
+	e.mu.Lock()
+	e.vars = nil
+	e.mu.Unlock()
+}
+
+// ApplyRequest 评估请求阶段的规则并执行命中规则的行为
+func (e *Engine) ApplyRequest(ctx context.Context, req *Request) *RequestResult {
+	res := &RequestResult{Request: req}
+	matched := e.rules.EvalForStage(evalContext(req, nil), rulespec.StageRequest)
+	res.Matched = ruleIDs(matched)
+
+	var agg *mutation.RequestMutation
+	merger := mutation.NewMerger(e.rules.GetConfig().ConflictPolicy())
+	origin := requestOf(req)
+	body := mutation.NewBodyContent(req.Body, origin.ContentType())
+
+	for _, mr := range matched {
+		if len(mr.Rule.Actions) == 0 {
+			continue
+		}
+		mut := mutation.ExecuteRequest(ctx, mr.Rule.Actions, origin, body, e.templateVars(mr.Params))
+		e.storeVars(mut.Captures)
+		if mut.Body != nil {
+			body = *mut.Body
+		}
+		delay := mut.Delay
+		if agg != nil {
+			delay += agg.Delay
+		}
+
+		// 终结性行为
+		if mut.Block != nil {
+			res.Response = &Response{
+				StatusCode: mut.Block.StatusCode,
+				Headers:    headerOf(mut.Block.Headers),
+				Body:       mut.Block.Body,
+			}
+			res.Delay = delay
+			res.Modified = true
+			return res
+		}
+		if mut.Terminate != nil {
+			res.Abort = mut.Terminate.Reason
+			res.Delay = delay + mut.Terminate.After
+			res.Modified = true
+			return res
+		}
+
+		if agg == nil {
+			agg = mutation.NewRequestMutation()
+		}
+		merger.MergeRequest(agg, mut, mr)
+	}
+	if agg == nil {
+		return res
+	}
+	res.Delay = agg.Delay
+	// 规则变更相互矛盾且策略为放弃时，原样放行
+	if merger.Aborted() {
+		return res
+	}
+
+	out := &Request{
+		Method:       req.Method,
+		URL:          req.URL,
+		Headers:      req.Headers.Clone(),
+		Body:         req.Body,
+		ResourceType: req.ResourceType,
+		Protocol:     req.Protocol,
+	}
+	if out.Headers == nil {
+		out.Headers = http.Header{}
+	}
+	if u := mutation.BuildURL(req.URL, agg); u != nil {
+		out.URL = *u
+	}
+	if agg.Method != nil {
+		out.Method = *agg.Method
+	}
+	applyHeaders(out.Headers, agg.RemoveHeaders, agg.Headers)
+	if len(agg.Cookies) > 0 || len(agg.RemoveCookies) > 0 {
+		if cookie := mutation.RewriteCookie(out.Headers.Get("Cookie"), agg); cookie != "" {
+			out.Headers.Set("Cookie", cookie)
+		} else {
+			out.Headers.Del("Cookie")
+		}
+	}
+	if agg.Body != nil {
+		out.Body = agg.Body.Data
+		out.Headers.Del("Content-Length")
+	}
+	res.Request = out
+	res.Modified = true
+	return res
+}
+
+// ApplyResponse 评估响应阶段的规则并执行命中规则的行为，req 为产生该响应的请求
+func (e *Engine) ApplyResponse(ctx context.Context, req *Request, resp *Response) *ResponseResult {
+	res := &ResponseResult{Response: resp}
+	matched := e.rules.EvalForStage(evalContext(req, resp), rulespec.StageResponse)
+	res.Matched = ruleIDs(matched)
+
+	var agg *mutation.ResponseMutation
+	merger := mutation.NewMerger(e.rules.GetConfig().ConflictPolicy())
+	origin, originResp := requestOf(req), responseOf(resp)
+	body := mutation.NewBodyContent(resp.Body, resp.Headers.Get("Content-Type"))
+
+	for _, mr := range matched {
+		if len(mr.Rule.Actions) == 0 {
+			continue
+		}
+		mut := mutation.ExecuteResponse(ctx, mr.Rule.Actions, origin, originResp, body, e.templateVars(mr.Params))
+		e.storeVars(mut.Captures)
+
+		// 终止行为（截断或延时失败），同样等待已命中的 delay 行为
+		if mut.Terminate != nil {
+			res.Abort = mut.Terminate.Reason
+			res.Delay = mut.Terminate.After + mut.Delay
+			if agg != nil {
+				res.Delay += agg.Delay
+			}
+			if mut.Terminate.Truncated {
+				res.Response = &Response{StatusCode: resp.StatusCode, Headers: resp.Headers, Body: mut.Terminate.Body}
+				res.Truncated = true
+			}
+			res.Modified = true
+			return res
+		}
+
+		if agg == nil {
+			agg = mutation.NewResponseMutation()
+		}
+		merger.MergeResponse(agg, mut, mr)
+		if mut.Body != nil {
+			body = *mut.Body
+		}
+	}
+	if agg == nil {
+		return res
+	}
+	// 规则变更相互矛盾且策略为放弃时，原样返回
+	if merger.Aborted() {
+		res.Delay = agg.Delay
+		return res
+	}
+
+	if agg.Compress != "" && agg.Body == nil && len(body.Data) > 0 {
+		agg.Body = &body
+	}
+	if err := mutation.ApplyCompression(originResp, agg); err != nil {
+		agg.Compress = ""
+	}
+
+	out := &Response{StatusCode: resp.StatusCode, Headers: resp.Headers.Clone(), Body: resp.Body}
+	if out.Headers == nil {
+		out.Headers = http.Header{}
+	}
+	if agg.StatusCode != nil {
+		out.StatusCode = *agg.StatusCode
+	}
+	applyHeaders(out.Headers, agg.RemoveHeaders, agg.Headers)
+	if agg.Body != nil {
+		out.Body = agg.Body.Data
+		out.Headers.Del("Content-Length")
+	}
+
+	res.Response = out
+	res.Delay = agg.Delay + throttleDelay(len(out.Body), agg.Throttle)
+	res.Modified = true
+	return res
+}
+
+// templateVars 返回变量与规则匹配变量合并后的模板变量
+func (e *Engine) templateVars(params map[string]string) map[string]string {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	if len(e.vars) == 0 {
+		return params
+	}
+	vars := make(map[string]string, len(params)+len(e.vars))
+	for k, v := range e.vars {
+		vars[mutation.VarPrefix+k] = v
+	}
+	for k, v := range params {
+		vars[k] = v
+	}
+	return vars
+}
+
+// storeVars 保存 captureVar 行为提取的变量，同名变量以最新的值为准
+func (e *Engine) storeVars(captures map[string]string) {
+	if len(captures) == 0 {
+		return
+	}
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.vars == nil {
+		e.vars = make(map[string]string, len(captures))
+	}
+	for k, v := range captures {
+		e.vars[k] = v
+	}
+}
+
+// evalContext 构建规则评估上下文，resp 为 nil 时为请求阶段
+func evalContext(req *Request, resp *Response) *rules.EvalContext {
+	ctx := &rules.EvalContext{
+		URL:          req.URL,
+		Method:       req.Method,
+		ResourceType: req.ResourceType,
+		Protocol:     req.Protocol,
+		Headers:      lowerHeaders(req.Headers),
+		Query:        map[string]string{},
+		Cookies:      map[string]string{},
+		Body:         string(req.Body),
+	}
+	if u, err := url.Parse(req.URL); err == nil {
+		for key, vals := range u.Query() {
+			if len(vals) > 0 {
+				ctx.Query[strings.ToLower(key)] = vals[0]
+			}
+		}
+	}
+	if v, ok := ctx.Headers["cookie"]; ok {
+		for name, val := range mutation.ParseCookie(v) {
+			ctx.Cookies[strings.ToLower(name)] = val
+		}
+	}
+	if resp != nil {
+		ctx.StatusCode = resp.StatusCode
+		ctx.ResponseHeaders = lowerHeaders(resp.Headers)
+	}
+	return ctx
+}
+
+// lowerHeaders 将头部转换为名称小写的映射，同名头部以逗号连接
+func lowerHeaders(h http.Header) map[string]string {
+	out := make(map[string]string, len(h))
+	for k, vals := range h {
+		out[strings.ToLower(k)] = strings.Join(vals, ", ")
+	}
+	return out
+}
+
+// requestOf 构建行为执行所需的请求信息
+func requestOf(req *Request) *mutation.Request {
+	headers := make(map[string]string, len(req.Headers))
+	for k, vals := range req.Headers {
+		headers[k] = strings.Join(vals, ", ")
+	}
+	return &mutation.Request{Method: req.Method, URL: req.URL, Headers: headers}
+}
+
+// responseOf 构建行为执行所需的响应信息
+func responseOf(resp *Response) *mutation.Response {
+	out := &mutation.Response{StatusCode: resp.StatusCode}
+	for k, vals := range resp.Headers {
+		for _, v := range vals {
+			out.Headers = append(out.Headers, mutation.Header{Name: k, Value: v})
+		}
+	}
+	return out
+}
+
+// headerOf 将单值头部映射转换为 http.Header
+func headerOf(m map[string]string) http.Header {
+	h := make(http.Header, len(m))
+	for k, v := range m {
+		h.Set(k, v)
+	}
+	return h
+}
+
+// applyHeaders 先移除再设置头部，保留未修改头部的多个取值
+func applyHeaders(h http.Header, remove []string, set map[string]string) {
+	for _, name := range remove {
+		h.Del(name)
+	}
+	for name, value := range set {
+		h.Set(name, value)
+	}
+}
+
+// throttleDelay 按限速折算 Body 的传输时间
+func throttleDelay(size, rate int) time.Duration {
+	if rate <= 0 || size <= 0 {
+		return 0
+	}
+	return time.Duration(int64(size) * int64(time.Second) / int64(rate))
+}
+
+// ruleIDs 返回命中规则的 ID 列表
+func ruleIDs(matched []*rules.MatchedRule) []string {
+	if len(matched) == 0 {
+		return nil
+	}
+	ids := make([]string, 0, len(matched))
+	for _, mr := range matched {
+		ids = append(ids, mr.Rule.ID)
+	}
+	return ids
+}
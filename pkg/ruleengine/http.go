@@ -0,0 +1,270 @@
+package ruleengine
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// ErrTerminated terminate 行为使请求失败时 Transport 返回的错误
+var ErrTerminated = errors.New("ruleengine: request terminated by rule")
+
+// Middleware 返回按规则处理请求与响应的 http.Handler，可用于 httptest.Server 或任意 HTTP 服务。
+// terminate 行为通过 http.ErrAbortHandler 中断连接
+func (e *Engine) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		req, err := requestFromHTTP(r)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		reqResult := e.ApplyRequest(r.Context(), req)
+		if !sleepContext(r.Context(), reqResult.Delay) {
+			return
+		}
+		if reqResult.Abort != "" {
+			panic(http.ErrAbortHandler)
+		}
+		if reqResult.Response != nil {
+			writeResponse(w, reqResult.Response)
+			return
+		}
+
+		out, err := reqResult.Request.toHTTP(r.Context())
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		out.RemoteAddr, out.RequestURI, out.TLS = r.RemoteAddr, r.RequestURI, r.TLS
+
+		rec := &recorder{header: http.Header{}}
+		next.ServeHTTP(rec, out)
+		if rec.status == 0 {
+			rec.status = http.StatusOK
+		}
+
+		respResult := e.ApplyResponse(r.Context(), reqResult.Request, &Response{
+			StatusCode: rec.status,
+			Headers:    rec.header,
+			Body:       rec.body.Bytes(),
+		})
+		if !sleepContext(r.Context(), respResult.Delay) {
+			return
+		}
+		if respResult.Abort != "" && !respResult.Truncated {
+			panic(http.ErrAbortHandler)
+		}
+		writeResponse(w, respResult.Response)
+		if respResult.Truncated {
+			panic(http.ErrAbortHandler)
+		}
+	})
+}
+
+// Transport 返回按规则处理请求与响应的 http.RoundTripper，base 为 nil 时使用 http.DefaultTransport。
+// terminate 行为使请求返回 ErrTerminated
+func (e *Engine) Transport(base http.RoundTripper) http.RoundTripper {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	return &transport{engine: e, base: base}
+}
+
+// transport 按规则处理请求与响应的 RoundTripper
+type transport struct {
+	engine *Engine
+	base   http.RoundTripper
+}
+
+// RoundTrip 实现 http.RoundTripper
+func (t *transport) RoundTrip(r *http.Request) (*http.Response, error) {
+	ctx := r.Context()
+	req, err := requestFromHTTP(r)
+	if err != nil {
+		return nil, err
+	}
+
+	reqResult := t.engine.ApplyRequest(ctx, req)
+	if !sleepContext(ctx, reqResult.Delay) {
+		return nil, ctx.Err()
+	}
+	if reqResult.Abort != "" {
+		return nil, ErrTerminated
+	}
+	if reqResult.Response != nil {
+		return reqResult.Response.toHTTP(r), nil
+	}
+
+	out, err := reqResult.Request.toHTTP(ctx)
+	if err != nil {
+		return nil, err
+	}
+	upstream, err := t.base.RoundTrip(out)
+	if err != nil {
+		return nil, err
+	}
+	body, err := io.ReadAll(upstream.Body)
+	upstream.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+
+	respResult := t.engine.ApplyResponse(ctx, reqResult.Request, &Response{
+		StatusCode: upstream.StatusCode,
+		Headers:    upstream.Header,
+		Body:       body,
+	})
+	if !sleepContext(ctx, respResult.Delay) {
+		return nil, ctx.Err()
+	}
+	if respResult.Abort != "" && !respResult.Truncated {
+		return nil, ErrTerminated
+	}
+	resp := respResult.Response.toHTTP(out)
+	resp.Proto, resp.ProtoMajor, resp.ProtoMinor, resp.TLS = upstream.Proto, upstream.ProtoMajor, upstream.ProtoMinor, upstream.TLS
+	if respResult.Truncated {
+		resp.Body = io.NopCloser(io.MultiReader(bytes.NewReader(respResult.Response.Body), errReader{ErrTerminated}))
+		resp.ContentLength = -1
+	}
+	return resp, nil
+}
+
+// requestFromHTTP 读取 http.Request 的 Body 并构建待评估的请求
+func requestFromHTTP(r *http.Request) (*Request, error) {
+	var body []byte
+	if r.Body != nil {
+		b, err := io.ReadAll(r.Body)
+		r.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+		body = b
+	}
+	target := r.URL.String()
+	if r.URL.Host == "" && r.Host != "" {
+		// 服务端收到的请求只有路径，补全为绝对 URL 以便按 URL 匹配
+		scheme := "http"
+		if r.TLS != nil {
+			scheme = "https"
+		}
+		target = scheme + "://" + r.Host + r.URL.RequestURI()
+	}
+	return &Request{
+		Method:   r.Method,
+		URL:      target,
+		Headers:  r.Header.Clone(),
+		Body:     body,
+		Protocol: protocolOf(r),
+	}, nil
+}
+
+// protocolOf 返回请求使用的协议，取值与 protocolEquals 条件一致
+func protocolOf(r *http.Request) string {
+	switch r.ProtoMajor {
+	case 2:
+		return "h2"
+	case 3:
+		return "h3"
+	case 1:
+		return "http/1.1"
+	}
+	return ""
+}
+
+// toHTTP 构建转发使用的 http.Request
+func (req *Request) toHTTP(ctx context.Context) (*http.Request, error) {
+	out, err := http.NewRequestWithContext(ctx, req.Method, req.URL, bytes.NewReader(req.Body))
+	if err != nil {
+		return nil, err
+	}
+	out.Header = req.Headers.Clone()
+	if host := out.Header.Get("Host"); host != "" {
+		out.Host = host
+		out.Header.Del("Host")
+	}
+	return out, nil
+}
+
+// toHTTP 构建返回给调用方的 http.Response
+func (resp *Response) toHTTP(req *http.Request) *http.Response {
+	return &http.Response{
+		Status:        strconv.Itoa(resp.StatusCode) + " " + http.StatusText(resp.StatusCode),
+		StatusCode:    resp.StatusCode,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        resp.Headers.Clone(),
+		Body:          io.NopCloser(bytes.NewReader(resp.Body)),
+		ContentLength: int64(len(resp.Body)),
+		Request:       req,
+	}
+}
+
+// writeResponse 将响应写入 ResponseWriter
+func writeResponse(w http.ResponseWriter, resp *Response) {
+	for k, vals := range resp.Headers {
+		for _, v := range vals {
+			w.Header().Add(k, v)
+		}
+	}
+	w.Header().Set("Content-Length", strconv.Itoa(len(resp.Body)))
+	w.WriteHeader(resp.StatusCode)
+	_, _ = w.Write(resp.Body)
+}
+
+// recorder 缓存下游 Handler 写出的响应，供响应阶段规则处理
+type recorder struct {
+	header http.Header
+	status int
+	body   bytes.Buffer
+}
+
+// Header 实现 http.ResponseWriter
+func (rec *recorder) Header() http.Header {
+	return rec.header
+}
+
+// WriteHeader 实现 http.ResponseWriter
+func (rec *recorder) WriteHeader(code int) {
+	if rec.status == 0 {
+		rec.status = code
+	}
+}
+
+// Write 实现 http.ResponseWriter
+func (rec *recorder) Write(b []byte) (int, error) {
+	if rec.status == 0 {
+		rec.status = http.StatusOK
+	}
+	return rec.body.Write(b)
+}
+
+// errReader 读取时返回固定错误，用于模拟截断后中断的连接
+type errReader struct {
+	err error
+}
+
+// Read 实现 io.Reader
+func (r errReader) Read([]byte) (int, error) {
+	return 0, r.err
+}
+
+// sleepContext 等待 d，ctx 先结束时返回 false
+func sleepContext(ctx context.Context, d time.Duration) bool {
+	if d <= 0 {
+		return true
+	}
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-t.C:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
@@ -0,0 +1,208 @@
+package rulespec
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// RulePatch 批量修改规则的部分字段，为 nil 的字段保持不变
+type RulePatch struct {
+	Enabled        *bool             `json:"enabled,omitempty"`
+	Priority       *int              `json:"priority,omitempty"`
+	Stage          *Stage            `json:"stage,omitempty"`
+	StopProcessing *bool             `json:"stopProcessing,omitempty"`
+	Replace        *ConditionReplace `json:"replace,omitempty"` // 替换匹配条件中的文本
+}
+
+// ConditionReplace 替换匹配条件 value 中的文本，例如把所有 urlPrefix 条件中的主机换成测试环境
+type ConditionReplace struct {
+	Types   []ConditionType `json:"types,omitempty"` // 生效的条件类型，为空时为全部非正则的 URL 条件
+	Search  string          `json:"search"`          // 查找的文本（替换全部出现）
+	Replace string          `json:"replace"`         // 替换为的文本
+}
+
+// defaultReplaceTypes 未指定条件类型时替换的 URL 条件
+var defaultReplaceTypes = []ConditionType{
+	ConditionURLEquals, ConditionURLPrefix, ConditionURLSuffix, ConditionURLContains, ConditionURLGlob, ConditionPathPattern,
+}
+
+// ruleIndex 返回规则在配置中的下标，不存在时返回 -1
+func (c *Config) ruleIndex(id string) int {
+	for i := range c.Rules {
+		if c.Rules[i].ID == id {
+			return i
+		}
+	}
+	return -1
+}
+
+// CloneRule 复制规则并插入到原规则之后：使用未占用的新 ID，名称追加「副本」，不继承作者、时间和变更记录
+func (c *Config) CloneRule(id string) (*Rule, error) {
+	i := c.ruleIndex(id)
+	if i < 0 {
+		return nil, fmt.Errorf("规则不存在: %s", id)
+	}
+	clone, err := copyRule(c.Rules[i])
+	if err != nil {
+		return nil, err
+	}
+	for n := len(c.Rules); ; n++ {
+		if c.ruleIndex(GenerateRuleID(n)) < 0 {
+			clone.ID = GenerateRuleID(n)
+			break
+		}
+	}
+	clone.Name += " (副本)"
+	clone.Author, clone.CreatedAt, clone.UpdatedAt, clone.Changelog = "", 0, 0, nil
+
+	c.Rules = append(c.Rules, Rule{})
+	copy(c.Rules[i+2:], c.Rules[i+1:])
+	c.Rules[i+1] = clone
+	return &c.Rules[i+1], nil
+}
+
+// copyRule 通过 JSON 往返深拷贝规则
+func copyRule(r Rule) (Rule, error) {
+	data, err := json.Marshal(r)
+	if err != nil {
+		return Rule{}, fmt.Errorf("复制规则失败: %w", err)
+	}
+	var out Rule
+	if err := json.Unmarshal(data, &out); err != nil {
+		return Rule{}, fmt.Errorf("复制规则失败: %w", err)
+	}
+	return out, nil
+}
+
+// BulkUpdateRules 对 ids 指定的规则应用修改，ids 为空时作用于全部规则；返回实际发生变化的规则数
+func (c *Config) BulkUpdateRules(ids []string, patch RulePatch) (int, error) {
+	if patch.Stage != nil && *patch.Stage != StageRequest && *patch.Stage != StageResponse {
+		return 0, fmt.Errorf("批量修改仅支持 request 或 response 阶段: %s", *patch.Stage)
+	}
+	if patch.Replace != nil && patch.Replace.Search == "" {
+		return 0, fmt.Errorf("替换的查找文本不能为空")
+	}
+
+	targets := make([]int, 0, len(c.Rules))
+	if len(ids) == 0 {
+		for i := range c.Rules {
+			targets = append(targets, i)
+		}
+	}
+	seen := make(map[string]bool, len(ids))
+	for _, id := range ids {
+		i := c.ruleIndex(id)
+		if i < 0 {
+			return 0, fmt.Errorf("规则不存在: %s", id)
+		}
+		if !seen[id] {
+			seen[id] = true
+			targets = append(targets, i)
+		}
+	}
+
+	changed := 0
+	for _, i := range targets {
+		before, err := copyRule(c.Rules[i])
+		if err != nil {
+			return changed, err
+		}
+		patch.apply(&c.Rules[i])
+		if !sameJSON(before, c.Rules[i]) {
+			changed++
+		}
+	}
+	return changed, nil
+}
+
+// HasTag 判断规则是否带有指定标签（不区分大小写）
+func (r *Rule) HasTag(tag string) bool {
+	for _, t := range r.Tags {
+		if strings.EqualFold(t, tag) {
+			return true
+		}
+	}
+	return false
+}
+
+// SetTagEnabled 启用或停用带有指定标签的全部规则，返回状态实际发生变化的规则数
+func (c *Config) SetTagEnabled(tag string, enabled bool) (int, error) {
+	tag = strings.TrimSpace(tag)
+	if tag == "" {
+		return 0, fmt.Errorf("标签不能为空")
+	}
+	found, changed := false, 0
+	for i := range c.Rules {
+		if !c.Rules[i].HasTag(tag) {
+			continue
+		}
+		found = true
+		if c.Rules[i].Enabled != enabled {
+			c.Rules[i].Enabled = enabled
+			changed++
+		}
+	}
+	if !found {
+		return 0, fmt.Errorf("没有带标签 %s 的规则", tag)
+	}
+	return changed, nil
+}
+
+// apply 将修改应用到单条规则
+func (p RulePatch) apply(r *Rule) {
+	if p.Enabled != nil {
+		r.Enabled = *p.Enabled
+	}
+	if p.Priority != nil {
+		r.Priority = *p.Priority
+	}
+	if p.Stage != nil {
+		r.Stage = *p.Stage
+	}
+	if p.StopProcessing != nil {
+		r.StopProcessing = *p.StopProcessing
+	}
+	if p.Replace != nil {
+		p.Replace.apply(r.Match.AllOf)
+		p.Replace.apply(r.Match.AnyOf)
+	}
+}
+
+// apply 在条件列表中执行文本替换
+func (cr *ConditionReplace) apply(conds []Condition) {
+	types := cr.Types
+	if len(types) == 0 {
+		types = defaultReplaceTypes
+	}
+	for i := range conds {
+		for _, t := range types {
+			if conds[i].Type == t {
+				conds[i].Value = strings.ReplaceAll(conds[i].Value, cr.Search, cr.Replace)
+				break
+			}
+		}
+	}
+}
+
+// ReorderRules 按 ids 的顺序重新排列规则，ids 必须恰好包含配置中的全部规则 ID
+func (c *Config) ReorderRules(ids []string) error {
+	if len(ids) != len(c.Rules) {
+		return fmt.Errorf("规则数量不一致: 需要 %d 个，实际 %d 个", len(c.Rules), len(ids))
+	}
+	ordered := make([]Rule, 0, len(c.Rules))
+	seen := make(map[string]bool, len(ids))
+	for _, id := range ids {
+		if seen[id] {
+			return fmt.Errorf("规则 ID 重复: %s", id)
+		}
+		seen[id] = true
+		i := c.ruleIndex(id)
+		if i < 0 {
+			return fmt.Errorf("规则不存在: %s", id)
+		}
+		ordered = append(ordered, c.Rules[i])
+	}
+	c.Rules = ordered
+	return nil
+}
@@ -0,0 +1,42 @@
+package rulespec
+
+import "strings"
+
+// URLGlob 拆分后的 urlGlob 模式，各部分为空表示不限
+type URLGlob struct {
+	Scheme   string // 协议，如 https 或 *
+	Host     string // 主机，可带端口；不带端口时匹配任意端口
+	Path     string // 路径，以 / 开头
+	Query    string // 查询串，不含 ?
+	HasQuery bool   // 模式中包含 ?，此时才匹配查询串
+}
+
+// ParseURLGlob 按协议、主机、路径、查询串拆分 urlGlob 模式。
+// 没有 :// 时模式从主机开始；以 / 开头时只匹配路径
+func ParseURLGlob(pattern string) URLGlob {
+	var g URLGlob
+	rest := pattern
+	if i := strings.Index(rest, "://"); i >= 0 {
+		g.Scheme, rest = rest[:i], rest[i+3:]
+	}
+	if i := strings.IndexAny(rest, "/?"); i >= 0 {
+		g.Host, rest = rest[:i], rest[i:]
+	} else {
+		g.Host, rest = rest, ""
+	}
+	if i := strings.Index(rest, "?"); i >= 0 {
+		g.Query, g.HasQuery = rest[i+1:], true
+		rest = rest[:i]
+	}
+	g.Path = rest
+	return g
+}
+
+// HostHasPort 判断主机部分是否指定了端口
+func (g URLGlob) HostHasPort() bool {
+	host := g.Host
+	if i := strings.LastIndex(host, "]"); i >= 0 {
+		host = host[i+1:] // IPv6 字面量
+	}
+	return strings.Contains(host, ":")
+}
@@ -0,0 +1,88 @@
+package rulespec
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// MaxIncludeDepth 配置引用的最大嵌套层数
+const MaxIncludeDepth = 8
+
+// Include 引用的其他已保存配置
+type Include struct {
+	ConfigID       string `json:"configId"`                 // 被引用配置的 ID
+	PriorityOffset int    `json:"priorityOffset,omitempty"` // 加到被引用配置中每条规则优先级上的偏移量
+}
+
+// UnmarshalJSON 支持直接以配置 ID 字符串表示不带偏移量的引用
+func (inc *Include) UnmarshalJSON(data []byte) error {
+	var id string
+	if err := json.Unmarshal(data, &id); err == nil {
+		*inc = Include{ConfigID: id}
+		return nil
+	}
+	type plain Include
+	return json.Unmarshal(data, (*plain)(inc))
+}
+
+// ConfigResolver 按配置 ID 查找已保存的配置
+type ConfigResolver func(configID string) (*Config, error)
+
+// Compose 展开配置引用：依次合并自身规则和各引用配置（含其嵌套引用）的规则，被引用规则的优先级加上偏移量。
+// 规则 ID 相同时先出现的生效，因此自身规则覆盖被引用的同 ID 规则，先列出的引用覆盖后列出的引用；
+// 优先级相同时自身规则排在被引用规则之前。check 不为 nil 时对每个被引用的配置调用（如校验签名）。
+// 没有引用时返回配置本身；合并结果不再带有签名
+func (c *Config) Compose(resolve ConfigResolver, check func(*Config) error) (*Config, error) {
+	if len(c.Includes) == 0 {
+		return c, nil
+	}
+	if resolve == nil {
+		return nil, fmt.Errorf("配置引用了其他配置，但未提供配置查找")
+	}
+
+	composed := *c
+	composed.Signature = ""
+	composed.Rules = nil
+	seen := make(map[string]bool)
+	if err := composed.collect(c, 0, []string{c.ID}, seen, resolve, check); err != nil {
+		return nil, err
+	}
+	return &composed, nil
+}
+
+// collect 递归收集配置 src 及其引用配置的规则，stack 为当前引用链，用于检测循环引用
+func (c *Config) collect(src *Config, offset int, stack []string, seen map[string]bool, resolve ConfigResolver, check func(*Config) error) error {
+	for _, rule := range src.Rules {
+		if seen[rule.ID] {
+			continue
+		}
+		seen[rule.ID] = true
+		rule.Priority += offset
+		c.Rules = append(c.Rules, rule)
+	}
+
+	for _, inc := range src.Includes {
+		for _, id := range stack {
+			if id == inc.ConfigID {
+				return fmt.Errorf("配置循环引用: %s -> %s", strings.Join(stack, " -> "), inc.ConfigID)
+			}
+		}
+		if len(stack) > MaxIncludeDepth {
+			return fmt.Errorf("配置引用层数超过 %d 层: %s", MaxIncludeDepth, strings.Join(stack, " -> "))
+		}
+		included, err := resolve(inc.ConfigID)
+		if err != nil {
+			return fmt.Errorf("加载引用的配置 %s 失败: %w", inc.ConfigID, err)
+		}
+		if check != nil {
+			if err := check(included); err != nil {
+				return fmt.Errorf("引用的配置 %s: %w", inc.ConfigID, err)
+			}
+		}
+		if err := c.collect(included, offset+inc.PriorityOffset, append(stack[:len(stack):len(stack)], inc.ConfigID), seen, resolve, check); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,149 @@
+package rulespec
+
+import (
+	"encoding/json"
+	"strings"
+	"time"
+)
+
+// MaxRuleChangelog 每条规则保留的变更记录上限
+const MaxRuleChangelog = 50
+
+// RuleChange 规则变更记录
+type RuleChange struct {
+	At      int64  `json:"at"`               // 变更时间（毫秒时间戳）
+	Author  string `json:"author,omitempty"` // 变更人
+	Summary string `json:"summary"`          // 变更摘要
+}
+
+// StampRules 对比保存前后的配置，自动维护规则的作者、时间和变更记录。
+// prev 为已保存的配置（可为 nil），其中的元数据视为权威值，不接受客户端修改
+func StampRules(prev, cur *Config, author string, now time.Time) {
+	if cur == nil {
+		return
+	}
+	ts := now.UnixMilli()
+	old := make(map[string]*Rule)
+	if prev != nil {
+		for i := range prev.Rules {
+			old[prev.Rules[i].ID] = &prev.Rules[i]
+		}
+	}
+
+	for i := range cur.Rules {
+		rule := &cur.Rules[i]
+		p, ok := old[rule.ID]
+		if !ok {
+			// 新规则（导入或复制的规则保留原有元数据）
+			if rule.CreatedAt == 0 {
+				rule.CreatedAt = ts
+				rule.UpdatedAt = ts
+				if rule.Author == "" {
+					rule.Author = author
+				}
+				rule.Changelog = appendChange(rule.Changelog, RuleChange{At: ts, Author: author, Summary: "创建规则"})
+			}
+			continue
+		}
+
+		rule.Author = p.Author
+		rule.CreatedAt = p.CreatedAt
+		rule.UpdatedAt = p.UpdatedAt
+		rule.Changelog = p.Changelog
+		if summary := describeRuleChange(p, rule); summary != "" {
+			rule.UpdatedAt = ts
+			rule.Changelog = appendChange(rule.Changelog, RuleChange{At: ts, Author: author, Summary: summary})
+		}
+	}
+}
+
+// RulesChangedSince 返回在 since（毫秒时间戳）之后有变更的规则
+func (c *Config) RulesChangedSince(since int64) []Rule {
+	out := []Rule{}
+	for _, rule := range c.Rules {
+		if rule.UpdatedAt > since {
+			out = append(out, rule)
+		}
+	}
+	return out
+}
+
+// SearchRules 返回说明中包含 text 的规则（不区分大小写），text 为空时返回空列表
+func (c *Config) SearchRules(text string) []Rule {
+	out := []Rule{}
+	text = strings.ToLower(strings.TrimSpace(text))
+	if text == "" {
+		return out
+	}
+	for _, rule := range c.Rules {
+		if strings.Contains(strings.ToLower(rule.Description), text) {
+			out = append(out, rule)
+		}
+	}
+	return out
+}
+
+// appendChange 追加变更记录并截断到上限
+func appendChange(log []RuleChange, c RuleChange) []RuleChange {
+	log = append(log, c)
+	if len(log) > MaxRuleChangelog {
+		log = log[len(log)-MaxRuleChangelog:]
+	}
+	return log
+}
+
+// describeRuleChange 生成两个版本规则之间的变更摘要，无变化时返回空字符串
+func describeRuleChange(a, b *Rule) string {
+	var parts []string
+	if a.Name != b.Name {
+		parts = append(parts, "名称")
+	}
+	if a.Description != b.Description {
+		parts = append(parts, "说明")
+	}
+	if a.Enabled != b.Enabled {
+		if b.Enabled {
+			parts = append(parts, "启用")
+		} else {
+			parts = append(parts, "禁用")
+		}
+	}
+	if !sameJSON(a.Tags, b.Tags) {
+		parts = append(parts, "标签")
+	}
+	if a.Priority != b.Priority {
+		parts = append(parts, "优先级")
+	}
+	if a.Stage != b.Stage {
+		parts = append(parts, "阶段")
+	}
+	if a.StopProcessing != b.StopProcessing {
+		parts = append(parts, "终止后续规则")
+	}
+	if !sameJSON(a.Match, b.Match) {
+		parts = append(parts, "匹配条件")
+	}
+	if !sameJSON(a.URLNormalize, b.URLNormalize) {
+		parts = append(parts, "URL 规范化")
+	}
+	if !sameJSON(a.Actions, b.Actions) {
+		parts = append(parts, "行为")
+	}
+	if !sameJSON(a.Count, b.Count) {
+		parts = append(parts, "计数条件")
+	}
+	if !sameJSON(a.RequiresRuleMatched, b.RequiresRuleMatched) {
+		parts = append(parts, "依赖规则")
+	}
+	if len(parts) == 0 {
+		return ""
+	}
+	return "修改" + strings.Join(parts, "、")
+}
+
+// sameJSON 按 JSON 序列化结果比较两个值
+func sameJSON(a, b any) bool {
+	ja, err1 := json.Marshal(a)
+	jb, err2 := json.Marshal(b)
+	return err1 == nil && err2 == nil && string(ja) == string(jb)
+}
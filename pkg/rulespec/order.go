@@ -0,0 +1,23 @@
+package rulespec
+
+import "sort"
+
+// EvaluationOrder 返回已启用规则的生效顺序：按 priority 从大到小，优先级相同时保持配置中的顺序。
+// stage 为空时返回所有阶段的规则
+func (c *Config) EvaluationOrder(stage Stage) []*Rule {
+	if c == nil {
+		return nil
+	}
+	ordered := make([]*Rule, 0, len(c.Rules))
+	for i := range c.Rules {
+		r := &c.Rules[i]
+		if !r.Enabled || (stage != "" && r.Stage != stage) {
+			continue
+		}
+		ordered = append(ordered, r)
+	}
+	sort.SliceStable(ordered, func(i, j int) bool {
+		return ordered[i].Priority > ordered[j].Priority
+	})
+	return ordered
+}
@@ -0,0 +1,78 @@
+package rulespec
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+)
+
+var (
+	ErrConfigUnsigned         = errors.New("配置未签名")
+	ErrConfigSignatureInvalid = errors.New("配置签名无效或内容已被修改")
+)
+
+// GenerateSigningKeyPair 生成 Ed25519 签名密钥对，返回 base64 编码的公钥和私钥
+func GenerateSigningKeyPair() (string, string, error) {
+	pub, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		return "", "", err
+	}
+	return base64.StdEncoding.EncodeToString(pub), base64.StdEncoding.EncodeToString(priv), nil
+}
+
+// ParsePublicKey 解析 base64 编码的 Ed25519 公钥
+func ParsePublicKey(s string) (ed25519.PublicKey, error) {
+	b, err := base64.StdEncoding.DecodeString(s)
+	if err != nil || len(b) != ed25519.PublicKeySize {
+		return nil, fmt.Errorf("无效的签名公钥")
+	}
+	return ed25519.PublicKey(b), nil
+}
+
+// ParsePrivateKey 解析 base64 编码的 Ed25519 私钥
+func ParsePrivateKey(s string) (ed25519.PrivateKey, error) {
+	b, err := base64.StdEncoding.DecodeString(s)
+	if err != nil || len(b) != ed25519.PrivateKeySize {
+		return nil, fmt.Errorf("无效的签名私钥")
+	}
+	return ed25519.PrivateKey(b), nil
+}
+
+// signingPayload 返回参与签名的内容：去除签名字段后的配置 JSON
+func (c *Config) signingPayload() ([]byte, error) {
+	cp := *c
+	cp.Signature = ""
+	return json.Marshal(&cp)
+}
+
+// Sign 使用私钥对配置签名，结果写入 Signature 字段
+func (c *Config) Sign(priv ed25519.PrivateKey) error {
+	payload, err := c.signingPayload()
+	if err != nil {
+		return fmt.Errorf("序列化配置失败: %w", err)
+	}
+	c.Signature = base64.StdEncoding.EncodeToString(ed25519.Sign(priv, payload))
+	return nil
+}
+
+// VerifySignature 使用公钥校验配置签名
+func (c *Config) VerifySignature(pub ed25519.PublicKey) error {
+	if c.Signature == "" {
+		return ErrConfigUnsigned
+	}
+	sig, err := base64.StdEncoding.DecodeString(c.Signature)
+	if err != nil {
+		return ErrConfigSignatureInvalid
+	}
+	payload, err := c.signingPayload()
+	if err != nil {
+		return fmt.Errorf("序列化配置失败: %w", err)
+	}
+	if !ed25519.Verify(pub, payload, sig) {
+		return ErrConfigSignatureInvalid
+	}
+	return nil
+}
@@ -5,6 +5,7 @@ import (
 	"crypto/rand"
 	"fmt"
 	"regexp"
+	"slices"
 	"time"
 )
 
@@ -28,12 +29,14 @@ var (
 
 // Config 配置文件根结构
 type Config struct {
-	ID          string         `json:"id"`          // 配置唯一标识符
-	Name        string         `json:"name"`        // 配置名称
-	Version     string         `json:"version"`     // 配置格式规范版本
-	Description string         `json:"description"` // 配置描述
-	Settings    map[string]any `json:"settings"`    // 预留设置项
-	Rules       []Rule         `json:"rules"`       // 规则列表
+	ID          string         `json:"id"`                  // 配置唯一标识符
+	Name        string         `json:"name"`                // 配置名称
+	Version     string         `json:"version"`             // 配置格式规范版本
+	Description string         `json:"description"`         // 配置描述
+	Settings    map[string]any `json:"settings"`            // 预留设置项
+	Rules       []Rule         `json:"rules"`               // 规则列表
+	Includes    []Include      `json:"includes,omitempty"`  // 加载时合并的其他已保存配置
+	Signature   string         `json:"signature,omitempty"` // Ed25519 签名（base64），覆盖除签名外的全部内容
 }
 
 // GenerateConfigID 生成配置 ID，格式：config-YYYYMMDD-随机6位
@@ -70,6 +73,24 @@ func ValidateRuleID(id string) error {
 	return nil
 }
 
+// TerminateErrorReasons terminate 行为可用的网络错误原因，与 CDP Network.ErrorReason 枚举一致
+var TerminateErrorReasons = []string{
+	"Failed", "Aborted", "TimedOut", "AccessDenied",
+	"ConnectionClosed", "ConnectionReset", "ConnectionRefused", "ConnectionAborted", "ConnectionFailed",
+	"NameNotResolved", "InternetDisconnected", "AddressUnreachable", "BlockedByClient", "BlockedByResponse",
+}
+
+// ValidateActions 校验规则行为参数
+func (r *Rule) ValidateActions() error {
+	for i := range r.Actions {
+		a := &r.Actions[i]
+		if a.Type == ActionTerminate && a.ErrorReason != "" && !slices.Contains(TerminateErrorReasons, a.ErrorReason) {
+			return fmt.Errorf("terminate 行为的错误原因 '%s' 无效", a.ErrorReason)
+		}
+	}
+	return nil
+}
+
 // generateRandomString 生成指定长度的随机字符串（字母+数字）
 func generateRandomString(length int) string {
 	const charset = "abcdefghijklmnopqrstuvwxyz0123456789"
@@ -96,12 +117,74 @@ func NewConfig(name string) *Config {
 	}
 }
 
+// SettingInterceptStage 配置级拦截阶段设置项（both/request/response），优先于会话选项
+const SettingInterceptStage = "interceptStage"
+
+// InterceptStage 返回配置中设置的拦截阶段，未设置时返回空字符串
+func (c *Config) InterceptStage() string {
+	if c == nil || c.Settings == nil {
+		return ""
+	}
+	v, _ := c.Settings[SettingInterceptStage].(string)
+	return v
+}
+
+// SettingInterceptResourceTypes 配置级拦截资源类型设置项（如 ["XHR", "Fetch", "Document"]），优先于会话选项
+const SettingInterceptResourceTypes = "interceptResourceTypes"
+
+// InterceptResourceTypes 返回配置中设置的拦截资源类型，未设置时返回 nil
+func (c *Config) InterceptResourceTypes() []string {
+	if c == nil || c.Settings == nil {
+		return nil
+	}
+	switch v := c.Settings[SettingInterceptResourceTypes].(type) {
+	case []string:
+		return v
+	case []any:
+		out := make([]string, 0, len(v))
+		for _, item := range v {
+			if s, ok := item.(string); ok {
+				out = append(out, s)
+			}
+		}
+		return out
+	}
+	return nil
+}
+
+// SettingConflictPolicy 配置级冲突处理策略设置项
+const SettingConflictPolicy = "conflictPolicy"
+
+// ConflictPolicy 多条规则对同一字段设置不同取值时的处理策略
+type ConflictPolicy string
+
+const (
+	ConflictPriorityWins ConflictPolicy = "priority" // 保留优先级高的规则的值（默认）
+	ConflictFirstWins    ConflictPolicy = "first"    // 保留配置中靠前的规则的值
+	ConflictAbort        ConflictPolicy = "abort"    // 放弃所有修改，原样放行
+)
+
+// ConflictPolicy 返回配置中设置的冲突处理策略，未设置或无效时返回 ConflictPriorityWins
+func (c *Config) ConflictPolicy() ConflictPolicy {
+	if c == nil || c.Settings == nil {
+		return ConflictPriorityWins
+	}
+	v, _ := c.Settings[SettingConflictPolicy].(string)
+	switch p := ConflictPolicy(v); p {
+	case ConflictFirstWins, ConflictAbort:
+		return p
+	}
+	return ConflictPriorityWins
+}
+
 // Stage 生命周期阶段
 type Stage string
 
 const (
-	StageRequest  Stage = "request"  // 请求阶段
-	StageResponse Stage = "response" // 响应阶段
+	StageRequest   Stage = "request"   // 请求阶段
+	StageResponse  Stage = "response"  // 响应阶段
+	StageWSSend    Stage = "wsSend"    // WebSocket 发送帧
+	StageWSReceive Stage = "wsReceive" // WebSocket 接收帧
 )
 
 // Rule 规则定义
@@ -113,6 +196,67 @@ type Rule struct {
 	Stage    Stage    `json:"stage"`    // 生命周期阶段
 	Match    Match    `json:"match"`    // 匹配规则
 	Actions  []Action `json:"actions"`  // 执行行为列表
+
+	// Description 规则说明（Markdown），记录规则的用途和设计理由
+	Description string `json:"description,omitempty"`
+
+	// Tags 规则所属的功能区域（如 payments、search），可按标签整体启用或停用并统计命中
+	Tags []string `json:"tags,omitempty"`
+
+	// StopProcessing 命中后不再执行优先级更低的规则（与 v1 短路模式一致）
+	StopProcessing bool `json:"stopProcessing,omitempty"`
+
+	// URLNormalize 评估 URL 条件前对请求 URL 的规范化，避免因末尾斜杠、默认端口等差异导致规则不匹配
+	URLNormalize *URLNormalize `json:"urlNormalize,omitempty"`
+
+	// Count 按条件已满足的次数决定规则是否生效，用于确定性地模拟偶发或逐步出现的故障
+	Count *MatchCount `json:"count,omitempty"`
+
+	// RequiresRuleMatched 依赖的规则 ID，全部在会话中至少命中过一次后本规则才参与匹配，用于编排多步骤场景
+	RequiresRuleMatched []string `json:"requiresRuleMatched,omitempty"`
+
+	// 以下元数据在保存时自动维护
+	Author    string       `json:"author,omitempty"`    // 创建人
+	CreatedAt int64        `json:"createdAt,omitempty"` // 创建时间（毫秒时间戳）
+	UpdatedAt int64        `json:"updatedAt,omitempty"` // 最后修改时间（毫秒时间戳）
+	Changelog []RuleChange `json:"changelog,omitempty"` // 变更记录
+}
+
+// MatchCount 规则的计数条件，多个字段同时设置时需全部满足
+type MatchCount struct {
+	First int `json:"first,omitempty"` // 只在前 N 次生效
+	Every int `json:"every,omitempty"` // 每 N 次生效一次（第 N、2N、3N… 次）
+	After int `json:"after,omitempty"` // 前 N 次不生效，之后每次生效
+}
+
+// Allows 判断第 n 次（从 1 开始）满足条件时规则是否生效
+func (c *MatchCount) Allows(n int64) bool {
+	if c == nil {
+		return true
+	}
+	if c.First > 0 && n > int64(c.First) {
+		return false
+	}
+	if c.Every > 0 && n%int64(c.Every) != 0 {
+		return false
+	}
+	if c.After > 0 && n <= int64(c.After) {
+		return false
+	}
+	return true
+}
+
+// URLNormalize URL 规范化选项
+type URLNormalize struct {
+	IgnoreTrailingSlash bool `json:"ignoreTrailingSlash,omitempty"` // 忽略路径末尾的 /
+	IgnoreDefaultPort   bool `json:"ignoreDefaultPort,omitempty"`   // 去掉 http 的 :80 和 https 的 :443
+	CaseInsensitiveHost bool `json:"caseInsensitiveHost,omitempty"` // 主机名不区分大小写
+	SortQuery           bool `json:"sortQuery,omitempty"`           // 查询参数按名称排序，同名参数保持原有顺序
+}
+
+// Enabled 是否启用了任一规范化选项
+func (n *URLNormalize) Enabled() bool {
+	return n != nil && (n.IgnoreTrailingSlash || n.IgnoreDefaultPort || n.CaseInsensitiveHost || n.SortQuery)
 }
 
 // NewRule 创建一个新的空规则，index 为当前规则列表中的索引
@@ -144,10 +288,13 @@ const (
 	ConditionURLSuffix   ConditionType = "urlSuffix"   // URL 后缀匹配
 	ConditionURLContains ConditionType = "urlContains" // URL 包含匹配
 	ConditionURLRegex    ConditionType = "urlRegex"    // URL 正则匹配
+	ConditionPathPattern ConditionType = "pathPattern" // 路径模板匹配并绑定路径参数
+	ConditionURLGlob     ConditionType = "urlGlob"     // 按协议、主机、路径的通配符匹配（* 与 **）
 
-	// Method 和 ResourceType 条件类型
-	ConditionMethod       ConditionType = "method"       // HTTP 方法
-	ConditionResourceType ConditionType = "resourceType" // 资源类型
+	// Method、ResourceType 和协议条件类型
+	ConditionMethod         ConditionType = "method"         // HTTP 方法
+	ConditionResourceType   ConditionType = "resourceType"   // 资源类型
+	ConditionProtocolEquals ConditionType = "protocolEquals" // 协商的 HTTP 协议（h2 / h3 / http/1.1）
 
 	// Header 条件类型
 	ConditionHeaderExists    ConditionType = "headerExists"    // Header 存在
@@ -169,37 +316,93 @@ const (
 	ConditionCookieEquals    ConditionType = "cookieEquals"    // Cookie 精确匹配
 	ConditionCookieContains  ConditionType = "cookieContains"  // Cookie 包含
 	ConditionCookieRegex     ConditionType = "cookieRegex"     // Cookie 正则
+	ConditionCookieBlocked   ConditionType = "cookieBlocked"   // Cookie 被浏览器阻止（可按名称与原因筛选）
 
 	// Body 条件类型
 	ConditionBodyContains ConditionType = "bodyContains" // Body 包含
 	ConditionBodyRegex    ConditionType = "bodyRegex"    // Body 正则
 	ConditionBodyJsonPath ConditionType = "bodyJsonPath" // JSON Path 匹配
+
+	// JWT 条件
+	ConditionJWTClaim ConditionType = "jwtClaim" // 解码 JWT 并匹配声明
+
+	// 表达式条件
+	ConditionExpression ConditionType = "expression" // CEL 风格表达式，结果为 true 时成立
+
+	// 频率条件
+	ConditionRateLimit ConditionType = "rateLimit" // 滑动窗口内的请求数超过上限时成立
+
+	// 时间条件
+	ConditionTimeWindow ConditionType = "timeWindow" // 当前本地时间位于 HH:MM-HH:MM 区间内（可限定星期）时成立
+
+	// 概率条件
+	ConditionProbability ConditionType = "probability" // 按 percent 指定的百分比随机成立
 )
 
 // Condition 条件定义
 type Condition struct {
 	Type    ConditionType `json:"type"`              // 条件类型
-	Value   string        `json:"value,omitempty"`   // 匹配值 (url*, *Equals, *Contains, bodyContains)
+	Value   string        `json:"value,omitempty"`   // 匹配值 (url*, *Equals, *Contains, bodyContains, bodyJsonPath, pathPattern, urlGlob, jwtClaim, rateLimit, timeWindow)
 	Values  []string      `json:"values,omitempty"`  // 匹配值列表 (method, resourceType)
-	Pattern string        `json:"pattern,omitempty"` // 正则表达式 (*Regex)
-	Name    string        `json:"name,omitempty"`    // 键名 (header*, query*, cookie*)
-	Path    string        `json:"path,omitempty"`    // JSON Path (bodyJsonPath)
+	Pattern string        `json:"pattern,omitempty"` // 正则表达式 (*Regex, jwtClaim, bodyJsonPath)
+	Name    string        `json:"name,omitempty"`    // 键名 (header*, query*, cookie*, jwtClaim)
+	Path    string        `json:"path,omitempty"`    // JSON Path (bodyJsonPath, jwtClaim)
+
+	// bodyJsonPath 专用
+	Op CompareOp `json:"op,omitempty"` // 路径选中的值与 value 的比较方式，默认 equals；指定 pattern 时默认 regex
+
+	// jwtClaim 专用
+	Source        string `json:"source,omitempty"`        // JWT 来源：header（默认）或 cookie
+	Key           string `json:"key,omitempty"`           // 签名校验密钥，HS* 为共享密钥，RS*/ES* 为 PEM 公钥；为空时不校验签名
+	ExpiresWithin *int   `json:"expiresWithin,omitempty"` // 声明作为 Unix 时间戳距当前不超过的秒数
+
+	// rateLimit 专用
+	Limit    int `json:"limit,omitempty"`    // 窗口内允许的请求数，超过后条件成立
+	WindowMS int `json:"windowMs,omitempty"` // 滑动窗口长度，毫秒
+
+	// timeWindow 专用
+	Days []string `json:"days,omitempty"` // 生效的星期（mon..sun，不区分大小写），为空时每天生效
+
+	// probability 专用
+	Percent float64 `json:"percent,omitempty"` // 成立的概率（0-100，可带小数）
 }
 
+// CompareOp bodyJsonPath 条件的比较方式
+type CompareOp string
+
+const (
+	CompareEquals    CompareOp = "equals"    // 等于 value（字符串为原始值，其他类型为 JSON 文本）
+	CompareNotEquals CompareOp = "notEquals" // 选中的值都不等于 value
+	CompareContains  CompareOp = "contains"  // 字符串包含 value，或数组中有等于 value 的元素
+	CompareRegex     CompareOp = "regex"     // 匹配 pattern，未指定时使用 value
+	CompareGt        CompareOp = "gt"        // 大于 value，两侧为数字时按数值比较，否则按字符串比较
+	CompareGte       CompareOp = "gte"       // 大于等于 value
+	CompareLt        CompareOp = "lt"        // 小于 value
+	CompareLte       CompareOp = "lte"       // 小于等于 value
+	CompareExists    CompareOp = "exists"    // 路径选中任意值即成立
+)
+
 // ActionType 行为类型
 type ActionType string
 
 const (
 	// 请求阶段行为类型
-	ActionSetUrl           ActionType = "setUrl"           // 设置请求 URL
-	ActionSetMethod        ActionType = "setMethod"        // 设置请求方法
-	ActionSetQueryParam    ActionType = "setQueryParam"    // 设置查询参数
-	ActionRemoveQueryParam ActionType = "removeQueryParam" // 移除查询参数
-	ActionSetCookie        ActionType = "setCookie"        // 设置 Cookie
-	ActionRemoveCookie     ActionType = "removeCookie"     // 移除 Cookie
-	ActionSetFormField     ActionType = "setFormField"     // 设置表单字段
-	ActionRemoveFormField  ActionType = "removeFormField"  // 移除表单字段
-	ActionBlock            ActionType = "block"            // 拦截请求
+	ActionSetUrl             ActionType = "setUrl"             // 设置请求 URL
+	ActionSetMethod          ActionType = "setMethod"          // 设置请求方法
+	ActionSetQueryParam      ActionType = "setQueryParam"      // 设置查询参数
+	ActionRemoveQueryParam   ActionType = "removeQueryParam"   // 移除查询参数
+	ActionSetCookie          ActionType = "setCookie"          // 设置 Cookie
+	ActionRemoveCookie       ActionType = "removeCookie"       // 移除 Cookie
+	ActionSetFormField       ActionType = "setFormField"       // 设置表单字段
+	ActionRemoveFormField    ActionType = "removeFormField"    // 移除表单字段
+	ActionBlock              ActionType = "block"              // 拦截请求
+	ActionNotModified        ActionType = "notModified"        // If-None-Match 命中时返回 304
+	ActionProvideCredentials ActionType = "provideCredentials" // 自动应答 HTTP 认证质询
+	ActionRedirect           ActionType = "redirect"           // 重定向到其他 URL
+	ActionSignHMAC           ActionType = "signHmac"           // 按最终请求重新计算 HMAC 签名
+	ActionSignAWSV4          ActionType = "signAwsV4"          // 按最终请求重新计算 AWS SigV4 签名
+	ActionInjectScript       ActionType = "injectScript"       // 向 URL 匹配的页面注入脚本
+	ActionSpoofOrigin        ActionType = "spoofOrigin"        // 一并改写或移除 Origin、Referer
 
 	// 请求/响应阶段通用行为类型
 	ActionSetHeader       ActionType = "setHeader"       // 设置头部
@@ -207,9 +410,19 @@ const (
 	ActionSetBody         ActionType = "setBody"         // 替换 Body
 	ActionReplaceBodyText ActionType = "replaceBodyText" // 字符串替换 Body
 	ActionPatchBodyJson   ActionType = "patchBodyJson"   // JSON Patch 修改 Body
+	ActionStripValidators ActionType = "stripValidators" // 移除缓存校验头
+	ActionTerminate       ActionType = "terminate"       // 延时或截断后终止请求
+	ActionDelay           ActionType = "delay"           // 按分布抽取延迟后再放行
+	ActionPause           ActionType = "pause"           // 断点：暂停请求，等待人工修改后放行或拒绝
+	ActionCaptureVar      ActionType = "captureVar"      // 提取请求或响应中的值，保存为会话变量供后续规则引用
 
 	// 响应阶段行为类型
-	ActionSetStatus ActionType = "setStatus" // 设置响应状态码
+	ActionSetStatus       ActionType = "setStatus"       // 设置响应状态码
+	ActionRewriteLocation ActionType = "rewriteLocation" // 改写 3xx 响应的 Location 头
+	ActionThrottle        ActionType = "throttle"        // 按限速推迟响应，模拟慢速传输
+	ActionStripPreload    ActionType = "stripPreload"    // 移除 Link 头中的预加载条目
+	ActionRewritePreload  ActionType = "rewritePreload"  // 改写 Link 头中预加载条目的地址
+	ActionCompressBody    ActionType = "compressBody"    // 按指定 Content-Encoding 重新压缩响应体
 )
 
 // BodyEncoding Body 编码方式
@@ -223,19 +436,75 @@ const (
 // Action 行为定义
 type Action struct {
 	Type         ActionType        `json:"type"`                   // 行为类型
-	Value        any               `json:"value,omitempty"`        // 目标值 (setUrl, setMethod, setStatus, setBody)
-	Name         string            `json:"name,omitempty"`         // 键名 (setHeader, removeHeader, setQueryParam, setCookie, setFormField)
+	Value        any               `json:"value,omitempty"`        // 目标值 (setUrl, setMethod, setStatus, setBody, redirect)，脚本源码 (injectScript)，编码 gzip/deflate/br (compressBody)，来源页面地址 (spoofOrigin)
+	Name         string            `json:"name,omitempty"`         // 键名 (setHeader, removeHeader, setQueryParam, setCookie, setFormField)，变量名 (captureVar)
 	Encoding     BodyEncoding      `json:"encoding,omitempty"`     // Body 编码方式 (setBody)
-	Search       string            `json:"search,omitempty"`       // 搜索内容 (replaceBodyText)
-	Replace      string            `json:"replace,omitempty"`      // 替换内容 (replaceBodyText)
+	Search       string            `json:"search,omitempty"`       // 搜索内容 (replaceBodyText, rewriteLocation, stripPreload, rewritePreload)
+	Replace      string            `json:"replace,omitempty"`      // 替换内容 (replaceBodyText, rewriteLocation, rewritePreload)
 	ReplaceAll   bool              `json:"replaceAll,omitempty"`   // 是否全部替换 (replaceBodyText)
 	Patches      []JSONPatchOp     `json:"patches,omitempty"`      // JSON Patch 操作列表 (patchBodyJson)
-	StatusCode   int               `json:"statusCode,omitempty"`   // HTTP 状态码 (block)
+	StatusCode   int               `json:"statusCode,omitempty"`   // HTTP 状态码 (block, redirect)
 	Headers      map[string]string `json:"headers,omitempty"`      // 响应头 (block)
 	Body         string            `json:"body,omitempty"`         // 响应体 (block)
 	BodyEncoding BodyEncoding      `json:"bodyEncoding,omitempty"` // Body 编码方式 (block)
+	ETag         string            `json:"etag,omitempty"`         // 实体标签 (notModified)
+	AfterBytes   int               `json:"afterBytes,omitempty"`   // 截断字节数 (terminate，仅响应阶段)
+	AfterMS      int               `json:"afterMs,omitempty"`      // 终止前等待毫秒数 (terminate)
+	ErrorReason  string            `json:"errorReason,omitempty"`  // 网络错误原因 (terminate)，取值见 TerminateErrorReasons
+	Username     string            `json:"username,omitempty"`     // 认证用户名 (provideCredentials)
+	Password     string            `json:"password,omitempty"`     // 认证密码 (provideCredentials)
+	AuthSource   string            `json:"authSource,omitempty"`   // 质询来源 server/proxy，空表示不限 (provideCredentials)
+	SecFetch     bool              `json:"secFetch,omitempty"`     // 同步改写 Sec-Fetch-Site (spoofOrigin)
+
+	// 签名参数，签名在所有规则的修改完成后按最终请求计算
+	SignFields        []string `json:"signFields,omitempty"`        // 参与签名的字段，按顺序拼接 (signHmac)
+	Separator         *string  `json:"separator,omitempty"`         // 字段分隔符，默认换行 (signHmac)
+	Algorithm         string   `json:"algorithm,omitempty"`         // 哈希算法 sha256/sha512/sha1，默认 sha256 (signHmac)
+	SigningKey        string   `json:"signingKey,omitempty"`        // HMAC 密钥 (signHmac)
+	SignatureEncoding string   `json:"signatureEncoding,omitempty"` // 签名编码 hex/base64，默认 hex (signHmac)
+	AccessKeyID       string   `json:"accessKeyId,omitempty"`       // 访问密钥 ID (signAwsV4)
+	SecretAccessKey   string   `json:"secretAccessKey,omitempty"`   // 私有访问密钥 (signAwsV4)
+	SessionToken      string   `json:"sessionToken,omitempty"`      // 临时凭证的会话令牌 (signAwsV4)
+	Region            string   `json:"region,omitempty"`            // 区域，如 us-east-1 (signAwsV4)
+	Service           string   `json:"service,omitempty"`           // 服务名，如 execute-api、s3 (signAwsV4)
+
+	// 延迟参数，每次命中按分布重新抽取
+	Distribution DelayDistribution `json:"distribution,omitempty"` // 延迟分布，默认 fixed (delay)
+	DelayMS      int               `json:"delayMs,omitempty"`      // fixed 的延迟、normal 的均值、pareto 的最小值，毫秒 (delay)
+	MinMS        int               `json:"minMs,omitempty"`        // 延迟下限，毫秒；uniform 的取值下限 (delay)
+	MaxMS        int               `json:"maxMs,omitempty"`        // 延迟上限，毫秒；uniform 的取值上限，也用于截断长尾 (delay)
+	StdDevMS     int               `json:"stdDevMs,omitempty"`     // 标准差，毫秒 (delay，normal)
+	Alpha        float64           `json:"alpha,omitempty"`        // 形状参数，越小长尾越重，默认 1.5 (delay，pareto)
+
+	TimeoutMS      int `json:"timeoutMs,omitempty"`      // 等待人工处理的毫秒数，默认 60000，超时后按规则修改放行 (pause)
+	BytesPerSecond int `json:"bytesPerSecond,omitempty"` // 响应体传输速率，字节/秒 (throttle)
+
+	// 变量提取参数，依次按来源取值、按 JSON Pointer 定位、按正则截取，取不到值时不修改变量
+	From    CaptureSource `json:"from,omitempty"`    // 取值来源，默认 body (captureVar)
+	Header  string        `json:"header,omitempty"`  // 取值的头部名称，from 为 header 时使用 (captureVar)
+	Pointer string        `json:"pointer,omitempty"` // JSON Pointer，如 /data/token，from 为 body 时使用 (captureVar)
+	Pattern string        `json:"pattern,omitempty"` // 正则，取第一个捕获组，没有捕获组时取整个匹配 (captureVar)
 }
 
+// CaptureSource captureVar 行为的取值来源
+type CaptureSource string
+
+const (
+	CaptureFromBody   CaptureSource = "body"   // 当前阶段的 Body（前序规则改写后的结果）
+	CaptureFromHeader CaptureSource = "header" // 当前阶段的头部：请求阶段为请求头，响应阶段为响应头
+	CaptureFromURL    CaptureSource = "url"    // 请求 URL
+)
+
+// DelayDistribution 延迟分布
+type DelayDistribution string
+
+const (
+	DelayFixed   DelayDistribution = "fixed"   // 固定延迟
+	DelayUniform DelayDistribution = "uniform" // [minMs, maxMs] 均匀分布
+	DelayNormal  DelayDistribution = "normal"  // 正态分布
+	DelayPareto  DelayDistribution = "pareto"  // 帕累托分布，少量请求出现长尾延迟
+)
+
 // JSONPatchOp JSON Patch 操作
 type JSONPatchOp struct {
 	Op    string `json:"op"`              // 操作类型: add, remove, replace, move, copy, test
@@ -244,23 +513,36 @@ type JSONPatchOp struct {
 	From  string `json:"from,omitempty"`  // 源路径 (move, copy)
 }
 
-// IsTerminal 判断行为是否为终结性行为
+// IsSigning 判断行为是否为签名行为
+func (a *Action) IsSigning() bool {
+	return a.Type == ActionSignHMAC || a.Type == ActionSignAWSV4
+}
+
+// IsTerminal 判断行为是否为终结性行为（notModified 仅在 ETag 命中时终结）
 func (a *Action) IsTerminal() bool {
-	return a.Type == ActionBlock
+	return a.Type == ActionBlock || a.Type == ActionNotModified || a.Type == ActionTerminate || a.Type == ActionRedirect
 }
 
 // IsValidForStage 判断行为是否适用于指定阶段
 func (a *Action) IsValidForStage(stage Stage) bool {
+	// WebSocket 帧阶段仅支持 Body 改写和丢弃
+	if stage == StageWSSend || stage == StageWSReceive {
+		return a.Type == ActionSetBody || a.Type == ActionReplaceBodyText || a.Type == ActionBlock
+	}
 	switch a.Type {
 	// 仅请求阶段
 	case ActionSetUrl, ActionSetMethod, ActionSetQueryParam, ActionRemoveQueryParam,
-		ActionSetCookie, ActionRemoveCookie, ActionSetFormField, ActionRemoveFormField, ActionBlock:
+		ActionSetCookie, ActionRemoveCookie, ActionSetFormField, ActionRemoveFormField, ActionBlock,
+		ActionNotModified, ActionProvideCredentials, ActionRedirect, ActionSignHMAC, ActionSignAWSV4,
+		ActionInjectScript, ActionSpoofOrigin:
 		return stage == StageRequest
 	// 仅响应阶段
-	case ActionSetStatus:
+	case ActionSetStatus, ActionRewriteLocation, ActionThrottle, ActionStripPreload, ActionRewritePreload,
+		ActionCompressBody:
 		return stage == StageResponse
 	// 两阶段通用
-	case ActionSetHeader, ActionRemoveHeader, ActionSetBody, ActionReplaceBodyText, ActionPatchBodyJson:
+	case ActionSetHeader, ActionRemoveHeader, ActionSetBody, ActionReplaceBodyText, ActionPatchBodyJson,
+		ActionStripValidators, ActionTerminate, ActionDelay, ActionPause, ActionCaptureVar:
 		return true
 	default:
 		return false